@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/awsauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/notify"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// channel is a configured destination of the watchdog's events
+type channel struct {
+	name     string
+	notifier watchdog.Notifier
+	// stream channels also receive the check event of every reading, and
+	// are registered with WithEventStream
+	stream bool
+	// checksOnly channels ignore every event but checks
+	checksOnly bool
+}
+
+// option returns the watchdog option registering the channel
+func (c channel) option() watchdog.Option {
+	if c.stream {
+		return watchdog.WithEventStream(c.notifier)
+	}
+	return watchdog.WithNotifier(c.notifier)
+}
+
+// newChannels creates the event stream and notifiers of the configuration,
+// and returns them with what must be closed on exit
+func newChannels(config watchdog.Config, eventsOut string) ([]channel, closers, error) {
+	var channels []channel
+	var toClose closers
+	fail := func(err error) ([]channel, closers, error) {
+		toClose.Close()
+		return nil, nil, err
+	}
+
+	if eventsOut == "-" {
+		channels = append(channels, channel{name: "events-out", notifier: notify.NewNDJSON(os.Stdout), stream: true})
+	} else if eventsOut != "" {
+		f, err := os.OpenFile(eventsOut, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			return fail(fmt.Errorf("Error opening events file: %v", err))
+		}
+		toClose = append(toClose, f)
+		channels = append(channels, channel{name: "events-out", notifier: notify.NewNDJSON(f), stream: true})
+	}
+	notifications := config.Notifications
+	if notifications.CloudEvents.URL != "" {
+		channels = append(channels, channel{name: "cloudevents", notifier: notify.NewCloudEvents(notifications.CloudEvents.URL, notifications.CloudEvents.Source)})
+	}
+	if notifications.NATS.URL != "" {
+		// the URL, with its optional user and password, is only read at
+		// startup
+		natsURL, err := secret.Resolve(context.Background(), notifications.NATS.URL)
+		if err != nil {
+			return fail(err)
+		}
+		nats, err := notify.NewNATS(natsURL, notifications.NATS.Subject, notifications.NATS.Token)
+		if err != nil {
+			return fail(err)
+		}
+		toClose = append(toClose, nats)
+		channels = append(channels, channel{name: "nats", notifier: nats})
+	}
+	if len(notifications.Kafka.Brokers) > 0 {
+		kafka, err := notify.NewKafka(notifications.Kafka.Brokers, notifications.Kafka.EventsTopic, notifications.Kafka.SamplesTopic, notify.KafkaOptions{
+			TLS:      notifications.Kafka.TLS,
+			Username: notifications.Kafka.Username,
+			Password: notifications.Kafka.Password,
+		})
+		if err != nil {
+			return fail(err)
+		}
+		toClose = append(toClose, kafka)
+		// the samples topic receives the check event of every reading
+		channels = append(channels, channel{name: "kafka", notifier: kafka, stream: true})
+	}
+	if notifications.SNS.TopicARN != "" {
+		creds, err := awsauth.CredentialsFromEnv()
+		if err != nil {
+			return fail(fmt.Errorf("SNS notifications require AWS credentials: %v", err))
+		}
+		sns, err := notify.NewSNS(notifications.SNS.TopicARN, creds)
+		if err != nil {
+			return fail(err)
+		}
+		tmpl, err := notify.NewTemplate(notify.TemplateConfig(notifications.Template))
+		if err != nil {
+			return fail(err)
+		}
+		// a custom body replaces the JSON event as the message
+		sns.SetTemplate(tmpl, notifications.Template.Body != "")
+		channels = append(channels, channel{name: "sns", notifier: sns})
+	}
+	if len(notifications.Alertmanager.URLs) > 0 {
+		tmpl, err := notify.NewTemplate(notify.TemplateConfig(notifications.Template))
+		if err != nil {
+			return fail(err)
+		}
+		// firing alerts are sent again on the check events
+		channels = append(channels, channel{name: "alertmanager", notifier: notify.NewAlertmanager(notifications.Alertmanager.URLs, notifications.Alertmanager.Labels, tmpl), stream: true})
+	}
+	if config.Heartbeat.URL != "" {
+		// pings follow the check events of successful readings
+		channels = append(channels, channel{name: "heartbeat", notifier: notify.NewHeartbeat(config.Heartbeat.URL, config.Heartbeat.Interval), stream: true, checksOnly: true})
+	}
+	return channels, toClose, nil
+}
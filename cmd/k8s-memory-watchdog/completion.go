@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+)
+
+// subcommands are completed as the first argument
+var subcommands = []string{"completion", "config", "generate", "history", "notify-test", "pause", "resume", "silence", "status", "trigger", "tui", "unsilence", "validate"}
+
+// completionScripts are the shell completion scripts printed by the
+// completion subcommand. They ask the hidden __complete subcommand for the
+// candidates of the word being completed.
+var completionScripts = map[string]string{
+	"bash": `# bash completion of k8s-memory-watchdog, load it with
+#   source <(k8s-memory-watchdog completion bash)
+_k8s_memory_watchdog() {
+    local IFS=$'\n'
+    COMPREPLY=($(k8s-memory-watchdog __complete "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _k8s_memory_watchdog k8s-memory-watchdog
+`,
+	"zsh": `#compdef k8s-memory-watchdog
+# zsh completion of k8s-memory-watchdog, load it with
+#   source <(k8s-memory-watchdog completion zsh)
+_k8s_memory_watchdog() {
+    local -a candidates
+    candidates=("${(@f)$(k8s-memory-watchdog __complete "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    compadd -- ${candidates:#}
+}
+compdef _k8s_memory_watchdog k8s-memory-watchdog
+`,
+	"fish": `# fish completion of k8s-memory-watchdog, load it with
+#   k8s-memory-watchdog completion fish | source
+complete -c k8s-memory-watchdog -f -a '(k8s-memory-watchdog __complete (commandline -opc)[2..-1] (commandline -ct) 2>/dev/null)'
+`,
+}
+
+// nameLister lists the names of Kubernetes resources
+type nameLister interface {
+	Names(ctx context.Context, resource, namespace string) ([]string, error)
+}
+
+// runCompletion implements the completion subcommand, which prints the
+// completion script of a shell
+func runCompletion(args []string) int {
+	if len(args) != 1 || completionScripts[args[0]] == "" {
+		fmt.Fprintln(os.Stderr, "usage: k8s-memory-watchdog completion bash|zsh|fish")
+		return 2
+	}
+	fmt.Print(completionScripts[args[0]])
+	return 0
+}
+
+// runComplete implements the hidden __complete subcommand called by the
+// completion scripts with the words after the program name, the last one
+// being completed, and prints a candidate per line. Namespaces and
+// deployments are listed with kubectl from the current context.
+func runComplete(args []string) int {
+	defineFlags()
+	runner := kubectl.NewRunner(flag.Lookup("kubectl").Value.String(), 0, 0)
+	runner.SetContext(flag.Lookup("kube-context").Value.String())
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	for _, candidate := range complete(ctx, runner, flag.CommandLine, args) {
+		fmt.Println(candidate)
+	}
+	return 0
+}
+
+// complete returns the candidates of the last of words given the ones
+// before it. Flags are those of the watchdog, which notify-test shares.
+func complete(ctx context.Context, lister nameLister, flags *flag.FlagSet, words []string) []string {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current, previous := words[len(words)-1], words[:len(words)-1]
+	last := ""
+	if len(previous) > 0 {
+		last = previous[len(previous)-1]
+	}
+
+	// bash splits --flag=value into three words
+	switch {
+	case current == "=" && last != "":
+		return flagValues(ctx, lister, last, "", words)
+	case last == "=" && len(previous) >= 2:
+		return flagValues(ctx, lister, previous[len(previous)-2], current, words)
+	}
+
+	subcommand := ""
+	if len(previous) > 0 && !strings.HasPrefix(previous[0], "-") {
+		subcommand = previous[0]
+	}
+	if strings.HasPrefix(current, "-") {
+		if subcommand != "" && subcommand != "notify-test" {
+			return nil
+		}
+		if i := strings.Index(current, "="); i >= 0 {
+			var candidates []string
+			for _, value := range flagValues(ctx, lister, current[:i], current[i+1:], words) {
+				candidates = append(candidates, current[:i+1]+value)
+			}
+			return candidates
+		}
+		var names []string
+		flags.VisitAll(func(f *flag.Flag) {
+			names = append(names, "--"+f.Name)
+		})
+		return withPrefix(names, "--"+strings.TrimLeft(current, "-"))
+	}
+	if takesValue(flags, last) {
+		return flagValues(ctx, lister, last, current, words)
+	}
+
+	switch {
+	case len(previous) == 0:
+		return withPrefix(subcommands, current)
+	case subcommand == "completion" && len(previous) == 1:
+		names := make([]string, 0, len(completionScripts))
+		for shell := range completionScripts {
+			names = append(names, shell)
+		}
+		sort.Strings(names)
+		return withPrefix(names, current)
+	case subcommand == "config" && len(previous) == 1:
+		return withPrefix([]string{"init"}, current)
+	case subcommand == "generate" && len(previous) == 1:
+		return withPrefix([]string{"manifests"}, current)
+	case subcommand == "history" && len(previous) == 1:
+		return withPrefix([]string{"export"}, current)
+	}
+	return nil
+}
+
+// flagValues returns the candidate values of the flag word: the namespaces
+// of the cluster for --namespace, and the deployments of the namespace
+// given on the command line, or of the current context, for --deployment
+func flagValues(ctx context.Context, lister nameLister, word, prefix string, words []string) []string {
+	var names []string
+	var err error
+	switch strings.TrimLeft(word, "-") {
+	case "namespace":
+		names, err = lister.Names(ctx, "namespaces", "")
+	case "deployment":
+		names, err = lister.Names(ctx, "deployments", namespaceArg(words))
+	}
+	if err != nil {
+		return nil
+	}
+	return withPrefix(names, prefix)
+}
+
+// namespaceArg returns the value of the --namespace flag among words
+func namespaceArg(words []string) string {
+	for i, word := range words {
+		name := strings.TrimLeft(word, "-")
+		switch {
+		case !strings.HasPrefix(word, "-"):
+		case strings.HasPrefix(name, "namespace="):
+			return strings.TrimPrefix(name, "namespace=")
+		case name == "namespace" && i+1 < len(words):
+			if words[i+1] == "=" && i+2 < len(words) {
+				return words[i+2]
+			}
+			return words[i+1]
+		}
+	}
+	return ""
+}
+
+// takesValue reports whether word is a flag whose value is the next word
+func takesValue(flags *flag.FlagSet, word string) bool {
+	if !strings.HasPrefix(word, "-") || strings.Contains(word, "=") {
+		return false
+	}
+	f := flags.Lookup(strings.TrimLeft(word, "-"))
+	if f == nil {
+		return false
+	}
+	if b, ok := f.Value.(interface{ IsBoolFlag() bool }); ok && b.IsBoolFlag() {
+		return false
+	}
+	return true
+}
+
+func withPrefix(candidates []string, prefix string) []string {
+	var matching []string
+	for _, c := range candidates {
+		if strings.HasPrefix(c, prefix) {
+			matching = append(matching, c)
+		}
+	}
+	return matching
+}
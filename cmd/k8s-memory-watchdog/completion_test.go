@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"reflect"
+	"testing"
+)
+
+// fakeNames lists names by "resource/namespace", the namespace being empty
+// for the current context
+type fakeNames map[string][]string
+
+func (f fakeNames) Names(ctx context.Context, resource, namespace string) ([]string, error) {
+	return f[resource+"/"+namespace], nil
+}
+
+func TestComplete(t *testing.T) {
+	lister := fakeNames{
+		"namespaces/":         {"prod", "staging"},
+		"deployments/":        {"web"},
+		"deployments/prod":    {"api", "api-v2", "worker"},
+		"deployments/staging": {"api"},
+	}
+	flags := flag.NewFlagSet("watchdog", flag.ContinueOnError)
+	flags.String("namespace", "default", "")
+	flags.String("deployment", "", "")
+	flags.Bool("once", false, "")
+
+	tests := []struct {
+		words []string
+		want  []string
+	}{
+		{words: nil, want: subcommands},
+		{words: []string{"no"}, want: []string{"notify-test"}},
+		{words: []string{"--"}, want: []string{"--deployment", "--namespace", "--once"}},
+		{words: []string{"-na"}, want: []string{"--namespace"}},
+		{words: []string{"--namespace", ""}, want: []string{"prod", "staging"}},
+		{words: []string{"--namespace", "p"}, want: []string{"prod"}},
+		{words: []string{"--namespace=s"}, want: []string{"--namespace=staging"}},
+		{words: []string{"--namespace", "=", "s"}, want: []string{"staging"}},
+		{words: []string{"--namespace", "prod", "--deployment", "api"}, want: []string{"api", "api-v2"}},
+		{words: []string{"--namespace=prod", "--deployment", "="}, want: []string{"api", "api-v2", "worker"}},
+		{words: []string{"--deployment", ""}, want: []string{"web"}},
+		{words: []string{"--once", ""}, want: nil},
+		{words: []string{"notify-test", "--dep"}, want: []string{"--deployment"}},
+		{words: []string{"history", "--"}, want: nil},
+		{words: []string{"completion", ""}, want: []string{"bash", "fish", "zsh"}},
+		{words: []string{"config", ""}, want: []string{"init"}},
+		{words: []string{"history", "e"}, want: []string{"export"}},
+	}
+	for _, tt := range tests {
+		got := complete(context.Background(), lister, flags, tt.words)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q) = %q, want %q", tt.words, got, tt.want)
+		}
+	}
+}
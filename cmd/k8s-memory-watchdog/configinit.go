@@ -0,0 +1,24 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+
+	k8smemorywatchdog "github.com/renancavalcantercb/k8s-memory-watchdog"
+)
+
+// runConfig implements the config subcommand. config init prints the
+// annotated example configuration, covering every setting, to start a
+// configuration file from.
+func runConfig(args []string) int {
+	if len(args) != 1 || args[0] != "init" {
+		fmt.Fprintln(os.Stderr, "usage: k8s-memory-watchdog config init > config.yaml")
+		return 2
+	}
+	if _, err := io.WriteString(os.Stdout, k8smemorywatchdog.ExampleConfig); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+	return 0
+}
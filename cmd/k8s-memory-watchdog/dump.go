@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"runtime/pprof"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// statusSource lists the state of the targets
+type statusSource interface {
+	Status() []watchdog.TargetStatus
+}
+
+// metricsWriter writes the watchdog's own metrics as text
+type metricsWriter interface {
+	WriteText(w io.Writer)
+}
+
+// writeDump writes the state of the watchdog for debugging a process that
+// seems stuck: its targets and their last results, its metrics, runtime
+// statistics and the stacks of every goroutine
+func writeDump(w io.Writer, status statusSource, metrics metricsWriter, now time.Time) {
+	fmt.Fprintf(w, "=== k8s-memory-watchdog state dump at %s ===\n", now.Format(time.RFC3339))
+
+	fmt.Fprintln(w, "\n--- targets ---")
+	for _, s := range status.Status() {
+		fmt.Fprintf(w, "%s: namespace=%s deployment=%s threshold=%dMi interval=%s paused=%t",
+			s.Target.Name, s.Target.Namespace, s.Target.DeploymentName, s.Target.MemoryThreshold, s.Target.CheckInterval, s.Paused)
+		if s.BreachedSince != nil {
+			fmt.Fprintf(w, " breached_since=%s", s.BreachedSince.Format(time.RFC3339))
+		}
+		fmt.Fprintln(w)
+		if s.LastResult == nil {
+			fmt.Fprintln(w, "  last check: none")
+			continue
+		}
+		last := s.LastResult
+		fmt.Fprintf(w, "  last check: %s (%s ago, took %s) memory=%dMi threshold=%dMi breached=%t",
+			last.Time.Format(time.RFC3339), now.Sub(last.Time).Round(time.Second), last.Duration, last.Memory, last.Threshold, last.Breached)
+		if last.Action != "" {
+			fmt.Fprintf(w, " action=%s", last.Action)
+		}
+		if last.Err != nil {
+			fmt.Fprintf(w, " error=%q", last.Err.Error())
+		}
+		fmt.Fprintln(w)
+	}
+
+	fmt.Fprintln(w, "\n--- metrics ---")
+	metrics.WriteText(w)
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	fmt.Fprintln(w, "\n--- runtime ---")
+	fmt.Fprintf(w, "goroutines: %d\nheap in use: %dKi\nsys: %dKi\ngc cycles: %d\n",
+		runtime.NumGoroutine(), mem.HeapInuse/1024, mem.Sys/1024, mem.NumGC)
+
+	fmt.Fprintln(w, "\n--- goroutines ---")
+	pprof.Lookup("goroutine").WriteTo(w, 2)
+	fmt.Fprintln(w, "=== end of state dump ===")
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+type staticStatus []watchdog.TargetStatus
+
+func (s staticStatus) Status() []watchdog.TargetStatus {
+	return s
+}
+
+type staticMetrics string
+
+func (m staticMetrics) WriteText(w io.Writer) {
+	io.WriteString(w, string(m))
+}
+
+func TestWriteDump(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	status := staticStatus{
+		{
+			Target: watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api", MemoryThreshold: 2000, CheckInterval: time.Minute},
+			LastResult: &watchdog.CheckResult{
+				Time: now.Add(-90 * time.Second), Memory: 3000, Threshold: 2000, Breached: true,
+				Err: errors.New("error restarting deployment: forbidden"),
+			},
+		},
+		{Target: watchdog.Target{Name: "prod/worker"}, Paused: true},
+	}
+
+	var buf bytes.Buffer
+	writeDump(&buf, status, staticMetrics("k8s_memory_watchdog_checks_total{target=\"prod/api\"} 12\n"), now)
+	dump := buf.String()
+
+	for _, expected := range []string{
+		"state dump at 2024-03-01T12:00:00Z",
+		"prod/api: namespace=prod deployment=api threshold=2000Mi interval=1m0s paused=false",
+		`last check: 2024-03-01T11:58:30Z (1m30s ago, took 0s) memory=3000Mi threshold=2000Mi breached=true error="error restarting deployment: forbidden"`,
+		"prod/worker: namespace= deployment= threshold=0Mi interval=0s paused=true\n  last check: none",
+		`k8s_memory_watchdog_checks_total{target="prod/api"} 12`,
+		"goroutines: ",
+		"goroutine ",
+		"TestWriteDump",
+		"=== end of state dump ===",
+	} {
+		if !strings.Contains(dump, expected) {
+			t.Errorf("dump is missing %q:\n%s", expected, dump)
+		}
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// runHistory implements the history subcommand and returns the process exit
+// code. history prints the breaches, actions and failed checks as a table
+// for reconstructing an incident, history export every record for other
+// tools.
+func runHistory(args []string) int {
+	name, defaultFormat := "history", "table"
+	if len(args) > 0 && args[0] == "export" {
+		name, defaultFormat, args = "history export", "csv", args[1:]
+	} else if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		fmt.Fprintln(os.Stderr, "usage: k8s-memory-watchdog history [--target api] [--since 24h] [--format table|json|csv] [--all]\n       k8s-memory-watchdog history export [--target api] [--since 7d] [--format csv|json]")
+		return 2
+	}
+
+	fs := flag.NewFlagSet(name, flag.ExitOnError)
+	configFile := fs.String("config", getEnv("CONFIG_FILE", ""), "Path to YAML configuration file")
+	stateFile := fs.String("state-file", getEnv("STATE_FILE", ""), "File the history is read from")
+	format := fs.String("format", defaultFormat, "Output format, table, csv or json")
+	since := fs.String("since", "", "Only show records newer than a duration (e.g. 12h, 7d) or an RFC 3339 time")
+	target := fs.String("target", "", "Only show the records of a target, by name or deployment")
+	all := name == "history export"
+	if !all {
+		fs.BoolVar(&all, "all", false, "Also show the checks without a breach, action or error")
+	}
+	fs.Parse(args)
+
+	config, err := loadStateConfig(*configFile, *stateFile)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+
+	start, err := parseSince(*since, time.Now())
+	if err != nil {
+		log.Printf("Invalid --since: %v", err)
+		return 1
+	}
+	records, err := watchdog.NewFileStateStore(config.StateFile).List(context.Background(), start)
+	if err != nil {
+		log.Printf("Error reading history: %v", err)
+		return 1
+	}
+	records = filterHistory(records, *target, all)
+	if err := exportHistory(os.Stdout, records, *format); err != nil {
+		log.Printf("Error exporting history: %v", err)
+		return 1
+	}
+	return 0
+}
+
+// loadStateConfig loads the configuration of the subcommands reading the
+// history, with the state file overridden by stateFile when set
+func loadStateConfig(configFile, stateFile string) (watchdog.Config, error) {
+	var config watchdog.Config
+	if configFile != "" {
+		var err error
+		if config, err = watchdog.LoadConfigFile(configFile, config); err != nil {
+			return config, fmt.Errorf("Error loading config file: %v", err)
+		}
+	}
+	if stateFile != "" {
+		config.StateFile = stateFile
+	}
+	if config.StateFile == "" {
+		return config, errors.New("No state file configured. Use --state-file, set STATE_FILE or configure state_file in the config file.")
+	}
+	return config, nil
+}
+
+// parseSince returns the start of the export window. It accepts a duration,
+// which may use a "d" suffix for days, or an RFC 3339 time. An empty value
+// exports everything.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if strings.HasSuffix(value, "d") {
+		days, err := strconv.Atoi(strings.TrimSuffix(value, "d"))
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid number of days %q", value)
+		}
+		return now.AddDate(0, 0, -days), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return now.Add(-d), nil
+}
+
+// filterHistory returns the records of target, matched by its name or by
+// its deployment, or of every target when it is empty. Unless all is set,
+// only the checks with a breach, action or error are kept.
+func filterHistory(records []watchdog.Record, target string, all bool) []watchdog.Record {
+	var filtered []watchdog.Record
+	for _, r := range records {
+		if target != "" && r.Target != target && !strings.HasSuffix(r.Target, "/"+target) {
+			continue
+		}
+		if !all && !r.Breached && r.Action == "" && r.Error == "" {
+			continue
+		}
+		filtered = append(filtered, r)
+	}
+	return filtered
+}
+
+// recordEvent names what happened in a record, for the table
+func recordEvent(r watchdog.Record) string {
+	switch {
+	case r.Action != "":
+		return r.Action
+	case r.Error != "":
+		return "check failed"
+	case r.Breached:
+		return "breach"
+	default:
+		return "check"
+	}
+}
+
+// exportHistory writes records to w as a table, as CSV, with a header row,
+// or as a JSON array
+func exportHistory(w io.Writer, records []watchdog.Record, format string) error {
+	switch format {
+	case "table":
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "TIME\tTARGET\tUSAGE\tTHRESHOLD\tEVENT\tDETAILS")
+		for _, r := range records {
+			usage := "-"
+			if r.Memory > 0 {
+				usage = fmt.Sprintf("%dMi", r.Memory)
+			}
+			details := r.Error
+			if details == "" && r.ActionID != "" {
+				details = "action " + r.ActionID
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%dMi\t%s\t%s\n",
+				r.Time.Format(time.RFC3339), r.Target, usage, r.Threshold, recordEvent(r), details)
+		}
+		return tw.Flush()
+	case "json":
+		if records == nil {
+			records = []watchdog.Record{}
+		}
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(records)
+	case "csv":
+		cw := csv.NewWriter(w)
+		cw.Write([]string{"time", "target", "namespace", "memory", "threshold", "breached", "action", "error", "check_id", "action_id"})
+		for _, r := range records {
+			cw.Write([]string{
+				r.Time.Format(time.RFC3339),
+				r.Target,
+				r.Namespace,
+				strconv.Itoa(r.Memory),
+				strconv.Itoa(r.Threshold),
+				strconv.FormatBool(r.Breached),
+				r.Action,
+				r.Error,
+				r.CheckID,
+				r.ActionID,
+			})
+		}
+		cw.Flush()
+		return cw.Error()
+	default:
+		return fmt.Errorf("unknown format %q, want table, csv or json", format)
+	}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestParseSince(t *testing.T) {
+	now := time.Date(2024, 1, 10, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		value    string
+		expected time.Time
+		wantErr  bool
+	}{
+		{value: "", expected: time.Time{}},
+		{value: "7d", expected: time.Date(2024, 1, 3, 12, 0, 0, 0, time.UTC)},
+		{value: "12h", expected: time.Date(2024, 1, 10, 0, 0, 0, 0, time.UTC)},
+		{value: "2024-01-01T00:00:00Z", expected: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{value: "xd", wantErr: true},
+		{value: "yesterday", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			result, err := parseSince(tt.value, now)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseSince() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !result.Equal(tt.expected) {
+				t.Errorf("parseSince() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExportHistory(t *testing.T) {
+	records := []watchdog.Record{{
+		Time:      time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		Target:    "default/app",
+		Namespace: "default",
+		Memory:    6000,
+		Threshold: 5000,
+		Breached:  true,
+		Action:    "restart",
+		CheckID:   "3f2a9c1e5b7d4a60",
+		ActionID:  "9b1d0e7c2a4f6358",
+	}}
+
+	var buf bytes.Buffer
+	if err := exportHistory(&buf, records, "csv"); err != nil {
+		t.Fatalf("exportHistory(csv) error = %v", err)
+	}
+	want := "time,target,namespace,memory,threshold,breached,action,error,check_id,action_id\n" +
+		"2024-01-01T00:00:00Z,default/app,default,6000,5000,true,restart,,3f2a9c1e5b7d4a60,9b1d0e7c2a4f6358\n"
+	if buf.String() != want {
+		t.Errorf("exportHistory(csv) = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	if err := exportHistory(&buf, nil, "json"); err != nil || buf.String() != "[]\n" {
+		t.Errorf("exportHistory(json) of no records = %q, %v, want []", buf.String(), err)
+	}
+
+	buf.Reset()
+	if err := exportHistory(&buf, records, "table"); err != nil {
+		t.Fatalf("exportHistory(table) error = %v", err)
+	}
+	want = "TIME                  TARGET       USAGE   THRESHOLD  EVENT    DETAILS\n" +
+		"2024-01-01T00:00:00Z  default/app  6000Mi  5000Mi     restart  action 9b1d0e7c2a4f6358\n"
+	if buf.String() != want {
+		t.Errorf("exportHistory(table) = %q, want %q", buf.String(), want)
+	}
+
+	if err := exportHistory(&buf, records, "xml"); err == nil {
+		t.Error("exportHistory(xml) expected an error")
+	}
+}
+
+func TestFilterHistory(t *testing.T) {
+	records := []watchdog.Record{
+		{Target: "prod/api", Memory: 1500},
+		{Target: "prod/api", Memory: 2500, Breached: true, Action: "restart"},
+		{Target: "prod/worker", Error: "metrics unavailable"},
+		{Target: "staging/api", Memory: 2500, Breached: true},
+		{Target: "payments", Memory: 900},
+	}
+	tests := []struct {
+		name   string
+		target string
+		all    bool
+		want   []int
+	}{
+		{name: "events", want: []int{1, 2, 3}},
+		{name: "all", all: true, want: []int{0, 1, 2, 3, 4}},
+		{name: "deployment", target: "api", want: []int{1, 3}},
+		{name: "name", target: "prod/api", all: true, want: []int{0, 1}},
+		{name: "custom name", target: "payments", all: true, want: []int{4}},
+		{name: "unknown", target: "missing", all: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var want []watchdog.Record
+			for _, i := range tt.want {
+				want = append(want, records[i])
+			}
+			if got := filterHistory(records, tt.target, tt.all); !reflect.DeepEqual(got, want) {
+				t.Errorf("filterHistory(%q, %t) = %+v, want %+v", tt.target, tt.all, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/logfile"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// logger is the leveled logger of the watchdog's components, writing to
+// the standard logger until setupLogging configures its outputs
+var logger = logging.New(log.Default(), logging.Levels{Default: logging.LevelInfo})
+
+// closers closes every log file and syslog connection of the outputs
+type closers []io.Closer
+
+func (c closers) Close() error {
+	for _, closer := range c {
+		closer.Close()
+	}
+	return nil
+}
+
+// setupLogging configures the standard logger and the outputs of logger,
+// and returns what must be closed on exit. Without sinks, logs are written
+// as text to stdout, or to the log file. Verbose logging adds the source
+// file of messages and enables the debug level unless another level than
+// info is configured.
+func setupLogging(verbose bool, config watchdog.LogConfig) (closers, error) {
+	levels, err := parseLogLevels(verbose, config.Level)
+	if err != nil {
+		return nil, err
+	}
+	flags := 0
+	if verbose {
+		flags = log.Ldate | log.Ltime | log.Lshortfile
+	}
+	log.SetFlags(flags)
+	log.SetOutput(os.Stdout)
+
+	if len(config.Sinks) > 0 {
+		return setupSinks(verbose, config)
+	}
+	if config.File == "" {
+		logger.SetOutputs(logging.Output{Sink: logging.NewTextSink(log.Default()), Levels: levels})
+		return nil, nil
+	}
+	f, err := openLogFile(config.File, config)
+	if err != nil {
+		return nil, err
+	}
+	// a file has no timestamps of its own, unlike a log collector
+	log.SetFlags(flags | log.Ldate | log.Ltime)
+	log.SetOutput(f)
+	logger.SetOutputs(logging.Output{Sink: logging.NewTextSink(log.Default()), Levels: levels})
+	return closers{f}, nil
+}
+
+// setupSinks sets every sink of config as an output of logger
+func setupSinks(verbose bool, config watchdog.LogConfig) (closers, error) {
+	var outputs []logging.Output
+	var opened closers
+	for i, sink := range config.Sinks {
+		spec := sink.Level
+		if spec == "" {
+			spec = config.Level
+		}
+		levels, err := parseLogLevels(verbose, spec)
+		if err != nil {
+			opened.Close()
+			return nil, fmt.Errorf("log sink %d: %v", i+1, err)
+		}
+		s, closer, err := newLogSink(verbose, sink, config)
+		if err != nil {
+			opened.Close()
+			return nil, fmt.Errorf("log sink %d: %v", i+1, err)
+		}
+		if closer != nil {
+			opened = append(opened, closer)
+		}
+		outputs = append(outputs, logging.Output{Sink: s, Levels: levels})
+	}
+	logger.SetOutputs(outputs...)
+	return opened, nil
+}
+
+// newLogSink creates the sink of a configuration and what must be closed
+// on exit
+func newLogSink(verbose bool, sink watchdog.LogSinkConfig, config watchdog.LogConfig) (logging.Sink, io.Closer, error) {
+	if sink.Format == "syslog" {
+		s, err := logging.NewSyslogSink(sink.Address, "k8s-memory-watchdog")
+		if err != nil {
+			return nil, nil, fmt.Errorf("Error connecting to syslog: %v", err)
+		}
+		return s, s, nil
+	}
+
+	var w io.Writer
+	var closer io.Closer
+	flags := 0
+	switch sink.Output {
+	case "", "stdout":
+		w = os.Stdout
+	case "stderr":
+		w = os.Stderr
+	default:
+		f, err := openLogFile(sink.Output, config)
+		if err != nil {
+			return nil, nil, err
+		}
+		w, closer = f, f
+		flags = log.Ldate | log.Ltime
+	}
+	if verbose {
+		flags |= log.Ldate | log.Ltime | log.Lshortfile
+	}
+
+	switch sink.Format {
+	case "", "text":
+		return logging.NewTextSink(log.New(w, "", flags)), closer, nil
+	case "json":
+		return logging.NewJSONSink(w), closer, nil
+	default:
+		if closer != nil {
+			closer.Close()
+		}
+		return nil, nil, fmt.Errorf("unknown log format '%s', want text, json or syslog", sink.Format)
+	}
+}
+
+// parseLogLevels parses a level spec, defaulting to debug when verbose
+func parseLogLevels(verbose bool, spec string) (logging.Levels, error) {
+	levels, err := logging.ParseLevels(spec)
+	if err != nil {
+		return levels, err
+	}
+	if verbose && (spec == "" || spec == "info") {
+		levels.Default = logging.LevelDebug
+	}
+	return levels, nil
+}
+
+// openLogFile opens a log file rotated as configured
+func openLogFile(path string, config watchdog.LogConfig) (*logfile.File, error) {
+	f, err := logfile.Open(path, logfile.Options{
+		MaxSize:    int64(config.MaxSize) << 20,
+		MaxAge:     config.MaxAge,
+		MaxBackups: config.MaxBackups,
+		Compress:   config.Compress,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("Error opening log file: %v", err)
+	}
+	return f, nil
+}
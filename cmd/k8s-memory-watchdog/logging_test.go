@@ -0,0 +1,67 @@
+package main
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestSetupLoggingSinks(t *testing.T) {
+	defer func() {
+		log.SetOutput(os.Stderr)
+		log.SetFlags(log.LstdFlags)
+		logger.SetOutputs(logging.Output{Sink: logging.NewTextSink(log.Default()), Levels: logging.Levels{Default: logging.LevelInfo}})
+	}()
+
+	dir := t.TempDir()
+	textPath := filepath.Join(dir, "watchdog.log")
+	jsonPath := filepath.Join(dir, "watchdog.json")
+	outputs, err := setupLogging(false, watchdog.LogConfig{
+		Level: "info",
+		Sinks: []watchdog.LogSinkConfig{
+			{Format: "text", Output: textPath, Level: "debug"},
+			{Format: "json", Output: jsonPath},
+		},
+	})
+	if err != nil {
+		t.Fatalf("setupLogging() error = %v", err)
+	}
+
+	logger.Component("watchdog").Debugf("usage 1000Mi")
+	logger.Component("watchdog").Infof("restarted")
+	outputs.Close()
+
+	text, _ := os.ReadFile(textPath)
+	if !strings.Contains(string(text), "DEBUG watchdog: usage 1000Mi") || !strings.Contains(string(text), "INFO watchdog: restarted") {
+		t.Errorf("text sink = %q, want both messages", text)
+	}
+	structured, _ := os.ReadFile(jsonPath)
+	if lines := strings.Split(strings.TrimSpace(string(structured)), "\n"); len(lines) != 1 || !strings.Contains(lines[0], `"msg":"restarted"`) {
+		t.Errorf("json sink = %q, want the info message only", structured)
+	}
+}
+
+func TestSetupLoggingErrors(t *testing.T) {
+	defer log.SetOutput(os.Stderr)
+
+	tests := []struct {
+		name   string
+		config watchdog.LogConfig
+	}{
+		{name: "unknown level", config: watchdog.LogConfig{Level: "loud"}},
+		{name: "unknown sink level", config: watchdog.LogConfig{Sinks: []watchdog.LogSinkConfig{{Level: "loud"}}}},
+		{name: "unknown format", config: watchdog.LogConfig{Sinks: []watchdog.LogSinkConfig{{Format: "xml"}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := setupLogging(false, tt.config); err == nil {
+				t.Error("setupLogging() error = nil, want an error")
+			}
+		})
+	}
+}
@@ -0,0 +1,1325 @@
+// Command k8s-memory-watchdog monitors the memory usage of Kubernetes
+// deployments and restarts them when a threshold is exceeded.
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/httpauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/actions"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/adminapi"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/calendar"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/dashboard"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/grpcapi"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/metrics"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/notify"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/replay"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// options holds the watchdog configuration together with the settings that
+// only affect how the command runs
+type options struct {
+	watchdog.Config
+
+	// Once checks every target a single time and exits
+	Once bool
+	// Output is the format of the results of Once, text or json
+	Output string
+	// ResultConfigMap is the ConfigMap the JSON results of Once are
+	// stored in
+	ResultConfigMap string
+	// Replay is a file of recorded samples to simulate instead of running
+	Replay string
+	// Record is a file every memory sample read is appended to
+	Record string
+	// EventsOut is a file, or "-" for stdout, every event is written to as
+	// newline-delimited JSON
+	EventsOut string
+	// RBACCheck verifies the Kubernetes permissions of the watchdog at
+	// startup
+	RBACCheck bool
+	// TargetValidation is what happens at startup to targets whose
+	// namespace or deployment doesn't exist: off, warn or fail
+	TargetValidation string
+	// DumpFile is the file the state is appended to on SIGQUIT, instead of
+	// stderr
+	DumpFile string
+}
+
+func main() {
+	if isPlugin(os.Args[0]) {
+		args, err := pluginArgs(os.Args[1:])
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(2)
+		}
+		os.Args = append(os.Args[:1:1], args...)
+	}
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "history":
+			os.Exit(runHistory(os.Args[2:]))
+		case "tui":
+			os.Exit(runTUI(os.Args[2:]))
+		case "silence", "unsilence":
+			os.Exit(runSilence(os.Args[1], os.Args[2:]))
+		case "pause", "resume":
+			os.Exit(runPause(os.Args[1], os.Args[2:]))
+		case "status":
+			os.Exit(runStatus(os.Args[2:]))
+		case "trigger":
+			os.Exit(runTrigger(os.Args[2:]))
+		case "notify-test":
+			os.Exit(runNotifyTest(os.Args[2:]))
+		case "validate":
+			os.Exit(runValidate(os.Args[2:]))
+		case "config":
+			os.Exit(runConfig(os.Args[2:]))
+		case "generate":
+			os.Exit(runGenerate(os.Args[2:]))
+		case "completion":
+			os.Exit(runCompletion(os.Args[2:]))
+		case "__complete":
+			os.Exit(runComplete(os.Args[2:]))
+		}
+	}
+
+	config := parseFlags()
+	logOutputs, err := setupLogging(config.Verbose, config.Log)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer logOutputs.Close()
+	if log.Writer() == os.Stdout && ((config.Once && config.Output == "json") || config.EventsOut == "-") {
+		// keep stdout for the result document or event stream
+		log.SetOutput(os.Stderr)
+	}
+
+	if len(config.ResolveTargets()) == 0 {
+		log.Fatal("Deployment name is required. Use --deployment flag, set DEPLOYMENT environment variable or configure targets in the config file.")
+	}
+
+	location, err := watchdog.LoadLocation(config.Timezone)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if config.Replay != "" {
+		os.Exit(runReplay(config.Replay, config.Config))
+	}
+
+	// the metric sources and notifiers send their requests with the
+	// default transport
+	transport, err := outboundTransport(config.Proxy)
+	if err != nil {
+		log.Fatal(err)
+	}
+	http.DefaultTransport = transport
+
+	collector := telemetry.NewTelemetry()
+	runner, err := newRunner(config.Config)
+	if err != nil {
+		log.Fatal(err)
+	}
+	runner.SetLogger(logger.Component("kubectl"))
+	collector.RegisterFunc(telemetry.MetricThrottledRequests, "counter", "Total number of Kubernetes API requests delayed by rate limiting",
+		func() float64 { return float64(runner.Throttled()) })
+	if config.StatsD.Address != "" {
+		sink, err := telemetry.NewStatsD(config.StatsD)
+		if err != nil {
+			log.Fatalf("Error connecting to StatsD: %v", err)
+		}
+		defer sink.Close()
+		collector.AddSink(sink)
+	}
+
+	secretsCtx, cancelSecrets := context.WithTimeout(context.Background(), time.Minute)
+	err = setupSecrets(secretsCtx, runner, config.Config)
+	cancelSecrets()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if config.RBACCheck {
+		checkCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		err := checkPermissions(checkCtx, runner, config)
+		cancel()
+		if err != nil {
+			log.Fatal(err)
+		}
+	}
+	validateCtx, cancelValidate := context.WithTimeout(context.Background(), time.Minute)
+	err = validateTargets(validateCtx, runner, config.ResolveTargets(), config.TargetValidation)
+	cancelValidate()
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	kubectlRestarter := actions.NewKubectlRestarter(runner)
+	var restarter watchdog.Restarter = kubectlRestarter
+	var recordFile *os.File
+	if config.Record != "" {
+		f, err := os.OpenFile(config.Record, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+		if err != nil {
+			log.Fatalf("Error opening record file: %v", err)
+		}
+		defer f.Close()
+		recordFile = f
+	}
+	// wrap records and caches the readings of a metrics source
+	wrap := func(provider watchdog.MetricsProvider) watchdog.MetricsProvider {
+		if recordFile != nil {
+			recorder := replay.NewRecorder(provider, recordFile)
+			recorder.SetLogger(logger.Component("recorder"))
+			provider = recorder
+		}
+		if config.MetricsCacheTTL > 0 {
+			cache := metrics.NewCachingProvider(provider, config.MetricsCacheTTL)
+			provider, restarter = cache, cache.InvalidatingRestarter(restarter)
+		}
+		return provider
+	}
+
+	provider, err := newMetricsSource(config.Source.Type, config.Config, runner)
+	if err != nil {
+		log.Fatal(err)
+	}
+	provider = wrap(provider)
+	opts := []watchdog.Option{
+		watchdog.WithTelemetry(collector),
+		watchdog.WithLogging(logger),
+		watchdog.WithReplicaCounter(runner),
+		watchdog.WithPodStarts(runner),
+		watchdog.WithPodOwners(runner),
+		watchdog.WithColorResolver(runner),
+		watchdog.WithAvailability(runner),
+		watchdog.WithRolloutStatus(runner),
+		watchdog.WithRolloutUndoer(kubectlRestarter),
+		watchdog.WithTenantPolicies(runner),
+	}
+	for _, name := range sourceChainNames(config.ResolveTargets()) {
+		source, err := newMetricsSource(name, config.Config, runner)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, watchdog.WithSource(name, wrap(source)))
+	}
+	if swap, throttling := signalThresholds(config.ResolveTargets()); swap || throttling {
+		signals, err := newSignalSource(config.Config)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if swap {
+			opts = append(opts, watchdog.WithSwapSource(signals))
+		}
+		if throttling {
+			opts = append(opts, watchdog.WithThrottlingSource(signals))
+		}
+	}
+	var store watchdog.StateStore
+	if config.StateFile != "" {
+		store = watchdog.NewFileStateStore(config.StateFile)
+	} else if config.Dashboard.Enabled {
+		// the dashboard needs some history even without a state file
+		store = watchdog.NewMemoryStateStore(10000)
+	}
+	if store != nil {
+		opts = append(opts, watchdog.WithStateStore(store))
+	}
+	if config.Freeze.Calendar != "" {
+		freeze := calendar.NewICal(config.Freeze.Calendar, config.Freeze.Refresh, location)
+		freeze.SetLogger(logger.Component("freeze"))
+		opts = append(opts, watchdog.WithFreeze(freeze))
+	}
+	channels, closeChannels, err := newChannels(config.Config, config.EventsOut)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer closeChannels.Close()
+	for _, c := range channels {
+		opts = append(opts, c.option())
+	}
+	if alertmanager := config.Notifications.Alertmanager; alertmanager.Silences {
+		opts = append(opts, watchdog.WithHold(notify.NewAlertmanagerSilences(alertmanager.URLs, alertmanager.Labels)))
+	}
+	if config.Freeze.HolidayCountry != "" || len(config.Freeze.Holidays) > 0 {
+		holidays, err := calendar.NewHolidays(config.Freeze.HolidayCountry, config.Freeze.Holidays, location)
+		if err != nil {
+			log.Fatal(err)
+		}
+		opts = append(opts, watchdog.WithFreeze(holidays))
+	}
+	grpcEvents := grpcapi.NewEvents()
+	if config.GRPC.Enabled {
+		if config.GRPC.TLS.CertFile == "" || config.GRPC.TLS.KeyFile == "" {
+			log.Fatal("The gRPC API requires a TLS certificate and key. Use --grpc-tls-cert and --grpc-tls-key or configure grpc.tls in the config file.")
+		}
+		opts = append(opts, watchdog.WithEventStream(grpcEvents))
+	}
+	w := watchdog.NewWatchdog(provider, restarter, config.Config, opts...)
+	registerSelfMetrics(collector, w)
+
+	// Setup context with cancellation
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if config.Once {
+		if config.Output == "json" {
+			os.Exit(runOnceJSON(ctx, w, os.Stdout, runner, config))
+		}
+		os.Exit(runOnce(ctx, w))
+	}
+
+	if config.Metrics.Enabled || config.Dashboard.Enabled || config.Admin.Enabled {
+		if (config.HTTP.TLS.CertFile == "") != (config.HTTP.TLS.KeyFile == "") {
+			log.Fatal("TLS requires both a certificate and a key. Use --http-tls-cert and --http-tls-key or configure http.tls in the config file.")
+		}
+		mux := http.NewServeMux()
+		mux.HandleFunc("/healthz", func(w http.ResponseWriter, r *http.Request) {
+			w.Write([]byte("ok\n"))
+		})
+		mux.Handle("/readyz", readyHandler(w))
+		if config.Metrics.Enabled {
+			mux.Handle(config.Metrics.Path, collector)
+			logger.Infof("Serving metrics on :%d%s", config.Metrics.Port, config.Metrics.Path)
+		}
+		if config.Dashboard.Enabled {
+			path := strings.TrimSuffix(config.Dashboard.Path, "/")
+			mux.Handle(path+"/", http.StripPrefix(path, dashboard.New(w, store)))
+			logger.Infof("Serving dashboard on :%d%s/", config.Metrics.Port, path)
+		}
+		if config.Admin.Enabled {
+			api := adminapi.New(w, config.Admin.RestartToken)
+			mux.Handle("/check/", api)
+			mux.Handle("/restart/", api)
+			mux.Handle("/silence/", api)
+			mux.Handle("/unsilence/", api)
+			mux.Handle("/pause/", api)
+			mux.Handle("/resume/", api)
+			mux.Handle("/status", api)
+			mux.Handle("/fleet", api)
+			logger.Infof("Serving admin API on :%d", config.Metrics.Port)
+		}
+		tlsConfig, err := serverTLSConfig(config.HTTP.TLS)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go serveAdmin(ctx, config.Metrics.Port, newAdminHandler(mux, config.Config), config.HTTP.TLS, tlsConfig)
+	}
+
+	if config.GRPC.Enabled {
+		tlsConfig, err := serverTLSConfig(config.GRPC.TLS)
+		if err != nil {
+			log.Fatal(err)
+		}
+		go serveGRPC(ctx, config.GRPC, grpcapi.NewServer(w, grpcEvents, config.Admin.RestartToken), tlsConfig)
+	}
+
+	// Setup graceful shutdown
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		<-sigChan
+		logger.Infof("Received shutdown signal. Shutting down...")
+		cancel()
+	}()
+
+	// SIGQUIT dumps the state instead of exiting with a stack trace
+	quitChan := make(chan os.Signal, 1)
+	signal.Notify(quitChan, syscall.SIGQUIT)
+	go func() {
+		for range quitChan {
+			dumpState(config.DumpFile, w, collector)
+		}
+	}()
+
+	go notifySystemd(ctx, w)
+	go newSelfMonitor(w, config.SelfMonitor.ExitOnStall).run(ctx)
+
+	if err := w.Run(ctx); err != nil && err != context.Canceled {
+		log.Fatalf("Error during execution: %v", err)
+	}
+}
+
+// dumpState appends the state dump to path, or writes it to stderr
+func dumpState(path string, w *watchdog.Watchdog, collector *telemetry.Telemetry) {
+	if path == "" {
+		writeDump(os.Stderr, w, collector, time.Now())
+		return
+	}
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		logger.Errorf("Error opening dump file: %v", err)
+		return
+	}
+	defer f.Close()
+	writeDump(f, w, collector, time.Now())
+	logger.Infof("State dumped to %s", path)
+}
+
+// runOnce checks every target a single time, logs the results and returns
+// the process exit code
+func runOnce(ctx context.Context, w *watchdog.Watchdog) int {
+	code := 0
+	for _, result := range w.CheckOnce(ctx) {
+		switch {
+		case result.Err != nil:
+			code = 1
+			log.Printf("%s: error: %v", result.Target.Name, result.Err)
+		case result.Breached:
+			log.Printf("%s: %dMi >= %dMi, action: %s", result.Target.Name, result.Memory, result.Threshold, result.Action)
+		default:
+			log.Printf("%s: %dMi < %dMi, ok", result.Target.Name, result.Memory, result.Threshold)
+		}
+	}
+	return code
+}
+
+// runReplay simulates the checks recorded in path against the configured
+// thresholds, logs the restarts that would have fired and returns the process
+// exit code
+func runReplay(path string, config watchdog.Config) int {
+	f, err := os.Open(path)
+	if err != nil {
+		log.Printf("Error opening recording: %v", err)
+		return 1
+	}
+	defer f.Close()
+
+	samples, err := replay.ReadSamples(f)
+	if err != nil {
+		log.Printf("Error reading recording %s: %v", path, err)
+		return 1
+	}
+	results, err := replay.Simulate(context.Background(), config, samples)
+	if err != nil {
+		log.Printf("Error replaying recording: %v", err)
+		return 1
+	}
+
+	restarts := 0
+	for _, result := range results {
+		if result.Breached {
+			restarts++
+			log.Printf("%s %s: %dMi >= %dMi, would restart", result.Time.Format(time.RFC3339),
+				result.Target.Name, result.Memory, result.Threshold)
+		}
+	}
+	log.Printf("Replayed %d checks, %d restarts would have fired", len(results), restarts)
+	return 0
+}
+
+// newAdminHandler guards the admin mux with the bearer tokens of the
+// configuration, when there are any
+func newAdminHandler(mux http.Handler, config watchdog.Config) http.Handler {
+	var tokens []httpauth.Token
+	if config.HTTP.BearerToken != "" {
+		tokens = append(tokens, httpauth.SecretToken(config.HTTP.BearerToken))
+	}
+	if config.HTTP.BearerTokenFile != "" {
+		tokens = append(tokens, httpauth.NewFileToken(config.HTTP.BearerTokenFile))
+	}
+	if len(tokens) == 0 {
+		return mux
+	}
+	if config.Admin.RestartToken != "" {
+		tokens = append(tokens, httpauth.SecretToken(config.Admin.RestartToken))
+	}
+	return httpauth.NewBearer(mux, config.HTTP.AuthExempt, tokens...)
+}
+
+// serveAdmin serves the metrics endpoint, dashboard and admin API until ctx
+// is done, over TLS when a certificate is configured. tlsConfig optionally
+// requires client certificates.
+func serveAdmin(ctx context.Context, port int, handler http.Handler, certs watchdog.TLSConfig, tlsConfig *tls.Config) {
+	server := &http.Server{Addr: fmt.Sprintf(":%d", port), Handler: handler, TLSConfig: tlsConfig}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	var err error
+	if certs.CertFile != "" {
+		err = server.ListenAndServeTLS(certs.CertFile, certs.KeyFile)
+	} else {
+		err = server.ListenAndServe()
+	}
+	if err != nil && err != http.ErrServerClosed {
+		logger.Errorf("Error serving admin endpoints: %v", err)
+	}
+}
+
+// serveGRPC serves the gRPC API over TLS until ctx is done. tlsConfig
+// optionally requires client certificates.
+func serveGRPC(ctx context.Context, config watchdog.GRPCConfig, handler http.Handler, tlsConfig *tls.Config) {
+	server := &http.Server{Addr: fmt.Sprintf(":%d", config.Port), Handler: handler, TLSConfig: tlsConfig}
+
+	go func() {
+		<-ctx.Done()
+		server.Close()
+	}()
+
+	logger.Infof("Serving gRPC API on :%d", config.Port)
+	if err := server.ListenAndServeTLS(config.TLS.CertFile, config.TLS.KeyFile); err != nil && err != http.ErrServerClosed {
+		logger.Errorf("Error serving gRPC API: %v", err)
+	}
+}
+
+func parseFlags() options {
+	build := defineFlags()
+	flag.Parse()
+	return build()
+}
+
+// defineFlags declares the flags of the watchdog on the command line flag
+// set and returns the function building the options from them once parsed
+func defineFlags() func() options {
+	configFile := flag.String("config", getEnv("CONFIG_FILE", ""), "Path to YAML configuration file")
+	once := flag.Bool("once", false, "Check every target once and exit")
+	eventsOut := flag.String("events-out", getEnv("EVENTS_OUT", ""),
+		"File, or - for stdout, every event is written to as newline-delimited JSON")
+	rbacCheck := flag.Bool("rbac-check", getEnvBool("RBAC_CHECK", true), "Verify the Kubernetes permissions of the watchdog at startup")
+	targetValidation := flag.String("target-validation", getEnv("TARGET_VALIDATION", "warn"),
+		"What happens at startup to targets whose namespace or deployment doesn't exist: off, warn or fail")
+	dumpFile := flag.String("dump-file", getEnv("DUMP_FILE", ""), "File the state is appended to on SIGQUIT (default: stderr)")
+	output := flag.String("output", getEnv("OUTPUT", "text"), "Format of the --once results: text or json")
+	resultConfigMap := flag.String("result-configmap", getEnv("RESULT_CONFIGMAP", ""),
+		"ConfigMap ([namespace/]name) the JSON --once results are stored in")
+	replayFile := flag.String("replay", "", "Simulate the checks recorded in a samples file and exit")
+	recordFile := flag.String("record", getEnv("RECORD_FILE", ""), "Append every memory sample read to a samples file")
+	checkInterval := flag.Duration("interval", getEnvDuration("CHECK_INTERVAL", 5*time.Minute),
+		"Check interval")
+	namespace := flag.String("namespace", getEnv("NAMESPACE", "default"), "Kubernetes namespace")
+	deploymentName := flag.String("deployment", getEnv("DEPLOYMENT", ""), "Deployment name to restart")
+	memoryThreshold := flag.Int("threshold", getEnvInt("MEMORY_THRESHOLD", 5000),
+		"Memory threshold in Mi")
+	replicaMemory := flag.Int("memory-per-replica", getEnvInt("MEMORY_PER_REPLICA", 0),
+		"Threshold of the average memory usage per ready replica in Mi, replacing --threshold so it follows the replica count (0 disables)")
+	baseMemory := flag.Int("memory-base", getEnvInt("MEMORY_BASE", 0),
+		"Memory in Mi added to --memory-per-replica times the ready replicas, for the usage that doesn't scale with them")
+	podThreshold := flag.Int("pod-threshold", getEnvInt("POD_THRESHOLD", 0),
+		"Memory threshold in Mi of the largest pod of the deployment, checked alongside --threshold (0 disables)")
+	swapThreshold := flag.Int("swap-threshold", getEnvInt("SWAP_THRESHOLD", 0),
+		"Swap usage threshold in Mi of the namespace, read from Prometheus on swap-enabled nodes and checked alongside --threshold (0 disables)")
+	cpuThrottling := flag.Int("cpu-throttling", getEnvInt("CPU_THROTTLING", 0),
+		"Percentage of throttled CPU periods of the namespace over five minutes, read from Prometheus and checked alongside --threshold (0 disables)")
+	warmUp := flag.Duration("warm-up", getEnvDuration("WARM_UP", 0),
+		"Age under which pods are ignored, so their startup spike doesn't trip the watchdog right after a restart (0 disables)")
+	scope := flag.String("scope", getEnv("SCOPE", watchdog.ScopeNamespace),
+		"Memory usage compared with the threshold, summed over the namespace or the deployment, or of each pod of the deployment (namespace, deployment or pod)")
+	batchPods := flag.String("batch-pods", getEnv("BATCH_PODS", watchdog.BatchPodsExclude),
+		"Whether the pods of Jobs and CronJobs count toward the namespace scope, exclude or include")
+	aggregation := flag.String("aggregation", getEnv("AGGREGATION", ""),
+		"Function applied across the pods in scope, sum, max, mean, median or a percentile like p90 (default sum, or max for the pod scope)")
+	recoveryPercent := flag.Float64("recovery-percent", getEnvFloat("RECOVERY_PERCENT", 90),
+		"Usage in percent of the threshold under which a breach is resolved")
+	kubectlPath := flag.String("kubectl", getEnv("KUBECTL_PATH", "/usr/local/bin/kubectl"),
+		"Path to kubectl binary")
+	verbose := flag.Bool("verbose", false, "Enable verbose logging (deprecated, use --log-level=debug)")
+	logLevel := flag.String("log-level", getEnv("LOG_LEVEL", "info"), "Log level, error, warn, info, debug or trace, with per-component overrides such as info,metrics=debug")
+	logFile := flag.String("log-file", getEnv("LOG_FILE", ""), "File the logs are written to instead of stdout, rotated by size and age")
+	logMaxSize := flag.Int("log-max-size", getEnvInt("LOG_MAX_SIZE", 100), "Size in megabytes after which the log file is rotated (0 disables)")
+	logMaxAge := flag.Duration("log-max-age", getEnvDuration("LOG_MAX_AGE", 24*time.Hour), "Age after which the log file is rotated (0 disables)")
+	logMaxBackups := flag.Int("log-max-backups", getEnvInt("LOG_MAX_BACKUPS", 7), "Number of rotated log files kept (0 keeps all)")
+	logCompress := flag.Bool("log-compress", getEnvBool("LOG_COMPRESS", true), "Compress rotated log files with gzip")
+	metricsTimeout := flag.Duration("metrics-timeout", getEnvDuration("METRICS_TIMEOUT", 30*time.Second),
+		"Timeout for collecting pod metrics (0 disables the timeout)")
+	restartTimeout := flag.Duration("restart-timeout", getEnvDuration("RESTART_TIMEOUT", 2*time.Minute),
+		"Timeout for restarting a deployment (0 disables the timeout)")
+	restartStrategy := flag.String("restart-strategy", getEnv("RESTART_STRATEGY", watchdog.RestartRollout),
+		"How deployments are restarted, a rolling restart, scaling them to zero and back, or deleting their pods one at a time (rollout, scale or delete)")
+	gracePeriod := flag.Duration("grace-period", getEnvDuration("GRACE_PERIOD", 0),
+		"Grace period given to each pod deleted by --restart-strategy=delete, in whole seconds (0 keeps the pod's own)")
+	deleteDelay := flag.Duration("delete-delay", getEnvDuration("DELETE_DELAY", 0),
+		"Delay between the pods deleted by --restart-strategy=delete")
+	rollback := flag.Bool("rollback", getEnvBool("ROLLBACK", false),
+		"Wait for rolling restarts to roll out within --restart-timeout, and undo the ones that fail with a critical notification")
+	surgeReplicas := flag.Int("surge-replicas", getEnvInt("SURGE_REPLICAS", 0),
+		"Replicas added and made ready before a restart, and removed once it has rolled out, to keep serving capacity (0 disables)")
+	colorService := flag.String("color-service", getEnv("COLOR_SERVICE", ""),
+		"Service whose selector picks the active deployment of a blue/green pair, the only one checked and restarted")
+	colorLabel := flag.String("color-label", getEnv("COLOR_LABEL", "color"),
+		"Label telling apart the colors of a blue/green pair in the selector of --color-service")
+	availableGuard := flag.Bool("available-guard", getEnvBool("AVAILABLE_GUARD", false),
+		"Escalate instead of restarting a deployment that doesn't have all its desired replicas available")
+	kubeQPS := flag.Float64("kube-qps", getEnvFloat("KUBE_QPS", 5),
+		"Maximum Kubernetes API requests per second (0 disables rate limiting)")
+	kubeBurst := flag.Int("kube-burst", getEnvInt("KUBE_BURST", 10), "Maximum burst of Kubernetes API requests")
+	kubeContext := flag.String("kube-context", getEnv("KUBE_CONTEXT", ""), "kubeconfig context kubectl uses (default: the current context)")
+	secretRefresh := flag.Duration("secret-refresh", getEnvDuration("SECRET_REFRESH", time.Minute),
+		"How often credentials referring to a Kubernetes Secret are read again")
+	metricsEnabled := flag.Bool("metrics", getEnvBool("METRICS_ENABLED", false), "Enable the Prometheus metrics endpoint")
+	metricsPort := flag.Int("metrics-port", getEnvInt("METRICS_PORT", 9090), "Port of the Prometheus metrics endpoint")
+	cloudEventsURL := flag.String("cloudevents-url", getEnv("CLOUDEVENTS_URL", ""), "HTTP endpoint events are posted to as CloudEvents")
+	cloudEventsSource := flag.String("cloudevents-source", getEnv("CLOUDEVENTS_SOURCE", notify.DefaultCloudEventSource), "Source attribute of the CloudEvents")
+	natsURL := flag.String("nats-url", getEnv("NATS_URL", ""), "NATS server events are published to, e.g. nats://nats:4222")
+	natsSubject := flag.String("nats-subject", getEnv("NATS_SUBJECT", notify.DefaultNATSSubject), "Subject template of the NATS events")
+	kafkaBrokers := flag.String("kafka-brokers", getEnv("KAFKA_BROKERS", ""), "Comma-separated Kafka brokers events are produced to")
+	kafkaEventsTopic := flag.String("kafka-events-topic", getEnv("KAFKA_EVENTS_TOPIC", "k8s-memory-watchdog.events"), "Kafka topic of the events")
+	kafkaSamplesTopic := flag.String("kafka-samples-topic", getEnv("KAFKA_SAMPLES_TOPIC", ""), "Kafka topic of the memory samples of every check (empty disables)")
+	kafkaTLS := flag.Bool("kafka-tls", getEnvBool("KAFKA_TLS", false), "Connect to the Kafka brokers over TLS")
+	snsTopicARN := flag.String("sns-topic-arn", getEnv("SNS_TOPIC_ARN", ""), "Amazon SNS topic events are published to")
+	alertmanagerURLs := flag.String("alertmanager-urls", getEnv("ALERTMANAGER_URLS", ""), "Comma-separated Prometheus Alertmanager instances events are sent to as alerts, e.g. http://alertmanager:9093")
+	alertmanagerSilences := flag.Bool("alertmanager-silences", getEnvBool("ALERTMANAGER_SILENCES", false), "Hold back the restarts of targets whose breach alert is silenced in Alertmanager")
+	subjectTemplate := flag.String("notification-subject-template", getEnv("NOTIFICATION_SUBJECT_TEMPLATE", ""), "Go template of the subject of notifications (default: \""+notify.DefaultSubjectTemplate+"\")")
+	bodyTemplate := flag.String("notification-body-template", getEnv("NOTIFICATION_BODY_TEMPLATE", ""), "Go template of the body of notifications, replacing the JSON event of SNS messages")
+	historyURL := flag.String("history-url", getEnv("HISTORY_URL", ""), "Go template of a link to a target's memory history added to notifications, e.g. a Grafana dashboard")
+	runbookURL := flag.String("runbook-url", getEnv("RUNBOOK_URL", ""), "Go template of a link to the runbook added to notifications of targets without their own")
+	stallIntervals := flag.Int("stall-intervals", getEnvInt("STALL_INTERVALS", 2), "Check intervals after which a target's checks are considered stalled, on top of the metrics and restart timeouts")
+	exitOnStall := flag.Bool("exit-on-stall", getEnvBool("EXIT_ON_STALL", false), "Exit when a target's checks are stalled, so that the watchdog is restarted")
+	heartbeatURL := flag.String("heartbeat-url", getEnv("HEARTBEAT_URL", ""), "URL pinged after successful checks, e.g. a healthchecks.io check")
+	heartbeatInterval := flag.Duration("heartbeat-interval", getEnvDuration("HEARTBEAT_INTERVAL", time.Minute), "Minimum time between two heartbeat pings")
+	grpcEnabled := flag.Bool("grpc", getEnvBool("GRPC_ENABLED", false), "Serve the gRPC control and status API")
+	grpcPort := flag.Int("grpc-port", getEnvInt("GRPC_PORT", 9091), "Port of the gRPC API")
+	grpcCert := flag.String("grpc-tls-cert", getEnv("GRPC_TLS_CERT_FILE", ""), "PEM certificate file of the gRPC API")
+	grpcKey := flag.String("grpc-tls-key", getEnv("GRPC_TLS_KEY_FILE", ""), "PEM private key file of the gRPC API")
+	httpCert := flag.String("http-tls-cert", getEnv("HTTP_TLS_CERT_FILE", ""), "PEM certificate file of the metrics, dashboard and admin API server")
+	httpKey := flag.String("http-tls-key", getEnv("HTTP_TLS_KEY_FILE", ""), "PEM private key file of the metrics, dashboard and admin API server")
+	httpClientCA := flag.String("http-tls-client-ca", getEnv("HTTP_TLS_CLIENT_CA_FILE", ""), "PEM CA bundle the client certificates of the metrics, dashboard and admin API server must be signed by")
+	grpcClientCA := flag.String("grpc-tls-client-ca", getEnv("GRPC_TLS_CLIENT_CA_FILE", ""), "PEM CA bundle the client certificates of the gRPC API must be signed by")
+	httpTokenFile := flag.String("http-bearer-token-file", getEnv("HTTP_BEARER_TOKEN_FILE", ""), "File of the bearer token required by the metrics, dashboard and admin API server")
+	adminEnabled := flag.Bool("admin-api", getEnvBool("ADMIN_API_ENABLED", false), "Serve the admin API on the port of the metrics endpoint")
+	dashboardEnabled := flag.Bool("dashboard", getEnvBool("DASHBOARD_ENABLED", false),
+		"Serve a read-only web dashboard on the port of the metrics endpoint")
+	dashboardPath := flag.String("dashboard-path", getEnv("DASHBOARD_PATH", "/dashboard"), "Path of the web dashboard")
+	metricsPath := flag.String("metrics-path", getEnv("METRICS_PATH", "/metrics"), "Path of the Prometheus metrics endpoint")
+	stateFile := flag.String("state-file", getEnv("STATE_FILE", ""), "File the history of checks and restarts is appended to")
+	outlierWindow := flag.Int("outlier-window", getEnvInt("OUTLIER_WINDOW", 0),
+		"Number of recent readings outliers are detected against (0 disables outlier rejection)")
+	outlierK := flag.Float64("outlier-k", getEnvFloat("OUTLIER_K", 3.5),
+		"How many deviations from the median of recent readings make a reading an outlier")
+	circuitFailures := flag.Int("circuit-failures", getEnvInt("CIRCUIT_FAILURES", 0),
+		"Failed restarts in a row after which the restarts of a target are held back (0 disables the circuit breaker)")
+	circuitProbe := flag.Duration("circuit-probe", getEnvDuration("CIRCUIT_PROBE", 10*time.Minute),
+		"How often a restart is let through to probe an open circuit")
+	restartBudget := flag.Int("restart-budget", getEnvInt("RESTART_BUDGET", 0),
+		"Restarts across all targets within the budget window after which the watchdog only notifies (0 disables the budget)")
+	budgetWindow := flag.Duration("restart-budget-window", getEnvDuration("RESTART_BUDGET_WINDOW", 10*time.Minute),
+		"Window the restart budget is counted over")
+	tenantNamespaces := flag.String("tenant-namespaces", getEnv("TENANT_NAMESPACES", ""),
+		"Comma-separated namespaces whose tenants define their own targets in a ConfigMap")
+	tenantConfigMap := flag.String("tenant-configmap", getEnv("TENANT_CONFIGMAP", "memory-watchdog"),
+		"ConfigMap of each tenant namespace listing its targets under the targets key")
+	tenantRefresh := flag.Duration("tenant-refresh", getEnvDuration("TENANT_REFRESH", time.Minute),
+		"How often the ConfigMaps of the tenants are read again")
+	proxyURL := flag.String("proxy-url", getEnv("PROXY_URL", ""),
+		"Proxy of the requests to metric sources, notifiers and the Kubernetes API (default: the proxy of the environment)")
+	noProxy := flag.String("no-proxy", getEnv("NO_PROXY", ""), "Comma-separated hosts, domains and CIDRs reached without the proxy")
+	caFile := flag.String("ca-file", getEnv("CA_FILE", ""), "PEM bundle of extra CAs trusted by the requests to metric sources and notifiers")
+	kubeCAFile := flag.String("kube-ca-file", getEnv("KUBE_CA_FILE", ""),
+		"PEM bundle the Kubernetes API is verified with instead of the kubeconfig's")
+	eksCluster := flag.String("eks-cluster", getEnv("EKS_CLUSTER", ""),
+		"Name of the EKS cluster kubectl authenticates to with IAM tokens from the AWS credentials of the environment")
+	eksRegion := flag.String("eks-region", getEnv("EKS_REGION", ""), "Region of the STS endpoint of the EKS tokens (default: AWS_REGION)")
+	gkeAuth := flag.Bool("gke-auth", getEnvBool("GKE_AUTH", false),
+		"Authenticate kubectl to a GKE cluster with the Application Default Credentials, such as Workload Identity")
+	aksAuth := flag.Bool("aks-auth", getEnvBool("AKS_AUTH", false),
+		"Authenticate kubectl to an AKS cluster with Azure AD tokens of a service principal, workload identity or managed identity")
+	oidcIssuerURL := flag.String("oidc-issuer-url", getEnv("OIDC_ISSUER_URL", ""),
+		"Issuer of the OIDC provider whose ID tokens kubectl authenticates with, refreshed before they expire")
+	oidcClientID := flag.String("oidc-client-id", getEnv("OIDC_CLIENT_ID", ""), "Client ID of the watchdog at the OIDC provider")
+	oidcClientSecret := flag.String("oidc-client-secret", getEnv("OIDC_CLIENT_SECRET", ""), "Client secret of the watchdog at the OIDC provider, if any")
+	oidcRefreshToken := flag.String("oidc-refresh-token", getEnv("OIDC_REFRESH_TOKEN", ""), "Refresh token the OIDC ID tokens are obtained with")
+	baselinePercent := flag.Float64("baseline-percent", getEnvFloat("BASELINE_PERCENT", 0),
+		"Restart when usage is this many percent above the same time last week (0 disables the baseline trigger)")
+	baselineWeeks := flag.Int("baseline-weeks", getEnvInt("BASELINE_WEEKS", 1), "Number of past weeks the baseline is averaged over")
+	timezone := flag.String("timezone", getEnv("TIMEZONE", ""),
+		"IANA time zone of schedules and freeze calendars, e.g. Europe/Berlin (default: the system time zone)")
+	freezeCalendar := flag.String("freeze-calendar", getEnv("FREEZE_CALENDAR", ""),
+		"URL or path of an iCalendar file of change freezes during which restarts are suppressed")
+	holidayCountry := flag.String("holiday-country", getEnv("HOLIDAY_COUNTRY", ""),
+		"Country code (US, BR, GB or DE) whose public holidays suppress restarts")
+	statsdAddress := flag.String("statsd-address", getEnv("STATSD_ADDRESS", ""), "Address of a StatsD agent metrics are pushed to (e.g. localhost:8125)")
+	statsdPrefix := flag.String("statsd-prefix", getEnv("STATSD_PREFIX", ""), "Prefix of the metric names pushed to StatsD")
+	dogstatsd := flag.Bool("dogstatsd", getEnvBool("DOGSTATSD", false), "Push labels as DogStatsD tags")
+	metricsSource := flag.String("metrics-source", getEnv("METRICS_SOURCE", "kubectl"),
+		"Where memory usage is read from: kubectl, datadog, cloudwatch, prometheus, newrelic, custom, external or scrape")
+	metricsCacheTTL := flag.Duration("metrics-cache-ttl", getEnvDuration("METRICS_CACHE_TTL", 10*time.Second),
+		"How long pod metrics are shared between targets in the same namespace (0 disables caching)")
+
+	return func() options {
+		config := watchdog.Config{
+			Namespace:       *namespace,
+			DeploymentName:  *deploymentName,
+			MemoryThreshold: *memoryThreshold,
+			ReplicaMemory:   *replicaMemory,
+			BaseMemory:      *baseMemory,
+			PodThreshold:    *podThreshold,
+			SwapThreshold:   *swapThreshold,
+			CPUThrottling:   *cpuThrottling,
+			WarmUp:          *warmUp,
+			Scope:           *scope,
+			BatchPods:       *batchPods,
+			Aggregation:     *aggregation,
+			RecoveryPercent: *recoveryPercent,
+			KubectlPath:     *kubectlPath,
+			KubeContext:     *kubeContext,
+			Verbose:         *verbose,
+			Log: watchdog.LogConfig{
+				Level:      *logLevel,
+				File:       *logFile,
+				MaxSize:    *logMaxSize,
+				MaxAge:     *logMaxAge,
+				MaxBackups: *logMaxBackups,
+				Compress:   *logCompress,
+			},
+			CheckInterval:   *checkInterval,
+			MetricsCacheTTL: *metricsCacheTTL,
+			MetricsTimeout:  *metricsTimeout,
+			RestartTimeout:  *restartTimeout,
+			RestartStrategy: *restartStrategy,
+			GracePeriod:     *gracePeriod,
+			DeleteDelay:     *deleteDelay,
+			SurgeReplicas:   *surgeReplicas,
+			Rollback:        *rollback,
+			ColorService:    *colorService,
+			ColorLabel:      *colorLabel,
+			AvailableGuard:  *availableGuard,
+			KubeQPS:         *kubeQPS,
+			KubeBurst:       *kubeBurst,
+			SecretRefresh:   *secretRefresh,
+			StateFile:       *stateFile,
+			Timezone:        *timezone,
+			Outliers: watchdog.OutlierConfig{
+				Window: *outlierWindow,
+				K:      *outlierK,
+				Method: "mad",
+			},
+			Circuit: watchdog.CircuitConfig{
+				Failures: *circuitFailures,
+				Probe:    *circuitProbe,
+			},
+			Budget: watchdog.BudgetConfig{
+				Restarts: *restartBudget,
+				Window:   *budgetWindow,
+			},
+			Tenants: watchdog.TenantConfig{
+				Namespaces: splitList(*tenantNamespaces),
+				ConfigMap:  *tenantConfigMap,
+				Refresh:    *tenantRefresh,
+			},
+			Proxy: watchdog.ProxyConfig{
+				URL:        *proxyURL,
+				NoProxy:    *noProxy,
+				CAFile:     *caFile,
+				KubeCAFile: *kubeCAFile,
+			},
+			EKS: watchdog.EKSConfig{
+				Cluster: *eksCluster,
+				Region:  *eksRegion,
+			},
+			GKE: watchdog.GKEConfig{
+				Enabled: *gkeAuth,
+			},
+			AKS: watchdog.AKSConfig{
+				Enabled: *aksAuth,
+			},
+			OIDC: watchdog.OIDCConfig{
+				IssuerURL:    *oidcIssuerURL,
+				ClientID:     *oidcClientID,
+				ClientSecret: *oidcClientSecret,
+				RefreshToken: *oidcRefreshToken,
+			},
+			Freeze: watchdog.FreezeConfig{
+				Calendar:       *freezeCalendar,
+				Refresh:        time.Hour,
+				HolidayCountry: *holidayCountry,
+			},
+			Baseline: watchdog.BaselineConfig{
+				Percent:   *baselinePercent,
+				Weeks:     *baselineWeeks,
+				Tolerance: 15 * time.Minute,
+			},
+			Metrics: telemetry.Config{
+				Enabled: *metricsEnabled,
+				Port:    *metricsPort,
+				Path:    *metricsPath,
+			},
+			Notifications: watchdog.NotificationsConfig{
+				CloudEvents: watchdog.CloudEventsConfig{
+					URL:    *cloudEventsURL,
+					Source: *cloudEventsSource,
+				},
+				NATS: watchdog.NATSConfig{
+					URL:     *natsURL,
+					Subject: *natsSubject,
+					Token:   getEnv("NATS_TOKEN", ""),
+				},
+				SNS: watchdog.SNSConfig{
+					TopicARN: *snsTopicARN,
+				},
+				Alertmanager: watchdog.AlertmanagerConfig{
+					URLs:     splitList(*alertmanagerURLs),
+					Silences: *alertmanagerSilences,
+				},
+				Template: watchdog.NotificationTemplateConfig{
+					Subject:    *subjectTemplate,
+					Body:       *bodyTemplate,
+					HistoryURL: *historyURL,
+					RunbookURL: *runbookURL,
+				},
+				Kafka: watchdog.KafkaConfig{
+					Brokers:      splitList(*kafkaBrokers),
+					EventsTopic:  *kafkaEventsTopic,
+					SamplesTopic: *kafkaSamplesTopic,
+					TLS:          *kafkaTLS,
+					Username:     getEnv("KAFKA_USERNAME", ""),
+					Password:     getEnv("KAFKA_PASSWORD", ""),
+				},
+			},
+			Heartbeat: watchdog.HeartbeatConfig{
+				URL:      *heartbeatURL,
+				Interval: *heartbeatInterval,
+			},
+			SelfMonitor: watchdog.SelfMonitorConfig{
+				StallIntervals: *stallIntervals,
+				ExitOnStall:    *exitOnStall,
+			},
+			GRPC: watchdog.GRPCConfig{
+				Enabled: *grpcEnabled,
+				Port:    *grpcPort,
+				TLS: watchdog.TLSConfig{
+					CertFile:     *grpcCert,
+					KeyFile:      *grpcKey,
+					ClientCAFile: *grpcClientCA,
+				},
+			},
+			HTTP: watchdog.HTTPConfig{
+				TLS: watchdog.TLSConfig{
+					CertFile:     *httpCert,
+					KeyFile:      *httpKey,
+					ClientCAFile: *httpClientCA,
+				},
+				BearerToken:     getEnv("HTTP_BEARER_TOKEN", ""),
+				BearerTokenFile: *httpTokenFile,
+				AuthExempt:      []string{"/healthz", "/readyz"},
+			},
+			Admin: watchdog.AdminConfig{
+				Enabled:      *adminEnabled,
+				RestartToken: getEnv("ADMIN_RESTART_TOKEN", ""),
+			},
+			Dashboard: watchdog.DashboardConfig{
+				Enabled: *dashboardEnabled,
+				Path:    *dashboardPath,
+			},
+			Source: watchdog.SourceConfig{
+				Type: *metricsSource,
+				Datadog: watchdog.DatadogConfig{
+					Site:   getEnv("DD_SITE", ""),
+					APIKey: getEnv("DD_API_KEY", ""),
+					AppKey: getEnv("DD_APP_KEY", ""),
+				},
+				CloudWatch: watchdog.CloudWatchConfig{
+					ClusterName: getEnv("CLOUDWATCH_CLUSTER_NAME", ""),
+				},
+				Prometheus: watchdog.PrometheusConfig{
+					URL:         getEnv("PROMETHEUS_URL", ""),
+					Auth:        getEnv("PROMETHEUS_AUTH", ""),
+					BearerToken: getEnv("PROMETHEUS_BEARER_TOKEN", ""),
+				},
+				NewRelic: watchdog.NewRelicConfig{
+					APIKey:    getEnv("NEW_RELIC_API_KEY", ""),
+					AccountID: getEnvInt("NEW_RELIC_ACCOUNT_ID", 0),
+					Region:    getEnv("NEW_RELIC_REGION", ""),
+				},
+				Kubectl: watchdog.KubectlConfig{
+					InitContainers: getEnv("KUBECTL_INIT_CONTAINERS", ""),
+				},
+				Custom: watchdog.CustomMetricsConfig{
+					Metric: getEnv("CUSTOM_METRIC", ""),
+				},
+				External: watchdog.ExternalMetricsConfig{
+					Metric: getEnv("EXTERNAL_METRIC", ""),
+				},
+				Scrape: watchdog.ScrapeConfig{
+					Port:   getEnvInt("SCRAPE_PORT", 0),
+					Path:   getEnv("SCRAPE_PATH", ""),
+					Metric: getEnv("SCRAPE_METRIC", ""),
+				},
+			},
+			StatsD: telemetry.StatsDConfig{
+				Address:   *statsdAddress,
+				Prefix:    *statsdPrefix,
+				DogStatsD: *dogstatsd,
+			},
+		}
+
+		if *configFile != "" {
+			fileConfig, err := watchdog.LoadConfigFile(*configFile, config)
+			if err != nil {
+				log.Fatalf("Error loading config file: %v", err)
+			}
+			config = mergeConfig(fileConfig, config)
+		}
+
+		return options{
+			Config:           config,
+			Once:             *once,
+			Output:           *output,
+			ResultConfigMap:  *resultConfigMap,
+			Replay:           *replayFile,
+			Record:           *recordFile,
+			EventsOut:        *eventsOut,
+			RBACCheck:        *rbacCheck,
+			TargetValidation: *targetValidation,
+			DumpFile:         *dumpFile,
+		}
+	}
+}
+
+// mergeConfig applies explicitly set flags and environment variables on top
+// of the file configuration, so they take precedence over the file
+func mergeConfig(file, flags watchdog.Config) watchdog.Config {
+	set := make(map[string]bool)
+	flag.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	overridden := func(name, env string) bool {
+		if _, ok := os.LookupEnv(env); ok {
+			return true
+		}
+		return set[name]
+	}
+
+	merged := file
+	if overridden("namespace", "NAMESPACE") {
+		merged.Namespace = flags.Namespace
+	}
+	if overridden("deployment", "DEPLOYMENT") {
+		merged.DeploymentName = flags.DeploymentName
+	}
+	if overridden("threshold", "MEMORY_THRESHOLD") {
+		merged.MemoryThreshold = flags.MemoryThreshold
+	}
+	if overridden("memory-per-replica", "MEMORY_PER_REPLICA") {
+		merged.ReplicaMemory = flags.ReplicaMemory
+	}
+	if overridden("memory-base", "MEMORY_BASE") {
+		merged.BaseMemory = flags.BaseMemory
+	}
+	if overridden("pod-threshold", "POD_THRESHOLD") {
+		merged.PodThreshold = flags.PodThreshold
+	}
+	if overridden("swap-threshold", "SWAP_THRESHOLD") {
+		merged.SwapThreshold = flags.SwapThreshold
+	}
+	if overridden("cpu-throttling", "CPU_THROTTLING") {
+		merged.CPUThrottling = flags.CPUThrottling
+	}
+	if overridden("warm-up", "WARM_UP") {
+		merged.WarmUp = flags.WarmUp
+	}
+	if overridden("scope", "SCOPE") {
+		merged.Scope = flags.Scope
+	}
+	if overridden("batch-pods", "BATCH_PODS") {
+		merged.BatchPods = flags.BatchPods
+	}
+	if overridden("aggregation", "AGGREGATION") {
+		merged.Aggregation = flags.Aggregation
+	}
+	if overridden("recovery-percent", "RECOVERY_PERCENT") {
+		merged.RecoveryPercent = flags.RecoveryPercent
+	}
+	if overridden("kubectl", "KUBECTL_PATH") {
+		merged.KubectlPath = flags.KubectlPath
+	}
+	if overridden("interval", "CHECK_INTERVAL") {
+		merged.CheckInterval = flags.CheckInterval
+	}
+	if overridden("metrics-cache-ttl", "METRICS_CACHE_TTL") {
+		merged.MetricsCacheTTL = flags.MetricsCacheTTL
+	}
+	if overridden("metrics-timeout", "METRICS_TIMEOUT") {
+		merged.MetricsTimeout = flags.MetricsTimeout
+	}
+	if overridden("restart-timeout", "RESTART_TIMEOUT") {
+		merged.RestartTimeout = flags.RestartTimeout
+	}
+	if overridden("restart-strategy", "RESTART_STRATEGY") {
+		merged.RestartStrategy = flags.RestartStrategy
+	}
+	if overridden("grace-period", "GRACE_PERIOD") {
+		merged.GracePeriod = flags.GracePeriod
+	}
+	if overridden("delete-delay", "DELETE_DELAY") {
+		merged.DeleteDelay = flags.DeleteDelay
+	}
+	if overridden("rollback", "ROLLBACK") {
+		merged.Rollback = flags.Rollback
+	}
+	if overridden("surge-replicas", "SURGE_REPLICAS") {
+		merged.SurgeReplicas = flags.SurgeReplicas
+	}
+	if overridden("color-service", "COLOR_SERVICE") {
+		merged.ColorService = flags.ColorService
+	}
+	if overridden("color-label", "COLOR_LABEL") {
+		merged.ColorLabel = flags.ColorLabel
+	}
+	if overridden("available-guard", "AVAILABLE_GUARD") {
+		merged.AvailableGuard = flags.AvailableGuard
+	}
+	if overridden("kube-qps", "KUBE_QPS") {
+		merged.KubeQPS = flags.KubeQPS
+	}
+	if overridden("kube-burst", "KUBE_BURST") {
+		merged.KubeBurst = flags.KubeBurst
+	}
+	if overridden("kube-context", "KUBE_CONTEXT") {
+		merged.KubeContext = flags.KubeContext
+	}
+	if overridden("secret-refresh", "SECRET_REFRESH") {
+		merged.SecretRefresh = flags.SecretRefresh
+	}
+	if overridden("state-file", "STATE_FILE") {
+		merged.StateFile = flags.StateFile
+	}
+	if overridden("outlier-window", "OUTLIER_WINDOW") {
+		merged.Outliers.Window = flags.Outliers.Window
+	}
+	if overridden("outlier-k", "OUTLIER_K") {
+		merged.Outliers.K = flags.Outliers.K
+	}
+	if overridden("circuit-failures", "CIRCUIT_FAILURES") {
+		merged.Circuit.Failures = flags.Circuit.Failures
+	}
+	if overridden("circuit-probe", "CIRCUIT_PROBE") {
+		merged.Circuit.Probe = flags.Circuit.Probe
+	}
+	if overridden("restart-budget", "RESTART_BUDGET") {
+		merged.Budget.Restarts = flags.Budget.Restarts
+	}
+	if overridden("restart-budget-window", "RESTART_BUDGET_WINDOW") {
+		merged.Budget.Window = flags.Budget.Window
+	}
+	if overridden("tenant-namespaces", "TENANT_NAMESPACES") {
+		merged.Tenants.Namespaces = flags.Tenants.Namespaces
+	}
+	if overridden("tenant-configmap", "TENANT_CONFIGMAP") {
+		merged.Tenants.ConfigMap = flags.Tenants.ConfigMap
+	}
+	if overridden("tenant-refresh", "TENANT_REFRESH") {
+		merged.Tenants.Refresh = flags.Tenants.Refresh
+	}
+	if overridden("proxy-url", "PROXY_URL") {
+		merged.Proxy.URL = flags.Proxy.URL
+	}
+	if overridden("no-proxy", "NO_PROXY") {
+		merged.Proxy.NoProxy = flags.Proxy.NoProxy
+	}
+	if overridden("ca-file", "CA_FILE") {
+		merged.Proxy.CAFile = flags.Proxy.CAFile
+	}
+	if overridden("kube-ca-file", "KUBE_CA_FILE") {
+		merged.Proxy.KubeCAFile = flags.Proxy.KubeCAFile
+	}
+	if overridden("eks-cluster", "EKS_CLUSTER") {
+		merged.EKS.Cluster = flags.EKS.Cluster
+	}
+	if overridden("eks-region", "EKS_REGION") {
+		merged.EKS.Region = flags.EKS.Region
+	}
+	if overridden("gke-auth", "GKE_AUTH") {
+		merged.GKE.Enabled = flags.GKE.Enabled
+	}
+	if overridden("aks-auth", "AKS_AUTH") {
+		merged.AKS.Enabled = flags.AKS.Enabled
+	}
+	if overridden("oidc-issuer-url", "OIDC_ISSUER_URL") {
+		merged.OIDC.IssuerURL = flags.OIDC.IssuerURL
+	}
+	if overridden("oidc-client-id", "OIDC_CLIENT_ID") {
+		merged.OIDC.ClientID = flags.OIDC.ClientID
+	}
+	if overridden("oidc-client-secret", "OIDC_CLIENT_SECRET") {
+		merged.OIDC.ClientSecret = flags.OIDC.ClientSecret
+	}
+	if overridden("oidc-refresh-token", "OIDC_REFRESH_TOKEN") {
+		merged.OIDC.RefreshToken = flags.OIDC.RefreshToken
+	}
+	if overridden("baseline-percent", "BASELINE_PERCENT") {
+		merged.Baseline.Percent = flags.Baseline.Percent
+	}
+	if overridden("baseline-weeks", "BASELINE_WEEKS") {
+		merged.Baseline.Weeks = flags.Baseline.Weeks
+	}
+	if overridden("timezone", "TIMEZONE") {
+		merged.Timezone = flags.Timezone
+	}
+	if overridden("freeze-calendar", "FREEZE_CALENDAR") {
+		merged.Freeze.Calendar = flags.Freeze.Calendar
+	}
+	if overridden("holiday-country", "HOLIDAY_COUNTRY") {
+		merged.Freeze.HolidayCountry = flags.Freeze.HolidayCountry
+	}
+	if overridden("metrics", "METRICS_ENABLED") {
+		merged.Metrics.Enabled = flags.Metrics.Enabled
+	}
+	if overridden("metrics-port", "METRICS_PORT") {
+		merged.Metrics.Port = flags.Metrics.Port
+	}
+	if overridden("metrics-path", "METRICS_PATH") {
+		merged.Metrics.Path = flags.Metrics.Path
+	}
+	if overridden("cloudevents-url", "CLOUDEVENTS_URL") {
+		merged.Notifications.CloudEvents.URL = flags.Notifications.CloudEvents.URL
+	}
+	if overridden("cloudevents-source", "CLOUDEVENTS_SOURCE") {
+		merged.Notifications.CloudEvents.Source = flags.Notifications.CloudEvents.Source
+	}
+	if overridden("nats-url", "NATS_URL") {
+		merged.Notifications.NATS.URL = flags.Notifications.NATS.URL
+	}
+	if overridden("nats-subject", "NATS_SUBJECT") {
+		merged.Notifications.NATS.Subject = flags.Notifications.NATS.Subject
+	}
+	if overridden("", "NATS_TOKEN") {
+		merged.Notifications.NATS.Token = flags.Notifications.NATS.Token
+	}
+	if overridden("kafka-brokers", "KAFKA_BROKERS") {
+		merged.Notifications.Kafka.Brokers = flags.Notifications.Kafka.Brokers
+	}
+	if overridden("kafka-events-topic", "KAFKA_EVENTS_TOPIC") {
+		merged.Notifications.Kafka.EventsTopic = flags.Notifications.Kafka.EventsTopic
+	}
+	if overridden("kafka-samples-topic", "KAFKA_SAMPLES_TOPIC") {
+		merged.Notifications.Kafka.SamplesTopic = flags.Notifications.Kafka.SamplesTopic
+	}
+	if overridden("kafka-tls", "KAFKA_TLS") {
+		merged.Notifications.Kafka.TLS = flags.Notifications.Kafka.TLS
+	}
+	if overridden("", "KAFKA_USERNAME") {
+		merged.Notifications.Kafka.Username = flags.Notifications.Kafka.Username
+	}
+	if overridden("", "KAFKA_PASSWORD") {
+		merged.Notifications.Kafka.Password = flags.Notifications.Kafka.Password
+	}
+	if overridden("sns-topic-arn", "SNS_TOPIC_ARN") {
+		merged.Notifications.SNS.TopicARN = flags.Notifications.SNS.TopicARN
+	}
+	if overridden("alertmanager-urls", "ALERTMANAGER_URLS") {
+		merged.Notifications.Alertmanager.URLs = flags.Notifications.Alertmanager.URLs
+	}
+	if overridden("alertmanager-silences", "ALERTMANAGER_SILENCES") {
+		merged.Notifications.Alertmanager.Silences = flags.Notifications.Alertmanager.Silences
+	}
+	if overridden("notification-subject-template", "NOTIFICATION_SUBJECT_TEMPLATE") {
+		merged.Notifications.Template.Subject = flags.Notifications.Template.Subject
+	}
+	if overridden("notification-body-template", "NOTIFICATION_BODY_TEMPLATE") {
+		merged.Notifications.Template.Body = flags.Notifications.Template.Body
+	}
+	if overridden("history-url", "HISTORY_URL") {
+		merged.Notifications.Template.HistoryURL = flags.Notifications.Template.HistoryURL
+	}
+	if overridden("runbook-url", "RUNBOOK_URL") {
+		merged.Notifications.Template.RunbookURL = flags.Notifications.Template.RunbookURL
+	}
+	if overridden("stall-intervals", "STALL_INTERVALS") {
+		merged.SelfMonitor.StallIntervals = flags.SelfMonitor.StallIntervals
+	}
+	if overridden("exit-on-stall", "EXIT_ON_STALL") {
+		merged.SelfMonitor.ExitOnStall = flags.SelfMonitor.ExitOnStall
+	}
+	if overridden("heartbeat-url", "HEARTBEAT_URL") {
+		merged.Heartbeat.URL = flags.Heartbeat.URL
+	}
+	if overridden("heartbeat-interval", "HEARTBEAT_INTERVAL") {
+		merged.Heartbeat.Interval = flags.Heartbeat.Interval
+	}
+	if overridden("grpc", "GRPC_ENABLED") {
+		merged.GRPC.Enabled = flags.GRPC.Enabled
+	}
+	if overridden("grpc-port", "GRPC_PORT") {
+		merged.GRPC.Port = flags.GRPC.Port
+	}
+	if overridden("grpc-tls-cert", "GRPC_TLS_CERT_FILE") {
+		merged.GRPC.TLS.CertFile = flags.GRPC.TLS.CertFile
+	}
+	if overridden("grpc-tls-key", "GRPC_TLS_KEY_FILE") {
+		merged.GRPC.TLS.KeyFile = flags.GRPC.TLS.KeyFile
+	}
+	if overridden("http-tls-cert", "HTTP_TLS_CERT_FILE") {
+		merged.HTTP.TLS.CertFile = flags.HTTP.TLS.CertFile
+	}
+	if overridden("http-tls-key", "HTTP_TLS_KEY_FILE") {
+		merged.HTTP.TLS.KeyFile = flags.HTTP.TLS.KeyFile
+	}
+	if overridden("http-tls-client-ca", "HTTP_TLS_CLIENT_CA_FILE") {
+		merged.HTTP.TLS.ClientCAFile = flags.HTTP.TLS.ClientCAFile
+	}
+	if overridden("grpc-tls-client-ca", "GRPC_TLS_CLIENT_CA_FILE") {
+		merged.GRPC.TLS.ClientCAFile = flags.GRPC.TLS.ClientCAFile
+	}
+	if overridden("", "HTTP_BEARER_TOKEN") {
+		merged.HTTP.BearerToken = flags.HTTP.BearerToken
+	}
+	if overridden("http-bearer-token-file", "HTTP_BEARER_TOKEN_FILE") {
+		merged.HTTP.BearerTokenFile = flags.HTTP.BearerTokenFile
+	}
+	if overridden("admin-api", "ADMIN_API_ENABLED") {
+		merged.Admin.Enabled = flags.Admin.Enabled
+	}
+	if overridden("", "ADMIN_RESTART_TOKEN") {
+		merged.Admin.RestartToken = flags.Admin.RestartToken
+	}
+	if overridden("dashboard", "DASHBOARD_ENABLED") {
+		merged.Dashboard.Enabled = flags.Dashboard.Enabled
+	}
+	if overridden("dashboard-path", "DASHBOARD_PATH") {
+		merged.Dashboard.Path = flags.Dashboard.Path
+	}
+	if overridden("metrics-source", "METRICS_SOURCE") {
+		merged.Source.Type = flags.Source.Type
+	}
+	if overridden("", "DD_SITE") {
+		merged.Source.Datadog.Site = flags.Source.Datadog.Site
+	}
+	if overridden("", "DD_API_KEY") {
+		merged.Source.Datadog.APIKey = flags.Source.Datadog.APIKey
+	}
+	if overridden("", "DD_APP_KEY") {
+		merged.Source.Datadog.AppKey = flags.Source.Datadog.AppKey
+	}
+	if overridden("", "CLOUDWATCH_CLUSTER_NAME") {
+		merged.Source.CloudWatch.ClusterName = flags.Source.CloudWatch.ClusterName
+	}
+	if overridden("", "PROMETHEUS_URL") {
+		merged.Source.Prometheus.URL = flags.Source.Prometheus.URL
+	}
+	if overridden("", "PROMETHEUS_AUTH") {
+		merged.Source.Prometheus.Auth = flags.Source.Prometheus.Auth
+	}
+	if overridden("", "PROMETHEUS_BEARER_TOKEN") {
+		merged.Source.Prometheus.BearerToken = flags.Source.Prometheus.BearerToken
+	}
+	if overridden("", "NEW_RELIC_API_KEY") {
+		merged.Source.NewRelic.APIKey = flags.Source.NewRelic.APIKey
+	}
+	if overridden("", "NEW_RELIC_ACCOUNT_ID") {
+		merged.Source.NewRelic.AccountID = flags.Source.NewRelic.AccountID
+	}
+	if overridden("", "NEW_RELIC_REGION") {
+		merged.Source.NewRelic.Region = flags.Source.NewRelic.Region
+	}
+	if overridden("", "KUBECTL_INIT_CONTAINERS") {
+		merged.Source.Kubectl.InitContainers = flags.Source.Kubectl.InitContainers
+	}
+	if overridden("", "CUSTOM_METRIC") {
+		merged.Source.Custom.Metric = flags.Source.Custom.Metric
+	}
+	if overridden("", "EXTERNAL_METRIC") {
+		merged.Source.External.Metric = flags.Source.External.Metric
+	}
+	if overridden("", "SCRAPE_PORT") {
+		merged.Source.Scrape.Port = flags.Source.Scrape.Port
+	}
+	if overridden("", "SCRAPE_PATH") {
+		merged.Source.Scrape.Path = flags.Source.Scrape.Path
+	}
+	if overridden("", "SCRAPE_METRIC") {
+		merged.Source.Scrape.Metric = flags.Source.Scrape.Metric
+	}
+	if overridden("statsd-address", "STATSD_ADDRESS") {
+		merged.StatsD.Address = flags.StatsD.Address
+	}
+	if overridden("statsd-prefix", "STATSD_PREFIX") {
+		merged.StatsD.Prefix = flags.StatsD.Prefix
+	}
+	if overridden("dogstatsd", "DOGSTATSD") {
+		merged.StatsD.DogStatsD = flags.StatsD.DogStatsD
+	}
+	if set["verbose"] {
+		merged.Verbose = flags.Verbose
+	}
+	if overridden("log-level", "LOG_LEVEL") {
+		merged.Log.Level = flags.Log.Level
+	}
+	if overridden("log-file", "LOG_FILE") {
+		merged.Log.File = flags.Log.File
+	}
+	if overridden("log-max-size", "LOG_MAX_SIZE") {
+		merged.Log.MaxSize = flags.Log.MaxSize
+	}
+	if overridden("log-max-age", "LOG_MAX_AGE") {
+		merged.Log.MaxAge = flags.Log.MaxAge
+	}
+	if overridden("log-max-backups", "LOG_MAX_BACKUPS") {
+		merged.Log.MaxBackups = flags.Log.MaxBackups
+	}
+	if overridden("log-compress", "LOG_COMPRESS") {
+		merged.Log.Compress = flags.Log.Compress
+	}
+	return merged
+}
+
+func getEnv(key, fallback string) string {
+	if value, ok := os.LookupEnv(key); ok {
+		return value
+	}
+	return fallback
+}
+
+func getEnvInt(key string, fallback int) int {
+	if value, ok := os.LookupEnv(key); ok {
+		if intValue, err := strconv.Atoi(value); err == nil {
+			return intValue
+		}
+	}
+	return fallback
+}
+
+func getEnvFloat(key string, fallback float64) float64 {
+	if value, ok := os.LookupEnv(key); ok {
+		if floatValue, err := strconv.ParseFloat(value, 64); err == nil {
+			return floatValue
+		}
+	}
+	return fallback
+}
+
+// splitList splits a comma-separated list, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	if value, ok := os.LookupEnv(key); ok {
+		if boolValue, err := strconv.ParseBool(value); err == nil {
+			return boolValue
+		}
+	}
+	return fallback
+}
+
+func getEnvDuration(key string, fallback time.Duration) time.Duration {
+	if value, ok := os.LookupEnv(key); ok {
+		if duration, err := time.ParseDuration(value); err == nil {
+			return duration
+		}
+	}
+	return fallback
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestGetEnvDuration(t *testing.T) {
+	tests := []struct {
+		name     string
+		envKey   string
+		envValue string
+		fallback time.Duration
+		expected time.Duration
+	}{
+		{
+			name:     "valid duration",
+			envKey:   "TEST_DURATION",
+			envValue: "1m",
+			fallback: 5 * time.Minute,
+			expected: 1 * time.Minute,
+		},
+		{
+			name:     "invalid duration",
+			envKey:   "TEST_DURATION",
+			envValue: "invalid",
+			fallback: 5 * time.Minute,
+			expected: 5 * time.Minute,
+		},
+		{
+			name:     "missing env var",
+			envKey:   "NONEXISTENT",
+			envValue: "",
+			fallback: 5 * time.Minute,
+			expected: 5 * time.Minute,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.envValue != "" {
+				os.Setenv(tt.envKey, tt.envValue)
+				defer os.Unsetenv(tt.envKey)
+			}
+
+			result := getEnvDuration(tt.envKey, tt.fallback)
+			if result != tt.expected {
+				t.Errorf("getEnvDuration() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseFlags(t *testing.T) {
+	// Reset flags and arguments before each test
+	flag.CommandLine = flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	args := os.Args
+	defer func() { os.Args = args }()
+	os.Args = args[:1]
+
+	// Test default values
+	config := parseFlags()
+	if config.Namespace != "default" {
+		t.Errorf("Expected default namespace, got %v", config.Namespace)
+	}
+	if config.MemoryThreshold != 5000 {
+		t.Errorf("Expected default memory threshold 5000, got %v", config.MemoryThreshold)
+	}
+	if config.CheckInterval != 5*time.Minute {
+		t.Errorf("Expected default check interval 5m, got %v", config.CheckInterval)
+	}
+	if config.MetricsTimeout != 30*time.Second || config.RestartTimeout != 2*time.Minute {
+		t.Errorf("Expected default timeouts 30s/2m, got %v/%v", config.MetricsTimeout, config.RestartTimeout)
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+)
+
+// configMountPath is where the generated Deployment mounts the ConfigMap of
+// the configuration file
+const configMountPath = "/etc/k8s-memory-watchdog"
+
+// manifestOptions parameterizes the generated manifests
+type manifestOptions struct {
+	// Name of the Deployment, ServiceAccount, ConfigMap and RBAC objects
+	Name string
+	// Namespace the watchdog is installed in
+	Namespace string
+	// CreateNamespace also generates the Namespace
+	CreateNamespace bool
+	Image           string
+	// ConfigFile is the content of the configuration file
+	ConfigFile string
+}
+
+// runGenerate implements the generate subcommand, whose only kind is
+// manifests: the Kubernetes objects installing the watchdog with a
+// configuration file, printed as a multi-document YAML stream for kubectl
+// apply
+func runGenerate(args []string) int {
+	if len(args) == 0 || args[0] != "manifests" {
+		fmt.Fprintln(os.Stderr, "usage: k8s-memory-watchdog generate manifests -f config.yaml [--namespace k8s-memory-watchdog] | kubectl apply -f -")
+		return 2
+	}
+
+	fs := flag.NewFlagSet("generate manifests", flag.ExitOnError)
+	var path, namespace string
+	fs.StringVar(&path, "f", getEnv("CONFIG_FILE", ""), "Configuration file of the watchdog")
+	fs.StringVar(&path, "config", getEnv("CONFIG_FILE", ""), "Configuration file of the watchdog")
+	fs.StringVar(&namespace, "n", "k8s-memory-watchdog", "Namespace the watchdog is installed in")
+	fs.StringVar(&namespace, "namespace", "k8s-memory-watchdog", "Namespace the watchdog is installed in")
+	name := fs.String("name", "k8s-memory-watchdog", "Name of the generated objects")
+	image := fs.String("image", "k8s-memory-watchdog", "Container image of the watchdog, which must include kubectl")
+	createNamespace := fs.Bool("create-namespace", true, "Also generate the Namespace")
+	fs.Parse(args[1:])
+	if path == "" {
+		fs.Usage()
+		return 2
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	// The permissions are those of the configuration as the watchdog reads
+	// it, with the defaults and environment variables applied
+	os.Args = append(os.Args[:1:1], "--config", path)
+	config := parseFlags()
+
+	m := manifestOptions{
+		Name:            *name,
+		Namespace:       namespace,
+		CreateNamespace: *createNamespace,
+		Image:           *image,
+		ConfigFile:      string(data),
+	}
+	if err := writeManifests(os.Stdout, m, config); err != nil {
+		log.Print(err)
+		return 1
+	}
+	return 0
+}
+
+// installPermissions returns the permissions the installed watchdog needs:
+// those checked at startup, and those of the lookups of the targets unless
+// target validation is off
+func installPermissions(config options) []kubectl.Permission {
+	permissions := requiredPermissions(config, nil)
+	if config.TargetValidation == "off" {
+		return permissions
+	}
+	seen := make(map[kubectl.Permission]bool)
+	for _, p := range permissions {
+		seen[p] = true
+	}
+	for _, target := range config.ResolveTargets() {
+		if target.Cluster != nil {
+			// the manifests only grant permissions in their own cluster
+			continue
+		}
+		for _, p := range []kubectl.Permission{
+			{Verb: "get", Resource: "namespaces/" + target.Namespace},
+			{Verb: "get", Resource: "deployments.apps/" + target.DeploymentName, Namespace: target.Namespace},
+		} {
+			if !seen[p] {
+				seen[p] = true
+				permissions = append(permissions, p)
+			}
+		}
+	}
+	return permissions
+}
+
+// policyRule is a rule of a Role or ClusterRole
+type policyRule struct {
+	apiGroup      string
+	resource      string
+	resourceNames []string
+	verbs         []string
+}
+
+// policyRules groups permissions into the rules of a Role per namespace,
+// the empty namespace standing for the ClusterRole of cluster-scoped
+// resources. Named resources only get their verbs, so the rules grant
+// exactly the permissions.
+func policyRules(permissions []kubectl.Permission) map[string][]policyRule {
+	type key struct{ namespace, apiGroup, resource, name string }
+	verbs := make(map[key][]string)
+	var keys []key
+	for _, p := range permissions {
+		resource, name := p.Resource, ""
+		if i := strings.Index(resource, "/"); i >= 0 {
+			resource, name = resource[:i], resource[i+1:]
+		}
+		apiGroup := ""
+		if i := strings.Index(resource, "."); i >= 0 {
+			resource, apiGroup = resource[:i], resource[i+1:]
+		}
+		k := key{p.Namespace, apiGroup, resource, name}
+		if _, ok := verbs[k]; !ok {
+			keys = append(keys, k)
+		}
+		verbs[k] = append(verbs[k], p.Verb)
+	}
+
+	// merge the rules of the resources sharing the same verbs
+	rules := make(map[string][]policyRule)
+	index := make(map[string]int)
+	for _, k := range keys {
+		sort.Strings(verbs[k])
+		id := strings.Join([]string{k.namespace, k.apiGroup, k.resource, strconv.FormatBool(k.name == ""), strings.Join(verbs[k], ",")}, "|")
+		if i, ok := index[id]; ok {
+			rules[k.namespace][i].resourceNames = append(rules[k.namespace][i].resourceNames, k.name)
+			continue
+		}
+		rule := policyRule{apiGroup: k.apiGroup, resource: k.resource, verbs: verbs[k]}
+		if k.name != "" {
+			rule.resourceNames = []string{k.name}
+		}
+		index[id] = len(rules[k.namespace])
+		rules[k.namespace] = append(rules[k.namespace], rule)
+	}
+	return rules
+}
+
+// writeManifests writes the Namespace, ServiceAccount, ConfigMap,
+// Roles, RoleBindings and Deployment of the watchdog
+func writeManifests(w io.Writer, m manifestOptions, config options) error {
+	if m.Name == "" || m.Namespace == "" {
+		return errors.New("the name and namespace of the manifests are required")
+	}
+	var b strings.Builder
+	document := func(format string, args ...interface{}) {
+		b.WriteString("---\n")
+		fmt.Fprintf(&b, format, args...)
+	}
+	labels := fmt.Sprintf("    app.kubernetes.io/name: %s\n", quote(m.Name))
+
+	if m.CreateNamespace {
+		document("apiVersion: v1\nkind: Namespace\nmetadata:\n  name: %s\n", quote(m.Namespace))
+	}
+	document("apiVersion: v1\nkind: ServiceAccount\nmetadata:\n  name: %s\n  namespace: %s\n  labels:\n%s",
+		quote(m.Name), quote(m.Namespace), labels)
+	document("apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: %s\n  namespace: %s\n  labels:\n%sdata:\n  config.yaml: |\n%s",
+		quote(m.Name), quote(m.Namespace), labels, indent(m.ConfigFile, "    "))
+
+	rules := policyRules(installPermissions(config))
+	namespaces := make([]string, 0, len(rules))
+	for namespace := range rules {
+		namespaces = append(namespaces, namespace)
+	}
+	sort.Strings(namespaces)
+	subject := fmt.Sprintf("subjects:\n  - kind: ServiceAccount\n    name: %s\n    namespace: %s\n", quote(m.Name), quote(m.Namespace))
+	for _, namespace := range namespaces {
+		kind, roleName := "Role", quote(m.Name)
+		meta := fmt.Sprintf("  name: %s\n  namespace: %s\n", roleName, quote(namespace))
+		if namespace == "" {
+			// cluster-scoped, so named after the namespace of the watchdog too
+			kind, roleName = "ClusterRole", quote(m.Name+"-"+m.Namespace)
+			meta = fmt.Sprintf("  name: %s\n", roleName)
+		}
+		document("apiVersion: rbac.authorization.k8s.io/v1\nkind: %s\nmetadata:\n%s  labels:\n%srules:\n%s",
+			kind, meta, labels, formatRules(rules[namespace]))
+		document("apiVersion: rbac.authorization.k8s.io/v1\nkind: %sBinding\nmetadata:\n%s  labels:\n%sroleRef:\n  apiGroup: rbac.authorization.k8s.io\n  kind: %s\n  name: %s\n%s",
+			kind, meta, labels, kind, roleName, subject)
+	}
+
+	document("apiVersion: apps/v1\nkind: Deployment\nmetadata:\n  name: %s\n  namespace: %s\n  labels:\n%s%s",
+		quote(m.Name), quote(m.Namespace), labels, deploymentSpec(m, config))
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func formatRules(rules []policyRule) string {
+	var b strings.Builder
+	for _, rule := range rules {
+		fmt.Fprintf(&b, "  - apiGroups: [%s]\n    resources: [%s]\n", quote(rule.apiGroup), quote(rule.resource))
+		if len(rule.resourceNames) > 0 {
+			fmt.Fprintf(&b, "    resourceNames: [%s]\n", quoteAll(rule.resourceNames))
+		}
+		fmt.Fprintf(&b, "    verbs: [%s]\n", quoteAll(rule.verbs))
+	}
+	return b.String()
+}
+
+// deploymentSpec returns the spec of the Deployment of the watchdog. A
+// single replica runs, as several would restart the same deployments.
+func deploymentSpec(m manifestOptions, config options) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "spec:\n  replicas: 1\n  strategy:\n    type: Recreate\n  selector:\n    matchLabels:\n      app.kubernetes.io/name: %s\n", quote(m.Name))
+	fmt.Fprintf(&b, "  template:\n    metadata:\n      labels:\n        app.kubernetes.io/name: %s\n    spec:\n      serviceAccountName: %s\n", quote(m.Name), quote(m.Name))
+	fmt.Fprintf(&b, "      securityContext:\n        runAsNonRoot: true\n        runAsUser: 65532\n")
+	fmt.Fprintf(&b, "      containers:\n        - name: watchdog\n          image: %s\n          args: [%s]\n",
+		quote(m.Image), quote("--config="+configMountPath+"/config.yaml"))
+	fmt.Fprintf(&b, "          securityContext:\n            allowPrivilegeEscalation: false\n")
+
+	var ports []string
+	if config.Metrics.Enabled || config.Dashboard.Enabled || config.Admin.Enabled {
+		ports = append(ports, fmt.Sprintf("            - name: http\n              containerPort: %d\n", config.Metrics.Port))
+	}
+	if config.GRPC.Enabled {
+		ports = append(ports, fmt.Sprintf("            - name: grpc\n              containerPort: %d\n", config.GRPC.Port))
+	}
+	if len(ports) > 0 {
+		b.WriteString("          ports:\n" + strings.Join(ports, ""))
+	}
+	if config.Metrics.Enabled || config.Dashboard.Enabled || config.Admin.Enabled {
+		scheme := "HTTP"
+		if config.HTTP.TLS.CertFile != "" {
+			scheme = "HTTPS"
+		}
+		for _, probe := range [][2]string{{"livenessProbe", "/healthz"}, {"readinessProbe", "/readyz"}} {
+			fmt.Fprintf(&b, "          %s:\n            httpGet:\n              path: %s\n              port: http\n              scheme: %s\n", probe[0], probe[1], scheme)
+		}
+	}
+	fmt.Fprintf(&b, "          resources:\n            requests:\n              cpu: 10m\n              memory: 32Mi\n            limits:\n              memory: 128Mi\n")
+	fmt.Fprintf(&b, "          volumeMounts:\n            - name: config\n              mountPath: %s\n              readOnly: true\n", configMountPath)
+	fmt.Fprintf(&b, "      volumes:\n        - name: config\n          configMap:\n            name: %s\n", quote(m.Name))
+	return b.String()
+}
+
+// quote returns s as a double-quoted YAML scalar
+func quote(s string) string {
+	return strconv.Quote(s)
+}
+
+func quoteAll(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quote(v)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// indent prefixes every non-empty line of text, for a YAML block scalar
+func indent(text, prefix string) string {
+	lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+	for i, line := range lines {
+		if line != "" {
+			lines[i] = prefix + line
+		}
+	}
+	return strings.Join(lines, "\n") + "\n"
+}
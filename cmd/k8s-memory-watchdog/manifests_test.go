@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestPolicyRules(t *testing.T) {
+	permissions := []kubectl.Permission{
+		{Verb: "list", Resource: "pods.metrics.k8s.io", Namespace: "prod"},
+		{Verb: "patch", Resource: "deployments.apps/api", Namespace: "prod"},
+		{Verb: "get", Resource: "deployments.apps/api", Namespace: "prod"},
+		{Verb: "patch", Resource: "deployments.apps/worker", Namespace: "prod"},
+		{Verb: "get", Resource: "deployments.apps/worker", Namespace: "prod"},
+		{Verb: "get", Resource: "configmaps/result", Namespace: "prod"},
+		{Verb: "create", Resource: "configmaps", Namespace: "prod"},
+		{Verb: "patch", Resource: "configmaps/result", Namespace: "prod"},
+		{Verb: "get", Resource: "namespaces/prod"},
+	}
+
+	expected := map[string][]policyRule{
+		"prod": {
+			{apiGroup: "metrics.k8s.io", resource: "pods", verbs: []string{"list"}},
+			{apiGroup: "apps", resource: "deployments", resourceNames: []string{"api", "worker"}, verbs: []string{"get", "patch"}},
+			{apiGroup: "", resource: "configmaps", resourceNames: []string{"result"}, verbs: []string{"get", "patch"}},
+			{apiGroup: "", resource: "configmaps", verbs: []string{"create"}},
+		},
+		"": {
+			{apiGroup: "", resource: "namespaces", resourceNames: []string{"prod"}, verbs: []string{"get"}},
+		},
+	}
+	if rules := policyRules(permissions); !reflect.DeepEqual(rules, expected) {
+		t.Errorf("policyRules() = %+v, want %+v", rules, expected)
+	}
+}
+
+func TestWriteManifests(t *testing.T) {
+	config := options{
+		Config: watchdog.Config{
+			Namespace: "prod",
+			Metrics:   telemetry.Config{Enabled: true, Port: 9090},
+			Targets:   []watchdog.Target{{DeploymentName: "api"}},
+		},
+		TargetValidation: "off",
+	}
+	m := manifestOptions{
+		Name:            "watchdog",
+		Namespace:       "ops",
+		CreateNamespace: true,
+		Image:           "registry.example.com/k8s-memory-watchdog:1.0",
+		ConfigFile:      "namespace: prod\ntargets:\n  - deployment: api\n",
+	}
+
+	var out strings.Builder
+	if err := writeManifests(&out, m, config); err != nil {
+		t.Fatalf("writeManifests() error = %v", err)
+	}
+	manifests := out.String()
+	for _, kind := range []string{"Namespace", "ServiceAccount", "ConfigMap", "Role", "RoleBinding", "Deployment"} {
+		if !strings.Contains(manifests, "\nkind: "+kind+"\n") {
+			t.Errorf("writeManifests() has no %s:\n%s", kind, manifests)
+		}
+	}
+	for _, fragment := range []string{
+		"  config.yaml: |\n    namespace: prod\n    targets:\n      - deployment: api\n",
+		"  - apiGroups: [\"apps\"]\n    resources: [\"deployments\"]\n    resourceNames: [\"api\"]\n    verbs: [\"get\", \"patch\"]\n",
+		"subjects:\n  - kind: ServiceAccount\n    name: \"watchdog\"\n    namespace: \"ops\"\n",
+		"image: \"registry.example.com/k8s-memory-watchdog:1.0\"",
+		"containerPort: 9090",
+	} {
+		if !strings.Contains(manifests, fragment) {
+			t.Errorf("writeManifests() has no %q:\n%s", fragment, manifests)
+		}
+	}
+	if strings.Contains(manifests, "ClusterRole") {
+		t.Errorf("writeManifests() has a ClusterRole without target validation:\n%s", manifests)
+	}
+}
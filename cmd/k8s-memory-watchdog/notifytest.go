@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// runNotifyTest implements the notify-test subcommand, which sends a
+// synthetic event through the configured notifiers and reports whether
+// each delivered it, and returns the process exit code. It takes the flags
+// and environment variables of the watchdog itself.
+func runNotifyTest(args []string) int {
+	only := flag.String("channel", "", "Only test this channel, e.g. sns (default: every configured channel)")
+	eventType := flag.String("type", string(watchdog.EventRestart), "Type of the synthetic event")
+	targetName := flag.String("target", "", "Target of the synthetic event (default: the first configured target)")
+	timeout := flag.Duration("timeout", 30*time.Second, "Timeout of each delivery")
+	os.Args = append(os.Args[:1:1], args...)
+	config := parseFlags()
+
+	runner, err := newRunner(config.Config)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	secretsCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	err = setupSecrets(secretsCtx, runner, config.Config)
+	cancel()
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	channels, closeChannels, err := newChannels(config.Config, config.EventsOut)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	defer closeChannels.Close()
+	if *only != "" {
+		var names []string
+		var selected []channel
+		for _, c := range channels {
+			names = append(names, c.name)
+			if c.name == *only {
+				selected = append(selected, c)
+			}
+		}
+		if len(selected) == 0 {
+			log.Printf("Channel '%s' is not configured (configured: %s)", *only, strings.Join(names, ", "))
+			return 1
+		}
+		channels = selected
+	}
+	if len(channels) == 0 {
+		log.Print("No notification channel is configured")
+		return 1
+	}
+
+	event, err := testEvent(config.ResolveTargets(), *targetName, watchdog.EventType(*eventType), time.Now())
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if !sendTestEvent(context.Background(), os.Stdout, channels, event, *timeout) {
+		return 1
+	}
+	return 0
+}
+
+// testEvent builds the synthetic event of a target, or of the first one
+// when name is empty, breaching its threshold by 10%
+func testEvent(targets []watchdog.Target, name string, eventType watchdog.EventType, now time.Time) (watchdog.Event, error) {
+	var target *watchdog.Target
+	for i := range targets {
+		if name == "" || targets[i].Name == name {
+			target = &targets[i]
+			break
+		}
+	}
+	if target == nil {
+		if name == "" {
+			name = "any target"
+		}
+		return watchdog.Event{}, fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	return watchdog.Event{
+		Type:      eventType,
+		Target:    *target,
+		Memory:    target.MemoryThreshold + target.MemoryThreshold/10,
+		Threshold: target.MemoryThreshold,
+		Time:      now,
+		Reason:    "test notification sent by k8s-memory-watchdog notify-test",
+	}, nil
+}
+
+// sendTestEvent delivers event through every channel, writing the outcome
+// of each to w, and reports whether all of them succeeded. Channels only
+// acting on checks receive a check event instead.
+func sendTestEvent(ctx context.Context, w io.Writer, channels []channel, event watchdog.Event, timeout time.Duration) bool {
+	ok := true
+	for _, c := range channels {
+		e := event
+		if c.checksOnly {
+			e.Type = watchdog.EventCheck
+		}
+		sendCtx, cancel := context.WithTimeout(ctx, timeout)
+		start := time.Now()
+		err := c.notifier.Notify(sendCtx, e)
+		cancel()
+		elapsed := time.Since(start).Round(time.Millisecond)
+		if err != nil {
+			ok = false
+			fmt.Fprintf(w, "FAIL  %-12s %s event after %s: %v\n", c.name, e.Type, elapsed, err)
+			continue
+		}
+		fmt.Fprintf(w, "ok    %-12s %s event in %s\n", c.name, e.Type, elapsed)
+	}
+	return ok
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestTestEvent(t *testing.T) {
+	targets := []watchdog.Target{
+		{Name: "prod/api", MemoryThreshold: 2000},
+		{Name: "prod/worker", MemoryThreshold: 1000},
+	}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	event, err := testEvent(targets, "", watchdog.EventRestart, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event.Target.Name != "prod/api" || event.Memory != 2200 || event.Threshold != 2000 || event.Type != watchdog.EventRestart {
+		t.Errorf("testEvent() = %+v, want a restart of the first target over its threshold", event)
+	}
+	if event, _ := testEvent(targets, "prod/worker", watchdog.EventBreach, now); event.Target.Name != "prod/worker" {
+		t.Errorf("testEvent() target = %q, want prod/worker", event.Target.Name)
+	}
+	if _, err := testEvent(targets, "prod/missing", watchdog.EventBreach, now); !errors.Is(err, watchdog.ErrTargetNotFound) {
+		t.Errorf("testEvent() error = %v, want ErrTargetNotFound", err)
+	}
+}
+
+func TestSendTestEvent(t *testing.T) {
+	var received []watchdog.EventType
+	record := watchdog.NotifierFunc(func(ctx context.Context, event watchdog.Event) error {
+		received = append(received, event.Type)
+		return nil
+	})
+	channels := []channel{
+		{name: "sns", notifier: record},
+		{name: "nats", notifier: watchdog.NotifierFunc(func(ctx context.Context, event watchdog.Event) error {
+			return errors.New("connection refused")
+		})},
+		{name: "heartbeat", notifier: record, stream: true, checksOnly: true},
+	}
+
+	var out bytes.Buffer
+	if sendTestEvent(context.Background(), &out, channels, watchdog.Event{Type: watchdog.EventRestart}, time.Second) {
+		t.Error("sendTestEvent() = true, want false when a channel fails")
+	}
+	if want := []watchdog.EventType{watchdog.EventRestart, watchdog.EventCheck}; len(received) != 2 || received[0] != want[0] || received[1] != want[1] {
+		t.Errorf("received = %v, want %v", received, want)
+	}
+	report := out.String()
+	for _, want := range []string{"ok    sns", "FAIL  nats", "connection refused", "ok    heartbeat    check event"} {
+		if !strings.Contains(report, want) {
+			t.Errorf("report = %q, want it to contain %q", report, want)
+		}
+	}
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// errPublishFailed is the operation sentinel of a failed result ConfigMap
+// update
+var errPublishFailed = errors.New("error publishing result")
+
+// onceReport is the JSON document written by --once --output=json
+type onceReport struct {
+	Time      time.Time              `json:"time"`
+	Checked   int                    `json:"checked"`
+	Breached  int                    `json:"breached"`
+	Restarted int                    `json:"restarted"`
+	Failed    int                    `json:"failed"`
+	Results   []watchdog.CheckResult `json:"results"`
+}
+
+// newOnceReport summarizes the results of a single run
+func newOnceReport(now time.Time, results []watchdog.CheckResult) onceReport {
+	report := onceReport{Time: now, Checked: len(results), Results: results}
+	for _, result := range results {
+		if result.Breached {
+			report.Breached++
+		}
+		if result.Action != "" {
+			report.Restarted++
+		}
+		if result.Err != nil {
+			report.Failed++
+		}
+	}
+	if report.Results == nil {
+		report.Results = []watchdog.CheckResult{}
+	}
+	return report
+}
+
+// writeReport writes the report as indented JSON
+func writeReport(w io.Writer, report onceReport) error {
+	data, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(data, '\n'))
+	return err
+}
+
+// splitConfigMap returns the namespace and name of a ConfigMap given as
+// "name" or "namespace/name"
+func splitConfigMap(configMap, defaultNamespace string) (string, string) {
+	namespace, name := defaultNamespace, configMap
+	if i := strings.IndexByte(configMap, '/'); i >= 0 {
+		namespace, name = configMap[:i], configMap[i+1:]
+	}
+	if namespace == "" {
+		namespace = "default"
+	}
+	return namespace, name
+}
+
+// publishReport stores the report under the key result.json of a ConfigMap,
+// given as "name" or "namespace/name", creating or replacing it
+func publishReport(ctx context.Context, runner *kubectl.Runner, configMap, defaultNamespace string, report onceReport) error {
+	namespace, name := splitConfigMap(configMap, defaultNamespace)
+
+	data, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	manifest, err := json.Marshal(map[string]interface{}{
+		"apiVersion": "v1",
+		"kind":       "ConfigMap",
+		"metadata": map[string]interface{}{
+			"name":      name,
+			"namespace": namespace,
+			"labels":    map[string]string{"app.kubernetes.io/managed-by": "k8s-memory-watchdog"},
+		},
+		"data": map[string]string{"result.json": string(data)},
+	})
+	if err != nil {
+		return err
+	}
+	if _, err := runner.RunInput(ctx, errPublishFailed, manifest, "apply", "-f", "-"); err != nil {
+		return fmt.Errorf("configmap %s/%s: %w", namespace, name, err)
+	}
+	return nil
+}
+
+// runOnceJSON checks every target a single time, writes the report to out
+// and optionally to a ConfigMap, and returns the process exit code
+func runOnceJSON(ctx context.Context, w *watchdog.Watchdog, out io.Writer, runner *kubectl.Runner, config options) int {
+	report := newOnceReport(time.Now(), w.CheckOnce(ctx))
+	code := 0
+	if report.Failed > 0 {
+		code = 1
+	}
+	if err := writeReport(out, report); err != nil {
+		log.Printf("Error writing result: %v", err)
+		code = 1
+	}
+	if config.ResultConfigMap != "" {
+		if err := publishReport(ctx, runner, config.ResultConfigMap, config.Namespace, report); err != nil {
+			log.Printf("Error publishing result: %v", err)
+			code = 1
+		}
+	}
+	return code
+}
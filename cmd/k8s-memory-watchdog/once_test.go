@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestWriteReport(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	report := newOnceReport(now, []watchdog.CheckResult{
+		{Target: watchdog.Target{Name: "prod/api"}, Memory: 3000, Threshold: 2000, Breached: true, Action: "restart"},
+		{Target: watchdog.Target{Name: "prod/worker"}, Memory: 1000, Threshold: 2000},
+		{Target: watchdog.Target{Name: "prod/cron"}, Err: errors.New("metrics unavailable")},
+	})
+	if report.Checked != 3 || report.Breached != 1 || report.Restarted != 1 || report.Failed != 1 {
+		t.Errorf("newOnceReport() = %+v", report)
+	}
+
+	var buf bytes.Buffer
+	if err := writeReport(&buf, report); err != nil {
+		t.Fatalf("writeReport() error = %v", err)
+	}
+	var decoded struct {
+		Checked int `json:"checked"`
+		Results []struct {
+			Breached bool   `json:"breached"`
+			Error    string `json:"error"`
+		} `json:"results"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatalf("writeReport() wrote invalid JSON: %v\n%s", err, buf.String())
+	}
+	if decoded.Checked != 3 || !decoded.Results[0].Breached || decoded.Results[2].Error != "metrics unavailable" {
+		t.Errorf("writeReport() = %s", buf.String())
+	}
+
+	buf.Reset()
+	writeReport(&buf, newOnceReport(now, nil))
+	if !bytes.Contains(buf.Bytes(), []byte(`"results": []`)) {
+		t.Errorf("writeReport() without results = %s, want an empty list", buf.String())
+	}
+}
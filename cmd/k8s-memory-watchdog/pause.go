@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// runPause implements the pause and resume subcommands, which stop or
+// restart the scheduled checks of a target through the admin API of a
+// running watchdog, and returns the process exit code
+func runPause(command string, args []string) int {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	addr := adminURLFlag(fs)
+	token := fs.String("token", getEnv("ADMIN_RESTART_TOKEN", ""), "Restart token of the admin API")
+	target := fs.String("target", "", "Target to "+command+", by name or deployment")
+	user := fs.String("user", getEnv("USER", ""), "Who acts on the target, for the audit log")
+	reason := fs.String("reason", "", "Why the target is "+command+"d, for the audit log")
+	var duration time.Duration
+	if command == "pause" {
+		fs.DurationVar(&duration, "for", 0, "How long the target is paused, until resumed when zero")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: k8s-memory-watchdog %s [flags] --target <target>\n", command)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if *target == "" && fs.NArg() == 1 {
+		*target = fs.Arg(0)
+	} else if fs.NArg() != 0 {
+		*target = ""
+	}
+	if *target == "" || duration < 0 {
+		fs.Usage()
+		return 2
+	}
+
+	name, err := resolveTargetName(http.DefaultClient, *addr, *token, *target)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	body := map[string]string{"user": *user, "reason": *reason}
+	if duration > 0 {
+		body["duration"] = duration.String()
+	}
+	var pause watchdog.Pause
+	if err := postOperatorAction(http.DefaultClient, *addr, command, name, *token, body, &pause); err != nil {
+		log.Print(err)
+		return 1
+	}
+	switch {
+	case command == "resume":
+		fmt.Printf("Resumed %s\n", name)
+	case pause.Until.IsZero():
+		fmt.Printf("Paused %s until resumed\n", name)
+	default:
+		fmt.Printf("Paused %s until %s\n", name, pause.Until.Local().Format(time.RFC3339))
+	}
+	return 0
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+)
+
+// pluginName is the executable name kubectl runs for kubectl memwatchdog
+const pluginName = "kubectl-memwatchdog"
+
+var errPluginUsage = errors.New(`usage: kubectl memwatchdog check [DEPLOYMENT] [flags]
+       kubectl memwatchdog watch [DEPLOYMENT] [flags]
+       kubectl memwatchdog history [DEPLOYMENT] [--since 24h] [--format table|json|csv]
+
+check checks once and exits, watch keeps watching. -n/--namespace,
+--context and --kubeconfig follow kubectl; other flags are those of
+k8s-memory-watchdog.`)
+
+// isPlugin reports whether the executable is named like the kubectl plugin
+func isPlugin(arg0 string) bool {
+	return strings.TrimSuffix(filepath.Base(arg0), ".exe") == pluginName
+}
+
+// kubectlFlags are the flags of kubectl that plugins are expected to honor
+type kubectlFlags struct {
+	namespace  string
+	context    string
+	kubeconfig string
+}
+
+// splitKubectlFlags removes the kubectl flags from args
+func splitKubectlFlags(args []string) (kubectlFlags, []string) {
+	var kube kubectlFlags
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		name, value := args[i], ""
+		hasValue := false
+		if j := strings.Index(name, "="); j >= 0 && strings.HasPrefix(name, "-") {
+			name, value, hasValue = name[:j], name[j+1:], true
+		}
+		var field *string
+		switch name {
+		case "-n", "--namespace", "-namespace":
+			field = &kube.namespace
+		case "--context", "-context":
+			field = &kube.context
+		case "--kubeconfig", "-kubeconfig":
+			field = &kube.kubeconfig
+		default:
+			rest = append(rest, args[i])
+			continue
+		}
+		if !hasValue && i+1 < len(args) {
+			i++
+			value = args[i]
+		}
+		*field = value
+	}
+	return kube, rest
+}
+
+// pluginArgs translates the arguments of kubectl memwatchdog into those of
+// the watchdog. Like kubectl, the watchdog then uses the current context and
+// its namespace unless --context or --namespace is given.
+func pluginArgs(args []string) ([]string, error) {
+	if len(args) == 0 {
+		return nil, errPluginUsage
+	}
+	kube, rest := splitKubectlFlags(args[1:])
+	if kube.kubeconfig != "" {
+		// inherited by every kubectl the watchdog runs
+		os.Setenv("KUBECONFIG", kube.kubeconfig)
+	}
+
+	var translated []string
+	switch args[0] {
+	case "history":
+		translated = append(translated, "history")
+		if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+			target := rest[0]
+			if kube.namespace != "" {
+				target = kube.namespace + "/" + target
+			}
+			translated = append(translated, "--target", target)
+			rest = rest[1:]
+		}
+		return append(translated, rest...), nil
+	case "check":
+		translated = append(translated, "--once")
+	case "watch":
+	default:
+		return nil, errPluginUsage
+	}
+
+	kubectlPath := os.Getenv("KUBECTL_PATH")
+	if kubectlPath == "" {
+		// the kubectl running the plugin, rather than the default path
+		if path, err := exec.LookPath("kubectl"); err == nil {
+			kubectlPath = path
+			translated = append(translated, "--kubectl", path)
+		}
+	}
+	if kube.context != "" {
+		translated = append(translated, "--kube-context", kube.context)
+	}
+	if kube.namespace == "" && os.Getenv("NAMESPACE") == "" && kubectlPath != "" {
+		kube.namespace = currentNamespace(kubectlPath, kube.context)
+	}
+	if kube.namespace != "" {
+		translated = append(translated, "--namespace", kube.namespace)
+	}
+	if len(rest) > 0 && !strings.HasPrefix(rest[0], "-") {
+		translated = append(translated, "--deployment", rest[0])
+		rest = rest[1:]
+	}
+	return append(translated, rest...), nil
+}
+
+// currentNamespace returns the namespace of a context of the kubeconfig,
+// default when it has none or it can't be read
+func currentNamespace(kubectlPath, kubeContext string) string {
+	runner := kubectl.NewRunner(kubectlPath, 0, 0)
+	runner.SetContext(kubeContext)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	namespace, err := runner.CurrentNamespace(ctx)
+	if err != nil || namespace == "" {
+		return "default"
+	}
+	return namespace
+}
@@ -0,0 +1,64 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestIsPlugin(t *testing.T) {
+	for arg0, want := range map[string]bool{
+		"/usr/local/bin/kubectl-memwatchdog": true,
+		"kubectl-memwatchdog.exe":            true,
+		"kubectl-memwatchdog":                true,
+		"/usr/local/bin/k8s-memory-watchdog": false,
+	} {
+		if got := isPlugin(arg0); got != want {
+			t.Errorf("isPlugin(%q) = %v, want %v", arg0, got, want)
+		}
+	}
+}
+
+func TestPluginArgs(t *testing.T) {
+	os.Setenv("KUBECTL_PATH", "/bin/false")
+	defer os.Unsetenv("KUBECTL_PATH")
+
+	tests := []struct {
+		args []string
+		want []string
+	}{
+		{
+			args: []string{"check", "api", "-n", "prod", "--threshold", "4000"},
+			want: []string{"--once", "--namespace", "prod", "--deployment", "api", "--threshold", "4000"},
+		},
+		{
+			args: []string{"watch", "worker", "--namespace=batch", "--context", "staging"},
+			want: []string{"--kube-context", "staging", "--namespace", "batch", "--deployment", "worker"},
+		},
+		{
+			// the namespace of a context that can't be read is default
+			args: []string{"check", "--config", "config.yaml"},
+			want: []string{"--once", "--namespace", "default", "--config", "config.yaml"},
+		},
+		{
+			args: []string{"history", "--since", "24h", "-n", "prod"},
+			want: []string{"history", "--since", "24h"},
+		},
+		{
+			args: []string{"history", "api", "-n", "prod", "--format", "json"},
+			want: []string{"history", "--target", "prod/api", "--format", "json"},
+		},
+	}
+	for _, tt := range tests {
+		got, err := pluginArgs(tt.args)
+		if err != nil || !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("pluginArgs(%q) = %q, %v, want %q", tt.args, got, err, tt.want)
+		}
+	}
+
+	for _, args := range [][]string{nil, {"restart", "api"}} {
+		if _, err := pluginArgs(args); err != errPluginUsage {
+			t.Errorf("pluginArgs(%q) error = %v, want the usage", args, err)
+		}
+	}
+}
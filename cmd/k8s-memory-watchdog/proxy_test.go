@@ -0,0 +1,77 @@
+package main
+
+import (
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestBypassProxy(t *testing.T) {
+	noProxy := "svc.cluster.local, .corp.example, 10.0.0.0/8, prometheus"
+	tests := []struct {
+		host   string
+		bypass bool
+	}{
+		{host: "localhost", bypass: true},
+		{host: "127.0.0.1", bypass: true},
+		{host: "prometheus", bypass: true},
+		{host: "thanos.monitoring.svc.cluster.local", bypass: true},
+		{host: "corp.example", bypass: true},
+		{host: "Grafana.Corp.Example", bypass: true},
+		{host: "10.96.0.1", bypass: true},
+		{host: "hooks.slack.com"},
+		{host: "192.168.1.10"},
+		{host: "notcorp.example"},
+	}
+	for _, tt := range tests {
+		if got := bypassProxy(tt.host, noProxy); got != tt.bypass {
+			t.Errorf("bypassProxy(%q) = %v, want %v", tt.host, got, tt.bypass)
+		}
+	}
+	if !bypassProxy("hooks.slack.com", "*") {
+		t.Error("bypassProxy() = false, want * to match every host")
+	}
+}
+
+func TestOutboundTransport(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	transport, err := outboundTransport(watchdog.ProxyConfig{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (&http.Client{Transport: transport}).Get(server.URL); err == nil {
+		t.Fatal("Get() error = nil, want the unknown CA rejected")
+	}
+
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	data := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(caFile, data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	transport, err = outboundTransport(watchdog.ProxyConfig{URL: "http://proxy.corp:3128", NoProxy: "127.0.0.0/8", CAFile: caFile})
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v, want the CA bundle trusted", err)
+	}
+	resp.Body.Close()
+
+	req := &http.Request{URL: &url.URL{Scheme: "https", Host: "hooks.slack.com"}}
+	if proxy, err := transport.Proxy(req); err != nil || proxy == nil || proxy.Host != "proxy.corp:3128" {
+		t.Errorf("Proxy() = %v, %v, want the configured proxy", proxy, err)
+	}
+
+	if _, err := outboundTransport(watchdog.ProxyConfig{CAFile: filepath.Join(t.TempDir(), "missing.pem")}); err == nil {
+		t.Error("outboundTransport() error = nil, want the missing bundle reported")
+	}
+}
@@ -0,0 +1,163 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// requiredPermissions returns the Kubernetes permissions the watchdog
+// needs in cluster, nil for the default one, for its targets there:
+// reading their metrics from the sources backed by kubectl, restarting
+// their deployments, counting their replicas and reading the ages of their
+// pods. In the default cluster, it also needs to read the Secrets
+// credentials refer to and the policies of the tenants, and to store the
+// --once results.
+func requiredPermissions(config options, cluster *watchdog.Cluster) []kubectl.Permission {
+	seen := make(map[kubectl.Permission]bool)
+	var permissions []kubectl.Permission
+	add := func(verb, resource, namespace string) {
+		p := kubectl.Permission{Verb: verb, Resource: resource, Namespace: namespace}
+		if !seen[p] {
+			seen[p] = true
+			permissions = append(permissions, p)
+		}
+	}
+
+	for _, target := range config.ResolveTargets() {
+		if clusterName(target.Cluster) != clusterName(cluster) {
+			continue
+		}
+		sources := target.Sources
+		if len(sources) == 0 {
+			sources = []string{config.Source.Type}
+		}
+		// the pods of Jobs are told apart by their owners in the readings
+		// of each pod of the namespace
+		batch := (target.Scope == "" || target.Scope == watchdog.ScopeNamespace) && target.BatchPods != watchdog.BatchPodsInclude
+		for _, source := range sources {
+			switch source {
+			case "", "kubectl":
+				add("list", "pods.metrics.k8s.io", target.Namespace)
+				if batch || config.Source.Kubectl.InitContainers == watchdog.InitContainersExclude {
+					add("list", "pods", target.Namespace)
+				}
+			case "custom":
+				add("get", "pods.custom.metrics.k8s.io", target.Namespace)
+				if batch {
+					add("list", "pods", target.Namespace)
+				}
+			case "external":
+				add("get", config.Source.External.Metric+".external.metrics.k8s.io", target.Namespace)
+			case "scrape":
+				add("list", "pods", target.Namespace)
+				add("list", "deployments.apps", target.Namespace)
+			}
+		}
+		deployment := "deployments.apps/" + target.DeploymentName
+		if target.ColorService != "" {
+			// the active color is read from the Service, and either
+			// deployment of the pair may be the one restarted
+			add("get", "services/"+target.ColorService, target.Namespace)
+			add("list", "deployments.apps", target.Namespace)
+			deployment = "deployments.apps"
+		}
+		// kubectl rollout restart patches the pod template, and the
+		// correlation IDs are annotated on the deployment
+		add("patch", deployment, target.Namespace)
+		// restarts are deferred while the deployment is rolling out, and
+		// per-replica thresholds, the availability guard, surges and the
+		// scale and delete strategies read its replicas or selector
+		add("get", deployment, target.Namespace)
+		if target.RestartStrategy == watchdog.RestartScale || target.RestartStrategy == watchdog.RestartDelete {
+			// both wait for or delete the pods of the deployment
+			add("list", "pods", target.Namespace)
+		}
+		if target.RestartStrategy == watchdog.RestartDelete {
+			add("delete", "pods", target.Namespace)
+		}
+		if target.Rollback || target.SurgeReplicas > 0 {
+			// kubectl rollout status watches the deployment, and rollout
+			// undo reads the ReplicaSets of its previous revisions
+			add("watch", deployment, target.Namespace)
+		}
+		if target.Rollback {
+			add("list", "replicasets.apps", target.Namespace)
+		}
+		if target.WarmUp > 0 {
+			// the warm-up reads the start times of the pods
+			add("list", "pods", target.Namespace)
+		}
+	}
+
+	if cluster != nil {
+		return permissions
+	}
+	for _, namespace := range config.Tenants.Namespaces {
+		// the targets of the tenants are only known once running, so only
+		// reading their policies is checked
+		add("get", "configmaps/"+config.Tenants.ConfigMap, namespace)
+	}
+
+	for _, ref := range secretRefs(config.Config) {
+		add("get", "secrets/"+ref[1], ref[0])
+	}
+
+	if config.Once && config.ResultConfigMap != "" {
+		namespace, name := splitConfigMap(config.ResultConfigMap, config.Namespace)
+		add("get", "configmaps/"+name, namespace)
+		add("create", "configmaps", namespace)
+		add("patch", "configmaps/"+name, namespace)
+	}
+	return permissions
+}
+
+// checkPermissions fails with the list of the permissions the watchdog
+// lacks in each of its clusters, so a missing RBAC rule is reported at
+// startup rather than as a failed restart
+func checkPermissions(ctx context.Context, runner *kubectl.Runner, config options) error {
+	var lines []string
+	for _, cluster := range targetClusters(config.ResolveTargets()) {
+		missing, err := runner.MissingPermissions(watchdog.ContextWithCluster(ctx, cluster), requiredPermissions(config, cluster))
+		if err != nil {
+			return err
+		}
+		for _, p := range missing {
+			line := "  - " + p.String()
+			if cluster != nil {
+				line += " of " + cluster.String()
+			}
+			lines = append(lines, line)
+		}
+	}
+	if len(lines) == 0 {
+		return nil
+	}
+	return fmt.Errorf("missing Kubernetes permissions, grant them to the watchdog's service account or use --rbac-check=false:\n%s",
+		strings.Join(lines, "\n"))
+}
+
+// targetClusters returns the distinct clusters of targets, starting with
+// the default one, nil, which the watchdog always reaches
+func targetClusters(targets []watchdog.Target) []*watchdog.Cluster {
+	clusters := []*watchdog.Cluster{nil}
+	seen := map[string]bool{"": true}
+	for _, t := range targets {
+		if name := clusterName(t.Cluster); !seen[name] {
+			seen[name] = true
+			clusters = append(clusters, t.Cluster)
+		}
+	}
+	return clusters
+}
+
+// clusterName identifies a cluster, empty for the default one
+func clusterName(cluster *watchdog.Cluster) string {
+	if cluster == nil || *cluster == (watchdog.Cluster{}) {
+		return ""
+	}
+	return cluster.String()
+}
@@ -0,0 +1,85 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestRequiredPermissions(t *testing.T) {
+	config := options{
+		Config: watchdog.Config{
+			Namespace: "prod",
+			Targets: []watchdog.Target{
+				{DeploymentName: "api"},
+				{DeploymentName: "worker", ReplicaMemory: 500},
+				{Namespace: "batch", DeploymentName: "jobs", Sources: []string{"prometheus", "scrape"}, WarmUp: time.Minute},
+				{Namespace: "ledger", DeploymentName: "ledger", Sources: []string{"prometheus"}, RestartStrategy: watchdog.RestartScale},
+				{Namespace: "edge", DeploymentName: "gateway", Sources: []string{"prometheus"}, RestartStrategy: watchdog.RestartDelete},
+				{Namespace: "shop", DeploymentName: "web", Sources: []string{"prometheus"}, ColorService: "web", ColorLabel: "color"},
+				{Namespace: "pay", DeploymentName: "checkout", Sources: []string{"prometheus"}, Rollback: true},
+				{Namespace: "pay", DeploymentName: "checkout", Sources: []string{"prometheus"}, Cluster: &watchdog.Cluster{Context: "staging"}},
+			},
+			Tenants: watchdog.TenantConfig{Namespaces: []string{"team-a"}, ConfigMap: "memory-watchdog"},
+			Source: watchdog.SourceConfig{
+				Prometheus: watchdog.PrometheusConfig{BearerToken: "secret:monitoring/prometheus-token/token"},
+			},
+		},
+		Once:            true,
+		ResultConfigMap: "ops/watchdog-result",
+	}
+
+	expected := []kubectl.Permission{
+		{Verb: "list", Resource: "pods.metrics.k8s.io", Namespace: "prod"},
+		{Verb: "list", Resource: "pods", Namespace: "prod"},
+		{Verb: "patch", Resource: "deployments.apps/api", Namespace: "prod"},
+		{Verb: "get", Resource: "deployments.apps/api", Namespace: "prod"},
+		{Verb: "patch", Resource: "deployments.apps/worker", Namespace: "prod"},
+		{Verb: "get", Resource: "deployments.apps/worker", Namespace: "prod"},
+		{Verb: "list", Resource: "pods", Namespace: "batch"},
+		{Verb: "list", Resource: "deployments.apps", Namespace: "batch"},
+		{Verb: "patch", Resource: "deployments.apps/jobs", Namespace: "batch"},
+		{Verb: "get", Resource: "deployments.apps/jobs", Namespace: "batch"},
+		{Verb: "patch", Resource: "deployments.apps/ledger", Namespace: "ledger"},
+		{Verb: "get", Resource: "deployments.apps/ledger", Namespace: "ledger"},
+		{Verb: "list", Resource: "pods", Namespace: "ledger"},
+		{Verb: "patch", Resource: "deployments.apps/gateway", Namespace: "edge"},
+		{Verb: "get", Resource: "deployments.apps/gateway", Namespace: "edge"},
+		{Verb: "list", Resource: "pods", Namespace: "edge"},
+		{Verb: "delete", Resource: "pods", Namespace: "edge"},
+		{Verb: "get", Resource: "services/web", Namespace: "shop"},
+		{Verb: "list", Resource: "deployments.apps", Namespace: "shop"},
+		{Verb: "patch", Resource: "deployments.apps", Namespace: "shop"},
+		{Verb: "get", Resource: "deployments.apps", Namespace: "shop"},
+		{Verb: "patch", Resource: "deployments.apps/checkout", Namespace: "pay"},
+		{Verb: "get", Resource: "deployments.apps/checkout", Namespace: "pay"},
+		{Verb: "watch", Resource: "deployments.apps/checkout", Namespace: "pay"},
+		{Verb: "list", Resource: "replicasets.apps", Namespace: "pay"},
+		{Verb: "get", Resource: "configmaps/memory-watchdog", Namespace: "team-a"},
+		{Verb: "get", Resource: "secrets/prometheus-token", Namespace: "monitoring"},
+		{Verb: "get", Resource: "configmaps/watchdog-result", Namespace: "ops"},
+		{Verb: "create", Resource: "configmaps", Namespace: "ops"},
+		{Verb: "patch", Resource: "configmaps/watchdog-result", Namespace: "ops"},
+	}
+	permissions := requiredPermissions(config, nil)
+	if len(permissions) != len(expected) {
+		t.Fatalf("requiredPermissions() = %v, want %v", permissions, expected)
+	}
+	for i := range expected {
+		if permissions[i] != expected[i] {
+			t.Errorf("requiredPermissions()[%d] = %v, want %v", i, permissions[i], expected[i])
+		}
+	}
+
+	// the target in staging needs its permissions there only
+	staging := requiredPermissions(config, &watchdog.Cluster{Context: "staging"})
+	expected = []kubectl.Permission{
+		{Verb: "patch", Resource: "deployments.apps/checkout", Namespace: "pay"},
+		{Verb: "get", Resource: "deployments.apps/checkout", Namespace: "pay"},
+	}
+	if len(staging) != len(expected) || staging[0] != expected[0] || staging[1] != expected[1] {
+		t.Errorf("requiredPermissions(staging) = %v, want %v", staging, expected)
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// credential is a setting of the configuration that may refer to a file or
+// a Kubernetes Secret
+type credential struct {
+	name  string
+	value string
+}
+
+// credentials returns the credentials of the configuration, named after
+// their environment variable
+func credentials(config watchdog.Config) []credential {
+	credentials := []credential{
+		{"DD_API_KEY", config.Source.Datadog.APIKey},
+		{"DD_APP_KEY", config.Source.Datadog.AppKey},
+		{"PROMETHEUS_BEARER_TOKEN", config.Source.Prometheus.BearerToken},
+		{"NEW_RELIC_API_KEY", config.Source.NewRelic.APIKey},
+		{"CLOUDEVENTS_URL", config.Notifications.CloudEvents.URL},
+		{"NATS_URL", config.Notifications.NATS.URL},
+		{"NATS_TOKEN", config.Notifications.NATS.Token},
+		{"KAFKA_PASSWORD", config.Notifications.Kafka.Password},
+		{"HEARTBEAT_URL", config.Heartbeat.URL},
+		{"HTTP_BEARER_TOKEN", config.HTTP.BearerToken},
+		{"ADMIN_RESTART_TOKEN", config.Admin.RestartToken},
+		{"OIDC_CLIENT_SECRET", config.OIDC.ClientSecret},
+		{"OIDC_REFRESH_TOKEN", config.OIDC.RefreshToken},
+	}
+	for _, url := range config.Notifications.Alertmanager.URLs {
+		credentials = append(credentials, credential{"ALERTMANAGER_URLS", url})
+	}
+	return credentials
+}
+
+// setupSecrets makes the credentials of the configuration resolve
+// Kubernetes Secrets with reader, and reads every file and Secret they
+// refer to once, so a wrong reference fails at startup rather than at the
+// first use
+func setupSecrets(ctx context.Context, reader secret.Reader, config watchdog.Config) error {
+	refresh := config.SecretRefresh
+	if refresh <= 0 {
+		refresh = time.Minute
+	}
+	secret.SetDefault(secret.NewResolver(reader, refresh))
+	for _, c := range credentials(config) {
+		if _, err := secret.Resolve(ctx, c.value); err != nil {
+			return fmt.Errorf("Error reading %s: %v", c.name, err)
+		}
+	}
+	return nil
+}
+
+// secretRefs returns the namespace and name of the Kubernetes Secrets the
+// credentials of the configuration refer to
+func secretRefs(config watchdog.Config) [][2]string {
+	var refs [][2]string
+	for _, c := range credentials(config) {
+		if !strings.HasPrefix(c.value, secret.SecretPrefix) {
+			continue
+		}
+		if namespace, name, _, err := secret.ParseSecretRef(c.value); err == nil {
+			refs = append(refs, [2]string{namespace, name})
+		}
+	}
+	return refs
+}
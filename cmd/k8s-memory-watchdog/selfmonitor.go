@@ -0,0 +1,113 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// selfMonitorInterval is how often the watchdog looks for stalled targets
+const selfMonitorInterval = 30 * time.Second
+
+// lastChecker reports when a target's memory usage was last read
+type lastChecker interface {
+	LastCheck() time.Time
+}
+
+// registerSelfMetrics exposes the watchdog's own goroutines, memory and
+// progress as metrics read at scrape time
+func registerSelfMetrics(collector *telemetry.Telemetry, w interface {
+	stallChecker
+	lastChecker
+}) {
+	collector.RegisterFunc(telemetry.MetricGoroutines, "gauge", "Number of goroutines of the watchdog",
+		func() float64 { return float64(runtime.NumGoroutine()) })
+	collector.RegisterFunc(telemetry.MetricHeapInUse, "gauge", "Heap memory in use by the watchdog in bytes",
+		func() float64 {
+			var stats runtime.MemStats
+			runtime.ReadMemStats(&stats)
+			return float64(stats.HeapInuse)
+		})
+	collector.RegisterFunc(telemetry.MetricSinceLastCheck, "gauge", "Seconds since a target's memory usage was last read",
+		func() float64 {
+			last := w.LastCheck()
+			if last.IsZero() {
+				return 0
+			}
+			return time.Since(last).Seconds()
+		})
+	collector.RegisterFunc(telemetry.MetricStalledTargets, "gauge", "Number of targets whose checks are stalled",
+		func() float64 { return float64(len(w.Stalled(time.Now()))) })
+}
+
+// readyHandler fails while the checks of a target are stalled, so the pod
+// is reported unready
+func readyHandler(w stallChecker) http.Handler {
+	return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if stalled := w.Stalled(time.Now()); len(stalled) > 0 {
+			http.Error(rw, fmt.Sprintf("stalled: %s", strings.Join(stalled, ", ")), http.StatusServiceUnavailable)
+			return
+		}
+		rw.Write([]byte("ok\n"))
+	})
+}
+
+// selfMonitor logs the targets whose checks stall or recover and, with
+// exitOnStall, exits once one is stalled so that the watchdog is restarted
+type selfMonitor struct {
+	checker     stallChecker
+	exitOnStall bool
+	exit        func(code int)
+	stalled     map[string]bool
+}
+
+func newSelfMonitor(checker stallChecker, exitOnStall bool) *selfMonitor {
+	return &selfMonitor{
+		checker:     checker,
+		exitOnStall: exitOnStall,
+		exit:        os.Exit,
+		stalled:     make(map[string]bool),
+	}
+}
+
+// check looks for stalled targets at now
+func (m *selfMonitor) check(now time.Time) {
+	stalled := make(map[string]bool)
+	for _, name := range m.checker.Stalled(now) {
+		stalled[name] = true
+		if !m.stalled[name] {
+			logger.Errorf("The checks of target '%s' are stalled, the watchdog is not monitoring it", name)
+		}
+	}
+	for name := range m.stalled {
+		if !stalled[name] {
+			logger.Infof("The checks of target '%s' recovered", name)
+		}
+	}
+	m.stalled = stalled
+
+	if len(stalled) > 0 && m.exitOnStall {
+		logger.Errorf("Exiting because checks are stalled")
+		m.exit(1)
+	}
+}
+
+// run checks for stalled targets until ctx is cancelled
+func (m *selfMonitor) run(ctx context.Context) {
+	ticker := time.NewTicker(selfMonitorInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			m.check(now)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+type fakeStallChecker struct {
+	stalled []string
+}
+
+func (f *fakeStallChecker) Stalled(now time.Time) []string {
+	return f.stalled
+}
+
+func TestReadyHandler(t *testing.T) {
+	tests := []struct {
+		name       string
+		stalled    []string
+		wantStatus int
+	}{
+		{name: "ready", wantStatus: http.StatusOK},
+		{name: "stalled", stalled: []string{"prod/api"}, wantStatus: http.StatusServiceUnavailable},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			readyHandler(&fakeStallChecker{stalled: tt.stalled}).ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+			if rec.Code != tt.wantStatus {
+				t.Errorf("status = %d, want %d", rec.Code, tt.wantStatus)
+			}
+		})
+	}
+}
+
+func TestSelfMonitor(t *testing.T) {
+	tests := []struct {
+		name        string
+		stalled     []string
+		exitOnStall bool
+		wantExit    bool
+	}{
+		{name: "healthy", exitOnStall: true},
+		{name: "stalled", stalled: []string{"prod/api"}},
+		{name: "stalled with exit", stalled: []string{"prod/api"}, exitOnStall: true, wantExit: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			monitor := newSelfMonitor(&fakeStallChecker{stalled: tt.stalled}, tt.exitOnStall)
+			exited := false
+			monitor.exit = func(code int) { exited = true }
+			monitor.check(time.Now())
+			if exited != tt.wantExit {
+				t.Errorf("exited = %v, want %v", exited, tt.wantExit)
+			}
+			if len(monitor.stalled) != len(tt.stalled) {
+				t.Errorf("stalled = %v, want %v", monitor.stalled, tt.stalled)
+			}
+		})
+	}
+}
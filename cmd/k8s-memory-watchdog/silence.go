@@ -0,0 +1,110 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// runSilence implements the silence and unsilence subcommands, which mute
+// or unmute the notifications of a target through the admin API of a
+// running watchdog, and returns the process exit code
+func runSilence(command string, args []string) int {
+	fs := flag.NewFlagSet(command, flag.ExitOnError)
+	adminURL := adminURLFlag(fs)
+	token := fs.String("token", getEnv("ADMIN_RESTART_TOKEN", ""), "Restart token of the admin API")
+	user := fs.String("user", getEnv("USER", ""), "Who acts on the target, for the audit log")
+	reason := fs.String("reason", "", "Why the target is "+command+"d, for the audit log")
+	var duration time.Duration
+	if command == "silence" {
+		fs.DurationVar(&duration, "for", time.Hour, "How long the target is silenced")
+	}
+	fs.Usage = func() {
+		fmt.Fprintf(fs.Output(), "usage: k8s-memory-watchdog %s [flags] <target>\n", command)
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return 2
+	}
+	target := fs.Arg(0)
+
+	body := map[string]string{"user": *user, "reason": *reason}
+	if command == "silence" {
+		body["duration"] = duration.String()
+	}
+	silence, err := postSilence(http.DefaultClient, *adminURL, command, target, *token, body)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if command == "silence" {
+		fmt.Printf("Silenced %s until %s\n", target, silence.Until.Local().Format(time.RFC3339))
+	} else {
+		fmt.Printf("Lifted the silence of %s\n", target)
+	}
+	return 0
+}
+
+// postSilence sends a silence or unsilence request to the admin API and
+// returns the silence it answers with
+func postSilence(client *http.Client, adminURL, command, target, token string, body map[string]string) (watchdog.Silence, error) {
+	var silence watchdog.Silence
+	if err := postOperatorAction(client, adminURL, command, target, token, body, &silence); err != nil {
+		return watchdog.Silence{}, err
+	}
+	return silence, nil
+}
+
+// postOperatorAction sends the request of an operator action, such as a
+// silence or pause, to the admin API and decodes the answer into out
+// unless there is none
+func postOperatorAction(client *http.Client, adminURL, command, target, token string, body map[string]string, out interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(adminURL, "/")+"/"+command+"/"+target, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return fmt.Errorf("%s of '%s' failed: %s", command, target, apiErr.Error)
+		}
+		return fmt.Errorf("%s of '%s' failed: %s", command, target, resp.Status)
+	}
+
+	if resp.StatusCode == http.StatusOK {
+		if err := json.Unmarshal(data, out); err != nil {
+			return fmt.Errorf("invalid response: %v", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestPostSilence(t *testing.T) {
+	var path, auth string
+	var body map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, auth = r.URL.Path, r.Header.Get("Authorization")
+		json.NewDecoder(r.Body).Decode(&body)
+		switch {
+		case strings.HasSuffix(path, "/missing"):
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"target not found: 'prod/missing'"}`))
+		case strings.HasPrefix(path, "/unsilence/"):
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.Write([]byte(`{"until":"2024-03-01T16:00:00Z","reason":"silence by alice"}`))
+		}
+	}))
+	defer server.Close()
+
+	silence, err := postSilence(server.Client(), server.URL+"/", "silence", "prod/api", "secret", map[string]string{"user": "alice", "duration": "4h"})
+	if err != nil {
+		t.Fatalf("postSilence() error = %v", err)
+	}
+	if path != "/silence/prod/api" || auth != "Bearer secret" || body["duration"] != "4h" {
+		t.Errorf("request = %s %q %v, want the target, token and duration", path, auth, body)
+	}
+	if !silence.Until.Equal(time.Date(2024, 3, 1, 16, 0, 0, 0, time.UTC)) {
+		t.Errorf("postSilence() = %+v, want the silence of the response", silence)
+	}
+
+	if _, err := postSilence(server.Client(), server.URL, "unsilence", "prod/api", "secret", nil); err != nil {
+		t.Errorf("postSilence() unsilence error = %v", err)
+	}
+	if _, err := postSilence(server.Client(), server.URL, "silence", "prod/missing", "secret", nil); err == nil || !strings.Contains(err.Error(), "target not found") {
+		t.Errorf("postSilence() error = %v, want the error of the API", err)
+	}
+
+	var pause watchdog.Pause
+	if err := postOperatorAction(server.Client(), server.URL, "pause", "prod/api", "secret", map[string]string{"duration": "2h"}, &pause); err != nil {
+		t.Fatalf("postOperatorAction() error = %v", err)
+	}
+	if path != "/pause/prod/api" || !pause.Until.Equal(time.Date(2024, 3, 1, 16, 0, 0, 0, time.UTC)) {
+		t.Errorf("postOperatorAction() = %s, %+v, want the pause of the response", path, pause)
+	}
+}
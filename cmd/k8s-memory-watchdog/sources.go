@@ -0,0 +1,179 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/awsauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/azureauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/gcpauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/oidcauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/metrics"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// sourceTypes are the metrics sources newMetricsSource can create
+var sourceTypes = []string{"kubectl", "datadog", "cloudwatch", "prometheus", "newrelic", "custom", "external", "scrape"}
+
+// newMetricsSource creates a metrics provider of the given type, configured
+// by the source configuration
+func newMetricsSource(sourceType string, config watchdog.Config, runner *kubectl.Runner) (watchdog.MetricsProvider, error) {
+	switch sourceType {
+	case "", "kubectl":
+		return metrics.NewKubectlSource(runner, config.Source.Kubectl), nil
+	case "datadog":
+		if config.Source.Datadog.APIKey == "" || config.Source.Datadog.AppKey == "" {
+			return nil, fmt.Errorf("the datadog source requires DD_API_KEY and DD_APP_KEY")
+		}
+		return metrics.NewDatadogSource(config.Source.Datadog), nil
+	case "cloudwatch":
+		cw := config.Source.CloudWatch
+		if cw.Region == "" {
+			cw.Region = awsauth.RegionFromEnv()
+		}
+		if cw.Region == "" || cw.ClusterName == "" {
+			return nil, fmt.Errorf("the cloudwatch source requires AWS_REGION and CLOUDWATCH_CLUSTER_NAME")
+		}
+		creds, err := awsauth.CredentialsFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("the cloudwatch source requires AWS credentials: %v", err)
+		}
+		return metrics.NewCloudWatchSource(cw, creds), nil
+	case "prometheus":
+		if config.Source.Prometheus.URL == "" {
+			return nil, fmt.Errorf("the prometheus source requires PROMETHEUS_URL")
+		}
+		auth, err := newPrometheusAuthorizer(config.Source.Prometheus)
+		if err != nil {
+			return nil, err
+		}
+		return metrics.NewPrometheusSource(config.Source.Prometheus, auth), nil
+	case "newrelic":
+		if config.Source.NewRelic.APIKey == "" || config.Source.NewRelic.AccountID == 0 {
+			return nil, fmt.Errorf("the newrelic source requires NEW_RELIC_API_KEY and NEW_RELIC_ACCOUNT_ID")
+		}
+		return metrics.NewNewRelicSource(config.Source.NewRelic), nil
+	case "custom":
+		if config.Source.Custom.Metric == "" {
+			return nil, fmt.Errorf("the custom source requires CUSTOM_METRIC")
+		}
+		return metrics.NewCustomMetricsSource(runner, config.Source.Custom), nil
+	case "external":
+		if config.Source.External.Metric == "" {
+			return nil, fmt.Errorf("the external source requires EXTERNAL_METRIC")
+		}
+		return metrics.NewExternalMetricsSource(runner, config.Source.External), nil
+	case "scrape":
+		return metrics.NewScrapeSource(runner, config.Source.Scrape)
+	default:
+		return nil, fmt.Errorf("unknown metrics source '%s'", sourceType)
+	}
+}
+
+// sourceChainNames returns the distinct sources listed in the source chains
+// of targets
+func sourceChainNames(targets []watchdog.Target) []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, t := range targets {
+		for _, name := range t.Sources {
+			if !seen[name] {
+				seen[name] = true
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// signalThresholds tells whether any of targets has a swap threshold, and
+// whether any has a CPU throttling threshold
+func signalThresholds(targets []watchdog.Target) (swap, throttling bool) {
+	for _, t := range targets {
+		swap = swap || t.SwapThreshold > 0
+		throttling = throttling || t.CPUThrottling > 0
+	}
+	return swap, throttling
+}
+
+// newSignalSource creates the source of the swap usage and the CPU
+// throttling, which are read from Prometheus whatever the metric source of
+// the targets
+func newSignalSource(config watchdog.Config) (*metrics.PrometheusSource, error) {
+	if config.Source.Prometheus.URL == "" {
+		return nil, fmt.Errorf("swap_threshold and cpu_throttling require PROMETHEUS_URL to read cAdvisor metrics")
+	}
+	auth, err := newPrometheusAuthorizer(config.Source.Prometheus)
+	if err != nil {
+		return nil, err
+	}
+	return metrics.NewPrometheusSource(config.Source.Prometheus, auth), nil
+}
+
+// newPrometheusAuthorizer creates the authorizer selected by the Prometheus
+// source configuration
+func newPrometheusAuthorizer(config watchdog.PrometheusConfig) (metrics.Authorizer, error) {
+	switch config.Auth {
+	case "", "bearer":
+		return metrics.BearerToken(config.BearerToken), nil
+	case "sigv4":
+		region := config.Region
+		if region == "" {
+			region = awsauth.RegionFromEnv()
+		}
+		creds, err := awsauth.CredentialsFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("sigv4 authentication requires AWS credentials: %v", err)
+		}
+		return metrics.SigV4{Signer: awsauth.Signer{Credentials: creds, Region: region, Service: "aps"}}, nil
+	case "google":
+		tokens, err := gcpauth.DefaultTokenSource("https://www.googleapis.com/auth/monitoring.read")
+		if err != nil {
+			return nil, fmt.Errorf("google authentication: %v", err)
+		}
+		return metrics.GoogleOAuth{Tokens: tokens}, nil
+	default:
+		return nil, fmt.Errorf("unknown prometheus authentication '%s'", config.Auth)
+	}
+}
+
+// newRunner creates the kubectl runner of the configuration, which
+// authenticates with IAM tokens when it targets an EKS cluster, or with
+// Google access tokens for a GKE cluster, with Azure AD tokens for an AKS
+// cluster, or with the ID tokens of an OIDC provider
+func newRunner(config watchdog.Config) (*kubectl.Runner, error) {
+	runner := kubectl.NewRunner(config.KubectlPath, config.KubeQPS, config.KubeBurst)
+	runner.SetContext(config.KubeContext)
+	runner.SetProxy(config.Proxy.URL, config.Proxy.NoProxy)
+	runner.SetCertificateAuthority(config.Proxy.KubeCAFile)
+	if config.EKS.Cluster != "" {
+		creds, err := awsauth.CredentialsFromEnv()
+		if err != nil {
+			return nil, fmt.Errorf("EKS authentication requires AWS credentials: %v", err)
+		}
+		region := config.EKS.Region
+		if region == "" {
+			region = awsauth.RegionFromEnv()
+		}
+		runner.SetTokenSource(&awsauth.EKSTokenSource{Credentials: creds, Region: region, Cluster: config.EKS.Cluster})
+	}
+	if config.GKE.Enabled {
+		tokens, err := gcpauth.DefaultTokenSource("https://www.googleapis.com/auth/cloud-platform", "https://www.googleapis.com/auth/userinfo.email")
+		if err != nil {
+			return nil, fmt.Errorf("GKE authentication: %v", err)
+		}
+		runner.SetTokenSource(tokens)
+	}
+	if config.AKS.Enabled {
+		tokens, err := azureauth.DefaultTokenSource(azureauth.AKSScope)
+		if err != nil {
+			return nil, fmt.Errorf("AKS authentication: %v", err)
+		}
+		runner.SetTokenSource(tokens)
+	}
+	if config.OIDC.IssuerURL != "" {
+		oidc := config.OIDC
+		runner.SetTokenSource(oidcauth.NewRefreshSource(oidc.IssuerURL, oidc.ClientID, oidc.ClientSecret, oidc.RefreshToken))
+	}
+	return runner, nil
+}
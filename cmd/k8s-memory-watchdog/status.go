@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// adminURLFlag registers the --url flag of the subcommands calling the
+// admin API, and --addr, its deprecated alias
+func adminURLFlag(fs *flag.FlagSet) *string {
+	adminURL := fs.String("url", getEnv("ADMIN_URL", "http://localhost:9090"), "Base URL of the admin API of the watchdog")
+	fs.StringVar(adminURL, "addr", *adminURL, "Base URL of the admin API of the watchdog (deprecated, use --url)")
+	return adminURL
+}
+
+// runStatus implements the status subcommand, which prints a table of the
+// targets of a running watchdog from its admin API, and returns the process
+// exit code
+func runStatus(args []string) int {
+	fs := flag.NewFlagSet("status", flag.ExitOnError)
+	addr := adminURLFlag(fs)
+	token := fs.String("token", getEnv("HTTP_BEARER_TOKEN", ""), "Bearer token of the HTTP server of the watchdog, if it requires one")
+	fs.Parse(args)
+	if fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	statuses, err := fetchStatus(http.DefaultClient, *addr, *token)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	writeStatusTable(os.Stdout, statuses, time.Now())
+	return 0
+}
+
+// fetchStatus gets the status of the targets from the admin API
+func fetchStatus(client *http.Client, adminURL, token string) ([]watchdog.TargetStatus, error) {
+	req, err := http.NewRequest(http.MethodGet, strings.TrimSuffix(adminURL, "/")+"/status", nil)
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return nil, fmt.Errorf("status failed: %s", apiErr.Error)
+		}
+		return nil, fmt.Errorf("status failed: %s", resp.Status)
+	}
+
+	var statuses []watchdog.TargetStatus
+	if err := json.Unmarshal(data, &statuses); err != nil {
+		return nil, fmt.Errorf("invalid response: %v", err)
+	}
+	return statuses, nil
+}
+
+// targetState summarizes the status of a target: paused, pending until its
+// first check, error when the check failed, warming up when every pod was
+// too young to check, breaching over the threshold, cooldown when under the
+// threshold again but not yet recovered, or ok
+func targetState(s watchdog.TargetStatus) string {
+	var state string
+	switch {
+	case s.Paused:
+		state = "paused"
+	case s.LastResult == nil:
+		state = "pending"
+	case s.LastResult.Err != nil:
+		state = "error"
+	case s.LastResult.WarmingUp:
+		state = "warming up"
+	case s.CircuitOpenSince != nil:
+		state = "circuit open"
+	case s.LastResult.Breached:
+		state = "breaching"
+	case s.BreachedSince != nil:
+		state = "cooldown"
+	default:
+		state = "ok"
+	}
+	if s.Pause != nil && !s.Pause.Until.IsZero() {
+		state += " until " + s.Pause.Until.Local().Format("15:04")
+	}
+	if s.Silence != nil {
+		state += " (silenced)"
+	}
+	return state
+}
+
+// writeStatusTable writes a row per target with its usage, threshold,
+// state and last action
+func writeStatusTable(w io.Writer, statuses []watchdog.TargetStatus, now time.Time) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TARGET\tUSAGE\tTHRESHOLD\tSTATE\tLAST ACTION\tLAST CHECK")
+	for _, s := range statuses {
+		usage, threshold, lastCheck := "-", fmt.Sprintf("%dMi", s.Target.MemoryThreshold), "-"
+		if last := s.LastResult; last != nil {
+			if last.Err == nil && !last.WarmingUp {
+				usage = fmt.Sprintf("%dMi", last.Memory)
+				if last.Threshold > 0 {
+					usage += fmt.Sprintf(" (%d%%)", last.Memory*100/last.Threshold)
+				}
+			}
+			threshold = fmt.Sprintf("%dMi", last.Threshold)
+			lastCheck = ago(now, last.Time)
+		}
+		lastAction := "-"
+		if s.LastAction != nil {
+			lastAction = s.LastAction.Action + " " + ago(now, s.LastAction.Time)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", s.Target.Name, usage, threshold, targetState(s), lastAction, lastCheck)
+	}
+	tw.Flush()
+}
+
+func ago(now, t time.Time) string {
+	return now.Sub(t).Round(time.Second).String() + " ago"
+}
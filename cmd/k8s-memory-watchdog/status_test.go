@@ -0,0 +1,94 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestFetchStatus(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	var auth string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		auth = r.Header.Get("Authorization")
+		if r.URL.Path != "/status" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		json.NewEncoder(w).Encode([]watchdog.TargetStatus{{
+			Target:     watchdog.Target{Name: "prod/worker"},
+			LastResult: &watchdog.CheckResult{Time: now, Err: errors.New("metrics unavailable")},
+		}})
+	}))
+	defer server.Close()
+
+	statuses, err := fetchStatus(server.Client(), server.URL+"/", "secret")
+	if err != nil {
+		t.Fatalf("fetchStatus() error = %v", err)
+	}
+	if auth != "Bearer secret" {
+		t.Errorf("Authorization = %q, want the token", auth)
+	}
+	if len(statuses) != 1 || statuses[0].LastResult == nil || statuses[0].LastResult.Err == nil {
+		t.Fatalf("fetchStatus() = %+v, want the failed check of prod/worker", statuses)
+	}
+
+	unauthorized := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+		w.Write([]byte(`{"error":"invalid bearer token"}`))
+	}))
+	defer unauthorized.Close()
+	if _, err := fetchStatus(unauthorized.Client(), unauthorized.URL, ""); err == nil || !strings.Contains(err.Error(), "invalid bearer token") {
+		t.Errorf("fetchStatus() error = %v, want the error of the API", err)
+	}
+}
+
+func TestWriteStatusTable(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	since := now.Add(-10 * time.Minute)
+	restart := &watchdog.CheckResult{Memory: 3000, Threshold: 2000, Breached: true, Action: "restart", Time: since}
+	statuses := []watchdog.TargetStatus{
+		{Target: watchdog.Target{Name: "prod/api"}, LastResult: &watchdog.CheckResult{Memory: 1500, Threshold: 2000, Time: now.Add(-30 * time.Second)}},
+		{Target: watchdog.Target{Name: "prod/web"}, LastResult: restart, LastAction: restart, BreachedSince: &since},
+		{Target: watchdog.Target{Name: "prod/cache"}, LastResult: &watchdog.CheckResult{Memory: 1900, Threshold: 2000, Time: now}, LastAction: restart, BreachedSince: &since},
+		{Target: watchdog.Target{Name: "prod/batch", MemoryThreshold: 4000}, Paused: true},
+		{Target: watchdog.Target{Name: "prod/worker"}, LastResult: &watchdog.CheckResult{Threshold: 2000, Time: now, Err: errors.New("forbidden")},
+			Silence: &watchdog.Silence{Until: now.Add(time.Hour)}},
+	}
+
+	var b strings.Builder
+	writeStatusTable(&b, statuses, now)
+	want := `TARGET       USAGE          THRESHOLD  STATE             LAST ACTION        LAST CHECK
+prod/api     1500Mi (75%)   2000Mi     ok                -                  30s ago
+prod/web     3000Mi (150%)  2000Mi     breaching         restart 10m0s ago  10m0s ago
+prod/cache   1900Mi (95%)   2000Mi     cooldown          restart 10m0s ago  0s ago
+prod/batch   -              4000Mi     paused            -                  -
+prod/worker  -              2000Mi     error (silenced)  -                  0s ago
+`
+	if b.String() != want {
+		t.Errorf("writeStatusTable() =\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestAdminURLFlag(t *testing.T) {
+	for _, args := range [][]string{
+		{"--url", "http://watchdog:9090"},
+		{"--addr", "http://watchdog:9090"},
+	} {
+		fs := flag.NewFlagSet("status", flag.ContinueOnError)
+		adminURL := adminURLFlag(fs)
+		if err := fs.Parse(args); err != nil {
+			t.Fatal(err)
+		}
+		if *adminURL != "http://watchdog:9090" {
+			t.Errorf("%s = %q, want http://watchdog:9090", args[0], *adminURL)
+		}
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/sdnotify"
+)
+
+// stallChecker reports the targets whose monitoring loop stopped
+type stallChecker interface {
+	Stalled(now time.Time) []string
+}
+
+// notifySystemd signals readiness to systemd and, when WatchdogSec is set,
+// pings its watchdog at half the timeout for as long as no target's loop
+// is stalled, so systemd restarts a hung watchdog. It does nothing outside
+// of a Type=notify service.
+func notifySystemd(ctx context.Context, w stallChecker) {
+	if sent, err := sdnotify.Notify("READY=1"); err != nil {
+		logger.Errorf("Error notifying systemd: %v", err)
+		return
+	} else if !sent {
+		return
+	}
+
+	timeout, ok := sdnotify.WatchdogInterval()
+	if !ok {
+		return
+	}
+	ticker := time.NewTicker(timeout / 2)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			sdnotify.Notify("STOPPING=1")
+			return
+		case now := <-ticker.C:
+			if stalled := w.Stalled(now); len(stalled) > 0 {
+				logger.Warnf("Not pinging the systemd watchdog, the checks of %s are stalled", strings.Join(stalled, ", "))
+				continue
+			}
+			if _, err := sdnotify.Notify("WATCHDOG=1"); err != nil {
+				logger.Errorf("Error pinging the systemd watchdog: %v", err)
+			}
+		}
+	}
+}
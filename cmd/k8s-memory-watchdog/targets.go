@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// resourceLookup reports whether a Kubernetes resource exists
+type resourceLookup interface {
+	Exists(ctx context.Context, resource, name, namespace string) (bool, error)
+}
+
+// missingTargets returns a problem for every target whose namespace or
+// deployment doesn't exist, so a typo in the configuration is caught at
+// startup rather than read as an empty namespace
+func missingTargets(ctx context.Context, lookup resourceLookup, targets []watchdog.Target) ([]string, error) {
+	namespaces := make(map[string]bool)
+	var problems []string
+	for _, target := range targets {
+		// each target is looked up in its own cluster
+		ctx := watchdog.ContextWithCluster(ctx, target.Cluster)
+		namespace := target.Namespace
+		if target.Cluster != nil {
+			namespace = target.Cluster.String() + "/" + namespace
+		}
+		exists, checked := namespaces[namespace]
+		if !checked {
+			var err error
+			exists, err = lookup.Exists(ctx, "namespace", target.Namespace, "")
+			if err != nil {
+				return nil, fmt.Errorf("looking up namespace '%s': %w", target.Namespace, err)
+			}
+			namespaces[namespace] = exists
+			if !exists {
+				problems = append(problems, fmt.Sprintf("namespace '%s' of target '%s' doesn't exist", target.Namespace, target.Name))
+			}
+		}
+		if !exists {
+			continue
+		}
+
+		exists, err := lookup.Exists(ctx, "deployment", target.DeploymentName, target.Namespace)
+		if err != nil {
+			return nil, fmt.Errorf("looking up deployment '%s': %w", target.DeploymentName, err)
+		}
+		if !exists {
+			problems = append(problems, fmt.Sprintf("deployment '%s' of target '%s' doesn't exist in namespace '%s'",
+				target.DeploymentName, target.Name, target.Namespace))
+		}
+	}
+	return problems, nil
+}
+
+// validateTargets looks up the targets according to mode: off, warn, which
+// logs the missing ones, or fail, which also returns an error
+func validateTargets(ctx context.Context, lookup resourceLookup, targets []watchdog.Target, mode string) error {
+	switch mode {
+	case "off":
+		return nil
+	case "warn", "fail":
+	default:
+		return fmt.Errorf("invalid target validation mode '%s', must be off, warn or fail", mode)
+	}
+
+	problems, err := missingTargets(ctx, lookup, targets)
+	if err != nil {
+		if mode == "fail" {
+			return err
+		}
+		logger.Warnf("Could not validate targets: %v", err)
+		return nil
+	}
+	for _, problem := range problems {
+		logger.Warnf("%s", problem)
+	}
+	if len(problems) > 0 && mode == "fail" {
+		return fmt.Errorf("%d targets are misconfigured, fix them or use --target-validation=warn", len(problems))
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// fakeLookup finds the resources it lists, as "resource/namespace/name",
+// prefixed with "context:" for the resources of a context other than the
+// default one
+type fakeLookup map[string]bool
+
+func (f fakeLookup) Exists(ctx context.Context, resource, name, namespace string) (bool, error) {
+	if name == "broken" {
+		return false, errors.New("connection refused")
+	}
+	if cluster := watchdog.ClusterFromContext(ctx); cluster != nil {
+		resource = cluster.Context + ":" + resource
+	}
+	return f[resource+"/"+namespace+"/"+name], nil
+}
+
+func TestValidateTargets(t *testing.T) {
+	lookup := fakeLookup{
+		"namespace//prod":      true,
+		"deployment/prod/api":  true,
+		"deployment/prod/jobs": false,
+	}
+	targets := []watchdog.Target{
+		{Name: "prod/api", Namespace: "prod", DeploymentName: "api"},
+		{Name: "prod/wroker", Namespace: "prod", DeploymentName: "wroker"},
+		{Name: "porod/api", Namespace: "porod", DeploymentName: "api"},
+		{Name: "porod/jobs", Namespace: "porod", DeploymentName: "jobs"},
+		{Name: "staging/api", Namespace: "prod", DeploymentName: "api", Cluster: &watchdog.Cluster{Context: "staging"}},
+	}
+
+	problems, err := missingTargets(context.Background(), lookup, targets)
+	if err != nil {
+		t.Fatalf("missingTargets() error = %v", err)
+	}
+	expected := []string{
+		"deployment 'wroker' of target 'prod/wroker' doesn't exist in namespace 'prod'",
+		"namespace 'porod' of target 'porod/api' doesn't exist",
+		"namespace 'prod' of target 'staging/api' doesn't exist",
+	}
+	if len(problems) != len(expected) || problems[0] != expected[0] || problems[1] != expected[1] || problems[2] != expected[2] {
+		t.Errorf("missingTargets() = %q, want %q", problems, expected)
+	}
+
+	tests := []struct {
+		mode    string
+		targets []watchdog.Target
+		wantErr bool
+	}{
+		{mode: "off", targets: targets},
+		{mode: "warn", targets: targets},
+		{mode: "fail", targets: targets, wantErr: true},
+		{mode: "fail", targets: targets[:1]},
+		{mode: "warn", targets: []watchdog.Target{{Namespace: "broken"}}},
+		{mode: "fail", targets: []watchdog.Target{{Namespace: "broken"}}, wantErr: true},
+		{mode: "strict", wantErr: true},
+	}
+	for _, tt := range tests {
+		if err := validateTargets(context.Background(), lookup, tt.targets, tt.mode); (err != nil) != tt.wantErr {
+			t.Errorf("validateTargets(%s, %d targets) error = %v, want error %v", tt.mode, len(tt.targets), err, tt.wantErr)
+		}
+	}
+}
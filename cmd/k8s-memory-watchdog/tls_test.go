@@ -0,0 +1,72 @@
+package main
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// writeCA writes a self-signed CA certificate to a PEM file
+func writeCA(t *testing.T) string {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "control-plane CA"},
+		NotBefore:             time.Now(),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestServerTLSConfig(t *testing.T) {
+	config, err := serverTLSConfig(watchdog.TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key"})
+	if config != nil || err != nil {
+		t.Errorf("serverTLSConfig() = %v, %v, want nil without a client CA", config, err)
+	}
+
+	ca := writeCA(t)
+	config, err = serverTLSConfig(watchdog.TLSConfig{CertFile: "tls.crt", KeyFile: "tls.key", ClientCAFile: ca})
+	if err != nil {
+		t.Fatalf("serverTLSConfig() error = %v", err)
+	}
+	if config.ClientAuth != tls.RequireAndVerifyClientCert || config.ClientCAs == nil {
+		t.Errorf("serverTLSConfig() = %+v, want required client certificates", config)
+	}
+
+	empty := filepath.Join(t.TempDir(), "empty.pem")
+	os.WriteFile(empty, []byte("not a certificate"), 0600)
+	for _, invalid := range []watchdog.TLSConfig{
+		{ClientCAFile: ca},
+		{CertFile: "tls.crt", KeyFile: "tls.key", ClientCAFile: empty},
+		{CertFile: "tls.crt", KeyFile: "tls.key", ClientCAFile: filepath.Join(t.TempDir(), "missing.pem")},
+	} {
+		if _, err := serverTLSConfig(invalid); err == nil {
+			t.Errorf("serverTLSConfig(%+v) error = nil, want an error", invalid)
+		}
+	}
+}
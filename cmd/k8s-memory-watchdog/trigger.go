@@ -0,0 +1,145 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// runTrigger implements the trigger subcommand, which checks a target of a
+// running watchdog immediately through its admin API and prints the result,
+// and returns the process exit code: 1 when the check failed or the target
+// breached its threshold, so it can gate a release
+func runTrigger(args []string) int {
+	fs := flag.NewFlagSet("trigger", flag.ExitOnError)
+	addr := adminURLFlag(fs)
+	token := fs.String("token", getEnv("ADMIN_RESTART_TOKEN", getEnv("HTTP_BEARER_TOKEN", "")), "Restart token of the admin API of the watchdog, which the HTTP server accepts too")
+	target := fs.String("target", "", "Target to check, by name or deployment")
+	output := fs.String("output", "text", "Output format, text or json")
+	fs.Parse(args)
+	if *target == "" || fs.NArg() != 0 || (*output != "text" && *output != "json") {
+		fs.Usage()
+		return 2
+	}
+
+	name, err := resolveTargetName(http.DefaultClient, *addr, *token, *target)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	result, err := postCheck(http.DefaultClient, *addr, *token, name)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	if *output == "json" {
+		data, err := json.MarshalIndent(result, "", "  ")
+		if err != nil {
+			log.Print(err)
+			return 1
+		}
+		os.Stdout.Write(append(data, '\n'))
+	} else {
+		fmt.Println(describeResult(result))
+	}
+	if result.Err != nil || result.Breached {
+		return 1
+	}
+	return 0
+}
+
+// resolveTargetName returns the name of the target of the watchdog given by
+// name or by deployment. Names with a slash are used as they are.
+func resolveTargetName(client *http.Client, adminURL, token, target string) (string, error) {
+	if strings.Contains(target, "/") {
+		return target, nil
+	}
+	statuses, err := fetchStatus(client, adminURL, token)
+	if err != nil {
+		return "", err
+	}
+	var matches []string
+	for _, s := range statuses {
+		if s.Target.Name == target {
+			return target, nil
+		}
+		if s.Target.DeploymentName == target {
+			matches = append(matches, s.Target.Name)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no target named '%s'", target)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("deployment '%s' is ambiguous, use one of the targets %s", target, strings.Join(matches, ", "))
+	}
+}
+
+// postCheck asks the admin API to check a target and returns the result it
+// answers with, including that of a failed check
+func postCheck(client *http.Client, adminURL, token, target string) (watchdog.CheckResult, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(adminURL, "/")+"/check/"+target, nil)
+	if err != nil {
+		return watchdog.CheckResult{}, err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return watchdog.CheckResult{}, err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 64<<10))
+	if err != nil {
+		return watchdog.CheckResult{}, err
+	}
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusBadGateway {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if json.Unmarshal(data, &apiErr) == nil && apiErr.Error != "" {
+			return watchdog.CheckResult{}, fmt.Errorf("check of '%s' failed: %s", target, apiErr.Error)
+		}
+		return watchdog.CheckResult{}, fmt.Errorf("check of '%s' failed: %s", target, resp.Status)
+	}
+
+	var result watchdog.CheckResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return watchdog.CheckResult{}, fmt.Errorf("invalid response: %v", err)
+	}
+	return result, nil
+}
+
+// describeResult summarizes a check result on one line
+func describeResult(r watchdog.CheckResult) string {
+	if r.Err != nil {
+		return fmt.Sprintf("%s: check failed: %v", r.Target.Name, r.Err)
+	}
+	s := fmt.Sprintf("%s: %dMi of %dMi", r.Target.Name, r.Memory, r.Threshold)
+	if r.Threshold > 0 {
+		s += fmt.Sprintf(" (%d%%)", r.Memory*100/r.Threshold)
+	}
+	switch {
+	case r.Breached && r.Action != "":
+		s += ", breached, " + r.Action + " triggered"
+	case r.Breached:
+		s += ", breached"
+	default:
+		s += ", ok"
+	}
+	if r.CheckID != "" {
+		s += " [check " + r.CheckID + "]"
+	}
+	return s
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// newAdminServer serves the status and check endpoints of an admin API
+// with the targets of results
+func newAdminServer(results map[string]watchdog.CheckResult) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/status" {
+			var statuses []watchdog.TargetStatus
+			for _, result := range results {
+				statuses = append(statuses, watchdog.TargetStatus{Target: result.Target})
+			}
+			json.NewEncoder(w).Encode(statuses)
+			return
+		}
+		result, ok := results[strings.TrimPrefix(r.URL.Path, "/check/")]
+		switch {
+		case r.Method != http.MethodPost:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case !ok:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`{"error":"target not found"}`))
+		case result.Err != nil:
+			w.WriteHeader(http.StatusBadGateway)
+			json.NewEncoder(w).Encode(result)
+		default:
+			json.NewEncoder(w).Encode(result)
+		}
+	}))
+}
+
+func TestPostCheck(t *testing.T) {
+	server := newAdminServer(map[string]watchdog.CheckResult{
+		"prod/api":    {Target: watchdog.Target{Name: "prod/api"}, Memory: 1200, Threshold: 2000},
+		"prod/worker": {Target: watchdog.Target{Name: "prod/worker"}, Err: errors.New("error getting memory usage: metrics unavailable")},
+	})
+	defer server.Close()
+
+	result, err := postCheck(server.Client(), server.URL, "", "prod/api")
+	if err != nil || result.Memory != 1200 {
+		t.Errorf("postCheck() = %+v, %v, want the result of prod/api", result, err)
+	}
+	result, err = postCheck(server.Client(), server.URL, "", "prod/worker")
+	if err != nil || result.Err == nil || !strings.Contains(result.Err.Error(), "metrics unavailable") {
+		t.Errorf("postCheck() = %+v, %v, want the failed check of prod/worker", result, err)
+	}
+	if _, err := postCheck(server.Client(), server.URL, "", "prod/missing"); err == nil || !strings.Contains(err.Error(), "target not found") {
+		t.Errorf("postCheck() error = %v, want the error of the API", err)
+	}
+}
+
+func TestResolveTargetName(t *testing.T) {
+	server := newAdminServer(map[string]watchdog.CheckResult{
+		"prod/api":    {Target: watchdog.Target{Name: "prod/api", DeploymentName: "api"}},
+		"staging/api": {Target: watchdog.Target{Name: "staging/api", DeploymentName: "api"}},
+		"payments":    {Target: watchdog.Target{Name: "payments", DeploymentName: "payments-api"}},
+	})
+	defer server.Close()
+
+	tests := []struct {
+		target  string
+		want    string
+		wantErr bool
+	}{
+		{target: "prod/api", want: "prod/api"},
+		{target: "payments", want: "payments"},
+		{target: "payments-api", want: "payments"},
+		{target: "api", wantErr: true},
+		{target: "missing", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := resolveTargetName(server.Client(), server.URL, "", tt.target)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("resolveTargetName(%q) = %q, %v, want %q", tt.target, got, err, tt.want)
+		}
+	}
+}
+
+func TestDescribeResult(t *testing.T) {
+	target := watchdog.Target{Name: "prod/api"}
+	tests := []struct {
+		result watchdog.CheckResult
+		want   string
+	}{
+		{result: watchdog.CheckResult{Target: target, Memory: 1200, Threshold: 2000, CheckID: "3f2a9c1e5b7d4a60"},
+			want: "prod/api: 1200Mi of 2000Mi (60%), ok [check 3f2a9c1e5b7d4a60]"},
+		{result: watchdog.CheckResult{Target: target, Memory: 2400, Threshold: 2000, Breached: true, Action: "restart"},
+			want: "prod/api: 2400Mi of 2000Mi (120%), breached, restart triggered"},
+		{result: watchdog.CheckResult{Target: target, Err: errors.New("forbidden")},
+			want: "prod/api: check failed: forbidden"},
+	}
+	for _, tt := range tests {
+		if got := describeResult(tt.result); got != tt.want {
+			t.Errorf("describeResult() = %q, want %q", got, tt.want)
+		}
+	}
+}
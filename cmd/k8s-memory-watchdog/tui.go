@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+	"unicode/utf8"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// ANSI escape sequences used by the dashboard
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	red         = "\x1b[31m"
+	reset       = "\x1b[0m"
+)
+
+// sparkBlocks are the levels of a sparkline, lowest first
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// runTUI implements the tui subcommand, a live terminal dashboard of the
+// history written by a running watchdog, and returns the process exit code
+func runTUI(args []string) int {
+	fs := flag.NewFlagSet("tui", flag.ExitOnError)
+	configFile := fs.String("config", getEnv("CONFIG_FILE", ""), "Path to YAML configuration file")
+	stateFile := fs.String("state-file", getEnv("STATE_FILE", ""), "File the history is read from")
+	refresh := fs.Duration("refresh", 2*time.Second, "How often the dashboard is redrawn")
+	window := fs.Duration("window", time.Hour, "How much history the dashboard shows")
+	fs.Parse(args)
+
+	config, err := loadStateConfig(*configFile, *stateFile)
+	if err != nil {
+		log.Print(err)
+		return 1
+	}
+	store := watchdog.NewFileStateStore(config.StateFile)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	ticker := time.NewTicker(*refresh)
+	defer ticker.Stop()
+
+	for {
+		now := time.Now()
+		records, err := store.List(ctx, now.Add(-*window))
+		var buf bytes.Buffer
+		buf.WriteString(clearScreen)
+		if err != nil {
+			fmt.Fprintf(&buf, "Error reading history: %v\n", err)
+		} else {
+			renderDashboard(&buf, config.ResolveTargets(), records, now, *window)
+		}
+		os.Stdout.Write(buf.Bytes())
+
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// renderDashboard writes one frame of the dashboard: a line per target with
+// its latest usage, a sparkline of its history and the time since its last
+// restart, followed by the recent events
+func renderDashboard(w io.Writer, targets []watchdog.Target, records []watchdog.Record, now time.Time, window time.Duration) {
+	fmt.Fprintf(w, "k8s-memory-watchdog  %s  (last %s)\n\n", now.Format("2006-01-02 15:04:05"), window)
+
+	byTarget := make(map[string][]watchdog.Record)
+	var names []string
+	for _, target := range targets {
+		if _, ok := byTarget[target.Name]; !ok {
+			byTarget[target.Name] = nil
+			names = append(names, target.Name)
+		}
+	}
+	for _, record := range records {
+		if _, ok := byTarget[record.Target]; !ok {
+			names = append(names, record.Target)
+		}
+		byTarget[record.Target] = append(byTarget[record.Target], record)
+	}
+
+	fmt.Fprintf(w, "%-30s %10s %10s  %-30s %s\n", "TARGET", "USAGE", "THRESHOLD", "HISTORY", "LAST RESTART")
+	for _, name := range names {
+		history := byTarget[name]
+		usage, threshold, color := "-", "-", ""
+		lastRestart := "-"
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Error == "" {
+				latest := history[i]
+				usage = fmt.Sprintf("%dMi", latest.Memory)
+				threshold = fmt.Sprintf("%dMi", latest.Threshold)
+				if latest.Breached {
+					color = red
+				}
+				break
+			}
+		}
+		for i := len(history) - 1; i >= 0; i-- {
+			if history[i].Action == string(watchdog.EventRestart) {
+				lastRestart = now.Sub(history[i].Time).Round(time.Second).String() + " ago"
+				break
+			}
+		}
+		spark := sparkline(history, 30)
+		// padded by hand, fmt pads by bytes
+		spark += strings.Repeat(" ", 30-utf8.RuneCountInString(spark))
+		line := fmt.Sprintf("%-30s %10s %10s  %s %s", name, usage, threshold, spark, lastRestart)
+		if color != "" {
+			line = color + line + reset
+		}
+		fmt.Fprintln(w, line)
+	}
+
+	fmt.Fprintf(w, "\nRECENT EVENTS\n")
+	var events []watchdog.Record
+	for _, record := range records {
+		if record.Breached || record.Action != "" || record.Error != "" {
+			events = append(events, record)
+		}
+	}
+	if len(events) > 10 {
+		events = events[len(events)-10:]
+	}
+	if len(events) == 0 {
+		fmt.Fprintln(w, "none")
+	}
+	for _, event := range events {
+		switch {
+		case event.Error != "":
+			fmt.Fprintf(w, "%s  %-30s error: %s\n", event.Time.Format("15:04:05"), event.Target, event.Error)
+		case event.Action != "":
+			fmt.Fprintf(w, "%s  %-30s %s at %dMi >= %dMi\n", event.Time.Format("15:04:05"), event.Target, event.Action, event.Memory, event.Threshold)
+		default:
+			fmt.Fprintf(w, "%s  %-30s breach at %dMi >= %dMi\n", event.Time.Format("15:04:05"), event.Target, event.Memory, event.Threshold)
+		}
+	}
+}
+
+// sparkline draws the usage of the last width successful checks relative
+// to the highest of their usage and threshold
+func sparkline(history []watchdog.Record, width int) string {
+	var values []watchdog.Record
+	for _, record := range history {
+		if record.Error == "" {
+			values = append(values, record)
+		}
+	}
+	if len(values) > width {
+		values = values[len(values)-width:]
+	}
+
+	top := 0
+	for _, record := range values {
+		if record.Memory > top {
+			top = record.Memory
+		}
+		if record.Threshold > top {
+			top = record.Threshold
+		}
+	}
+	line := make([]rune, len(values))
+	for i, record := range values {
+		level := 0
+		if top > 0 {
+			level = record.Memory * (len(sparkBlocks) - 1) / top
+		}
+		line[i] = sparkBlocks[level]
+	}
+	return string(line)
+}
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestRenderDashboard(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	records := []watchdog.Record{
+		{Time: now.Add(-3 * time.Minute), Target: "prod/api", Memory: 1000, Threshold: 4000},
+		{Time: now.Add(-2 * time.Minute), Target: "prod/api", Memory: 4000, Threshold: 4000, Breached: true, Action: "restart"},
+		{Time: now.Add(-time.Minute), Target: "prod/api", Memory: 2000, Threshold: 4000},
+		{Time: now.Add(-time.Minute), Target: "prod/worker", Error: "metrics unavailable"},
+	}
+	targets := []watchdog.Target{{Name: "prod/idle"}, {Name: "prod/api"}}
+
+	var buf bytes.Buffer
+	renderDashboard(&buf, targets, records, now, time.Hour)
+	output := buf.String()
+
+	for _, want := range []string{
+		"prod/idle",
+		"prod/api                           2000Mi     4000Mi  ▂█▄                            2m0s ago",
+		"prod/worker                             -          -",
+		"11:58:00  prod/api                       restart at 4000Mi >= 4000Mi",
+		"11:59:00  prod/worker                    error: metrics unavailable",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("renderDashboard() = \n%s\nwant it to contain %q", output, want)
+		}
+	}
+	if strings.Index(output, "prod/idle") > strings.Index(output, "prod/api") {
+		t.Error("renderDashboard() should list configured targets first")
+	}
+}
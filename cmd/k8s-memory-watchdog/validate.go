@@ -0,0 +1,160 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/yaml"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// runValidate implements the validate subcommand, which reports every
+// problem of a configuration file, and of the cluster it refers to unless
+// --offline is given, and returns the process exit code: 1 when there are
+// problems, so it can gate configuration changes in CI
+func runValidate(args []string) int {
+	fs := flag.NewFlagSet("validate", flag.ExitOnError)
+	var path string
+	fs.StringVar(&path, "f", getEnv("CONFIG_FILE", ""), "Configuration file to validate")
+	fs.StringVar(&path, "file", getEnv("CONFIG_FILE", ""), "Configuration file to validate")
+	offline := fs.Bool("offline", false, "Only validate the file, without connecting to the cluster")
+	timeout := fs.Duration("timeout", time.Minute, "Timeout of the cluster checks")
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "usage: k8s-memory-watchdog validate [flags] -f <config.yaml>")
+		fs.PrintDefaults()
+	}
+	fs.Parse(args)
+	if path == "" || fs.NArg() != 0 {
+		fs.Usage()
+		return 2
+	}
+
+	problems, err := fileProblems(path)
+	if err != nil {
+		fmt.Fprintf(os.Stdout, "%s: %v\n", path, err)
+		return 1
+	}
+	// The file is read again with the defaults, environment variables and
+	// precedence of the watchdog itself
+	os.Args = append(os.Args[:1:1], "--config", path)
+	config := parseFlags()
+	problems = append(problems, configProblems(config)...)
+
+	if !*offline {
+		ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+		problems = append(problems, clusterProblems(ctx, config)...)
+		cancel()
+	}
+	return reportProblems(os.Stdout, path, problems)
+}
+
+// fileProblems reads the configuration file and returns the settings it has
+// that the watchdog doesn't know. The error is for files that can't be read
+// or parsed at all.
+func fileProblems(path string) ([]string, error) {
+	_, err := watchdog.LoadConfigFileStrict(path, watchdog.Config{})
+	var unknown *yaml.UnknownFieldsError
+	if errors.As(err, &unknown) {
+		problems := make([]string, len(unknown.Fields))
+		for i, f := range unknown.Fields {
+			problems[i] = f.String() + ", see config.yaml for the available settings"
+		}
+		return problems, nil
+	}
+	return nil, err
+}
+
+// configProblems returns the problems of the effective configuration that
+// can be found without connecting to anything
+func configProblems(config options) []string {
+	problems := config.Problems()
+	for _, name := range configuredSources(config.Config) {
+		if !knownSource(name) {
+			problems = append(problems, fmt.Sprintf("unknown metrics source '%s', must be one of %s", name, strings.Join(sourceTypes, ", ")))
+		}
+	}
+	switch config.TargetValidation {
+	case "off", "warn", "fail":
+	default:
+		problems = append(problems, fmt.Sprintf("invalid target validation mode '%s', must be off, warn or fail", config.TargetValidation))
+	}
+	return problems
+}
+
+// configuredSources returns the default metrics source and the distinct
+// sources of the source chains of the targets
+func configuredSources(config watchdog.Config) []string {
+	names := []string{config.Source.Type}
+	if names[0] == "" {
+		names[0] = "kubectl"
+	}
+	for _, name := range sourceChainNames(config.ResolveTargets()) {
+		if name != names[0] {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+func knownSource(name string) bool {
+	for _, t := range sourceTypes {
+		if t == name {
+			return true
+		}
+	}
+	return false
+}
+
+// clusterProblems checks that the cluster is reachable, that the
+// credentials and metrics sources of the configuration can be set up and
+// that the targets exist
+func clusterProblems(ctx context.Context, config options) []string {
+	runner, err := newRunner(config.Config)
+	if err != nil {
+		return []string{err.Error()}
+	}
+	var problems []string
+	if err := setupSecrets(ctx, runner, config.Config); err != nil {
+		problems = append(problems, err.Error())
+	}
+	for _, name := range configuredSources(config.Config) {
+		if !knownSource(name) {
+			continue
+		}
+		if _, err := newMetricsSource(name, config.Config, runner); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+
+	missing, err := missingTargets(ctx, runner, config.ResolveTargets())
+	if err != nil {
+		return append(problems, fmt.Sprintf("the cluster is unreachable: %v; check the kubeconfig of kubectl or use --offline", err))
+	}
+	problems = append(problems, missing...)
+	if config.RBACCheck {
+		if err := checkPermissions(ctx, runner, config); err != nil {
+			problems = append(problems, err.Error())
+		}
+	}
+	return problems
+}
+
+// reportProblems writes the problems of the configuration file and returns
+// the exit code of the validate subcommand
+func reportProblems(w io.Writer, path string, problems []string) int {
+	for _, problem := range problems {
+		fmt.Fprintf(w, "%s: %s\n", path, problem)
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(w, "%s: %d problems found\n", path, len(problems))
+		return 1
+	}
+	fmt.Fprintf(w, "%s is valid\n", path)
+	return 0
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestFileProblems(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	data := `namespace: prod
+memory_treshold: 4000
+targets:
+  - deployment: api
+    check_intervall: 30s
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	problems, err := fileProblems(path)
+	if err != nil {
+		t.Fatalf("fileProblems() error = %v", err)
+	}
+	expected := []string{
+		"line 2: unknown field 'memory_treshold', see config.yaml for the available settings",
+		"line 5: unknown field 'targets[0].check_intervall', see config.yaml for the available settings",
+	}
+	if !reflect.DeepEqual(problems, expected) {
+		t.Errorf("fileProblems() = %q, want %q", problems, expected)
+	}
+
+	if err := os.WriteFile(path, []byte("check_interval: soon\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := fileProblems(path); err == nil {
+		t.Error("fileProblems() succeeded for an invalid duration")
+	}
+}
+
+func TestConfigProblems(t *testing.T) {
+	config := options{
+		Config: watchdog.Config{
+			Namespace:       "prod",
+			MemoryThreshold: 4000,
+			CheckInterval:   time.Minute,
+			Source:          watchdog.SourceConfig{Type: "datadog"},
+			Targets: []watchdog.Target{
+				{DeploymentName: "api", Sources: []string{"datadog", "promethues"}},
+			},
+		},
+		TargetValidation: "strict",
+	}
+
+	expected := []string{
+		"unknown metrics source 'promethues', must be one of kubectl, datadog, cloudwatch, prometheus, newrelic, custom, external, scrape",
+		"invalid target validation mode 'strict', must be off, warn or fail",
+	}
+	if problems := configProblems(config); !reflect.DeepEqual(problems, expected) {
+		t.Errorf("configProblems() = %q, want %q", problems, expected)
+	}
+}
+
+func TestReportProblems(t *testing.T) {
+	var out bytes.Buffer
+	if code := reportProblems(&out, "config.yaml", nil); code != 0 || out.String() != "config.yaml is valid\n" {
+		t.Errorf("reportProblems() = %d, %q", code, out.String())
+	}
+
+	out.Reset()
+	code := reportProblems(&out, "config.yaml", []string{"target 'api' has no check interval"})
+	expected := "config.yaml: target 'api' has no check interval\nconfig.yaml: 1 problems found\n"
+	if code != 1 || out.String() != expected {
+		t.Errorf("reportProblems() = %d, %q, want 1, %q", code, out.String(), expected)
+	}
+}
@@ -0,0 +1,13 @@
+// Package k8smemorywatchdog holds the annotated example configuration of
+// the watchdog, printed by its config init subcommand.
+package k8smemorywatchdog
+
+import (
+	_ "embed"
+)
+
+// ExampleConfig is the content of config.yaml: every setting of the
+// configuration file with its default value and a comment
+//
+//go:embed config.yaml
+var ExampleConfig string
@@ -0,0 +1,63 @@
+package k8smemorywatchdog
+
+import (
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/yaml"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// commentedKey matches a key of a commented-out example setting
+var commentedKey = regexp.MustCompile(`^#\s*(?:- )?([a-z0-9_]+):`)
+
+func TestExampleConfigIsValid(t *testing.T) {
+	var config watchdog.Config
+	if err := yaml.UnmarshalStrict([]byte(ExampleConfig), &config); err != nil {
+		t.Fatalf("config.yaml is invalid: %v", err)
+	}
+}
+
+// TestExampleConfigIsComplete checks that every setting of the
+// configuration file is documented in config.yaml, set or commented out
+func TestExampleConfigIsComplete(t *testing.T) {
+	keys := make(map[string]bool)
+	for _, line := range strings.Split(ExampleConfig, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := commentedKey.FindStringSubmatch(trimmed); m != nil {
+			keys[m[1]] = true
+			continue
+		}
+		trimmed = strings.TrimPrefix(trimmed, "- ")
+		if i := strings.Index(trimmed, ":"); i > 0 {
+			keys[trimmed[:i]] = true
+		}
+	}
+
+	seen := make(map[reflect.Type]bool)
+	var check func(reflect.Type, string)
+	check = func(typ reflect.Type, path string) {
+		for typ.Kind() == reflect.Ptr || typ.Kind() == reflect.Slice || typ.Kind() == reflect.Map {
+			typ = typ.Elem()
+		}
+		if typ.Kind() != reflect.Struct || typ == reflect.TypeOf(time.Time{}) || seen[typ] {
+			return
+		}
+		seen[typ] = true
+		for i := 0; i < typ.NumField(); i++ {
+			field := typ.Field(i)
+			name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+			if field.PkgPath != "" || name == "-" || name == "" {
+				continue
+			}
+			if !keys[name] {
+				t.Errorf("config.yaml doesn't document %s%s", path, name)
+			}
+			check(field.Type, path+name+".")
+		}
+	}
+	check(reflect.TypeOf(watchdog.Config{}), "")
+}
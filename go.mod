@@ -0,0 +1,3 @@
+module github.com/renancavalcantercb/k8s-memory-watchdog
+
+go 1.16
@@ -0,0 +1,57 @@
+package awsauth
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestSign(t *testing.T) {
+	// get-vanilla and get-vanilla-query-order-key from the AWS Signature
+	// Version 4 test suite
+	tests := []struct {
+		name     string
+		url      string
+		expected string
+	}{
+		{
+			name:     "vanilla",
+			url:      "https://example.amazonaws.com/",
+			expected: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31",
+		},
+		{
+			name:     "query order",
+			url:      "https://example.amazonaws.com/?Param2=value2&Param1=value1",
+			expected: "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, SignedHeaders=host;x-amz-date, Signature=b97d918cfa904a5beff61c982a1b6f458b799221646efd99d3219ec94cdf2500",
+		},
+	}
+
+	signer := Signer{
+		Credentials: Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+		Region:      "us-east-1",
+		Service:     "service",
+	}
+	now := time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req, _ := http.NewRequest(http.MethodGet, tt.url, nil)
+			signer.Sign(req, nil, now)
+			if got := req.Header.Get("Authorization"); got != tt.expected {
+				t.Errorf("Authorization = %v, want %v", got, tt.expected)
+			}
+			if got := req.Header.Get("X-Amz-Date"); got != "20150830T123600Z" {
+				t.Errorf("X-Amz-Date = %v", got)
+			}
+		})
+	}
+}
+
+func TestSignSessionToken(t *testing.T) {
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	Signer{Credentials: Credentials{AccessKeyID: "a", SecretAccessKey: "s", SessionToken: "token"}, Region: "r", Service: "s"}.
+		Sign(req, nil, time.Now())
+	if req.Header.Get("X-Amz-Security-Token") != "token" {
+		t.Error("session token header not set")
+	}
+}
@@ -0,0 +1,59 @@
+package awsauth
+
+import (
+	"context"
+	"encoding/base64"
+	"net/http"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/tokencache"
+)
+
+const (
+	// eksTokenPrefix marks the bearer tokens of EKS, as generated by
+	// aws-iam-authenticator and aws eks get-token
+	eksTokenPrefix = "k8s-aws-v1."
+	// eksClusterHeader binds a token to the cluster it authenticates to
+	eksClusterHeader = "x-k8s-aws-id"
+	// eksTokenLifetime is how long EKS accepts a token after it is signed
+	eksTokenLifetime = 15 * time.Minute
+)
+
+// EKSTokenSource generates the bearer tokens authenticating an IAM identity
+// to an EKS cluster: a presigned STS GetCallerIdentity request, which the
+// cluster makes to learn who the caller is
+type EKSTokenSource struct {
+	Credentials Credentials
+	// Region selects the regional STS endpoint, the global one when empty
+	Region  string
+	Cluster string
+
+	cache tokencache.Cache
+}
+
+// Token returns a cached or freshly generated token
+func (s *EKSTokenSource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(func(now time.Time) (string, time.Time, error) {
+		return s.generate(ctx, now)
+	})
+}
+
+// generate presigns a GetCallerIdentity request for the cluster, valid for
+// the lifetime of EKS tokens from now
+func (s *EKSTokenSource) generate(ctx context.Context, now time.Time) (string, time.Time, error) {
+	region, host := s.Region, "sts.amazonaws.com"
+	if region == "" {
+		region = "us-east-1"
+	} else {
+		host = "sts." + region + ".amazonaws.com"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, "https://"+host+"/?Action=GetCallerIdentity&Version=2011-06-15", nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set(eksClusterHeader, s.Cluster)
+	Signer{Credentials: s.Credentials, Region: region, Service: "sts"}.Presign(req, time.Minute, now)
+
+	token := eksTokenPrefix + base64.RawURLEncoding.EncodeToString([]byte(req.URL.String()))
+	return token, now.Add(eksTokenLifetime), nil
+}
@@ -0,0 +1,65 @@
+package awsauth
+
+import (
+	"context"
+	"encoding/base64"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEKSTokenSource(t *testing.T) {
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	source := &EKSTokenSource{
+		Credentials: Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "session"},
+		Region:      "eu-west-1",
+		Cluster:     "prod",
+	}
+	source.cache.Now = func() time.Time { return now }
+
+	token, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(token, "k8s-aws-v1.") {
+		t.Fatalf("Token() = %q, want an EKS token", token)
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(token, "k8s-aws-v1."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	u, err := url.Parse(string(raw))
+	if err != nil {
+		t.Fatal(err)
+	}
+	query := u.Query()
+	if u.Host != "sts.eu-west-1.amazonaws.com" || query.Get("Action") != "GetCallerIdentity" {
+		t.Errorf("URL = %s, want a regional GetCallerIdentity request", u)
+	}
+	expected := map[string]string{
+		"X-Amz-Credential":     "AKID/20240304/eu-west-1/sts/aws4_request",
+		"X-Amz-Date":           "20240304T100000Z",
+		"X-Amz-Expires":        "60",
+		"X-Amz-SignedHeaders":  "host;x-k8s-aws-id",
+		"X-Amz-Security-Token": "session",
+	}
+	for key, value := range expected {
+		if got := query.Get(key); got != value {
+			t.Errorf("%s = %q, want %q", key, got, value)
+		}
+	}
+	if len(query.Get("X-Amz-Signature")) != 64 {
+		t.Errorf("X-Amz-Signature = %q, want a signature", query.Get("X-Amz-Signature"))
+	}
+
+	// the token is reused until shortly before it expires
+	now = now.Add(10 * time.Minute)
+	if again, _ := source.Token(context.Background()); again != token {
+		t.Error("Token() generated a new token, want the cached one")
+	}
+	now = now.Add(5 * time.Minute)
+	if again, _ := source.Token(context.Background()); again == token {
+		t.Error("Token() = the cached token, want a new one once it is about to expire")
+	}
+}
@@ -0,0 +1,171 @@
+// Package azureauth obtains Azure AD access tokens, with the credentials of
+// a service principal, a federated workload identity or a managed identity,
+// without the Azure SDK.
+package azureauth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/tokencache"
+)
+
+// AKSScope is the scope of the tokens accepted by the API servers of AKS
+// clusters with Azure AD integration, the application of the AKS AAD server
+const AKSScope = "6dae42f8-4368-4678-94ff-3960e28e3630/.default"
+
+// TokenSource returns Azure AD access tokens
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// DefaultTokenSource returns a cached token source for scope using the
+// credentials of the environment: the client secret of AZURE_CLIENT_SECRET,
+// else the federated token of AZURE_FEDERATED_TOKEN_FILE, as mounted by AKS
+// workload identity, both for the AZURE_CLIENT_ID of AZURE_TENANT_ID, else
+// the managed identity of the VM, optionally the one of AZURE_CLIENT_ID
+func DefaultTokenSource(scope string) (TokenSource, error) {
+	clientID, tenantID := os.Getenv("AZURE_CLIENT_ID"), os.Getenv("AZURE_TENANT_ID")
+	secret, tokenFile := os.Getenv("AZURE_CLIENT_SECRET"), os.Getenv("AZURE_FEDERATED_TOKEN_FILE")
+	if secret == "" && tokenFile == "" {
+		return NewManagedIdentitySource(scope, clientID), nil
+	}
+	if clientID == "" || tenantID == "" {
+		return nil, errors.New("AZURE_CLIENT_ID and AZURE_TENANT_ID are not set")
+	}
+	return NewClientSource(tenantID, clientID, secret, tokenFile, scope), nil
+}
+
+// token is an access token with its lifetime in seconds, which the
+// managed identity endpoint sends as a string
+type token struct {
+	AccessToken string      `json:"access_token"`
+	ExpiresIn   json.Number `json:"expires_in"`
+}
+
+// ClientSource obtains tokens for an application registered in Azure AD,
+// with its client secret or a federated token
+type ClientSource struct {
+	tokenURL  string
+	clientID  string
+	secret    string
+	tokenFile string
+	scope     string
+	client    *http.Client
+	cache     tokencache.Cache
+}
+
+// NewClientSource creates a new instance of ClientSource authenticating
+// with secret, or with the federated token read from tokenFile when secret
+// is empty
+func NewClientSource(tenantID, clientID, secret, tokenFile, scope string) *ClientSource {
+	return &ClientSource{
+		tokenURL:  "https://login.microsoftonline.com/" + url.PathEscape(tenantID) + "/oauth2/v2.0/token",
+		clientID:  clientID,
+		secret:    secret,
+		tokenFile: tokenFile,
+		scope:     scope,
+		client:    http.DefaultClient,
+	}
+}
+
+// Token returns a cached or freshly obtained access token
+func (s *ClientSource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(func(now time.Time) (string, time.Time, error) {
+		form := url.Values{}
+		form.Set("grant_type", "client_credentials")
+		form.Set("client_id", s.clientID)
+		form.Set("scope", s.scope)
+		if s.secret != "" {
+			form.Set("client_secret", s.secret)
+		} else {
+			// the federated token is rotated by the kubelet, so it is
+			// read again for every exchange
+			assertion, err := os.ReadFile(s.tokenFile)
+			if err != nil {
+				return "", time.Time{}, err
+			}
+			form.Set("client_assertion_type", "urn:ietf:params:oauth:client-assertion-type:jwt-bearer")
+			form.Set("client_assertion", strings.TrimSpace(string(assertion)))
+		}
+		req, err := http.NewRequest(http.MethodPost, s.tokenURL, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return fetchToken(ctx, s.client, req, now)
+	})
+}
+
+// ManagedIdentitySource fetches the tokens of a managed identity from the
+// instance metadata service of the VM
+type ManagedIdentitySource struct {
+	url    string
+	client *http.Client
+	cache  tokencache.Cache
+}
+
+// NewManagedIdentitySource creates a new instance of ManagedIdentitySource.
+// clientID selects a user-assigned identity, the system-assigned one when
+// it is empty.
+func NewManagedIdentitySource(scope, clientID string) *ManagedIdentitySource {
+	query := url.Values{}
+	query.Set("api-version", "2018-02-01")
+	// the metadata service takes a resource rather than a scope
+	query.Set("resource", strings.TrimSuffix(scope, "/.default"))
+	if clientID != "" {
+		query.Set("client_id", clientID)
+	}
+	return &ManagedIdentitySource{
+		url:    "http://169.254.169.254/metadata/identity/oauth2/token?" + query.Encode(),
+		client: http.DefaultClient,
+	}
+}
+
+// Token returns a cached or freshly fetched access token
+func (s *ManagedIdentitySource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(func(now time.Time) (string, time.Time, error) {
+		req, err := http.NewRequest(http.MethodGet, s.url, nil)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("Metadata", "true")
+		return fetchToken(ctx, s.client, req, now)
+	})
+}
+
+// fetchToken sends a token request and returns the token of the response
+// and when it expires
+func fetchToken(ctx context.Context, client *http.Client, req *http.Request, now time.Time) (string, time.Time, error) {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", time.Time{}, fmt.Errorf("token request failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var t token
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", time.Time{}, err
+	}
+	if t.AccessToken == "" {
+		return "", time.Time{}, errors.New("token response has no access token")
+	}
+	seconds, err := strconv.Atoi(t.ExpiresIn.String())
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid token lifetime %q", t.ExpiresIn)
+	}
+	return t.AccessToken, now.Add(time.Duration(seconds) * time.Second), nil
+}
@@ -0,0 +1,100 @@
+package azureauth
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestClientSource(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "client_credentials" || r.Form.Get("client_id") != "app" || r.Form.Get("scope") != AKSScope {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		calls++
+		switch {
+		case r.Form.Get("client_secret") == "secret":
+			w.Write([]byte(`{"access_token":"secret-token","expires_in":3599}`))
+		case r.Form.Get("client_assertion") == "federated-jwt":
+			w.Write([]byte(`{"access_token":"federated-token","expires_in":3599}`))
+		default:
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	source := NewClientSource("tenant", "app", "secret", "", AKSScope)
+	source.tokenURL = server.URL
+	now := time.Now()
+	source.cache.Now = func() time.Time { return now }
+	for i := 0; i < 2; i++ {
+		if token, err := source.Token(context.Background()); err != nil || token != "secret-token" {
+			t.Fatalf("Token() = %v, %v, want secret-token, nil", token, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("token endpoint called %d times, want 1", calls)
+	}
+
+	tokenFile := filepath.Join(t.TempDir(), "azure-identity-token")
+	if err := os.WriteFile(tokenFile, []byte("federated-jwt\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	source = NewClientSource("tenant", "app", "", tokenFile, AKSScope)
+	source.tokenURL = server.URL
+	if token, err := source.Token(context.Background()); err != nil || token != "federated-token" {
+		t.Errorf("Token() = %v, %v, want federated-token, nil", token, err)
+	}
+}
+
+func TestManagedIdentitySource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		if r.Header.Get("Metadata") != "true" || query.Get("resource") != "6dae42f8-4368-4678-94ff-3960e28e3630" || query.Get("client_id") != "identity" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		// the metadata service sends the lifetime as a string
+		w.Write([]byte(`{"access_token":"msi-token","expires_in":"3599"}`))
+	}))
+	defer server.Close()
+
+	source := NewManagedIdentitySource(AKSScope, "identity")
+	u := source.url
+	source.url = server.URL + u[len("http://169.254.169.254"):]
+	if token, err := source.Token(context.Background()); err != nil || token != "msi-token" {
+		t.Errorf("Token() = %v, %v, want msi-token, nil", token, err)
+	}
+}
+
+func TestDefaultTokenSource(t *testing.T) {
+	for _, key := range []string{"AZURE_CLIENT_ID", "AZURE_TENANT_ID", "AZURE_CLIENT_SECRET", "AZURE_FEDERATED_TOKEN_FILE"} {
+		defer os.Setenv(key, os.Getenv(key))
+		os.Unsetenv(key)
+	}
+
+	if source, err := DefaultTokenSource(AKSScope); err != nil {
+		t.Errorf("DefaultTokenSource() error = %v", err)
+	} else if _, ok := source.(*ManagedIdentitySource); !ok {
+		t.Errorf("DefaultTokenSource() = %T, want the managed identity without credentials", source)
+	}
+
+	os.Setenv("AZURE_CLIENT_SECRET", "secret")
+	if _, err := DefaultTokenSource(AKSScope); err == nil {
+		t.Error("DefaultTokenSource() error = nil, want the missing client and tenant reported")
+	}
+	os.Setenv("AZURE_CLIENT_ID", "app")
+	os.Setenv("AZURE_TENANT_ID", "tenant")
+	if source, err := DefaultTokenSource(AKSScope); err != nil {
+		t.Errorf("DefaultTokenSource() error = %v", err)
+	} else if _, ok := source.(*ClientSource); !ok {
+		t.Errorf("DefaultTokenSource() = %T, want the client secret", source)
+	}
+}
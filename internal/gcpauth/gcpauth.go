@@ -0,0 +1,282 @@
+// Package gcpauth obtains Google OAuth2 access tokens, from the GCE/GKE
+// metadata server or a service account key, without the Google SDK.
+package gcpauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/tokencache"
+)
+
+// TokenSource returns OAuth2 access tokens
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// DefaultTokenSource returns a cached token source using the Application
+// Default Credentials: the credentials file named by
+// GOOGLE_APPLICATION_CREDENTIALS, else the one written by gcloud auth
+// application-default login, else the metadata server, which serves the
+// tokens of Workload Identity on GKE
+func DefaultTokenSource(scopes ...string) (TokenSource, error) {
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		path = gcloudCredentialsPath()
+		if _, err := os.Stat(path); err != nil {
+			return NewMetadataSource(scopes...), nil
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	source, err := NewCredentialsSource(data, scopes...)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return source, nil
+}
+
+// gcloudCredentialsPath returns where gcloud auth application-default login
+// writes the credentials of the user
+func gcloudCredentialsPath() string {
+	dir := os.Getenv("CLOUDSDK_CONFIG")
+	if dir == "" {
+		home, _ := os.UserHomeDir()
+		dir = filepath.Join(home, ".config", "gcloud")
+	}
+	return filepath.Join(dir, "application_default_credentials.json")
+}
+
+// NewCredentialsSource creates the token source of a JSON credentials file,
+// either the key of a service account or the refresh token of a user
+func NewCredentialsSource(credentialsJSON []byte, scopes ...string) (TokenSource, error) {
+	var credentials struct {
+		Type string `json:"type"`
+	}
+	if err := json.Unmarshal(credentialsJSON, &credentials); err != nil {
+		return nil, err
+	}
+	if credentials.Type == "authorized_user" {
+		return NewUserSource(credentialsJSON)
+	}
+	return NewServiceAccountSource(credentialsJSON, scopes...)
+}
+
+// token is an access token with its expiry
+type token struct {
+	AccessToken string `json:"access_token"`
+	ExpiresIn   int    `json:"expires_in"`
+}
+
+// MetadataSource fetches tokens of the instance's service account from the
+// metadata server, as available on GCE and to GKE workload identity
+type MetadataSource struct {
+	url    string
+	client *http.Client
+	cache  tokencache.Cache
+}
+
+// NewMetadataSource creates a new instance of MetadataSource
+func NewMetadataSource(scopes ...string) *MetadataSource {
+	u := "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+	if len(scopes) > 0 {
+		u += "?scopes=" + url.QueryEscape(strings.Join(scopes, ","))
+	}
+	return &MetadataSource{
+		url:    u,
+		client: http.DefaultClient,
+	}
+}
+
+// Token returns a cached or freshly fetched access token
+func (s *MetadataSource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(func(now time.Time) (string, time.Time, error) {
+		req, err := http.NewRequest(http.MethodGet, s.url, nil)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("Metadata-Flavor", "Google")
+		return fetchToken(ctx, s.client, req, now)
+	})
+}
+
+// ServiceAccountSource exchanges a JWT signed with a service account key for
+// access tokens
+type ServiceAccountSource struct {
+	email    string
+	key      *rsa.PrivateKey
+	tokenURI string
+	scopes   []string
+	client   *http.Client
+	cache    tokencache.Cache
+}
+
+// NewServiceAccountSource creates a new instance of ServiceAccountSource from
+// the JSON key of a service account
+func NewServiceAccountSource(keyJSON []byte, scopes ...string) (*ServiceAccountSource, error) {
+	var key struct {
+		ClientEmail string `json:"client_email"`
+		PrivateKey  string `json:"private_key"`
+		TokenURI    string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(keyJSON, &key); err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode([]byte(key.PrivateKey))
+	if block == nil {
+		return nil, errors.New("no PEM private key in service account key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	rsaKey, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("service account key is not an RSA key")
+	}
+	if key.TokenURI == "" {
+		key.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &ServiceAccountSource{
+		email:    key.ClientEmail,
+		key:      rsaKey,
+		tokenURI: key.TokenURI,
+		scopes:   scopes,
+		client:   http.DefaultClient,
+	}, nil
+}
+
+// UserSource exchanges the refresh token of a user, as stored by gcloud
+// auth application-default login, for access tokens
+type UserSource struct {
+	clientID     string
+	clientSecret string
+	refreshToken string
+	tokenURI     string
+	client       *http.Client
+	cache        tokencache.Cache
+}
+
+// NewUserSource creates a new instance of UserSource from authorized_user
+// credentials
+func NewUserSource(credentialsJSON []byte) (*UserSource, error) {
+	var credentials struct {
+		ClientID     string `json:"client_id"`
+		ClientSecret string `json:"client_secret"`
+		RefreshToken string `json:"refresh_token"`
+		TokenURI     string `json:"token_uri"`
+	}
+	if err := json.Unmarshal(credentialsJSON, &credentials); err != nil {
+		return nil, err
+	}
+	if credentials.RefreshToken == "" {
+		return nil, errors.New("no refresh token in user credentials")
+	}
+	if credentials.TokenURI == "" {
+		credentials.TokenURI = "https://oauth2.googleapis.com/token"
+	}
+	return &UserSource{
+		clientID:     credentials.ClientID,
+		clientSecret: credentials.ClientSecret,
+		refreshToken: credentials.RefreshToken,
+		tokenURI:     credentials.TokenURI,
+		client:       http.DefaultClient,
+	}, nil
+}
+
+// Token returns a cached or freshly refreshed access token
+func (s *UserSource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(func(now time.Time) (string, time.Time, error) {
+		form := url.Values{}
+		form.Set("grant_type", "refresh_token")
+		form.Set("client_id", s.clientID)
+		form.Set("client_secret", s.clientSecret)
+		form.Set("refresh_token", s.refreshToken)
+		req, err := http.NewRequest(http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return fetchToken(ctx, s.client, req, now)
+	})
+}
+
+// Token returns a cached or freshly exchanged access token
+func (s *ServiceAccountSource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(func(now time.Time) (string, time.Time, error) {
+		assertion, err := s.assertion(now)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		form := url.Values{}
+		form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+		form.Set("assertion", assertion)
+		req, err := http.NewRequest(http.MethodPost, s.tokenURI, strings.NewReader(form.Encode()))
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		return fetchToken(ctx, s.client, req, now)
+	})
+}
+
+// assertion builds the signed JWT exchanged for a token
+func (s *ServiceAccountSource) assertion(now time.Time) (string, error) {
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   s.email,
+		"scope": strings.Join(s.scopes, " "),
+		"aud":   s.tokenURI,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	hash := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, hash[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+// fetchToken sends a token request and returns the token of the response
+// and when it expires
+func fetchToken(ctx context.Context, client *http.Client, req *http.Request, now time.Time) (string, time.Time, error) {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return "", time.Time{}, fmt.Errorf("token request failed: %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	var t token
+	if err := json.NewDecoder(resp.Body).Decode(&t); err != nil {
+		return "", time.Time{}, err
+	}
+	if t.AccessToken == "" {
+		return "", time.Time{}, errors.New("token response has no access token")
+	}
+	return t.AccessToken, now.Add(time.Duration(t.ExpiresIn) * time.Second), nil
+}
@@ -0,0 +1,148 @@
+package gcpauth
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestMetadataSource(t *testing.T) {
+	calls := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		calls++
+		w.Write([]byte(`{"access_token":"token-1","expires_in":3600,"token_type":"Bearer"}`))
+	}))
+	defer server.Close()
+
+	source := NewMetadataSource()
+	source.url = server.URL
+	now := time.Now()
+	source.cache.Now = func() time.Time { return now }
+
+	for i := 0; i < 2; i++ {
+		if token, err := source.Token(context.Background()); err != nil || token != "token-1" {
+			t.Fatalf("Token() = %v, %v, want token-1, nil", token, err)
+		}
+	}
+	if calls != 1 {
+		t.Errorf("metadata server called %d times, want 1", calls)
+	}
+
+	now = now.Add(time.Hour)
+	source.Token(context.Background())
+	if calls != 2 {
+		t.Errorf("metadata server called %d times after expiry, want 2", calls)
+	}
+}
+
+func TestServiceAccountSource(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, _ := x509.MarshalPKCS8PrivateKey(key)
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		parts := strings.Split(r.Form.Get("assertion"), ".")
+		if len(parts) != 3 {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+		hash := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+		if err := rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, hash[:], signature); err != nil {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		claims, _ := base64.RawURLEncoding.DecodeString(parts[1])
+		var c map[string]interface{}
+		json.Unmarshal(claims, &c)
+		if c["iss"] != "watchdog@project.iam.gserviceaccount.com" || c["scope"] != "scope-a scope-b" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"sa-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	keyJSON, _ := json.Marshal(map[string]string{
+		"client_email": "watchdog@project.iam.gserviceaccount.com",
+		"private_key":  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		"token_uri":    server.URL,
+	})
+	source, err := NewServiceAccountSource(keyJSON, "scope-a", "scope-b")
+	if err != nil {
+		t.Fatalf("NewServiceAccountSource() error = %v", err)
+	}
+
+	if token, err := source.Token(context.Background()); err != nil || token != "sa-token" {
+		t.Errorf("Token() = %v, %v, want sa-token, nil", token, err)
+	}
+
+	if _, err := NewServiceAccountSource([]byte(`{"private_key":"nope"}`)); err == nil {
+		t.Error("NewServiceAccountSource() with an invalid key expected an error")
+	}
+}
+
+func TestUserSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("refresh_token") != "refresh-1" || r.Form.Get("client_id") != "client" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		w.Write([]byte(`{"access_token":"user-token","expires_in":3600}`))
+	}))
+	defer server.Close()
+
+	source, err := NewCredentialsSource([]byte(`{"type":"authorized_user","client_id":"client","client_secret":"secret","refresh_token":"refresh-1","token_uri":"` + server.URL + `"}`))
+	if err != nil {
+		t.Fatalf("NewCredentialsSource() error = %v", err)
+	}
+	if token, err := source.Token(context.Background()); err != nil || token != "user-token" {
+		t.Errorf("Token() = %v, %v, want user-token, nil", token, err)
+	}
+
+	if _, err := NewCredentialsSource([]byte(`{"type":"authorized_user"}`)); err == nil {
+		t.Error("NewCredentialsSource() without a refresh token expected an error")
+	}
+}
+
+func TestDefaultTokenSource(t *testing.T) {
+	dir := t.TempDir()
+	os.Setenv("CLOUDSDK_CONFIG", dir)
+	defer os.Unsetenv("CLOUDSDK_CONFIG")
+	os.Unsetenv("GOOGLE_APPLICATION_CREDENTIALS")
+
+	source, err := DefaultTokenSource()
+	if _, ok := source.(*MetadataSource); err != nil || !ok {
+		t.Errorf("DefaultTokenSource() = %T, %v, want the metadata server without credentials files", source, err)
+	}
+
+	credentials := `{"type":"authorized_user","client_id":"client","refresh_token":"refresh-1"}`
+	if err := os.WriteFile(filepath.Join(dir, "application_default_credentials.json"), []byte(credentials), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	source, err = DefaultTokenSource()
+	if _, ok := source.(*UserSource); err != nil || !ok {
+		t.Errorf("DefaultTokenSource() = %T, %v, want the gcloud credentials", source, err)
+	}
+}
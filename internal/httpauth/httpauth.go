@@ -0,0 +1,138 @@
+// Package httpauth guards HTTP handlers with static bearer tokens.
+package httpauth
+
+import (
+	"context"
+	"crypto/subtle"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+)
+
+// Token is a bearer token accepted by Bearer
+type Token interface {
+	Value() (string, error)
+}
+
+// StaticToken is a token set in the configuration
+type StaticToken string
+
+// Value returns the token
+func (t StaticToken) Value() (string, error) {
+	return string(t), nil
+}
+
+// SecretToken is a token set in the configuration, which may refer to a
+// file or a Kubernetes Secret as resolved by secret.Resolve
+type SecretToken string
+
+// Value returns the token
+func (t SecretToken) Value() (string, error) {
+	return secret.Resolve(context.Background(), string(t))
+}
+
+// FileToken is a token read from a file, such as a mounted Secret. The file
+// is read again when it changes, so the token can be rotated without a
+// restart. Surrounding whitespace is ignored.
+type FileToken struct {
+	path string
+
+	mu      sync.Mutex
+	value   string
+	modTime time.Time
+}
+
+// NewFileToken creates a new instance of FileToken reading path
+func NewFileToken(path string) *FileToken {
+	return &FileToken{
+		path: path,
+	}
+}
+
+// Value returns the token, reading the file when it changed since the
+// last read
+func (t *FileToken) Value() (string, error) {
+	info, err := os.Stat(t.path)
+	if err != nil {
+		return "", err
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if info.ModTime().Equal(t.modTime) && t.value != "" {
+		return t.value, nil
+	}
+	data, err := os.ReadFile(t.path)
+	if err != nil {
+		return "", err
+	}
+	t.value = strings.TrimSpace(string(data))
+	t.modTime = info.ModTime()
+	return t.value, nil
+}
+
+// Bearer requires one of its tokens as the bearer token of every request,
+// except those to its exempt paths, such as a health check
+type Bearer struct {
+	next   http.Handler
+	tokens []Token
+	exempt map[string]bool
+}
+
+// NewBearer creates a new instance of Bearer guarding next
+func NewBearer(next http.Handler, exempt []string, tokens ...Token) *Bearer {
+	b := &Bearer{
+		next:   next,
+		tokens: tokens,
+		exempt: make(map[string]bool, len(exempt)),
+	}
+	for _, path := range exempt {
+		b.exempt[path] = true
+	}
+	return b
+}
+
+// ServeHTTP answers 401 Unauthorized unless the request is exempt or
+// carries a valid token. Tokens that can't be read, or are empty, never
+// match.
+func (b *Bearer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if b.exempt[r.URL.Path] || b.authorized(r) {
+		b.next.ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("WWW-Authenticate", `Bearer realm="k8s-memory-watchdog"`)
+	http.Error(w, "unauthorized", http.StatusUnauthorized)
+}
+
+func (b *Bearer) authorized(r *http.Request) bool {
+	token, ok := BearerToken(r)
+	if !ok {
+		return false
+	}
+	given := []byte(token)
+	for _, token := range b.tokens {
+		value, err := token.Value()
+		if err != nil || value == "" {
+			continue
+		}
+		if subtle.ConstantTimeCompare(given, []byte(value)) == 1 {
+			return true
+		}
+	}
+	return false
+}
+
+// BearerToken returns the token of the Authorization header of r, which
+// must use the Bearer scheme, in any case
+func BearerToken(r *http.Request) (string, bool) {
+	const scheme = "Bearer "
+	header := r.Header.Get("Authorization")
+	if len(header) < len(scheme) || !strings.EqualFold(header[:len(scheme)], scheme) {
+		return "", false
+	}
+	return header[len(scheme):], true
+}
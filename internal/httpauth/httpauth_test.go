@@ -0,0 +1,75 @@
+package httpauth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestBearer(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	handler := NewBearer(next, []string{"/healthz"}, StaticToken("static"), NewFileToken(path), StaticToken(""))
+
+	tests := []struct {
+		name   string
+		path   string
+		auth   string
+		status int
+	}{
+		{name: "static token", path: "/metrics", auth: "Bearer static", status: http.StatusNoContent},
+		{name: "file token", path: "/metrics", auth: "Bearer from-file", status: http.StatusNoContent},
+		{name: "wrong token", path: "/metrics", auth: "Bearer guess", status: http.StatusUnauthorized},
+		{name: "empty token", path: "/metrics", auth: "Bearer ", status: http.StatusUnauthorized},
+		{name: "lowercase scheme", path: "/metrics", auth: "bearer static", status: http.StatusNoContent},
+		{name: "basic auth", path: "/metrics", auth: "Basic c3RhdGlj", status: http.StatusUnauthorized},
+		{name: "no scheme", path: "/metrics", auth: "static", status: http.StatusUnauthorized},
+		{name: "other scheme", path: "/metrics", auth: "Token static", status: http.StatusUnauthorized},
+		{name: "missing", path: "/metrics", status: http.StatusUnauthorized},
+		{name: "exempt", path: "/healthz", status: http.StatusNoContent},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Code != tt.status {
+				t.Errorf("ServeHTTP() status = %v, want %v", rec.Code, tt.status)
+			}
+		})
+	}
+}
+
+func TestFileTokenRotation(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	os.WriteFile(path, []byte("old"), 0600)
+	token := NewFileToken(path)
+	if value, err := token.Value(); err != nil || value != "old" {
+		t.Fatalf("Value() = %q, %v, want old", value, err)
+	}
+
+	os.WriteFile(path, []byte("new"), 0600)
+	// make the change visible on file systems with a coarse mtime
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if value, err := token.Value(); err != nil || value != "new" {
+		t.Errorf("Value() = %q, %v, want new", value, err)
+	}
+
+	os.Remove(path)
+	if _, err := token.Value(); err == nil {
+		t.Error("Value() error = nil, want an error for a missing file")
+	}
+}
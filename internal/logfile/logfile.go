@@ -0,0 +1,177 @@
+// Package logfile implements a log file rotated by size and age, with
+// optionally compressed backups, for hosts without a log collector.
+package logfile
+
+import (
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the time in the name of rotated files, such as
+// watchdog-2024-03-01T12-00-00.000.log
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Options configures the rotation of a File. A zero MaxSize or MaxAge
+// disables rotation by size or age, and a zero MaxBackups keeps every
+// backup.
+type Options struct {
+	MaxSize    int64
+	MaxAge     time.Duration
+	MaxBackups int
+	Compress   bool
+}
+
+// File is an io.Writer appending to a log file, which is renamed to a
+// timestamped backup and replaced by a new file once it grows beyond
+// MaxSize or gets older than MaxAge
+type File struct {
+	path    string
+	options Options
+	now     func() time.Time
+
+	mu     sync.Mutex
+	file   *os.File
+	size   int64
+	opened time.Time
+}
+
+// Open opens or creates the log file at path
+func Open(path string, options Options) (*File, error) {
+	f := &File{path: path, options: options, now: time.Now}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+func (f *File) open() error {
+	file, err := os.OpenFile(f.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return err
+	}
+	f.file = file
+	f.size = info.Size()
+	f.opened = f.now()
+	return nil
+}
+
+// Write appends p to the log file, rotating it first when p would make it
+// exceed MaxSize or it is older than MaxAge
+func (f *File) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.size > 0 && f.due(int64(len(p))) {
+		if err := f.rotate(); err != nil {
+			return 0, fmt.Errorf("rotating %s: %w", f.path, err)
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// due reports whether the file must be rotated before writing n bytes
+func (f *File) due(n int64) bool {
+	if f.options.MaxSize > 0 && f.size+n > f.options.MaxSize {
+		return true
+	}
+	return f.options.MaxAge > 0 && f.now().Sub(f.opened) >= f.options.MaxAge
+}
+
+// Close closes the log file
+func (f *File) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.file.Close()
+}
+
+// rotate renames the log file to a backup, opens a new one and removes the
+// backups beyond MaxBackups
+func (f *File) rotate() error {
+	if err := f.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(f.path)
+	backup := strings.TrimSuffix(f.path, ext) + "-" + f.now().Format(backupTimeFormat) + ext
+	if err := os.Rename(f.path, backup); err != nil {
+		return err
+	}
+	if err := f.open(); err != nil {
+		return err
+	}
+	if f.options.Compress {
+		if err := compress(backup); err != nil {
+			return err
+		}
+	}
+	return f.prune()
+}
+
+// compress replaces path by a gzip-compressed path.gz
+func compress(path string) error {
+	in, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(path+".gz", os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	if _, err := io.Copy(zw, in); err != nil {
+		out.Close()
+		return err
+	}
+	if err := zw.Close(); err != nil {
+		out.Close()
+		return err
+	}
+	if err := out.Close(); err != nil {
+		return err
+	}
+	return os.Remove(path)
+}
+
+// Backups returns the rotated files of the log file at path, oldest first
+func Backups(path string) ([]string, error) {
+	ext := filepath.Ext(path)
+	matches, err := filepath.Glob(strings.TrimSuffix(path, ext) + "-*" + ext + "*")
+	if err != nil {
+		return nil, err
+	}
+	// the timestamps sort chronologically
+	sort.Strings(matches)
+	return matches, nil
+}
+
+// prune removes the oldest backups beyond MaxBackups
+func (f *File) prune() error {
+	if f.options.MaxBackups <= 0 {
+		return nil
+	}
+	backups, err := Backups(f.path)
+	if err != nil {
+		return err
+	}
+	for len(backups) > f.options.MaxBackups {
+		if err := os.Remove(backups[0]); err != nil {
+			return err
+		}
+		backups = backups[1:]
+	}
+	return nil
+}
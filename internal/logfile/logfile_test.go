@@ -0,0 +1,89 @@
+package logfile
+
+import (
+	"compress/gzip"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRotation(t *testing.T) {
+	tests := []struct {
+		name        string
+		options     Options
+		advance     time.Duration
+		wantBackups int
+	}{
+		{name: "no rotation", options: Options{}, wantBackups: 0},
+		{name: "by size", options: Options{MaxSize: 25}, wantBackups: 3},
+		{name: "by size with max backups", options: Options{MaxSize: 25, MaxBackups: 2}, wantBackups: 2},
+		{name: "by age", options: Options{MaxAge: time.Hour}, advance: 30 * time.Minute, wantBackups: 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "watchdog.log")
+			now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+			f, err := Open(path, tt.options)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			f.now = func() time.Time { return now }
+			f.opened = now
+
+			for i := 0; i < 4; i++ {
+				if _, err := f.Write([]byte("line of twenty bytes\n")); err != nil {
+					t.Fatalf("Write() error = %v", err)
+				}
+				now = now.Add(tt.advance + time.Second)
+			}
+
+			backups, err := Backups(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(backups) != tt.wantBackups {
+				t.Errorf("backups = %v, want %d", backups, tt.wantBackups)
+			}
+			if _, err := os.Stat(path); err != nil {
+				t.Errorf("log file missing after rotation: %v", err)
+			}
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "watchdog.log")
+	f, err := Open(path, Options{MaxSize: 10, Compress: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	f.Write([]byte("first line\n"))
+	f.Write([]byte("second line\n"))
+
+	backups, _ := Backups(path)
+	if len(backups) != 1 || !strings.HasSuffix(backups[0], ".log.gz") {
+		t.Fatalf("backups = %v, want one compressed backup", backups)
+	}
+	in, err := os.Open(backups[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer in.Close()
+	zr, err := gzip.NewReader(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	content, _ := io.ReadAll(zr)
+	if string(content) != "first line\n" {
+		t.Errorf("backup = %q, want the first line", content)
+	}
+	current, _ := os.ReadFile(path)
+	if string(current) != "second line\n" {
+		t.Errorf("log file = %q, want the second line", current)
+	}
+}
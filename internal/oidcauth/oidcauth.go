@@ -0,0 +1,185 @@
+// Package oidcauth obtains the ID tokens of an OpenID Connect provider with
+// a refresh token, as Kubernetes API servers configured with an OIDC issuer
+// accept them.
+package oidcauth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/tokencache"
+)
+
+// RefreshSource returns the ID tokens of a client of an OIDC provider,
+// refreshing them with the refresh token of the client shortly before they
+// expire. ClientSecret and RefreshToken may refer to a file, as resolved by
+// secret.Resolve. Providers rotating refresh tokens are supported: the
+// latest refresh token received is used until the configured one changes.
+type RefreshSource struct {
+	issuer       string
+	clientID     string
+	clientSecret string
+	refreshToken string
+	client       *http.Client
+	cache        tokencache.Cache
+
+	// the token endpoint and the refresh tokens are only used by the fetch
+	// of the cache, which runs one at a time
+	endpoint  string
+	refreshed string
+	rotated   string
+}
+
+// NewRefreshSource creates a new instance of RefreshSource for the client
+// clientID of the provider at issuer
+func NewRefreshSource(issuer, clientID, clientSecret, refreshToken string) *RefreshSource {
+	return &RefreshSource{
+		issuer:       strings.TrimSuffix(issuer, "/"),
+		clientID:     clientID,
+		clientSecret: clientSecret,
+		refreshToken: refreshToken,
+		client:       http.DefaultClient,
+	}
+}
+
+// Token returns a cached or freshly refreshed ID token
+func (s *RefreshSource) Token(ctx context.Context) (string, error) {
+	return s.cache.Token(func(now time.Time) (string, time.Time, error) {
+		return s.refresh(ctx, now)
+	})
+}
+
+// Invalidate drops the cached token, such as one the API server rejected,
+// so the next call to Token refreshes it
+func (s *RefreshSource) Invalidate() {
+	s.cache.Invalidate()
+}
+
+// refresh exchanges the refresh token for a new ID token, returning it
+// with its expiry
+func (s *RefreshSource) refresh(ctx context.Context, now time.Time) (string, time.Time, error) {
+	if s.endpoint == "" {
+		endpoint, err := s.discover(ctx)
+		if err != nil {
+			return "", time.Time{}, err
+		}
+		s.endpoint = endpoint
+	}
+	refreshToken, err := secret.Resolve(ctx, s.refreshToken)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if refreshToken == s.refreshed && s.rotated != "" {
+		refreshToken = s.rotated
+	} else {
+		// the configured refresh token is new, such as a rotated file,
+		// and replaces the one last received from the provider
+		s.refreshed, s.rotated = refreshToken, ""
+	}
+	clientSecret, err := secret.Resolve(ctx, s.clientSecret)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "refresh_token")
+	form.Set("refresh_token", refreshToken)
+	form.Set("client_id", s.clientID)
+	if clientSecret != "" {
+		form.Set("client_secret", clientSecret)
+	}
+	req, err := http.NewRequest(http.MethodPost, s.endpoint, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	var response struct {
+		IDToken      string `json:"id_token"`
+		RefreshToken string `json:"refresh_token"`
+		ExpiresIn    int    `json:"expires_in"`
+	}
+	if err := s.do(ctx, req, &response); err != nil {
+		return "", time.Time{}, fmt.Errorf("token refresh: %v", err)
+	}
+	if response.IDToken == "" {
+		return "", time.Time{}, errors.New("token refresh: the response has no ID token")
+	}
+
+	expires, err := expiry(response.IDToken)
+	if err != nil {
+		if response.ExpiresIn <= 0 {
+			return "", time.Time{}, fmt.Errorf("token refresh: %v", err)
+		}
+		expires = now.Add(time.Duration(response.ExpiresIn) * time.Second)
+	}
+	if response.RefreshToken != "" && response.RefreshToken != refreshToken {
+		s.rotated = response.RefreshToken
+	}
+	return response.IDToken, expires, nil
+}
+
+// discover returns the token endpoint of the provider from its discovery
+// document
+func (s *RefreshSource) discover(ctx context.Context) (string, error) {
+	req, err := http.NewRequest(http.MethodGet, s.issuer+"/.well-known/openid-configuration", nil)
+	if err != nil {
+		return "", err
+	}
+	var document struct {
+		TokenEndpoint string `json:"token_endpoint"`
+	}
+	if err := s.do(ctx, req, &document); err != nil {
+		return "", fmt.Errorf("OIDC discovery: %v", err)
+	}
+	if document.TokenEndpoint == "" {
+		return "", errors.New("OIDC discovery: the provider has no token endpoint")
+	}
+	return document.TokenEndpoint, nil
+}
+
+// do sends req and decodes its JSON response into v
+func (s *RefreshSource) do(ctx context.Context, req *http.Request, v interface{}) error {
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+// expiry returns the exp claim of a JWT, without verifying its signature,
+// which is the API server's job
+func expiry(token string) (time.Time, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}, errors.New("the ID token is not a JWT")
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid ID token payload: %v", err)
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}, fmt.Errorf("invalid ID token payload: %v", err)
+	}
+	if claims.Exp == 0 {
+		return time.Time{}, errors.New("the ID token has no expiry")
+	}
+	return time.Unix(claims.Exp, 0), nil
+}
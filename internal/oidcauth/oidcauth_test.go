@@ -0,0 +1,100 @@
+package oidcauth
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// idToken returns an unsigned JWT expiring at exp
+func idToken(subject string, exp time.Time) string {
+	encode := base64.RawURLEncoding.EncodeToString
+	return encode([]byte(`{"alg":"none"}`)) + "." + encode([]byte(fmt.Sprintf(`{"sub":%q,"exp":%d}`, subject, exp.Unix()))) + ".sig"
+}
+
+func TestRefreshSource(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	var refreshTokens []string
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			fmt.Fprintf(w, `{"issuer":%q,"token_endpoint":%q}`, server.URL, server.URL+"/token")
+		case "/token":
+			r.ParseForm()
+			if r.Form.Get("grant_type") != "refresh_token" || r.Form.Get("client_id") != "watchdog" || r.Form.Get("client_secret") != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			refreshTokens = append(refreshTokens, r.Form.Get("refresh_token"))
+			// the provider rotates the refresh token on every use
+			fmt.Fprintf(w, `{"id_token":%q,"refresh_token":"rotated-%d","expires_in":300}`,
+				idToken(r.Form.Get("refresh_token"), now.Add(10*time.Minute)), len(refreshTokens))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	path := filepath.Join(t.TempDir(), "refresh-token")
+	if err := os.WriteFile(path, []byte("initial\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	source := NewRefreshSource(server.URL+"/", "watchdog", "secret", "file:"+path)
+	source.cache.Now = func() time.Time { return now }
+
+	first, err := source.Token(context.Background())
+	if err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+	if again, _ := source.Token(context.Background()); again != first {
+		t.Errorf("Token() = %q, want the cached %q", again, first)
+	}
+
+	// the token is refreshed a minute before it expires
+	now = now.Add(9 * time.Minute)
+	if token, _ := source.Token(context.Background()); token == first {
+		t.Error("Token() returned the token about to expire")
+	}
+	source.Invalidate()
+	source.Token(context.Background())
+
+	// a refresh token written to the file replaces the rotated ones
+	later := time.Now().Add(time.Minute)
+	if err := os.WriteFile(path, []byte("replaced\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	os.Chtimes(path, later, later)
+	source.Invalidate()
+	if _, err := source.Token(context.Background()); err != nil {
+		t.Fatalf("Token() error = %v", err)
+	}
+
+	expected := []string{"initial", "rotated-1", "rotated-2", "replaced"}
+	if fmt.Sprint(refreshTokens) != fmt.Sprint(expected) {
+		t.Errorf("refresh tokens used = %q, want %q", refreshTokens, expected)
+	}
+}
+
+func TestRefreshSourceRejected(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/.well-known/openid-configuration" {
+			fmt.Fprintf(w, `{"token_endpoint":"http://%s/token"}`, r.Host)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"error":"invalid_grant"}`))
+	}))
+	defer server.Close()
+
+	source := NewRefreshSource(server.URL, "watchdog", "", "expired")
+	if _, err := source.Token(context.Background()); err == nil {
+		t.Error("Token() error = nil, want the rejected refresh token reported")
+	}
+}
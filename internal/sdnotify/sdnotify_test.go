@@ -0,0 +1,61 @@
+package sdnotify
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestNotify(t *testing.T) {
+	defer os.Unsetenv("NOTIFY_SOCKET")
+	os.Unsetenv("NOTIFY_SOCKET")
+	if sent, err := Notify("READY=1"); sent || err != nil {
+		t.Errorf("Notify() = %v, %v, want false without a socket", sent, err)
+	}
+
+	path := filepath.Join(t.TempDir(), "notify.sock")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: path, Net: "unixgram"})
+	if err != nil {
+		t.Skipf("unixgram sockets unavailable: %v", err)
+	}
+	defer conn.Close()
+	os.Setenv("NOTIFY_SOCKET", path)
+
+	if sent, err := Notify("READY=1"); !sent || err != nil {
+		t.Fatalf("Notify() = %v, %v, want sent", sent, err)
+	}
+	buf := make([]byte, 64)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	n, err := conn.Read(buf)
+	if err != nil || string(buf[:n]) != "READY=1" {
+		t.Errorf("received %q, %v, want READY=1", buf[:n], err)
+	}
+}
+
+func TestWatchdogInterval(t *testing.T) {
+	tests := []struct {
+		usec     string
+		pid      string
+		interval time.Duration
+		ok       bool
+	}{
+		{usec: "", ok: false},
+		{usec: "30000000", interval: 30 * time.Second, ok: true},
+		{usec: "30000000", pid: strconv.Itoa(os.Getpid()), interval: 30 * time.Second, ok: true},
+		{usec: "30000000", pid: "1", ok: false},
+		{usec: "invalid", ok: false},
+	}
+	defer os.Unsetenv("WATCHDOG_USEC")
+	defer os.Unsetenv("WATCHDOG_PID")
+	for _, tt := range tests {
+		os.Setenv("WATCHDOG_USEC", tt.usec)
+		os.Setenv("WATCHDOG_PID", tt.pid)
+		interval, ok := WatchdogInterval()
+		if interval != tt.interval || ok != tt.ok {
+			t.Errorf("WatchdogInterval(%s, %s) = %v, %v, want %v, %v", tt.usec, tt.pid, interval, ok, tt.interval, tt.ok)
+		}
+	}
+}
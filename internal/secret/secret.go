@@ -0,0 +1,163 @@
+// Package secret resolves the credentials of the configuration, which may
+// be given literally, as a file such as a mounted Secret, or as a key of a
+// Kubernetes Secret, and reads them again when they are rotated.
+package secret
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Prefixes of the values referring to a credential instead of holding it
+const (
+	FilePrefix   = "file:"
+	SecretPrefix = "secret:"
+)
+
+// Reader reads the data of a Kubernetes Secret
+type Reader interface {
+	SecretData(ctx context.Context, namespace, name string) (map[string][]byte, error)
+}
+
+// Resolver resolves credential values. A value of "file:PATH" is the
+// content of the file, read again when it changes. A value of
+// "secret:NAMESPACE/NAME/KEY" is the key of a Kubernetes Secret, read
+// again once it is older than the refresh interval; the last value read
+// is kept while the Secret can't be. Surrounding whitespace is ignored.
+// Any other value is the credential itself.
+type Resolver struct {
+	reader  Reader
+	refresh time.Duration
+	now     func() time.Time
+
+	mu      sync.Mutex
+	files   map[string]file
+	secrets map[string]secret
+}
+
+// file is a credential file as last read
+type file struct {
+	value   string
+	modTime time.Time
+}
+
+// secret is the data of a Kubernetes Secret as last read
+type secret struct {
+	data    map[string][]byte
+	fetched time.Time
+}
+
+// NewResolver creates a new instance of Resolver reading Kubernetes
+// Secrets with reader every refresh. References to Secrets fail without a
+// reader.
+func NewResolver(reader Reader, refresh time.Duration) *Resolver {
+	return &Resolver{
+		reader:  reader,
+		refresh: refresh,
+		now:     time.Now,
+		files:   make(map[string]file),
+		secrets: make(map[string]secret),
+	}
+}
+
+// Resolve returns the credential of value
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	switch {
+	case strings.HasPrefix(value, FilePrefix):
+		return r.file(strings.TrimPrefix(value, FilePrefix))
+	case strings.HasPrefix(value, SecretPrefix):
+		namespace, name, key, err := ParseSecretRef(value)
+		if err != nil {
+			return "", err
+		}
+		return r.secret(ctx, namespace, name, key)
+	}
+	return value, nil
+}
+
+// file returns the content of a credential file, reading it when it
+// changed since the last read
+func (r *Resolver) file(path string) (string, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("credential file: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if cached, ok := r.files[path]; ok && info.ModTime().Equal(cached.modTime) {
+		return cached.value, nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("credential file: %w", err)
+	}
+	value := strings.TrimSpace(string(data))
+	r.files[path] = file{value: value, modTime: info.ModTime()}
+	return value, nil
+}
+
+// secret returns a key of a Kubernetes Secret, reading the Secret when the
+// last read is older than the refresh interval
+func (r *Resolver) secret(ctx context.Context, namespace, name, key string) (string, error) {
+	if r.reader == nil {
+		return "", fmt.Errorf("secret %s/%s: Kubernetes Secrets can't be read here", namespace, name)
+	}
+	id := namespace + "/" + name
+
+	r.mu.Lock()
+	cached, ok := r.secrets[id]
+	r.mu.Unlock()
+	if !ok || r.now().Sub(cached.fetched) >= r.refresh {
+		data, err := r.reader.SecretData(ctx, namespace, name)
+		if err != nil && !ok {
+			return "", fmt.Errorf("secret %s: %w", id, err)
+		}
+		if err == nil {
+			cached = secret{data: data, fetched: r.now()}
+			r.mu.Lock()
+			r.secrets[id] = cached
+			r.mu.Unlock()
+		}
+	}
+
+	value, ok := cached.data[key]
+	if !ok {
+		return "", fmt.Errorf("secret %s has no key '%s'", id, key)
+	}
+	return strings.TrimSpace(string(value)), nil
+}
+
+// ParseSecretRef splits a "secret:NAMESPACE/NAME/KEY" reference
+func ParseSecretRef(value string) (namespace, name, key string, err error) {
+	parts := strings.Split(strings.TrimPrefix(value, SecretPrefix), "/")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return "", "", "", fmt.Errorf("invalid secret reference '%s', want secret:NAMESPACE/NAME/KEY", value)
+	}
+	return parts[0], parts[1], parts[2], nil
+}
+
+var (
+	defaultMu       sync.Mutex
+	defaultResolver = NewResolver(nil, time.Minute)
+)
+
+// SetDefault replaces the resolver of Resolve
+func SetDefault(r *Resolver) {
+	defaultMu.Lock()
+	defer defaultMu.Unlock()
+	defaultResolver = r
+}
+
+// Resolve returns the credential of value with the default resolver, which
+// reads files but no Kubernetes Secrets unless replaced with SetDefault
+func Resolve(ctx context.Context, value string) (string, error) {
+	defaultMu.Lock()
+	r := defaultResolver
+	defaultMu.Unlock()
+	return r.Resolve(ctx, value)
+}
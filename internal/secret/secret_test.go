@@ -0,0 +1,93 @@
+package secret
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// fakeReader serves the data of Secrets, counting the reads
+type fakeReader struct {
+	data  map[string]map[string][]byte
+	err   error
+	reads int
+}
+
+func (r *fakeReader) SecretData(ctx context.Context, namespace, name string) (map[string][]byte, error) {
+	r.reads++
+	if r.err != nil {
+		return nil, r.err
+	}
+	data, ok := r.data[namespace+"/"+name]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return data, nil
+}
+
+func TestResolveFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	if err := os.WriteFile(path, []byte("first\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	r := NewResolver(nil, time.Minute)
+
+	if got, err := r.Resolve(context.Background(), "file:"+path); err != nil || got != "first" {
+		t.Fatalf("Resolve() = %q, %v, want first", got, err)
+	}
+	// rotate the file, as the kubelet does for a mounted Secret
+	if err := os.WriteFile(path, []byte("second"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if got, _ := r.Resolve(context.Background(), "file:"+path); got != "second" {
+		t.Errorf("Resolve() = %q after rotation, want second", got)
+	}
+	if _, err := r.Resolve(context.Background(), "file:"+path+".missing"); err == nil {
+		t.Error("Resolve() error = nil, want an error for a missing file")
+	}
+	if got, _ := r.Resolve(context.Background(), "literal-key"); got != "literal-key" {
+		t.Errorf("Resolve() = %q, want the literal value", got)
+	}
+}
+
+func TestResolveSecret(t *testing.T) {
+	reader := &fakeReader{data: map[string]map[string][]byte{
+		"monitoring/api-keys": {"datadog": []byte("first\n")},
+	}}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	r := NewResolver(reader, time.Minute)
+	r.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	if got, err := r.Resolve(ctx, "secret:monitoring/api-keys/datadog"); err != nil || got != "first" {
+		t.Fatalf("Resolve() = %q, %v, want first", got, err)
+	}
+	reader.data["monitoring/api-keys"] = map[string][]byte{"datadog": []byte("second")}
+	if got, _ := r.Resolve(ctx, "secret:monitoring/api-keys/datadog"); got != "first" || reader.reads != 1 {
+		t.Errorf("Resolve() = %q after %d reads, want the cached value", got, reader.reads)
+	}
+	now = now.Add(time.Minute)
+	if got, _ := r.Resolve(ctx, "secret:monitoring/api-keys/datadog"); got != "second" {
+		t.Errorf("Resolve() = %q after the refresh interval, want second", got)
+	}
+
+	reader.err = errors.New("connection refused")
+	now = now.Add(time.Minute)
+	if got, err := r.Resolve(ctx, "secret:monitoring/api-keys/datadog"); err != nil || got != "second" {
+		t.Errorf("Resolve() = %q, %v, want the last value read while the Secret can't be", got, err)
+	}
+
+	for _, value := range []string{"secret:monitoring/api-keys/newrelic", "secret:monitoring/other/key", "secret:api-keys/datadog"} {
+		if _, err := r.Resolve(ctx, value); err == nil {
+			t.Errorf("Resolve(%q) error = nil, want an error", value)
+		}
+	}
+	if _, err := NewResolver(nil, time.Minute).Resolve(ctx, "secret:monitoring/api-keys/datadog"); err == nil {
+		t.Error("Resolve() error = nil, want an error without a reader")
+	}
+}
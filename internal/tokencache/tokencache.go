@@ -0,0 +1,57 @@
+// Package tokencache caches the bearer tokens of the token sources until
+// shortly before they expire.
+package tokencache
+
+import (
+	"sync"
+	"time"
+)
+
+// Early is how long before it expires a token is fetched again, so that
+// in-flight requests don't use an expired token
+const Early = time.Minute
+
+// Fetch returns a new token and when it expires, given the current time
+type Fetch func(now time.Time) (string, time.Time, error)
+
+// Cache holds the current token of a source. The zero value is an empty
+// cache reading the time with time.Now.
+type Cache struct {
+	// Now returns the current time, time.Now when nil
+	Now func() time.Time
+
+	mu      sync.Mutex
+	token   string
+	expires time.Time
+}
+
+// Token returns the cached token, or the one returned by fetch once the
+// cached token is about to expire. Concurrent callers wait for a single
+// fetch.
+func (c *Cache) Token(fetch Fetch) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	if c.Now != nil {
+		now = c.Now()
+	}
+	if c.token != "" && now.Before(c.expires) {
+		return c.token, nil
+	}
+	token, expires, err := fetch(now)
+	if err != nil {
+		return "", err
+	}
+	c.token = token
+	c.expires = expires.Add(-Early)
+	return c.token, nil
+}
+
+// Invalidate drops the cached token, such as one that was rejected, so the
+// next call to Token fetches a new one
+func (c *Cache) Invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.token = ""
+}
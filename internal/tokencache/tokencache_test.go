@@ -0,0 +1,45 @@
+package tokencache
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC)
+	cache := Cache{Now: func() time.Time { return now }}
+	fetches := 0
+	fetch := func(at time.Time) (string, time.Time, error) {
+		fetches++
+		return fmt.Sprintf("token-%d", fetches), at.Add(10 * time.Minute), nil
+	}
+
+	for _, tt := range []struct {
+		after time.Duration
+		token string
+	}{
+		{0, "token-1"},
+		{8 * time.Minute, "token-1"},
+		// a minute before it expires
+		{time.Minute, "token-2"},
+	} {
+		now = now.Add(tt.after)
+		if token, err := cache.Token(fetch); err != nil || token != tt.token {
+			t.Errorf("Token() after %s = %q, %v, want %q", tt.after, token, err, tt.token)
+		}
+	}
+
+	cache.Invalidate()
+	if token, _ := cache.Token(fetch); token != "token-3" {
+		t.Errorf("Token() after Invalidate() = %q, want token-3", token)
+	}
+
+	failing := Cache{}
+	if token, err := failing.Token(func(time.Time) (string, time.Time, error) {
+		return "", time.Time{}, errors.New("unauthorized")
+	}); err == nil || token != "" {
+		t.Errorf("Token() = %q, %v, want the error of fetch", token, err)
+	}
+}
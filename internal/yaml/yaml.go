@@ -0,0 +1,589 @@
+// Package yaml implements the subset of YAML used by the watchdog
+// configuration file.
+package yaml
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// yamlNode is a parsed YAML value. Only the subset of YAML used by the
+// watchdog configuration is supported: block mappings, block sequences,
+// plain and quoted scalars, literal block scalars and simple flow collections.
+type yamlNode struct {
+	kind   yamlKind
+	value  string
+	keys   []string
+	values []*yamlNode
+	items  []*yamlNode
+	line   int
+	// keyLines holds the line of each key of a mapping
+	keyLines []int
+}
+
+type yamlKind int
+
+const (
+	yamlScalar yamlKind = iota
+	yamlMapping
+	yamlSequence
+)
+
+type yamlLine struct {
+	indent int
+	text   string
+	number int
+}
+
+type yamlParser struct {
+	raw   []string
+	lines []yamlLine
+	pos   int
+}
+
+// Unmarshal decodes YAML data into the value pointed to by v. Struct fields
+// are matched by their yaml tag, or by their lowercased name when untagged.
+// Keys that match no field are ignored.
+func Unmarshal(data []byte, v interface{}) error {
+	return unmarshal(data, v, nil)
+}
+
+// UnmarshalStrict decodes YAML data like Unmarshal, but fails with an
+// *UnknownFieldsError when keys match no field. The rest of the data is
+// still decoded into v.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	var unknown []UnknownField
+	if err := unmarshal(data, v, &unknown); err != nil {
+		return err
+	}
+	if len(unknown) > 0 {
+		return &UnknownFieldsError{Fields: unknown}
+	}
+	return nil
+}
+
+// UnknownField is a key of the YAML data that matches no struct field. Path
+// is the dotted path of the key, such as targets[0].name.
+type UnknownField struct {
+	Line int
+	Path string
+}
+
+func (f UnknownField) String() string {
+	return fmt.Sprintf("line %d: unknown field '%s'", f.Line, f.Path)
+}
+
+// UnknownFieldsError lists the keys rejected by UnmarshalStrict
+type UnknownFieldsError struct {
+	Fields []UnknownField
+}
+
+func (e *UnknownFieldsError) Error() string {
+	lines := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		lines[i] = f.String()
+	}
+	return "yaml: " + strings.Join(lines, "; ")
+}
+
+func unmarshal(data []byte, v interface{}, unknown *[]UnknownField) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() {
+		return fmt.Errorf("yaml: decode target must be a non-nil pointer")
+	}
+
+	root, err := parseYAML(string(data))
+	if err != nil {
+		return err
+	}
+	if root == nil {
+		return nil
+	}
+	d := decoder{unknown: unknown}
+	return d.decode(root, rv.Elem(), "")
+}
+
+func parseYAML(data string) (*yamlNode, error) {
+	p := &yamlParser{raw: strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")}
+	for i, raw := range p.raw {
+		if strings.HasPrefix(strings.TrimLeft(raw, " "), "\t") && strings.TrimSpace(raw) != "" {
+			return nil, fmt.Errorf("yaml: line %d: tabs are not allowed for indentation", i+1)
+		}
+		text := strings.TrimRight(stripYAMLComment(raw), " ")
+		trimmed := strings.TrimLeft(text, " ")
+		if trimmed == "" || trimmed == "---" {
+			continue
+		}
+		p.lines = append(p.lines, yamlLine{indent: len(text) - len(trimmed), text: trimmed, number: i + 1})
+	}
+	if len(p.lines) == 0 {
+		return nil, nil
+	}
+
+	node, err := p.parseBlock(p.lines[0].indent)
+	if err != nil {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, fmt.Errorf("yaml: line %d: unexpected indentation", p.lines[p.pos].number)
+	}
+	return node, nil
+}
+
+func (p *yamlParser) parseBlock(indent int) (*yamlNode, error) {
+	if isYAMLSeqItem(p.lines[p.pos].text) {
+		return p.parseSequence(indent)
+	}
+	return p.parseMapping(indent)
+}
+
+func (p *yamlParser) parseMapping(indent int) (*yamlNode, error) {
+	node := &yamlNode{kind: yamlMapping, line: p.lines[p.pos].number}
+
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent < indent {
+			break
+		}
+		if line.indent > indent {
+			return nil, fmt.Errorf("yaml: line %d: unexpected indentation", line.number)
+		}
+		if isYAMLSeqItem(line.text) {
+			break
+		}
+
+		key, rest, ok := splitYAMLKey(line.text)
+		if !ok {
+			return nil, fmt.Errorf("yaml: line %d: expected a 'key: value' pair", line.number)
+		}
+		p.pos++
+
+		value, err := p.parseValue(rest, indent, line.number, true)
+		if err != nil {
+			return nil, err
+		}
+		node.keys = append(node.keys, key)
+		node.values = append(node.values, value)
+		node.keyLines = append(node.keyLines, line.number)
+	}
+
+	return node, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (*yamlNode, error) {
+	node := &yamlNode{kind: yamlSequence, line: p.lines[p.pos].number}
+
+	for p.pos < len(p.lines) {
+		line := p.lines[p.pos]
+		if line.indent != indent || !isYAMLSeqItem(line.text) {
+			if line.indent > indent {
+				return nil, fmt.Errorf("yaml: line %d: unexpected indentation", line.number)
+			}
+			break
+		}
+
+		rest := strings.TrimLeft(strings.TrimPrefix(line.text, "-"), " ")
+		if _, _, isKey := splitYAMLKey(rest); isKey && rest != "" {
+			// "- key: value" starts a mapping indented at the key's column
+			p.lines[p.pos] = yamlLine{
+				indent: line.indent + len(line.text) - len(rest),
+				text:   rest,
+				number: line.number,
+			}
+			item, err := p.parseMapping(p.lines[p.pos].indent)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, item)
+			continue
+		}
+
+		p.pos++
+		item, err := p.parseValue(rest, indent, line.number, false)
+		if err != nil {
+			return nil, err
+		}
+		node.items = append(node.items, item)
+	}
+
+	return node, nil
+}
+
+// parseValue parses the value following a mapping key or sequence dash.
+// An empty value is either a nested block or null.
+func (p *yamlParser) parseValue(rest string, indent, number int, allowSameIndentSeq bool) (*yamlNode, error) {
+	switch {
+	case rest == "":
+		if p.pos < len(p.lines) {
+			next := p.lines[p.pos]
+			if next.indent > indent {
+				return p.parseBlock(next.indent)
+			}
+			if allowSameIndentSeq && next.indent == indent && isYAMLSeqItem(next.text) {
+				return p.parseSequence(indent)
+			}
+		}
+		return nil, nil
+	case rest == "|" || rest == "|-" || rest == ">" || rest == ">-":
+		return p.parseBlockScalar(rest, indent, number), nil
+	default:
+		return parseYAMLInline(rest, number)
+	}
+}
+
+// parseBlockScalar reads a literal (|) or folded (>) block scalar from the
+// raw lines, since comments and blank lines are significant inside it
+func (p *yamlParser) parseBlockScalar(style string, indent, number int) *yamlNode {
+	var body []string
+	blockIndent := -1
+	last := number
+
+	for i := number; i < len(p.raw); i++ {
+		raw := strings.TrimRight(p.raw[i], " ")
+		trimmed := strings.TrimLeft(raw, " ")
+		if trimmed == "" {
+			body = append(body, "")
+			continue
+		}
+		lineIndent := len(raw) - len(trimmed)
+		if lineIndent <= indent {
+			break
+		}
+		if blockIndent < 0 {
+			blockIndent = lineIndent
+		}
+		if lineIndent < blockIndent {
+			break
+		}
+		body = append(body, raw[blockIndent:])
+		last = i + 1
+	}
+
+	for p.pos < len(p.lines) && p.lines[p.pos].number <= last {
+		p.pos++
+	}
+
+	for len(body) > 0 && body[len(body)-1] == "" {
+		body = body[:len(body)-1]
+	}
+
+	sep := "\n"
+	if strings.HasPrefix(style, ">") {
+		sep = " "
+	}
+	value := strings.Join(body, sep)
+	if !strings.HasSuffix(style, "-") && value != "" {
+		value += "\n"
+	}
+	return &yamlNode{kind: yamlScalar, value: value, line: number}
+}
+
+func parseYAMLInline(text string, number int) (*yamlNode, error) {
+	text = strings.TrimSpace(text)
+
+	switch {
+	case strings.HasPrefix(text, "["):
+		if !strings.HasSuffix(text, "]") {
+			return nil, fmt.Errorf("yaml: line %d: unterminated flow sequence", number)
+		}
+		node := &yamlNode{kind: yamlSequence, line: number}
+		for _, part := range splitYAMLFlow(text[1 : len(text)-1]) {
+			item, err := parseYAMLInline(part, number)
+			if err != nil {
+				return nil, err
+			}
+			node.items = append(node.items, item)
+		}
+		return node, nil
+	case strings.HasPrefix(text, "{"):
+		if !strings.HasSuffix(text, "}") {
+			return nil, fmt.Errorf("yaml: line %d: unterminated flow mapping", number)
+		}
+		node := &yamlNode{kind: yamlMapping, line: number}
+		for _, part := range splitYAMLFlow(text[1 : len(text)-1]) {
+			key, rest, ok := splitYAMLKey(part)
+			if !ok {
+				return nil, fmt.Errorf("yaml: line %d: expected 'key: value' in flow mapping", number)
+			}
+			value, err := parseYAMLInline(rest, number)
+			if err != nil {
+				return nil, err
+			}
+			node.keys = append(node.keys, key)
+			node.values = append(node.values, value)
+			node.keyLines = append(node.keyLines, number)
+		}
+		return node, nil
+	case text == "~" || text == "null" || text == "":
+		return nil, nil
+	}
+
+	value, err := unquoteYAML(text)
+	if err != nil {
+		return nil, fmt.Errorf("yaml: line %d: %v", number, err)
+	}
+	return &yamlNode{kind: yamlScalar, value: value, line: number}, nil
+}
+
+func unquoteYAML(text string) (string, error) {
+	if len(text) >= 2 && text[0] == '"' && text[len(text)-1] == '"' {
+		return strconv.Unquote(text)
+	}
+	if len(text) >= 2 && text[0] == '\'' && text[len(text)-1] == '\'' {
+		return strings.ReplaceAll(text[1:len(text)-1], "''", "'"), nil
+	}
+	return text, nil
+}
+
+func isYAMLSeqItem(text string) bool {
+	return text == "-" || strings.HasPrefix(text, "- ")
+}
+
+// splitYAMLKey splits "key: value" outside of quotes
+func splitYAMLKey(text string) (key, rest string, ok bool) {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 {
+				quote = c
+			}
+		case c == '[' || c == '{':
+			if i == 0 {
+				return "", "", false
+			}
+		case c == ':' && (i == len(text)-1 || text[i+1] == ' '):
+			key, err := unquoteYAML(strings.TrimSpace(text[:i]))
+			if err != nil {
+				return "", "", false
+			}
+			return key, strings.TrimSpace(text[i+1:]), true
+		}
+	}
+	return "", "", false
+}
+
+func splitYAMLFlow(text string) []string {
+	var parts []string
+	var quote byte
+	depth, start := 0, 0
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '[' || c == '{':
+			depth++
+		case c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(text[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(text[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
+
+func stripYAMLComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			if i == 0 || line[i-1] == ' ' || line[i-1] == '[' || line[i-1] == ',' {
+				quote = c
+			}
+		case c == '#' && (i == 0 || line[i-1] == ' '):
+			return line[:i]
+		}
+	}
+	return line
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// decoder decodes parsed YAML into Go values, collecting the keys that
+// match no struct field into unknown when it isn't nil
+type decoder struct {
+	unknown *[]UnknownField
+}
+
+func (d decoder) decode(node *yamlNode, v reflect.Value, path string) error {
+	if node == nil {
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.decode(node, v.Elem(), path)
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		if node.kind != yamlMapping {
+			return fmt.Errorf("yaml: line %d: expected a mapping", node.line)
+		}
+		fields := yamlFields(v.Type())
+		for i, key := range node.keys {
+			index, ok := fields[key]
+			if !ok {
+				if d.unknown != nil {
+					*d.unknown = append(*d.unknown, UnknownField{Line: node.keyLines[i], Path: joinPath(path, key)})
+				}
+				continue
+			}
+			if err := d.decode(node.values[i], v.Field(index), joinPath(path, key)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if node.kind != yamlMapping {
+			return fmt.Errorf("yaml: line %d: expected a mapping", node.line)
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for i, key := range node.keys {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := d.decode(node.values[i], elem, joinPath(path, key)); err != nil {
+				return err
+			}
+			v.SetMapIndex(reflect.ValueOf(key).Convert(v.Type().Key()), elem)
+		}
+	case reflect.Slice:
+		if node.kind != yamlSequence {
+			return fmt.Errorf("yaml: line %d: expected a sequence", node.line)
+		}
+		slice := reflect.MakeSlice(v.Type(), len(node.items), len(node.items))
+		for i, item := range node.items {
+			if err := d.decode(item, slice.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(yamlGeneric(node)))
+	default:
+		if node.kind != yamlScalar {
+			return fmt.Errorf("yaml: line %d: expected a scalar value", node.line)
+		}
+		return decodeYAMLScalar(node, v)
+	}
+
+	return nil
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+func decodeYAMLScalar(node *yamlNode, v reflect.Value) error {
+	value := node.value
+
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("yaml: line %d: invalid duration %q", node.line, value)
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(value)
+	case v.Kind() == reflect.Bool:
+		switch strings.ToLower(value) {
+		case "true", "yes", "on":
+			v.SetBool(true)
+		case "false", "no", "off":
+			v.SetBool(false)
+		default:
+			return fmt.Errorf("yaml: line %d: invalid boolean %q", node.line, value)
+		}
+	case v.Kind() >= reflect.Int && v.Kind() <= reflect.Int64:
+		n, err := strconv.ParseInt(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("yaml: line %d: invalid integer %q", node.line, value)
+		}
+		v.SetInt(n)
+	case v.Kind() >= reflect.Uint && v.Kind() <= reflect.Uint64:
+		n, err := strconv.ParseUint(value, 10, 64)
+		if err != nil {
+			return fmt.Errorf("yaml: line %d: invalid unsigned integer %q", node.line, value)
+		}
+		v.SetUint(n)
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return fmt.Errorf("yaml: line %d: invalid number %q", node.line, value)
+		}
+		v.SetFloat(f)
+	default:
+		return fmt.Errorf("yaml: line %d: unsupported field type %s", node.line, v.Type())
+	}
+
+	return nil
+}
+
+func yamlFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(field.Tag.Get("yaml"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+func yamlGeneric(node *yamlNode) interface{} {
+	if node == nil {
+		return nil
+	}
+	switch node.kind {
+	case yamlMapping:
+		m := make(map[string]interface{}, len(node.keys))
+		for i, key := range node.keys {
+			m[key] = yamlGeneric(node.values[i])
+		}
+		return m
+	case yamlSequence:
+		items := make([]interface{}, len(node.items))
+		for i, item := range node.items {
+			items[i] = yamlGeneric(item)
+		}
+		return items
+	default:
+		return node.value
+	}
+}
@@ -0,0 +1,137 @@
+package yaml
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestUnmarshalYAML(t *testing.T) {
+	type nested struct {
+		Level string `yaml:"level"`
+		Port  int    `yaml:"port"`
+	}
+	type document struct {
+		Name     string            `yaml:"name"`
+		Enabled  bool              `yaml:"enabled"`
+		Ratio    float64           `yaml:"ratio"`
+		Interval time.Duration     `yaml:"interval"`
+		Tags     []string          `yaml:"tags"`
+		Labels   map[string]string `yaml:"labels"`
+		Nested   nested            `yaml:"nested"`
+		Items    []nested          `yaml:"items"`
+		Body     string            `yaml:"body"`
+	}
+
+	input := `# comment
+name: "watchdog"  # trailing comment
+enabled: true
+ratio: 0.75
+interval: 5m
+tags: [a, 'b', "c"]
+labels:
+  team: platform
+  url: http://example.com/#anchor
+nested:
+  level: debug
+  port: 9090
+items:
+- level: info
+  port: 1
+-   level: warn
+    port: 2
+body: |
+  line one
+
+  line two
+`
+
+	var doc document
+	if err := Unmarshal([]byte(input), &doc); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if doc.Name != "watchdog" || !doc.Enabled || doc.Ratio != 0.75 || doc.Interval != 5*time.Minute {
+		t.Errorf("Unmarshal() scalars = %+v", doc)
+	}
+	if len(doc.Tags) != 3 || doc.Tags[1] != "b" || doc.Tags[2] != "c" {
+		t.Errorf("Unmarshal() tags = %v", doc.Tags)
+	}
+	if doc.Labels["team"] != "platform" || doc.Labels["url"] != "http://example.com/#anchor" {
+		t.Errorf("Unmarshal() labels = %v", doc.Labels)
+	}
+	if doc.Nested.Level != "debug" || doc.Nested.Port != 9090 {
+		t.Errorf("Unmarshal() nested = %+v", doc.Nested)
+	}
+	if len(doc.Items) != 2 || doc.Items[1].Level != "warn" || doc.Items[1].Port != 2 {
+		t.Errorf("Unmarshal() items = %+v", doc.Items)
+	}
+	if doc.Body != "line one\n\nline two\n" {
+		t.Errorf("Unmarshal() body = %q", doc.Body)
+	}
+}
+
+func TestUnmarshalYAMLErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+	}{
+		{name: "bad indentation", input: "a: 1\n  b: 2\n"},
+		{name: "invalid integer", input: "port: abc\n"},
+		{name: "invalid duration", input: "interval: soon\n"},
+		{name: "missing colon", input: "just text\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var doc struct {
+				A        int           `yaml:"a"`
+				Port     int           `yaml:"port"`
+				Interval time.Duration `yaml:"interval"`
+			}
+			if err := Unmarshal([]byte(tt.input), &doc); err == nil {
+				t.Errorf("Unmarshal() expected error for %q", tt.input)
+			}
+		})
+	}
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	var doc struct {
+		Name    string `yaml:"name"`
+		Targets []struct {
+			Name string `yaml:"name"`
+		} `yaml:"targets"`
+		Labels map[string]string `yaml:"labels"`
+	}
+	input := `name: watchdog
+nmae: typo
+labels:
+  team: platform
+targets:
+  - name: api
+    threshold: 10
+  - {name: worker, port: 8080}
+`
+	err := UnmarshalStrict([]byte(input), &doc)
+	var unknown *UnknownFieldsError
+	if !errors.As(err, &unknown) {
+		t.Fatalf("UnmarshalStrict() error = %v, want *UnknownFieldsError", err)
+	}
+	want := []UnknownField{
+		{Line: 2, Path: "nmae"},
+		{Line: 7, Path: "targets[0].threshold"},
+		{Line: 8, Path: "targets[1].port"},
+	}
+	if !reflect.DeepEqual(unknown.Fields, want) {
+		t.Errorf("UnmarshalStrict() unknown fields = %+v, want %+v", unknown.Fields, want)
+	}
+	if doc.Name != "watchdog" || len(doc.Targets) != 2 || doc.Targets[1].Name != "worker" || doc.Labels["team"] != "platform" {
+		t.Errorf("UnmarshalStrict() did not decode the known fields: %+v", doc)
+	}
+
+	if err := Unmarshal([]byte(input), &doc); err != nil {
+		t.Errorf("Unmarshal() error = %v, want unknown fields ignored", err)
+	}
+}
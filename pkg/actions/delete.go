@@ -0,0 +1,56 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// deleteRestart deletes the pods of deployment one at a time, waiting for
+// each to be gone, for the deployment to have all its replicas ready again
+// and then for the delay in ctx before the next, so only one replica is away
+// at a time. The grace period in ctx, when set, overrides the
+// terminationGracePeriodSeconds of the pods.
+func (k *KubectlRestarter) deleteRestart(ctx context.Context, namespace, deployment string) error {
+	selector, err := k.selector(ctx, namespace, deployment)
+	if err != nil {
+		return err
+	}
+	out, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "get", "pods", "-n", namespace, "-l", selector, "-o", "name")
+	if err != nil {
+		return err
+	}
+	pods := strings.Fields(string(out))
+	if len(pods) == 0 {
+		return fmt.Errorf("%w: deployment '%s' has no pods to delete", watchdog.ErrRestartFailed, deployment)
+	}
+	replicas, err := k.replicas(ctx, namespace, deployment)
+	if err != nil {
+		return err
+	}
+
+	gracePeriod, delay := watchdog.PodDeletion(ctx)
+	for i, pod := range pods {
+		if i > 0 && delay > 0 {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("%w: deleted %d of %d pods: %v", watchdog.ErrRestartFailed, i, len(pods), ctx.Err())
+			case <-time.After(delay):
+			}
+		}
+		args := []string{"delete", pod, "-n", namespace, "--ignore-not-found", "--wait=true"}
+		if gracePeriod > 0 {
+			args = append(args, fmt.Sprintf("--grace-period=%d", int(gracePeriod/time.Second)))
+		}
+		if _, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, args...); err != nil {
+			return err
+		}
+		if err := k.waitForReady(ctx, namespace, deployment, replicas); err != nil {
+			return fmt.Errorf("%w: waiting for the replacement of %s: %v", watchdog.ErrRestartFailed, pod, err)
+		}
+	}
+	return nil
+}
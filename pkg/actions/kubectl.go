@@ -0,0 +1,92 @@
+// Package actions implements the remediation performed by the watchdog when
+// a target exceeds its threshold.
+package actions
+
+import (
+	"context"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Annotations recording on a restarted deployment the check and action that
+// restarted it
+const (
+	AnnotationCheckID  = "k8s-memory-watchdog/check-id"
+	AnnotationActionID = "k8s-memory-watchdog/action-id"
+)
+
+// KubectlRestarter restarts deployments with kubectl rollout restart, by
+// scaling them to zero and back with watchdog.RestartScale, or by deleting
+// their pods one at a time with watchdog.RestartDelete
+type KubectlRestarter struct {
+	runner *kubectl.Runner
+	poll   time.Duration
+}
+
+// NewKubectlRestarter creates a new instance of KubectlRestarter
+func NewKubectlRestarter(runner *kubectl.Runner) *KubectlRestarter {
+	return &KubectlRestarter{
+		runner: runner,
+		poll:   2 * time.Second,
+	}
+}
+
+// RestartDeployment restarts the specified deployment with the restart
+// strategy in ctx. The IDs of the check and action in ctx are first
+// annotated on the deployment.
+func (k *KubectlRestarter) RestartDeployment(ctx context.Context, namespace, deployment string) error {
+	var annotations []string
+	if id := watchdog.CheckID(ctx); id != "" {
+		annotations = append(annotations, AnnotationCheckID+"="+id)
+	}
+	if id := watchdog.ActionID(ctx); id != "" {
+		annotations = append(annotations, AnnotationActionID+"="+id)
+	}
+	if len(annotations) > 0 {
+		args := append([]string{"annotate", "deployment/" + deployment, "-n", namespace, "--overwrite"}, annotations...)
+		if _, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, args...); err != nil {
+			return err
+		}
+	}
+
+	if surge := watchdog.Surge(ctx); surge > 0 {
+		return k.surgeRestart(ctx, namespace, deployment, surge)
+	}
+	if err := k.restart(ctx, namespace, deployment); err != nil {
+		return err
+	}
+	if watchdog.RolloutCheck(ctx) {
+		return k.rolloutStatus(ctx, namespace, deployment)
+	}
+	return nil
+}
+
+// rolloutStatus waits until the restart of deployment has rolled out,
+// failing with watchdog.ErrRolloutNotReady when it doesn't
+func (k *KubectlRestarter) rolloutStatus(ctx context.Context, namespace, deployment string) error {
+	if _, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "rollout", "status", "deployment/"+deployment, "-n", namespace); err != nil {
+		return watchdog.RolloutNotReady(err)
+	}
+	return nil
+}
+
+// UndoRollout rolls deployment back to its previous revision
+func (k *KubectlRestarter) UndoRollout(ctx context.Context, namespace, deployment string) error {
+	_, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "rollout", "undo", "deployment/"+deployment, "-n", namespace)
+	return err
+}
+
+// restart restarts deployment with the restart strategy in ctx
+func (k *KubectlRestarter) restart(ctx context.Context, namespace, deployment string) error {
+	switch watchdog.RestartStrategy(ctx) {
+	case watchdog.RestartScale:
+		return k.scaleRestart(ctx, namespace, deployment)
+	case watchdog.RestartDelete:
+		return k.deleteRestart(ctx, namespace, deployment)
+	}
+	_, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "rollout", "restart",
+		"deployment/"+deployment, "-n", namespace)
+	return err
+}
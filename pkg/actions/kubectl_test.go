@@ -0,0 +1,272 @@
+package actions
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestRestartDeployment(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	path := filepath.Join(dir, "kubectl")
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		ctx   context.Context
+		calls []string
+	}{
+		{
+			name:  "without IDs",
+			ctx:   context.Background(),
+			calls: []string{"rollout restart deployment/api -n prod"},
+		},
+		{
+			name: "with IDs",
+			ctx:  watchdog.ContextWithIDs(context.Background(), "c1", "a1"),
+			calls: []string{
+				"annotate deployment/api -n prod --overwrite k8s-memory-watchdog/check-id=c1 k8s-memory-watchdog/action-id=a1",
+				"rollout restart deployment/api -n prod",
+			},
+		},
+		{
+			name: "manual restart",
+			ctx:  watchdog.ContextWithIDs(context.Background(), "", "a2"),
+			calls: []string{
+				"annotate deployment/api -n prod --overwrite k8s-memory-watchdog/action-id=a2",
+				"rollout restart deployment/api -n prod",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			os.Remove(log)
+			restarter := NewKubectlRestarter(kubectl.NewRunner(path, 0, 0))
+			if err := restarter.RestartDeployment(tt.ctx, "prod", "api"); err != nil {
+				t.Fatalf("RestartDeployment() error = %v", err)
+			}
+			out, err := os.ReadFile(log)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := strings.Split(strings.TrimSpace(string(out)), "\n"); strings.Join(got, "|") != strings.Join(tt.calls, "|") {
+				t.Errorf("calls = %q, want %q", got, tt.calls)
+			}
+		})
+	}
+}
+
+func TestScaleRestart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	path := filepath.Join(dir, "kubectl")
+	// the pods of the deployment are listed once before they're gone
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n" +
+		"case \"$*\" in\n" +
+		"*spec.replicas*) printf 3 ;;\n" +
+		"*matchLabels*) printf '{\"tier\":\"web\",\"app\":\"api\"}' ;;\n" +
+		"get\\ pods*) if [ ! -e " + dir + "/listed ]; then touch " + dir + "/listed; echo pod/api-1; fi ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	restarter := NewKubectlRestarter(kubectl.NewRunner(path, 0, 0))
+	restarter.poll = time.Millisecond
+	ctx := watchdog.ContextWithRestartStrategy(context.Background(), watchdog.RestartScale)
+	if err := restarter.RestartDeployment(ctx, "prod", "api"); err != nil {
+		t.Fatalf("RestartDeployment() error = %v", err)
+	}
+	out, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := []string{
+		"get deployment/api -n prod -o jsonpath={.spec.replicas}",
+		"get deployment/api -n prod -o jsonpath={.spec.selector.matchLabels}",
+		`patch deployment/api -n prod --type=merge -p {"spec":{"replicas":0}}`,
+		"get pods -n prod -l app=api,tier=web -o name",
+		"get pods -n prod -l app=api,tier=web -o name",
+		`patch deployment/api -n prod --type=merge -p {"spec":{"replicas":3}}`,
+	}
+	if got := strings.Split(strings.TrimSpace(string(out)), "\n"); strings.Join(got, "|") != strings.Join(calls, "|") {
+		t.Errorf("calls = %q, want %q", got, calls)
+	}
+}
+
+func TestDeleteRestart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	path := filepath.Join(dir, "kubectl")
+	// the replacement of the first pod is ready at the second poll
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n" +
+		"case \"$*\" in\n" +
+		"*matchLabels*) printf '{\"app\":\"api\"}' ;;\n" +
+		"get\\ pods*) printf 'pod/api-1\\npod/api-2\\n' ;;\n" +
+		"*spec.replicas*) printf 2 ;;\n" +
+		"*readyReplicas*) if [ -e " + dir + "/polled ]; then printf 2; else touch " + dir + "/polled; printf 1; fi ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	restarter := NewKubectlRestarter(kubectl.NewRunner(path, 0, 0))
+	restarter.poll = time.Millisecond
+	ctx := watchdog.ContextWithRestartStrategy(context.Background(), watchdog.RestartDelete)
+	ctx = watchdog.ContextWithPodDeletion(ctx, 15*time.Second, time.Millisecond)
+	if err := restarter.RestartDeployment(ctx, "prod", "api"); err != nil {
+		t.Fatalf("RestartDeployment() error = %v", err)
+	}
+	out, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := []string{
+		"get deployment/api -n prod -o jsonpath={.spec.selector.matchLabels}",
+		"get pods -n prod -l app=api -o name",
+		"get deployment/api -n prod -o jsonpath={.spec.replicas}",
+		"delete pod/api-1 -n prod --ignore-not-found --wait=true --grace-period=15",
+		"get deployment/api -n prod -o jsonpath={.status.readyReplicas}",
+		"get deployment/api -n prod -o jsonpath={.status.readyReplicas}",
+		"delete pod/api-2 -n prod --ignore-not-found --wait=true --grace-period=15",
+		"get deployment/api -n prod -o jsonpath={.status.readyReplicas}",
+	}
+	if got := strings.Split(strings.TrimSpace(string(out)), "\n"); strings.Join(got, "|") != strings.Join(calls, "|") {
+		t.Errorf("calls = %q, want %q", got, calls)
+	}
+}
+
+func TestSurgeRestart(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	path := filepath.Join(dir, "kubectl")
+	// the surge replicas are ready at the second poll
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n" +
+		"case \"$*\" in\n" +
+		"*spec.replicas*) printf 2 ;;\n" +
+		"*readyReplicas*) if [ -e " + dir + "/polled ]; then printf 4; else touch " + dir + "/polled; printf 2; fi ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	restarter := NewKubectlRestarter(kubectl.NewRunner(path, 0, 0))
+	restarter.poll = time.Millisecond
+	ctx := watchdog.ContextWithSurge(context.Background(), 2)
+	if err := restarter.RestartDeployment(ctx, "prod", "api"); err != nil {
+		t.Fatalf("RestartDeployment() error = %v", err)
+	}
+	out, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := []string{
+		"get deployment/api -n prod -o jsonpath={.spec.replicas}",
+		`patch deployment/api -n prod --type=merge -p {"spec":{"replicas":4}}`,
+		"get deployment/api -n prod -o jsonpath={.status.readyReplicas}",
+		"get deployment/api -n prod -o jsonpath={.status.readyReplicas}",
+		"rollout restart deployment/api -n prod",
+		"rollout status deployment/api -n prod",
+		`patch deployment/api -n prod --type=merge -p {"spec":{"replicas":2}}`,
+	}
+	if got := strings.Split(strings.TrimSpace(string(out)), "\n"); strings.Join(got, "|") != strings.Join(calls, "|") {
+		t.Errorf("calls = %q, want %q", got, calls)
+	}
+}
+
+func TestRolloutCheck(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	dir := t.TempDir()
+	log := filepath.Join(dir, "calls")
+	path := filepath.Join(dir, "kubectl")
+	script := "#!/bin/sh\necho \"$*\" >> " + log + "\n" +
+		"case \"$*\" in\n" +
+		"rollout\\ status*) echo 'error: timed out waiting for the condition'; exit 1 ;;\n" +
+		"esac\n"
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	restarter := NewKubectlRestarter(kubectl.NewRunner(path, 0, 0))
+	ctx := watchdog.ContextWithRolloutCheck(context.Background(), true)
+	if err := restarter.RestartDeployment(ctx, "prod", "api"); !errors.Is(err, watchdog.ErrRestartFailed) || !errors.Is(err, watchdog.ErrRolloutNotReady) {
+		t.Fatalf("RestartDeployment() error = %v, want %v and %v", err, watchdog.ErrRestartFailed, watchdog.ErrRolloutNotReady)
+	}
+	if err := restarter.UndoRollout(context.Background(), "prod", "api"); err != nil {
+		t.Fatalf("UndoRollout() error = %v", err)
+	}
+	out, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	calls := []string{
+		"rollout restart deployment/api -n prod",
+		"rollout status deployment/api -n prod",
+		"rollout undo deployment/api -n prod",
+	}
+	if got := strings.Split(strings.TrimSpace(string(out)), "\n"); strings.Join(got, "|") != strings.Join(calls, "|") {
+		t.Errorf("calls = %q, want %q", got, calls)
+	}
+}
+
+func TestRestartFailuresBeforeRollout(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	tests := []struct {
+		name  string
+		fails string
+		ctx   context.Context
+	}{
+		{"annotate", "annotate*", watchdog.ContextWithIDs(context.Background(), "check-1", "action-1")},
+		{"restart", "rollout\\ restart*", context.Background()},
+		{"surge", "*readyReplicas*", watchdog.ContextWithSurge(context.Background(), 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "kubectl")
+			script := "#!/bin/sh\n" +
+				"case \"$*\" in\n" +
+				tt.fails + ") echo 'Error from server (Forbidden)'; exit 1 ;;\n" +
+				"*spec.replicas*) printf 2 ;;\n" +
+				"esac\n"
+			if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+				t.Fatal(err)
+			}
+
+			restarter := NewKubectlRestarter(kubectl.NewRunner(path, 0, 0))
+			ctx := watchdog.ContextWithRolloutCheck(tt.ctx, true)
+			err := restarter.RestartDeployment(ctx, "prod", "api")
+			if err == nil || errors.Is(err, watchdog.ErrRolloutNotReady) {
+				t.Errorf("RestartDeployment() error = %v, want an error that isn't %v", err, watchdog.ErrRolloutNotReady)
+			}
+		})
+	}
+}
@@ -0,0 +1,107 @@
+package actions
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// scaleTimeout bounds the wait for the pods of a deployment scaled to zero
+// to terminate, so a stuck finalizer can't leave it down indefinitely
+const scaleTimeout = 5 * time.Minute
+
+// scaleRestart scales deployment to zero, waits until none of its pods are
+// left, then scales it back to the replica count it had. The replicas are
+// restored even when the wait fails, so a failed restart doesn't leave the
+// deployment down.
+func (k *KubectlRestarter) scaleRestart(ctx context.Context, namespace, deployment string) error {
+	replicas, err := k.replicas(ctx, namespace, deployment)
+	if err != nil {
+		return err
+	}
+	selector, err := k.selector(ctx, namespace, deployment)
+	if err != nil {
+		return err
+	}
+
+	if err := k.scale(ctx, namespace, deployment, 0); err != nil {
+		return err
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, scaleTimeout)
+	waitErr := k.waitForTermination(waitCtx, namespace, selector)
+	cancel()
+
+	// scale back up even if ctx expired while waiting
+	scaleCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := k.scale(scaleCtx, namespace, deployment, replicas); err != nil {
+		return err
+	}
+	if waitErr != nil {
+		return fmt.Errorf("%w: waiting for the pods of deployment '%s' to terminate: %v", watchdog.ErrRestartFailed, deployment, waitErr)
+	}
+	return nil
+}
+
+// scale sets the replica count of deployment
+func (k *KubectlRestarter) scale(ctx context.Context, namespace, deployment string, replicas int) error {
+	_, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "patch", "deployment/"+deployment, "-n", namespace,
+		"--type=merge", "-p", fmt.Sprintf(`{"spec":{"replicas":%d}}`, replicas))
+	return err
+}
+
+// waitForTermination polls the pods matching selector until there are none
+// left, terminating pods included
+func (k *KubectlRestarter) waitForTermination(ctx context.Context, namespace, selector string) error {
+	for {
+		out, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "get", "pods", "-n", namespace, "-l", selector, "-o", "name")
+		if err != nil {
+			return err
+		}
+		if strings.TrimSpace(string(out)) == "" {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(k.poll):
+		}
+	}
+}
+
+// selector returns the label selector of the pods of deployment
+func (k *KubectlRestarter) selector(ctx context.Context, namespace, deployment string) (string, error) {
+	out, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "get", "deployment/"+deployment, "-n", namespace,
+		"-o", "jsonpath={.spec.selector.matchLabels}")
+	if err != nil {
+		return "", err
+	}
+	selector, err := labelSelector(out)
+	if err != nil {
+		return "", fmt.Errorf("%w: selector of deployment '%s': %v", watchdog.ErrRestartFailed, deployment, err)
+	}
+	return selector, nil
+}
+
+// labelSelector turns the matchLabels of a deployment, as printed by
+// jsonpath, into a label selector
+func labelSelector(matchLabels []byte) (string, error) {
+	var labels map[string]string
+	if err := json.Unmarshal(matchLabels, &labels); err != nil {
+		return "", err
+	}
+	if len(labels) == 0 {
+		return "", fmt.Errorf("no matchLabels")
+	}
+	var selector []string
+	for key, value := range labels {
+		selector = append(selector, key+"="+value)
+	}
+	sort.Strings(selector)
+	return strings.Join(selector, ","), nil
+}
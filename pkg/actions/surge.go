@@ -0,0 +1,84 @@
+package actions
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// surgeRestart adds surge replicas to deployment and waits for them to be
+// ready before restarting it, then waits for the restart to roll out and
+// scales it back, so it keeps its serving capacity throughout. The replicas
+// are restored even when a step fails.
+func (k *KubectlRestarter) surgeRestart(ctx context.Context, namespace, deployment string, surge int) error {
+	replicas, err := k.replicas(ctx, namespace, deployment)
+	if err != nil {
+		return err
+	}
+	if err := k.scale(ctx, namespace, deployment, replicas+surge); err != nil {
+		return err
+	}
+
+	restartErr := k.surged(ctx, namespace, deployment, replicas+surge)
+
+	// scale back even if ctx expired during the restart
+	scaleCtx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if err := k.scale(scaleCtx, namespace, deployment, replicas); err != nil {
+		return err
+	}
+	return restartErr
+}
+
+// surged restarts deployment once its surge replicas are ready, and waits
+// for the restart to roll out
+func (k *KubectlRestarter) surged(ctx context.Context, namespace, deployment string, replicas int) error {
+	waitCtx, cancel := context.WithTimeout(ctx, scaleTimeout)
+	err := k.waitForReady(waitCtx, namespace, deployment, replicas)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("%w: waiting for the surge replicas of deployment '%s': %v", watchdog.ErrRestartFailed, deployment, err)
+	}
+	if err := k.restart(ctx, namespace, deployment); err != nil {
+		return err
+	}
+	return k.rolloutStatus(ctx, namespace, deployment)
+}
+
+// replicas returns the replica count of deployment
+func (k *KubectlRestarter) replicas(ctx context.Context, namespace, deployment string) (int, error) {
+	out, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "get", "deployment/"+deployment, "-n", namespace,
+		"-o", "jsonpath={.spec.replicas}")
+	if err != nil {
+		return 0, err
+	}
+	replicas, err := strconv.Atoi(strings.TrimSpace(string(out)))
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid replica count %q of deployment '%s'", watchdog.ErrRestartFailed, out, deployment)
+	}
+	return replicas, nil
+}
+
+// waitForReady polls deployment until it has at least replicas ready
+func (k *KubectlRestarter) waitForReady(ctx context.Context, namespace, deployment string, replicas int) error {
+	for {
+		out, err := k.runner.Run(ctx, watchdog.ErrRestartFailed, "get", "deployment/"+deployment, "-n", namespace,
+			"-o", "jsonpath={.status.readyReplicas}")
+		if err != nil {
+			return err
+		}
+		// readyReplicas is omitted while no replica is ready
+		if ready, _ := strconv.Atoi(strings.TrimSpace(string(out))); ready >= replicas {
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(k.poll):
+		}
+	}
+}
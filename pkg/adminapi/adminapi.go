@@ -0,0 +1,352 @@
+// Package adminapi serves the HTTP endpoints operators and pipelines use to
+// act on the watchdog's targets.
+package adminapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/httpauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Controller is the part of the watchdog the API acts on
+type Controller interface {
+	CheckTarget(ctx context.Context, name string) (watchdog.CheckResult, error)
+	RestartTarget(ctx context.Context, name, reason string) (watchdog.CheckResult, error)
+	Silence(ctx context.Context, name string, duration time.Duration, reason string) (watchdog.Silence, error)
+	Unsilence(ctx context.Context, name, reason string) error
+	PauseTarget(name string, duration time.Duration, reason string) (watchdog.Pause, error)
+	ResumeTarget(name, reason string) error
+	Status() []watchdog.TargetStatus
+}
+
+// Handler serves the endpoints of the API. Target names may contain
+// slashes, e.g. /check/prod/api.
+//
+//   - POST /check/{target} checks the target immediately, remediating it on
+//     a breach as a scheduled check would, and returns the CheckResult. It
+//     requires the restart token as a bearer token.
+//   - POST /restart/{target} runs the action of the target, requiring the
+//     restart token as a bearer token, and returns the CheckResult. The
+//     optional JSON body names the user and reason, which are logged and
+//     notified with the restart.
+//   - POST /silence/{target} mutes the notifications of the target for the
+//     duration of the JSON body, e.g. {"duration":"4h"}, and returns the
+//     Silence. POST /unsilence/{target} lifts it early. Both require the
+//     restart token and take a user and reason like restarts.
+//   - POST /pause/{target} stops the scheduled checks of the target for the
+//     optional duration of the JSON body, or until POST /resume/{target},
+//     and returns the Pause. Both require the restart token and take a user
+//     and reason like restarts.
+//   - GET /status returns the TargetStatus of every target
+//   - GET /fleet returns the ClusterSummary of every cluster, aggregated
+//     from the status of their targets
+type Handler struct {
+	controller   Controller
+	restartToken string
+	mux          *http.ServeMux
+}
+
+// operatorRequest is the optional body of a restart or unsilence, naming
+// who acts and why
+type operatorRequest struct {
+	User   string `json:"user"`
+	Reason string `json:"reason"`
+}
+
+// silenceRequest is the body of a silence
+type silenceRequest struct {
+	operatorRequest
+	Duration string `json:"duration"`
+}
+
+// New creates a new instance of Handler acting on c. Restarts are refused
+// when restartToken is empty. The token may refer to a file or Kubernetes
+// Secret, as resolved by secret.Resolve on every request.
+func New(c Controller, restartToken string) *Handler {
+	h := &Handler{
+		controller:   c,
+		restartToken: restartToken,
+		mux:          http.NewServeMux(),
+	}
+	h.mux.HandleFunc("/check/", h.check)
+	h.mux.HandleFunc("/restart/", h.restart)
+	h.mux.HandleFunc("/silence/", h.silence)
+	h.mux.HandleFunc("/unsilence/", h.unsilence)
+	h.mux.HandleFunc("/pause/", h.pause)
+	h.mux.HandleFunc("/resume/", h.resume)
+	h.mux.HandleFunc("/status", h.status)
+	h.mux.HandleFunc("/fleet", h.fleet)
+	return h
+}
+
+// ServeHTTP dispatches to the endpoints, which only accept POST but for
+// the read-only status and fleet
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	method := http.MethodPost
+	if r.URL.Path == "/status" || r.URL.Path == "/fleet" {
+		method = http.MethodGet
+	}
+	if r.Method != method {
+		w.Header().Set("Allow", method)
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+// status lists the targets. It is read-only, so like the dashboard it only
+// requires the bearer token of the HTTP server, if any.
+func (h *Handler) status(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.controller.Status())
+}
+
+// fleet summarizes the targets by cluster. It is read-only like status.
+func (h *Handler) fleet(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, watchdog.Fleet(h.controller.Status()))
+}
+
+// check runs the check and answers 502 Bad Gateway when it failed, so
+// pipelines can rely on the status code alone. As the check restarts the
+// target on a breach, it requires the restart token like restarts.
+func (h *Handler) check(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/check/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, "missing target")
+		return
+	}
+	result, err := h.controller.CheckTarget(r.Context(), name)
+	if errors.Is(err, watchdog.ErrTargetNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if result.Err != nil {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, result)
+}
+
+// authorize checks the restart token of a request, answering it with an
+// error when it is missing or wrong
+func (h *Handler) authorize(w http.ResponseWriter, r *http.Request) bool {
+	restartToken, err := secret.Resolve(r.Context(), h.restartToken)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, "restart token unavailable")
+		return false
+	}
+	if restartToken == "" {
+		writeError(w, http.StatusForbidden, "operator actions are disabled without a restart token")
+		return false
+	}
+	token, ok := httpauth.BearerToken(r)
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(restartToken)) != 1 {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="k8s-memory-watchdog"`)
+		writeError(w, http.StatusUnauthorized, "invalid restart token")
+		return false
+	}
+	return true
+}
+
+// decodeBody decodes the optional JSON body of a request into v, answering
+// it with an error when the body is invalid
+func decodeBody(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(io.LimitReader(r.Body, 64<<10)).Decode(v); err != nil && err != io.EOF {
+		writeError(w, http.StatusBadRequest, "invalid body: "+err.Error())
+		return false
+	}
+	return true
+}
+
+// restart runs the action and answers 502 Bad Gateway when it failed
+func (h *Handler) restart(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/restart/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, "missing target")
+		return
+	}
+	var req operatorRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	result, err := h.controller.RestartTarget(r.Context(), name, operatorReason("manual restart", req, r))
+	if errors.Is(err, watchdog.ErrTargetNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	status := http.StatusOK
+	if result.Err != nil {
+		status = http.StatusBadGateway
+	}
+	writeJSON(w, status, result)
+}
+
+// silence mutes the target for the requested duration
+func (h *Handler) silence(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/silence/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, "missing target")
+		return
+	}
+	var req silenceRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	duration, err := time.ParseDuration(req.Duration)
+	if err != nil || duration <= 0 {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid duration '%s'", req.Duration))
+		return
+	}
+
+	silence, err := h.controller.Silence(r.Context(), name, duration, operatorReason("silence", req.operatorRequest, r))
+	if errors.Is(err, watchdog.ErrTargetNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, silence)
+}
+
+// unsilence lifts the silence of the target and answers 409 Conflict when
+// it has none
+func (h *Handler) unsilence(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/unsilence/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, "missing target")
+		return
+	}
+	var req operatorRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	err := h.controller.Unsilence(r.Context(), name, operatorReason("unsilence", req, r))
+	switch {
+	case errors.Is(err, watchdog.ErrTargetNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, watchdog.ErrNotSilenced):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// pause stops the checks of the target for the requested duration, if any
+func (h *Handler) pause(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/pause/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, "missing target")
+		return
+	}
+	var req silenceRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	var duration time.Duration
+	if req.Duration != "" {
+		var err error
+		if duration, err = time.ParseDuration(req.Duration); err != nil || duration <= 0 {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("invalid duration '%s'", req.Duration))
+			return
+		}
+	}
+
+	pause, err := h.controller.PauseTarget(name, duration, operatorReason("pause", req.operatorRequest, r))
+	if errors.Is(err, watchdog.ErrTargetNotFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	} else if err != nil {
+		writeError(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, pause)
+}
+
+// resume restarts the checks of the target and answers 409 Conflict when it
+// isn't paused
+func (h *Handler) resume(w http.ResponseWriter, r *http.Request) {
+	if !h.authorize(w, r) {
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/resume/")
+	if name == "" {
+		writeError(w, http.StatusNotFound, "missing target")
+		return
+	}
+	var req operatorRequest
+	if !decodeBody(w, r, &req) {
+		return
+	}
+
+	err := h.controller.ResumeTarget(name, operatorReason("resume", req, r))
+	switch {
+	case errors.Is(err, watchdog.ErrTargetNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, watchdog.ErrNotPaused):
+		writeError(w, http.StatusConflict, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+// operatorReason describes who requested an action, from where and why
+func operatorReason(action string, req operatorRequest, r *http.Request) string {
+	user := req.User
+	if user == "" {
+		user = "unknown user"
+	}
+	reason := fmt.Sprintf("%s by %s from %s", action, user, r.RemoteAddr)
+	if req.Reason != "" {
+		reason += ": " + req.Reason
+	}
+	return reason
+}
+
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
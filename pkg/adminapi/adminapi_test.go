@@ -0,0 +1,380 @@
+package adminapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// fakeController checks its targets with a fixed outcome
+type fakeController struct {
+	results map[string]watchdog.CheckResult
+	checked []string
+	reasons []string
+	// silenced holds the duration of every silenced target
+	silenced map[string]time.Duration
+	// paused holds the duration of every paused target
+	paused map[string]time.Duration
+}
+
+func (c *fakeController) CheckTarget(ctx context.Context, name string) (watchdog.CheckResult, error) {
+	result, ok := c.results[name]
+	if !ok {
+		return watchdog.CheckResult{}, fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	c.checked = append(c.checked, name)
+	return result, nil
+}
+
+func (c *fakeController) RestartTarget(ctx context.Context, name, reason string) (watchdog.CheckResult, error) {
+	result, ok := c.results[name]
+	if !ok {
+		return watchdog.CheckResult{}, fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	c.reasons = append(c.reasons, reason)
+	return result, nil
+}
+
+func (c *fakeController) Silence(ctx context.Context, name string, duration time.Duration, reason string) (watchdog.Silence, error) {
+	if _, ok := c.results[name]; !ok {
+		return watchdog.Silence{}, fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	c.silenced[name] = duration
+	c.reasons = append(c.reasons, reason)
+	return watchdog.Silence{Until: time.Date(2024, 3, 1, 16, 0, 0, 0, time.UTC), Reason: reason}, nil
+}
+
+func (c *fakeController) Unsilence(ctx context.Context, name, reason string) error {
+	if _, ok := c.results[name]; !ok {
+		return fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	if _, ok := c.silenced[name]; !ok {
+		return fmt.Errorf("%w: '%s'", watchdog.ErrNotSilenced, name)
+	}
+	delete(c.silenced, name)
+	c.reasons = append(c.reasons, reason)
+	return nil
+}
+
+func (c *fakeController) PauseTarget(name string, duration time.Duration, reason string) (watchdog.Pause, error) {
+	if _, ok := c.results[name]; !ok {
+		return watchdog.Pause{}, fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	c.paused[name] = duration
+	c.reasons = append(c.reasons, reason)
+	return watchdog.Pause{Reason: reason}, nil
+}
+
+func (c *fakeController) ResumeTarget(name, reason string) error {
+	if _, ok := c.results[name]; !ok {
+		return fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	if _, ok := c.paused[name]; !ok {
+		return fmt.Errorf("%w: '%s'", watchdog.ErrNotPaused, name)
+	}
+	delete(c.paused, name)
+	c.reasons = append(c.reasons, reason)
+	return nil
+}
+
+func (c *fakeController) Status() []watchdog.TargetStatus {
+	statuses := make([]watchdog.TargetStatus, 0, len(c.results))
+	for name, result := range c.results {
+		result := result
+		statuses = append(statuses, watchdog.TargetStatus{Target: watchdog.Target{Name: name}, LastResult: &result})
+	}
+	return statuses
+}
+
+func TestCheck(t *testing.T) {
+	controller := &fakeController{results: map[string]watchdog.CheckResult{
+		"prod/api":    {Target: watchdog.Target{Name: "prod/api"}, Memory: 3000, Threshold: 2000, Breached: true, Action: "restart"},
+		"prod/worker": {Target: watchdog.Target{Name: "prod/worker"}, Err: errors.New("metrics unavailable")},
+	}}
+
+	tests := []struct {
+		name    string
+		method  string
+		token   string
+		auth    string
+		path    string
+		status  int
+		checked string
+	}{
+		{name: "breach", token: "secret", auth: "Bearer secret", path: "/check/prod/api", status: http.StatusOK, checked: "prod/api"},
+		{name: "failed check", token: "secret", auth: "Bearer secret", path: "/check/prod/worker", status: http.StatusBadGateway, checked: "prod/worker"},
+		{name: "unknown target", token: "secret", auth: "Bearer secret", path: "/check/prod/missing", status: http.StatusNotFound},
+		{name: "missing target", token: "secret", auth: "Bearer secret", path: "/check/", status: http.StatusNotFound},
+		{name: "post only", method: http.MethodGet, token: "secret", auth: "Bearer secret", path: "/check/prod/api", status: http.StatusMethodNotAllowed},
+		{name: "wrong token", token: "secret", auth: "Bearer guess", path: "/check/prod/api", status: http.StatusUnauthorized},
+		{name: "no token", token: "secret", path: "/check/prod/api", status: http.StatusUnauthorized},
+		{name: "disabled", path: "/check/prod/api", status: http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller.checked = nil
+			method := tt.method
+			if method == "" {
+				method = http.MethodPost
+			}
+			req := httptest.NewRequest(method, tt.path, nil)
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			New(controller, tt.token).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("ServeHTTP() status = %v, want %v", rec.Code, tt.status)
+			}
+			if rec.Header().Get("Content-Type") != "application/json" {
+				t.Errorf("Content-Type = %v, want application/json", rec.Header().Get("Content-Type"))
+			}
+			if tt.checked == "" {
+				if len(controller.checked) != 0 {
+					t.Errorf("checked %v, want no check", controller.checked)
+				}
+				return
+			}
+			var result struct {
+				Target   watchdog.Target `json:"target"`
+				Breached bool            `json:"breached"`
+				Error    string          `json:"error"`
+			}
+			if err := json.NewDecoder(rec.Body).Decode(&result); err != nil {
+				t.Fatal(err)
+			}
+			if result.Target.Name != tt.checked {
+				t.Errorf("result target = %q, want %q", result.Target.Name, tt.checked)
+			}
+		})
+	}
+}
+
+func TestRestart(t *testing.T) {
+	controller := &fakeController{results: map[string]watchdog.CheckResult{
+		"prod/api":    {Target: watchdog.Target{Name: "prod/api"}, Action: "restart"},
+		"prod/worker": {Target: watchdog.Target{Name: "prod/worker"}, Err: errors.New("forbidden")},
+	}}
+
+	tests := []struct {
+		name   string
+		token  string
+		auth   string
+		path   string
+		body   string
+		status int
+		reason string
+	}{
+		{name: "restart", token: "secret", auth: "Bearer secret", path: "/restart/prod/api", body: `{"user":"alice","reason":"INC-42"}`,
+			status: http.StatusOK, reason: "manual restart by alice from 192.0.2.1:1234: INC-42"},
+		{name: "no body", token: "secret", auth: "Bearer secret", path: "/restart/prod/api",
+			status: http.StatusOK, reason: "manual restart by unknown user from 192.0.2.1:1234"},
+		{name: "failed restart", token: "secret", auth: "Bearer secret", path: "/restart/prod/worker",
+			status: http.StatusBadGateway, reason: "manual restart by unknown user from 192.0.2.1:1234"},
+		{name: "wrong token", token: "secret", auth: "Bearer guess", path: "/restart/prod/api", status: http.StatusUnauthorized},
+		{name: "no token", token: "secret", path: "/restart/prod/api", status: http.StatusUnauthorized},
+		{name: "no scheme", token: "secret", auth: "secret", path: "/restart/prod/api", status: http.StatusUnauthorized},
+		{name: "other scheme", token: "secret", auth: "Token secret", path: "/restart/prod/api", status: http.StatusUnauthorized},
+		{name: "lowercase scheme", token: "secret", auth: "bearer secret", path: "/restart/prod/api",
+			status: http.StatusOK, reason: "manual restart by unknown user from 192.0.2.1:1234"},
+		{name: "disabled", auth: "Bearer ", path: "/restart/prod/api", status: http.StatusForbidden},
+		{name: "invalid body", token: "secret", auth: "Bearer secret", path: "/restart/prod/api", body: "{", status: http.StatusBadRequest},
+		{name: "unknown target", token: "secret", auth: "Bearer secret", path: "/restart/prod/missing", status: http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller.reasons = nil
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			if tt.auth != "" {
+				req.Header.Set("Authorization", tt.auth)
+			}
+			rec := httptest.NewRecorder()
+			New(controller, tt.token).ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("ServeHTTP() status = %v, want %v: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.reason == "" {
+				if len(controller.reasons) != 0 {
+					t.Errorf("restarted with %v, want no restart", controller.reasons)
+				}
+				return
+			}
+			if len(controller.reasons) != 1 || controller.reasons[0] != tt.reason {
+				t.Errorf("reasons = %q, want %q", controller.reasons, tt.reason)
+			}
+		})
+	}
+}
+
+func TestSilence(t *testing.T) {
+	controller := &fakeController{
+		results:  map[string]watchdog.CheckResult{"prod/api": {}, "prod/worker": {}},
+		silenced: map[string]time.Duration{"prod/worker": time.Hour},
+	}
+	handler := New(controller, "secret")
+
+	tests := []struct {
+		name   string
+		auth   string
+		path   string
+		body   string
+		status int
+		reason string
+	}{
+		{name: "silence", auth: "Bearer secret", path: "/silence/prod/api", body: `{"user":"alice","reason":"INC-42","duration":"4h"}`,
+			status: http.StatusOK, reason: "silence by alice from 192.0.2.1:1234: INC-42"},
+		{name: "invalid duration", auth: "Bearer secret", path: "/silence/prod/api", body: `{"duration":"soon"}`, status: http.StatusBadRequest},
+		{name: "no duration", auth: "Bearer secret", path: "/silence/prod/api", status: http.StatusBadRequest},
+		{name: "wrong token", auth: "Bearer guess", path: "/silence/prod/api", body: `{"duration":"4h"}`, status: http.StatusUnauthorized},
+		{name: "unknown target", auth: "Bearer secret", path: "/silence/prod/missing", body: `{"duration":"4h"}`, status: http.StatusNotFound},
+		{name: "unsilence", auth: "Bearer secret", path: "/unsilence/prod/worker",
+			status: http.StatusNoContent, reason: "unsilence by unknown user from 192.0.2.1:1234"},
+		{name: "not silenced", auth: "Bearer secret", path: "/unsilence/prod/worker", status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller.reasons = nil
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.auth)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("ServeHTTP() status = %v, want %v: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.reason == "" {
+				if len(controller.reasons) != 0 {
+					t.Errorf("acted with %v, want no action", controller.reasons)
+				}
+				return
+			}
+			if len(controller.reasons) != 1 || controller.reasons[0] != tt.reason {
+				t.Errorf("reasons = %q, want %q", controller.reasons, tt.reason)
+			}
+		})
+	}
+	if controller.silenced["prod/api"] != 4*time.Hour {
+		t.Errorf("silenced = %v, want prod/api for 4h", controller.silenced)
+	}
+}
+
+func TestPause(t *testing.T) {
+	controller := &fakeController{
+		results: map[string]watchdog.CheckResult{"prod/api": {}, "prod/worker": {}},
+		paused:  map[string]time.Duration{"prod/worker": time.Hour},
+	}
+	handler := New(controller, "secret")
+
+	tests := []struct {
+		name   string
+		auth   string
+		path   string
+		body   string
+		status int
+		reason string
+	}{
+		{name: "pause", auth: "Bearer secret", path: "/pause/prod/api", body: `{"user":"alice","reason":"migration","duration":"2h"}`,
+			status: http.StatusOK, reason: "pause by alice from 192.0.2.1:1234: migration"},
+		{name: "invalid duration", auth: "Bearer secret", path: "/pause/prod/api", body: `{"duration":"-1h"}`, status: http.StatusBadRequest},
+		{name: "wrong token", auth: "Bearer guess", path: "/pause/prod/api", status: http.StatusUnauthorized},
+		{name: "unknown target", auth: "Bearer secret", path: "/pause/prod/missing", status: http.StatusNotFound},
+		{name: "resume", auth: "Bearer secret", path: "/resume/prod/worker",
+			status: http.StatusNoContent, reason: "resume by unknown user from 192.0.2.1:1234"},
+		{name: "not paused", auth: "Bearer secret", path: "/resume/prod/worker", status: http.StatusConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			controller.reasons = nil
+			req := httptest.NewRequest(http.MethodPost, tt.path, strings.NewReader(tt.body))
+			req.Header.Set("Authorization", tt.auth)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tt.status {
+				t.Fatalf("ServeHTTP() status = %v, want %v: %s", rec.Code, tt.status, rec.Body)
+			}
+			if tt.reason == "" {
+				if len(controller.reasons) != 0 {
+					t.Errorf("acted with %v, want no action", controller.reasons)
+				}
+				return
+			}
+			if len(controller.reasons) != 1 || controller.reasons[0] != tt.reason {
+				t.Errorf("reasons = %q, want %q", controller.reasons, tt.reason)
+			}
+		})
+	}
+	if controller.paused["prod/api"] != 2*time.Hour {
+		t.Errorf("paused = %v, want prod/api for 2h", controller.paused)
+	}
+
+	// without a duration, the target is paused until resumed
+	req := httptest.NewRequest(http.MethodPost, "/pause/prod/worker", nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+	if duration, ok := controller.paused["prod/worker"]; !ok || duration != 0 {
+		t.Errorf("paused = %v, want prod/worker until resumed", controller.paused)
+	}
+}
+
+func TestStatus(t *testing.T) {
+	controller := &fakeController{results: map[string]watchdog.CheckResult{
+		"prod/api": {Memory: 1500, Threshold: 2000},
+	}}
+	handler := New(controller, "")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	var statuses []watchdog.TargetStatus
+	if err := json.NewDecoder(rec.Body).Decode(&statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Target.Name != "prod/api" || statuses[0].LastResult == nil || statuses[0].LastResult.Memory != 1500 {
+		t.Errorf("statuses = %+v, want the status of prod/api", statuses)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/status", nil))
+	if rec.Code != http.StatusMethodNotAllowed || rec.Header().Get("Allow") != "GET" {
+		t.Errorf("POST /status = %v, Allow %q, want %v, GET", rec.Code, rec.Header().Get("Allow"), http.StatusMethodNotAllowed)
+	}
+}
+
+func TestFleet(t *testing.T) {
+	controller := &fakeController{results: map[string]watchdog.CheckResult{
+		"prod/api":    {Memory: 1500, Threshold: 2000},
+		"prod/worker": {Memory: 500, Threshold: 1000},
+	}}
+	handler := New(controller, "")
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/fleet", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("ServeHTTP() status = %v, want %v", rec.Code, http.StatusOK)
+	}
+	var summaries []watchdog.ClusterSummary
+	if err := json.NewDecoder(rec.Body).Decode(&summaries); err != nil {
+		t.Fatal(err)
+	}
+	expected := watchdog.ClusterSummary{Cluster: watchdog.DefaultCluster, Targets: 2, Memory: 2000, Threshold: 3000}
+	if len(summaries) != 1 || summaries[0] != expected {
+		t.Errorf("summaries = %+v, want %+v", summaries, expected)
+	}
+}
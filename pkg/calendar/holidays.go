@@ -0,0 +1,171 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// holiday computes the date of a holiday in a year
+type holiday struct {
+	name string
+	date func(year int) time.Time
+}
+
+// fixed is a holiday on the same date every year
+func fixed(name string, month time.Month, day int) holiday {
+	return holiday{name, func(year int) time.Time { return date(year, month, day) }}
+}
+
+// nthWeekday is a holiday on the n-th weekday of a month, counting from
+// the end of the month when n is negative
+func nthWeekday(name string, month time.Month, weekday time.Weekday, n int) holiday {
+	return holiday{name, func(year int) time.Time {
+		if n < 0 {
+			last := date(year, month+1, 0)
+			offset := (int(last.Weekday()) - int(weekday) + 7) % 7
+			return last.AddDate(0, 0, -offset+7*(n+1))
+		}
+		first := date(year, month, 1)
+		offset := (int(weekday) - int(first.Weekday()) + 7) % 7
+		return first.AddDate(0, 0, offset+7*(n-1))
+	}}
+}
+
+// fromEaster is a holiday a number of days after Easter Sunday
+func fromEaster(name string, days int) holiday {
+	return holiday{name, func(year int) time.Time { return easter(year).AddDate(0, 0, days) }}
+}
+
+// countries holds the national public holidays of the supported countries,
+// on their calendar date
+var countries = map[string][]holiday{
+	"US": {
+		fixed("New Year's Day", time.January, 1),
+		nthWeekday("Martin Luther King Jr. Day", time.January, time.Monday, 3),
+		nthWeekday("Presidents' Day", time.February, time.Monday, 3),
+		nthWeekday("Memorial Day", time.May, time.Monday, -1),
+		fixed("Juneteenth", time.June, 19),
+		fixed("Independence Day", time.July, 4),
+		nthWeekday("Labor Day", time.September, time.Monday, 1),
+		nthWeekday("Columbus Day", time.October, time.Monday, 2),
+		fixed("Veterans Day", time.November, 11),
+		nthWeekday("Thanksgiving Day", time.November, time.Thursday, 4),
+		fixed("Christmas Day", time.December, 25),
+	},
+	"BR": {
+		fixed("Confraternização Universal", time.January, 1),
+		fromEaster("Carnaval", -48),
+		fromEaster("Carnaval", -47),
+		fromEaster("Sexta-feira Santa", -2),
+		fixed("Tiradentes", time.April, 21),
+		fixed("Dia do Trabalho", time.May, 1),
+		fromEaster("Corpus Christi", 60),
+		fixed("Independência do Brasil", time.September, 7),
+		fixed("Nossa Senhora Aparecida", time.October, 12),
+		fixed("Finados", time.November, 2),
+		fixed("Proclamação da República", time.November, 15),
+		fixed("Dia Nacional de Zumbi e da Consciência Negra", time.November, 20),
+		fixed("Natal", time.December, 25),
+	},
+	"GB": {
+		fixed("New Year's Day", time.January, 1),
+		fromEaster("Good Friday", -2),
+		fromEaster("Easter Monday", 1),
+		nthWeekday("Early May Bank Holiday", time.May, time.Monday, 1),
+		nthWeekday("Spring Bank Holiday", time.May, time.Monday, -1),
+		nthWeekday("Summer Bank Holiday", time.August, time.Monday, -1),
+		fixed("Christmas Day", time.December, 25),
+		fixed("Boxing Day", time.December, 26),
+	},
+	"DE": {
+		fixed("Neujahr", time.January, 1),
+		fromEaster("Karfreitag", -2),
+		fromEaster("Ostermontag", 1),
+		fixed("Tag der Arbeit", time.May, 1),
+		fromEaster("Christi Himmelfahrt", 39),
+		fromEaster("Pfingstmontag", 50),
+		fixed("Tag der Deutschen Einheit", time.October, 3),
+		fixed("1. Weihnachtstag", time.December, 25),
+		fixed("2. Weihnachtstag", time.December, 26),
+	},
+}
+
+// Countries returns the codes of the countries with an embedded holiday
+// table
+func Countries() []string {
+	codes := make([]string, 0, len(countries))
+	for code := range countries {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// Holidays is a holiday calendar made of the public holidays of a country
+// and a list of extra dates. Restarts are suppressed for the whole day.
+type Holidays struct {
+	country []holiday
+	dates   map[string]string
+	loc     *time.Location
+}
+
+// NewHolidays creates a new instance of Holidays with the holidays of the
+// ISO 3166 country code, if any, and the given "2006-01-02" dates. Days are
+// read in loc.
+func NewHolidays(country string, dates []string, loc *time.Location) (*Holidays, error) {
+	h := &Holidays{dates: make(map[string]string), loc: loc}
+	if country != "" {
+		table, ok := countries[strings.ToUpper(country)]
+		if !ok {
+			return nil, fmt.Errorf("no holiday table for country '%s', known countries are %s",
+				country, strings.Join(Countries(), ", "))
+		}
+		h.country = table
+	}
+	for _, d := range dates {
+		if _, err := time.Parse("2006-01-02", d); err != nil {
+			return nil, fmt.Errorf("invalid holiday '%s', want YYYY-MM-DD", d)
+		}
+		h.dates[d] = "holiday"
+	}
+	return h, nil
+}
+
+// Frozen returns the name of the holiday t falls on, if any
+func (h *Holidays) Frozen(ctx context.Context, t time.Time) (string, bool, error) {
+	t = t.In(h.loc)
+	day := t.Format("2006-01-02")
+	if name, ok := h.dates[day]; ok {
+		return name, true, nil
+	}
+	for _, holiday := range h.country {
+		if holiday.date(t.Year()).Format("2006-01-02") == day {
+			return holiday.name, true, nil
+		}
+	}
+	return "", false, nil
+}
+
+// date returns midnight UTC of a day, normalizing out of range days
+func date(year int, month time.Month, day int) time.Time {
+	return time.Date(year, month, day, 0, 0, 0, 0, time.UTC)
+}
+
+// easter returns Easter Sunday of a year in the Gregorian calendar
+func easter(year int) time.Time {
+	a := year % 19
+	b, c := year/100, year%100
+	d, e := b/4, b%4
+	f := (b + 8) / 25
+	g := (b - f + 1) / 3
+	h := (19*a + b - d - g + 15) % 30
+	i, k := c/4, c%4
+	l := (32 + 2*e + 2*i - h - k) % 7
+	m := (a + 11*h + 22*l) / 451
+	month := (h + l - 7*m + 114) / 31
+	day := (h+l-7*m+114)%31 + 1
+	return date(year, time.Month(month), day)
+}
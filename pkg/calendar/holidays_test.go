@@ -0,0 +1,53 @@
+package calendar
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestHolidaysFrozen(t *testing.T) {
+	tests := []struct {
+		country  string
+		dates    []string
+		time     time.Time
+		expected string
+	}{
+		{country: "US", time: time.Date(2024, 11, 28, 9, 0, 0, 0, time.UTC), expected: "Thanksgiving Day"},
+		{country: "US", time: time.Date(2024, 5, 27, 9, 0, 0, 0, time.UTC), expected: "Memorial Day"},
+		{country: "US", time: time.Date(2024, 1, 15, 9, 0, 0, 0, time.UTC), expected: "Martin Luther King Jr. Day"},
+		{country: "us", time: time.Date(2024, 11, 27, 9, 0, 0, 0, time.UTC)},
+		{country: "BR", time: time.Date(2024, 2, 13, 9, 0, 0, 0, time.UTC), expected: "Carnaval"},
+		{country: "GB", time: time.Date(2024, 4, 1, 9, 0, 0, 0, time.UTC), expected: "Easter Monday"},
+		{country: "DE", time: time.Date(2025, 6, 9, 9, 0, 0, 0, time.UTC), expected: "Pfingstmontag"},
+		{dates: []string{"2024-08-15"}, time: time.Date(2024, 8, 15, 23, 59, 0, 0, time.UTC), expected: "holiday"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.country+" "+tt.time.Format("2006-01-02"), func(t *testing.T) {
+			holidays, err := NewHolidays(tt.country, tt.dates, time.UTC)
+			if err != nil {
+				t.Fatalf("NewHolidays() error = %v", err)
+			}
+			name, frozen, err := holidays.Frozen(context.Background(), tt.time)
+			if err != nil || name != tt.expected || frozen != (tt.expected != "") {
+				t.Errorf("Frozen() = %q, %v, %v, want %q", name, frozen, err, tt.expected)
+			}
+		})
+	}
+
+	if _, err := NewHolidays("XX", nil, time.UTC); err == nil {
+		t.Error("NewHolidays() with an unknown country expected an error")
+	}
+	if _, err := NewHolidays("", []string{"12/25"}, time.UTC); err == nil {
+		t.Error("NewHolidays() with an invalid date expected an error")
+	}
+}
+
+func TestEaster(t *testing.T) {
+	for year, expected := range map[int]string{2024: "2024-03-31", 2025: "2025-04-20", 2038: "2038-04-25"} {
+		if result := easter(year).Format("2006-01-02"); result != expected {
+			t.Errorf("easter(%d) = %v, want %v", year, result, expected)
+		}
+	}
+}
@@ -0,0 +1,304 @@
+// Package calendar provides the calendars during which the watchdog
+// suppresses automated restarts, such as change freezes.
+package calendar
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+)
+
+// Event is a single event of a calendar, ending before End. Recurring is
+// set for events with a recurrence rule, of which only the first occurrence
+// is read.
+type Event struct {
+	Summary   string
+	Start     time.Time
+	End       time.Time
+	Recurring bool
+}
+
+// ParseICal reads the events of an iCalendar (RFC 5545) document. Floating
+// times and dates are read in loc. An event ends at DTEND, or after its
+// DURATION. Recurrence rules are not expanded, and cancelled events are
+// skipped.
+func ParseICal(r io.Reader, loc *time.Location) ([]Event, error) {
+	lines, err := unfoldLines(r)
+	if err != nil {
+		return nil, err
+	}
+
+	var events []Event
+	var event *Event
+	var cancelled, allDay bool
+	var days int
+	var duration time.Duration
+	for number, line := range lines {
+		name, params, value := splitProperty(line)
+		switch {
+		case name == "BEGIN" && value == "VEVENT":
+			event = &Event{}
+			cancelled, allDay = false, false
+			days, duration = 0, 0
+		case event == nil:
+		case name == "END" && value == "VEVENT":
+			if event.Start.IsZero() {
+				return nil, fmt.Errorf("line %d: event without DTSTART", number+1)
+			}
+			if event.End.IsZero() {
+				event.End = event.Start.AddDate(0, 0, days).Add(duration)
+				if allDay && days == 0 && duration == 0 {
+					event.End = event.Start.AddDate(0, 0, 1)
+				}
+			}
+			if !cancelled {
+				events = append(events, *event)
+			}
+			event = nil
+		case name == "SUMMARY":
+			event.Summary = unescapeText(value)
+		case name == "STATUS":
+			cancelled = value == "CANCELLED"
+		case name == "RRULE" || name == "RDATE":
+			event.Recurring = true
+		case name == "DURATION":
+			if days, duration, err = parseICalDuration(value); err != nil {
+				return nil, fmt.Errorf("line %d: %s: %v", number+1, name, err)
+			}
+		case name == "DTSTART" || name == "DTEND":
+			t, date, err := parseICalTime(value, params, loc)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s: %v", number+1, name, err)
+			}
+			if name == "DTSTART" {
+				event.Start, allDay = t, date
+			} else {
+				event.End = t
+			}
+		}
+	}
+	return events, nil
+}
+
+// unfoldLines splits a document into content lines, joining folded lines
+func unfoldLines(r io.Reader) ([]string, error) {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		if (strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")) && len(lines) > 0 {
+			lines[len(lines)-1] += line[1:]
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines, scanner.Err()
+}
+
+// splitProperty splits a content line into its name, parameters and value
+func splitProperty(line string) (string, map[string]string, string) {
+	colon := strings.IndexByte(line, ':')
+	if colon < 0 {
+		return strings.ToUpper(line), nil, ""
+	}
+	parts := strings.Split(line[:colon], ";")
+	params := make(map[string]string, len(parts)-1)
+	for _, param := range parts[1:] {
+		if eq := strings.IndexByte(param, '='); eq > 0 {
+			params[strings.ToUpper(param[:eq])] = strings.Trim(param[eq+1:], `"`)
+		}
+	}
+	return strings.ToUpper(parts[0]), params, line[colon+1:]
+}
+
+// parseICalTime parses a DATE or DATE-TIME value, reporting whether it is
+// a date
+func parseICalTime(value string, params map[string]string, loc *time.Location) (time.Time, bool, error) {
+	if tzid, ok := params["TZID"]; ok {
+		if l, err := time.LoadLocation(tzid); err == nil {
+			loc = l
+		}
+	}
+	if params["VALUE"] == "DATE" || len(value) == len("20060102") {
+		t, err := time.ParseInLocation("20060102", value, loc)
+		return t, true, err
+	}
+	if strings.HasSuffix(value, "Z") {
+		t, err := time.Parse("20060102T150405Z", value)
+		return t, false, err
+	}
+	t, err := time.ParseInLocation("20060102T150405", value, loc)
+	return t, false, err
+}
+
+// parseICalDuration parses a DURATION value such as P1D or PT2H30M into
+// its days, which are nominal and may span a DST change, and its exact
+// time
+func parseICalDuration(value string) (int, time.Duration, error) {
+	s := value
+	sign := 1
+	if strings.HasPrefix(s, "-") || strings.HasPrefix(s, "+") {
+		if s[0] == '-' {
+			sign = -1
+		}
+		s = s[1:]
+	}
+	if !strings.HasPrefix(s, "P") || len(s) == 1 {
+		return 0, 0, fmt.Errorf("invalid duration %q", value)
+	}
+	s = s[1:]
+
+	var days int
+	var duration time.Duration
+	inTime := false
+	components := 0
+	for s != "" {
+		if s[0] == 'T' && !inTime {
+			inTime = true
+			s = s[1:]
+			continue
+		}
+		i := 0
+		for i < len(s) && s[i] >= '0' && s[i] <= '9' {
+			i++
+		}
+		if i == 0 || i == len(s) {
+			return 0, 0, fmt.Errorf("invalid duration %q", value)
+		}
+		n, err := strconv.Atoi(s[:i])
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid duration %q", value)
+		}
+		switch unit := s[i]; {
+		case unit == 'W' && !inTime:
+			days += 7 * n
+		case unit == 'D' && !inTime:
+			days += n
+		case unit == 'H' && inTime:
+			duration += time.Duration(n) * time.Hour
+		case unit == 'M' && inTime:
+			duration += time.Duration(n) * time.Minute
+		case unit == 'S' && inTime:
+			duration += time.Duration(n) * time.Second
+		default:
+			return 0, 0, fmt.Errorf("invalid duration %q", value)
+		}
+		s = s[i+1:]
+		components++
+	}
+	if components == 0 || strings.HasSuffix(value, "T") {
+		return 0, 0, fmt.Errorf("invalid duration %q", value)
+	}
+	return sign * days, time.Duration(sign) * duration, nil
+}
+
+// unescapeText decodes the escapes of a TEXT value
+func unescapeText(value string) string {
+	return strings.NewReplacer(`\n`, "\n", `\N`, "\n", `\,`, ",", `\;`, ";", `\\`, `\`).Replace(value)
+}
+
+// ICal is a freeze calendar read from an iCalendar URL or file. Restarts are
+// suppressed during its events. The calendar is read again once the refresh
+// interval has passed, and the last calendar read is kept when that fails.
+// Recurring events only suppress restarts during their first occurrence,
+// which is logged as a warning when the calendar is read.
+type ICal struct {
+	location string
+	refresh  time.Duration
+	loc      *time.Location
+	client   *http.Client
+	logger   *logging.Logger
+
+	mu     sync.Mutex
+	events []Event
+	loaded time.Time
+}
+
+// NewICal creates a new instance of ICal reading the calendar at location,
+// an http(s) URL or a file path, every refresh interval. Floating times are
+// read in loc.
+func NewICal(location string, refresh time.Duration, loc *time.Location) *ICal {
+	return &ICal{
+		location: location,
+		refresh:  refresh,
+		loc:      loc,
+		client:   &http.Client{Timeout: 30 * time.Second},
+		logger:   logging.New(log.Default(), logging.Levels{Default: logging.LevelInfo}).Component("freeze"),
+	}
+}
+
+// SetLogger logs the warnings about the calendar to logger instead of the
+// standard logger. A nil logger discards them.
+func (c *ICal) SetLogger(logger *logging.Logger) {
+	c.logger = logger
+}
+
+// Frozen returns the summary of the event in progress at t, if any
+func (c *ICal) Frozen(ctx context.Context, t time.Time) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var err error
+	if c.loaded.IsZero() || t.Sub(c.loaded) >= c.refresh {
+		var events []Event
+		if events, err = c.load(ctx); err == nil {
+			c.events = events
+			c.loaded = t
+			for _, event := range events {
+				if event.Recurring {
+					c.logger.Warnf("Event '%s' of freeze calendar %s recurs, only its first occurrence from %s suppresses restarts",
+						event.Summary, c.location, event.Start.Format(time.RFC3339))
+				}
+			}
+		} else {
+			err = fmt.Errorf("error reading freeze calendar %s: %w", c.location, err)
+		}
+	}
+
+	for _, event := range c.events {
+		if !t.Before(event.Start) && t.Before(event.End) {
+			summary := event.Summary
+			if summary == "" {
+				summary = "change freeze"
+			}
+			return summary, true, err
+		}
+	}
+	return "", false, err
+}
+
+// load reads and parses the calendar
+func (c *ICal) load(ctx context.Context) ([]Event, error) {
+	if !strings.HasPrefix(c.location, "http://") && !strings.HasPrefix(c.location, "https://") {
+		f, err := os.Open(c.location)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		return ParseICal(f, c.loc)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.location, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return ParseICal(resp.Body, c.loc)
+}
@@ -0,0 +1,129 @@
+package calendar
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+)
+
+const freezeCalendar = "BEGIN:VCALENDAR\r\n" +
+	"VERSION:2.0\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Black Friday\\, code freeze\r\n" +
+	"DTSTART:20241129T000000Z\r\n" +
+	"DTEND:20241202T000000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Year end\r\n" +
+	"DTSTART;VALUE=DATE:20241224\r\n" +
+	"DTEND;VALUE=DATE:20241227\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Release \r\n" +
+	" night\r\n" +
+	"DTSTART;TZID=America/New_York:20241015T200000\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Cancelled\r\n" +
+	"STATUS:CANCELLED\r\n" +
+	"DTSTART:20241001T000000Z\r\n" +
+	"DTEND:20241002T000000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Migration\r\n" +
+	"DURATION:P1DT2H30M\r\n" +
+	"DTSTART:20241105T220000Z\r\n" +
+	"END:VEVENT\r\n" +
+	"BEGIN:VEVENT\r\n" +
+	"SUMMARY:Weekly release\r\n" +
+	"DTSTART:20241104T090000Z\r\n" +
+	"DURATION:PT1H\r\n" +
+	"RRULE:FREQ=WEEKLY;BYDAY=MO\r\n" +
+	"END:VEVENT\r\n" +
+	"END:VCALENDAR\r\n"
+
+func TestParseICal(t *testing.T) {
+	events, err := ParseICal(strings.NewReader(freezeCalendar), time.UTC)
+	if err != nil {
+		t.Fatalf("ParseICal() error = %v", err)
+	}
+
+	newYork, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	release := time.Date(2024, 10, 15, 20, 0, 0, 0, newYork)
+	expected := []Event{
+		{Summary: "Black Friday, code freeze", Start: time.Date(2024, 11, 29, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 12, 2, 0, 0, 0, 0, time.UTC)},
+		{Summary: "Year end", Start: time.Date(2024, 12, 24, 0, 0, 0, 0, time.UTC), End: time.Date(2024, 12, 27, 0, 0, 0, 0, time.UTC)},
+		{Summary: "Release night", Start: release, End: release},
+		{Summary: "Migration", Start: time.Date(2024, 11, 5, 22, 0, 0, 0, time.UTC), End: time.Date(2024, 11, 7, 0, 30, 0, 0, time.UTC)},
+		{Summary: "Weekly release", Start: time.Date(2024, 11, 4, 9, 0, 0, 0, time.UTC), End: time.Date(2024, 11, 4, 10, 0, 0, 0, time.UTC), Recurring: true},
+	}
+	if len(events) != len(expected) {
+		t.Fatalf("ParseICal() = %+v, want %+v", events, expected)
+	}
+	for i := range expected {
+		if events[i].Summary != expected[i].Summary || !events[i].Start.Equal(expected[i].Start) || !events[i].End.Equal(expected[i].End) ||
+			events[i].Recurring != expected[i].Recurring {
+			t.Errorf("ParseICal()[%d] = %+v, want %+v", i, events[i], expected[i])
+		}
+	}
+
+	if _, err := ParseICal(strings.NewReader("BEGIN:VEVENT\nDTSTART:tomorrow\nEND:VEVENT\n"), time.UTC); err == nil {
+		t.Error("ParseICal() with an invalid time expected an error")
+	}
+	for _, duration := range []string{"P", "PT", "P1H", "PT1D", "1D", "P1.5D"} {
+		calendar := "BEGIN:VEVENT\nDTSTART:20241105T220000Z\nDURATION:" + duration + "\nEND:VEVENT\n"
+		if _, err := ParseICal(strings.NewReader(calendar), time.UTC); err == nil {
+			t.Errorf("ParseICal() with DURATION %s expected an error", duration)
+		}
+	}
+}
+
+func TestICalFrozen(t *testing.T) {
+	requests := 0
+	fail := false
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if fail {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		fmt.Fprint(w, freezeCalendar)
+	}))
+	defer server.Close()
+
+	calendar := NewICal(server.URL, time.Hour, time.UTC)
+	var warnings []string
+	calendar.SetLogger(logging.NewWithOutputs(logging.Output{Sink: logging.SinkFunc(func(record logging.Record) {
+		warnings = append(warnings, record.Message)
+	}), Levels: logging.Levels{Default: logging.LevelWarn}}))
+	ctx := context.Background()
+
+	reason, frozen, err := calendar.Frozen(ctx, time.Date(2024, 11, 30, 12, 0, 0, 0, time.UTC))
+	if err != nil || !frozen || reason != "Black Friday, code freeze" {
+		t.Errorf("Frozen() = %q, %v, %v, want the Black Friday freeze", reason, frozen, err)
+	}
+	if _, frozen, _ := calendar.Frozen(ctx, time.Date(2024, 11, 30, 12, 30, 0, 0, time.UTC)); !frozen || requests != 1 {
+		t.Errorf("Frozen() = %v after %d requests, want the cached calendar", frozen, requests)
+	}
+	if len(warnings) != 1 || !strings.HasPrefix(warnings[0], "Event 'Weekly release' of freeze calendar") {
+		t.Errorf("warnings = %q, want the recurring event reported once", warnings)
+	}
+
+	fail = true
+	_, frozen, err = calendar.Frozen(ctx, time.Date(2024, 12, 25, 12, 0, 0, 0, time.UTC))
+	if err == nil || !frozen {
+		t.Errorf("Frozen() = %v, %v, want the last calendar read and an error", frozen, err)
+	}
+	if _, frozen, _ := calendar.Frozen(ctx, time.Date(2024, 12, 27, 0, 0, 0, 0, time.UTC)); frozen {
+		t.Error("Frozen() at the end of an event = true, want false")
+	}
+}
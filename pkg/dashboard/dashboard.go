@@ -0,0 +1,101 @@
+// Package dashboard serves a read-only web UI of the watchdog's targets,
+// their usage history and the restarts performed.
+package dashboard
+
+import (
+	_ "embed"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+//go:embed index.html
+var indexHTML []byte
+
+// Targets lists the targets being watched
+type Targets interface {
+	Targets() []watchdog.Target
+}
+
+// Handler serves the dashboard page and the JSON endpoints it reads:
+// targets lists the targets and history returns the records of the last
+// window, given as a duration and defaulting to six hours
+type Handler struct {
+	targets Targets
+	store   watchdog.StateStore
+	now     func() time.Time
+	mux     *http.ServeMux
+}
+
+// New creates a new instance of Handler showing the targets of w and the
+// history in store
+func New(w Targets, store watchdog.StateStore) *Handler {
+	h := &Handler{
+		targets: w,
+		store:   store,
+		now:     time.Now,
+		mux:     http.NewServeMux(),
+	}
+	h.mux.HandleFunc("/", h.index)
+	h.mux.HandleFunc("/api/targets", h.listTargets)
+	h.mux.HandleFunc("/api/history", h.history)
+	return h
+}
+
+// ServeHTTP dispatches to the page and the JSON endpoints. Paths are
+// relative, so the handler can be mounted under a prefix with
+// http.StripPrefix.
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	h.mux.ServeHTTP(w, r)
+}
+
+func (h *Handler) index(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" && r.URL.Path != "" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(indexHTML)
+}
+
+func (h *Handler) listTargets(w http.ResponseWriter, r *http.Request) {
+	targets := h.targets.Targets()
+	if targets == nil {
+		targets = []watchdog.Target{}
+	}
+	writeJSON(w, targets)
+}
+
+func (h *Handler) history(w http.ResponseWriter, r *http.Request) {
+	window := 6 * time.Hour
+	if value := r.URL.Query().Get("window"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid window", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+
+	records, err := h.store.List(r.Context(), h.now().Add(-window))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if records == nil {
+		records = []watchdog.Record{}
+	}
+	writeJSON(w, records)
+}
+
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
@@ -0,0 +1,70 @@
+package dashboard
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+type staticTargets []watchdog.Target
+
+func (t staticTargets) Targets() []watchdog.Target {
+	return t
+}
+
+func TestHandler(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	store := watchdog.NewMemoryStateStore(0)
+	store.Save(context.Background(), watchdog.Record{Time: now.Add(-2 * time.Hour), Target: "prod/api", Memory: 1000})
+	store.Save(context.Background(), watchdog.Record{Time: now.Add(-time.Minute), Target: "prod/api", Memory: 3000, Action: "restart"})
+
+	handler := New(staticTargets{{Name: "prod/api"}}, store)
+	handler.now = func() time.Time { return now }
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		status      int
+		contentType string
+		records     int
+	}{
+		{name: "page", path: "/", status: http.StatusOK, contentType: "text/html; charset=utf-8"},
+		{name: "targets", path: "/api/targets", status: http.StatusOK, contentType: "application/json"},
+		{name: "default window", path: "/api/history", status: http.StatusOK, contentType: "application/json", records: 2},
+		{name: "short window", path: "/api/history?window=1h", status: http.StatusOK, contentType: "application/json", records: 1},
+		{name: "invalid window", path: "/api/history?window=soon", status: http.StatusBadRequest},
+		{name: "unknown path", path: "/missing", status: http.StatusNotFound},
+		{name: "read-only", method: http.MethodPost, path: "/api/history", status: http.StatusMethodNotAllowed},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			method := tt.method
+			if method == "" {
+				method = http.MethodGet
+			}
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, httptest.NewRequest(method, tt.path, nil))
+
+			if rec.Code != tt.status {
+				t.Fatalf("ServeHTTP() status = %v, want %v", rec.Code, tt.status)
+			}
+			if tt.contentType != "" && rec.Header().Get("Content-Type") != tt.contentType {
+				t.Errorf("Content-Type = %v, want %v", rec.Header().Get("Content-Type"), tt.contentType)
+			}
+			if strings.HasPrefix(tt.path, "/api/history") && tt.status == http.StatusOK {
+				var records []watchdog.Record
+				if err := json.Unmarshal(rec.Body.Bytes(), &records); err != nil || len(records) != tt.records {
+					t.Errorf("history = %s, want %d records", rec.Body.String(), tt.records)
+				}
+			}
+		})
+	}
+}
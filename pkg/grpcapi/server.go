@@ -0,0 +1,400 @@
+// Package grpcapi serves the watchdog's control and status API over gRPC,
+// as described in watchdog.proto. The protocol is implemented on top of the
+// HTTP/2 support of net/http, which requires TLS.
+package grpcapi
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/httpauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Service is the name of the gRPC service
+const Service = "k8smemorywatchdog.v1.Watchdog"
+
+// maxMessageSize limits the size of request messages
+const maxMessageSize = 1 << 20
+
+// gRPC status codes
+const (
+	codeOK                 = 0
+	codeInvalidArgument    = 3
+	codeNotFound           = 5
+	codePermissionDenied   = 7
+	codeFailedPrecondition = 9
+	codeUnimplemented      = 12
+	codeInternal           = 13
+	codeUnavailable        = 14
+	codeUnauthenticated    = 16
+)
+
+// Controller is the part of the watchdog driven by the API
+type Controller interface {
+	Status() []watchdog.TargetStatus
+	PauseTarget(name string, duration time.Duration, reason string) (watchdog.Pause, error)
+	ResumeTarget(name, reason string) error
+	CheckTarget(ctx context.Context, name string) (watchdog.CheckResult, error)
+}
+
+// Events receives the watchdog's events, to be registered with
+// watchdog.WithEventStream, and forwards them to the StreamEvents calls
+type Events struct {
+	mu          sync.Mutex
+	subscribers map[chan watchdog.Event]string
+}
+
+// NewEvents creates a new instance of Events
+func NewEvents() *Events {
+	return &Events{
+		subscribers: make(map[chan watchdog.Event]string),
+	}
+}
+
+// Notify forwards an event to the subscribers of its target, dropping it
+// for subscribers that fall behind
+func (e *Events) Notify(ctx context.Context, event watchdog.Event) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	for ch, target := range e.subscribers {
+		if target != "" && target != event.Target.Name {
+			continue
+		}
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+	return nil
+}
+
+// subscribe returns a channel receiving the events of target, or of every
+// target when it is empty
+func (e *Events) subscribe(target string) chan watchdog.Event {
+	ch := make(chan watchdog.Event, 64)
+	e.mu.Lock()
+	e.subscribers[ch] = target
+	e.mu.Unlock()
+	return ch
+}
+
+func (e *Events) unsubscribe(ch chan watchdog.Event) {
+	e.mu.Lock()
+	delete(e.subscribers, ch)
+	e.mu.Unlock()
+}
+
+// Server implements the gRPC service
+type Server struct {
+	controller   Controller
+	events       *Events
+	restartToken string
+}
+
+// NewServer creates a new instance of Server controlling c and streaming
+// the events received by events. Pause, Resume and TriggerCheck act on the
+// targets, so like the operator actions of the admin API they require
+// restartToken as "authorization: Bearer" metadata, and are refused when
+// it is empty. It may refer to a file or a Kubernetes Secret.
+func NewServer(c Controller, events *Events, restartToken string) *Server {
+	return &Server{
+		controller:   c,
+		events:       events,
+		restartToken: restartToken,
+	}
+}
+
+// ServeHTTP dispatches a gRPC call
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.ProtoMajor != 2 || r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+
+	request, err := readMessage(r.Body)
+	if err != nil {
+		writeStatus(w, codeInvalidArgument, err.Error())
+		return
+	}
+	target, err := decodeString(request, 1)
+	if err != nil {
+		writeStatus(w, codeInvalidArgument, err.Error())
+		return
+	}
+
+	method := strings.TrimPrefix(r.URL.Path, "/"+Service+"/")
+	switch method {
+	case "Pause", "Resume", "TriggerCheck":
+		if !s.authorize(w, r) {
+			return
+		}
+	}
+	switch method {
+	case "Status":
+		var response encoder
+		for _, status := range s.controller.Status() {
+			response.message(1, encodeStatus(status))
+		}
+		s.reply(w, &response, nil)
+	case "Pause", "Resume":
+		user, err := decodeString(request, 2)
+		if err != nil {
+			writeStatus(w, codeInvalidArgument, err.Error())
+			return
+		}
+		why, err := decodeString(request, 3)
+		if err != nil {
+			writeStatus(w, codeInvalidArgument, err.Error())
+			return
+		}
+		names, err := s.setPaused(target, method == "Pause", operatorReason(strings.ToLower(method), user, why, r))
+		s.reply(w, encodeNames(names), err)
+	case "TriggerCheck":
+		result, err := s.controller.CheckTarget(r.Context(), target)
+		s.reply(w, encodeResult(result), err)
+	case "StreamEvents":
+		s.streamEvents(w, r, target)
+	default:
+		writeStatus(w, codeUnimplemented, "unknown method "+r.URL.Path)
+	}
+}
+
+// setPaused pauses or resumes the named target, or every target when name
+// is empty, and returns the names of the targets changed. Resuming every
+// target skips those that aren't paused.
+func (s *Server) setPaused(name string, pause bool, reason string) ([]string, error) {
+	names := []string{name}
+	if name == "" {
+		names = nil
+		for _, status := range s.controller.Status() {
+			names = append(names, status.Target.Name)
+		}
+	}
+	changed := make([]string, 0, len(names))
+	for _, n := range names {
+		var err error
+		if pause {
+			_, err = s.controller.PauseTarget(n, 0, reason)
+		} else {
+			err = s.controller.ResumeTarget(n, reason)
+		}
+		if name == "" && errors.Is(err, watchdog.ErrNotPaused) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		changed = append(changed, n)
+	}
+	return changed, nil
+}
+
+// operatorReason describes who requested an action, from where and why,
+// like the admin API
+func operatorReason(action, user, why string, r *http.Request) string {
+	if user == "" {
+		user = "unknown user"
+	}
+	reason := fmt.Sprintf("%s by %s from %s over gRPC", action, user, r.RemoteAddr)
+	if why != "" {
+		reason += ": " + why
+	}
+	return reason
+}
+
+// authorize checks the restart token of an operator action, writing the
+// status of the call when it is refused
+func (s *Server) authorize(w http.ResponseWriter, r *http.Request) bool {
+	restartToken, err := secret.Resolve(r.Context(), s.restartToken)
+	if err != nil {
+		writeStatus(w, codeUnavailable, "restart token unavailable")
+		return false
+	}
+	if restartToken == "" {
+		writeStatus(w, codePermissionDenied, "operator actions are disabled without a restart token")
+		return false
+	}
+	token, ok := httpauth.BearerToken(r)
+	if !ok || subtle.ConstantTimeCompare([]byte(token), []byte(restartToken)) != 1 {
+		writeStatus(w, codeUnauthenticated, "invalid restart token")
+		return false
+	}
+	return true
+}
+
+// reply writes the response of a unary call, or the status of its error
+func (s *Server) reply(w http.ResponseWriter, response *encoder, err error) {
+	switch {
+	case errors.Is(err, watchdog.ErrTargetNotFound):
+		writeStatus(w, codeNotFound, err.Error())
+	case errors.Is(err, watchdog.ErrNotPaused):
+		writeStatus(w, codeFailedPrecondition, err.Error())
+	case err != nil:
+		writeStatus(w, codeInternal, err.Error())
+	default:
+		writeMessage(w, response.buf)
+		writeStatus(w, codeOK, "")
+	}
+}
+
+// streamEvents sends events until the call is cancelled
+func (s *Server) streamEvents(w http.ResponseWriter, r *http.Request, target string) {
+	if target != "" {
+		found := false
+		for _, status := range s.controller.Status() {
+			found = found || status.Target.Name == target
+		}
+		if !found {
+			writeStatus(w, codeNotFound, "target not found: '"+target+"'")
+			return
+		}
+	}
+
+	ch := s.events.subscribe(target)
+	defer s.events.unsubscribe(ch)
+
+	flusher, _ := w.(http.Flusher)
+	w.WriteHeader(http.StatusOK)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			writeStatus(w, codeOK, "")
+			return
+		case event := <-ch:
+			if err := writeMessage(w, encodeEvent(event).buf); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// readMessage reads a single length-prefixed message
+func readMessage(r io.Reader) ([]byte, error) {
+	var header [5]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, errors.New("missing request message")
+	}
+	if header[0] != 0 {
+		return nil, errors.New("compressed messages are not supported")
+	}
+	size := binary.BigEndian.Uint32(header[1:])
+	if size > maxMessageSize {
+		return nil, errors.New("request message too large")
+	}
+	message := make([]byte, size)
+	if _, err := io.ReadFull(r, message); err != nil {
+		return nil, errors.New("truncated request message")
+	}
+	return message, nil
+}
+
+// writeMessage writes a single length-prefixed message
+func writeMessage(w io.Writer, message []byte) error {
+	var header [5]byte
+	binary.BigEndian.PutUint32(header[1:], uint32(len(message)))
+	_, err := w.Write(append(header[:], message...))
+	return err
+}
+
+// writeStatus sets the status trailers of the call
+func writeStatus(w http.ResponseWriter, code int, message string) {
+	w.Header().Set("Grpc-Status", strconv.Itoa(code))
+	if message != "" {
+		w.Header().Set("Grpc-Message", encodeStatusMessage(message))
+	}
+}
+
+// encodeStatusMessage percent-encodes the bytes of a status message that
+// aren't printable ASCII, and the percent sign, as the gRPC protocol
+// requires, so multi-line errors are still valid header values
+func encodeStatusMessage(message string) string {
+	var b strings.Builder
+	for i := 0; i < len(message); i++ {
+		c := message[i]
+		if c < 0x20 || c > 0x7e || c == '%' {
+			fmt.Fprintf(&b, "%%%02X", c)
+		} else {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+func encodeNames(names []string) *encoder {
+	var e encoder
+	for _, name := range names {
+		e.bytes(1, []byte(name))
+	}
+	return &e
+}
+
+func encodeTarget(target watchdog.Target) *encoder {
+	var e encoder
+	e.string(1, target.Name)
+	e.string(2, target.Namespace)
+	e.string(3, target.DeploymentName)
+	e.int64(4, int64(target.MemoryThreshold))
+	return &e
+}
+
+func encodeStatus(status watchdog.TargetStatus) *encoder {
+	var e encoder
+	e.message(1, encodeTarget(status.Target))
+	e.bool(2, status.Paused)
+	if status.LastResult != nil {
+		e.message(3, encodeResult(*status.LastResult))
+	}
+	return &e
+}
+
+func encodeResult(result watchdog.CheckResult) *encoder {
+	var e encoder
+	e.string(1, result.Target.Name)
+	e.int64(2, int64(result.Memory))
+	e.int64(3, int64(result.Threshold))
+	e.bool(4, result.Breached)
+	e.string(5, result.Action)
+	e.string(6, result.Source)
+	if !result.Time.IsZero() {
+		e.int64(7, result.Time.UnixNano()/1e6)
+	}
+	if result.Err != nil {
+		e.string(8, result.Err.Error())
+	}
+	return &e
+}
+
+func encodeEvent(event watchdog.Event) *encoder {
+	var e encoder
+	e.string(1, string(event.Type))
+	e.string(2, event.Target.Name)
+	e.int64(3, int64(event.Memory))
+	e.int64(4, int64(event.Threshold))
+	if !event.Time.IsZero() {
+		e.int64(5, event.Time.UnixNano()/1e6)
+	}
+	e.string(6, event.Reason)
+	if event.Err != nil {
+		e.string(7, event.Err.Error())
+	}
+	return &e
+}
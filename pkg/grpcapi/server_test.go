@@ -0,0 +1,255 @@
+package grpcapi
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+type fakeController struct {
+	paused  []string
+	reasons []string
+}
+
+func (c *fakeController) Status() []watchdog.TargetStatus {
+	return []watchdog.TargetStatus{
+		{Target: watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api", MemoryThreshold: 2000}, Paused: true},
+	}
+}
+
+func (c *fakeController) PauseTarget(name string, duration time.Duration, reason string) (watchdog.Pause, error) {
+	if name != "prod/api" {
+		return watchdog.Pause{}, fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	c.paused = append(c.paused, name)
+	c.reasons = append(c.reasons, reason)
+	return watchdog.Pause{Reason: reason}, nil
+}
+
+func (c *fakeController) ResumeTarget(name, reason string) error {
+	if name != "prod/api" {
+		return fmt.Errorf("%w: '%s'", watchdog.ErrTargetNotFound, name)
+	}
+	if len(c.paused) == 0 {
+		return fmt.Errorf("%w: '%s'", watchdog.ErrNotPaused, name)
+	}
+	c.paused = c.paused[:len(c.paused)-1]
+	c.reasons = append(c.reasons, reason)
+	return nil
+}
+
+func (c *fakeController) CheckTarget(ctx context.Context, name string) (watchdog.CheckResult, error) {
+	return watchdog.CheckResult{
+		Target:    watchdog.Target{Name: name},
+		Memory:    3000,
+		Threshold: 2000,
+		Breached:  true,
+		Action:    "restart",
+		Time:      time.Unix(1700000000, 0),
+	}, nil
+}
+
+// call performs a gRPC call and returns the response messages and status
+func call(t *testing.T, client *http.Client, url, method string, request []byte) ([][]byte, string) {
+	return callWithAuth(t, client, url, method, request, "Bearer "+testToken)
+}
+
+// testToken is the restart token of the test servers
+const testToken = "s3cret"
+
+// callWithAuth performs a gRPC call with auth as its Authorization header
+func callWithAuth(t *testing.T, client *http.Client, url, method string, request []byte, auth string) ([][]byte, string) {
+	t.Helper()
+	var body bytes.Buffer
+	writeMessage(&body, request)
+	req, _ := http.NewRequest(http.MethodPost, url+"/"+Service+"/"+method, &body)
+	req.Header.Set("Content-Type", "application/grpc")
+	if auth != "" {
+		req.Header.Set("Authorization", auth)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("%s: %v", method, err)
+	}
+	defer resp.Body.Close()
+	if resp.ProtoMajor != 2 {
+		t.Fatalf("%s: served over %s, want HTTP/2", method, resp.Proto)
+	}
+
+	var messages [][]byte
+	for {
+		message, err := readMessage(resp.Body)
+		if err != nil {
+			break
+		}
+		messages = append(messages, message)
+	}
+	io.Copy(io.Discard, resp.Body)
+	status := resp.Trailer.Get("Grpc-Status")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+	}
+	return messages, status
+}
+
+func targetRequest(target string) []byte {
+	var e encoder
+	e.string(1, target)
+	return e.buf
+}
+
+func TestServerUnary(t *testing.T) {
+	controller := &fakeController{}
+	server := httptest.NewUnstartedServer(NewServer(controller, NewEvents(), testToken))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	client := server.Client()
+
+	messages, status := callWithAuth(t, client, server.URL, "Status", nil, "")
+	var target encoder
+	target.string(1, "prod/api")
+	target.string(2, "prod")
+	target.string(3, "api")
+	target.int64(4, 2000)
+	var entry, expected encoder
+	entry.message(1, &target)
+	entry.bool(2, true)
+	expected.message(1, &entry)
+	if status != "0" || len(messages) != 1 || !bytes.Equal(messages[0], expected.buf) {
+		t.Errorf("Status() = %x, status %s, want %x", messages, status, expected.buf)
+	}
+
+	var pause encoder
+	pause.string(1, "prod/api")
+	pause.string(2, "alice")
+	pause.string(3, "INC-42")
+	messages, status = call(t, client, server.URL, "Pause", pause.buf)
+	if name, _ := decodeString(messages[0], 1); status != "0" || name != "prod/api" || len(controller.paused) != 1 {
+		t.Errorf("Pause() = %q, status %s", name, status)
+	}
+	if reason := controller.reasons[0]; !strings.HasPrefix(reason, "pause by alice from ") || !strings.HasSuffix(reason, " over gRPC: INC-42") {
+		t.Errorf("pause reason = %q, want the user and reason of the request", reason)
+	}
+	messages, status = call(t, client, server.URL, "Resume", targetRequest(""))
+	if name, _ := decodeString(messages[0], 1); status != "0" || name != "prod/api" || len(controller.paused) != 0 {
+		t.Errorf("Resume() = %q, status %s, want every paused target resumed", name, status)
+	}
+	if !strings.HasPrefix(controller.reasons[1], "resume by unknown user from ") {
+		t.Errorf("resume reason = %q", controller.reasons[1])
+	}
+	if _, status = call(t, client, server.URL, "Resume", targetRequest("prod/api")); status != "9" {
+		t.Errorf("Resume() of a target that isn't paused status = %s, want 9 (FAILED_PRECONDITION)", status)
+	}
+	if _, status = call(t, client, server.URL, "Pause", targetRequest("missing")); status != "5" {
+		t.Errorf("Pause() of an unknown target status = %s, want 5 (NOT_FOUND)", status)
+	}
+
+	messages, status = call(t, client, server.URL, "TriggerCheck", targetRequest("prod/api"))
+	if action, _ := decodeString(messages[0], 5); status != "0" || action != "restart" {
+		t.Errorf("TriggerCheck() action = %q, status %s", action, status)
+	}
+
+	if _, status = call(t, client, server.URL, "Delete", nil); status != "12" {
+		t.Errorf("unknown method status = %s, want 12 (UNIMPLEMENTED)", status)
+	}
+}
+
+func TestServerStreamEvents(t *testing.T) {
+	events := NewEvents()
+	server := httptest.NewUnstartedServer(NewServer(&fakeController{}, events, testToken))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var body bytes.Buffer
+	writeMessage(&body, targetRequest("prod/api"))
+	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, server.URL+"/"+Service+"/StreamEvents", &body)
+	req.Header.Set("Content-Type", "application/grpc")
+	resp, err := server.Client().Do(req)
+	if err != nil {
+		t.Fatalf("StreamEvents: %v", err)
+	}
+	defer resp.Body.Close()
+
+	// the subscription is registered before the response headers are sent
+	events.Notify(ctx, watchdog.Event{Type: watchdog.EventBreach, Target: watchdog.Target{Name: "prod/worker"}})
+	events.Notify(ctx, watchdog.Event{Type: watchdog.EventRestartFailed, Target: watchdog.Target{Name: "prod/api"}, Err: errors.New("forbidden")})
+
+	message, err := readMessage(resp.Body)
+	if err != nil {
+		t.Fatalf("readMessage() error = %v", err)
+	}
+	eventType, _ := decodeString(message, 1)
+	eventErr, _ := decodeString(message, 7)
+	if eventType != "restart_failed" || eventErr != "forbidden" {
+		t.Errorf("StreamEvents() = %q (%q), want the restart_failed event of prod/api only", eventType, eventErr)
+	}
+}
+
+func TestDecodeString(t *testing.T) {
+	var e encoder
+	e.int64(2, 42)
+	e.string(1, "prod/api")
+	e.buf = append(e.buf, 0x1d, 1, 2, 3, 4) // field 3, fixed32
+	if value, err := decodeString(e.buf, 1); err != nil || value != "prod/api" {
+		t.Errorf("decodeString() = %q, %v", value, err)
+	}
+	if _, err := decodeString([]byte{0x0a, 10, 'a'}, 1); err == nil {
+		t.Error("decodeString() of a truncated message expected an error")
+	}
+	if size := binary.PutUvarint(make([]byte, binary.MaxVarintLen64), 300); len(appendUvarint(nil, 300)) != size {
+		t.Error("appendUvarint() length mismatch")
+	}
+}
+
+func TestServerRequiresRestartToken(t *testing.T) {
+	controller := &fakeController{}
+	server := httptest.NewUnstartedServer(NewServer(controller, NewEvents(), testToken))
+	server.EnableHTTP2 = true
+	server.StartTLS()
+	defer server.Close()
+	client := server.Client()
+
+	for _, method := range []string{"Pause", "Resume", "TriggerCheck"} {
+		for _, auth := range []string{"", "Bearer wrong", testToken, "Basic " + testToken} {
+			if _, status := callWithAuth(t, client, server.URL, method, targetRequest("prod/api"), auth); status != "16" {
+				t.Errorf("%s() with authorization %q status = %s, want 16 (UNAUTHENTICATED)", method, auth, status)
+			}
+		}
+	}
+	if len(controller.paused) != 0 {
+		t.Errorf("paused = %v, want nothing paused without the token", controller.paused)
+	}
+
+	disabled := httptest.NewUnstartedServer(NewServer(controller, NewEvents(), ""))
+	disabled.EnableHTTP2 = true
+	disabled.StartTLS()
+	defer disabled.Close()
+	if _, status := callWithAuth(t, disabled.Client(), disabled.URL, "Pause", targetRequest("prod/api"), ""); status != "7" {
+		t.Errorf("Pause() without a restart token configured status = %s, want 7 (PERMISSION_DENIED)", status)
+	}
+	if _, status := callWithAuth(t, disabled.Client(), disabled.URL, "Status", nil, ""); status != "0" {
+		t.Errorf("Status() status = %s, want 0 without a token", status)
+	}
+}
+
+func TestEncodeStatusMessage(t *testing.T) {
+	message := "error restarting: exit status 1\nError from server (Forbidden): 100% denied: \u00e9"
+	want := "error restarting: exit status 1%0AError from server (Forbidden): 100%25 denied: %C3%A9"
+	if got := encodeStatusMessage(message); got != want {
+		t.Errorf("encodeStatusMessage() = %q, want %q", got, want)
+	}
+}
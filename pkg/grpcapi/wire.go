@@ -0,0 +1,110 @@
+package grpcapi
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Protocol buffers wire types
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// encoder appends the fields of a protocol buffers message. Fields holding
+// their default value are omitted, as in proto3.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) tag(field, wireType int) {
+	e.buf = appendUvarint(e.buf, uint64(field)<<3|uint64(wireType))
+}
+
+func (e *encoder) string(field int, value string) {
+	if value == "" {
+		return
+	}
+	e.bytes(field, []byte(value))
+}
+
+func (e *encoder) bytes(field int, value []byte) {
+	e.tag(field, wireBytes)
+	e.buf = appendUvarint(e.buf, uint64(len(value)))
+	e.buf = append(e.buf, value...)
+}
+
+func (e *encoder) int64(field int, value int64) {
+	if value == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = appendUvarint(e.buf, uint64(value))
+}
+
+func (e *encoder) bool(field int, value bool) {
+	if !value {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.buf = append(e.buf, 1)
+}
+
+// message appends a nested message, which is kept even when empty to
+// record its presence
+func (e *encoder) message(field int, m *encoder) {
+	e.bytes(field, m.buf)
+}
+
+// appendUvarint appends the varint encoding of v
+func appendUvarint(buf []byte, v uint64) []byte {
+	var tmp [binary.MaxVarintLen64]byte
+	return append(buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+// decodeString returns the last value of a string field of a message,
+// skipping every other field
+func decodeString(data []byte, field int) (string, error) {
+	var value string
+	for len(data) > 0 {
+		key, n := binary.Uvarint(data)
+		if n <= 0 {
+			return "", errors.New("invalid field key")
+		}
+		data = data[n:]
+		number, wireType := int(key>>3), int(key&7)
+
+		switch wireType {
+		case wireVarint:
+			if _, n = binary.Uvarint(data); n <= 0 {
+				return "", errors.New("invalid varint")
+			}
+			data = data[n:]
+		case wireFixed64, wireFixed32:
+			size := 8
+			if wireType == wireFixed32 {
+				size = 4
+			}
+			if len(data) < size {
+				return "", io.ErrUnexpectedEOF
+			}
+			data = data[size:]
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 || uint64(len(data)-n) < length {
+				return "", io.ErrUnexpectedEOF
+			}
+			if number == field {
+				value = string(data[n : n+int(length)])
+			}
+			data = data[n+int(length):]
+		default:
+			return "", fmt.Errorf("unsupported wire type %d", wireType)
+		}
+	}
+	return value, nil
+}
@@ -0,0 +1,294 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// errAccessReview is the operation sentinel of a failed access review
+var errAccessReview = errors.New("access review failed")
+
+// Permission is a verb on a resource, optionally named, in a namespace
+type Permission struct {
+	Verb      string
+	Resource  string
+	Namespace string
+}
+
+func (p Permission) String() string {
+	if p.Namespace == "" {
+		return p.Verb + " " + p.Resource
+	}
+	return fmt.Sprintf("%s %s in namespace %s", p.Verb, p.Resource, p.Namespace)
+}
+
+// CanI reports whether the current identity has the permission, with a
+// SelfSubjectAccessReview made by kubectl auth can-i
+func (r *Runner) CanI(ctx context.Context, p Permission) (bool, error) {
+	args := []string{"auth", "can-i", p.Verb, p.Resource}
+	if p.Namespace != "" {
+		args = append(args, "-n", p.Namespace)
+	}
+	// can-i exits with 1 when the answer is no
+	output, err := r.Run(ctx, errAccessReview, args...)
+	answer := strings.TrimSpace(string(output))
+	switch {
+	case strings.HasPrefix(answer, "yes"):
+		return true, nil
+	case strings.HasPrefix(answer, "no"):
+		return false, nil
+	case err != nil:
+		return false, err
+	}
+	return false, fmt.Errorf("%w: unexpected answer %q", errAccessReview, answer)
+}
+
+// MissingPermissions returns the permissions the current identity lacks
+func (r *Runner) MissingPermissions(ctx context.Context, permissions []Permission) ([]Permission, error) {
+	var missing []Permission
+	for _, p := range permissions {
+		allowed, err := r.CanI(ctx, p)
+		if err != nil {
+			return nil, fmt.Errorf("checking permission to %s: %w", p, err)
+		}
+		if !allowed {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
+// errLookup is the operation sentinel of a failed existence check
+var errLookup = errors.New("lookup failed")
+
+// Exists reports whether the named resource exists, in namespace when it
+// is namespaced
+func (r *Runner) Exists(ctx context.Context, resource, name, namespace string) (bool, error) {
+	args := []string{"get", resource, name, "-o", "name"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	_, err := r.Run(ctx, errLookup, args...)
+	switch {
+	case err == nil:
+		return true, nil
+	case errors.Is(err, watchdog.ErrTargetNotFound):
+		return false, nil
+	}
+	return false, err
+}
+
+// Names returns the names of the resources of a kind, in namespace when it
+// is namespaced, or in the namespace of the current context when it is
+// empty
+func (r *Runner) Names(ctx context.Context, resource, namespace string) ([]string, error) {
+	args := []string{"get", resource, "-o", "jsonpath={.items[*].metadata.name}"}
+	if namespace != "" {
+		args = append(args, "-n", namespace)
+	}
+	output, err := r.Run(ctx, errLookup, args...)
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(output)), nil
+}
+
+// ReadyReplicas returns the number of ready replicas of a deployment
+func (r *Runner) ReadyReplicas(ctx context.Context, namespace, deployment string) (int, error) {
+	output, err := r.Run(ctx, errLookup, "get", "deployment", deployment, "-o", "jsonpath={.status.readyReplicas}", "-n", namespace)
+	if err != nil {
+		return 0, err
+	}
+	// readyReplicas is omitted while no replica is ready
+	value := strings.TrimSpace(string(output))
+	if value == "" {
+		return 0, nil
+	}
+	replicas, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("%w: invalid ready replicas %q", errLookup, value)
+	}
+	return replicas, nil
+}
+
+// AvailableReplicas returns the number of available replicas of a
+// deployment and the number it should have
+func (r *Runner) AvailableReplicas(ctx context.Context, namespace, deployment string) (int, int, error) {
+	output, err := r.Run(ctx, errLookup, "get", "deployment", deployment, "-o", "jsonpath={.spec.replicas} {.status.availableReplicas}", "-n", namespace)
+	if err != nil {
+		return 0, 0, err
+	}
+	// availableReplicas is omitted while no replica is available
+	fields := strings.Fields(string(output))
+	if len(fields) == 0 {
+		return 0, 0, fmt.Errorf("%w: no replica count", errLookup)
+	}
+	desired, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: invalid replica count %q", errLookup, fields[0])
+	}
+	available := 0
+	if len(fields) > 1 {
+		if available, err = strconv.Atoi(fields[1]); err != nil {
+			return 0, 0, fmt.Errorf("%w: invalid available replicas %q", errLookup, fields[1])
+		}
+	}
+	return available, desired, nil
+}
+
+// RolloutInProgress reports whether a deployment is rolling out, the way
+// kubectl rollout status does: its controller hasn't observed its latest
+// generation yet, not every replica has been updated, or old replicas are
+// still terminating
+func (r *Runner) RolloutInProgress(ctx context.Context, namespace, deployment string) (string, bool, error) {
+	output, err := r.Run(ctx, errLookup, "get", "deployment", deployment, "-o",
+		"jsonpath={.metadata.generation} {.status.observedGeneration} {.spec.replicas} {.status.updatedReplicas} {.status.replicas}", "-n", namespace)
+	if err != nil {
+		return "", false, err
+	}
+	// omitted status fields print as empty strings
+	fields := strings.Split(strings.TrimRight(string(output), "\n"), " ")
+	if len(fields) != 5 {
+		return "", false, fmt.Errorf("%w: invalid rollout status %q", errLookup, output)
+	}
+	values := make([]int, len(fields))
+	for i, field := range fields {
+		if field == "" {
+			continue
+		}
+		if values[i], err = strconv.Atoi(field); err != nil {
+			return "", false, fmt.Errorf("%w: invalid rollout status %q", errLookup, output)
+		}
+	}
+	generation, observed, desired, updated, replicas := values[0], values[1], values[2], values[3], values[4]
+	switch {
+	case observed < generation:
+		return fmt.Sprintf("generation %d not observed yet", generation), true, nil
+	case updated < desired:
+		return fmt.Sprintf("%d of %d replicas updated", updated, desired), true, nil
+	case replicas > updated:
+		return fmt.Sprintf("%d old replicas pending termination", replicas-updated), true, nil
+	}
+	return "", false, nil
+}
+
+// PodStartTimes returns the start time of each pod in namespace that has
+// started, by pod name
+func (r *Runner) PodStartTimes(ctx context.Context, namespace string) (map[string]time.Time, error) {
+	output, err := r.Run(ctx, errLookup, "get", "pods", "-o", `jsonpath={range .items[*]}{.metadata.name} {.status.startTime}{"\n"}{end}`, "-n", namespace)
+	if err != nil {
+		return nil, err
+	}
+	starts := make(map[string]time.Time)
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.Fields(line)
+		if len(fields) != 2 {
+			// pending pods have no start time yet
+			continue
+		}
+		start, err := time.Parse(time.RFC3339, fields[1])
+		if err != nil {
+			return nil, fmt.Errorf("%w: invalid start time of pod %s: %v", errLookup, fields[0], err)
+		}
+		starts[fields[0]] = start
+	}
+	return starts, nil
+}
+
+// PodOwnerKinds returns the kind of the controller owning each pod in
+// namespace that has an owner, such as ReplicaSet or Job, by pod name
+func (r *Runner) PodOwnerKinds(ctx context.Context, namespace string) (map[string]string, error) {
+	output, err := r.Run(ctx, errLookup, "get", "pods", "-o", `jsonpath={range .items[*]}{.metadata.name} {.metadata.ownerReferences[0].kind}{"\n"}{end}`, "-n", namespace)
+	if err != nil {
+		return nil, err
+	}
+	kinds := make(map[string]string)
+	for _, line := range strings.Split(string(output), "\n") {
+		if fields := strings.Fields(line); len(fields) == 2 {
+			kinds[fields[0]] = fields[1]
+		}
+	}
+	return kinds, nil
+}
+
+// ActiveDeployment returns the deployment of namespace whose pods are
+// selected by service, and the value of label in the selector of service.
+// It fails unless exactly one deployment matches, so the two colors of a
+// blue/green pair are never mistaken for one another.
+func (r *Runner) ActiveDeployment(ctx context.Context, namespace, service, label string) (string, string, error) {
+	output, err := r.Run(ctx, errLookup, "get", "service", service, "-o", "jsonpath={.spec.selector}", "-n", namespace)
+	if err != nil {
+		return "", "", err
+	}
+	var selector map[string]string
+	if err := json.Unmarshal(output, &selector); err != nil {
+		return "", "", fmt.Errorf("%w: invalid selector of service %s: %v", errLookup, service, err)
+	}
+	color := selector[label]
+	if color == "" {
+		return "", "", fmt.Errorf("%w: the selector of service %s has no %s label", errLookup, service, label)
+	}
+
+	output, err = r.Run(ctx, errLookup, "get", "deployments", "-o", `jsonpath={range .items[*]}{.metadata.name} {.spec.template.metadata.labels}{"\n"}{end}`, "-n", namespace)
+	if err != nil {
+		return "", "", err
+	}
+	var active []string
+	for _, line := range strings.Split(string(output), "\n") {
+		fields := strings.SplitN(line, " ", 2)
+		if len(fields) != 2 {
+			continue
+		}
+		var labels map[string]string
+		if err := json.Unmarshal([]byte(fields[1]), &labels); err != nil {
+			continue
+		}
+		if matchLabels(selector, labels) {
+			active = append(active, fields[0])
+		}
+	}
+	if len(active) != 1 {
+		return "", "", fmt.Errorf("%w: service %s selects the pods of %d deployments %v, want 1", errLookup, service, len(active), active)
+	}
+	return active[0], color, nil
+}
+
+// matchLabels reports whether labels has every label of selector
+func matchLabels(selector, labels map[string]string) bool {
+	for key, value := range selector {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// CurrentNamespace returns the namespace of the current context of the
+// kubeconfig, or an empty string when it has none
+func (r *Runner) CurrentNamespace(ctx context.Context) (string, error) {
+	output, err := r.Run(ctx, errLookup, "config", "view", "--minify", "-o", "jsonpath={..namespace}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(output)), nil
+}
+
+// TenantPolicy returns the watchdog.TenantPolicyKey entry of the named
+// ConfigMap of namespace, and false when the ConfigMap doesn't exist
+func (r *Runner) TenantPolicy(ctx context.Context, namespace, configMap string) (string, bool, error) {
+	output, err := r.Run(ctx, errLookup, "get", "configmap", configMap, "-o", "jsonpath={.data."+watchdog.TenantPolicyKey+"}", "-n", namespace)
+	switch {
+	case err == nil:
+		return string(output), true, nil
+	case errors.Is(err, watchdog.ErrTargetNotFound):
+		return "", false, nil
+	}
+	return "", false, err
+}
@@ -0,0 +1,218 @@
+package kubectl
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// fakeKubectl writes a kubectl answering auth can-i with yes for patching
+// and no for anything else, failing for the namespace "broken". Get finds
+// the deployment api, with 3 ready replicas, and the secret api-keys only.
+// It lists the deployments api and worker, and the pods api-1 and api-2,
+// the latter still pending, along with the pod of the job backup and the
+// bare pod debug. The namespace of the current context is prod, and only
+// the namespace team-a has a memory-watchdog ConfigMap.
+func fakeKubectl(t *testing.T) string {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "kubectl")
+	script := `#!/bin/sh
+case "$*" in
+  *broken*) echo "error: You must be logged in to the server (Unauthorized)"; exit 1 ;;
+  "auth can-i patch"*) echo yes ;;
+  "get deployments -o jsonpath={range"*) printf 'web-blue {"app":"web","color":"blue"}\nweb-green {"app":"web","color":"green"}\ncache {"app":"cache"}\n' ;;
+  "get deployments -o jsonpath"*) printf 'api worker' ;;
+  "get service web -o jsonpath"*) printf '{"app":"web","color":"blue"}' ;;
+  "get service web-all -o jsonpath"*) printf '{"app":"web"}' ;;
+  "get pods -o jsonpath={range .items[*]}{.metadata.name} {.status"*) printf 'api-1 2026-01-01T00:00:00Z\napi-2 \n' ;;
+  "get pods -o jsonpath"*) printf 'api-1 ReplicaSet\nbackup-28391-x7k2p Job\ndebug \n' ;;
+  "config view --minify"*) printf 'prod' ;;
+  "get deployment api -o jsonpath={.spec.replicas} "*) printf '3 2' ;;
+  "get deployment api -o jsonpath={.metadata.generation}"*) printf '4 4 3 2 4' ;;
+  "get deployment web -o jsonpath={.metadata.generation}"*) printf '7 7 2 2 2' ;;
+  "get deployment new -o jsonpath={.metadata.generation}"*) printf '1  2  ' ;;
+  "get deployment api -o jsonpath"*) printf 3 ;;
+  "get deployment api"*) echo deployment.apps/api ;;
+  "get configmap memory-watchdog -o jsonpath={.data.targets} -n team-a") printf -- '- deployment: api\n' ;;
+  "get secret api-keys"*) echo '{"data":{"datadog":"c2VjcmV0Cg=="}}' ;;
+  "get "*) echo 'Error from server (NotFound): deployments.apps "worker" not found'; exit 1 ;;
+  *) echo no; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestMissingPermissions(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+	permissions := []Permission{
+		{Verb: "patch", Resource: "deployments.apps/api", Namespace: "prod"},
+		{Verb: "list", Resource: "pods.metrics.k8s.io", Namespace: "prod"},
+	}
+
+	missing, err := runner.MissingPermissions(context.Background(), permissions)
+	if err != nil {
+		t.Fatalf("MissingPermissions() error = %v", err)
+	}
+	if len(missing) != 1 || missing[0] != permissions[1] {
+		t.Errorf("MissingPermissions() = %v, want %v", missing, permissions[1:])
+	}
+	if got := missing[0].String(); got != "list pods.metrics.k8s.io in namespace prod" {
+		t.Errorf("String() = %q", got)
+	}
+
+	_, err = runner.MissingPermissions(context.Background(), []Permission{{Verb: "patch", Resource: "deployments.apps", Namespace: "broken"}})
+	if err == nil {
+		t.Error("MissingPermissions() error = nil, want the kubectl error")
+	}
+}
+
+func TestExists(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	tests := []struct {
+		name    string
+		exists  bool
+		wantErr bool
+	}{
+		{name: "api", exists: true},
+		{name: "worker", exists: false},
+		{name: "broken", wantErr: true},
+	}
+	for _, tt := range tests {
+		exists, err := runner.Exists(context.Background(), "deployment", tt.name, "prod")
+		if exists != tt.exists || (err != nil) != tt.wantErr {
+			t.Errorf("Exists(%s) = %v, %v, want %v, error %v", tt.name, exists, err, tt.exists, tt.wantErr)
+		}
+	}
+}
+
+func TestNames(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	names, err := runner.Names(context.Background(), "deployments", "prod")
+	if err != nil {
+		t.Fatalf("Names() error = %v", err)
+	}
+	if len(names) != 2 || names[0] != "api" || names[1] != "worker" {
+		t.Errorf("Names() = %q, want [api worker]", names)
+	}
+
+	if _, err := runner.Names(context.Background(), "deployments", "broken"); err == nil {
+		t.Error("Names() error = nil, want the kubectl error")
+	}
+}
+
+func TestReadyReplicas(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	replicas, err := runner.ReadyReplicas(context.Background(), "prod", "api")
+	if err != nil || replicas != 3 {
+		t.Errorf("ReadyReplicas(api) = %d, %v, want 3", replicas, err)
+	}
+	if _, err := runner.ReadyReplicas(context.Background(), "prod", "worker"); err == nil {
+		t.Error("ReadyReplicas(worker) error = nil, want the kubectl error")
+	}
+}
+
+func TestAvailableReplicas(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	available, desired, err := runner.AvailableReplicas(context.Background(), "prod", "api")
+	if err != nil || available != 2 || desired != 3 {
+		t.Errorf("AvailableReplicas(api) = %d, %d, %v, want 2, 3", available, desired, err)
+	}
+	if _, _, err := runner.AvailableReplicas(context.Background(), "prod", "worker"); err == nil {
+		t.Error("AvailableReplicas(worker) error = nil, want the kubectl error")
+	}
+}
+
+func TestRolloutInProgress(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	tests := []struct {
+		deployment string
+		reason     string
+		inProgress bool
+	}{
+		{deployment: "api", reason: "2 of 3 replicas updated", inProgress: true},
+		{deployment: "web"},
+		{deployment: "new", reason: "generation 1 not observed yet", inProgress: true},
+	}
+	for _, tt := range tests {
+		reason, inProgress, err := runner.RolloutInProgress(context.Background(), "prod", tt.deployment)
+		if err != nil || reason != tt.reason || inProgress != tt.inProgress {
+			t.Errorf("RolloutInProgress(%s) = %q, %v, %v, want %q, %v", tt.deployment, reason, inProgress, err, tt.reason, tt.inProgress)
+		}
+	}
+	if _, _, err := runner.RolloutInProgress(context.Background(), "prod", "worker"); err == nil {
+		t.Error("RolloutInProgress(worker) error = nil, want the kubectl error")
+	}
+}
+
+func TestPodStartTimes(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	starts, err := runner.PodStartTimes(context.Background(), "prod")
+	if err != nil {
+		t.Fatalf("PodStartTimes() error = %v", err)
+	}
+	want := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	if len(starts) != 1 || !starts["api-1"].Equal(want) {
+		t.Errorf("PodStartTimes() = %v, want api-1 started at %v", starts, want)
+	}
+}
+
+func TestPodOwnerKinds(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	kinds, err := runner.PodOwnerKinds(context.Background(), "prod")
+	if err != nil {
+		t.Fatalf("PodOwnerKinds() error = %v", err)
+	}
+	if len(kinds) != 2 || kinds["api-1"] != "ReplicaSet" || kinds["backup-28391-x7k2p"] != "Job" {
+		t.Errorf("PodOwnerKinds() = %v, want api-1 owned by a ReplicaSet and backup-28391-x7k2p by a Job", kinds)
+	}
+}
+
+func TestActiveDeployment(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	deployment, color, err := runner.ActiveDeployment(context.Background(), "prod", "web", "color")
+	if err != nil || deployment != "web-blue" || color != "blue" {
+		t.Errorf("ActiveDeployment(web) = %q, %q, %v, want web-blue, blue", deployment, color, err)
+	}
+	if _, _, err := runner.ActiveDeployment(context.Background(), "prod", "web-all", "color"); err == nil {
+		t.Error("ActiveDeployment(web-all) error = nil, want an error for a selector without color")
+	}
+}
+
+func TestCurrentNamespace(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+	namespace, err := runner.CurrentNamespace(context.Background())
+	if err != nil || namespace != "prod" {
+		t.Errorf("CurrentNamespace() = %q, %v, want prod", namespace, err)
+	}
+}
+
+func TestTenantPolicy(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	policy, found, err := runner.TenantPolicy(context.Background(), "team-a", "memory-watchdog")
+	if err != nil || !found || policy != "- deployment: api\n" {
+		t.Errorf("TenantPolicy(team-a) = %q, %v, %v, want the targets entry", policy, found, err)
+	}
+	if _, found, err := runner.TenantPolicy(context.Background(), "team-b", "memory-watchdog"); err != nil || found {
+		t.Errorf("TenantPolicy(team-b) = %v, %v, want no policy", found, err)
+	}
+	if _, _, err := runner.TenantPolicy(context.Background(), "broken", "memory-watchdog"); err == nil {
+		t.Error("TenantPolicy(broken) error = nil, want the kubectl error")
+	}
+}
@@ -0,0 +1,275 @@
+// Package kubectl runs kubectl commands on behalf of the metric sources and
+// actions, sharing a single client-side rate limiter between them.
+package kubectl
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Runner executes kubectl commands. Commands whose context carries the
+// cluster of a target, see watchdog.ContextWithCluster, run on a runner of
+// that cluster, created on first use and kept in a pool.
+type Runner struct {
+	path    string
+	context string
+	server  string
+	ca      string
+	env     []string
+	tokens  TokenSource
+	qps     float64
+	burst   int
+	limiter *RateLimiter
+	logger  *logging.Logger
+
+	mu       sync.Mutex
+	clusters map[watchdog.Cluster]*Runner
+}
+
+// NewRunner creates a new instance of Runner. Invocations are rate limited
+// to qps per second with the given burst, unless qps is not positive.
+func NewRunner(path string, qps float64, burst int) *Runner {
+	r := &Runner{
+		path:     path,
+		qps:      qps,
+		burst:    burst,
+		clusters: make(map[watchdog.Cluster]*Runner),
+	}
+	if qps > 0 {
+		r.limiter = NewRateLimiter(qps, burst)
+	}
+	return r
+}
+
+// SetLogger logs every command at the debug level, and its output at the
+// trace level, to logger
+func (r *Runner) SetLogger(logger *logging.Logger) {
+	r.logger = logger
+}
+
+// SetContext makes kubectl use the named context of the kubeconfig instead
+// of the current one. An empty name keeps the current context.
+func (r *Runner) SetContext(name string) {
+	r.context = name
+}
+
+// SetProxy makes kubectl reach the Kubernetes API through the proxy at
+// proxyURL, except for the comma-separated hosts of noProxy. An empty
+// proxyURL keeps the proxy of the environment.
+func (r *Runner) SetProxy(proxyURL, noProxy string) {
+	r.env = nil
+	if proxyURL == "" {
+		return
+	}
+	r.env = append(os.Environ(), "HTTP_PROXY="+proxyURL, "HTTPS_PROXY="+proxyURL, "NO_PROXY="+noProxy)
+}
+
+// SetCertificateAuthority makes kubectl verify the Kubernetes API with the
+// CA bundle at path instead of the one of the kubeconfig. An empty path
+// keeps the kubeconfig's.
+func (r *Runner) SetCertificateAuthority(path string) {
+	r.ca = path
+}
+
+// TokenSource returns the bearer tokens kubectl authenticates with
+type TokenSource interface {
+	Token(ctx context.Context) (string, error)
+}
+
+// SetTokenSource makes kubectl authenticate with the tokens of tokens
+// instead of the credentials of the kubeconfig, such as the IAM tokens of
+// an EKS cluster. The tokens are left out of the logs.
+func (r *Runner) SetTokenSource(tokens TokenSource) {
+	r.tokens = tokens
+}
+
+// Invalidator is implemented by the token sources caching their tokens.
+// When the API server rejects a token as unauthorized, such as one revoked
+// or expired earlier than announced, the Runner invalidates it and runs
+// the command once more with a fresh token.
+type Invalidator interface {
+	Invalidate()
+}
+
+// Run executes kubectl with the given arguments and returns its combined
+// output. On failure the returned *Error matches op with errors.Is.
+func (r *Runner) Run(ctx context.Context, op error, args ...string) ([]byte, error) {
+	return r.RunInput(ctx, op, nil, args...)
+}
+
+// RunInput executes kubectl like Run, with input as its standard input
+func (r *Runner) RunInput(ctx context.Context, op error, input []byte, args ...string) ([]byte, error) {
+	return r.run(ctx, op, input, false, args...)
+}
+
+// run executes kubectl, logging its output unless it is sensitive
+func (r *Runner) run(ctx context.Context, op error, input []byte, sensitive bool, args ...string) ([]byte, error) {
+	if cluster := watchdog.ClusterFromContext(ctx); cluster != nil && r.clusters != nil {
+		r = r.cluster(*cluster)
+	}
+	if r.limiter != nil {
+		if err := r.limiter.Wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+
+	if r.ca != "" {
+		args = append([]string{"--certificate-authority", r.ca}, args...)
+	}
+	if r.context != "" {
+		args = append([]string{"--context", r.context}, args...)
+	}
+	if r.server != "" {
+		// the inline credentials replace the kubeconfig entirely
+		args = append([]string{"--kubeconfig", os.DevNull, "--server", r.server}, args...)
+	}
+	output, err := r.exec(ctx, op, input, sensitive, args)
+	if err != nil && ctx.Err() == nil && strings.Contains(strings.ToLower(string(output)), "unauthorized") {
+		if invalidator, ok := r.tokens.(Invalidator); ok {
+			invalidator.Invalidate()
+			output, err = r.exec(ctx, op, input, sensitive, args)
+		}
+	}
+	return output, err
+}
+
+// exec executes kubectl once, authenticating with a token of the token
+// source if there is one
+func (r *Runner) exec(ctx context.Context, op error, input []byte, sensitive bool, args []string) ([]byte, error) {
+	command := args
+	if r.tokens != nil {
+		token, err := r.tokens.Token(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("%w: error getting a Kubernetes API token: %v", op, err)
+		}
+		command = append([]string{"--token", token}, args...)
+	}
+	cmd := exec.CommandContext(ctx, r.path, command...)
+	cmd.Env = r.env
+	if input != nil {
+		cmd.Stdin = bytes.NewReader(input)
+	}
+	start := time.Now()
+	output, err := cmd.CombinedOutput()
+	r.logger.Debugf("kubectl %s (%v, error: %v)", strings.Join(args, " "), time.Since(start).Round(time.Millisecond), err)
+	if !sensitive {
+		r.logger.Tracef("kubectl %s output:\n%s", strings.Join(args, " "), output)
+	}
+	if err != nil {
+		if ctx.Err() != nil {
+			err = ctx.Err()
+		}
+		return output, newError(op, err, output)
+	}
+	return output, nil
+}
+
+// cluster returns the runner of a cluster from the pool, creating it on
+// first use. It has its own rate limiter, as each cluster has its own API
+// server, and authenticates with the credentials of its context or the
+// inline ones rather than with the token source of the default cluster.
+func (r *Runner) cluster(cluster watchdog.Cluster) *Runner {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if c, ok := r.clusters[cluster]; ok {
+		return c
+	}
+
+	c := &Runner{
+		path:    r.path,
+		context: cluster.Context,
+		server:  cluster.Server,
+		ca:      r.ca,
+		env:     r.env,
+		logger:  r.logger,
+	}
+	if r.qps > 0 {
+		c.limiter = NewRateLimiter(r.qps, r.burst)
+	}
+	if cluster.CAFile != "" {
+		c.ca = cluster.CAFile
+	}
+	if cluster.Token != "" {
+		c.tokens = credentialToken(cluster.Token)
+	}
+	r.clusters[cluster] = c
+	return c
+}
+
+// credentialToken is the token of an inline cluster, which may refer to a
+// file or a Kubernetes Secret as resolved by secret.Resolve
+type credentialToken string
+
+func (t credentialToken) Token(ctx context.Context) (string, error) {
+	// Secrets are read from the default cluster
+	return secret.Resolve(watchdog.ContextWithCluster(ctx, nil), string(t))
+}
+
+// Throttled returns how many kubectl invocations were delayed by the
+// client-side rate limiters of all clusters
+func (r *Runner) Throttled() uint64 {
+	var throttled uint64
+	if r.limiter != nil {
+		throttled = r.limiter.Throttled()
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, c := range r.clusters {
+		if c.limiter != nil {
+			throttled += c.limiter.Throttled()
+		}
+	}
+	return throttled
+}
+
+// Error is returned when a kubectl invocation fails. It matches the sentinel
+// of the failed operation (watchdog.ErrMetricsUnavailable or
+// watchdog.ErrRestartFailed) and, when it can be determined from the output,
+// the sentinel of the cause (watchdog.ErrForbidden or
+// watchdog.ErrTargetNotFound).
+type Error struct {
+	Op     error
+	Reason error
+	Output string
+	Err    error
+}
+
+// newError classifies a failed kubectl invocation from its output
+func newError(op error, err error, output []byte) *Error {
+	text := strings.TrimSpace(string(output))
+	lower := strings.ToLower(text)
+
+	var reason error
+	switch {
+	case strings.Contains(lower, "forbidden"):
+		reason = watchdog.ErrForbidden
+	case strings.Contains(lower, "notfound") || strings.Contains(lower, "not found"):
+		reason = watchdog.ErrTargetNotFound
+	}
+
+	return &Error{Op: op, Reason: reason, Output: text, Err: err}
+}
+
+func (e *Error) Error() string {
+	return e.Op.Error() + ": " + e.Err.Error() + ": " + e.Output
+}
+
+// Unwrap returns the underlying execution error
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is the operation or reason sentinel
+func (e *Error) Is(target error) bool {
+	return target == e.Op || (e.Reason != nil && target == e.Reason)
+}
@@ -0,0 +1,232 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestNewError(t *testing.T) {
+	execErr := errors.New("exit status 1")
+
+	tests := []struct {
+		name   string
+		op     error
+		output string
+		is     []error
+		isNot  []error
+		class  string
+	}{
+		{
+			name:   "forbidden restart",
+			op:     watchdog.ErrRestartFailed,
+			output: `Error from server (Forbidden): deployments.apps "app" is forbidden: User "system:serviceaccount:default:watchdog" cannot patch resource "deployments"`,
+			is:     []error{watchdog.ErrRestartFailed, watchdog.ErrForbidden},
+			isNot:  []error{watchdog.ErrTargetNotFound, watchdog.ErrMetricsUnavailable},
+			class:  "forbidden",
+		},
+		{
+			name:   "missing deployment",
+			op:     watchdog.ErrRestartFailed,
+			output: `Error from server (NotFound): deployments.apps "app" not found`,
+			is:     []error{watchdog.ErrRestartFailed, watchdog.ErrTargetNotFound},
+			isNot:  []error{watchdog.ErrForbidden},
+			class:  "target_not_found",
+		},
+		{
+			name:   "metrics api missing",
+			op:     watchdog.ErrMetricsUnavailable,
+			output: "error: Metrics API not available",
+			is:     []error{watchdog.ErrMetricsUnavailable},
+			isNot:  []error{watchdog.ErrRestartFailed, watchdog.ErrForbidden, watchdog.ErrTargetNotFound},
+			class:  "metrics_unavailable",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := fmt.Errorf("error getting memory usage: %w", newError(tt.op, execErr, []byte(tt.output)))
+			for _, target := range tt.is {
+				if !errors.Is(err, target) {
+					t.Errorf("errors.Is(err, %v) = false, want true", target)
+				}
+			}
+			for _, target := range tt.isNot {
+				if errors.Is(err, target) {
+					t.Errorf("errors.Is(err, %v) = true, want false", target)
+				}
+			}
+			if !errors.Is(err, execErr) {
+				t.Errorf("errors.Is(err, execErr) = false, want true")
+			}
+			if got := watchdog.ClassifyError(err); got != tt.class {
+				t.Errorf("ClassifyError() = %v, want %v", got, tt.class)
+			}
+		})
+	}
+}
+
+func TestClassifyErrorTimeout(t *testing.T) {
+	err := newError(watchdog.ErrMetricsUnavailable, context.DeadlineExceeded, nil)
+	if got := watchdog.ClassifyError(err); got != "timeout" {
+		t.Errorf("ClassifyError() = %v, want timeout", got)
+	}
+	if got := watchdog.ClassifyError(errors.New("boom")); got != "unknown" {
+		t.Errorf("ClassifyError() = %v, want unknown", got)
+	}
+}
+
+func TestRunnerRunInput(t *testing.T) {
+	runner := NewRunner("cat", 0, 0)
+	output, err := runner.RunInput(context.Background(), watchdog.ErrRestartFailed, []byte("apiVersion: v1\n"))
+	if err != nil {
+		t.Skipf("cat is not available: %v", err)
+	}
+	if string(output) != "apiVersion: v1\n" {
+		t.Errorf("RunInput() = %q, want the input echoed", output)
+	}
+}
+
+func TestSetContext(t *testing.T) {
+	runner := NewRunner("echo", 0, 0)
+	output, err := runner.Run(context.Background(), watchdog.ErrMetricsUnavailable, "top", "pods")
+	if err != nil || string(output) != "top pods\n" {
+		t.Errorf("Run() = %q, %v, want the arguments unchanged", output, err)
+	}
+
+	runner.SetContext("staging")
+	output, err = runner.Run(context.Background(), watchdog.ErrMetricsUnavailable, "top", "pods")
+	if err != nil || string(output) != "--context staging top pods\n" {
+		t.Errorf("Run() = %q, %v, want the context first", output, err)
+	}
+}
+
+func TestRunnerClusters(t *testing.T) {
+	runner := NewRunner("echo", 10, 1)
+	runner.SetContext("prod")
+	runner.SetTokenSource(staticTokens{token: "k8s-aws-v1.abc"})
+
+	staging := watchdog.ContextWithCluster(context.Background(), &watchdog.Cluster{Context: "staging"})
+	output, err := runner.Run(staging, watchdog.ErrMetricsUnavailable, "top", "pods")
+	if err != nil || string(output) != "--context staging top pods\n" {
+		t.Errorf("Run() = %q, %v, want the context of the target without the default token", output, err)
+	}
+	if runner.cluster(watchdog.Cluster{Context: "staging"}) != runner.cluster(watchdog.Cluster{Context: "staging"}) {
+		t.Error("cluster() created a second runner for the same cluster")
+	}
+
+	edge := watchdog.ContextWithCluster(context.Background(), &watchdog.Cluster{
+		Server: "https://edge.example.com:6443",
+		CAFile: "/etc/watchdog/edge-ca.pem",
+		Token:  "edge-token",
+	})
+	output, err = runner.Run(edge, watchdog.ErrMetricsUnavailable, "top", "pods")
+	expected := "--token edge-token --kubeconfig /dev/null --server https://edge.example.com:6443 --certificate-authority /etc/watchdog/edge-ca.pem top pods\n"
+	if err != nil || string(output) != expected {
+		t.Errorf("Run() = %q, %v, want %q", output, err, expected)
+	}
+
+	output, err = runner.Run(watchdog.ContextWithCluster(context.Background(), nil), watchdog.ErrMetricsUnavailable, "top", "pods")
+	if err != nil || string(output) != "--token k8s-aws-v1.abc --context prod top pods\n" {
+		t.Errorf("Run() = %q, %v, want the default cluster", output, err)
+	}
+}
+
+func TestSetCertificateAuthority(t *testing.T) {
+	runner := NewRunner("echo", 0, 0)
+	runner.SetContext("staging")
+	runner.SetCertificateAuthority("/etc/watchdog/kube-ca.pem")
+	output, err := runner.Run(context.Background(), watchdog.ErrMetricsUnavailable, "top", "pods")
+	if err != nil || string(output) != "--context staging --certificate-authority /etc/watchdog/kube-ca.pem top pods\n" {
+		t.Errorf("Run() = %q, %v, want the CA bundle before the arguments", output, err)
+	}
+}
+
+func TestSetProxy(t *testing.T) {
+	runner := NewRunner("sh", 0, 0)
+	runner.SetProxy("http://proxy.corp:3128", "10.0.0.0/8")
+	output, err := runner.Run(context.Background(), watchdog.ErrMetricsUnavailable, "-c", `echo "$HTTPS_PROXY $NO_PROXY"`)
+	if err != nil {
+		t.Skipf("sh is not available: %v", err)
+	}
+	if string(output) != "http://proxy.corp:3128 10.0.0.0/8\n" {
+		t.Errorf("Run() = %q, want the proxy in the environment of kubectl", output)
+	}
+}
+
+// staticTokens returns token, or fails with err
+type staticTokens struct {
+	token string
+	err   error
+}
+
+func (s staticTokens) Token(ctx context.Context) (string, error) {
+	return s.token, s.err
+}
+
+func TestSetTokenSource(t *testing.T) {
+	runner := NewRunner("echo", 0, 0)
+	runner.SetContext("eks")
+	runner.SetTokenSource(staticTokens{token: "k8s-aws-v1.abc"})
+	output, err := runner.Run(context.Background(), watchdog.ErrMetricsUnavailable, "top", "pods")
+	if err != nil || string(output) != "--token k8s-aws-v1.abc --context eks top pods\n" {
+		t.Errorf("Run() = %q, %v, want the token first", output, err)
+	}
+
+	runner.SetTokenSource(staticTokens{err: errors.New("no AWS credentials")})
+	if _, err := runner.Run(context.Background(), watchdog.ErrMetricsUnavailable, "top", "pods"); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("Run() error = %v, want the operation of the failed command", err)
+	}
+}
+
+// expiringTokens returns stale until it is invalidated, and fresh after
+type expiringTokens struct {
+	invalidated int
+}
+
+func (e *expiringTokens) Token(ctx context.Context) (string, error) {
+	if e.invalidated == 0 {
+		return "stale", nil
+	}
+	return "fresh", nil
+}
+
+func (e *expiringTokens) Invalidate() {
+	e.invalidated++
+}
+
+func TestRunnerRefreshesRejectedTokens(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("requires a POSIX shell")
+	}
+	path := filepath.Join(t.TempDir(), "kubectl")
+	script := `#!/bin/sh
+case "$2" in
+  fresh) echo ok ;;
+  *) echo "error: You must be logged in to the server (Unauthorized)"; exit 1 ;;
+esac
+`
+	if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	tokens := &expiringTokens{}
+	runner := NewRunner(path, 0, 0)
+	runner.SetTokenSource(tokens)
+	output, err := runner.Run(context.Background(), watchdog.ErrMetricsUnavailable, "top", "pods")
+	if err != nil || string(output) != "ok\n" || tokens.invalidated != 1 {
+		t.Errorf("Run() = %q, %v after %d invalidations, want ok after 1", output, err, tokens.invalidated)
+	}
+
+	// tokens that can't be invalidated fail like any other credentials
+	runner.SetTokenSource(staticTokens{token: "stale"})
+	if _, err := runner.Run(context.Background(), watchdog.ErrMetricsUnavailable, "top", "pods"); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("Run() error = %v, want the rejected token reported", err)
+	}
+}
@@ -0,0 +1,79 @@
+package kubectl
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// RateLimiter is a token bucket limiting how often the Kubernetes API is
+// called. It allows bursts of up to burst requests and refills at qps tokens
+// per second.
+type RateLimiter struct {
+	qps   float64
+	burst float64
+	now   func() time.Time
+
+	mu     sync.Mutex
+	tokens float64
+	last   time.Time
+
+	throttled uint64
+}
+
+// NewRateLimiter creates a new instance of RateLimiter. A burst lower than
+// one is treated as one.
+func NewRateLimiter(qps float64, burst int) *RateLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &RateLimiter{
+		qps:    qps,
+		burst:  float64(burst),
+		now:    time.Now,
+		tokens: float64(burst),
+	}
+}
+
+// Wait blocks until a request is allowed or the context is cancelled
+func (r *RateLimiter) Wait(ctx context.Context) error {
+	r.mu.Lock()
+	now := r.now()
+	if !r.last.IsZero() {
+		r.tokens += now.Sub(r.last).Seconds() * r.qps
+		if r.tokens > r.burst {
+			r.tokens = r.burst
+		}
+	}
+	r.last = now
+	r.tokens--
+	wait := time.Duration(0)
+	if r.tokens < 0 {
+		wait = time.Duration(-r.tokens / r.qps * float64(time.Second))
+	}
+	r.mu.Unlock()
+
+	if wait == 0 {
+		return nil
+	}
+
+	atomic.AddUint64(&r.throttled, 1)
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		// give the reserved token back
+		r.mu.Lock()
+		r.tokens++
+		r.mu.Unlock()
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Throttled returns the number of requests that had to wait for a token
+func (r *RateLimiter) Throttled() uint64 {
+	return atomic.LoadUint64(&r.throttled)
+}
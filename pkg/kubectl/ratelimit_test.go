@@ -0,0 +1,58 @@
+package kubectl
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterBurst(t *testing.T) {
+	limiter := NewRateLimiter(1, 3)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	if got := limiter.Throttled(); got != 0 {
+		t.Errorf("Throttled() = %v, want 0 within burst", got)
+	}
+
+	// the bucket is empty, so the next request has to wait
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := limiter.Wait(ctx); err != context.DeadlineExceeded {
+		t.Errorf("Wait() error = %v, want %v", err, context.DeadlineExceeded)
+	}
+	if got := limiter.Throttled(); got != 1 {
+		t.Errorf("Throttled() = %v, want 1", got)
+	}
+
+	// after refilling, requests pass again
+	now = now.Add(2 * time.Second)
+	if err := limiter.Wait(context.Background()); err != nil {
+		t.Errorf("Wait() after refill error = %v", err)
+	}
+	if got := limiter.Throttled(); got != 1 {
+		t.Errorf("Throttled() = %v, want 1", got)
+	}
+}
+
+func TestRateLimiterWaits(t *testing.T) {
+	limiter := NewRateLimiter(100, 1)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := limiter.Wait(context.Background()); err != nil {
+			t.Fatalf("Wait() error = %v", err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 15*time.Millisecond {
+		t.Errorf("3 requests at 100 QPS took %v, want at least 15ms", elapsed)
+	}
+	if got := limiter.Throttled(); got != 2 {
+		t.Errorf("Throttled() = %v, want 2", got)
+	}
+}
@@ -0,0 +1,28 @@
+package kubectl
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// errSecret is the operation sentinel of a failed Secret read
+var errSecret = errors.New("secret read failed")
+
+// SecretData returns the decoded data of a Secret. The output of the
+// command is never logged.
+func (r *Runner) SecretData(ctx context.Context, namespace, name string) (map[string][]byte, error) {
+	output, err := r.run(ctx, errSecret, nil, true, "get", "secret", name, "-n", namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	// []byte values are decoded from base64
+	var secret struct {
+		Data map[string][]byte `json:"data"`
+	}
+	if err := json.Unmarshal(output, &secret); err != nil {
+		return nil, fmt.Errorf("invalid secret %s/%s: %v", namespace, name, err)
+	}
+	return secret.Data, nil
+}
@@ -0,0 +1,24 @@
+package kubectl
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestSecretData(t *testing.T) {
+	runner := NewRunner(fakeKubectl(t), 0, 0)
+
+	data, err := runner.SecretData(context.Background(), "prod", "api-keys")
+	if err != nil {
+		t.Fatalf("SecretData() error = %v", err)
+	}
+	if string(data["datadog"]) != "secret\n" {
+		t.Errorf("SecretData() = %q, want the decoded data", data)
+	}
+	if _, err := runner.SecretData(context.Background(), "prod", "missing"); !errors.Is(err, watchdog.ErrTargetNotFound) {
+		t.Errorf("SecretData() error = %v, want a not found error", err)
+	}
+}
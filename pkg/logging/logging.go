@@ -0,0 +1,200 @@
+// Package logging provides leveled loggers for the components of the
+// watchdog, with a default level and per-component overrides.
+package logging
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level is the severity of a log message. Messages above the level of their
+// component are discarded.
+type Level int
+
+// Log levels, from the least to the most verbose
+const (
+	LevelError Level = iota
+	LevelWarn
+	LevelInfo
+	LevelDebug
+	LevelTrace
+)
+
+var levelNames = []string{"error", "warn", "info", "debug", "trace"}
+
+// String returns the name of the level, such as "debug"
+func (l Level) String() string {
+	if l < LevelError || l > LevelTrace {
+		return fmt.Sprintf("level(%d)", int(l))
+	}
+	return levelNames[l]
+}
+
+// ParseLevel parses a level name, case-insensitively
+func ParseLevel(name string) (Level, error) {
+	for i, n := range levelNames {
+		if strings.EqualFold(name, n) {
+			return Level(i), nil
+		}
+	}
+	if strings.EqualFold(name, "warning") {
+		return LevelWarn, nil
+	}
+	return LevelInfo, fmt.Errorf("unknown log level '%s', want error, warn, info, debug or trace", name)
+}
+
+// Levels holds the default level and the overrides of some components
+type Levels struct {
+	Default    Level
+	Components map[string]Level
+}
+
+// ParseLevels parses a comma-separated list of a default level and
+// component=level overrides, such as "info,metrics=debug". An empty spec
+// is the info level.
+func ParseLevels(spec string) (Levels, error) {
+	levels := Levels{Default: LevelInfo}
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		eq := strings.Index(part, "=")
+		if eq < 0 {
+			level, err := ParseLevel(part)
+			if err != nil {
+				return levels, err
+			}
+			levels.Default = level
+			continue
+		}
+		level, err := ParseLevel(strings.TrimSpace(part[eq+1:]))
+		if err != nil {
+			return levels, err
+		}
+		if levels.Components == nil {
+			levels.Components = make(map[string]Level)
+		}
+		levels.Components[strings.TrimSpace(part[:eq])] = level
+	}
+	return levels, nil
+}
+
+// Level returns the level of a component
+func (l Levels) Level(component string) Level {
+	if level, ok := l.Components[component]; ok {
+		return level
+	}
+	return l.Default
+}
+
+// Output is a sink with the levels of the messages it receives
+type Output struct {
+	Sink   Sink
+	Levels Levels
+}
+
+// Logger writes the messages of a component to every output whose level
+// for the component allows them. The loggers derived with Component share
+// their outputs. A nil *Logger discards all messages.
+type Logger struct {
+	component string
+	shared    *shared
+}
+
+type shared struct {
+	mu      sync.RWMutex
+	outputs []Output
+}
+
+// New creates a new instance of Logger writing text to out
+func New(out *log.Logger, levels Levels) *Logger {
+	return NewWithOutputs(Output{Sink: NewTextSink(out), Levels: levels})
+}
+
+// NewWithOutputs creates a new instance of Logger writing to every output
+func NewWithOutputs(outputs ...Output) *Logger {
+	return &Logger{shared: &shared{outputs: outputs}}
+}
+
+// Component returns a logger for the named component, such as "metrics",
+// whose messages are prefixed with its name
+func (l *Logger) Component(name string) *Logger {
+	if l == nil {
+		return nil
+	}
+	return &Logger{component: name, shared: l.shared}
+}
+
+// SetOutputs replaces the outputs of the logger and of every logger derived
+// from the same root
+func (l *Logger) SetOutputs(outputs ...Output) {
+	if l == nil {
+		return
+	}
+	l.shared.mu.Lock()
+	defer l.shared.mu.Unlock()
+	l.shared.outputs = outputs
+}
+
+// Enabled reports whether messages at level are written to any output
+func (l *Logger) Enabled(level Level) bool {
+	if l == nil {
+		return false
+	}
+	l.shared.mu.RLock()
+	defer l.shared.mu.RUnlock()
+	for _, output := range l.shared.outputs {
+		if level <= output.Levels.Level(l.component) {
+			return true
+		}
+	}
+	return false
+}
+
+// Errorf logs a message at the error level
+func (l *Logger) Errorf(format string, args ...interface{}) {
+	l.logf(LevelError, format, args...)
+}
+
+// Warnf logs a message at the warn level
+func (l *Logger) Warnf(format string, args ...interface{}) {
+	l.logf(LevelWarn, format, args...)
+}
+
+// Infof logs a message at the info level
+func (l *Logger) Infof(format string, args ...interface{}) {
+	l.logf(LevelInfo, format, args...)
+}
+
+// Debugf logs a message at the debug level
+func (l *Logger) Debugf(format string, args ...interface{}) {
+	l.logf(LevelDebug, format, args...)
+}
+
+// Tracef logs a message at the trace level
+func (l *Logger) Tracef(format string, args ...interface{}) {
+	l.logf(LevelTrace, format, args...)
+}
+
+func (l *Logger) logf(level Level, format string, args ...interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+	record := Record{
+		Time:      time.Now(),
+		Level:     level,
+		Component: l.component,
+		Message:   fmt.Sprintf(format, args...),
+	}
+	l.shared.mu.RLock()
+	defer l.shared.mu.RUnlock()
+	for _, output := range l.shared.outputs {
+		if level <= output.Levels.Level(l.component) {
+			output.Sink.Log(record)
+		}
+	}
+}
@@ -0,0 +1,92 @@
+package logging
+
+import (
+	"bytes"
+	"log"
+	"strings"
+	"testing"
+)
+
+func TestParseLevels(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    map[string]Level
+		wantErr bool
+	}{
+		{spec: "", want: map[string]Level{"watchdog": LevelInfo}},
+		{spec: "debug", want: map[string]Level{"watchdog": LevelDebug, "metrics": LevelDebug}},
+		{spec: "warn, metrics=trace,kubectl=DEBUG", want: map[string]Level{"watchdog": LevelWarn, "metrics": LevelTrace, "kubectl": LevelDebug}},
+		{spec: "metrics=debug", want: map[string]Level{"watchdog": LevelInfo, "metrics": LevelDebug}},
+		{spec: "loud", wantErr: true},
+		{spec: "info,metrics=loud", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.spec, func(t *testing.T) {
+			levels, err := ParseLevels(tt.spec)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLevels() error = %v, want error %v", err, tt.wantErr)
+			}
+			for component, want := range tt.want {
+				if got := levels.Level(component); got != want {
+					t.Errorf("Level(%q) = %v, want %v", component, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	root := New(log.New(&buf, "", 0), Levels{Default: LevelInfo, Components: map[string]Level{"metrics": LevelTrace}})
+	decisions := root.Component("watchdog")
+	metrics := root.Component("metrics")
+
+	decisions.Infof("restarting %s", "api")
+	decisions.Debugf("usage %dMi", 1000)
+	metrics.Tracef("raw output")
+	root.Errorf("failed")
+
+	want := "INFO watchdog: restarting api\nTRACE metrics: raw output\nERROR: failed\n"
+	if buf.String() != want {
+		t.Errorf("output = %q, want %q", buf.String(), want)
+	}
+
+	buf.Reset()
+	root.SetOutputs(Output{Sink: NewTextSink(log.New(&buf, "", 0)), Levels: Levels{Default: LevelError}})
+	metrics.Infof("dropped")
+	decisions.Errorf("kept")
+	if got := strings.TrimSpace(buf.String()); got != "ERROR watchdog: kept" {
+		t.Errorf("output after SetOutputs = %q", got)
+	}
+
+	var nilLogger *Logger
+	nilLogger.Component("x").Errorf("discarded")
+}
+
+func TestOutputs(t *testing.T) {
+	var text, structured bytes.Buffer
+	root := NewWithOutputs(
+		Output{Sink: NewTextSink(log.New(&text, "", 0)), Levels: Levels{Default: LevelDebug}},
+		Output{Sink: NewJSONSink(&structured), Levels: Levels{Default: LevelWarn, Components: map[string]Level{"metrics": LevelInfo}}},
+	)
+
+	root.Component("watchdog").Debugf("usage %dMi", 1000)
+	root.Component("watchdog").Warnf("outlier")
+	root.Component("metrics").Infof("fallback")
+
+	if want := "DEBUG watchdog: usage 1000Mi\nWARN watchdog: outlier\nINFO metrics: fallback\n"; text.String() != want {
+		t.Errorf("text output = %q, want %q", text.String(), want)
+	}
+	lines := strings.Split(strings.TrimSpace(structured.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("JSON output = %q, want 2 lines", structured.String())
+	}
+	for i, want := range []string{`"level":"warn","component":"watchdog","msg":"outlier"`, `"level":"info","component":"metrics","msg":"fallback"`} {
+		if !strings.Contains(lines[i], want) {
+			t.Errorf("JSON line %d = %s, want %s", i, lines[i], want)
+		}
+	}
+	if root.Component("kubectl").Enabled(LevelTrace) {
+		t.Error("Enabled(trace) = true, want false when no output allows it")
+	}
+}
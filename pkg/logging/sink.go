@@ -0,0 +1,83 @@
+package logging
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Record is a message logged by a component
+type Record struct {
+	Time      time.Time
+	Level     Level
+	Component string
+	Message   string
+}
+
+// Sink writes the records of a logger to a destination
+type Sink interface {
+	Log(record Record)
+}
+
+// SinkFunc adapts a function to the Sink interface, such as one handing
+// the records to the logger of an application embedding the watchdog
+type SinkFunc func(record Record)
+
+// Log calls f(record)
+func (f SinkFunc) Log(record Record) {
+	f(record)
+}
+
+// TextSink writes records as lines of text, such as
+// "INFO watchdog: Deployment successfully restarted.", to a standard
+// logger, which adds the timestamp and source file selected by its flags
+type TextSink struct {
+	out *log.Logger
+}
+
+// NewTextSink creates a new instance of TextSink writing to out
+func NewTextSink(out *log.Logger) *TextSink {
+	return &TextSink{out: out}
+}
+
+// Log writes the record as a line of text
+func (s *TextSink) Log(record Record) {
+	prefix := strings.ToUpper(record.Level.String())
+	if record.Component != "" {
+		prefix += " " + record.Component
+	}
+	// skip Log, Logger.logf and its level method, so Lshortfile reports
+	// the caller
+	s.out.Output(4, prefix+": "+record.Message)
+}
+
+// JSONSink writes records as newline-delimited JSON objects, for log
+// collectors parsing structured logs
+type JSONSink struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewJSONSink creates a new instance of JSONSink writing to w
+func NewJSONSink(w io.Writer) *JSONSink {
+	return &JSONSink{w: w}
+}
+
+// Log writes the record as a JSON object
+func (s *JSONSink) Log(record Record) {
+	line, err := json.Marshal(struct {
+		Time      time.Time `json:"time"`
+		Level     string    `json:"level"`
+		Component string    `json:"component,omitempty"`
+		Message   string    `json:"msg"`
+	}{record.Time, record.Level.String(), record.Component, record.Message})
+	if err != nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.w.Write(append(line, '\n'))
+}
@@ -0,0 +1,56 @@
+//go:build !windows
+// +build !windows
+
+package logging
+
+import (
+	"log/syslog"
+	"strings"
+)
+
+// SyslogSink writes records to a syslog daemon, with the severity of their
+// level
+type SyslogSink struct {
+	w *syslog.Writer
+}
+
+// NewSyslogSink connects to the syslog daemon at address, such as
+// "udp://syslog:514", or to the local daemon when address is empty.
+// Messages are tagged with tag.
+func NewSyslogSink(address, tag string) (*SyslogSink, error) {
+	var network, raddr string
+	if address != "" {
+		network, raddr = "udp", address
+		if i := strings.Index(address, "://"); i >= 0 {
+			network, raddr = address[:i], address[i+3:]
+		}
+	}
+	w, err := syslog.Dial(network, raddr, syslog.LOG_DAEMON|syslog.LOG_INFO, tag)
+	if err != nil {
+		return nil, err
+	}
+	return &SyslogSink{w: w}, nil
+}
+
+// Log writes the record with the severity of its level
+func (s *SyslogSink) Log(record Record) {
+	message := record.Message
+	if record.Component != "" {
+		message = record.Component + ": " + message
+	}
+	switch record.Level {
+	case LevelError:
+		s.w.Err(message)
+	case LevelWarn:
+		s.w.Warning(message)
+	case LevelInfo:
+		s.w.Info(message)
+	default:
+		s.w.Debug(message)
+	}
+}
+
+// Close closes the connection to the syslog daemon
+func (s *SyslogSink) Close() error {
+	return s.w.Close()
+}
@@ -0,0 +1,19 @@
+package logging
+
+import "errors"
+
+// SyslogSink is not supported on Windows
+type SyslogSink struct{}
+
+// NewSyslogSink fails, syslog is not supported on Windows
+func NewSyslogSink(address, tag string) (*SyslogSink, error) {
+	return nil, errors.New("syslog is not supported on Windows")
+}
+
+// Log discards the record
+func (s *SyslogSink) Log(record Record) {}
+
+// Close does nothing
+func (s *SyslogSink) Close() error {
+	return nil
+}
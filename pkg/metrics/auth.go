@@ -0,0 +1,62 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/awsauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/gcpauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+)
+
+// Authorizer adds credentials to the requests of the HTTP metric sources.
+// body is the request payload, for authorizers signing it.
+type Authorizer interface {
+	Authorize(ctx context.Context, req *http.Request, body []byte) error
+}
+
+// BearerToken authorizes requests with a bearer token, which may refer to
+// a file or Kubernetes Secret as resolved by secret.Resolve
+type BearerToken string
+
+// Authorize sets the Authorization header
+func (t BearerToken) Authorize(ctx context.Context, req *http.Request, body []byte) error {
+	token, err := secret.Resolve(ctx, string(t))
+	if err != nil {
+		return fmt.Errorf("bearer token: %w", err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return nil
+}
+
+// SigV4 authorizes requests to AWS services, such as Amazon Managed
+// Prometheus, with Signature Version 4
+type SigV4 struct {
+	Signer awsauth.Signer
+}
+
+// Authorize signs the request
+func (a SigV4) Authorize(ctx context.Context, req *http.Request, body []byte) error {
+	a.Signer.Sign(req, body, time.Now())
+	return nil
+}
+
+// GoogleOAuth authorizes requests to Google APIs, such as Google Managed
+// Prometheus, with OAuth2 access tokens
+type GoogleOAuth struct {
+	Tokens gcpauth.TokenSource
+}
+
+// Authorize sets the Authorization header to a current access token
+func (a GoogleOAuth) Authorize(ctx context.Context, req *http.Request, body []byte) error {
+	token, err := a.Tokens.Token(ctx)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	return nil
+}
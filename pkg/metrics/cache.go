@@ -0,0 +1,162 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// CachingProvider wraps a watchdog.MetricsProvider and shares pod metrics
+// between targets in the same namespace of the same cluster, as carried by
+// their context. Readings are cached for a short TTL
+// and concurrent requests for the same namespace are batched into a single
+// call. The readings of each pod are cached too when the provider is a
+// PodMetricsProvider.
+type CachingProvider struct {
+	provider watchdog.MetricsProvider
+	ttl      time.Duration
+	now      func() time.Time
+
+	mu      sync.Mutex
+	entries map[string]*cacheEntry
+}
+
+// cacheEntry holds a namespace reading, or a fetch still in flight
+type cacheEntry struct {
+	memory    int
+	pods      []PodMemory
+	err       error
+	fetchedAt time.Time
+	done      chan struct{}
+	// noPods is set when the provider only reports the namespace total
+	noPods bool
+}
+
+// NewCachingProvider creates a new instance of CachingProvider
+func NewCachingProvider(provider watchdog.MetricsProvider, ttl time.Duration) *CachingProvider {
+	return &CachingProvider{
+		provider: provider,
+		ttl:      ttl,
+		now:      time.Now,
+		entries:  make(map[string]*cacheEntry),
+	}
+}
+
+// GetPodMemoryUsage returns the cached memory usage of a namespace, fetching
+// it when missing or expired
+func (c *CachingProvider) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	entry, err := c.get(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return entry.memory, entry.err
+}
+
+// GetPodMemory returns the cached memory usage of each pod in a namespace,
+// fetching it when missing or expired. It fails with
+// watchdog.ErrNoPodMetrics when the provider isn't a PodMetricsProvider.
+func (c *CachingProvider) GetPodMemory(ctx context.Context, namespace string) ([]PodMemory, error) {
+	entry, err := c.get(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if entry.err == nil && entry.noPods {
+		return nil, fmt.Errorf("%w: the source only reports the memory of a namespace", watchdog.ErrNoPodMetrics)
+	}
+	return entry.pods, entry.err
+}
+
+// get returns the completed cache entry of a namespace, fetching it when
+// missing or expired
+func (c *CachingProvider) get(ctx context.Context, namespace string) (*cacheEntry, error) {
+	key := cacheKey(ctx, namespace)
+	c.mu.Lock()
+	entry, ok := c.entries[key]
+	if ok {
+		select {
+		case <-entry.done:
+			if entry.err != nil || c.now().Sub(entry.fetchedAt) >= c.ttl {
+				ok = false
+			}
+		default:
+			// another target is fetching this namespace, wait for it
+		}
+	}
+	if !ok {
+		entry = &cacheEntry{done: make(chan struct{})}
+		c.entries[key] = entry
+		c.mu.Unlock()
+
+		source, ok := c.provider.(PodMetricsProvider)
+		if ok {
+			entry.pods, entry.err = source.GetPodMemory(ctx, namespace)
+			entry.memory = TotalMemory(entry.pods)
+		}
+		if !ok || errors.Is(entry.err, watchdog.ErrNoPodMetrics) {
+			// wrappers such as the recorder forward pod readings only when
+			// their own provider reports them
+			entry.pods, entry.noPods = nil, true
+			entry.memory, entry.err = c.provider.GetPodMemoryUsage(ctx, namespace)
+		}
+		entry.fetchedAt = c.now()
+		close(entry.done)
+		return entry, nil
+	}
+	c.mu.Unlock()
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-entry.done:
+		return entry, nil
+	}
+}
+
+// Invalidate drops the cached reading of a namespace of the default
+// cluster, unless a fetch is in flight
+func (c *CachingProvider) Invalidate(namespace string) {
+	c.invalidate(namespace)
+}
+
+// invalidate drops the cached reading of key, unless a fetch is in flight
+func (c *CachingProvider) invalidate(key string) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok {
+		select {
+		case <-entry.done:
+			delete(c.entries, key)
+		default:
+		}
+	}
+	c.mu.Unlock()
+}
+
+// cacheKey returns the key of the readings of a namespace, prefixed with
+// the cluster of ctx unless it is the default one
+func cacheKey(ctx context.Context, namespace string) string {
+	if cluster := watchdog.ClusterFromContext(ctx); cluster != nil {
+		return cluster.String() + "/" + namespace
+	}
+	return namespace
+}
+
+// InvalidatingRestarter wraps restarter so that restarting a deployment
+// invalidates the cached reading of its namespace
+func (c *CachingProvider) InvalidatingRestarter(restarter watchdog.Restarter) watchdog.Restarter {
+	return invalidatingRestarter{cache: c, restarter: restarter}
+}
+
+type invalidatingRestarter struct {
+	cache     *CachingProvider
+	restarter watchdog.Restarter
+}
+
+func (r invalidatingRestarter) RestartDeployment(ctx context.Context, namespace, deployment string) error {
+	err := r.restarter.RestartDeployment(ctx, namespace, deployment)
+	r.cache.invalidate(cacheKey(ctx, namespace))
+	return err
+}
@@ -0,0 +1,125 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestCachingProviderSharesReadings(t *testing.T) {
+	inner := watchdogtest.NewFakeClient(1500)
+	client := NewCachingProvider(inner, time.Minute)
+	now := time.Now()
+	client.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		memory, err := client.GetPodMemoryUsage(context.Background(), "default")
+		if err != nil || memory != 1500 {
+			t.Fatalf("GetPodMemoryUsage() = %v, %v, want 1500, nil", memory, err)
+		}
+	}
+	if got := inner.MetricsCalls("default"); got != 1 {
+		t.Errorf("inner client called %d times, want 1", got)
+	}
+
+	client.GetPodMemoryUsage(context.Background(), "other")
+	if got := inner.MetricsCalls("other"); got != 1 {
+		t.Errorf("inner client called %d times for other namespace, want 1", got)
+	}
+
+	now = now.Add(time.Minute)
+	client.GetPodMemoryUsage(context.Background(), "default")
+	if got := inner.MetricsCalls("default"); got != 2 {
+		t.Errorf("inner client called %d times after expiry, want 2", got)
+	}
+
+	client.InvalidatingRestarter(inner).RestartDeployment(context.Background(), "default", "app")
+	client.GetPodMemoryUsage(context.Background(), "default")
+	if got := inner.MetricsCalls("default"); got != 3 {
+		t.Errorf("inner client called %d times after restart, want 3", got)
+	}
+}
+
+func TestCachingProviderSeparatesClusters(t *testing.T) {
+	inner := watchdogtest.NewFakeClient(1500)
+	client := NewCachingProvider(inner, time.Minute)
+	staging := watchdog.ContextWithCluster(context.Background(), &watchdog.Cluster{Context: "staging"})
+
+	client.GetPodMemoryUsage(context.Background(), "default")
+	client.GetPodMemoryUsage(staging, "default")
+	client.GetPodMemoryUsage(staging, "default")
+	if got := inner.MetricsCalls("default"); got != 2 {
+		t.Errorf("inner client called %d times, want once per cluster", got)
+	}
+
+	client.InvalidatingRestarter(inner).RestartDeployment(staging, "default", "app")
+	client.GetPodMemoryUsage(context.Background(), "default")
+	client.GetPodMemoryUsage(staging, "default")
+	if got := inner.MetricsCalls("default"); got != 3 {
+		t.Errorf("inner client called %d times after a restart in staging, want 3", got)
+	}
+}
+
+func TestCachingProviderBatchesConcurrentCalls(t *testing.T) {
+	inner := watchdogtest.NewFakeClient(1000)
+	inner.SetDelay(20 * time.Millisecond)
+	client := NewCachingProvider(inner, time.Minute)
+
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if memory, err := client.GetPodMemoryUsage(context.Background(), "default"); err != nil || memory != 1000 {
+				t.Errorf("GetPodMemoryUsage() = %v, %v, want 1000, nil", memory, err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if got := inner.MetricsCalls("default"); got != 1 {
+		t.Errorf("inner client called %d times, want 1", got)
+	}
+}
+
+// countingPodSource reports fixed pod readings and counts its calls
+type countingPodSource struct {
+	pods  []PodMemory
+	calls int
+}
+
+func (s *countingPodSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	s.calls++
+	return TotalMemory(s.pods), nil
+}
+
+func (s *countingPodSource) GetPodMemory(ctx context.Context, namespace string) ([]PodMemory, error) {
+	s.calls++
+	return s.pods, nil
+}
+
+func TestCachingProviderSharesPodReadings(t *testing.T) {
+	inner := &countingPodSource{pods: []PodMemory{{Pod: "api-1", Memory: 600}, {Pod: "worker-1", Memory: 400}}}
+	client := NewCachingProvider(inner, time.Minute)
+
+	pods, err := client.GetPodMemory(context.Background(), "default")
+	if err != nil || len(pods) != 2 {
+		t.Fatalf("GetPodMemory() = %v, %v, want both pods", pods, err)
+	}
+	if memory, err := client.GetPodMemoryUsage(context.Background(), "default"); err != nil || memory != 1000 {
+		t.Errorf("GetPodMemoryUsage() = %v, %v, want 1000, nil", memory, err)
+	}
+	if inner.calls != 1 {
+		t.Errorf("inner source called %d times, want 1", inner.calls)
+	}
+
+	totals := NewCachingProvider(watchdogtest.NewFakeClient(1000), time.Minute)
+	if _, err := totals.GetPodMemory(context.Background(), "default"); !errors.Is(err, watchdog.ErrNoPodMetrics) {
+		t.Errorf("GetPodMemory() error = %v, want %v", err, watchdog.ErrNoPodMetrics)
+	}
+}
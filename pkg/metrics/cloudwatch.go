@@ -0,0 +1,89 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/awsauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// CloudWatchSource reads pod memory usage from the Container Insights
+// metrics published to AWS CloudWatch
+type CloudWatchSource struct {
+	config   watchdog.CloudWatchConfig
+	signer   awsauth.Signer
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+}
+
+// NewCloudWatchSource creates a new instance of CloudWatchSource signing
+// requests with creds
+func NewCloudWatchSource(config watchdog.CloudWatchConfig, creds awsauth.Credentials) *CloudWatchSource {
+	if config.Metric == "" {
+		config.Metric = "pod_memory_working_set"
+	}
+	return &CloudWatchSource{
+		config:   config,
+		signer:   awsauth.Signer{Credentials: creds, Region: config.Region, Service: "monitoring"},
+		endpoint: "https://monitoring." + config.Region + ".amazonaws.com/",
+		client:   http.DefaultClient,
+		now:      time.Now,
+	}
+}
+
+// cloudWatchResponse is the subset of the GetMetricStatistics response used
+type cloudWatchResponse struct {
+	Datapoints []struct {
+		Timestamp time.Time `xml:"Timestamp"`
+		Sum       float64   `xml:"Sum"`
+	} `xml:"GetMetricStatisticsResult>Datapoints>member"`
+}
+
+// GetPodMemoryUsage returns the total memory usage of pods in a namespace:
+// the sum of the pod metric over the latest complete minute
+func (c *CloudWatchSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	now := c.now().UTC()
+	form := url.Values{}
+	form.Set("Action", "GetMetricStatistics")
+	form.Set("Version", "2010-08-01")
+	form.Set("Namespace", "ContainerInsights")
+	form.Set("MetricName", c.config.Metric)
+	form.Set("Dimensions.member.1.Name", "ClusterName")
+	form.Set("Dimensions.member.1.Value", c.config.ClusterName)
+	form.Set("Dimensions.member.2.Name", "Namespace")
+	form.Set("Dimensions.member.2.Value", namespace)
+	form.Set("StartTime", now.Add(-5*time.Minute).Format(time.RFC3339))
+	form.Set("EndTime", now.Format(time.RFC3339))
+	form.Set("Period", "60")
+	form.Set("Statistics.member.1", "Sum")
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequest(http.MethodPost, c.endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	c.signer.Sign(req, body, now)
+
+	var resp cloudWatchResponse
+	if err := getXML(ctx, c.client, "cloudwatch", req, &resp); err != nil {
+		return 0, err
+	}
+	if len(resp.Datapoints) == 0 {
+		return 0, fmt.Errorf("%w: cloudwatch: no data for namespace '%s'", watchdog.ErrMetricsUnavailable, namespace)
+	}
+
+	latest := resp.Datapoints[0]
+	for _, p := range resp.Datapoints[1:] {
+		if p.Timestamp.After(latest.Timestamp) {
+			latest = p
+		}
+	}
+	return bytesToMi(latest.Sum), nil
+}
@@ -0,0 +1,55 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/awsauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestCloudWatchSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		r.ParseForm()
+		if r.Form.Get("Action") != "GetMetricStatistics" || r.Form.Get("Dimensions.member.1.Value") != "prod-cluster" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		if r.Form.Get("Dimensions.member.2.Value") != "prod" {
+			w.Write([]byte(`<GetMetricStatisticsResponse><GetMetricStatisticsResult><Datapoints/></GetMetricStatisticsResult></GetMetricStatisticsResponse>`))
+			return
+		}
+		w.Write([]byte(`<GetMetricStatisticsResponse xmlns="http://monitoring.amazonaws.com/doc/2010-08-01/">
+  <GetMetricStatisticsResult>
+    <Datapoints>
+      <member><Timestamp>2026-01-01T00:01:00Z</Timestamp><Sum>2097152000</Sum><Unit>Bytes</Unit></member>
+      <member><Timestamp>2026-01-01T00:00:00Z</Timestamp><Sum>1048576000</Sum><Unit>Bytes</Unit></member>
+    </Datapoints>
+  </GetMetricStatisticsResult>
+</GetMetricStatisticsResponse>`))
+	}))
+	defer server.Close()
+
+	source := NewCloudWatchSource(watchdog.CloudWatchConfig{Region: "us-east-1", ClusterName: "prod-cluster"},
+		awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	source.endpoint = server.URL
+	source.now = func() time.Time { return time.Date(2026, 1, 1, 0, 2, 0, 0, time.UTC) }
+
+	memory, err := source.GetPodMemoryUsage(context.Background(), "prod")
+	if err != nil || memory != 2000 {
+		t.Errorf("GetPodMemoryUsage() = %v, %v, want 2000, nil", memory, err)
+	}
+
+	if _, err := source.GetPodMemoryUsage(context.Background(), "empty"); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("GetPodMemoryUsage() without data error = %v, want %v", err, watchdog.ErrMetricsUnavailable)
+	}
+}
@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// CustomMetricsSource reads a per-pod metric from the custom metrics API
+// (custom.metrics.k8s.io), as served by prometheus-adapter, through kubectl
+type CustomMetricsSource struct {
+	runner *kubectl.Runner
+	config watchdog.CustomMetricsConfig
+}
+
+// NewCustomMetricsSource creates a new instance of CustomMetricsSource
+func NewCustomMetricsSource(runner *kubectl.Runner, config watchdog.CustomMetricsConfig) *CustomMetricsSource {
+	return &CustomMetricsSource{
+		runner: runner,
+		config: config,
+	}
+}
+
+// metricValueList is the subset of a custom metrics API MetricValueList, or
+// an external metrics API ExternalMetricValueList, used
+type metricValueList struct {
+	Items []struct {
+		DescribedObject struct {
+			Name string `json:"name"`
+		} `json:"describedObject"`
+		MetricName string `json:"metricName"`
+		Value      string `json:"value"`
+	} `json:"items"`
+}
+
+// GetPodMemoryUsage returns the metric summed over the pods of a namespace
+func (c *CustomMetricsSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	pods, err := c.GetPodMemory(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return TotalMemory(pods), nil
+}
+
+// GetPodMemory returns the metric of each pod in a namespace
+func (c *CustomMetricsSource) GetPodMemory(ctx context.Context, namespace string) ([]PodMemory, error) {
+	path := fmt.Sprintf("/apis/custom.metrics.k8s.io/v1beta1/namespaces/%s/pods/*/%s",
+		url.PathEscape(namespace), url.PathEscape(c.config.Metric))
+	if c.config.Selector != "" {
+		path += "?labelSelector=" + url.QueryEscape(c.config.Selector)
+	}
+
+	output, err := c.runner.Run(ctx, watchdog.ErrMetricsUnavailable, "get", "--raw", path)
+	if err != nil {
+		return nil, err
+	}
+	return parseMetricValueList(output, c.config.Unit)
+}
+
+// parseMetricValueList converts the items of a metric value list to
+// readings, named after the pod they describe or, for external metrics,
+// the metric. Values are converted from bytes to Mi unless unit is "raw".
+func parseMetricValueList(data []byte, unit string) ([]PodMemory, error) {
+	var list metricValueList
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("%w: decoding metric values: %v", watchdog.ErrMetricsUnavailable, err)
+	}
+
+	pods := make([]PodMemory, 0, len(list.Items))
+	for _, item := range list.Items {
+		name := item.DescribedObject.Name
+		if name == "" {
+			name = item.MetricName
+		}
+		value, err := parseQuantity(item.Value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: '%s': %v", watchdog.ErrMetricsUnavailable, name, err)
+		}
+		memory := int(value)
+		if unit != "raw" {
+			memory = bytesToMi(value)
+		}
+		pods = append(pods, PodMemory{Pod: name, Memory: memory})
+	}
+	return pods, nil
+}
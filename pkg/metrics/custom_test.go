@@ -0,0 +1,71 @@
+package metrics
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+		wantErr  bool
+	}{
+		{input: "1024", expected: 1024},
+		{input: "512Mi", expected: 512 << 20},
+		{input: "2Gi", expected: 2 << 30},
+		{input: "1500m", expected: 1.5},
+		{input: "3k", expected: 3000},
+		{input: "1e3", expected: 1000},
+		{input: "12Zi", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			result, err := parseQuantity(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseQuantity() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if result != tt.expected {
+				t.Errorf("parseQuantity() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseMetricValueList(t *testing.T) {
+	data := []byte(`{
+		"kind": "MetricValueList",
+		"apiVersion": "custom.metrics.k8s.io/v1beta1",
+		"items": [
+			{"describedObject": {"kind": "Pod", "namespace": "prod", "name": "api-1"}, "metricName": "heap_inuse_bytes", "value": "300Mi"},
+			{"describedObject": {"kind": "Pod", "namespace": "prod", "name": "api-2"}, "metricName": "heap_inuse_bytes", "value": "209715200"}
+		]
+	}`)
+
+	pods, err := parseMetricValueList(data, "")
+	want := []PodMemory{{Pod: "api-1", Memory: 300}, {Pod: "api-2", Memory: 200}}
+	if err != nil || !reflect.DeepEqual(pods, want) {
+		t.Errorf("parseMetricValueList() = %v, %v, want %v", pods, err, want)
+	}
+
+	pods, _ = parseMetricValueList([]byte(`{"items":[{"describedObject":{"name":"api-1"},"value":"42"}]}`), "raw")
+	if len(pods) != 1 || pods[0].Memory != 42 {
+		t.Errorf("parseMetricValueList(raw) = %v, want 42", pods)
+	}
+
+	external := []byte(`{"kind":"ExternalMetricValueList","items":[
+		{"metricName":"queue_depth","metricLabels":{"queue":"jobs"},"value":"1500"}
+	]}`)
+	pods, _ = parseMetricValueList(external, "raw")
+	if len(pods) != 1 || pods[0].Pod != "queue_depth" || pods[0].Memory != 1500 {
+		t.Errorf("parseMetricValueList(external) = %v, want queue_depth 1500", pods)
+	}
+
+	if _, err := parseMetricValueList([]byte(`not json`), ""); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("parseMetricValueList() error = %v, want %v", err, watchdog.ErrMetricsUnavailable)
+	}
+}
@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// DefaultDatadogQuery sums the working set of the pods in a namespace, as
+// reported by the Datadog agent
+const DefaultDatadogQuery = "sum:kubernetes.memory.working_set{kube_namespace:{namespace}}"
+
+// DatadogSource reads pod memory usage from the Datadog metrics API
+type DatadogSource struct {
+	config  watchdog.DatadogConfig
+	baseURL string
+	client  *http.Client
+	now     func() time.Time
+}
+
+// NewDatadogSource creates a new instance of DatadogSource
+func NewDatadogSource(config watchdog.DatadogConfig) *DatadogSource {
+	if config.Site == "" {
+		config.Site = "datadoghq.com"
+	}
+	if config.Query == "" {
+		config.Query = DefaultDatadogQuery
+	}
+	if config.Window <= 0 {
+		config.Window = 5 * time.Minute
+	}
+	return &DatadogSource{
+		config:  config,
+		baseURL: "https://api." + config.Site,
+		client:  http.DefaultClient,
+		now:     time.Now,
+	}
+}
+
+// datadogResponse is the subset of the /api/v1/query response used
+type datadogResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Series []struct {
+		Pointlist [][2]*float64 `json:"pointlist"`
+	} `json:"series"`
+}
+
+// GetPodMemoryUsage returns the total memory usage of pods in a namespace,
+// summing the latest point of every series returned by the query
+func (d *DatadogSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	now := d.now()
+	params := url.Values{}
+	params.Set("from", strconv.FormatInt(now.Add(-d.config.Window).Unix(), 10))
+	params.Set("to", strconv.FormatInt(now.Unix(), 10))
+	params.Set("query", strings.ReplaceAll(d.config.Query, "{namespace}", namespace))
+
+	req, err := http.NewRequest(http.MethodGet, d.baseURL+"/api/v1/query?"+params.Encode(), nil)
+	if err != nil {
+		return 0, err
+	}
+	apiKey, err := secret.Resolve(ctx, d.config.APIKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: datadog API key: %v", watchdog.ErrMetricsUnavailable, err)
+	}
+	appKey, err := secret.Resolve(ctx, d.config.AppKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: datadog application key: %v", watchdog.ErrMetricsUnavailable, err)
+	}
+	req.Header.Set("DD-API-KEY", apiKey)
+	req.Header.Set("DD-APPLICATION-KEY", appKey)
+
+	var resp datadogResponse
+	if err := getJSON(ctx, d.client, "datadog", req, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Status == "error" {
+		return 0, fmt.Errorf("%w: datadog: %s", watchdog.ErrMetricsUnavailable, resp.Error)
+	}
+
+	total, found := 0.0, false
+	for _, series := range resp.Series {
+		for i := len(series.Pointlist) - 1; i >= 0; i-- {
+			if value := series.Pointlist[i][1]; value != nil {
+				total += *value
+				found = true
+				break
+			}
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("%w: datadog: no data for namespace '%s'", watchdog.ErrMetricsUnavailable, namespace)
+	}
+	return bytesToMi(total), nil
+}
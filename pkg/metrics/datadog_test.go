@@ -0,0 +1,64 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestDatadogSource(t *testing.T) {
+	var query string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("DD-API-KEY") != "api" || r.Header.Get("DD-APPLICATION-KEY") != "app" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		query = r.URL.Query().Get("query")
+		switch r.URL.Query().Get("query") {
+		case "sum:kubernetes.memory.working_set{kube_namespace:prod}":
+			w.Write([]byte(`{"status":"ok","series":[
+				{"pointlist":[[1000,524288000],[2000,1048576000]]},
+				{"pointlist":[[1000,104857600],[2000,null]]}
+			]}`))
+		default:
+			w.Write([]byte(`{"status":"ok","series":[]}`))
+		}
+	}))
+	defer server.Close()
+
+	source := NewDatadogSource(watchdog.DatadogConfig{APIKey: "api", AppKey: "app"})
+	source.baseURL = server.URL
+	source.now = func() time.Time { return time.Unix(3000, 0) }
+
+	memory, err := source.GetPodMemoryUsage(context.Background(), "prod")
+	if err != nil || memory != 1100 {
+		t.Errorf("GetPodMemoryUsage() = %v, %v, want 1100, nil", memory, err)
+	}
+	if query != "sum:kubernetes.memory.working_set{kube_namespace:prod}" {
+		t.Errorf("query = %q", query)
+	}
+
+	if _, err := source.GetPodMemoryUsage(context.Background(), "empty"); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("GetPodMemoryUsage() without data error = %v, want %v", err, watchdog.ErrMetricsUnavailable)
+	}
+
+	keyFile := filepath.Join(t.TempDir(), "api-key")
+	os.WriteFile(keyFile, []byte("api\n"), 0600)
+	source.config.APIKey = "file:" + keyFile
+	if memory, err := source.GetPodMemoryUsage(context.Background(), "prod"); err != nil || memory != 1100 {
+		t.Errorf("GetPodMemoryUsage() with a key file = %v, %v, want 1100, nil", memory, err)
+	}
+
+	source.config.APIKey = "wrong"
+	_, err = source.GetPodMemoryUsage(context.Background(), "prod")
+	if !errors.Is(err, watchdog.ErrForbidden) || !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("GetPodMemoryUsage() with a wrong key error = %v, want %v", err, watchdog.ErrForbidden)
+	}
+}
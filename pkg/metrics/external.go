@@ -0,0 +1,49 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// ExternalMetricsSource reads a metric from the external metrics API
+// (external.metrics.k8s.io) through kubectl, so restarts can be triggered by
+// signals from outside the cluster, like the HPAs of the same workloads
+type ExternalMetricsSource struct {
+	runner *kubectl.Runner
+	config watchdog.ExternalMetricsConfig
+}
+
+// NewExternalMetricsSource creates a new instance of ExternalMetricsSource
+func NewExternalMetricsSource(runner *kubectl.Runner, config watchdog.ExternalMetricsConfig) *ExternalMetricsSource {
+	return &ExternalMetricsSource{
+		runner: runner,
+		config: config,
+	}
+}
+
+// GetPodMemoryUsage returns the sum of the metric's series in a namespace
+func (e *ExternalMetricsSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	path := fmt.Sprintf("/apis/external.metrics.k8s.io/v1beta1/namespaces/%s/%s",
+		url.PathEscape(namespace), url.PathEscape(e.config.Metric))
+	if e.config.Selector != "" {
+		path += "?labelSelector=" + url.QueryEscape(e.config.Selector)
+	}
+
+	output, err := e.runner.Run(ctx, watchdog.ErrMetricsUnavailable, "get", "--raw", path)
+	if err != nil {
+		return 0, err
+	}
+	values, err := parseMetricValueList(output, e.config.Unit)
+	if err != nil {
+		return 0, err
+	}
+	if len(values) == 0 {
+		return 0, fmt.Errorf("%w: external metric '%s' has no values in namespace '%s'",
+			watchdog.ErrMetricsUnavailable, e.config.Metric, namespace)
+	}
+	return TotalMemory(values), nil
+}
@@ -0,0 +1,82 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// HTTPError is returned when a metrics API answers with an error status. It
+// matches watchdog.ErrMetricsUnavailable and, for 401 and 403 responses,
+// watchdog.ErrForbidden.
+type HTTPError struct {
+	Source     string
+	StatusCode int
+	Body       string
+}
+
+func (e *HTTPError) Error() string {
+	if e.Body == "" {
+		return fmt.Sprintf("%s: %s", e.Source, http.StatusText(e.StatusCode))
+	}
+	return fmt.Sprintf("%s: %s: %s", e.Source, http.StatusText(e.StatusCode), e.Body)
+}
+
+// Is reports whether the error matches target
+func (e *HTTPError) Is(target error) bool {
+	switch target {
+	case watchdog.ErrMetricsUnavailable:
+		return true
+	case watchdog.ErrForbidden:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	}
+	return false
+}
+
+// getJSON sends req and decodes the JSON response body into out
+func getJSON(ctx context.Context, client *http.Client, source string, req *http.Request, out interface{}) error {
+	return get(ctx, client, source, req, func(r io.Reader) error {
+		return json.NewDecoder(r).Decode(out)
+	})
+}
+
+// getXML sends req and decodes the XML response body into out
+func getXML(ctx context.Context, client *http.Client, source string, req *http.Request, out interface{}) error {
+	return get(ctx, client, source, req, func(r io.Reader) error {
+		return xml.NewDecoder(r).Decode(out)
+	})
+}
+
+// get sends req and decodes the response body with decode. Transport
+// failures and error statuses are reported as watchdog.ErrMetricsUnavailable,
+// except for cancellation and timeouts of ctx.
+func get(ctx context.Context, client *http.Client, source string, req *http.Request, decode func(io.Reader) error) error {
+	resp, err := client.Do(req.WithContext(ctx))
+	if err != nil {
+		if ctx.Err() != nil {
+			return fmt.Errorf("%s: %w", source, ctx.Err())
+		}
+		return fmt.Errorf("%w: %s: %v", watchdog.ErrMetricsUnavailable, source, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return &HTTPError{Source: source, StatusCode: resp.StatusCode, Body: strings.TrimSpace(string(body))}
+	}
+	if err := decode(resp.Body); err != nil {
+		return fmt.Errorf("%w: %s: decoding response: %v", watchdog.ErrMetricsUnavailable, source, err)
+	}
+	return nil
+}
+
+// bytesToMi converts a memory reading in bytes to Mi
+func bytesToMi(bytes float64) int {
+	return int(bytes / (1024 * 1024))
+}
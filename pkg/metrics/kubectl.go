@@ -0,0 +1,129 @@
+// Package metrics collects the memory usage of pods for the watchdog.
+package metrics
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// KubectlSource reads pod memory usage with kubectl top
+type KubectlSource struct {
+	runner *kubectl.Runner
+	config watchdog.KubectlConfig
+}
+
+// NewKubectlSource creates a new instance of KubectlSource
+func NewKubectlSource(runner *kubectl.Runner, config watchdog.KubectlConfig) *KubectlSource {
+	return &KubectlSource{
+		runner: runner,
+		config: config,
+	}
+}
+
+// GetPodMemoryUsage returns the total memory usage of pods in a namespace
+func (k *KubectlSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	pods, err := k.GetPodMemory(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return TotalMemory(pods), nil
+}
+
+// GetPodMemory returns the memory usage of each pod in a namespace. When
+// init containers are excluded, the usage of each container is read
+// instead and the init containers of each pod are left out.
+func (k *KubectlSource) GetPodMemory(ctx context.Context, namespace string) ([]PodMemory, error) {
+	if k.config.InitContainers != watchdog.InitContainersExclude {
+		output, err := k.runner.Run(ctx, watchdog.ErrMetricsUnavailable, "top", "pods", "-n", namespace)
+		if err != nil {
+			return nil, err
+		}
+		return ExtractPodMemory(string(output)), nil
+	}
+
+	output, err := k.runner.Run(ctx, watchdog.ErrMetricsUnavailable, "top", "pods", "--containers", "-n", namespace)
+	if err != nil {
+		return nil, err
+	}
+	initOutput, err := k.runner.Run(ctx, watchdog.ErrMetricsUnavailable, "get", "pods", "-o",
+		`jsonpath={range .items[*]}{.metadata.name} {.spec.initContainers[*].name}{"\n"}{end}`, "-n", namespace)
+	if err != nil {
+		return nil, err
+	}
+	return ExtractContainerMemory(string(output), ExtractInitContainers(string(initOutput))), nil
+}
+
+// ExtractTotalMemory sums the memory column of kubectl top pods output
+func ExtractTotalMemory(output string) int {
+	return TotalMemory(ExtractPodMemory(output))
+}
+
+// ExtractContainerMemory sums the memory column of kubectl top pods
+// --containers output by pod, leaving out the containers listed for the pod
+// in skip. Pods whose containers are all skipped are left out.
+func ExtractContainerMemory(output string, skip map[string]map[string]bool) []PodMemory {
+	lines := strings.Split(output, "\n")
+	var pods []PodMemory
+	index := make(map[string]int)
+
+	for i := 1; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		if len(fields) < 4 || skip[fields[0]][fields[1]] {
+			continue
+		}
+		memory, err := strconv.Atoi(strings.ReplaceAll(fields[3], "Mi", ""))
+		if err != nil {
+			continue
+		}
+		if j, ok := index[fields[0]]; ok {
+			pods[j].Memory += memory
+			continue
+		}
+		index[fields[0]] = len(pods)
+		pods = append(pods, PodMemory{Pod: fields[0], Memory: memory})
+	}
+
+	return pods
+}
+
+// ExtractInitContainers parses lines of a pod name followed by the names of
+// its init containers into a set of init containers by pod
+func ExtractInitContainers(output string) map[string]map[string]bool {
+	initContainers := make(map[string]map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 {
+			continue
+		}
+		names := make(map[string]bool)
+		for _, name := range fields[1:] {
+			names[name] = true
+		}
+		initContainers[fields[0]] = names
+	}
+	return initContainers
+}
+
+// ExtractPodMemory parses the pod name and memory columns of kubectl top pods
+// output. Lines without a valid memory value are skipped.
+func ExtractPodMemory(output string) []PodMemory {
+	lines := strings.Split(output, "\n")
+	var pods []PodMemory
+
+	for i := 1; i < len(lines); i++ {
+		fields := strings.Fields(lines[i])
+		if len(fields) > 2 {
+			memoryStr := strings.ReplaceAll(fields[2], "Mi", "")
+			memory, err := strconv.Atoi(memoryStr)
+			if err == nil {
+				pods = append(pods, PodMemory{Pod: fields[0], Memory: memory})
+			}
+		}
+	}
+
+	return pods
+}
@@ -0,0 +1,74 @@
+package metrics
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractTotalMemory(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected int
+	}{
+		{
+			name: "valid input",
+			input: `NAME                     CPU(cores)   MEMORY(bytes)
+pod-1                    100m         1000Mi
+pod-2                    200m         2000Mi`,
+			expected: 3000,
+		},
+		{
+			name:     "empty input",
+			input:    `NAME                     CPU(cores)   MEMORY(bytes)`,
+			expected: 0,
+		},
+		{
+			name: "invalid memory format",
+			input: `NAME                     CPU(cores)   MEMORY(bytes)
+pod-1                    100m         invalid`,
+			expected: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ExtractTotalMemory(tt.input)
+			if result != tt.expected {
+				t.Errorf("ExtractTotalMemory() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestExtractPodMemory(t *testing.T) {
+	output := `NAME                     CPU(cores)   MEMORY(bytes)
+pod-1                    100m         1000Mi
+pod-2                    200m         invalid
+pod-3                    300m         250Mi`
+
+	want := []PodMemory{{Pod: "pod-1", Memory: 1000}, {Pod: "pod-3", Memory: 250}}
+	if got := ExtractPodMemory(output); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractPodMemory() = %v, want %v", got, want)
+	}
+}
+
+func TestExtractContainerMemory(t *testing.T) {
+	output := `POD                    NAME      CPU(cores)   MEMORY(bytes)
+api-1                  api       100m         800Mi
+api-1                  migrate   50m          1500Mi
+api-1                  proxy     10m          50Mi
+api-2                  api       100m         700Mi
+api-3                  migrate   50m          1200Mi`
+	initContainers := ExtractInitContainers("api-1 migrate\napi-2 \napi-3 migrate setup\n")
+
+	want := []PodMemory{{Pod: "api-1", Memory: 850}, {Pod: "api-2", Memory: 700}}
+	if got := ExtractContainerMemory(output, initContainers); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractContainerMemory() = %v, want %v", got, want)
+	}
+
+	want = []PodMemory{{Pod: "api-1", Memory: 2350}, {Pod: "api-2", Memory: 700}, {Pod: "api-3", Memory: 1200}}
+	if got := ExtractContainerMemory(output, nil); !reflect.DeepEqual(got, want) {
+		t.Errorf("ExtractContainerMemory() without init containers = %v, want %v", got, want)
+	}
+}
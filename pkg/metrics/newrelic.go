@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// DefaultNewRelicQuery sums the latest working set of every container in a
+// namespace, as reported by the New Relic Kubernetes integration
+const DefaultNewRelicQuery = "SELECT sum(memory) FROM (SELECT latest(memoryWorkingSetBytes) AS memory FROM K8sContainerSample " +
+	"WHERE namespaceName = '{namespace}' FACET podName, containerName LIMIT MAX) SINCE 5 minutes ago"
+
+// NewRelicSource reads pod memory usage by running an NRQL query through the
+// New Relic NerdGraph API
+type NewRelicSource struct {
+	config   watchdog.NewRelicConfig
+	endpoint string
+	client   *http.Client
+}
+
+// NewNewRelicSource creates a new instance of NewRelicSource
+func NewNewRelicSource(config watchdog.NewRelicConfig) *NewRelicSource {
+	if config.Query == "" {
+		config.Query = DefaultNewRelicQuery
+	}
+	endpoint := "https://api.newrelic.com/graphql"
+	if strings.EqualFold(config.Region, "eu") {
+		endpoint = "https://api.eu.newrelic.com/graphql"
+	}
+	return &NewRelicSource{
+		config:   config,
+		endpoint: endpoint,
+		client:   http.DefaultClient,
+	}
+}
+
+// newRelicResponse is the subset of the NerdGraph NRQL response used
+type newRelicResponse struct {
+	Data struct {
+		Actor struct {
+			Account struct {
+				NRQL struct {
+					Results []map[string]interface{} `json:"results"`
+				} `json:"nrql"`
+			} `json:"account"`
+		} `json:"actor"`
+	} `json:"data"`
+	Errors []struct {
+		Message string `json:"message"`
+	} `json:"errors"`
+}
+
+// GetPodMemoryUsage returns the total memory usage of pods in a namespace,
+// summing the numeric value of every result row of the query
+func (n *NewRelicSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	nrql := strings.ReplaceAll(n.config.Query, "{namespace}", namespace)
+	body, err := json.Marshal(map[string]interface{}{
+		"query": "query($account: Int!, $nrql: Nrql!) { actor { account(id: $account) { nrql(query: $nrql) { results } } } }",
+		"variables": map[string]interface{}{
+			"account": n.config.AccountID,
+			"nrql":    nrql,
+		},
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	apiKey, err := secret.Resolve(ctx, n.config.APIKey)
+	if err != nil {
+		return 0, fmt.Errorf("%w: new relic API key: %v", watchdog.ErrMetricsUnavailable, err)
+	}
+	req.Header.Set("API-Key", apiKey)
+
+	var resp newRelicResponse
+	if err := getJSON(ctx, n.client, "newrelic", req, &resp); err != nil {
+		return 0, err
+	}
+	if len(resp.Errors) > 0 {
+		return 0, fmt.Errorf("%w: newrelic: %s", watchdog.ErrMetricsUnavailable, resp.Errors[0].Message)
+	}
+
+	total, found := 0.0, false
+	for _, row := range resp.Data.Actor.Account.NRQL.Results {
+		if value, ok := rowValue(row); ok {
+			total += value
+			found = true
+		}
+	}
+	if !found {
+		return 0, fmt.Errorf("%w: newrelic: no data for namespace '%s'", watchdog.ErrMetricsUnavailable, namespace)
+	}
+	return bytesToMi(total), nil
+}
+
+// rowValue returns the numeric value of an NRQL result row. The key of the
+// value depends on the query, so the first numeric field by name is used.
+func rowValue(row map[string]interface{}) (float64, bool) {
+	keys := make([]string, 0, len(row))
+	for key := range row {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		if value, ok := row[key].(float64); ok {
+			return value, true
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestNewRelicSource(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("API-Key") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var req struct {
+			Variables struct {
+				Account int    `json:"account"`
+				NRQL    string `json:"nrql"`
+			} `json:"variables"`
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		switch {
+		case req.Variables.Account != 42:
+			w.Write([]byte(`{"errors":[{"message":"account not found"}]}`))
+		case strings.Contains(req.Variables.NRQL, "namespaceName = 'prod'"):
+			w.Write([]byte(`{"data":{"actor":{"account":{"nrql":{"results":[{"sum.memory":2097152000}]}}}}}`))
+		default:
+			w.Write([]byte(`{"data":{"actor":{"account":{"nrql":{"results":[{"sum.memory":null}]}}}}}`))
+		}
+	}))
+	defer server.Close()
+
+	source := NewNewRelicSource(watchdog.NewRelicConfig{APIKey: "key", AccountID: 42})
+	source.endpoint = server.URL
+
+	memory, err := source.GetPodMemoryUsage(context.Background(), "prod")
+	if err != nil || memory != 2000 {
+		t.Errorf("GetPodMemoryUsage() = %v, %v, want 2000, nil", memory, err)
+	}
+	if _, err := source.GetPodMemoryUsage(context.Background(), "empty"); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("GetPodMemoryUsage() without data error = %v, want %v", err, watchdog.ErrMetricsUnavailable)
+	}
+
+	source.config.AccountID = 1
+	if _, err := source.GetPodMemoryUsage(context.Background(), "prod"); err == nil || !strings.Contains(err.Error(), "account not found") {
+		t.Errorf("GetPodMemoryUsage() error = %v, want the GraphQL error", err)
+	}
+
+	source.config.APIKey = "wrong"
+	if _, err := source.GetPodMemoryUsage(context.Background(), "prod"); !errors.Is(err, watchdog.ErrForbidden) {
+		t.Errorf("GetPodMemoryUsage() with a wrong key error = %v, want %v", err, watchdog.ErrForbidden)
+	}
+}
@@ -0,0 +1,19 @@
+package metrics
+
+import "github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+
+// PodMemory is the memory usage of a single pod, in Mi
+type PodMemory = watchdog.PodMemory
+
+// PodMetricsProvider is implemented by sources able to report the memory
+// usage of each pod in a namespace, rather than only the total
+type PodMetricsProvider = watchdog.PodMetricsProvider
+
+// TotalMemory sums the memory usage of pods
+func TotalMemory(pods []PodMemory) int {
+	total := 0
+	for _, p := range pods {
+		total += p.Memory
+	}
+	return total
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// DefaultPrometheusQuery sums the working set of the containers in a
+// namespace, as reported by cAdvisor
+const DefaultPrometheusQuery = `sum(container_memory_working_set_bytes{namespace="{namespace}",container!=""})`
+
+// DefaultPrometheusSwapQuery sums the swap usage of the containers in a
+// namespace, as reported by cAdvisor on nodes with swap enabled
+const DefaultPrometheusSwapQuery = `sum(container_memory_swap{namespace="{namespace}",container!=""})`
+
+// DefaultPrometheusCPUQuery is the percentage of the CFS periods of the
+// containers in a namespace that were throttled over the last five minutes,
+// as reported by cAdvisor
+const DefaultPrometheusCPUQuery = `100 * sum(rate(container_cpu_cfs_throttled_periods_total{namespace="{namespace}",container!=""}[5m])) / sum(rate(container_cpu_cfs_periods_total{namespace="{namespace}",container!=""}[5m]))`
+
+// PrometheusSource reads pod memory usage with an instant query against the
+// Prometheus HTTP API, or a compatible managed service
+type PrometheusSource struct {
+	config watchdog.PrometheusConfig
+	auth   Authorizer
+	client *http.Client
+}
+
+// NewPrometheusSource creates a new instance of PrometheusSource authorizing
+// requests with auth
+func NewPrometheusSource(config watchdog.PrometheusConfig, auth Authorizer) *PrometheusSource {
+	if config.Query == "" {
+		config.Query = DefaultPrometheusQuery
+	}
+	if config.SwapQuery == "" {
+		config.SwapQuery = DefaultPrometheusSwapQuery
+	}
+	if config.CPUQuery == "" {
+		config.CPUQuery = DefaultPrometheusCPUQuery
+	}
+	if auth == nil {
+		auth = BearerToken("")
+	}
+	return &PrometheusSource{
+		config: config,
+		auth:   auth,
+		client: http.DefaultClient,
+	}
+}
+
+// prometheusResponse is the subset of the /api/v1/query response used
+type prometheusResponse struct {
+	Status string `json:"status"`
+	Error  string `json:"error"`
+	Data   struct {
+		Result []struct {
+			Value [2]interface{} `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// GetPodMemoryUsage returns the total memory usage of pods in a namespace,
+// summing the samples of the query result
+func (p *PrometheusSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	total, err := p.query(ctx, p.config.Query, namespace)
+	return bytesToMi(total), err
+}
+
+// GetSwapUsage returns the total swap usage of pods in a namespace, summing
+// the samples of the swap query result
+func (p *PrometheusSource) GetSwapUsage(ctx context.Context, namespace string) (int, error) {
+	total, err := p.query(ctx, p.config.SwapQuery, namespace)
+	return bytesToMi(total), err
+}
+
+// GetCPUThrottling returns the percentage of CPU periods throttled for the
+// pods of a namespace, summing the samples of the throttling query result
+func (p *PrometheusSource) GetCPUThrottling(ctx context.Context, namespace string) (int, error) {
+	total, err := p.query(ctx, p.config.CPUQuery, namespace)
+	return int(math.Round(total)), err
+}
+
+// query runs query with {namespace} replaced and returns the sum of its
+// samples
+func (p *PrometheusSource) query(ctx context.Context, query, namespace string) (float64, error) {
+	form := url.Values{}
+	form.Set("query", strings.ReplaceAll(query, "{namespace}", namespace))
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(p.config.URL, "/")+"/api/v1/query", strings.NewReader(string(body)))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	if err := p.auth.Authorize(ctx, req, body); err != nil {
+		return 0, fmt.Errorf("%w: prometheus: authorizing request: %v", watchdog.ErrMetricsUnavailable, err)
+	}
+
+	var resp prometheusResponse
+	if err := getJSON(ctx, p.client, "prometheus", req, &resp); err != nil {
+		return 0, err
+	}
+	if resp.Status != "success" {
+		return 0, fmt.Errorf("%w: prometheus: %s", watchdog.ErrMetricsUnavailable, resp.Error)
+	}
+	if len(resp.Data.Result) == 0 {
+		return 0, fmt.Errorf("%w: prometheus: no data for namespace '%s'", watchdog.ErrMetricsUnavailable, namespace)
+	}
+
+	total := 0.0
+	for _, sample := range resp.Data.Result {
+		text, _ := sample.Value[1].(string)
+		value, err := strconv.ParseFloat(text, 64)
+		if err != nil {
+			return 0, fmt.Errorf("%w: prometheus: invalid sample value %q", watchdog.ErrMetricsUnavailable, text)
+		}
+		total += value
+	}
+	return total, nil
+}
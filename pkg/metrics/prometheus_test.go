@@ -0,0 +1,90 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/awsauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// staticTokens is a gcpauth.TokenSource returning a fixed token
+type staticTokens string
+
+func (s staticTokens) Token(ctx context.Context) (string, error) {
+	return string(s), nil
+}
+
+func TestPrometheusSource(t *testing.T) {
+	var authorization string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		authorization = r.Header.Get("Authorization")
+		r.ParseForm()
+		if r.URL.Path != "/workspace/api/v1/query" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		switch r.Form.Get("query") {
+		case `sum(container_memory_working_set_bytes{namespace="prod",container!=""})`:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{},"value":[1700000000.5,"2097152000"]}
+			]}}`))
+		case `sum(container_memory_swap{namespace="prod",container!=""})`:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{},"value":[1700000000.5,"314572800"]}
+			]}}`))
+		case `100 * sum(rate(container_cpu_cfs_throttled_periods_total{namespace="prod",container!=""}[5m])) / sum(rate(container_cpu_cfs_periods_total{namespace="prod",container!=""}[5m]))`:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[
+				{"metric":{},"value":[1700000000.5,"42.6"]}
+			]}}`))
+		case `bad_query`:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+		default:
+			w.Write([]byte(`{"status":"success","data":{"resultType":"vector","result":[]}}`))
+		}
+	}))
+	defer server.Close()
+
+	tests := []struct {
+		name   string
+		auth   Authorizer
+		prefix string
+	}{
+		{name: "bearer", auth: BearerToken("secret"), prefix: "Bearer secret"},
+		{name: "sigv4", auth: SigV4{Signer: awsauth.Signer{Credentials: awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "s"}, Region: "us-east-1", Service: "aps"}}, prefix: "AWS4-HMAC-SHA256 Credential=AKID/"},
+		{name: "google", auth: GoogleOAuth{Tokens: staticTokens("oauth")}, prefix: "Bearer oauth"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			source := NewPrometheusSource(watchdog.PrometheusConfig{URL: server.URL + "/workspace/"}, tt.auth)
+			memory, err := source.GetPodMemoryUsage(context.Background(), "prod")
+			if err != nil || memory != 2000 {
+				t.Errorf("GetPodMemoryUsage() = %v, %v, want 2000, nil", memory, err)
+			}
+			if !strings.HasPrefix(authorization, tt.prefix) {
+				t.Errorf("Authorization = %q, want prefix %q", authorization, tt.prefix)
+			}
+		})
+	}
+
+	source := NewPrometheusSource(watchdog.PrometheusConfig{URL: server.URL + "/workspace"}, nil)
+	if swap, err := source.GetSwapUsage(context.Background(), "prod"); err != nil || swap != 300 {
+		t.Errorf("GetSwapUsage() = %v, %v, want 300, nil", swap, err)
+	}
+	if throttling, err := source.GetCPUThrottling(context.Background(), "prod"); err != nil || throttling != 43 {
+		t.Errorf("GetCPUThrottling() = %v, %v, want 43, nil", throttling, err)
+	}
+	if _, err := source.GetPodMemoryUsage(context.Background(), "empty"); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("GetPodMemoryUsage() without data error = %v, want %v", err, watchdog.ErrMetricsUnavailable)
+	}
+	source.config.Query = "bad_query"
+	if _, err := source.GetPodMemoryUsage(context.Background(), "prod"); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+		t.Errorf("GetPodMemoryUsage() with a bad query error = %v, want %v", err, watchdog.ErrMetricsUnavailable)
+	}
+}
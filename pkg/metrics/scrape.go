@@ -0,0 +1,363 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/kubectl"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Annotations of a deployment overriding how its pods are scraped
+const (
+	AnnotationMetric = "memory-watchdog.io/metric"
+	AnnotationPort   = "memory-watchdog.io/port"
+	AnnotationPath   = "memory-watchdog.io/path"
+)
+
+// ScrapeSource reads memory usage reported by the applications themselves,
+// scraping the Prometheus endpoint of every running pod in a namespace. This
+// allows triggering on runtime heap metrics rather than container memory.
+// Deployments may override the configured metric, port and path of their
+// pods with annotations.
+type ScrapeSource struct {
+	runner *kubectl.Runner
+	config watchdog.ScrapeConfig
+	client *http.Client
+}
+
+// NewScrapeSource creates a new instance of ScrapeSource. The metric may
+// select series with label matchers, e.g. jvm_memory_used_bytes{area="heap"}.
+func NewScrapeSource(runner *kubectl.Runner, config watchdog.ScrapeConfig) (*ScrapeSource, error) {
+	if config.Path == "" {
+		config.Path = "/metrics"
+	}
+	if config.Scheme == "" {
+		config.Scheme = "http"
+	}
+	if config.Metric != "" {
+		if _, err := parseSeriesMatcher(config.Metric); err != nil {
+			return nil, err
+		}
+	}
+	return &ScrapeSource{
+		runner: runner,
+		config: config,
+		client: http.DefaultClient,
+	}, nil
+}
+
+// podList is the subset of kubectl get pods -o json used
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Status struct {
+			Phase string `json:"phase"`
+			PodIP string `json:"podIP"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// deploymentList is the subset of kubectl get deployments -o json used
+type deploymentList struct {
+	Items []deployment `json:"items"`
+}
+
+type deployment struct {
+	Metadata struct {
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		Selector struct {
+			MatchLabels map[string]string `json:"matchLabels"`
+		} `json:"selector"`
+	} `json:"spec"`
+}
+
+// selects reports whether the deployment's label selector matches labels.
+// Only matchLabels is considered.
+func (d deployment) selects(labels map[string]string) bool {
+	if len(d.Spec.Selector.MatchLabels) == 0 {
+		return false
+	}
+	for name, value := range d.Spec.Selector.MatchLabels {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// endpoint is how a pod is scraped
+type endpoint struct {
+	port   int
+	path   string
+	metric string
+}
+
+// endpoint returns how the pods of a namespace matching labels are scraped:
+// the configuration, overridden by the annotations of their deployment
+func (s *ScrapeSource) endpoint(deployments []deployment, labels map[string]string) (endpoint, error) {
+	e := endpoint{port: s.config.Port, path: s.config.Path, metric: s.config.Metric}
+	for _, d := range deployments {
+		if !d.selects(labels) {
+			continue
+		}
+		annotations := d.Metadata.Annotations
+		if metric, ok := annotations[AnnotationMetric]; ok {
+			e.metric = metric
+		}
+		if path, ok := annotations[AnnotationPath]; ok {
+			e.path = path
+		}
+		if port, ok := annotations[AnnotationPort]; ok {
+			p, err := strconv.Atoi(port)
+			if err != nil {
+				return e, fmt.Errorf("invalid %s annotation %q", AnnotationPort, port)
+			}
+			e.port = p
+		}
+		break
+	}
+	return e, nil
+}
+
+// GetPodMemoryUsage returns the metric summed over the pods of a namespace
+func (s *ScrapeSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	pods, err := s.GetPodMemory(ctx, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return TotalMemory(pods), nil
+}
+
+// GetPodMemory scrapes every running pod of a namespace concurrently. The
+// reading fails if any pod cannot be scraped, as a partial sum would
+// understate the usage.
+func (s *ScrapeSource) GetPodMemory(ctx context.Context, namespace string) ([]PodMemory, error) {
+	args := []string{"get", "pods", "-n", namespace, "-o", "json"}
+	if s.config.Selector != "" {
+		args = append(args, "-l", s.config.Selector)
+	}
+	output, err := s.runner.Run(ctx, watchdog.ErrMetricsUnavailable, args...)
+	if err != nil {
+		return nil, err
+	}
+	var list podList
+	if err := json.Unmarshal(output, &list); err != nil {
+		return nil, fmt.Errorf("%w: decoding pods: %v", watchdog.ErrMetricsUnavailable, err)
+	}
+
+	output, err = s.runner.Run(ctx, watchdog.ErrMetricsUnavailable, "get", "deployments", "-n", namespace, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var deployments deploymentList
+	if err := json.Unmarshal(output, &deployments); err != nil {
+		return nil, fmt.Errorf("%w: decoding deployments: %v", watchdog.ErrMetricsUnavailable, err)
+	}
+
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		pods []PodMemory
+		errs []error
+	)
+	for _, item := range list.Items {
+		if item.Status.Phase != "Running" || item.Status.PodIP == "" {
+			continue
+		}
+		e, err := s.endpoint(deployments.Items, item.Metadata.Labels)
+		if err != nil {
+			return nil, fmt.Errorf("%w: pod '%s': %v", watchdog.ErrMetricsUnavailable, item.Metadata.Name, err)
+		}
+		if e.port == 0 || e.metric == "" {
+			// neither configured nor annotated, the pod can't be scraped
+			continue
+		}
+		wg.Add(1)
+		go func(name, ip string, e endpoint) {
+			defer wg.Done()
+			value, err := s.scrape(ctx, ip, e)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("pod '%s': %v", name, err))
+				return
+			}
+			pods = append(pods, PodMemory{Pod: name, Memory: bytesToMi(value)})
+		}(item.Metadata.Name, item.Status.PodIP, e)
+	}
+	wg.Wait()
+
+	if len(errs) > 0 {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("scrape: %w", ctx.Err())
+		}
+		return nil, fmt.Errorf("%w: scrape: %v", watchdog.ErrMetricsUnavailable, errs[0])
+	}
+	return pods, nil
+}
+
+// scrape fetches the endpoint of a pod and sums the matching series
+func (s *ScrapeSource) scrape(ctx context.Context, ip string, e endpoint) (float64, error) {
+	matcher, err := parseSeriesMatcher(e.metric)
+	if err != nil {
+		return 0, err
+	}
+	u := s.config.Scheme + "://" + net.JoinHostPort(ip, strconv.Itoa(e.port)) + e.path
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := s.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("%s: %s", u, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 16<<20))
+	if err != nil {
+		return 0, err
+	}
+	value, ok := matcher.sum(data)
+	if !ok {
+		return 0, fmt.Errorf("%s: no series matching %s", u, e.metric)
+	}
+	return value, nil
+}
+
+// seriesMatcher selects series of the Prometheus text format by name and
+// label equality
+type seriesMatcher struct {
+	name   string
+	labels map[string]string
+}
+
+// parseSeriesMatcher parses a selector such as name{label="value"}
+func parseSeriesMatcher(selector string) (seriesMatcher, error) {
+	name, labels, err := parseSeries(selector)
+	if err != nil || name == "" {
+		return seriesMatcher{}, fmt.Errorf("invalid metric selector %q", selector)
+	}
+	return seriesMatcher{name: name, labels: labels}, nil
+}
+
+// sum adds up the values of the matching series in an exposition
+func (m seriesMatcher) sum(data []byte) (float64, bool) {
+	total, found := 0.0, false
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		end := strings.LastIndex(line, "}")
+		if end < 0 {
+			end = strings.IndexAny(line, " \t")
+		} else {
+			end++
+		}
+		if end <= 0 {
+			continue
+		}
+		name, labels, err := parseSeries(line[:end])
+		if err != nil || name != m.name || !m.matches(labels) {
+			continue
+		}
+		fields := strings.Fields(line[end:])
+		if len(fields) == 0 {
+			continue
+		}
+		value, err := strconv.ParseFloat(fields[0], 64)
+		if err != nil {
+			continue
+		}
+		total += value
+		found = true
+	}
+	return total, found
+}
+
+func (m seriesMatcher) matches(labels map[string]string) bool {
+	for name, value := range m.labels {
+		if labels[name] != value {
+			return false
+		}
+	}
+	return true
+}
+
+// parseSeries splits a series such as name{a="1",b="2"} into its name and
+// labels
+func parseSeries(s string) (string, map[string]string, error) {
+	open := strings.Index(s, "{")
+	if open < 0 {
+		return strings.TrimSpace(s), nil, nil
+	}
+	if !strings.HasSuffix(s, "}") {
+		return "", nil, fmt.Errorf("unterminated labels in %q", s)
+	}
+
+	labels := make(map[string]string)
+	rest := s[open+1 : len(s)-1]
+	for {
+		rest = strings.TrimLeft(rest, " ,")
+		if rest == "" {
+			break
+		}
+		eq := strings.Index(rest, "=")
+		if eq < 0 || eq+1 >= len(rest) || rest[eq+1] != '"' {
+			return "", nil, fmt.Errorf("invalid labels in %q", s)
+		}
+		name := strings.TrimSpace(rest[:eq])
+		value, n, err := unquoteLabel(rest[eq+1:])
+		if err != nil {
+			return "", nil, err
+		}
+		labels[name] = value
+		rest = rest[eq+1+n:]
+	}
+	return strings.TrimSpace(s[:open]), labels, nil
+}
+
+// unquoteLabel reads a quoted label value at the start of s and returns it
+// with the number of bytes consumed
+func unquoteLabel(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			if i+1 < len(s) {
+				i++
+				if s[i] == 'n' {
+					b.WriteByte('\n')
+				} else {
+					b.WriteByte(s[i])
+				}
+			}
+		case '"':
+			return b.String(), i + 1, nil
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated label value in %q", s)
+}
@@ -0,0 +1,103 @@
+package metrics
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestSeriesMatcherSum(t *testing.T) {
+	exposition := []byte(`# HELP jvm_memory_used_bytes Used bytes of a given JVM memory area.
+# TYPE jvm_memory_used_bytes gauge
+jvm_memory_used_bytes{area="heap",id="G1 Eden Space"} 104857600
+jvm_memory_used_bytes{area="heap",id="G1 Old Gen"} 209715200 1700000000000
+jvm_memory_used_bytes{area="nonheap",id="Metaspace"} 52428800
+go_memstats_heap_inuse_bytes 3.145728e+08
+label_with_escapes{path="a\"b,c"} 7
+`)
+
+	tests := []struct {
+		selector string
+		expected float64
+		found    bool
+	}{
+		{selector: `jvm_memory_used_bytes{area="heap"}`, expected: 314572800, found: true},
+		{selector: `jvm_memory_used_bytes`, expected: 367001600, found: true},
+		{selector: `go_memstats_heap_inuse_bytes`, expected: 314572800, found: true},
+		{selector: `label_with_escapes{path="a\"b,c"}`, expected: 7, found: true},
+		{selector: `jvm_memory_used_bytes{area="missing"}`},
+		{selector: `missing`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.selector, func(t *testing.T) {
+			matcher, err := parseSeriesMatcher(tt.selector)
+			if err != nil {
+				t.Fatalf("parseSeriesMatcher() error = %v", err)
+			}
+			result, found := matcher.sum(exposition)
+			if result != tt.expected || found != tt.found {
+				t.Errorf("sum() = %v, %v, want %v, %v", result, found, tt.expected, tt.found)
+			}
+		})
+	}
+
+	if _, err := parseSeriesMatcher(`name{area=heap}`); err == nil {
+		t.Error("parseSeriesMatcher() with an unquoted value expected an error")
+	}
+}
+
+func TestScrapeSourceEndpoint(t *testing.T) {
+	source, err := NewScrapeSource(nil, watchdog.ScrapeConfig{Port: 9090, Metric: "go_memstats_heap_inuse_bytes"})
+	if err != nil {
+		t.Fatalf("NewScrapeSource() error = %v", err)
+	}
+
+	var list deploymentList
+	if err := json.Unmarshal([]byte(`{"items":[
+		{"metadata":{"name":"api","annotations":{
+			"memory-watchdog.io/metric":"jvm_memory_used_bytes{area=\"heap\"}",
+			"memory-watchdog.io/port":"9404"
+		}},"spec":{"selector":{"matchLabels":{"app":"api"}}}},
+		{"metadata":{"name":"bad","annotations":{"memory-watchdog.io/port":"http"}},
+		 "spec":{"selector":{"matchLabels":{"app":"bad"}}}}
+	]}`), &list); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		labels   map[string]string
+		expected endpoint
+		wantErr  bool
+	}{
+		{
+			name:     "annotated deployment",
+			labels:   map[string]string{"app": "api", "pod-template-hash": "abc"},
+			expected: endpoint{port: 9404, path: "/metrics", metric: `jvm_memory_used_bytes{area="heap"}`},
+		},
+		{
+			name:     "other pod",
+			labels:   map[string]string{"app": "worker"},
+			expected: endpoint{port: 9090, path: "/metrics", metric: "go_memstats_heap_inuse_bytes"},
+		},
+		{
+			name:    "invalid port annotation",
+			labels:  map[string]string{"app": "bad"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := source.endpoint(list.Items, tt.labels)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("endpoint() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && result != tt.expected {
+				t.Errorf("endpoint() = %+v, want %+v", result, tt.expected)
+			}
+		})
+	}
+}
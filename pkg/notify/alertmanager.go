@@ -0,0 +1,293 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// AlertNamePrefix prefixes the alertname label of the watchdog's alerts,
+// followed by the event type in CamelCase, e.g. "MemoryWatchdogBreach"
+const AlertNamePrefix = "MemoryWatchdog"
+
+// AlertmanagerResend is how often the alerts still firing are sent again,
+// so Alertmanager doesn't resolve them on its own
+const AlertmanagerResend = time.Minute
+
+// Alertmanager posts the events to the v2 API of Prometheus Alertmanager
+// instances as alerts, for its routing, inhibition and silences to apply.
+// The alerts of a breach, a failed restart, an open circuit, a failed
+// check and an escalation fire until the event closing them:
+//
+//	breach, escalated     resolved
+//	restart_failed        restart, resolved
+//	circuit_open          circuit_closed
+//	check_failed          check
+//
+// and are sent again while they fire. The other events fire alerts that
+// resolve on their own. It receives the check events, so it should be
+// registered with watchdog.WithEventStream; the silences of the watchdog
+// don't apply, use those of Alertmanager instead.
+type Alertmanager struct {
+	urls     []string
+	labels   map[string]string
+	template *Template
+	client   *http.Client
+
+	mu     sync.Mutex
+	firing map[string]*alert
+}
+
+// alert is the JSON format of an alert of the Alertmanager v2 API
+type alert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL,omitempty"`
+
+	// sent is when a firing alert was last sent, zero when sending failed
+	sent time.Time
+	ttl  time.Duration
+}
+
+// closes lists the events closing the alerts that fire until then
+var closes = map[watchdog.EventType][]watchdog.EventType{
+	watchdog.EventResolved:      {watchdog.EventBreach, watchdog.EventEscalated, watchdog.EventRestartFailed},
+	watchdog.EventRestart:       {watchdog.EventRestartFailed},
+	watchdog.EventCircuitClosed: {watchdog.EventCircuitOpen},
+	watchdog.EventCheck:         {watchdog.EventCheckFailed},
+}
+
+// lasting are the events whose alerts fire until an event closes them
+var lasting = map[watchdog.EventType]bool{
+	watchdog.EventBreach:        true,
+	watchdog.EventEscalated:     true,
+	watchdog.EventRestartFailed: true,
+	watchdog.EventCircuitOpen:   true,
+	watchdog.EventCheckFailed:   true,
+}
+
+// severities of the alerts, warning when not listed
+var severities = map[watchdog.EventType]string{
+	watchdog.EventRestart:         "info",
+	watchdog.EventRestartFailed:   "critical",
+	watchdog.EventEscalated:       "critical",
+	watchdog.EventRollback:        "critical",
+	watchdog.EventCircuitOpen:     "critical",
+	watchdog.EventBudgetExhausted: "critical",
+}
+
+// NewAlertmanager creates a new instance of Alertmanager posting to the
+// Alertmanager instances at urls, adding labels to every alert. The
+// summary and description annotations are rendered with template.
+func NewAlertmanager(urls []string, labels map[string]string, template *Template) *Alertmanager {
+	return &Alertmanager{
+		urls:     urls,
+		labels:   labels,
+		template: template,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		firing:   map[string]*alert{},
+	}
+}
+
+// Notify fires or resolves the alerts of the event, and sends again the
+// alerts of its target that still fire
+func (a *Alertmanager) Notify(ctx context.Context, event watchdog.Event) error {
+	now := event.Time
+	if now.IsZero() {
+		now = time.Now()
+	}
+	var alerts []*alert
+
+	a.mu.Lock()
+	for _, closed := range closes[event.Type] {
+		key := alertKey(closed, event.Target)
+		if firing, ok := a.firing[key]; ok {
+			delete(a.firing, key)
+			firing.EndsAt = now
+			alerts = append(alerts, firing)
+		}
+	}
+	a.mu.Unlock()
+
+	switch event.Type {
+	case watchdog.EventCheck, watchdog.EventResolved, watchdog.EventCircuitClosed, watchdog.EventSilenced, watchdog.EventUnsilenced:
+	default:
+		fired, err := a.alert(event, now)
+		if err != nil {
+			return err
+		}
+		alerts = append(alerts, fired)
+	}
+
+	a.mu.Lock()
+	for _, firing := range a.firing {
+		if firing.Labels["target"] == event.Target.Name && !containsAlert(alerts, firing) && now.Sub(firing.sent) >= AlertmanagerResend {
+			firing.EndsAt = now.Add(firing.ttl)
+			alerts = append(alerts, firing)
+		}
+	}
+	body, err := json.Marshal(alerts)
+	a.mu.Unlock()
+	if err != nil || len(alerts) == 0 {
+		return err
+	}
+
+	err = a.post(ctx, body)
+	a.mu.Lock()
+	for _, sent := range alerts {
+		if err == nil {
+			sent.sent = now
+		} else {
+			// sent again with the next event of the target
+			sent.sent = time.Time{}
+		}
+	}
+	a.mu.Unlock()
+	return err
+}
+
+// alert returns the alert of an event, registering it as firing until the
+// event closing it
+func (a *Alertmanager) alert(event watchdog.Event, now time.Time) (*alert, error) {
+	data, err := a.template.Data(event)
+	if err != nil {
+		return nil, err
+	}
+	subject, body, err := a.template.Render(event)
+	if err != nil {
+		return nil, err
+	}
+
+	labels := alertLabels(a.labels, event.Type, event.Target)
+	annotations := map[string]string{
+		"summary":     subject,
+		"description": strings.TrimSpace(body),
+	}
+	if data.RunbookURL != "" {
+		annotations["runbook_url"] = data.RunbookURL
+	}
+
+	// alerts are resolved by Alertmanager unless sent again before they
+	// end, after a few missed checks
+	ttl := AlertmanagerResend
+	if event.Target.CheckInterval > ttl {
+		ttl = event.Target.CheckInterval
+	}
+	ttl *= 4
+	fired := &alert{
+		Labels:       labels,
+		Annotations:  annotations,
+		StartsAt:     now,
+		EndsAt:       now.Add(ttl),
+		GeneratorURL: data.HistoryURL,
+		ttl:          ttl,
+	}
+
+	if !lasting[event.Type] {
+		return fired, nil
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	key := alertKey(event.Type, event.Target)
+	// an alert firing again keeps its start
+	if firing, ok := a.firing[key]; ok {
+		fired.StartsAt = firing.StartsAt
+	}
+	a.firing[key] = fired
+	return fired, nil
+}
+
+// alertLabels returns the labels of the alert of an event type and target,
+// on top of the labels added to every alert
+func alertLabels(extra map[string]string, eventType watchdog.EventType, target watchdog.Target) map[string]string {
+	labels := map[string]string{}
+	for name, value := range extra {
+		labels[name] = value
+	}
+	labels["alertname"] = AlertNamePrefix + camelCase(string(eventType))
+	labels["target"] = target.Name
+	labels["namespace"] = target.Namespace
+	labels["deployment"] = target.DeploymentName
+	labels["severity"] = "warning"
+	if severity, ok := severities[eventType]; ok {
+		labels["severity"] = severity
+	}
+	if target.Cluster != nil && *target.Cluster != (watchdog.Cluster{}) {
+		labels["cluster"] = target.Cluster.String()
+	}
+	return labels
+}
+
+// post sends the alerts to every Alertmanager, as they don't share their
+// alerts with each other, failing when one of them couldn't receive them
+func (a *Alertmanager) post(ctx context.Context, body []byte) error {
+	var failures []string
+	for _, u := range a.urls {
+		if err := a.postTo(ctx, u, body); err != nil {
+			failures = append(failures, err.Error())
+		}
+	}
+	if len(failures) > 0 {
+		return fmt.Errorf("alertmanager: %s", strings.Join(failures, "; "))
+	}
+	return nil
+}
+
+func (a *Alertmanager) postTo(ctx context.Context, rawURL string, body []byte) error {
+	u, err := secret.Resolve(ctx, rawURL)
+	if err != nil {
+		return fmt.Errorf("URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(u, "/")+"/api/v2/alerts", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	return nil
+}
+
+// alertKey identifies the firing alert of an event type and target
+func alertKey(eventType watchdog.EventType, target watchdog.Target) string {
+	return string(eventType) + "/" + target.Name
+}
+
+func containsAlert(alerts []*alert, a *alert) bool {
+	for _, b := range alerts {
+		if a == b {
+			return true
+		}
+	}
+	return false
+}
+
+// camelCase turns an event type such as restart_failed into RestartFailed
+func camelCase(s string) string {
+	parts := strings.Split(s, "_")
+	for i, part := range parts {
+		if part != "" {
+			parts[i] = strings.ToUpper(part[:1]) + part[1:]
+		}
+	}
+	return strings.Join(parts, "")
+}
@@ -0,0 +1,182 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// receivedAlert is an alert as decoded by Alertmanager
+type receivedAlert struct {
+	Labels       map[string]string `json:"labels"`
+	Annotations  map[string]string `json:"annotations"`
+	StartsAt     time.Time         `json:"startsAt"`
+	EndsAt       time.Time         `json:"endsAt"`
+	GeneratorURL string            `json:"generatorURL"`
+}
+
+func newAlertmanagerServer(t *testing.T, received *[][]receivedAlert) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.URL.Path != "/api/v2/alerts" {
+			t.Errorf("request = %s %s, want POST /api/v2/alerts", r.Method, r.URL.Path)
+		}
+		var alerts []receivedAlert
+		if err := json.NewDecoder(r.Body).Decode(&alerts); err != nil {
+			t.Errorf("Error decoding alerts: %v", err)
+		}
+		*received = append(*received, alerts)
+	}))
+}
+
+func newTestAlertmanager(t *testing.T, urls []string) *Alertmanager {
+	tmpl, err := NewTemplate(TemplateConfig{HistoryURL: "https://grafana.example.com/d/memory?var-target={{.Target}}"})
+	if err != nil {
+		t.Fatalf("NewTemplate() error = %v", err)
+	}
+	return NewAlertmanager(urls, map[string]string{"team": "platform", "severity": "page"}, tmpl)
+}
+
+func TestAlertmanagerFiresAndResolves(t *testing.T) {
+	var received [][]receivedAlert
+	server := newAlertmanagerServer(t, &received)
+	defer server.Close()
+	am := newTestAlertmanager(t, []string{server.URL + "/"})
+
+	target := watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api", CheckInterval: 30 * time.Second, RunbookURL: "https://runbooks.example.com/api"}
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	if err := am.Notify(ctx, watchdog.Event{Type: watchdog.EventBreach, Target: target, Memory: 2200, Threshold: 2000, Time: start}); err != nil {
+		t.Fatalf("Notify(breach) error = %v", err)
+	}
+	if len(received) != 1 || len(received[0]) != 1 {
+		t.Fatalf("received = %v, want one alert", received)
+	}
+	breach := received[0][0]
+	labels := map[string]string{
+		"alertname":  "MemoryWatchdogBreach",
+		"target":     "prod/api",
+		"namespace":  "prod",
+		"deployment": "api",
+		"severity":   "warning",
+		"team":       "platform",
+	}
+	for name, want := range labels {
+		if breach.Labels[name] != want {
+			t.Errorf("label %s = %q, want %q", name, breach.Labels[name], want)
+		}
+	}
+	if _, ok := breach.Labels["cluster"]; ok {
+		t.Errorf("labels = %v, want no cluster for the default cluster", breach.Labels)
+	}
+	if breach.Annotations["summary"] != "k8s-memory-watchdog: breach prod/api" || breach.Annotations["runbook_url"] != "https://runbooks.example.com/api" {
+		t.Errorf("annotations = %v", breach.Annotations)
+	}
+	if breach.GeneratorURL != "https://grafana.example.com/d/memory?var-target=prod/api" {
+		t.Errorf("generatorURL = %q", breach.GeneratorURL)
+	}
+	if !breach.StartsAt.Equal(start) || !breach.EndsAt.Equal(start.Add(4*time.Minute)) {
+		t.Errorf("alert from %v to %v, want from %v for 4m", breach.StartsAt, breach.EndsAt, start)
+	}
+
+	// checks only send the firing alert again once a minute
+	for _, after := range []time.Duration{30 * time.Second, time.Minute} {
+		if err := am.Notify(ctx, watchdog.Event{Type: watchdog.EventCheck, Target: target, Time: start.Add(after)}); err != nil {
+			t.Fatalf("Notify(check) error = %v", err)
+		}
+	}
+	if len(received) != 2 {
+		t.Fatalf("received %d requests, want the alert sent again once", len(received))
+	}
+	if again := received[1][0]; !again.StartsAt.Equal(start) || !again.EndsAt.Equal(start.Add(5*time.Minute)) {
+		t.Errorf("alert sent again from %v to %v, want from %v to %v", again.StartsAt, again.EndsAt, start, start.Add(5*time.Minute))
+	}
+
+	end := start.Add(2 * time.Minute)
+	if err := am.Notify(ctx, watchdog.Event{Type: watchdog.EventResolved, Target: target, Time: end}); err != nil {
+		t.Fatalf("Notify(resolved) error = %v", err)
+	}
+	if len(received) != 3 || len(received[2]) != 1 {
+		t.Fatalf("received = %v, want the breach resolved", received)
+	}
+	if resolved := received[2][0]; resolved.Labels["alertname"] != "MemoryWatchdogBreach" || !resolved.EndsAt.Equal(end) {
+		t.Errorf("resolved alert = %v, want the breach ending at %v", resolved, end)
+	}
+	if err := am.Notify(ctx, watchdog.Event{Type: watchdog.EventCheck, Target: target, Time: end.Add(time.Hour)}); err != nil {
+		t.Fatalf("Notify(check) error = %v", err)
+	}
+	if len(received) != 3 {
+		t.Errorf("received %d requests, want none after the breach was resolved", len(received))
+	}
+}
+
+func TestAlertmanagerClosingEvents(t *testing.T) {
+	var received [][]receivedAlert
+	server := newAlertmanagerServer(t, &received)
+	defer server.Close()
+	am := newTestAlertmanager(t, []string{server.URL})
+
+	target := watchdog.Target{Name: "edge/api", Namespace: "prod", DeploymentName: "api", Cluster: &watchdog.Cluster{Context: "edge"}}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	events := []watchdog.Event{
+		{Type: watchdog.EventRestartFailed, Target: target, Time: now, Err: errors.New("timed out")},
+		{Type: watchdog.EventCheckFailed, Target: target, Time: now, Err: errors.New("metrics unavailable")},
+		{Type: watchdog.EventRestart, Target: target, Time: now},
+		{Type: watchdog.EventCheck, Target: target, Time: now},
+	}
+	for _, event := range events {
+		if err := am.Notify(ctx, event); err != nil {
+			t.Fatalf("Notify(%s) error = %v", event.Type, err)
+		}
+	}
+
+	failed := received[0][0]
+	if failed.Labels["alertname"] != "MemoryWatchdogRestartFailed" || failed.Labels["severity"] != "critical" || failed.Labels["cluster"] != "context edge" {
+		t.Errorf("restart_failed labels = %v", failed.Labels)
+	}
+	// the restart resolves the failed restart and fires an alert of its own
+	restart := received[2]
+	if len(restart) != 2 || restart[0].Labels["alertname"] != "MemoryWatchdogRestartFailed" || !restart[0].EndsAt.Equal(now) {
+		t.Fatalf("restart alerts = %v, want the failed restart resolved first", restart)
+	}
+	if restart[1].Labels["alertname"] != "MemoryWatchdogRestart" || restart[1].Labels["severity"] != "info" || !restart[1].EndsAt.After(now) {
+		t.Errorf("restart alert = %v, want an info alert resolving on its own", restart[1])
+	}
+	if check := received[3]; len(check) != 1 || check[0].Labels["alertname"] != "MemoryWatchdogCheckFailed" || !check[0].EndsAt.Equal(now) {
+		t.Errorf("check alerts = %v, want the failed check resolved", check)
+	}
+}
+
+func TestAlertmanagerNotifyError(t *testing.T) {
+	var received [][]receivedAlert
+	server := newAlertmanagerServer(t, &received)
+	defer server.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+	am := newTestAlertmanager(t, []string{server.URL, down.URL})
+
+	target := watchdog.Target{Name: "api", Namespace: "prod", DeploymentName: "api"}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	ctx := context.Background()
+	if err := am.Notify(ctx, watchdog.Event{Type: watchdog.EventBreach, Target: target, Time: now}); err == nil {
+		t.Error("Notify() error = nil, want an error on 503")
+	}
+	if len(received) != 1 {
+		t.Errorf("received %d requests, want the other instance to get the alert", len(received))
+	}
+	// a failed send is retried on the next event of the target
+	if err := am.Notify(ctx, watchdog.Event{Type: watchdog.EventCheck, Target: target, Time: now.Add(time.Second)}); err == nil {
+		t.Error("Notify() error = nil, want an error on 503")
+	}
+	if len(received) != 2 {
+		t.Errorf("received %d requests, want the breach sent again", len(received))
+	}
+}
@@ -0,0 +1,107 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// CloudEventTypePrefix prefixes the type of the watchdog's CloudEvents,
+// followed by the event type, e.g. "...k8s-memory-watchdog.restart"
+const CloudEventTypePrefix = "com.github.renancavalcantercb.k8s-memory-watchdog."
+
+// DefaultCloudEventSource is the source of the CloudEvents when none is set
+const DefaultCloudEventSource = "k8s-memory-watchdog"
+
+// CloudEvents posts every event to an HTTP endpoint as a CloudEvent in the
+// structured content mode. The data of the CloudEvent is the same object
+// as in the NDJSON stream, and its subject the name of the target.
+type CloudEvents struct {
+	url    string
+	source string
+	client *http.Client
+}
+
+// cloudEvent is the JSON format of a CloudEvent
+type cloudEvent struct {
+	SpecVersion     string         `json:"specversion"`
+	ID              string         `json:"id"`
+	Source          string         `json:"source"`
+	Type            string         `json:"type"`
+	Subject         string         `json:"subject"`
+	Time            time.Time      `json:"time"`
+	DataContentType string         `json:"datacontenttype"`
+	Data            watchdog.Event `json:"data"`
+}
+
+// NewCloudEvents creates a new instance of CloudEvents posting to url
+func NewCloudEvents(url, source string) *CloudEvents {
+	if source == "" {
+		source = DefaultCloudEventSource
+	}
+	return &CloudEvents{
+		url:    url,
+		source: source,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Notify posts the event, failing on a non-2xx response
+func (c *CloudEvents) Notify(ctx context.Context, event watchdog.Event) error {
+	id, err := newEventID()
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(cloudEvent{
+		SpecVersion:     "1.0",
+		ID:              id,
+		Source:          c.source,
+		Type:            CloudEventTypePrefix + string(event.Type),
+		Subject:         event.Target.Name,
+		Time:            event.Time,
+		DataContentType: "application/json",
+		Data:            event,
+	})
+	if err != nil {
+		return err
+	}
+
+	url, err := secret.Resolve(ctx, c.url)
+	if err != nil {
+		return fmt.Errorf("cloudevents URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("cloudevents endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// newEventID returns a random identifier of a CloudEvent
+func newEventID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestCloudEventsNotify(t *testing.T) {
+	var received map[string]interface{}
+	var contentType string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		if err := json.NewDecoder(r.Body).Decode(&received); err != nil {
+			t.Errorf("Error decoding CloudEvent: %v", err)
+		}
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer server.Close()
+
+	target := watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api"}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	event := watchdog.Event{Type: watchdog.EventRestart, Target: target, Memory: 3000, Threshold: 2000, Time: now}
+	if err := NewCloudEvents(server.URL, "").Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	if contentType != "application/cloudevents+json; charset=utf-8" {
+		t.Errorf("Content-Type = %q", contentType)
+	}
+	expected := map[string]string{
+		"specversion": "1.0",
+		"source":      DefaultCloudEventSource,
+		"type":        CloudEventTypePrefix + "restart",
+		"subject":     "prod/api",
+		"time":        "2024-03-01T12:00:00Z",
+	}
+	for key, want := range expected {
+		if received[key] != want {
+			t.Errorf("%s = %v, want %q", key, received[key], want)
+		}
+	}
+	if id, _ := received["id"].(string); len(id) != 32 {
+		t.Errorf("id = %v, want 32 hex characters", received["id"])
+	}
+	data, _ := received["data"].(map[string]interface{})
+	if data["deployment"] != "api" || data["memory"] != float64(3000) {
+		t.Errorf("data = %v", data)
+	}
+}
+
+func TestCloudEventsNotifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	err := NewCloudEvents(server.URL, "watchdog/prod").Notify(context.Background(), watchdog.Event{Type: watchdog.EventBreach})
+	if err == nil {
+		t.Error("Notify() error = nil, want an error on 503")
+	}
+}
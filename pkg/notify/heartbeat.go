@@ -0,0 +1,82 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Heartbeat pings a URL, such as a healthchecks.io check, after successful
+// checks, so external monitoring alerts when the watchdog silently stops
+// working. It receives the check events, so it should be registered with
+// watchdog.WithEventStream. Pings are sent at most once per interval.
+type Heartbeat struct {
+	url      string
+	interval time.Duration
+	client   *http.Client
+	now      func() time.Time
+
+	mu   sync.Mutex
+	last time.Time
+}
+
+// NewHeartbeat creates a new instance of Heartbeat pinging url
+func NewHeartbeat(url string, interval time.Duration) *Heartbeat {
+	return &Heartbeat{
+		url:      url,
+		interval: interval,
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+	}
+}
+
+// Notify pings the URL on a check event, unless it was pinged less than an
+// interval ago
+func (h *Heartbeat) Notify(ctx context.Context, event watchdog.Event) error {
+	if event.Type != watchdog.EventCheck {
+		return nil
+	}
+	now := h.now()
+	h.mu.Lock()
+	if !h.last.IsZero() && now.Sub(h.last) < h.interval {
+		h.mu.Unlock()
+		return nil
+	}
+	h.last = now
+	h.mu.Unlock()
+
+	url, err := secret.Resolve(ctx, h.url)
+	if err != nil {
+		h.retry()
+		return fmt.Errorf("heartbeat URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := h.client.Do(req)
+	if err != nil {
+		h.retry()
+		return fmt.Errorf("heartbeat: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		h.retry()
+		return fmt.Errorf("heartbeat returned %s", resp.Status)
+	}
+	return nil
+}
+
+// retry lets the next check ping again after a failed ping
+func (h *Heartbeat) retry() {
+	h.mu.Lock()
+	h.last = time.Time{}
+	h.mu.Unlock()
+}
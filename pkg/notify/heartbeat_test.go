@@ -0,0 +1,57 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestHeartbeat(t *testing.T) {
+	var pings, status int32 = 0, http.StatusOK
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pings, 1)
+		w.WriteHeader(int(atomic.LoadInt32(&status)))
+	}))
+	defer server.Close()
+
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	heartbeat := NewHeartbeat(server.URL, time.Minute)
+	heartbeat.now = func() time.Time { return now }
+	ctx := context.Background()
+
+	steps := []struct {
+		advance time.Duration
+		event   watchdog.EventType
+		status  int32
+		pings   int32
+		wantErr bool
+	}{
+		{event: watchdog.EventCheck, pings: 1},
+		{advance: 30 * time.Second, event: watchdog.EventCheck, pings: 1},
+		{event: watchdog.EventCheckFailed, pings: 1},
+		{advance: time.Minute, event: watchdog.EventCheckFailed, pings: 1},
+		{event: watchdog.EventCheck, pings: 2},
+		{advance: time.Minute, event: watchdog.EventCheck, status: http.StatusServiceUnavailable, pings: 3, wantErr: true},
+		// a failed ping is retried on the next check
+		{event: watchdog.EventCheck, pings: 4},
+	}
+	for i, step := range steps {
+		now = now.Add(step.advance)
+		if step.status == 0 {
+			step.status = http.StatusOK
+		}
+		atomic.StoreInt32(&status, step.status)
+		err := heartbeat.Notify(ctx, watchdog.Event{Type: step.event})
+		if (err != nil) != step.wantErr {
+			t.Errorf("step %d: Notify() error = %v, want error %v", i, err, step.wantErr)
+		}
+		if got := atomic.LoadInt32(&pings); got != step.pings {
+			t.Errorf("step %d: pings = %d, want %d", i, got, step.pings)
+		}
+	}
+}
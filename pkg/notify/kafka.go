@@ -0,0 +1,369 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// kafkaTimeout bounds a request to a broker when ctx has no deadline
+const kafkaTimeout = 10 * time.Second
+
+// KafkaOptions configures the connection to the Kafka brokers
+type KafkaOptions struct {
+	// TLS connects to the brokers over TLS
+	TLS bool
+	// Username and Password authenticate with SASL/PLAIN when set
+	Username string
+	Password string
+}
+
+// Kafka produces events to Kafka topics, keyed by the name of the target so
+// the events of a target stay ordered in one partition. Check events, the
+// raw memory samples of every reading, go to their own topic when one is
+// set, so it should be registered with watchdog.WithEventStream. Records
+// are produced one at a time and acknowledged by all in-sync replicas.
+type Kafka struct {
+	brokers      []string
+	eventsTopic  string
+	samplesTopic string
+	options      KafkaOptions
+
+	mu            sync.Mutex
+	conns         map[string]*kafkaConn
+	leaders       map[string][]string
+	correlationID int32
+}
+
+// kafkaConn is a connection to a broker
+type kafkaConn struct {
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// NewKafka creates a new instance of Kafka bootstrapping from brokers. An
+// empty topic drops the events, or samples, it would receive.
+func NewKafka(brokers []string, eventsTopic, samplesTopic string, options KafkaOptions) (*Kafka, error) {
+	if len(brokers) == 0 {
+		return nil, errors.New("no Kafka brokers configured")
+	}
+	return &Kafka{
+		brokers:      brokers,
+		eventsTopic:  eventsTopic,
+		samplesTopic: samplesTopic,
+		options:      options,
+		conns:        make(map[string]*kafkaConn),
+		leaders:      make(map[string][]string),
+	}, nil
+}
+
+// Notify produces the event to the samples topic for check events and to
+// the events topic for the others
+func (k *Kafka) Notify(ctx context.Context, event watchdog.Event) error {
+	topic := k.eventsTopic
+	if event.Type == watchdog.EventCheck {
+		topic = k.samplesTopic
+	}
+	if topic == "" {
+		return nil
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, kafkaTimeout)
+		defer cancel()
+	}
+
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if err := k.produce(ctx, topic, []byte(event.Target.Name), data, event.Time); err != nil {
+		// the leaders may have moved, start over on the next event
+		k.reset()
+		return fmt.Errorf("kafka: %w", err)
+	}
+	return nil
+}
+
+func (k *Kafka) produce(ctx context.Context, topic string, key, value []byte, t time.Time) error {
+	leaders, err := k.partitions(ctx, topic)
+	if err != nil {
+		return err
+	}
+	h := fnv.New32a()
+	h.Write(key)
+	partition := int32(h.Sum32() % uint32(len(leaders)))
+	if leaders[partition] == "" {
+		return fmt.Errorf("partition %d of topic '%s' has no leader", partition, topic)
+	}
+	conn, err := k.conn(ctx, leaders[partition])
+	if err != nil {
+		return err
+	}
+
+	var req kafkaEncoder
+	req.nullableString("") // transactional id
+	req.int16(-1)          // acks from all in-sync replicas
+	req.int32(int32(kafkaTimeout / time.Millisecond))
+	req.int32(1)
+	req.string(topic)
+	req.int32(1)
+	req.int32(partition)
+	req.bytes(recordBatch(key, value, t.UnixNano()/int64(time.Millisecond)))
+
+	resp, err := k.roundTrip(ctx, conn, kafkaProduce, kafkaProduceVersion, req.buf)
+	if err != nil {
+		return err
+	}
+	d := &kafkaDecoder{buf: resp}
+	for i := d.arrayLen(); i > 0; i-- {
+		d.string()
+		for j := d.arrayLen(); j > 0; j-- {
+			d.int32()
+			code := d.int16()
+			d.int64() // base offset
+			d.int64() // log append time
+			if code != 0 && d.err == nil {
+				return fmt.Errorf("producing to topic '%s': %w", topic, kafkaError(code))
+			}
+		}
+	}
+	return d.err
+}
+
+// partitions returns the addresses of the leaders of the partitions of the
+// topic, indexed by partition, read from the first broker that answers
+func (k *Kafka) partitions(ctx context.Context, topic string) ([]string, error) {
+	if leaders, ok := k.leaders[topic]; ok {
+		return leaders, nil
+	}
+
+	var req kafkaEncoder
+	req.int32(1)
+	req.string(topic)
+	req.int8(0) // don't create the topic
+
+	var lastErr error
+	for _, broker := range k.brokers {
+		conn, err := k.conn(ctx, broker)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp, err := k.roundTrip(ctx, conn, kafkaMetadata, kafkaMetadataVersion, req.buf)
+		if err != nil {
+			k.closeConn(broker)
+			lastErr = err
+			continue
+		}
+		leaders, err := parseMetadata(resp, topic)
+		if err != nil {
+			return nil, err
+		}
+		k.leaders[topic] = leaders
+		return leaders, nil
+	}
+	return nil, lastErr
+}
+
+// parseMetadata reads the leaders of the partitions of the topic from a
+// metadata response
+func parseMetadata(resp []byte, topic string) ([]string, error) {
+	d := &kafkaDecoder{buf: resp}
+	d.int32() // throttle time
+
+	brokers := make(map[int32]string)
+	for i := d.arrayLen(); i > 0; i-- {
+		id := d.int32()
+		host := d.string()
+		port := d.int32()
+		d.string() // rack
+		brokers[id] = net.JoinHostPort(host, strconv.Itoa(int(port)))
+	}
+	d.string() // cluster id
+	d.int32()  // controller id
+
+	var leaders []string
+	found := false
+	for i := d.arrayLen(); i > 0; i-- {
+		code := d.int16()
+		name := d.string()
+		d.int8() // internal
+		var partitions []string
+		for j := d.arrayLen(); j > 0; j-- {
+			d.int16() // error code, a partition without a leader has none
+			index := d.int32()
+			leader := d.int32()
+			for n := d.arrayLen(); n > 0; n-- {
+				d.int32()
+			}
+			for n := d.arrayLen(); n > 0; n-- {
+				d.int32()
+			}
+			if index < 0 || index > 1<<16 {
+				return nil, fmt.Errorf("invalid partition %d", index)
+			}
+			for int(index) >= len(partitions) {
+				partitions = append(partitions, "")
+			}
+			partitions[index] = brokers[leader]
+		}
+		if name != topic || d.err != nil {
+			continue
+		}
+		if code != 0 {
+			return nil, fmt.Errorf("topic '%s': %w", topic, kafkaError(code))
+		}
+		leaders, found = partitions, true
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if !found || len(leaders) == 0 {
+		return nil, fmt.Errorf("topic '%s': %w", topic, kafkaError(3))
+	}
+	return leaders, nil
+}
+
+// conn returns the connection to the broker at addr, connecting and
+// authenticating first when needed
+func (k *Kafka) conn(ctx context.Context, addr string) (*kafkaConn, error) {
+	if c, ok := k.conns[addr]; ok {
+		return c, nil
+	}
+
+	dialer := &net.Dialer{}
+	var conn net.Conn
+	var err error
+	if k.options.TLS {
+		host, _, _ := net.SplitHostPort(addr)
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: host}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", addr)
+	} else {
+		conn, err = dialer.DialContext(ctx, "tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &kafkaConn{conn: conn, r: bufio.NewReader(conn)}
+	k.conns[addr] = c
+
+	if k.options.Username != "" {
+		if err := k.authenticate(ctx, c); err != nil {
+			k.closeConn(addr)
+			return nil, fmt.Errorf("authenticating with %s: %w", addr, err)
+		}
+	}
+	return c, nil
+}
+
+// authenticate runs the SASL/PLAIN exchange on a new connection
+func (k *Kafka) authenticate(ctx context.Context, c *kafkaConn) error {
+	var req kafkaEncoder
+	req.string("PLAIN")
+	resp, err := k.roundTrip(ctx, c, kafkaSaslHandshake, kafkaSaslHandshakeVersion, req.buf)
+	if err != nil {
+		return err
+	}
+	d := &kafkaDecoder{buf: resp}
+	if code := d.int16(); code != 0 {
+		return kafkaError(code)
+	}
+
+	password, err := secret.Resolve(ctx, k.options.Password)
+	if err != nil {
+		return fmt.Errorf("password: %w", err)
+	}
+	req = kafkaEncoder{}
+	req.bytes([]byte("\x00" + k.options.Username + "\x00" + password))
+	resp, err = k.roundTrip(ctx, c, kafkaSaslAuthenticate, kafkaSaslAuthenticateVersion, req.buf)
+	if err != nil {
+		return err
+	}
+	d = &kafkaDecoder{buf: resp}
+	code := d.int16()
+	message := d.string()
+	if code != 0 {
+		if message != "" {
+			return fmt.Errorf("%w: %s", kafkaError(code), message)
+		}
+		return kafkaError(code)
+	}
+	return d.err
+}
+
+// roundTrip sends a request and returns the body of its response
+func (k *Kafka) roundTrip(ctx context.Context, c *kafkaConn, apiKey, version int16, body []byte) ([]byte, error) {
+	if deadline, ok := ctx.Deadline(); ok {
+		c.conn.SetDeadline(deadline)
+	}
+
+	k.correlationID++
+	var req kafkaEncoder
+	req.int32(0) // size, set below
+	req.int16(apiKey)
+	req.int16(version)
+	req.int32(k.correlationID)
+	req.string("k8s-memory-watchdog")
+	req.buf = append(req.buf, body...)
+	binary.BigEndian.PutUint32(req.buf, uint32(len(req.buf)-4))
+	if _, err := c.conn.Write(req.buf); err != nil {
+		return nil, err
+	}
+
+	var header [8]byte
+	if _, err := io.ReadFull(c.r, header[:]); err != nil {
+		return nil, err
+	}
+	size := int32(binary.BigEndian.Uint32(header[:4]))
+	if size < 4 || size > 64<<20 {
+		return nil, fmt.Errorf("invalid response size %d", size)
+	}
+	if id := int32(binary.BigEndian.Uint32(header[4:])); id != k.correlationID {
+		return nil, fmt.Errorf("response to request %d, want %d", id, k.correlationID)
+	}
+	resp := make([]byte, size-4)
+	if _, err := io.ReadFull(c.r, resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
+func (k *Kafka) closeConn(addr string) {
+	if c, ok := k.conns[addr]; ok {
+		c.conn.Close()
+		delete(k.conns, addr)
+	}
+}
+
+// reset closes the connections and forgets the partition leaders
+func (k *Kafka) reset() {
+	for addr := range k.conns {
+		k.closeConn(addr)
+	}
+	k.leaders = make(map[string][]string)
+}
+
+// Close closes the connections to the brokers
+func (k *Kafka) Close() error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	k.reset()
+	return nil
+}
@@ -0,0 +1,278 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"hash/crc32"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// producedRecord is a record received by fakeBroker
+type producedRecord struct {
+	topic     string
+	partition int32
+	key       string
+	value     string
+}
+
+// fakeBroker is a single Kafka broker leading the two partitions of every
+// topic but "missing", requiring SASL/PLAIN when password is set
+type fakeBroker struct {
+	t        *testing.T
+	addr     string
+	password string
+
+	mu       sync.Mutex
+	records  []producedRecord
+	authed   bool
+	requests []int16
+}
+
+func newFakeBroker(t *testing.T, password string) *fakeBroker {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	b := &fakeBroker{t: t, addr: listener.Addr().String(), password: password}
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go b.serve(conn)
+		}
+	}()
+	return b
+}
+
+func (b *fakeBroker) serve(conn net.Conn) {
+	defer conn.Close()
+	r := bufio.NewReader(conn)
+	authed := b.password == ""
+	for {
+		var size [4]byte
+		if _, err := io.ReadFull(r, size[:]); err != nil {
+			return
+		}
+		req := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(r, req); err != nil {
+			return
+		}
+		d := &kafkaDecoder{buf: req}
+		apiKey := d.int16()
+		d.int16()
+		correlationID := d.int32()
+		d.string()
+
+		b.mu.Lock()
+		b.requests = append(b.requests, apiKey)
+		b.mu.Unlock()
+
+		var resp kafkaEncoder
+		resp.int32(correlationID)
+		switch {
+		case apiKey == kafkaSaslHandshake:
+			if d.string() != "PLAIN" {
+				resp.int16(33)
+			} else {
+				resp.int16(0)
+			}
+			resp.int32(1)
+			resp.string("PLAIN")
+		case apiKey == kafkaSaslAuthenticate:
+			if string(d.bytes()) == "\x00watchdog\x00"+b.password {
+				authed = true
+				resp.int16(0)
+				resp.nullableString("")
+			} else {
+				resp.int16(58)
+				resp.string("Invalid username or password")
+			}
+			resp.bytes(nil)
+		case !authed:
+			return
+		case apiKey == kafkaMetadata:
+			d.int32()
+			topic := d.string()
+			host, port, _ := net.SplitHostPort(b.addr)
+			p, _ := strconv.Atoi(port)
+			resp.int32(0)
+			resp.int32(1)
+			resp.int32(1)
+			resp.string(host)
+			resp.int32(int32(p))
+			resp.nullableString("")
+			resp.nullableString("cluster")
+			resp.int32(1)
+			resp.int32(1)
+			if topic == "missing" {
+				resp.int16(3)
+				resp.string(topic)
+				resp.int8(0)
+				resp.int32(0)
+				break
+			}
+			resp.int16(0)
+			resp.string(topic)
+			resp.int8(0)
+			resp.int32(2)
+			for partition := int32(0); partition < 2; partition++ {
+				resp.int16(0)
+				resp.int32(partition)
+				resp.int32(1)
+				resp.int32(1)
+				resp.int32(1)
+				resp.int32(1)
+				resp.int32(1)
+			}
+		case apiKey == kafkaProduce:
+			d.string()
+			d.int16()
+			d.int32()
+			d.int32()
+			topic := d.string()
+			d.int32()
+			partition := d.int32()
+			key, value := b.decodeBatch(d.bytes())
+			b.mu.Lock()
+			b.records = append(b.records, producedRecord{topic, partition, key, value})
+			b.mu.Unlock()
+			resp.int32(1)
+			resp.string(topic)
+			resp.int32(1)
+			resp.int32(partition)
+			resp.int16(0)
+			resp.int64(0)
+			resp.int64(-1)
+			resp.int32(0)
+		}
+		binary.BigEndian.PutUint32(size[:], uint32(len(resp.buf)))
+		conn.Write(append(size[:], resp.buf...))
+	}
+}
+
+// decodeBatch checks the CRC of a record batch of one record and returns
+// its key and value
+func (b *fakeBroker) decodeBatch(batch []byte) (string, string) {
+	d := &kafkaDecoder{buf: batch}
+	d.int64()
+	if int(d.int32()) != len(d.buf) {
+		b.t.Error("batch length doesn't match")
+	}
+	d.int32()
+	if magic := d.int8(); magic != 2 {
+		b.t.Errorf("magic = %d, want 2", magic)
+	}
+	crc := uint32(d.int32())
+	if crc32.Checksum(d.buf, castagnoli) != crc {
+		b.t.Error("batch CRC doesn't match")
+	}
+	d.next(2 + 4 + 8 + 8 + 8 + 2 + 4)
+	if count := d.int32(); count != 1 {
+		b.t.Errorf("record count = %d, want 1", count)
+	}
+	r := &varintReader{buf: d.buf}
+	r.varint() // length
+	r.buf = r.buf[1:]
+	r.varint()
+	r.varint()
+	key := r.next(int(r.varint()))
+	value := r.next(int(r.varint()))
+	return string(key), string(value)
+}
+
+type varintReader struct{ buf []byte }
+
+func (r *varintReader) varint() int64 {
+	v, n := binary.Varint(r.buf)
+	r.buf = r.buf[n:]
+	return v
+}
+
+func (r *varintReader) next(n int) []byte {
+	b := r.buf[:n]
+	r.buf = r.buf[n:]
+	return b
+}
+
+func TestKafkaNotify(t *testing.T) {
+	broker := newFakeBroker(t, "secret")
+	k, err := NewKafka([]string{broker.addr}, "events", "samples", KafkaOptions{Username: "watchdog", Password: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer k.Close()
+
+	target := watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api"}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for _, eventType := range []watchdog.EventType{watchdog.EventCheck, watchdog.EventRestart} {
+		if err := k.Notify(context.Background(), watchdog.Event{Type: eventType, Target: target, Memory: 3000, Time: now}); err != nil {
+			t.Fatalf("Notify(%s) error = %v", eventType, err)
+		}
+	}
+
+	broker.mu.Lock()
+	defer broker.mu.Unlock()
+	if len(broker.records) != 2 {
+		t.Fatalf("produced %d records, want 2", len(broker.records))
+	}
+	samples, events := broker.records[0], broker.records[1]
+	if samples.topic != "samples" || events.topic != "events" {
+		t.Errorf("topics = %s, %s, want samples, events", samples.topic, events.topic)
+	}
+	if samples.partition != events.partition {
+		t.Errorf("partitions = %d, %d, want the same partition for a target", samples.partition, events.partition)
+	}
+	if events.key != "prod/api" || !strings.Contains(events.value, `"type":"restart"`) {
+		t.Errorf("record = %+v", events)
+	}
+	// one handshake and one metadata request per topic on a single connection
+	expected := []int16{kafkaSaslHandshake, kafkaSaslAuthenticate, kafkaMetadata, kafkaProduce, kafkaMetadata, kafkaProduce}
+	if len(broker.requests) != len(expected) {
+		t.Fatalf("requests = %v, want %v", broker.requests, expected)
+	}
+	for i := range expected {
+		if broker.requests[i] != expected[i] {
+			t.Errorf("requests = %v, want %v", broker.requests, expected)
+			break
+		}
+	}
+}
+
+func TestKafkaNotifyErrors(t *testing.T) {
+	broker := newFakeBroker(t, "secret")
+	target := watchdog.Target{Name: "prod/api"}
+
+	k, _ := NewKafka([]string{broker.addr}, "events", "", KafkaOptions{Username: "watchdog", Password: "wrong"})
+	err := k.Notify(context.Background(), watchdog.Event{Type: watchdog.EventRestart, Target: target})
+	if err == nil || !strings.Contains(err.Error(), "SASL authentication failed") {
+		t.Errorf("Notify() error = %v, want an authentication error", err)
+	}
+
+	k, _ = NewKafka([]string{broker.addr}, "missing", "", KafkaOptions{Username: "watchdog", Password: "secret"})
+	err = k.Notify(context.Background(), watchdog.Event{Type: watchdog.EventRestart, Target: target})
+	if err == nil || !strings.Contains(err.Error(), "unknown topic") {
+		t.Errorf("Notify() error = %v, want an unknown topic error", err)
+	}
+
+	// samples are dropped without a samples topic
+	if err := k.Notify(context.Background(), watchdog.Event{Type: watchdog.EventCheck, Target: target}); err != nil {
+		t.Errorf("Notify(check) error = %v, want nil", err)
+	}
+
+	if _, err := NewKafka(nil, "events", "", KafkaOptions{}); err == nil {
+		t.Error("NewKafka() error = nil, want an error without brokers")
+	}
+}
@@ -0,0 +1,233 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// DefaultNATSSubject is the subject template of the events when none is set
+const DefaultNATSSubject = "k8s-memory-watchdog.{namespace}.{deployment}.{type}"
+
+// natsTimeout bounds connecting and publishing when ctx has no deadline
+const natsTimeout = 10 * time.Second
+
+// NATS publishes every event to a NATS server, on a subject rendered from
+// a template where {namespace}, {deployment}, {target} and {type} are
+// replaced by those of the event. Publishes are confirmed with a PING, so
+// an error is returned when the server rejects them; subjects captured by
+// a JetStream stream are persisted like any other. The connection is
+// opened on the first event and opened again after a failure.
+type NATS struct {
+	url     *url.URL
+	subject string
+	token   string
+
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+}
+
+// natsInfo is the part of the server's INFO message used by the client
+type natsInfo struct {
+	TLSRequired bool `json:"tls_required"`
+}
+
+// natsConnect is the CONNECT message of the client
+type natsConnect struct {
+	Verbose  bool   `json:"verbose"`
+	Pedantic bool   `json:"pedantic"`
+	Name     string `json:"name"`
+	Lang     string `json:"lang"`
+	Protocol int    `json:"protocol"`
+	User     string `json:"user,omitempty"`
+	Pass     string `json:"pass,omitempty"`
+	Token    string `json:"auth_token,omitempty"`
+}
+
+// NewNATS creates a new instance of NATS publishing to the server at
+// rawURL, nats://[user:password@]host:port or tls:// for TLS, with an
+// optional authentication token
+func NewNATS(rawURL, subject, token string) (*NATS, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid NATS URL '%s': %v", rawURL, err)
+	}
+	if u.Scheme != "nats" && u.Scheme != "tls" {
+		return nil, fmt.Errorf("invalid NATS URL '%s': scheme must be nats or tls", rawURL)
+	}
+	if u.Port() == "" {
+		u.Host = net.JoinHostPort(u.Hostname(), "4222")
+	}
+	if subject == "" {
+		subject = DefaultNATSSubject
+	}
+	return &NATS{
+		url:     u,
+		subject: subject,
+		token:   token,
+	}, nil
+}
+
+// Subject returns the subject the event is published on
+func (n *NATS) Subject(event watchdog.Event) string {
+	return strings.NewReplacer(
+		"{namespace}", event.Target.Namespace,
+		"{deployment}", event.Target.DeploymentName,
+		"{target}", natsToken(event.Target.Name),
+		"{type}", string(event.Type),
+	).Replace(n.subject)
+}
+
+// natsToken replaces the characters that separate or are not allowed in
+// the tokens of a subject
+func natsToken(s string) string {
+	return strings.NewReplacer(".", "_", "/", "_", " ", "_", "*", "_", ">", "_").Replace(s)
+}
+
+// Notify publishes the event and waits for the server to process it
+func (n *NATS) Notify(ctx context.Context, event watchdog.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if err := n.publish(ctx, n.Subject(event), data); err != nil {
+		n.close()
+		return fmt.Errorf("nats: %w", err)
+	}
+	return nil
+}
+
+func (n *NATS) publish(ctx context.Context, subject string, data []byte) error {
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		deadline = time.Now().Add(natsTimeout)
+	}
+	if n.conn == nil {
+		if err := n.connect(ctx, deadline); err != nil {
+			return err
+		}
+	}
+	n.conn.SetDeadline(deadline)
+
+	msg := fmt.Sprintf("PUB %s %d\r\n%s\r\nPING\r\n", subject, len(data), data)
+	if _, err := n.conn.Write([]byte(msg)); err != nil {
+		return err
+	}
+	return n.waitPong()
+}
+
+// connect opens the connection and authenticates
+func (n *NATS) connect(ctx context.Context, deadline time.Time) error {
+	dialer := &net.Dialer{Deadline: deadline}
+	conn, err := dialer.DialContext(ctx, "tcp", n.url.Host)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(deadline)
+	n.conn = conn
+	n.r = bufio.NewReader(conn)
+
+	line, err := n.readLine()
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		return fmt.Errorf("unexpected greeting %q", line)
+	}
+	var info natsInfo
+	if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "INFO ")), &info); err != nil {
+		return fmt.Errorf("invalid INFO: %v", err)
+	}
+
+	if info.TLSRequired || n.url.Scheme == "tls" {
+		tlsConn := tls.Client(conn, &tls.Config{ServerName: n.url.Hostname()})
+		if err := tlsConn.Handshake(); err != nil {
+			return err
+		}
+		n.conn = tlsConn
+		n.r = bufio.NewReader(tlsConn)
+	}
+
+	token, err := secret.Resolve(ctx, n.token)
+	if err != nil {
+		return fmt.Errorf("token: %w", err)
+	}
+	connect := natsConnect{
+		Name:     "k8s-memory-watchdog",
+		Lang:     "go",
+		Protocol: 1,
+		Token:    token,
+	}
+	if n.url.User != nil {
+		connect.User = n.url.User.Username()
+		connect.Pass, _ = n.url.User.Password()
+	}
+	data, err := json.Marshal(connect)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(n.conn, "CONNECT %s\r\nPING\r\n", data); err != nil {
+		return err
+	}
+	return n.waitPong()
+}
+
+// waitPong reads until the PONG answering the client's PING, answering the
+// server's own PINGs and failing on an -ERR
+func (n *NATS) waitPong() error {
+	for {
+		line, err := n.readLine()
+		if err != nil {
+			return err
+		}
+		switch {
+		case line == "PONG":
+			return nil
+		case line == "PING":
+			if _, err := n.conn.Write([]byte("PONG\r\n")); err != nil {
+				return err
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			return errors.New(strings.Trim(strings.TrimSpace(strings.TrimPrefix(line, "-ERR")), "'"))
+		}
+	}
+}
+
+func (n *NATS) readLine() (string, error) {
+	line, err := n.r.ReadString('\n')
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+func (n *NATS) close() {
+	if n.conn != nil {
+		n.conn.Close()
+		n.conn = nil
+	}
+}
+
+// Close closes the connection to the server
+func (n *NATS) Close() error {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.close()
+	return nil
+}
@@ -0,0 +1,117 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// fakeNATS accepts a single connection and records the protocol lines it
+// receives, rejecting publishes to subjects starting with "denied"
+func fakeNATS(t *testing.T) (string, <-chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	lines := make(chan string, 100)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		fmt.Fprint(conn, "INFO {\"server_id\":\"test\",\"max_payload\":1048576}\r\n")
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines <- line
+			switch {
+			case line == "PING":
+				fmt.Fprint(conn, "PONG\r\n")
+			case strings.HasPrefix(line, "PUB denied"):
+				r.ReadString('\n')
+				fmt.Fprint(conn, "-ERR 'Permissions Violation for Publish to denied'\r\n")
+			case strings.HasPrefix(line, "PUB "):
+				payload, _ := r.ReadString('\n')
+				lines <- strings.TrimRight(payload, "\r\n")
+			}
+		}
+	}()
+	return listener.Addr().String(), lines
+}
+
+func TestNATSSubject(t *testing.T) {
+	n, err := NewNATS("nats://localhost", "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := watchdog.Event{
+		Type:   watchdog.EventRestart,
+		Target: watchdog.Target{Name: "prod/api.v2", Namespace: "prod", DeploymentName: "api"},
+	}
+	if got := n.Subject(event); got != "k8s-memory-watchdog.prod.api.restart" {
+		t.Errorf("Subject() = %q", got)
+	}
+
+	n, _ = NewNATS("nats://localhost", "events.{target}", "")
+	if got := n.Subject(event); got != "events.prod_api_v2" {
+		t.Errorf("Subject() = %q", got)
+	}
+	if n.url.Host != "localhost:4222" {
+		t.Errorf("host = %q, want the default port", n.url.Host)
+	}
+}
+
+func TestNewNATSInvalidURL(t *testing.T) {
+	if _, err := NewNATS("http://localhost:4222", "", ""); err == nil {
+		t.Error("NewNATS() error = nil, want an error for an http URL")
+	}
+}
+
+func TestNATSNotify(t *testing.T) {
+	addr, lines := fakeNATS(t)
+	n, err := NewNATS("nats://watchdog:secret@"+addr, "", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer n.Close()
+
+	target := watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api"}
+	if err := n.Notify(context.Background(), watchdog.Event{Type: watchdog.EventBreach, Target: target, Memory: 3000}); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+
+	connect := <-lines
+	if !strings.HasPrefix(connect, "CONNECT ") || !strings.Contains(connect, `"user":"watchdog"`) || !strings.Contains(connect, `"pass":"secret"`) {
+		t.Errorf("CONNECT = %q", connect)
+	}
+	if ping := <-lines; ping != "PING" {
+		t.Errorf("got %q, want PING", ping)
+	}
+	pub := <-lines
+	payload := <-lines
+	if pub != fmt.Sprintf("PUB k8s-memory-watchdog.prod.api.breach %d", len(payload)) {
+		t.Errorf("PUB = %q", pub)
+	}
+	if !strings.Contains(payload, `"type":"breach"`) || !strings.Contains(payload, `"memory":3000`) {
+		t.Errorf("payload = %q", payload)
+	}
+
+	n.subject = "denied.{type}"
+	err = n.Notify(context.Background(), watchdog.Event{Type: watchdog.EventRestart, Target: target})
+	if err == nil || !strings.Contains(err.Error(), "Permissions Violation") {
+		t.Errorf("Notify() error = %v, want the server's error", err)
+	}
+}
@@ -0,0 +1,38 @@
+// Package notify implements destinations for the watchdog's events.
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"sync"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// NDJSON writes every event as a line of JSON, so external pipelines can
+// tail the stream without parsing the logs
+type NDJSON struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+// NewNDJSON creates a new instance of NDJSON writing to w
+func NewNDJSON(w io.Writer) *NDJSON {
+	return &NDJSON{
+		w: w,
+	}
+}
+
+// Notify writes the event as a single line
+func (n *NDJSON) Notify(ctx context.Context, event watchdog.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	_, err = n.w.Write(append(data, '\n'))
+	return err
+}
@@ -0,0 +1,28 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestNDJSONNotify(t *testing.T) {
+	var buf bytes.Buffer
+	stream := NewNDJSON(&buf)
+	target := watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api"}
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+
+	stream.Notify(context.Background(), watchdog.Event{Type: watchdog.EventCheck, Target: target, Memory: 3000, Threshold: 2000, Time: now})
+	stream.Notify(context.Background(), watchdog.Event{Type: watchdog.EventRestartFailed, Target: target, Memory: 3000, Threshold: 2000, Time: now, Err: errors.New("forbidden")})
+
+	expected := `{"time":"2024-03-01T12:00:00Z","type":"check","target":"prod/api","namespace":"prod","deployment":"api","memory":3000,"threshold":2000}
+{"time":"2024-03-01T12:00:00Z","type":"restart_failed","target":"prod/api","namespace":"prod","deployment":"api","memory":3000,"threshold":2000,"error":"forbidden"}
+`
+	if buf.String() != expected {
+		t.Errorf("Notify() wrote\n%s\nwant\n%s", buf.String(), expected)
+	}
+}
@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// AlertmanagerSilences holds back the restarts of the targets whose breach
+// alert is silenced in Prometheus Alertmanager, so a silence set because
+// the team knows about the target also stops the watchdog from acting on
+// it. The silences are read from the first instance answering, as the
+// instances of a highly available Alertmanager share them.
+type AlertmanagerSilences struct {
+	urls   []string
+	labels map[string]string
+	client *http.Client
+}
+
+// silence is the JSON format of a silence of the Alertmanager v2 API
+type silence struct {
+	Matchers []matcher `json:"matchers"`
+	EndsAt   time.Time `json:"endsAt"`
+	By       string    `json:"createdBy"`
+	Comment  string    `json:"comment"`
+	Status   struct {
+		State string `json:"state"`
+	} `json:"status"`
+}
+
+// matcher is a label matcher of a silence. IsEqual is missing from the
+// silences of Alertmanager before 0.22, which only had equality matchers.
+type matcher struct {
+	Name    string `json:"name"`
+	Value   string `json:"value"`
+	IsRegex bool   `json:"isRegex"`
+	IsEqual *bool  `json:"isEqual"`
+}
+
+// NewAlertmanagerSilences creates a new instance of AlertmanagerSilences
+// reading the silences of the Alertmanager instances at urls, matched
+// against the labels of the breach alerts, labels included
+func NewAlertmanagerSilences(urls []string, labels map[string]string) *AlertmanagerSilences {
+	return &AlertmanagerSilences{
+		urls:   urls,
+		labels: labels,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// Held reports whether an active silence matches the breach alert of the
+// target, with who set it, until when and why
+func (s *AlertmanagerSilences) Held(ctx context.Context, target watchdog.Target) (string, bool, error) {
+	silences, err := s.silences(ctx)
+	if err != nil {
+		return "", false, err
+	}
+	labels := alertLabels(s.labels, watchdog.EventBreach, target)
+	for _, silence := range silences {
+		if silence.Status.State != "active" || !silence.matches(labels) {
+			continue
+		}
+		reason := fmt.Sprintf("silenced in Alertmanager by %s until %s", silence.By, silence.EndsAt.Format(time.RFC3339))
+		if silence.Comment != "" {
+			reason += ": " + silence.Comment
+		}
+		return reason, true, nil
+	}
+	return "", false, nil
+}
+
+// silences returns the silences of the first instance answering
+func (s *AlertmanagerSilences) silences(ctx context.Context) ([]silence, error) {
+	var failures []string
+	for _, u := range s.urls {
+		silences, err := s.silencesOf(ctx, u)
+		if err == nil {
+			return silences, nil
+		}
+		failures = append(failures, err.Error())
+	}
+	return nil, fmt.Errorf("alertmanager silences: %s", strings.Join(failures, "; "))
+}
+
+func (s *AlertmanagerSilences) silencesOf(ctx context.Context, rawURL string) ([]silence, error) {
+	u, err := secret.Resolve(ctx, rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("URL: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(u, "/")+"/api/v2/silences", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return nil, fmt.Errorf("%s returned %s", req.URL.Host, resp.Status)
+	}
+	var silences []silence
+	if err := json.NewDecoder(resp.Body).Decode(&silences); err != nil {
+		return nil, fmt.Errorf("decoding the silences of %s: %v", req.URL.Host, err)
+	}
+	return silences, nil
+}
+
+// matches reports whether every matcher of the silence matches labels,
+// missing labels being empty as in Alertmanager
+func (s silence) matches(labels map[string]string) bool {
+	for _, m := range s.Matchers {
+		matched := labels[m.Name] == m.Value
+		if m.IsRegex {
+			re, err := regexp.Compile("^(?:" + m.Value + ")$")
+			if err != nil {
+				return false
+			}
+			matched = re.MatchString(labels[m.Name])
+		}
+		if m.IsEqual != nil && !*m.IsEqual {
+			matched = !matched
+		}
+		if !matched {
+			return false
+		}
+	}
+	return len(s.Matchers) > 0
+}
@@ -0,0 +1,72 @@
+package notify
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+const testSilences = `[
+  {"id": "1", "status": {"state": "expired"}, "createdBy": "alice", "comment": "old",
+   "endsAt": "2024-02-01T00:00:00Z", "matchers": [{"name": "target", "value": "prod/api", "isRegex": false}]},
+  {"id": "2", "status": {"state": "active"}, "createdBy": "bob", "comment": "",
+   "endsAt": "2024-03-01T14:00:00Z", "matchers": [{"name": "alertname", "value": "MemoryWatchdogRestart", "isRegex": false}]},
+  {"id": "3", "status": {"state": "active"}, "createdBy": "carol", "comment": "known leak, fix in progress",
+   "endsAt": "2024-03-01T18:00:00Z", "matchers": [
+     {"name": "namespace", "value": "prod|staging", "isRegex": true, "isEqual": true},
+     {"name": "deployment", "value": "worker", "isRegex": false, "isEqual": false},
+     {"name": "team", "value": "platform", "isRegex": false}
+   ]}
+]`
+
+func TestAlertmanagerSilencesHeld(t *testing.T) {
+	var paths []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		paths = append(paths, r.URL.Path)
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(testSilences))
+	}))
+	defer server.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	silences := NewAlertmanagerSilences([]string{down.URL, server.URL}, map[string]string{"team": "platform"})
+	tests := []struct {
+		target watchdog.Target
+		reason string
+		held   bool
+	}{
+		{watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api"}, "silenced in Alertmanager by carol until 2024-03-01T18:00:00Z: known leak, fix in progress", true},
+		{watchdog.Target{Name: "prod/worker", Namespace: "prod", DeploymentName: "worker"}, "", false},
+		{watchdog.Target{Name: "dev/api", Namespace: "dev", DeploymentName: "api"}, "", false},
+	}
+	for _, tt := range tests {
+		reason, held, err := silences.Held(context.Background(), tt.target)
+		if err != nil {
+			t.Fatalf("Held(%s) error = %v", tt.target.Name, err)
+		}
+		if held != tt.held || reason != tt.reason {
+			t.Errorf("Held(%s) = %q, %v, want %q, %v", tt.target.Name, reason, held, tt.reason, tt.held)
+		}
+	}
+	if len(paths) == 0 || paths[0] != "/api/v2/silences" {
+		t.Errorf("paths = %v, want /api/v2/silences", paths)
+	}
+}
+
+func TestAlertmanagerSilencesError(t *testing.T) {
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer down.Close()
+
+	silences := NewAlertmanagerSilences([]string{down.URL}, nil)
+	if _, held, err := silences.Held(context.Background(), watchdog.Target{Name: "api"}); err == nil || held {
+		t.Errorf("Held() = %v, %v, want an error when no instance answers", held, err)
+	}
+}
@@ -0,0 +1,139 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/awsauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// SNS publishes every event to an Amazon SNS topic. The message is the
+// same JSON object as in the NDJSON stream, for Lambda and SQS
+// subscribers, with a readable subject for email. The event type and
+// namespace are set as the message attributes event_type and namespace, so
+// subscriptions can filter on them. With SetTemplate, the subject and, if
+// the template has a body, the message are rendered from it instead.
+type SNS struct {
+	topicARN string
+	signer   awsauth.Signer
+	endpoint string
+	client   *http.Client
+	now      func() time.Time
+	template *Template
+	textBody bool
+}
+
+// NewSNS creates a new instance of SNS publishing to the topic, in the
+// region of its ARN, signing requests with creds
+func NewSNS(topicARN string, creds awsauth.Credentials) (*SNS, error) {
+	// arn:partition:sns:region:account:name
+	parts := strings.Split(topicARN, ":")
+	if len(parts) != 6 || parts[0] != "arn" || parts[2] != "sns" || parts[3] == "" {
+		return nil, fmt.Errorf("invalid SNS topic ARN '%s'", topicARN)
+	}
+	region := parts[3]
+	host := "sns." + region + ".amazonaws.com"
+	if strings.HasPrefix(region, "cn-") {
+		host += ".cn"
+	}
+	return &SNS{
+		topicARN: topicARN,
+		signer:   awsauth.Signer{Credentials: creds, Region: region, Service: "sns"},
+		endpoint: "https://" + host + "/",
+		client:   &http.Client{Timeout: 10 * time.Second},
+		now:      time.Now,
+	}, nil
+}
+
+// SetTemplate renders the subject of the messages from t and, with
+// textBody, the message itself instead of the JSON object of the event
+func (s *SNS) SetTemplate(t *Template, textBody bool) {
+	s.template = t
+	s.textBody = textBody
+}
+
+// snsError is the error document of the SNS API
+type snsError struct {
+	Code    string `xml:"Error>Code"`
+	Message string `xml:"Error>Message"`
+}
+
+// Notify publishes the event
+func (s *SNS) Notify(ctx context.Context, event watchdog.Event) error {
+	subject := fmt.Sprintf("k8s-memory-watchdog: %s %s", event.Type, event.Target.Name)
+	var message []byte
+	if s.template != nil {
+		renderedSubject, body, err := s.template.Render(event)
+		if err != nil {
+			return err
+		}
+		subject = renderedSubject
+		if s.textBody {
+			message = []byte(body)
+		}
+	}
+	if message == nil {
+		var err error
+		if message, err = json.Marshal(event); err != nil {
+			return err
+		}
+	}
+
+	form := url.Values{}
+	form.Set("Action", "Publish")
+	form.Set("Version", "2010-03-31")
+	form.Set("TopicArn", s.topicARN)
+	form.Set("Subject", snsSubject(subject))
+	form.Set("Message", string(message))
+	form.Set("MessageAttributes.entry.1.Name", "event_type")
+	form.Set("MessageAttributes.entry.1.Value.DataType", "String")
+	form.Set("MessageAttributes.entry.1.Value.StringValue", string(event.Type))
+	if event.Target.Namespace != "" {
+		form.Set("MessageAttributes.entry.2.Name", "namespace")
+		form.Set("MessageAttributes.entry.2.Value.DataType", "String")
+		form.Set("MessageAttributes.entry.2.Value.StringValue", event.Target.Namespace)
+	}
+	body := []byte(form.Encode())
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.endpoint, strings.NewReader(string(body)))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+	s.signer.Sign(req, body, s.now())
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		io.Copy(io.Discard, resp.Body)
+		return nil
+	}
+	var apiErr snsError
+	if err := xml.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Code != "" {
+		return fmt.Errorf("sns: %s: %s", apiErr.Code, apiErr.Message)
+	}
+	return fmt.Errorf("sns returned %s", resp.Status)
+}
+
+// snsSubject returns a subject SNS accepts, on a single line of at most
+// 100 characters
+func snsSubject(subject string) string {
+	if i := strings.IndexAny(subject, "\r\n"); i >= 0 {
+		subject = subject[:i]
+	}
+	if len(subject) > 100 {
+		subject = subject[:100]
+	}
+	return subject
+}
@@ -0,0 +1,116 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/awsauth"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestSNSNotify(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "/eu-west-1/sns/aws4_request") {
+			t.Errorf("Authorization = %q, want a SigV4 signature for sns in eu-west-1", r.Header.Get("Authorization"))
+		}
+		r.ParseForm()
+		expected := map[string]string{
+			"Action":   "Publish",
+			"TopicArn": "arn:aws:sns:eu-west-1:123456789012:watchdog",
+			"Subject":  "k8s-memory-watchdog: restart_failed prod/api",
+			"MessageAttributes.entry.1.Value.StringValue": "restart_failed",
+			"MessageAttributes.entry.2.Value.StringValue": "prod",
+		}
+		for key, want := range expected {
+			if got := r.Form.Get(key); got != want {
+				t.Errorf("%s = %q, want %q", key, got, want)
+			}
+		}
+		if !strings.Contains(r.Form.Get("Message"), `"error":"forbidden"`) {
+			t.Errorf("Message = %q", r.Form.Get("Message"))
+		}
+		w.Write([]byte("<PublishResponse><PublishResult><MessageId>1</MessageId></PublishResult></PublishResponse>"))
+	}))
+	defer server.Close()
+
+	sns, err := NewSNS("arn:aws:sns:eu-west-1:123456789012:watchdog", awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sns.endpoint != "https://sns.eu-west-1.amazonaws.com/" {
+		t.Errorf("endpoint = %q", sns.endpoint)
+	}
+	sns.endpoint = server.URL
+	sns.now = func() time.Time { return time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC) }
+
+	event := watchdog.Event{
+		Type:   watchdog.EventRestartFailed,
+		Target: watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api"},
+		Err:    errors.New("forbidden"),
+	}
+	if err := sns.Notify(context.Background(), event); err != nil {
+		t.Errorf("Notify() error = %v", err)
+	}
+}
+
+func TestSNSNotifyTemplate(t *testing.T) {
+	var subject, message string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.ParseForm()
+		subject, message = r.Form.Get("Subject"), r.Form.Get("Message")
+		w.Write([]byte("<PublishResponse/>"))
+	}))
+	defer server.Close()
+
+	sns, _ := NewSNS("arn:aws:sns:us-east-1:123456789012:watchdog", awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	sns.endpoint = server.URL
+	tmpl, err := NewTemplate(TemplateConfig{Subject: "{{.Deployment}}\nrestarted", Body: "{{.Target}} restarted"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event := watchdog.Event{Type: watchdog.EventRestart, Target: watchdog.Target{Name: "prod/api", DeploymentName: "api"}}
+
+	sns.SetTemplate(tmpl, false)
+	if err := sns.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if subject != "api" || !strings.HasPrefix(message, "{") {
+		t.Errorf("subject, message = %q, %q, want the first line of the template and the JSON event", subject, message)
+	}
+
+	sns.SetTemplate(tmpl, true)
+	if err := sns.Notify(context.Background(), event); err != nil {
+		t.Fatalf("Notify() error = %v", err)
+	}
+	if message != "prod/api restarted" {
+		t.Errorf("message = %q, want the rendered body", message)
+	}
+}
+
+func TestSNSNotifyError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+		w.Write([]byte("<ErrorResponse><Error><Type>Sender</Type><Code>AuthorizationError</Code><Message>not authorized to publish</Message></Error></ErrorResponse>"))
+	}))
+	defer server.Close()
+
+	sns, _ := NewSNS("arn:aws:sns:us-east-1:123456789012:watchdog", awsauth.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"})
+	sns.endpoint = server.URL
+	err := sns.Notify(context.Background(), watchdog.Event{Type: watchdog.EventRestart})
+	if err == nil || err.Error() != "sns: AuthorizationError: not authorized to publish" {
+		t.Errorf("Notify() error = %v", err)
+	}
+}
+
+func TestNewSNSInvalidARN(t *testing.T) {
+	for _, arn := range []string{"", "watchdog", "arn:aws:sqs:us-east-1:123456789012:watchdog", "arn:aws:sns::123456789012:watchdog"} {
+		if _, err := NewSNS(arn, awsauth.Credentials{}); err == nil {
+			t.Errorf("NewSNS(%q) error = nil, want an error", arn)
+		}
+	}
+}
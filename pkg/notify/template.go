@@ -0,0 +1,157 @@
+package notify
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Default templates of the subject and body of human-facing notifications
+const (
+	DefaultSubjectTemplate = `k8s-memory-watchdog: {{.Type}} {{.Target}}`
+	DefaultBodyTemplate    = `{{.Type}} of {{.Target}} (deployment {{.Deployment}} in namespace {{.Namespace}}) at {{.Time.Format "2006-01-02T15:04:05Z07:00"}}
+Memory usage: {{.Memory}}Mi, threshold: {{.Threshold}}Mi ({{.Percent}}%)
+{{- if .Reason}}
+Reason: {{.Reason}}{{end}}
+{{- if .Error}}
+Error: {{.Error}}{{end}}
+{{- if .HistoryURL}}
+History: {{.HistoryURL}}{{end}}
+{{- if .RunbookURL}}
+Runbook: {{.RunbookURL}}{{end}}
+{{- if .CheckID}}
+Check: {{.CheckID}}{{end}}
+{{- if .ActionID}}
+Action: {{.ActionID}}{{end}}
+`
+)
+
+// TemplateData is the context of notification templates
+type TemplateData struct {
+	Type       watchdog.EventType
+	Target     string
+	Namespace  string
+	Deployment string
+	Memory     int
+	Threshold  int
+	// Percent is the usage in percent of the threshold
+	Percent    int
+	Time       time.Time
+	Reason     string
+	Error      string
+	CheckID    string
+	ActionID   string
+	HistoryURL string
+	RunbookURL string
+}
+
+// Template renders the subject and body of notifications from Go templates
+// receiving a TemplateData. The history and runbook URLs are templates too,
+// rendered first; a target's own RunbookURL replaces the default one.
+type Template struct {
+	subject *template.Template
+	body    *template.Template
+	history *template.Template
+	runbook *template.Template
+}
+
+// TemplateConfig holds the source of the templates of a Template. Empty
+// subject and body templates use the defaults.
+type TemplateConfig struct {
+	Subject    string
+	Body       string
+	HistoryURL string
+	RunbookURL string
+}
+
+// NewTemplate parses the templates of config
+func NewTemplate(config TemplateConfig) (*Template, error) {
+	if config.Subject == "" {
+		config.Subject = DefaultSubjectTemplate
+	}
+	if config.Body == "" {
+		config.Body = DefaultBodyTemplate
+	}
+	t := &Template{}
+	for _, tt := range []struct {
+		name   string
+		source string
+		dest   **template.Template
+	}{
+		{"subject", config.Subject, &t.subject},
+		{"body", config.Body, &t.body},
+		{"history_url", config.HistoryURL, &t.history},
+		{"runbook_url", config.RunbookURL, &t.runbook},
+	} {
+		parsed, err := template.New(tt.name).Option("missingkey=error").Parse(tt.source)
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s template: %v", tt.name, err)
+		}
+		*tt.dest = parsed
+	}
+	return t, nil
+}
+
+// Data returns the context of the templates for an event
+func (t *Template) Data(event watchdog.Event) (TemplateData, error) {
+	data := TemplateData{
+		Type:       event.Type,
+		Target:     event.Target.Name,
+		Namespace:  event.Target.Namespace,
+		Deployment: event.Target.DeploymentName,
+		Memory:     event.Memory,
+		Threshold:  event.Threshold,
+		Time:       event.Time,
+		Reason:     event.Reason,
+		CheckID:    event.CheckID,
+		ActionID:   event.ActionID,
+	}
+	if event.Threshold > 0 {
+		data.Percent = event.Memory * 100 / event.Threshold
+	}
+	if event.Err != nil {
+		data.Error = event.Err.Error()
+	}
+
+	var err error
+	if data.HistoryURL, err = execute(t.history, data); err != nil {
+		return data, err
+	}
+	runbook := t.runbook
+	if event.Target.RunbookURL != "" {
+		if runbook, err = template.New("runbook_url").Parse(event.Target.RunbookURL); err != nil {
+			return data, fmt.Errorf("invalid runbook_url of target '%s': %v", event.Target.Name, err)
+		}
+	}
+	if data.RunbookURL, err = execute(runbook, data); err != nil {
+		return data, err
+	}
+	return data, nil
+}
+
+// Render returns the subject and body of the notification of an event
+func (t *Template) Render(event watchdog.Event) (subject, body string, err error) {
+	data, err := t.Data(event)
+	if err != nil {
+		return "", "", err
+	}
+	if subject, err = execute(t.subject, data); err != nil {
+		return "", "", err
+	}
+	if body, err = execute(t.body, data); err != nil {
+		return "", "", err
+	}
+	return strings.TrimSpace(subject), body, nil
+}
+
+func execute(t *template.Template, data TemplateData) (string, error) {
+	var buf bytes.Buffer
+	if err := t.Execute(&buf, data); err != nil {
+		return "", fmt.Errorf("rendering %s template: %v", t.Name(), err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,103 @@
+package notify
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+func TestTemplateRender(t *testing.T) {
+	event := watchdog.Event{
+		Type:      watchdog.EventRestart,
+		Target:    watchdog.Target{Name: "prod/api", Namespace: "prod", DeploymentName: "api"},
+		Memory:    3000,
+		Threshold: 2000,
+		Time:      time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+		CheckID:   "3f2a9c1e5b7d4a60",
+	}
+
+	tests := []struct {
+		name        string
+		config      TemplateConfig
+		event       func(watchdog.Event) watchdog.Event
+		wantSubject string
+		wantBody    []string
+	}{
+		{
+			name:        "defaults",
+			wantSubject: "k8s-memory-watchdog: restart prod/api",
+			wantBody:    []string{"restart of prod/api (deployment api in namespace prod)", "Memory usage: 3000Mi, threshold: 2000Mi (150%)", "Check: 3f2a9c1e5b7d4a60"},
+		},
+		{
+			name: "custom with links",
+			config: TemplateConfig{
+				Subject:    "[{{.Namespace}}] {{.Deployment}} used {{.Percent}}%",
+				Body:       "{{.Target}}: see {{.RunbookURL}} and {{.HistoryURL}}",
+				HistoryURL: "https://grafana.example.com/d/memory?var-namespace={{.Namespace}}",
+				RunbookURL: "https://wiki.example.com/runbooks/memory",
+			},
+			wantSubject: "[prod] api used 150%",
+			wantBody:    []string{"prod/api: see https://wiki.example.com/runbooks/memory and https://grafana.example.com/d/memory?var-namespace=prod"},
+		},
+		{
+			name:   "target runbook",
+			config: TemplateConfig{Body: "{{.RunbookURL}}", RunbookURL: "https://wiki.example.com/runbooks/memory"},
+			event: func(e watchdog.Event) watchdog.Event {
+				e.Target.RunbookURL = "https://wiki.example.com/runbooks/{{.Deployment}}"
+				return e
+			},
+			wantSubject: "k8s-memory-watchdog: restart prod/api",
+			wantBody:    []string{"https://wiki.example.com/runbooks/api"},
+		},
+		{
+			name:   "error",
+			config: TemplateConfig{Body: "{{.Error}}"},
+			event: func(e watchdog.Event) watchdog.Event {
+				e.Err = errors.New("forbidden")
+				return e
+			},
+			wantSubject: "k8s-memory-watchdog: restart prod/api",
+			wantBody:    []string{"forbidden"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tmpl, err := NewTemplate(tt.config)
+			if err != nil {
+				t.Fatalf("NewTemplate() error = %v", err)
+			}
+			e := event
+			if tt.event != nil {
+				e = tt.event(e)
+			}
+			subject, body, err := tmpl.Render(e)
+			if err != nil {
+				t.Fatalf("Render() error = %v", err)
+			}
+			if subject != tt.wantSubject {
+				t.Errorf("subject = %q, want %q", subject, tt.wantSubject)
+			}
+			for _, want := range tt.wantBody {
+				if !strings.Contains(body, want) {
+					t.Errorf("body = %q, want it to contain %q", body, want)
+				}
+			}
+		})
+	}
+}
+
+func TestTemplateErrors(t *testing.T) {
+	if _, err := NewTemplate(TemplateConfig{Body: "{{.Target"}); err == nil {
+		t.Error("NewTemplate() error = nil, want a parse error")
+	}
+	tmpl, err := NewTemplate(TemplateConfig{Body: "{{.Unknown}}"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := tmpl.Render(watchdog.Event{Type: watchdog.EventBreach}); err == nil {
+		t.Error("Render() error = nil, want an error for an unknown field")
+	}
+}
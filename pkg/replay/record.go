@@ -0,0 +1,96 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/metrics"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Recorder wraps a watchdog.MetricsProvider and writes every reading it
+// returns as samples, in the format read by ReadSamples. Providers
+// implementing metrics.PodMetricsProvider are recorded per pod; others as a
+// single sample per reading. Failed readings are not recorded.
+type Recorder struct {
+	provider watchdog.MetricsProvider
+	now      func() time.Time
+	logger   *logging.Logger
+
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewRecorder creates a new instance of Recorder writing samples to w
+func NewRecorder(provider watchdog.MetricsProvider, w io.Writer) *Recorder {
+	return &Recorder{
+		provider: provider,
+		now:      time.Now,
+		logger:   logging.New(log.Default(), logging.Levels{Default: logging.LevelInfo}).Component("recorder"),
+		enc:      json.NewEncoder(w),
+	}
+}
+
+// SetLogger logs the errors writing samples to logger instead of the
+// standard logger. A nil logger discards them.
+func (r *Recorder) SetLogger(logger *logging.Logger) {
+	r.logger = logger
+}
+
+// GetPodMemoryUsage returns the total memory usage of pods in a namespace
+// and records the samples it was computed from
+func (r *Recorder) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	source, ok := r.provider.(metrics.PodMetricsProvider)
+	if !ok {
+		memory, err := r.provider.GetPodMemoryUsage(ctx, namespace)
+		if err == nil {
+			r.record(Sample{Time: r.now(), Namespace: namespace, Memory: memory})
+		}
+		return memory, err
+	}
+
+	pods, err := r.getPodMemory(ctx, source, namespace)
+	if err != nil {
+		return 0, err
+	}
+	return metrics.TotalMemory(pods), nil
+}
+
+// GetPodMemory returns the memory usage of each pod in a namespace and
+// records it. It fails with watchdog.ErrNoPodMetrics when the provider
+// isn't a metrics.PodMetricsProvider.
+func (r *Recorder) GetPodMemory(ctx context.Context, namespace string) ([]metrics.PodMemory, error) {
+	source, ok := r.provider.(metrics.PodMetricsProvider)
+	if !ok {
+		return nil, fmt.Errorf("%w: the source only reports the memory of a namespace", watchdog.ErrNoPodMetrics)
+	}
+	return r.getPodMemory(ctx, source, namespace)
+}
+
+func (r *Recorder) getPodMemory(ctx context.Context, source metrics.PodMetricsProvider, namespace string) ([]metrics.PodMemory, error) {
+	pods, err := source.GetPodMemory(ctx, namespace)
+	if err != nil {
+		return nil, err
+	}
+	now := r.now()
+	for _, p := range pods {
+		r.record(Sample{Time: now, Namespace: namespace, Pod: p.Pod, Memory: p.Memory})
+	}
+	return pods, nil
+}
+
+// record writes a sample. Write errors are logged rather than returned, so
+// a full disk does not stop the watchdog from checking.
+func (r *Recorder) record(s Sample) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err := r.enc.Encode(s); err != nil {
+		r.logger.Errorf("Error recording sample: %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/metrics"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// podSource reports fixed per-pod readings
+type podSource []metrics.PodMemory
+
+func (s podSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	return metrics.TotalMemory(s), nil
+}
+
+func (s podSource) GetPodMemory(ctx context.Context, namespace string) ([]metrics.PodMemory, error) {
+	return s, nil
+}
+
+func TestRecorder(t *testing.T) {
+	at := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	var buf bytes.Buffer
+	recorder := NewRecorder(podSource{{Pod: "api-1", Memory: 1200}, {Pod: "api-2", Memory: 900}}, &buf)
+	recorder.now = func() time.Time { return at }
+
+	memory, err := recorder.GetPodMemoryUsage(context.Background(), "prod")
+	if err != nil || memory != 2100 {
+		t.Fatalf("GetPodMemoryUsage() = %v, %v, want 2100, nil", memory, err)
+	}
+
+	samples, err := ReadSamples(&buf)
+	if err != nil {
+		t.Fatalf("ReadSamples() error = %v", err)
+	}
+	want := []Sample{
+		{Time: at, Namespace: "prod", Pod: "api-1", Memory: 1200},
+		{Time: at, Namespace: "prod", Pod: "api-2", Memory: 900},
+	}
+	if !reflect.DeepEqual(samples, want) {
+		t.Errorf("recorded samples = %+v, want %+v", samples, want)
+	}
+
+	results, err := Simulate(context.Background(), watchdog.Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+	}, samples)
+	if err != nil || len(results) != 1 || !results[0].Breached {
+		t.Errorf("Simulate() of the recording = %+v, %v, want a single breach", results, err)
+	}
+}
+
+func TestRecorderTotalOnly(t *testing.T) {
+	var buf bytes.Buffer
+	client := watchdogtest.NewFakeClient(1500)
+	client.FailMetrics(watchdog.ErrMetricsUnavailable, 1)
+	recorder := NewRecorder(client, &buf)
+
+	if _, err := recorder.GetPodMemoryUsage(context.Background(), "prod"); err == nil {
+		t.Fatal("GetPodMemoryUsage() expected an error")
+	}
+	if memory, err := recorder.GetPodMemoryUsage(context.Background(), "prod"); err != nil || memory != 1500 {
+		t.Fatalf("GetPodMemoryUsage() = %v, %v, want 1500, nil", memory, err)
+	}
+
+	samples, _ := ReadSamples(&buf)
+	if len(samples) != 1 || samples[0].Memory != 1500 || samples[0].Pod != "" {
+		t.Errorf("recorded samples = %+v, want a single total", samples)
+	}
+	if _, err := recorder.GetPodMemory(context.Background(), "prod"); !errors.Is(err, watchdog.ErrNoPodMetrics) {
+		t.Errorf("GetPodMemory() error = %v, want %v", err, watchdog.ErrNoPodMetrics)
+	}
+}
@@ -0,0 +1,153 @@
+// Package replay records the memory samples read by the watchdog and runs
+// recordings through its decision logic, so thresholds can be tuned offline
+// against real data.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/clock"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+// Sample is the memory usage of a single pod at a point in time. Samples are
+// stored one JSON object per line. A sample without a target applies to
+// every target in its namespace.
+type Sample struct {
+	Time      time.Time `json:"time"`
+	Target    string    `json:"target"`
+	Namespace string    `json:"namespace"`
+	Pod       string    `json:"pod,omitempty"`
+	Memory    int       `json:"memory"`
+}
+
+// ReadSamples decodes JSON lines samples from r. Blank lines are skipped.
+func ReadSamples(r io.Reader) ([]Sample, error) {
+	var samples []Sample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		data := scanner.Bytes()
+		if len(data) == 0 {
+			continue
+		}
+		var sample Sample
+		if err := json.Unmarshal(data, &sample); err != nil {
+			return nil, fmt.Errorf("line %d: %v", line, err)
+		}
+		samples = append(samples, sample)
+	}
+	return samples, scanner.Err()
+}
+
+// step is the reading of a target at one check, summed over its pods
+type step struct {
+	time   time.Time
+	target string
+	memory int
+	pods   []watchdog.PodMemory
+}
+
+// Simulate replays samples through a watchdog built from config and returns
+// the result of every recorded check in time order. Samples of the same
+// target taken at the same time form one check. Breaching checks report the
+// restart that would have fired; nothing is restarted.
+func Simulate(ctx context.Context, config watchdog.Config, samples []Sample) ([]watchdog.CheckResult, error) {
+	byNamespace := make(map[string][]string)
+	for _, t := range config.ResolveTargets() {
+		byNamespace[t.Namespace] = append(byNamespace[t.Namespace], t.Name)
+	}
+
+	steps := make(map[string]map[time.Time]*step)
+	var order []*step
+	for _, s := range samples {
+		targets := []string{s.Target}
+		if s.Target == "" {
+			targets = byNamespace[s.Namespace]
+		}
+		for _, target := range targets {
+			byTime, ok := steps[target]
+			if !ok {
+				byTime = make(map[time.Time]*step)
+				steps[target] = byTime
+			}
+			st, ok := byTime[s.Time]
+			if !ok {
+				st = &step{time: s.Time, target: target}
+				byTime[s.Time] = st
+				order = append(order, st)
+			}
+			st.memory += s.Memory
+			st.pods = append(st.pods, watchdog.PodMemory{Pod: s.Pod, Memory: s.Memory})
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool { return order[i].time.Before(order[j].time) })
+
+	// every reading comes from the recording, whatever the targets' sources
+	targets := make([]watchdog.Target, len(config.Targets))
+	for i, t := range config.Targets {
+		t.Sources = nil
+		targets[i] = t
+	}
+	config.Targets = targets
+
+	source := &replaySource{}
+	clk := &replayClock{}
+	w := watchdog.NewWatchdog(source, nil, config,
+		watchdog.WithClock(clk),
+		watchdog.WithLogging(nil),
+		watchdog.WithAction(watchdog.ActionFunc(func(context.Context, watchdog.Target) error { return nil })),
+	)
+
+	results := make([]watchdog.CheckResult, 0, len(order))
+	for _, st := range order {
+		source.memory, source.pods = st.memory, st.pods
+		clk.now = st.time
+		result, err := w.CheckTarget(ctx, st.target)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// replaySource reports the memory usage of the step being replayed
+type replaySource struct {
+	memory int
+	pods   []watchdog.PodMemory
+}
+
+func (s *replaySource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	return s.memory, nil
+}
+
+func (s *replaySource) GetPodMemory(ctx context.Context, namespace string) ([]watchdog.PodMemory, error) {
+	return s.pods, nil
+}
+
+// replayClock reports the time of the step being replayed. Its tickers never
+// fire, as the simulation drives checks itself.
+type replayClock struct {
+	now time.Time
+}
+
+func (c *replayClock) Now() time.Time {
+	return c.now
+}
+
+func (c *replayClock) NewTicker(d time.Duration) clock.Ticker {
+	return stoppedTicker{}
+}
+
+type stoppedTicker struct{}
+
+func (stoppedTicker) C() <-chan time.Time { return nil }
+
+func (stoppedTicker) Stop() {}
@@ -0,0 +1,107 @@
+package replay
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+)
+
+const recording = `{"time":"2026-01-01T00:00:00Z","target":"prod/api","namespace":"prod","pod":"api-1","memory":800}
+{"time":"2026-01-01T00:00:00Z","target":"prod/api","namespace":"prod","pod":"api-2","memory":700}
+
+{"time":"2026-01-01T00:05:00Z","target":"prod/api","namespace":"prod","pod":"api-1","memory":1200}
+{"time":"2026-01-01T00:05:00Z","target":"prod/api","namespace":"prod","pod":"api-2","memory":900}
+{"time":"2026-01-01T00:10:00Z","target":"prod/api","namespace":"prod","pod":"api-1","memory":400}
+`
+
+func TestReadSamples(t *testing.T) {
+	samples, err := ReadSamples(strings.NewReader(recording))
+	if err != nil {
+		t.Fatalf("ReadSamples() error = %v", err)
+	}
+	if len(samples) != 5 {
+		t.Fatalf("ReadSamples() returned %d samples, want 5", len(samples))
+	}
+	if s := samples[1]; s.Pod != "api-2" || s.Memory != 700 || s.Target != "prod/api" {
+		t.Errorf("ReadSamples()[1] = %+v", s)
+	}
+
+	if _, err := ReadSamples(strings.NewReader("{}\nnot json\n")); err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("ReadSamples() error = %v, want a line 2 error", err)
+	}
+}
+
+func TestSimulate(t *testing.T) {
+	samples, _ := ReadSamples(strings.NewReader(recording))
+	config := watchdog.Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+	}
+
+	results, err := Simulate(context.Background(), config, samples)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+
+	want := []struct {
+		memory   int
+		breached bool
+	}{
+		{1500, false},
+		{2100, true},
+		{400, false},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("Simulate() returned %d results, want %d", len(results), len(want))
+	}
+	start := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, w := range want {
+		r := results[i]
+		if r.Memory != w.memory || r.Breached != w.breached || r.Err != nil {
+			t.Errorf("Simulate()[%d] = %+v, want memory %d, breached %v", i, r, w.memory, w.breached)
+		}
+		if at := start.Add(time.Duration(i) * 5 * time.Minute); !r.Time.Equal(at) {
+			t.Errorf("Simulate()[%d].Time = %v, want %v", i, r.Time, at)
+		}
+	}
+
+	config.MemoryThreshold = 2500
+	results, _ = Simulate(context.Background(), config, samples)
+	for i, r := range results {
+		if r.Breached {
+			t.Errorf("Simulate()[%d] breached with a 2500Mi threshold", i)
+		}
+	}
+
+	config.DeploymentName = "worker"
+	if _, err := Simulate(context.Background(), config, samples); !errors.Is(err, watchdog.ErrTargetNotFound) {
+		t.Errorf("Simulate() error = %v, want %v", err, watchdog.ErrTargetNotFound)
+	}
+}
+
+func TestSimulatePodScope(t *testing.T) {
+	samples := []Sample{
+		{Time: time.Unix(0, 0), Namespace: "prod", Pod: "api-7d9f8b6c5d-x2k4p", Memory: 800},
+		{Time: time.Unix(0, 0), Namespace: "prod", Pod: "api-7d9f8b6c5d-q8z7m", Memory: 700},
+		{Time: time.Unix(0, 0), Namespace: "prod", Pod: "redis-0", Memory: 900},
+	}
+	config := watchdog.Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 1000,
+		Scope:           watchdog.ScopePod,
+	}
+
+	results, err := Simulate(context.Background(), config, samples)
+	if err != nil {
+		t.Fatalf("Simulate() error = %v", err)
+	}
+	if len(results) != 1 || results[0].Memory != 800 || results[0].Breached {
+		t.Errorf("Simulate() = %+v, want one check of 800Mi", results)
+	}
+}
@@ -0,0 +1,84 @@
+package telemetry
+
+import (
+	"net"
+	"strings"
+)
+
+// StatsDConfig configures the StatsD sink
+type StatsDConfig struct {
+	Address   string `yaml:"address"`
+	Prefix    string `yaml:"prefix"`
+	DogStatsD bool   `yaml:"dogstatsd"`
+}
+
+// StatsD is a Sink sending metrics over UDP to a StatsD agent. With DogStatsD
+// enabled labels are sent as tags; otherwise their values are appended to
+// the metric name.
+type StatsD struct {
+	conn      net.Conn
+	prefix    string
+	dogstatsd bool
+}
+
+// NewStatsD creates a new instance of StatsD sending to the agent at
+// config.Address
+func NewStatsD(config StatsDConfig) (*StatsD, error) {
+	conn, err := net.Dial("udp", config.Address)
+	if err != nil {
+		return nil, err
+	}
+	return &StatsD{
+		conn:      conn,
+		prefix:    config.Prefix,
+		dogstatsd: config.DogStatsD,
+	}, nil
+}
+
+// Add sends a counter increment
+func (s *StatsD) Add(name string, delta float64, labels []string) {
+	s.send(name, delta, "c", labels)
+}
+
+// Set sends a gauge value
+func (s *StatsD) Set(name string, value float64, labels []string) {
+	s.send(name, value, "g", labels)
+}
+
+// Close closes the connection to the agent
+func (s *StatsD) Close() error {
+	return s.conn.Close()
+}
+
+// send writes a single metric line. StatsD is fire-and-forget, so write
+// errors are ignored.
+func (s *StatsD) send(name string, value float64, kind string, labels []string) {
+	s.conn.Write([]byte(s.format(name, value, kind, labels)))
+}
+
+func (s *StatsD) format(name string, value float64, kind string, labels []string) string {
+	if s.prefix != "" {
+		name = s.prefix + "." + name
+	}
+	var tags []string
+	for i := 0; i+1 < len(labels); i += 2 {
+		if s.dogstatsd {
+			tags = append(tags, statsdReplacer.Replace(labels[i])+":"+statsdReplacer.Replace(labels[i+1]))
+		} else {
+			name += "." + statsdNameReplacer.Replace(labels[i+1])
+		}
+	}
+
+	line := name + ":" + formatValue(value) + "|" + kind
+	if len(tags) > 0 {
+		line += "|#" + strings.Join(tags, ",")
+	}
+	return line
+}
+
+var (
+	// statsdReplacer strips the separators of the DogStatsD line format
+	statsdReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_")
+	// statsdNameReplacer also strips characters meaningful in metric paths
+	statsdNameReplacer = strings.NewReplacer("|", "_", ",", "_", "#", "_", "\n", "_", ":", "_", ".", "_", "/", "_", "@", "_")
+)
@@ -0,0 +1,76 @@
+package telemetry
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestStatsDFormat(t *testing.T) {
+	tests := []struct {
+		name     string
+		config   StatsDConfig
+		kind     string
+		labels   []string
+		expected string
+	}{
+		{
+			name:     "plain counter",
+			config:   StatsDConfig{},
+			kind:     "c",
+			labels:   []string{"target", "default/app"},
+			expected: "k8s_memory_watchdog_checks_total.default_app:1|c",
+		},
+		{
+			name:     "prefixed gauge",
+			config:   StatsDConfig{Prefix: "infra"},
+			kind:     "g",
+			expected: "infra.k8s_memory_watchdog_checks_total:1|g",
+		},
+		{
+			name:     "dogstatsd tags",
+			config:   StatsDConfig{DogStatsD: true},
+			kind:     "c",
+			labels:   []string{"target", "default/app", "reason", "a,b"},
+			expected: "k8s_memory_watchdog_checks_total:1|c|#target:default/app,reason:a_b",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := &StatsD{prefix: tt.config.Prefix, dogstatsd: tt.config.DogStatsD}
+			result := s.format(MetricChecksTotal, 1, tt.kind, tt.labels)
+			if result != tt.expected {
+				t.Errorf("format() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestStatsDSink(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Skipf("cannot listen on UDP: %v", err)
+	}
+	defer conn.Close()
+
+	sink, err := NewStatsD(StatsDConfig{Address: conn.LocalAddr().String(), DogStatsD: true})
+	if err != nil {
+		t.Fatalf("NewStatsD() error = %v", err)
+	}
+	defer sink.Close()
+
+	telemetry := NewTelemetry()
+	telemetry.AddSink(sink)
+	telemetry.Set(MetricMemoryUsage, 1234, "target", "default/app")
+
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 512)
+	n, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatalf("ReadFrom() error = %v", err)
+	}
+	if got, want := string(buf[:n]), "k8s_memory_watchdog_memory_usage:1234|g|#target:default/app"; got != want {
+		t.Errorf("received %q, want %q", got, want)
+	}
+}
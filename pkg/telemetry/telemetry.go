@@ -0,0 +1,217 @@
+// Package telemetry collects the watchdog's own metrics, exposes them for
+// Prometheus and pushes them to sinks such as StatsD.
+package telemetry
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Metric names exposed by the watchdog
+const (
+	MetricMemoryUsage       = "k8s_memory_watchdog_memory_usage"
+	MetricSwapUsage         = "k8s_memory_watchdog_swap_usage"
+	MetricCPUThrottling     = "k8s_memory_watchdog_cpu_throttling_percent"
+	MetricRestartsTotal     = "k8s_memory_watchdog_deployment_restarts_total"
+	MetricChecksTotal       = "k8s_memory_watchdog_checks_total"
+	MetricThrottledRequests = "k8s_memory_watchdog_throttled_requests_total"
+	MetricErrorsTotal       = "k8s_memory_watchdog_errors_total"
+	MetricSourceFallbacks   = "k8s_memory_watchdog_source_fallbacks_total"
+	MetricGoroutines        = "k8s_memory_watchdog_goroutines"
+	MetricHeapInUse         = "k8s_memory_watchdog_heap_inuse_bytes"
+	MetricSinceLastCheck    = "k8s_memory_watchdog_seconds_since_last_check"
+	MetricStalledTargets    = "k8s_memory_watchdog_stalled_targets"
+	MetricCircuitOpen       = "k8s_memory_watchdog_circuit_open"
+	MetricNotifyOnly        = "k8s_memory_watchdog_notify_only"
+	MetricClusterTargets    = "k8s_memory_watchdog_cluster_targets"
+	MetricClusterMemory     = "k8s_memory_watchdog_cluster_memory_usage"
+	MetricClusterThreshold  = "k8s_memory_watchdog_cluster_memory_threshold"
+	MetricClusterBreaching  = "k8s_memory_watchdog_cluster_breaching_targets"
+	MetricClusterRestarts   = "k8s_memory_watchdog_cluster_restarts_total"
+)
+
+// Config configures the Prometheus metrics endpoint
+type Config struct {
+	Enabled bool   `yaml:"enabled"`
+	Port    int    `yaml:"port"`
+	Path    string `yaml:"path"`
+}
+
+// Telemetry collects the watchdog's own metrics and exposes them in the
+// Prometheus text format. A nil *Telemetry discards all observations.
+type Telemetry struct {
+	mu       sync.Mutex
+	families map[string]*metricFamily
+	sinks    []Sink
+}
+
+// Sink receives every counter increment and gauge update as it happens, for
+// push-based metric pipelines. Labels are given as name/value pairs.
+type Sink interface {
+	Add(name string, delta float64, labels []string)
+	Set(name string, value float64, labels []string)
+}
+
+type metricFamily struct {
+	help   string
+	kind   string
+	series map[string]float64
+	fn     func() float64
+}
+
+// NewTelemetry creates a new instance of Telemetry with the standard
+// watchdog metrics registered
+func NewTelemetry() *Telemetry {
+	t := &Telemetry{families: make(map[string]*metricFamily)}
+	t.Register(MetricMemoryUsage, "gauge", "Current memory usage in Mi")
+	t.Register(MetricSwapUsage, "gauge", "Current swap usage in Mi, for targets with a swap threshold")
+	t.Register(MetricCPUThrottling, "gauge", "Percentage of throttled CPU periods, for targets with a throttling threshold")
+	t.Register(MetricRestartsTotal, "counter", "Total number of restarts")
+	t.Register(MetricChecksTotal, "counter", "Total number of checks")
+	t.Register(MetricErrorsTotal, "counter", "Total number of failed checks by reason")
+	t.Register(MetricSourceFallbacks, "counter", "Total number of failed metric sources replaced by the next one of a chain")
+	t.Register(MetricCircuitOpen, "gauge", "Whether the restarts of a target are held back by an open circuit")
+	t.Register(MetricNotifyOnly, "gauge", "Whether restarts are disabled after the restart budget was exhausted")
+	t.Register(MetricClusterTargets, "gauge", "Number of targets of a cluster")
+	t.Register(MetricClusterMemory, "gauge", "Total memory usage in Mi of the targets of a cluster")
+	t.Register(MetricClusterThreshold, "gauge", "Total memory threshold in Mi of the targets of a cluster")
+	t.Register(MetricClusterBreaching, "gauge", "Number of targets of a cluster with an open breach")
+	t.Register(MetricClusterRestarts, "counter", "Total number of restarts of the targets of a cluster")
+	return t
+}
+
+// Register declares a metric family with its type and help text
+func (t *Telemetry) Register(name, kind, help string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.familyLocked(name, kind).help = help
+}
+
+// RegisterFunc declares a metric whose value is read from fn at scrape time
+func (t *Telemetry) RegisterFunc(name, kind, help string, fn func() float64) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	family := t.familyLocked(name, kind)
+	family.help = help
+	family.fn = fn
+}
+
+// AddSink forwards all further observations to sink. Metrics registered with
+// RegisterFunc are only read at scrape time and are not forwarded.
+func (t *Telemetry) AddSink(sink Sink) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.sinks = append(t.sinks, sink)
+}
+
+// Inc increments a counter. Labels are given as name/value pairs.
+func (t *Telemetry) Inc(name string, labels ...string) {
+	t.Add(name, 1, labels...)
+}
+
+// Add adds delta to a counter. Labels are given as name/value pairs.
+func (t *Telemetry) Add(name string, delta float64, labels ...string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.familyLocked(name, "counter").series[formatLabels(labels)] += delta
+	sinks := t.sinks
+	t.mu.Unlock()
+
+	for _, sink := range sinks {
+		sink.Add(name, delta, labels)
+	}
+}
+
+// Set sets a gauge. Labels are given as name/value pairs.
+func (t *Telemetry) Set(name string, value float64, labels ...string) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	t.familyLocked(name, "gauge").series[formatLabels(labels)] = value
+	sinks := t.sinks
+	t.mu.Unlock()
+
+	for _, sink := range sinks {
+		sink.Set(name, value, labels)
+	}
+}
+
+func (t *Telemetry) familyLocked(name, kind string) *metricFamily {
+	family, ok := t.families[name]
+	if !ok {
+		family = &metricFamily{kind: kind, series: make(map[string]float64)}
+		t.families[name] = family
+	}
+	return family
+}
+
+// ServeHTTP writes all metrics in the Prometheus text exposition format
+func (t *Telemetry) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	t.WriteText(w)
+}
+
+// WriteText writes all metrics in the Prometheus text exposition format
+func (t *Telemetry) WriteText(w io.Writer) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	names := make([]string, 0, len(t.families))
+	for name := range t.families {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		family := t.families[name]
+		if family.help != "" {
+			fmt.Fprintf(w, "# HELP %s %s\n", name, family.help)
+		}
+		fmt.Fprintf(w, "# TYPE %s %s\n", name, family.kind)
+		if family.fn != nil {
+			fmt.Fprintf(w, "%s %s\n", name, formatValue(family.fn()))
+			continue
+		}
+
+		keys := make([]string, 0, len(family.series))
+		for key := range family.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fmt.Fprintf(w, "%s%s %s\n", name, key, formatValue(family.series[key]))
+		}
+	}
+}
+
+func formatLabels(labels []string) string {
+	if len(labels) < 2 {
+		return ""
+	}
+	pairs := make([]string, 0, len(labels)/2)
+	for i := 0; i+1 < len(labels); i += 2 {
+		pairs = append(pairs, labels[i]+"="+strconv.Quote(labels[i+1]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatValue(value float64) string {
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
@@ -0,0 +1,37 @@
+package telemetry
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestTelemetryServeHTTP(t *testing.T) {
+	telemetry := NewTelemetry()
+	telemetry.Inc(MetricChecksTotal, "target", "default/app")
+	telemetry.Inc(MetricChecksTotal, "target", "default/app")
+	telemetry.Set(MetricMemoryUsage, 1234, "target", "default/app")
+	telemetry.RegisterFunc(MetricThrottledRequests, "counter", "Throttled requests", func() float64 { return 7 })
+
+	recorder := httptest.NewRecorder()
+	telemetry.ServeHTTP(recorder, httptest.NewRequest("GET", "/metrics", nil))
+	body := recorder.Body.String()
+
+	for _, want := range []string{
+		"# TYPE k8s_memory_watchdog_checks_total counter",
+		`k8s_memory_watchdog_checks_total{target="default/app"} 2`,
+		"# TYPE k8s_memory_watchdog_memory_usage gauge",
+		`k8s_memory_watchdog_memory_usage{target="default/app"} 1234`,
+		"k8s_memory_watchdog_throttled_requests_total 7",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("metrics output missing %q:\n%s", want, body)
+		}
+	}
+}
+
+func TestTelemetryNil(t *testing.T) {
+	var telemetry *Telemetry
+	telemetry.Inc(MetricChecksTotal, "target", "app")
+	telemetry.Set(MetricMemoryUsage, 1, "target", "app")
+}
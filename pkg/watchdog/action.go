@@ -0,0 +1,128 @@
+package watchdog
+
+import (
+	"context"
+	"time"
+)
+
+// How the default Action restarts the deployment of a target
+const (
+	// RestartRollout runs a rolling restart, the default
+	RestartRollout = "rollout"
+	// RestartScale scales the deployment to zero, waits for its pods to
+	// terminate, then scales it back to its replica count, for apps that
+	// misbehave when old and new pods run side by side
+	RestartScale = "scale"
+	// RestartDelete deletes the pods of the deployment one at a time,
+	// waiting for the replacement of each to be ready, optionally
+	// overriding their grace period and pausing between pods
+	RestartDelete = "delete"
+)
+
+// validRestartStrategy reports whether strategy is empty or one of the
+// restart strategies
+func validRestartStrategy(strategy string) bool {
+	return strategy == "" || strategy == RestartRollout || strategy == RestartScale || strategy == RestartDelete
+}
+
+// restartStrategyKey is the context key of the restart strategy of the
+// current action
+type restartStrategyKey struct{}
+
+// podDeletionKey is the context key of the pod deletion settings of the
+// current action
+type podDeletionKey struct{}
+
+// podDeletion is how RestartDelete deletes the pods of a deployment
+type podDeletion struct {
+	gracePeriod time.Duration
+	delay       time.Duration
+}
+
+// ContextWithRestartStrategy returns a copy of ctx carrying the restart
+// strategy of the target being restarted
+func ContextWithRestartStrategy(ctx context.Context, strategy string) context.Context {
+	return context.WithValue(ctx, restartStrategyKey{}, strategy)
+}
+
+// RestartStrategy returns the restart strategy carried by ctx, RestartRollout
+// when unset. Restarters pick how to restart the deployment with it.
+func RestartStrategy(ctx context.Context) string {
+	if strategy, _ := ctx.Value(restartStrategyKey{}).(string); strategy != "" {
+		return strategy
+	}
+	return RestartRollout
+}
+
+// surgeKey is the context key of the surge replicas of the current action
+type surgeKey struct{}
+
+// ContextWithSurge returns a copy of ctx carrying the number of replicas
+// added to the deployment being restarted until the restart has rolled out
+func ContextWithSurge(ctx context.Context, surge int) context.Context {
+	return context.WithValue(ctx, surgeKey{}, surge)
+}
+
+// Surge returns the surge replicas carried by ctx, zero when unset
+func Surge(ctx context.Context) int {
+	surge, _ := ctx.Value(surgeKey{}).(int)
+	return surge
+}
+
+// rolloutCheckKey is the context key telling restarters to wait for the
+// restart to roll out
+type rolloutCheckKey struct{}
+
+// ContextWithRolloutCheck returns a copy of ctx telling restarters whether
+// to wait until the restart has rolled out, failing the restart if it
+// doesn't before ctx is done
+func ContextWithRolloutCheck(ctx context.Context, check bool) context.Context {
+	return context.WithValue(ctx, rolloutCheckKey{}, check)
+}
+
+// RolloutCheck reports whether ctx tells restarters to wait for the restart
+// to roll out
+func RolloutCheck(ctx context.Context) bool {
+	check, _ := ctx.Value(rolloutCheckKey{}).(bool)
+	return check
+}
+
+// ContextWithPodDeletion returns a copy of ctx carrying how RestartDelete
+// deletes the pods of the target being restarted: the grace period given to
+// each pod, its own when zero, and the delay before deleting the next one
+func ContextWithPodDeletion(ctx context.Context, gracePeriod, delay time.Duration) context.Context {
+	return context.WithValue(ctx, podDeletionKey{}, podDeletion{gracePeriod: gracePeriod, delay: delay})
+}
+
+// PodDeletion returns the grace period and delay between pods carried by
+// ctx, zero when unset
+func PodDeletion(ctx context.Context) (gracePeriod, delay time.Duration) {
+	d, _ := ctx.Value(podDeletionKey{}).(podDeletion)
+	return d.gracePeriod, d.delay
+}
+
+// Action is the remediation performed when a target exceeds its threshold
+type Action interface {
+	Execute(ctx context.Context, target Target) error
+}
+
+// ActionFunc adapts a function to the Action interface
+type ActionFunc func(ctx context.Context, target Target) error
+
+// Execute calls f(ctx, target)
+func (f ActionFunc) Execute(ctx context.Context, target Target) error {
+	return f(ctx, target)
+}
+
+// restartAction is the default Action, restarting the target's deployment
+type restartAction struct {
+	restarter Restarter
+}
+
+func (a restartAction) Execute(ctx context.Context, target Target) error {
+	ctx = ContextWithRestartStrategy(ctx, target.RestartStrategy)
+	ctx = ContextWithPodDeletion(ctx, target.GracePeriod, target.DeleteDelay)
+	ctx = ContextWithSurge(ctx, target.SurgeReplicas)
+	ctx = ContextWithRolloutCheck(ctx, target.Rollback)
+	return a.restarter.RestartDeployment(ctx, target.Namespace, target.DeploymentName)
+}
@@ -0,0 +1,94 @@
+package watchdog
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Aggregation functions applied to the memory usage of the pods in the
+// scope of a target before it is compared with the threshold. Percentiles
+// are written p followed by the percentile, e.g. p90.
+const (
+	AggregateSum    = "sum"
+	AggregateMax    = "max"
+	AggregateMean   = "mean"
+	AggregateMedian = "median"
+)
+
+// aggregation returns the aggregation function of target, defaulting to the
+// largest pod for ScopePod and to the sum otherwise
+func (t Target) aggregation() string {
+	switch {
+	case t.Aggregation != "":
+		return t.Aggregation
+	case t.Scope == ScopePod:
+		return AggregateMax
+	}
+	return AggregateSum
+}
+
+// parsePercentile returns the percentile of an aggregation like p90
+func parsePercentile(aggregation string) (float64, error) {
+	if !strings.HasPrefix(aggregation, "p") {
+		return 0, fmt.Errorf("unknown aggregation '%s', want sum, max, mean, median or a percentile like p90", aggregation)
+	}
+	p, err := strconv.ParseFloat(aggregation[1:], 64)
+	if err != nil || p <= 0 || p > 100 {
+		return 0, fmt.Errorf("invalid percentile '%s', want p followed by a number between 0 and 100", aggregation)
+	}
+	return p, nil
+}
+
+// validAggregation returns an error when aggregation is not empty and not
+// one of the aggregation functions
+func validAggregation(aggregation string) error {
+	switch aggregation {
+	case "", AggregateSum, AggregateMax, AggregateMean, AggregateMedian:
+		return nil
+	}
+	_, err := parsePercentile(aggregation)
+	return err
+}
+
+// aggregate applies aggregation to values, the memory usage of pods, which
+// must not be empty. The mean is rounded, and percentiles use the nearest rank.
+func aggregate(aggregation string, values []int) (int, error) {
+	sorted := append([]int(nil), values...)
+	sort.Ints(sorted)
+	n := len(sorted)
+
+	switch aggregation {
+	case AggregateSum, "":
+		total := 0
+		for _, v := range sorted {
+			total += v
+		}
+		return total, nil
+	case AggregateMax:
+		return sorted[n-1], nil
+	case AggregateMean:
+		total := 0
+		for _, v := range sorted {
+			total += v
+		}
+		return int(math.Round(float64(total) / float64(n))), nil
+	case AggregateMedian:
+		if n%2 == 1 {
+			return sorted[n/2], nil
+		}
+		return int(math.Round(float64(sorted[n/2-1]+sorted[n/2]) / 2)), nil
+	}
+
+	p, err := parsePercentile(aggregation)
+	if err != nil {
+		return 0, err
+	}
+	rank := int(math.Ceil(p / 100 * float64(n)))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1], nil
+}
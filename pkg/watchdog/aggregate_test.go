@@ -0,0 +1,35 @@
+package watchdog
+
+import "testing"
+
+func TestAggregate(t *testing.T) {
+	values := []int{500, 3000, 400, 600, 500, 700}
+	tests := []struct {
+		aggregation string
+		want        int
+	}{
+		{aggregation: AggregateSum, want: 5700},
+		{aggregation: AggregateMax, want: 3000},
+		{aggregation: AggregateMean, want: 950},
+		{aggregation: AggregateMedian, want: 550},
+		{aggregation: "p50", want: 500},
+		{aggregation: "p90", want: 3000},
+		{aggregation: "p10", want: 400},
+		{aggregation: "p100", want: 3000},
+	}
+	for _, tt := range tests {
+		got, err := aggregate(tt.aggregation, values)
+		if err != nil || got != tt.want {
+			t.Errorf("aggregate(%s) = %d, %v, want %d", tt.aggregation, got, err, tt.want)
+		}
+	}
+	if got, _ := aggregate(AggregateMedian, []int{300, 100, 200}); got != 200 {
+		t.Errorf("aggregate(median) of an odd count = %d, want 200", got)
+	}
+
+	for _, aggregation := range []string{"avg", "p0", "p101", "pxx"} {
+		if err := validAggregation(aggregation); err == nil {
+			t.Errorf("validAggregation(%s) expected an error", aggregation)
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package watchdog
+
+import (
+	"context"
+	"time"
+)
+
+// BaselineConfig configures the week-over-week baseline trigger. A target
+// also breaches when its usage is more than Percent above the average usage
+// recorded at the same time of the week over the last Weeks weeks, within
+// Tolerance of it. The history is read from the state store, and a Percent
+// of zero disables the trigger.
+type BaselineConfig struct {
+	Percent   float64       `yaml:"percent"`
+	Weeks     int           `yaml:"weeks"`
+	Tolerance time.Duration `yaml:"tolerance"`
+}
+
+// baseline returns the average usage of a target at the same time of the
+// week as now in its recorded history, and whether any was recorded. Weeks
+// are counted in the target's time zone, following daylight saving changes.
+func (w *Watchdog) baseline(ctx context.Context, target Target, now time.Time) (int, bool) {
+	config := w.config.Baseline
+	if config.Percent <= 0 || w.store == nil {
+		return 0, false
+	}
+	weeks := config.Weeks
+	if weeks <= 0 {
+		weeks = 1
+	}
+	tolerance := config.Tolerance
+	if tolerance <= 0 {
+		tolerance = 15 * time.Minute
+	}
+
+	local := now.In(target.location())
+	records, err := w.store.List(ctx, local.AddDate(0, 0, -7*weeks).Add(-tolerance))
+	if err != nil {
+		w.logger.Errorf("Error reading the baseline of target '%s': %v", target.Name, err)
+		return 0, false
+	}
+
+	var sum, count int
+	for _, record := range records {
+		if record.Target != target.Name || record.Error != "" {
+			continue
+		}
+		for i := 1; i <= weeks; i++ {
+			offset := record.Time.Sub(local.AddDate(0, 0, -7*i))
+			if offset >= -tolerance && offset <= tolerance {
+				sum += record.Memory
+				count++
+				break
+			}
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+	return sum / count, true
+}
+
+// aboveBaseline reports whether memory exceeds baseline by more than the
+// configured percentage
+func (c BaselineConfig) aboveBaseline(memory, baseline int) bool {
+	return float64(memory) > float64(baseline)*(1+c.Percent/100)
+}
@@ -0,0 +1,65 @@
+package watchdog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestBaselineTrigger(t *testing.T) {
+	now := time.Date(2024, 3, 11, 14, 0, 0, 0, time.UTC)
+	store := NewMemoryStateStore(0)
+	for _, record := range []Record{
+		{Time: now.Add(-7*24*time.Hour - 5*time.Minute), Target: "prod/api", Memory: 1000},
+		{Time: now.Add(-7*24*time.Hour + 5*time.Minute), Target: "prod/api", Memory: 1200},
+		{Time: now.Add(-7*24*time.Hour + time.Hour), Target: "prod/api", Memory: 9000},
+		{Time: now.Add(-7 * 24 * time.Hour), Target: "prod/worker", Memory: 9000},
+		{Time: now.Add(-7 * 24 * time.Hour), Target: "prod/api", Error: "metrics unavailable"},
+		{Time: now.Add(-14 * 24 * time.Hour), Target: "prod/api", Memory: 500},
+	} {
+		store.Save(context.Background(), record)
+	}
+
+	tests := []struct {
+		name     string
+		memory   int
+		weeks    int
+		baseline int
+		breached bool
+	}{
+		{name: "normal usage", memory: 1500, baseline: 1100},
+		{name: "above baseline", memory: 1700, baseline: 1100, breached: true},
+		{name: "above absolute threshold", memory: 5000, breached: true},
+		{name: "two weeks", memory: 1500, weeks: 2, baseline: 900, breached: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := watchdogtest.NewFakeClient(tt.memory)
+			fakeClock := watchdogtest.NewFakeClock(now)
+			watchdog := NewWatchdog(client, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 4000,
+				CheckInterval:   time.Minute,
+				Baseline:        BaselineConfig{Percent: 50, Weeks: tt.weeks},
+			}, WithClock(fakeClock), WithStateStore(&readOnlyStore{store}))
+
+			result, _ := watchdog.CheckTarget(context.Background(), "prod/api")
+			if result.Baseline != tt.baseline || result.Breached != tt.breached {
+				t.Errorf("CheckTarget() = %+v, want baseline %v and breached %v", result, tt.baseline, tt.breached)
+			}
+		})
+	}
+}
+
+// readOnlyStore keeps the shared history of a test unchanged by the checks
+type readOnlyStore struct {
+	*MemoryStateStore
+}
+
+func (readOnlyStore) Save(ctx context.Context, record Record) error {
+	return nil
+}
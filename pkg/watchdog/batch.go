@@ -0,0 +1,56 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+)
+
+// Whether the pods of Jobs, including those of CronJobs, count toward the
+// memory usage of a target in the namespace scope. Unset, they are left out
+// when the owners of pods are read with WithPodOwners, as the command does,
+// and counted otherwise.
+const (
+	// BatchPodsExclude leaves them out, which requires WithPodOwners
+	BatchPodsExclude = "exclude"
+	// BatchPodsInclude counts them like the other pods of the namespace
+	BatchPodsInclude = "include"
+)
+
+// PodOwners reports the kind of the controller owning each pod of a
+// namespace, so that batch pods can be left out of the namespace scope
+type PodOwners interface {
+	PodOwnerKinds(ctx context.Context, namespace string) (map[string]string, error)
+}
+
+// validBatchPods reports whether batchPods is empty or one of the batch pod
+// settings
+func validBatchPods(batchPods string) bool {
+	return batchPods == "" || batchPods == BatchPodsExclude || batchPods == BatchPodsInclude
+}
+
+// checkBatchPods fails for a target of the namespace scope excluding batch
+// pods explicitly when no PodOwners was registered with WithPodOwners, as
+// they would be counted regardless
+func (w *Watchdog) checkBatchPods(target Target) error {
+	namespaceScope := target.Scope == "" || target.Scope == ScopeNamespace
+	if namespaceScope && target.BatchPods == BatchPodsExclude && w.podOwners == nil {
+		return fmt.Errorf("target '%s' excludes batch pods, which requires the owners of pods (WithPodOwners)", target.Name)
+	}
+	return nil
+}
+
+// batchPods returns the pods of the namespace of target owned by a Job
+func (w *Watchdog) batchPods(ctx context.Context, target Target) (map[string]bool, error) {
+	kinds, err := w.podOwners.PodOwnerKinds(ctx, target.Namespace)
+	if err != nil {
+		return nil, err
+	}
+	batch := make(map[string]bool)
+	for pod, kind := range kinds {
+		// the pods of a CronJob are owned by the Jobs it creates
+		if kind == "Job" {
+			batch[pod] = true
+		}
+	}
+	return batch, nil
+}
@@ -0,0 +1,110 @@
+package watchdog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// podOwners reports fixed owner kinds
+type podOwners map[string]string
+
+func (o podOwners) PodOwnerKinds(ctx context.Context, namespace string) (map[string]string, error) {
+	return o, nil
+}
+
+func TestBatchPods(t *testing.T) {
+	pods := podSource{
+		{Pod: "api-7d9f8b6c5d-x2k4p", Memory: 900},
+		{Pod: "api-7d9f8b6c5d-q8z7m", Memory: 600},
+		{Pod: "backup-28391520-x7k2p", Memory: 3000},
+		{Pod: "redis-0", Memory: 500},
+	}
+	owners := podOwners{
+		"api-7d9f8b6c5d-x2k4p":  "ReplicaSet",
+		"api-7d9f8b6c5d-q8z7m":  "ReplicaSet",
+		"backup-28391520-x7k2p": "Job",
+		"redis-0":               "StatefulSet",
+	}
+	tests := []struct {
+		name      string
+		source    MetricsProvider
+		batchPods string
+		memory    int
+	}{
+		{name: "excluded by default", source: pods, memory: 2000},
+		{name: "excluded", source: pods, batchPods: BatchPodsExclude, memory: 2000},
+		{name: "included", source: pods, batchPods: BatchPodsInclude, memory: 5000},
+		{name: "total only source", source: watchdogtest.NewFakeClient(5000), memory: 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := watchdogtest.NewFakeClient()
+			watchdog := NewWatchdog(tt.source, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 4000,
+				BatchPods:       tt.batchPods,
+				CheckInterval:   time.Minute,
+			}, WithPodOwners(owners))
+
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Err != nil || result.Memory != tt.memory {
+				t.Errorf("CheckTarget() memory = %d, %v, want %d", result.Memory, result.Err, tt.memory)
+			}
+		})
+	}
+}
+
+func TestBatchPodsWithoutOwners(t *testing.T) {
+	client := watchdogtest.NewFakeClient()
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 4000,
+		BatchPods:       BatchPodsExclude,
+		CheckInterval:   time.Minute,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := watchdog.Run(ctx); err == nil || !strings.Contains(err.Error(), "excludes batch pods") {
+		t.Errorf("Run() error = %v, want the exclusion of batch pods reported", err)
+	}
+	if err := watchdog.AddTarget(Target{Namespace: "prod", DeploymentName: "worker", MemoryThreshold: 4000, BatchPods: BatchPodsExclude}); err == nil {
+		t.Error("AddTarget() error = nil, want the exclusion of batch pods reported")
+	}
+	for _, target := range []Target{
+		{Namespace: "prod", DeploymentName: "web", MemoryThreshold: 4000, BatchPods: BatchPodsInclude},
+		{Namespace: "prod", DeploymentName: "cron", MemoryThreshold: 4000, BatchPods: BatchPodsExclude, Scope: ScopeDeployment},
+	} {
+		if err := watchdog.AddTarget(target); err != nil {
+			t.Errorf("AddTarget(%s) error = %v", target.DeploymentName, err)
+		}
+	}
+
+	// unset, batch pods are counted without the owners of pods
+	pods := podSource{
+		{Pod: "api-7d9f8b6c5d-x2k4p", Memory: 900},
+		{Pod: "backup-28391520-x7k2p", Memory: 3000},
+	}
+	counting := NewWatchdog(pods, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 4000,
+		CheckInterval:   time.Minute,
+	})
+	if err := counting.AddTarget(Target{Namespace: "prod", DeploymentName: "worker", MemoryThreshold: 4000}); err != nil {
+		t.Errorf("AddTarget() error = %v, want batch pods counted when unset", err)
+	}
+	if result, err := counting.CheckTarget(context.Background(), "prod/api"); err != nil || result.Memory != 3900 {
+		t.Errorf("CheckTarget() memory = %d, %v, want the batch pods counted", result.Memory, err)
+	}
+}
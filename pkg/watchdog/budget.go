@@ -0,0 +1,64 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// BudgetConfig configures the restart budget shared by every target. When
+// more than Restarts automated restarts happen within Window, something
+// systemic such as a cluster-wide memory spike is more likely than leaky
+// deployments, so the watchdog stops restarting anything and only reports
+// breaches until it is restarted. Zero Restarts disables the budget.
+type BudgetConfig struct {
+	Restarts int           `yaml:"restarts"`
+	Window   time.Duration `yaml:"window"`
+}
+
+// notifyOnly returns why restarts are disabled, once the restart budget
+// has been exhausted
+func (w *Watchdog) notifyOnly() (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.disabled.IsZero() {
+		return "", false
+	}
+	return fmt.Sprintf("notify-only since %s: more than %d restarts within %s",
+		w.disabled.Format(time.RFC3339), w.config.Budget.Restarts, w.config.Budget.Window), true
+}
+
+// spendBudget records an automated restart of target at now, disabling
+// restarts and paging with an EventBudgetExhausted once the restarts within
+// the budget window exceed it
+func (w *Watchdog) spendBudget(ctx context.Context, target Target, now time.Time, memory int) {
+	if w.config.Budget.Restarts <= 0 {
+		return
+	}
+	w.mu.Lock()
+	recent := w.restarts[:0]
+	for _, t := range w.restarts {
+		if now.Sub(t) < w.config.Budget.Window {
+			recent = append(recent, t)
+		}
+	}
+	w.restarts = append(recent, now)
+	count := len(w.restarts)
+	exhausted := count > w.config.Budget.Restarts && w.disabled.IsZero()
+	if exhausted {
+		w.disabled = now
+	}
+	w.mu.Unlock()
+
+	if !exhausted {
+		return
+	}
+	w.logger.Errorf("%d restarts within %s exceeded the restart budget of %d, switching to notify-only mode",
+		count, w.config.Budget.Window, w.config.Budget.Restarts)
+	w.telemetry.Set(telemetry.MetricNotifyOnly, 1)
+	event := w.event(EventBudgetExhausted, target, memory, nil)
+	event.Reason = fmt.Sprintf("%d restarts within %s", count, w.config.Budget.Window)
+	w.notify(ctx, event)
+}
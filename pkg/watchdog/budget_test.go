@@ -0,0 +1,68 @@
+package watchdog
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestRestartBudget(t *testing.T) {
+	var exhausted []Event
+	fakeClock := watchdogtest.NewFakeClock(time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC))
+	client := watchdogtest.NewFakeClient(3000)
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Budget:          BudgetConfig{Restarts: 2, Window: 10 * time.Minute},
+		Targets: []Target{
+			{Name: "api", DeploymentName: "api"},
+			{Name: "web", DeploymentName: "web"},
+			{Name: "jobs", DeploymentName: "jobs"},
+		},
+	}, WithClock(fakeClock), WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+		if event.Type == EventBudgetExhausted {
+			exhausted = append(exhausted, event)
+		}
+		return nil
+	})))
+	// the exhausted budget pages on-call even for a silenced target
+	if _, err := watchdog.Silence(context.Background(), "jobs", time.Hour, "maintenance"); err != nil {
+		t.Fatal(err)
+	}
+
+	// two restarts in the window are within the budget, even after an
+	// older one has left it
+	for _, name := range []string{"api", "web", "api"} {
+		fakeClock.Advance(6 * time.Minute)
+		if result, err := watchdog.CheckTarget(context.Background(), name); err != nil || result.Action != string(EventRestart) {
+			t.Fatalf("CheckTarget(%s) = %+v, %v, want a restart", name, result, err)
+		}
+	}
+	if len(exhausted) != 0 {
+		t.Fatalf("events = %+v, want the budget to last", exhausted)
+	}
+
+	fakeClock.Advance(time.Minute)
+	if _, err := watchdog.CheckTarget(context.Background(), "jobs"); err != nil {
+		t.Fatal(err)
+	}
+	if len(exhausted) != 1 || exhausted[0].Target.Name != "jobs" || exhausted[0].Reason != "3 restarts within 10m0s" {
+		t.Fatalf("events = %+v, want the budget exhausted by jobs", exhausted)
+	}
+
+	fakeClock.Advance(time.Hour)
+	result, err := watchdog.CheckTarget(context.Background(), "web")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if result.Action != "" || !strings.HasPrefix(result.Disabled, "notify-only since") {
+		t.Errorf("CheckTarget() = %+v, want a breach reported in notify-only mode", result)
+	}
+	if restarts := client.Restarts(); len(restarts) != 4 {
+		t.Errorf("restarts = %+v, want none after the budget was exhausted", restarts)
+	}
+}
@@ -0,0 +1,92 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// CircuitConfig configures the circuit breaker of the restarts of each
+// target. Once Failures restarts of a target have failed in a row, its
+// circuit opens: the target is still checked, but breaches don't restart it
+// anymore. Every Probe, one restart is let through; the circuit closes when
+// it succeeds, and stays open for another Probe when it fails. Zero
+// Failures disables the circuit breaker.
+type CircuitConfig struct {
+	Failures int           `yaml:"failures"`
+	Probe    time.Duration `yaml:"probe"`
+}
+
+// circuitOpen returns why the restarts of the named target are held back,
+// when its circuit is open and isn't due a probe. A due probe is let
+// through and the next one scheduled.
+func (w *Watchdog) circuitOpen(name string, now time.Time) (string, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	loop, exists := w.targets[name]
+	if !exists || loop.circuitAt.IsZero() {
+		return "", false
+	}
+	if now.Sub(loop.circuitAt) >= w.config.Circuit.Probe {
+		loop.circuitAt = now
+		return "", false
+	}
+	return fmt.Sprintf("circuit open after %d failed restarts, next probe at %s",
+		loop.failures, loop.circuitAt.Add(w.config.Circuit.Probe).Format(time.RFC3339)), true
+}
+
+// restartFailed counts a failed restart of target, opening its circuit once
+// the failures in a row reach CircuitConfig.Failures
+func (w *Watchdog) restartFailed(ctx context.Context, target Target, now time.Time, memory int) {
+	if w.config.Circuit.Failures <= 0 {
+		return
+	}
+	w.mu.Lock()
+	loop, exists := w.targets[target.Name]
+	if !exists {
+		w.mu.Unlock()
+		return
+	}
+	loop.failures++
+	failures, opened := loop.failures, false
+	if failures >= w.config.Circuit.Failures {
+		opened = loop.circuitAt.IsZero()
+		loop.circuitAt = now
+	}
+	w.mu.Unlock()
+
+	if !opened {
+		return
+	}
+	w.logger.Errorf("Opening the circuit of target '%s' after %d failed restarts, probing again every %s",
+		target.Name, failures, w.config.Circuit.Probe)
+	w.telemetry.Set(telemetry.MetricCircuitOpen, 1, "target", target.Name)
+	event := w.event(EventCircuitOpen, target, memory, nil)
+	event.Reason = fmt.Sprintf("%d failed restarts", failures)
+	w.notify(ctx, event)
+}
+
+// restartSucceeded resets the failures of target, closing its circuit if
+// it was open
+func (w *Watchdog) restartSucceeded(ctx context.Context, target Target, memory int) {
+	if w.config.Circuit.Failures <= 0 {
+		return
+	}
+	w.mu.Lock()
+	loop, exists := w.targets[target.Name]
+	closed := exists && !loop.circuitAt.IsZero()
+	if exists {
+		loop.failures = 0
+		loop.circuitAt = time.Time{}
+	}
+	w.mu.Unlock()
+
+	if !closed {
+		return
+	}
+	w.logger.Infof("Closing the circuit of target '%s' after a successful restart", target.Name)
+	w.telemetry.Set(telemetry.MetricCircuitOpen, 0, "target", target.Name)
+	w.notify(ctx, w.event(EventCircuitClosed, target, memory, nil))
+}
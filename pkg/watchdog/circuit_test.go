@@ -0,0 +1,80 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	var events []EventType
+	fakeClock := watchdogtest.NewFakeClock(time.Date(2024, 3, 4, 10, 0, 0, 0, time.UTC))
+	client := watchdogtest.NewFakeClient(3000)
+	client.FailRestarts(errors.New("forbidden"), 3)
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Circuit:         CircuitConfig{Failures: 2, Probe: 10 * time.Minute},
+	}, WithClock(fakeClock), WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+		if event.Type != EventBreach {
+			events = append(events, event.Type)
+		}
+		return nil
+	})))
+
+	steps := []struct {
+		advance time.Duration
+		circuit bool
+		failed  bool
+		events  []EventType
+	}{
+		{failed: true, events: []EventType{EventRestartFailed}},
+		{advance: time.Minute, failed: true, events: []EventType{EventRestartFailed, EventCircuitOpen}},
+		{advance: time.Minute, circuit: true, events: []EventType{EventSuppressed}},
+		// the failed probe keeps the circuit open without opening it again
+		{advance: 10 * time.Minute, failed: true, events: []EventType{EventRestartFailed}},
+		{advance: time.Minute, circuit: true, events: []EventType{EventSuppressed}},
+		{advance: 10 * time.Minute, events: []EventType{EventRestart, EventCircuitClosed}},
+	}
+	for i, step := range steps {
+		fakeClock.Advance(step.advance)
+		events = nil
+		result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if (result.Circuit != "") != step.circuit {
+			t.Errorf("step %d: Circuit = %q, want open %v", i, result.Circuit, step.circuit)
+		}
+		if (result.Err != nil) != step.failed {
+			t.Errorf("step %d: Err = %v, want failed %v", i, result.Err, step.failed)
+		}
+		if !equalEvents(events, step.events) {
+			t.Errorf("step %d: events = %v, want %v", i, events, step.events)
+		}
+		open := watchdog.Status()[0].CircuitOpenSince != nil
+		if wantOpen := i >= 1 && i < len(steps)-1; open != wantOpen {
+			t.Errorf("step %d: circuit open in status = %v, want %v", i, open, wantOpen)
+		}
+	}
+	if restarts := client.Restarts(); len(restarts) != 1 {
+		t.Errorf("restarts = %+v, want the successful probe", restarts)
+	}
+}
+
+func equalEvents(got, want []EventType) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,65 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+)
+
+// Cluster selects the cluster of a target other than the one of the
+// configuration: either a context of the kubeconfig, or an API server
+// reached with inline credentials. CAFile defaults to the system CAs, and
+// Token may refer to a file or to a Kubernetes Secret of the cluster of
+// the configuration. The Token is never serialized to JSON.
+type Cluster struct {
+	Context string `yaml:"context" json:"context,omitempty"`
+	Server  string `yaml:"server" json:"server,omitempty"`
+	CAFile  string `yaml:"ca_file" json:"ca_file,omitempty"`
+	Token   string `yaml:"token" json:"-"`
+}
+
+// String names the cluster in logs and messages
+func (c Cluster) String() string {
+	if c.Server != "" {
+		return "server " + c.Server
+	}
+	return "context " + c.Context
+}
+
+// validate checks that the cluster is selected one way only
+func (c Cluster) validate() error {
+	switch {
+	case c.Context != "" && c.Server != "":
+		return errors.New("cluster sets both a context and a server; use one of them")
+	case c.Context == "" && c.Server == "":
+		return errors.New("cluster sets neither a context nor a server")
+	case c.Context != "" && (c.CAFile != "" || c.Token != ""):
+		return errors.New("cluster sets ca_file or token with a context, which has its own credentials")
+	case c.Server != "":
+		if u, err := url.Parse(c.Server); err != nil || u.Scheme != "https" || u.Host == "" {
+			return fmt.Errorf("cluster server '%s' is invalid, want an https URL", c.Server)
+		}
+	}
+	return nil
+}
+
+// clusterKey is the context key of the cluster of a target
+type clusterKey struct{}
+
+// ContextWithCluster returns a copy of ctx carrying the cluster of a
+// target, so the clients of the Kubernetes API it is passed to reach that
+// cluster. A nil cluster is the one of the configuration.
+func ContextWithCluster(ctx context.Context, cluster *Cluster) context.Context {
+	return context.WithValue(ctx, clusterKey{}, cluster)
+}
+
+// ClusterFromContext returns the cluster ctx carries, nil for the one of
+// the configuration
+func ClusterFromContext(ctx context.Context) *Cluster {
+	c, _ := ctx.Value(clusterKey{}).(*Cluster)
+	if c == nil || *c == (Cluster{}) {
+		return nil
+	}
+	return c
+}
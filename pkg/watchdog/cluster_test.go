@@ -0,0 +1,59 @@
+package watchdog
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+)
+
+// clusterRecorder records the cluster of the context of each reading and
+// restart, by namespace
+type clusterRecorder struct {
+	mu       sync.Mutex
+	readings map[string]string
+	restarts map[string]string
+}
+
+func (c *clusterRecorder) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.readings[namespace] = clusterOf(ctx)
+	return 3000, nil
+}
+
+func (c *clusterRecorder) RestartDeployment(ctx context.Context, namespace, deployment string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.restarts[namespace] = clusterOf(ctx)
+	return nil
+}
+
+func clusterOf(ctx context.Context) string {
+	if cluster := ClusterFromContext(ctx); cluster != nil {
+		return cluster.String()
+	}
+	return "default"
+}
+
+func TestCheckCarriesCluster(t *testing.T) {
+	recorder := &clusterRecorder{readings: make(map[string]string), restarts: make(map[string]string)}
+	watchdog := NewWatchdog(recorder, recorder, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets: []Target{
+			{Name: "prod", Namespace: "prod", DeploymentName: "api"},
+			{Name: "staging", Namespace: "staging", DeploymentName: "api", Cluster: &Cluster{Context: "staging"}},
+			{Name: "edge", Namespace: "edge", DeploymentName: "api", Cluster: &Cluster{Server: "https://edge.example.com"}},
+		},
+	})
+	watchdog.CheckOnce(context.Background())
+
+	expected := map[string]string{"prod": "default", "staging": "context staging", "edge": "server https://edge.example.com"}
+	for namespace, cluster := range expected {
+		if recorder.readings[namespace] != cluster || recorder.restarts[namespace] != cluster {
+			t.Errorf("namespace %s read in %q and restarted in %q, want %q", namespace,
+				recorder.readings[namespace], recorder.restarts[namespace], cluster)
+		}
+	}
+}
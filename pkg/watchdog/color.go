@@ -0,0 +1,37 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+)
+
+// ColorResolver finds the active deployment of a blue/green pair: the one
+// whose pods are selected by service, along with the value of its color
+// label
+type ColorResolver interface {
+	ActiveDeployment(ctx context.Context, namespace, service, label string) (deployment, color string, err error)
+}
+
+// activeColor returns target with its deployment replaced by the active
+// deployment of its blue/green pair, or target unchanged when it has no
+// ColorService. Resolving it at each check means the idle color is never
+// measured or restarted, so the two colors can't be restarted together.
+func (w *Watchdog) activeColor(ctx context.Context, target Target) (Target, error) {
+	if target.ColorService == "" {
+		return target, nil
+	}
+	if w.colors == nil {
+		return target, fmt.Errorf("%w: no color resolver to find the active deployment", ErrMetricsUnavailable)
+	}
+
+	colorCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+	deployment, color, err := w.colors.ActiveDeployment(colorCtx, target.Namespace, target.ColorService, target.ColorLabel)
+	cancel()
+	if err != nil {
+		return target, err
+	}
+
+	w.logger.Debugf("Active color of target '%s' is '%s', deployment '%s'", target.Name, color, deployment)
+	target.DeploymentName = deployment
+	return target, nil
+}
@@ -0,0 +1,67 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// activeColor reports a fixed active deployment for every service
+type activeColor string
+
+func (c activeColor) ActiveDeployment(ctx context.Context, namespace, service, label string) (string, string, error) {
+	return string(c), "green", nil
+}
+
+func TestBlueGreen(t *testing.T) {
+	tests := []struct {
+		name    string
+		colors  ColorResolver
+		wantErr error
+	}{
+		{name: "active color", colors: activeColor("web-green")},
+		{name: "no color resolver", wantErr: ErrMetricsUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []Option
+			if tt.colors != nil {
+				options = append(options, WithColorResolver(tt.colors))
+			}
+			client := watchdogtest.NewFakeClient(6000)
+			watchdog := NewWatchdog(client, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "web",
+				MemoryThreshold: 5000,
+				ColorService:    "web",
+				ColorLabel:      "color",
+				CheckInterval:   time.Minute,
+			}, options...)
+
+			result, err := watchdog.CheckTarget(context.Background(), "prod/web")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !errors.Is(result.Err, tt.wantErr) || (tt.wantErr == nil && result.Err != nil) {
+				t.Fatalf("CheckTarget() error = %v, want %v", result.Err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				if len(client.Restarts()) != 0 {
+					t.Errorf("restarts = %v, want none", client.Restarts())
+				}
+				return
+			}
+			want := []watchdogtest.Restart{{Namespace: "prod", Deployment: "web-green"}}
+			if got := client.Restarts(); len(got) != 1 || got[0] != want[0] {
+				t.Errorf("restarts = %v, want %v", got, want)
+			}
+			if result.Target.DeploymentName != "web-green" {
+				t.Errorf("CheckTarget() deployment = %q, want web-green", result.Target.DeploymentName)
+			}
+		})
+	}
+}
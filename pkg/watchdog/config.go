@@ -0,0 +1,499 @@
+package watchdog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/yaml"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// Config represents the watchdog configuration
+type Config struct {
+	Namespace       string                 `yaml:"namespace"`
+	DeploymentName  string                 `yaml:"deployment"`
+	MemoryThreshold int                    `yaml:"memory_threshold"`
+	ReplicaMemory   int                    `yaml:"memory_per_replica"`
+	BaseMemory      int                    `yaml:"memory_base"`
+	PodThreshold    int                    `yaml:"pod_threshold"`
+	SwapThreshold   int                    `yaml:"swap_threshold"`
+	CPUThrottling   int                    `yaml:"cpu_throttling"`
+	WarmUp          time.Duration          `yaml:"warm_up"`
+	Scope           string                 `yaml:"scope"`
+	BatchPods       string                 `yaml:"batch_pods"`
+	Aggregation     string                 `yaml:"aggregation"`
+	RecoveryPercent float64                `yaml:"recovery_percent"`
+	KubectlPath     string                 `yaml:"kubectl_path"`
+	KubeContext     string                 `yaml:"kube_context"`
+	Verbose         bool                   `yaml:"verbose"`
+	Log             LogConfig              `yaml:"log"`
+	CheckInterval   time.Duration          `yaml:"check_interval"`
+	MetricsCacheTTL time.Duration          `yaml:"metrics_cache_ttl"`
+	MetricsTimeout  time.Duration          `yaml:"metrics_timeout"`
+	RestartTimeout  time.Duration          `yaml:"restart_timeout"`
+	RestartStrategy string                 `yaml:"restart_strategy"`
+	GracePeriod     time.Duration          `yaml:"grace_period"`
+	DeleteDelay     time.Duration          `yaml:"delete_delay"`
+	SurgeReplicas   int                    `yaml:"surge_replicas"`
+	Rollback        bool                   `yaml:"rollback"`
+	ColorService    string                 `yaml:"color_service"`
+	ColorLabel      string                 `yaml:"color_label"`
+	AvailableGuard  bool                   `yaml:"available_guard"`
+	KubeQPS         float64                `yaml:"kube_qps"`
+	KubeBurst       int                    `yaml:"kube_burst"`
+	SecretRefresh   time.Duration          `yaml:"secret_refresh"`
+	StateFile       string                 `yaml:"state_file"`
+	Timezone        string                 `yaml:"timezone"`
+	Outliers        OutlierConfig          `yaml:"outliers"`
+	Circuit         CircuitConfig          `yaml:"circuit_breaker"`
+	Budget          BudgetConfig           `yaml:"restart_budget"`
+	Tenants         TenantConfig           `yaml:"tenants"`
+	Proxy           ProxyConfig            `yaml:"proxy"`
+	EKS             EKSConfig              `yaml:"eks"`
+	GKE             GKEConfig              `yaml:"gke"`
+	AKS             AKSConfig              `yaml:"aks"`
+	OIDC            OIDCConfig             `yaml:"oidc"`
+	Baseline        BaselineConfig         `yaml:"baseline"`
+	Freeze          FreezeConfig           `yaml:"freeze"`
+	Source          SourceConfig           `yaml:"source"`
+	Metrics         telemetry.Config       `yaml:"metrics"`
+	Dashboard       DashboardConfig        `yaml:"dashboard"`
+	Admin           AdminConfig            `yaml:"admin"`
+	HTTP            HTTPConfig             `yaml:"http"`
+	GRPC            GRPCConfig             `yaml:"grpc"`
+	Notifications   NotificationsConfig    `yaml:"notifications"`
+	Heartbeat       HeartbeatConfig        `yaml:"heartbeat"`
+	SelfMonitor     SelfMonitorConfig      `yaml:"self_monitor"`
+	StatsD          telemetry.StatsDConfig `yaml:"statsd"`
+	Targets         []Target               `yaml:"targets"`
+}
+
+// recoveryThreshold returns the usage under which an open breach of a
+// target with the given threshold is resolved: RecoveryPercent of the
+// threshold, or the threshold itself when unset
+func (c Config) recoveryThreshold(threshold int) int {
+	if c.RecoveryPercent <= 0 || c.RecoveryPercent >= 100 {
+		return threshold
+	}
+	return int(float64(threshold) * c.RecoveryPercent / 100)
+}
+
+// LogConfig configures the logs. Level is a default level and per-component
+// overrides, such as "info,metrics=debug". With a File, logs are written to
+// it instead of stdout; the file is rotated once it grows beyond MaxSize megabytes or gets older than
+// MaxAge, keeping MaxBackups rotated files, gzip-compressed with Compress.
+type LogConfig struct {
+	Level      string        `yaml:"level"`
+	File       string        `yaml:"file"`
+	MaxSize    int           `yaml:"max_size"`
+	MaxAge     time.Duration `yaml:"max_age"`
+	MaxBackups int           `yaml:"max_backups"`
+	Compress   bool          `yaml:"compress"`
+	// Sinks replace the text output of File or stdout when given
+	Sinks []LogSinkConfig `yaml:"sinks"`
+}
+
+// LogSinkConfig configures one of several simultaneous log outputs. Format
+// is text, json or syslog. Text and JSON are written to Output, stdout,
+// stderr or a file rotated like LogConfig.File; syslog is sent to the
+// daemon at Address, or to the local one. An empty Level uses the level of
+// LogConfig.
+type LogSinkConfig struct {
+	Format  string `yaml:"format"`
+	Output  string `yaml:"output"`
+	Address string `yaml:"address"`
+	Level   string `yaml:"level"`
+}
+
+// DashboardConfig configures the read-only web dashboard, served under
+// Path on the port of the metrics endpoint
+type DashboardConfig struct {
+	Enabled bool   `yaml:"enabled"`
+	Path    string `yaml:"path"`
+}
+
+// AdminConfig configures the admin API, served on the port of the metrics
+// endpoint, whose endpoints act on the targets. Manual restarts and
+// silences require RestartToken as a bearer token and are refused without
+// one.
+type AdminConfig struct {
+	Enabled      bool   `yaml:"enabled"`
+	RestartToken string `yaml:"restart_token"`
+}
+
+// HTTPConfig secures the HTTP server of the metrics endpoint, dashboard and
+// admin API. With a BearerToken or BearerTokenFile, every request but those
+// to the AuthExempt paths must carry the token, or the restart token of the
+// admin API.
+type HTTPConfig struct {
+	TLS             TLSConfig `yaml:"tls"`
+	BearerToken     string    `yaml:"bearer_token"`
+	BearerTokenFile string    `yaml:"bearer_token_file"`
+	AuthExempt      []string  `yaml:"auth_exempt"`
+}
+
+// GRPCConfig configures the gRPC control and status API. It is only served
+// over TLS, from the given certificate and key files.
+type GRPCConfig struct {
+	Enabled bool      `yaml:"enabled"`
+	Port    int       `yaml:"port"`
+	TLS     TLSConfig `yaml:"tls"`
+}
+
+// HeartbeatConfig configures a dead man's switch: URL is pinged after
+// successful checks, at most once per Interval, so an external monitor such
+// as healthchecks.io alerts when the pings stop
+type HeartbeatConfig struct {
+	URL      string        `yaml:"url"`
+	Interval time.Duration `yaml:"interval"`
+}
+
+// SelfMonitorConfig configures the detection of stuck target loops. A loop
+// is stalled when it hasn't finished a check in StallIntervals check
+// intervals plus the metrics and restart timeouts; with ExitOnStall, the
+// watchdog then exits so that it is restarted.
+type SelfMonitorConfig struct {
+	StallIntervals int  `yaml:"stall_intervals"`
+	ExitOnStall    bool `yaml:"exit_on_stall"`
+}
+
+// TLSConfig holds the PEM certificate and private key files of a server.
+// With a ClientCAFile, clients must present a certificate signed by one of
+// the CAs of the bundle.
+type TLSConfig struct {
+	CertFile     string `yaml:"cert_file"`
+	KeyFile      string `yaml:"key_file"`
+	ClientCAFile string `yaml:"client_ca_file"`
+}
+
+// ProxyConfig configures the outbound connections of the watchdog, for
+// clusters behind a proxy that may intercept TLS. The requests to metric
+// sources, notifiers and the Kubernetes API go through the proxy at URL,
+// except for the comma-separated hosts, domains and CIDRs of NoProxy. The
+// certificates of the PEM bundle CAFile are trusted on top of the system
+// ones by the HTTP clients, and KubeCAFile replaces the certificate
+// authority of the kubeconfig for the Kubernetes API.
+type ProxyConfig struct {
+	URL        string `yaml:"url"`
+	NoProxy    string `yaml:"no_proxy"`
+	CAFile     string `yaml:"ca_file"`
+	KubeCAFile string `yaml:"kube_ca_file"`
+}
+
+// EKSConfig configures the IAM authentication of kubectl to the EKS
+// cluster named Cluster, with tokens generated from the AWS credentials of
+// the environment like aws-iam-authenticator does, so the kubeconfig needs
+// no token or exec plugin. Region selects the STS endpoint the tokens are
+// signed for, the one of the environment when empty.
+type EKSConfig struct {
+	Cluster string `yaml:"cluster"`
+	Region  string `yaml:"region"`
+}
+
+// GKEConfig configures the Google authentication of kubectl to a GKE
+// cluster. When Enabled, kubectl authenticates with the access tokens of
+// the Application Default Credentials, such as those of Workload Identity,
+// so the kubeconfig needs no token or gke-gcloud-auth-plugin.
+type GKEConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// AKSConfig configures the Azure AD authentication of kubectl to an AKS
+// cluster. When Enabled, kubectl authenticates with the access tokens of the
+// service principal, workload identity or managed identity of the
+// environment, so the kubeconfig needs no token or kubelogin.
+type AKSConfig struct {
+	Enabled bool `yaml:"enabled"`
+}
+
+// OIDCConfig configures the authentication of kubectl to a cluster whose
+// API server trusts the OpenID Connect provider at IssuerURL. kubectl
+// authenticates with the ID tokens of the client ClientID, refreshed with
+// RefreshToken before they expire, so a long-running watchdog doesn't
+// depend on the expiring token of a kubeconfig. ClientSecret and
+// RefreshToken may refer to a file.
+type OIDCConfig struct {
+	IssuerURL    string `yaml:"issuer_url"`
+	ClientID     string `yaml:"client_id"`
+	ClientSecret string `yaml:"client_secret"`
+	RefreshToken string `yaml:"refresh_token"`
+}
+
+// Target represents a single deployment watched by the watchdog. Scope is
+// what its threshold is compared with: the memory usage of the whole
+// namespace (ScopeNamespace, the default), of the deployment's pods
+// (ScopeDeployment) or of its largest pod (ScopePod). BatchPods is whether
+// the pods of Jobs count toward the namespace; unset, they are left out
+// only with WithPodOwners.
+// Aggregation is the function applied across the pods in scope, the sum by
+// default, or the largest pod for ScopePod; see AggregateSum.
+//
+// A ReplicaMemory in Mi replaces MemoryThreshold with BaseMemory plus
+// ReplicaMemory per ready replica, so the threshold follows the replica
+// count. A PodThreshold also breaches the target when the largest pod of
+// its deployment reaches it, whatever the aggregate, a SwapThreshold when
+// the swap usage of the namespace does, and CPUThrottling when the
+// percentage of throttled CPU periods does. Pods started less than WarmUp
+// ago are left out of the readings.
+//
+// Sources optionally names an ordered chain of metric sources, registered
+// with WithSource, tried in turn until one succeeds. With a Quorum, every
+// source is read instead and the target only breaches when at least Quorum
+// of them report a breach. Schedules vary the threshold by time of day, read in
+// the IANA Timezone of the target. A Cron expression restricts checks to
+// the minutes it matches instead of running them every CheckInterval.
+// RunbookURL is linked from the notifications of the target.
+// RestartStrategy picks how the default action restarts the deployment,
+// RestartRollout, RestartScale or RestartDelete, which gives each pod
+// GracePeriod to terminate and waits DeleteDelay between pods. With
+// SurgeReplicas, that many replicas are added and made ready before the
+// restart and removed once it has rolled out. With Rollback, set on the
+// target or at the top level, a rolling restart has to roll out before
+// RestartTimeout, and is undone with EventRollback when it doesn't.
+//
+// A target with a ColorService manages a blue/green pair: at each check,
+// DeploymentName is replaced by the deployment whose pods the Service
+// selects, told apart by its ColorLabel, and only that one is measured and
+// restarted.
+//
+// With AvailableGuard, set on the target or at the top level, a breaching
+// target whose deployment doesn't have all its desired replicas available
+// is escalated with EventEscalated instead of restarted.
+//
+// A target with a Cluster lives in another cluster than the one of the
+// configuration, such as the staging context of a kubeconfig also holding
+// the production one. Its checks and restarts carry the cluster in their
+// context, see ContextWithCluster.
+type Target struct {
+	Name            string              `yaml:"name" json:"name"`
+	Namespace       string              `yaml:"namespace" json:"namespace"`
+	DeploymentName  string              `yaml:"deployment" json:"deployment"`
+	MemoryThreshold int                 `yaml:"memory_threshold" json:"memory_threshold"`
+	ReplicaMemory   int                 `yaml:"memory_per_replica" json:"memory_per_replica,omitempty"`
+	BaseMemory      int                 `yaml:"memory_base" json:"memory_base,omitempty"`
+	PodThreshold    int                 `yaml:"pod_threshold" json:"pod_threshold,omitempty"`
+	SwapThreshold   int                 `yaml:"swap_threshold" json:"swap_threshold,omitempty"`
+	CPUThrottling   int                 `yaml:"cpu_throttling" json:"cpu_throttling,omitempty"`
+	WarmUp          time.Duration       `yaml:"warm_up" json:"warm_up,omitempty"`
+	Scope           string              `yaml:"scope" json:"scope,omitempty"`
+	BatchPods       string              `yaml:"batch_pods" json:"batch_pods,omitempty"`
+	Aggregation     string              `yaml:"aggregation" json:"aggregation,omitempty"`
+	CheckInterval   time.Duration       `yaml:"check_interval" json:"check_interval"`
+	Sources         []string            `yaml:"sources" json:"sources,omitempty"`
+	Quorum          int                 `yaml:"quorum" json:"quorum,omitempty"`
+	Schedules       []ThresholdSchedule `yaml:"schedules" json:"schedules,omitempty"`
+	Timezone        string              `yaml:"timezone" json:"timezone,omitempty"`
+	Cron            string              `yaml:"cron" json:"cron,omitempty"`
+	RunbookURL      string              `yaml:"runbook_url" json:"runbook_url,omitempty"`
+	RestartStrategy string              `yaml:"restart_strategy" json:"restart_strategy,omitempty"`
+	GracePeriod     time.Duration       `yaml:"grace_period" json:"grace_period,omitempty"`
+	DeleteDelay     time.Duration       `yaml:"delete_delay" json:"delete_delay,omitempty"`
+	SurgeReplicas   int                 `yaml:"surge_replicas" json:"surge_replicas,omitempty"`
+	Rollback        bool                `yaml:"rollback" json:"rollback,omitempty"`
+	ColorService    string              `yaml:"color_service" json:"color_service,omitempty"`
+	ColorLabel      string              `yaml:"color_label" json:"color_label,omitempty"`
+	AvailableGuard  bool                `yaml:"available_guard" json:"available_guard,omitempty"`
+	Cluster         *Cluster            `yaml:"cluster" json:"cluster,omitempty"`
+}
+
+// ResolveTargets returns the configured targets with unset fields inherited
+// from the top-level configuration. The top-level deployment is used as the
+// only target when no explicit targets are configured.
+func (c Config) ResolveTargets() []Target {
+	targets := c.Targets
+	if len(targets) == 0 && c.DeploymentName != "" {
+		targets = []Target{{DeploymentName: c.DeploymentName}}
+	}
+
+	resolved := make([]Target, 0, len(targets))
+	for _, t := range targets {
+		resolved = append(resolved, c.resolveTarget(t))
+	}
+	return resolved
+}
+
+// resolveTarget fills the unset fields of a target from the configuration
+func (c Config) resolveTarget(t Target) Target {
+	if t.Namespace == "" {
+		t.Namespace = c.Namespace
+	}
+	if t.MemoryThreshold == 0 {
+		t.MemoryThreshold = c.MemoryThreshold
+	}
+	if t.CheckInterval == 0 {
+		t.CheckInterval = c.CheckInterval
+	}
+	if t.Timezone == "" {
+		t.Timezone = c.Timezone
+	}
+	if t.Scope == "" {
+		t.Scope = c.Scope
+	}
+	if t.BatchPods == "" {
+		t.BatchPods = c.BatchPods
+	}
+	if t.RestartStrategy == "" {
+		t.RestartStrategy = c.RestartStrategy
+	}
+	if t.GracePeriod == 0 {
+		t.GracePeriod = c.GracePeriod
+	}
+	if t.DeleteDelay == 0 {
+		t.DeleteDelay = c.DeleteDelay
+	}
+	if t.SurgeReplicas == 0 {
+		t.SurgeReplicas = c.SurgeReplicas
+	}
+	if t.ColorService == "" {
+		t.ColorService = c.ColorService
+	}
+	if t.ColorLabel == "" {
+		t.ColorLabel = c.ColorLabel
+	}
+	t.AvailableGuard = t.AvailableGuard || c.AvailableGuard
+	t.Rollback = t.Rollback || c.Rollback
+	if t.ReplicaMemory == 0 {
+		t.ReplicaMemory = c.ReplicaMemory
+	}
+	if t.BaseMemory == 0 {
+		t.BaseMemory = c.BaseMemory
+	}
+	if t.PodThreshold == 0 {
+		t.PodThreshold = c.PodThreshold
+	}
+	if t.SwapThreshold == 0 {
+		t.SwapThreshold = c.SwapThreshold
+	}
+	if t.CPUThrottling == 0 {
+		t.CPUThrottling = c.CPUThrottling
+	}
+	if t.WarmUp == 0 {
+		t.WarmUp = c.WarmUp
+	}
+	if t.Aggregation == "" {
+		t.Aggregation = c.Aggregation
+	}
+	if t.Name == "" {
+		t.Name = t.Namespace + "/" + t.DeploymentName
+	}
+	return t
+}
+
+// locations caches the time zones loaded by LoadLocation
+var locations sync.Map
+
+// LoadLocation returns the IANA time zone name, or the local time zone of
+// the system when name is empty
+func LoadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.Local, nil
+	}
+	if loc, ok := locations.Load(name); ok {
+		return loc.(*time.Location), nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("invalid timezone '%s': %v", name, err)
+	}
+	locations.Store(name, loc)
+	return loc, nil
+}
+
+// location returns the time zone of the target. An invalid timezone falls
+// back to UTC; AddTarget rejects them.
+func (t Target) location() *time.Location {
+	loc, err := LoadLocation(t.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// LoadConfigFile reads the YAML configuration file on top of the given
+// defaults. Settings missing from the file keep their default value.
+// References to environment variables in the file are expanded first, see
+// ExpandEnv.
+func LoadConfigFile(path string, defaults Config) (Config, error) {
+	return loadConfigFile(path, defaults, yaml.Unmarshal)
+}
+
+// LoadConfigFileStrict reads the configuration file like LoadConfigFile, but
+// fails with a wrapped *yaml.UnknownFieldsError when the file has settings
+// the watchdog doesn't know, such as misspelled ones
+func LoadConfigFileStrict(path string, defaults Config) (Config, error) {
+	return loadConfigFile(path, defaults, yaml.UnmarshalStrict)
+}
+
+func loadConfigFile(path string, defaults Config, unmarshal func([]byte, interface{}) error) (Config, error) {
+	config := defaults
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return config, err
+	}
+	expanded, err := ExpandEnv(string(data), os.LookupEnv)
+	if err != nil {
+		return config, fmt.Errorf("%s: %v", path, err)
+	}
+	if err := unmarshal([]byte(expanded), &config); err != nil {
+		return config, fmt.Errorf("%s: %w", path, err)
+	}
+	return config, nil
+}
+
+// ExpandEnv replaces ${VAR} with the value of the variable VAR and
+// ${VAR:-default} with the value of VAR, or default when VAR is unset or
+// empty. An unset variable without a default expands to the empty string.
+// $${ is kept as a literal ${; a $ not followed by { is left alone, so
+// passwords and expressions containing $ don't need escaping.
+func ExpandEnv(text string, lookup func(string) (string, bool)) (string, error) {
+	var b strings.Builder
+	pos := 0
+	for {
+		i := strings.Index(text[pos:], "${")
+		if i < 0 {
+			b.WriteString(text[pos:])
+			return b.String(), nil
+		}
+		start := pos + i
+		b.WriteString(text[pos:start])
+		if start > 0 && text[start-1] == '$' {
+			b.WriteString("{")
+			pos = start + 2
+			continue
+		}
+		line := strings.Count(text[:start], "\n") + 1
+		end := strings.IndexByte(text[start:], '}')
+		if end < 0 {
+			return "", fmt.Errorf("line %d: unterminated variable reference", line)
+		}
+		name, fallback, hasDefault := text[start+2:start+end], "", false
+		if sep := strings.Index(name, ":-"); sep >= 0 {
+			name, fallback, hasDefault = name[:sep], name[sep+2:], true
+		}
+		if !isEnvName(name) {
+			return "", fmt.Errorf("line %d: invalid variable name '%s'", line, name)
+		}
+		value, ok := lookup(name)
+		if hasDefault && (!ok || value == "") {
+			value = fallback
+		}
+		b.WriteString(value)
+		pos = start + end + 1
+	}
+}
+
+func isEnvName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, c := range name {
+		if c == '_' || c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || i > 0 && c >= '0' && c <= '9' {
+			continue
+		}
+		return false
+	}
+	return true
+}
@@ -0,0 +1,230 @@
+package watchdog
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestConfigTargets(t *testing.T) {
+	config := Config{
+		Namespace:       "default",
+		DeploymentName:  "app",
+		MemoryThreshold: 5000,
+		CheckInterval:   time.Minute,
+	}
+
+	targets := config.ResolveTargets()
+	if len(targets) != 1 {
+		t.Fatalf("ResolveTargets() returned %d targets, want 1", len(targets))
+	}
+	if targets[0].Name != "default/app" || targets[0].MemoryThreshold != 5000 {
+		t.Errorf("ResolveTargets()[0] = %+v, want inherited defaults", targets[0])
+	}
+
+	config.Targets = []Target{
+		{Name: "api", Namespace: "prod", DeploymentName: "api", MemoryThreshold: 3000},
+		{DeploymentName: "worker", CheckInterval: 30 * time.Second},
+	}
+	targets = config.ResolveTargets()
+	if len(targets) != 2 {
+		t.Fatalf("ResolveTargets() returned %d targets, want 2", len(targets))
+	}
+	if targets[0].Namespace != "prod" || targets[0].MemoryThreshold != 3000 || targets[0].CheckInterval != time.Minute {
+		t.Errorf("ResolveTargets()[0] = %+v", targets[0])
+	}
+	if targets[1].Name != "default/worker" || targets[1].CheckInterval != 30*time.Second {
+		t.Errorf("ResolveTargets()[1] = %+v", targets[1])
+	}
+}
+
+func TestLoadConfigFile(t *testing.T) {
+	path := t.TempDir() + "/config.yaml"
+	data := `namespace: "staging"
+memory_threshold: 4000
+check_interval: "1m"
+targets:
+  - name: api
+    deployment: api
+  - deployment: worker
+    namespace: batch
+    memory_threshold: 8000
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfigFile(path, Config{KubectlPath: "kubectl", MetricsCacheTTL: time.Second})
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	if config.Namespace != "staging" || config.MemoryThreshold != 4000 || config.CheckInterval != time.Minute {
+		t.Errorf("LoadConfigFile() = %+v", config)
+	}
+	if config.KubectlPath != "kubectl" || config.MetricsCacheTTL != time.Second {
+		t.Errorf("LoadConfigFile() did not keep defaults: %+v", config)
+	}
+	if len(config.Targets) != 2 || config.Targets[1].Namespace != "batch" || config.Targets[1].MemoryThreshold != 8000 {
+		t.Errorf("LoadConfigFile() targets = %+v", config.Targets)
+	}
+}
+
+func TestExpandEnv(t *testing.T) {
+	env := map[string]string{"NAMESPACE": "prod", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"namespace: ${NAMESPACE}", "namespace: prod"},
+		{"memory_threshold: ${THRESHOLD:-5000}", "memory_threshold: 5000"},
+		{"namespace: ${NAMESPACE:-default}", "namespace: prod"},
+		{"namespace: ${EMPTY:-default}", "namespace: default"},
+		{"namespace: ${UNSET}", "namespace: "},
+		{"password: pa$$word$", "password: pa$$word$"},
+		{"template: $${NAMESPACE}", "template: ${NAMESPACE}"},
+		{"url: ${NAMESPACE:-http://localhost:8080/}", "url: prod"},
+	}
+	for _, tt := range tests {
+		got, err := ExpandEnv(tt.text, lookup)
+		if err != nil || got != tt.want {
+			t.Errorf("ExpandEnv(%q) = %q, %v, want %q", tt.text, got, err, tt.want)
+		}
+	}
+
+	for _, text := range []string{"a: 1\nb: ${NAMESPACE", "a: 1\nb: ${NAME SPACE}", "b: ${:-x}"} {
+		if _, err := ExpandEnv(text, lookup); err == nil {
+			t.Errorf("ExpandEnv(%q) succeeded, want error", text)
+		}
+	}
+	if _, err := ExpandEnv("a: 1\nb: ${NAMESPACE", lookup); err == nil || err.Error() != "line 2: unterminated variable reference" {
+		t.Errorf("ExpandEnv() error = %v, want the line of the reference", err)
+	}
+}
+
+func TestLoadConfigFileExpandsEnv(t *testing.T) {
+	os.Setenv("WATCHDOG_TEST_NAMESPACE", "staging")
+	defer os.Unsetenv("WATCHDOG_TEST_NAMESPACE")
+
+	path := t.TempDir() + "/config.yaml"
+	data := `namespace: "${WATCHDOG_TEST_NAMESPACE}"
+memory_threshold: ${WATCHDOG_TEST_THRESHOLD:-5000}
+`
+	if err := os.WriteFile(path, []byte(data), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := LoadConfigFile(path, Config{})
+	if err != nil {
+		t.Fatalf("LoadConfigFile() error = %v", err)
+	}
+	if config.Namespace != "staging" || config.MemoryThreshold != 5000 {
+		t.Errorf("LoadConfigFile() = %+v", config)
+	}
+}
+
+func TestConfigProblems(t *testing.T) {
+	config := Config{
+		Namespace:       "prod",
+		MemoryThreshold: 4000,
+		CheckInterval:   time.Minute,
+		MetricsTimeout:  30 * time.Second,
+		Targets: []Target{
+			{Name: "api", DeploymentName: "api"},
+		},
+	}
+	if problems := config.Problems(); len(problems) != 0 {
+		t.Errorf("Problems() = %q, want none", problems)
+	}
+
+	config.MetricsTimeout = 2 * time.Minute
+	config.RecoveryPercent = 120
+	config.Source.Kubectl.InitContainers = "skip"
+	config.Baseline.Percent = 50
+	config.Circuit.Failures = 3
+	config.Budget.Restarts = 10
+	config.Tenants.Namespaces = []string{"team-a"}
+	config.Proxy.URL = "proxy.corp:3128"
+	config.EKS.Cluster = "prod"
+	config.GKE.Enabled = true
+	config.OIDC.IssuerURL = "http://dex.corp"
+	config.OIDC.RefreshToken = "secret:kube-system/oidc/refresh-token"
+	config.Notifications.Alertmanager.URLs = []string{"http://alertmanager:9093", "alertmanager:9093"}
+	config.Notifications.Alertmanager.Labels = map[string]string{"team": "platform", "1team": "platform"}
+	config.GRPC.Enabled = true
+	config.Targets = []Target{
+		{Name: "api", DeploymentName: "api"},
+		{Name: "api", DeploymentName: "api-v2", Cron: "*/5 * * * *"},
+		{Name: "worker", DeploymentName: "worker", Quorum: 2, Sources: []string{"kubectl"}},
+		{Name: "batch", DeploymentName: "batch", Scope: "container", Cron: "0 * * * *"},
+		{Name: "cache", DeploymentName: "cache", Aggregation: "p200", Cron: "0 * * * *"},
+		{Name: "etl", DeploymentName: "etl", BatchPods: "skip", Cron: "0 * * * *"},
+		{Name: "jobs", DeploymentName: "jobs", BaseMemory: 1000, Cron: "0 * * * *"},
+		{Name: "web", DeploymentName: "web", ReplicaMemory: 500, Cron: "0 * * * *", Schedules: []ThresholdSchedule{{Start: "08:00", End: "20:00", MemoryThreshold: 3000}}},
+		{Name: "search", DeploymentName: "search", CPUThrottling: 150, Cron: "0 * * * *"},
+		{Name: "ledger", DeploymentName: "ledger", RestartStrategy: "recreate", Cron: "0 * * * *"},
+		{Name: "gateway", DeploymentName: "gateway", RestartStrategy: RestartDelete, GracePeriod: 1500 * time.Millisecond, Cron: "0 * * * *"},
+		{Name: "shop", DeploymentName: "shop", ColorService: "shop", Cron: "0 * * * *"},
+		{Name: "checkout", DeploymentName: "checkout", RestartStrategy: RestartScale, SurgeReplicas: 2, Cron: "0 * * * *"},
+		{Name: "search-v2", DeploymentName: "search-v2", RestartStrategy: RestartDelete, Rollback: true, Cron: "0 * * * *"},
+		{Name: "edge", DeploymentName: "edge", Cluster: &Cluster{Server: "edge.example.com:6443"}, Cron: "0 * * * *"},
+		{Name: "staging", DeploymentName: "staging", Cluster: &Cluster{Context: "staging", Token: "file:/token"}, Cron: "0 * * * *"},
+	}
+	expected := []string{
+		"metrics_timeout (2m0s) is not shorter than the check interval of target 'api' (1m0s), so a slow metric source delays its next check; lower metrics_timeout or raise check_interval",
+		"target 'api' is configured more than once, give the targets distinct names",
+		"target 'worker' has a quorum of 2 but only 1 sources",
+		"metrics_timeout (2m0s) is not shorter than the check interval of target 'worker' (1m0s), so a slow metric source delays its next check; lower metrics_timeout or raise check_interval",
+		"target 'batch' has an unknown scope 'container', want namespace, deployment or pod",
+		"target 'cache': invalid percentile 'p200', want p followed by a number between 0 and 100",
+		"target 'etl' has an unknown batch_pods 'skip', want exclude or include",
+		"target 'jobs' sets memory_base without memory_per_replica, which it is added to",
+		"target 'web' sets both memory_per_replica and schedules, whose thresholds are totals; use one of them",
+		"target 'search' has a cpu_throttling of 150%, not between 0 and 100",
+		"target 'ledger' has an unknown restart_strategy 'recreate', want rollout, scale or delete",
+		"target 'gateway' has a grace_period of 1.5s, not a whole number of seconds",
+		"target 'shop' sets color_service without the color_label telling the colors apart",
+		"target 'checkout' sets surge_replicas with the scale restart strategy, which scales the deployment to zero anyway",
+		"target 'search-v2' sets rollback with the delete restart strategy, which doesn't roll out a new revision to undo",
+		"target 'edge': cluster server 'edge.example.com:6443' is invalid, want an https URL",
+		"target 'staging': cluster sets ca_file or token with a context, which has its own credentials",
+		"recovery_percent (120) must be between 0 and 100",
+		"source.kubectl.init_containers 'skip' is unknown, want include or exclude",
+		"circuit_breaker.probe must be positive when circuit_breaker.failures is set",
+		"restart_budget.window must be positive when restart_budget.restarts is set",
+		"tenants.namespaces requires the tenants.configmap read from them and a positive tenants.refresh",
+		"proxy.url 'proxy.corp:3128' is invalid, want an http, https or socks5 URL",
+		"oidc.issuer_url 'http://dex.corp' is invalid, want an https URL",
+		"oidc.issuer_url requires the oidc.client_id and oidc.refresh_token of the watchdog",
+		"oidc.client_secret and oidc.refresh_token can't refer to a Kubernetes Secret, use a file instead",
+		"eks.cluster, gke.enabled, aks.enabled and oidc.issuer_url each set how kubectl authenticates; use only one of them",
+		"notifications.alertmanager.urls has an invalid URL 'alertmanager:9093', want an http or https URL",
+		"notifications.alertmanager.labels has an invalid label name '1team'",
+		"baseline.percent has no effect without the history of a state_file",
+		"the gRPC API requires grpc.tls.cert_file and grpc.tls.key_file",
+	}
+	problems := config.Problems()
+	if len(problems) != len(expected) {
+		t.Fatalf("Problems() = %q, want %q", problems, expected)
+	}
+	for i := range expected {
+		if problems[i] != expected[i] {
+			t.Errorf("Problems()[%d] = %q, want %q", i, problems[i], expected[i])
+		}
+	}
+
+	if problems := (Config{}).Problems(); len(problems) != 1 {
+		t.Errorf("Problems() of an empty configuration = %q, want the missing deployment", problems)
+	}
+
+	silences := Config{Namespace: "prod", DeploymentName: "api", MemoryThreshold: 4000, CheckInterval: time.Minute}
+	silences.Notifications.Alertmanager.Silences = true
+	want := "notifications.alertmanager.silences requires the notifications.alertmanager.urls they are read from"
+	if problems := silences.Problems(); len(problems) != 1 || problems[0] != want {
+		t.Errorf("Problems() = %q, want %q", problems, want)
+	}
+}
@@ -0,0 +1,253 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// TargetStatus is the state of a monitored target
+type TargetStatus struct {
+	Target     Target       `json:"target"`
+	Paused     bool         `json:"paused"`
+	LastResult *CheckResult `json:"last_result,omitempty"`
+	// LastAction is the latest result with an action, of a check or of an
+	// operator's restart
+	LastAction *CheckResult `json:"last_action,omitempty"`
+	// BreachedSince is when the target's open breach started, if it has
+	// one that hasn't been resolved yet
+	BreachedSince *time.Time `json:"breached_since,omitempty"`
+	// Silence mutes the notifications of the target until it expires
+	Silence *Silence `json:"silence,omitempty"`
+	// Pause is the pause of an operator, when the target is paused with
+	// PauseTarget
+	Pause *Pause `json:"pause,omitempty"`
+	// CircuitOpenSince is when the circuit of the target opened or was last
+	// probed, while repeated failed restarts hold its restarts back
+	CircuitOpenSince *time.Time `json:"circuit_open_since,omitempty"`
+	// Restarts counts the successful restarts of the target since the
+	// watchdog started
+	Restarts int `json:"restarts"`
+}
+
+// Status returns the state of every target, in target order
+func (w *Watchdog) Status() []TargetStatus {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	now := w.clock.Now()
+	statuses := make([]TargetStatus, 0, len(w.order))
+	for _, name := range w.order {
+		loop := w.targets[name]
+		status := TargetStatus{Target: loop.target, Paused: loop.paused && !loop.pause.expired(now), Restarts: loop.restarts}
+		if status.Paused && loop.pause != nil {
+			pause := *loop.pause
+			status.Pause = &pause
+		}
+		if loop.last != nil {
+			last := *loop.last
+			status.LastResult = &last
+		}
+		if loop.lastAction != nil {
+			action := *loop.lastAction
+			status.LastAction = &action
+		}
+		if !loop.breachedAt.IsZero() {
+			since := loop.breachedAt
+			status.BreachedSince = &since
+		}
+		if loop.silence != nil && now.Before(loop.silence.Until) {
+			silence := *loop.silence
+			status.Silence = &silence
+		}
+		if !loop.circuitAt.IsZero() {
+			since := loop.circuitAt
+			status.CircuitOpenSince = &since
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses
+}
+
+// RestartTarget runs the action of the named target outside of a check, as
+// requested by an operator, and returns its result. The restart is recorded
+// and notified like an automatic one, with reason, which names who asked
+// for it, as the Reason of its event, and the request and its outcome are
+// logged by the audit component. An operator overrides the guards of
+// automatic restarts, so none of them holds it back:
+//
+//   - freezes and holds don't apply
+//   - the notify-only mode of an exhausted restart budget doesn't apply,
+//     and the restart isn't counted against the budget
+//   - an open circuit doesn't apply, and a successful restart closes it
+//   - rollouts in progress and AvailableGuard don't defer or escalate it
+func (w *Watchdog) RestartTarget(ctx context.Context, name, reason string) (CheckResult, error) {
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	w.mu.Unlock()
+	if !exists {
+		return CheckResult{}, fmt.Errorf("%w: '%s'", ErrTargetNotFound, name)
+	}
+	target, err := w.activeColor(ctx, loop.target)
+	if err != nil {
+		return CheckResult{}, fmt.Errorf("error resolving the active color: %w", err)
+	}
+
+	now := w.clock.Now()
+	result := CheckResult{
+		Target:    target,
+		Threshold: target.thresholdAt(now),
+		Time:      now,
+		ActionID:  newID(),
+	}
+	ctx = ContextWithCluster(ContextWithIDs(ctx, "", result.ActionID), target.Cluster)
+	w.auditLog.Infof("Manual restart of deployment '%s' of target '%s' requested: %s%s", target.DeploymentName, target.Name, reason, result.correlation())
+
+	restartCtx, cancel := withOptionalTimeout(ctx, w.config.RestartTimeout)
+	defer cancel()
+	err = w.action.Execute(restartCtx, target)
+	result.Duration = w.clock.Now().Sub(now)
+	if err != nil {
+		result.Err = fmt.Errorf("error restarting deployment: %w", err)
+		event := w.event(EventRestartFailed, target, 0, result.Err)
+		event.Reason = reason
+		w.notify(ctx, event)
+		w.auditLog.Errorf("Manual restart of deployment '%s' failed: %v%s", target.DeploymentName, err, result.correlation())
+		w.rollBack(ctx, target, 0, err)
+	} else {
+		result.Action = string(EventRestart)
+		w.countRestart(target)
+		event := w.event(EventRestart, target, 0, nil)
+		event.Reason = reason
+		w.notify(ctx, event)
+		w.auditLog.Infof("Manual restart of deployment '%s' succeeded: %s%s", target.DeploymentName, reason, result.correlation())
+		w.restartSucceeded(ctx, target, 0)
+	}
+	w.saveRecord(ctx, result.record())
+	if result.Action != "" {
+		w.setLastAction(result)
+	}
+	return result, nil
+}
+
+// paused reports whether the scheduled checks of a target are paused. An
+// expired pause is removed and logged.
+func (w *Watchdog) paused(name string) bool {
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	if !exists || !loop.paused || !loop.pause.expired(w.clock.Now()) {
+		w.mu.Unlock()
+		return exists && loop.paused
+	}
+	loop.paused = false
+	loop.pause = nil
+	w.mu.Unlock()
+
+	w.auditLog.Infof("Pause of target '%s' expired", name)
+	return false
+}
+
+// setLastResult keeps the latest result of a target for Status
+func (w *Watchdog) setLastResult(result CheckResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if loop, exists := w.targets[result.Target.Name]; exists {
+		loop.last = &result
+		if result.Action != "" {
+			loop.lastAction = &result
+		}
+	}
+}
+
+// setLastAction keeps the result of an action taken outside of a check
+func (w *Watchdog) setLastAction(result CheckResult) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if loop, exists := w.targets[result.Target.Name]; exists {
+		loop.lastAction = &result
+	}
+}
+
+// stallFactor is how many intervals a target's loop may go without
+// finishing a check before it is considered stalled, unless set by
+// SelfMonitorConfig.StallIntervals
+const stallFactor = 2
+
+// Stalled returns the names of the running targets whose loop hasn't
+// finished a check in stallFactor intervals plus the metrics and restart
+// timeouts, such as a check hanging on a kubectl call without a timeout
+func (w *Watchdog) Stalled(now time.Time) []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	factor := time.Duration(w.config.SelfMonitor.StallIntervals)
+	if factor <= 0 {
+		factor = stallFactor
+	}
+	var stalled []string
+	for _, name := range w.order {
+		loop := w.targets[name]
+		if loop.alive.IsZero() {
+			continue
+		}
+		interval := loop.target.CheckInterval
+		if loop.target.Cron != "" {
+			interval = time.Minute
+		}
+		limit := factor*interval + w.config.MetricsTimeout + w.config.RestartTimeout
+		if now.Sub(loop.alive) > limit {
+			stalled = append(stalled, name)
+		}
+	}
+	return stalled
+}
+
+// LastCheck returns when a target's memory usage was last read
+// successfully, zero before the first reading
+func (w *Watchdog) LastCheck() time.Time {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.lastCheck
+}
+
+// setLastCheck records a successful reading of a target's memory usage
+func (w *Watchdog) setLastCheck(now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if now.After(w.lastCheck) {
+		w.lastCheck = now
+	}
+}
+
+// openBreach records that a target breached at now, unless it already has
+// an open breach
+func (w *Watchdog) openBreach(name string, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if loop, exists := w.targets[name]; exists && loop.breachedAt.IsZero() {
+		loop.breachedAt = now
+	}
+}
+
+// closeBreach clears the open breach of a target and returns when it
+// started, if it had one
+func (w *Watchdog) closeBreach(name string) (time.Time, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	loop, exists := w.targets[name]
+	if !exists || loop.breachedAt.IsZero() {
+		return time.Time{}, false
+	}
+	since := loop.breachedAt
+	loop.breachedAt = time.Time{}
+	return since, true
+}
+
+// setAlive records that the loop of a target is waiting for its next tick
+func (w *Watchdog) setAlive(name string, now time.Time) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if loop, exists := w.targets[name]; exists {
+		loop.alive = now
+	}
+}
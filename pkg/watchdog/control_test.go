@@ -0,0 +1,343 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestPauseResume(t *testing.T) {
+	client := watchdogtest.NewFakeClient()
+	client.SetSeries("prod", 1000)
+	client.SetSeries("staging", 1000)
+	fakeClock := watchdogtest.NewFakeClock(time.Now())
+
+	watchdog := NewWatchdog(client, client, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets: []Target{
+			{Namespace: "prod", DeploymentName: "api"},
+			{Namespace: "staging", DeploymentName: "api"},
+		},
+	}, WithClock(fakeClock))
+
+	if _, err := watchdog.PauseTarget("prod/api", 0, "maintenance"); err != nil {
+		t.Fatalf("PauseTarget() error = %v", err)
+	}
+	if _, err := watchdog.PauseTarget("missing", 0, "maintenance"); !errors.Is(err, ErrTargetNotFound) {
+		t.Errorf("PauseTarget() error = %v, want %v", err, ErrTargetNotFound)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchdog.Run(ctx)
+	fakeClock.WaitForTickers(2)
+
+	fakeClock.Advance(time.Minute)
+	eventually(t, func() bool { return client.MetricsCalls("staging") == 1 })
+	if got := client.MetricsCalls("prod"); got != 0 {
+		t.Errorf("MetricsCalls(prod) = %v while paused, want 0", got)
+	}
+
+	statuses := watchdog.Status()
+	if !statuses[0].Paused || statuses[0].LastResult != nil {
+		t.Errorf("Status()[0] = %+v, want paused without results", statuses[0])
+	}
+	eventually(t, func() bool { return watchdog.Status()[1].LastResult != nil })
+	if last := watchdog.Status()[1].LastResult; last.Memory != 1000 {
+		t.Errorf("Status()[1].LastResult = %+v, want the latest check", last)
+	}
+
+	if err := watchdog.ResumeTarget("prod/api", "done"); err != nil {
+		t.Fatalf("ResumeTarget() error = %v", err)
+	}
+	fakeClock.Advance(time.Minute)
+	eventually(t, func() bool { return client.MetricsCalls("prod") == 1 })
+}
+
+func TestRestartTarget(t *testing.T) {
+	client := watchdogtest.NewFakeClient()
+	store := NewMemoryStateStore(0)
+	var events []Event
+	watchdog := NewWatchdog(client, client, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets:         []Target{{Namespace: "prod", DeploymentName: "api"}},
+	}, WithStateStore(store), WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+		events = append(events, event)
+		return nil
+	})))
+
+	result, err := watchdog.RestartTarget(context.Background(), "prod/api", "manual by alice: INC-42")
+	if err != nil {
+		t.Fatalf("RestartTarget() error = %v", err)
+	}
+	if result.Action != "restart" || result.Err != nil {
+		t.Errorf("RestartTarget() = %+v, want a restart", result)
+	}
+	if restarts := client.Restarts(); len(restarts) != 1 || restarts[0].Deployment != "api" {
+		t.Errorf("restarts = %v, want api restarted", restarts)
+	}
+	if len(events) != 1 || events[0].Type != EventRestart || events[0].Reason != "manual by alice: INC-42" {
+		t.Errorf("events = %v, want a restart with the reason", events)
+	}
+	records, _ := store.List(context.Background(), time.Time{})
+	if len(records) != 1 || records[0].Action != "restart" {
+		t.Errorf("records = %v, want the restart recorded", records)
+	}
+
+	client.FailRestarts(errors.New("forbidden"), 1)
+	result, _ = watchdog.RestartTarget(context.Background(), "prod/api", "retry")
+	if result.Err == nil || events[len(events)-1].Type != EventRestartFailed {
+		t.Errorf("RestartTarget() = %+v, want a failed restart notified", result)
+	}
+
+	if _, err := watchdog.RestartTarget(context.Background(), "missing", ""); !errors.Is(err, ErrTargetNotFound) {
+		t.Errorf("RestartTarget() error = %v, want %v", err, ErrTargetNotFound)
+	}
+}
+
+func TestRestartTargetAudit(t *testing.T) {
+	client := watchdogtest.NewFakeClient()
+	var records []logging.Record
+	logger := logging.NewWithOutputs(logging.Output{Sink: logging.SinkFunc(func(record logging.Record) {
+		records = append(records, record)
+	}), Levels: logging.Levels{Default: logging.LevelInfo}})
+	watchdog := NewWatchdog(client, client, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets:         []Target{{Namespace: "prod", DeploymentName: "api"}},
+	}, WithLogging(logger))
+
+	watchdog.RestartTarget(context.Background(), "prod/api", "manual restart by alice: INC-42")
+	client.FailRestarts(errors.New("forbidden"), 1)
+	watchdog.RestartTarget(context.Background(), "prod/api", "manual restart by bob")
+
+	var audit []string
+	for _, record := range records {
+		if record.Component == "audit" {
+			audit = append(audit, record.Level.String()+" "+record.Message)
+		}
+	}
+	want := []string{
+		"info Manual restart of deployment 'api' of target 'prod/api' requested: manual restart by alice: INC-42",
+		"info Manual restart of deployment 'api' succeeded: manual restart by alice: INC-42",
+		"info Manual restart of deployment 'api' of target 'prod/api' requested: manual restart by bob",
+		"error Manual restart of deployment 'api' failed: forbidden",
+	}
+	if len(audit) != len(want) {
+		t.Fatalf("audit = %q, want %q", audit, want)
+	}
+	for i := range want {
+		if !strings.HasPrefix(audit[i], want[i]) {
+			t.Errorf("audit[%d] = %q, want %q", i, audit[i], want[i])
+		}
+	}
+}
+
+func TestRestartTargetOverridesGuards(t *testing.T) {
+	tests := []struct {
+		name    string
+		options []Option
+		budget  BudgetConfig
+		setup   func(w *Watchdog)
+		check   func(t *testing.T, w *Watchdog)
+	}{
+		{name: "freeze", options: []Option{WithFreeze(fakeFreeze{reason: "Black Friday"})}},
+		{name: "hold", options: []Option{WithHold(fakeHold{"prod/api": "silenced in Alertmanager"})}},
+		{
+			name:   "notify-only",
+			budget: BudgetConfig{Restarts: 1, Window: time.Hour},
+			setup: func(w *Watchdog) {
+				w.disabled = time.Now()
+			},
+		},
+		{
+			name:   "budget",
+			budget: BudgetConfig{Restarts: 1, Window: time.Hour},
+			check: func(t *testing.T, w *Watchdog) {
+				if len(w.restarts) != 0 {
+					t.Errorf("restarts = %v, want the manual restart not counted against the budget", w.restarts)
+				}
+			},
+		},
+		{
+			name: "circuit",
+			setup: func(w *Watchdog) {
+				w.targets["prod/api"].failures = 3
+				w.targets["prod/api"].circuitAt = time.Now()
+			},
+			check: func(t *testing.T, w *Watchdog) {
+				if !w.targets["prod/api"].circuitAt.IsZero() {
+					t.Error("circuit still open, want it closed by the successful restart")
+				}
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := watchdogtest.NewFakeClient()
+			watchdog := NewWatchdog(client, client, Config{
+				MemoryThreshold: 2000,
+				CheckInterval:   time.Minute,
+				Budget:          tt.budget,
+				Circuit:         CircuitConfig{Failures: 3, Probe: time.Hour},
+				Targets:         []Target{{Namespace: "prod", DeploymentName: "api"}},
+			}, tt.options...)
+			if tt.setup != nil {
+				tt.setup(watchdog)
+			}
+
+			result, err := watchdog.RestartTarget(context.Background(), "prod/api", "manual restart by alice")
+			if err != nil || result.Action != string(EventRestart) {
+				t.Fatalf("RestartTarget() = %+v, %v, want a restart", result, err)
+			}
+			if restarts := client.Restarts(); len(restarts) != 1 {
+				t.Errorf("Restarts() = %v, want the deployment restarted", restarts)
+			}
+			if tt.check != nil {
+				tt.check(t, watchdog)
+			}
+		})
+	}
+}
+
+func TestStalled(t *testing.T) {
+	client := watchdogtest.NewFakeClient()
+	client.SetSeries("prod", 1000)
+	start := time.Now()
+	fakeClock := watchdogtest.NewFakeClock(start)
+
+	watchdog := NewWatchdog(client, client, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		MetricsTimeout:  30 * time.Second,
+		Targets:         []Target{{Namespace: "prod", DeploymentName: "api"}},
+	}, WithClock(fakeClock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchdog.Run(ctx)
+	fakeClock.WaitForTickers(1)
+
+	// the check hangs on the metrics source, so the loop never waits for
+	// its next tick again
+	client.SetDelay(time.Hour)
+	fakeClock.Advance(time.Minute)
+
+	if stalled := watchdog.Stalled(start.Add(2 * time.Minute)); len(stalled) != 0 {
+		t.Errorf("Stalled() = %v, want none within 2 intervals and the timeout", stalled)
+	}
+	if stalled := watchdog.Stalled(start.Add(3 * time.Minute)); len(stalled) != 1 || stalled[0] != "prod/api" {
+		t.Errorf("Stalled() = %v, want [prod/api]", stalled)
+	}
+}
+
+func TestStalledIntervals(t *testing.T) {
+	client := watchdogtest.NewFakeClient()
+	client.SetSeries("prod", 1000)
+	start := time.Now()
+	fakeClock := watchdogtest.NewFakeClock(start)
+
+	watchdog := NewWatchdog(client, client, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		MetricsTimeout:  30 * time.Second,
+		SelfMonitor:     SelfMonitorConfig{StallIntervals: 5},
+		Targets:         []Target{{Namespace: "prod", DeploymentName: "api"}},
+	}, WithClock(fakeClock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchdog.Run(ctx)
+	fakeClock.WaitForTickers(1)
+
+	if last := watchdog.LastCheck(); !last.IsZero() {
+		t.Errorf("LastCheck() = %v before the first check, want zero", last)
+	}
+	fakeClock.Advance(time.Minute)
+	eventually(t, func() bool { return watchdog.LastCheck().Equal(start.Add(time.Minute)) })
+	// wait for the loop to wait for its next tick before hanging the check
+	eventually(t, func() bool {
+		watchdog.mu.Lock()
+		defer watchdog.mu.Unlock()
+		return watchdog.targets["prod/api"].alive.Equal(start.Add(time.Minute))
+	})
+
+	client.SetDelay(time.Hour)
+	fakeClock.Advance(time.Minute)
+
+	if stalled := watchdog.Stalled(start.Add(6 * time.Minute)); len(stalled) != 0 {
+		t.Errorf("Stalled() = %v, want none within 5 intervals and the timeout", stalled)
+	}
+	if stalled := watchdog.Stalled(start.Add(7 * time.Minute)); len(stalled) != 1 {
+		t.Errorf("Stalled() = %v, want [prod/api]", stalled)
+	}
+}
+
+func TestPauseTarget(t *testing.T) {
+	client := watchdogtest.NewFakeClient()
+	client.SetSeries("prod", 1000)
+	fakeClock := watchdogtest.NewFakeClock(time.Now())
+
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+	}, WithClock(fakeClock))
+
+	if _, err := watchdog.PauseTarget("prod/missing", time.Hour, ""); !errors.Is(err, ErrTargetNotFound) {
+		t.Errorf("PauseTarget() error = %v, want ErrTargetNotFound", err)
+	}
+	if err := watchdog.ResumeTarget("prod/api", ""); !errors.Is(err, ErrNotPaused) {
+		t.Errorf("ResumeTarget() error = %v, want ErrNotPaused", err)
+	}
+	pause, err := watchdog.PauseTarget("prod/api", 2*time.Minute+30*time.Second, "pause by alice: migration")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fakeClock.Now().Add(2*time.Minute + 30*time.Second); !pause.Until.Equal(want) {
+		t.Errorf("PauseTarget().Until = %v, want %v", pause.Until, want)
+	}
+	if status := watchdog.Status()[0]; !status.Paused || status.Pause == nil || status.Pause.Reason != "pause by alice: migration" {
+		t.Errorf("Status()[0] = %+v, want the pause", status)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchdog.Run(ctx)
+	fakeClock.WaitForTickers(1)
+
+	for i := 0; i < 2; i++ {
+		fakeClock.Advance(time.Minute)
+	}
+	// the pause expires before the third tick, which checks the target
+	fakeClock.Advance(time.Minute)
+	eventually(t, func() bool { return watchdog.Status()[0].LastResult != nil })
+	if last := watchdog.Status()[0].LastResult; last.Time.Before(pause.Until) {
+		t.Errorf("checked at %v, want no check before the pause expires at %v", last.Time, pause.Until)
+	}
+	if status := watchdog.Status()[0]; status.Paused || status.Pause != nil {
+		t.Errorf("Status()[0] = %+v, want the pause expired", status)
+	}
+
+	if _, err := watchdog.PauseTarget("prod/api", 0, "indefinitely"); err != nil {
+		t.Fatal(err)
+	}
+	fakeClock.Advance(24 * time.Hour)
+	if status := watchdog.Status()[0]; !status.Paused || !status.Pause.Until.IsZero() {
+		t.Errorf("Status()[0] = %+v, want paused until resumed", status)
+	}
+	if err := watchdog.ResumeTarget("prod/api", "done"); err != nil {
+		t.Errorf("ResumeTarget() error = %v", err)
+	}
+	if watchdog.Status()[0].Paused {
+		t.Error("Status()[0].Paused = true after ResumeTarget()")
+	}
+}
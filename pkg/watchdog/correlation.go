@@ -0,0 +1,47 @@
+package watchdog
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// correlationKey is the context key of the IDs of the current check and
+// action
+type correlationKey struct{}
+
+// correlation identifies a check and the action it triggered, so that
+// logs, events, records and the annotations of a restarted deployment can
+// be tied together
+type correlation struct {
+	checkID  string
+	actionID string
+}
+
+// newID returns a random 16 hex digit ID
+func newID() string {
+	var b [8]byte
+	rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// ContextWithIDs returns a copy of ctx carrying the IDs of a check and of
+// the action it triggered
+func ContextWithIDs(ctx context.Context, checkID, actionID string) context.Context {
+	return context.WithValue(ctx, correlationKey{}, correlation{checkID: checkID, actionID: actionID})
+}
+
+// CheckID returns the ID of the check ctx belongs to, empty outside of a
+// check
+func CheckID(ctx context.Context) string {
+	c, _ := ctx.Value(correlationKey{}).(correlation)
+	return c.checkID
+}
+
+// ActionID returns the ID of the action ctx belongs to, such as the
+// restart of a breaching target, empty outside of an action. Restarters
+// can record it on the restarted deployment.
+func ActionID(ctx context.Context) string {
+	c, _ := ctx.Value(correlationKey{}).(correlation)
+	return c.actionID
+}
@@ -0,0 +1,47 @@
+package watchdog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCronScheduleMatches(t *testing.T) {
+	tests := []struct {
+		expr     string
+		time     string
+		expected bool
+	}{
+		{expr: "*/2 8-20 * * 1-5", time: "2024-03-13T10:04:00Z", expected: true},
+		{expr: "*/2 8-20 * * 1-5", time: "2024-03-13T10:05:00Z", expected: false},
+		{expr: "*/2 8-20 * * 1-5", time: "2024-03-13T21:00:00Z", expected: false},
+		{expr: "*/2 8-20 * * 1-5", time: "2024-03-16T10:04:00Z", expected: false},
+		{expr: "0 0 * * 7", time: "2024-03-17T00:00:30Z", expected: true},
+		{expr: "30 6 * jan-mar mon,fri", time: "2024-03-15T06:30:00Z", expected: true},
+		{expr: "30 6 * jan-mar mon,fri", time: "2024-04-15T06:30:00Z", expected: false},
+		{expr: "15/20 * * * *", time: "2024-03-13T10:55:00Z", expected: true},
+		{expr: "0 12 1 * mon", time: "2024-03-01T12:00:00Z", expected: true},
+		{expr: "0 12 1 * mon", time: "2024-03-04T12:00:00Z", expected: true},
+		{expr: "0 12 1 * mon", time: "2024-03-05T12:00:00Z", expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.expr+" "+tt.time, func(t *testing.T) {
+			schedule, err := parseCron(tt.expr)
+			if err != nil {
+				t.Fatalf("parseCron() error = %v", err)
+			}
+			now, _ := time.Parse(time.RFC3339, tt.time)
+			if result := schedule.matches(now); result != tt.expected {
+				t.Errorf("matches() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestParseCronErrors(t *testing.T) {
+	for _, expr := range []string{"* * * *", "60 * * * *", "* * * * 8", "*/0 * * * *", "5-1 * * * *", "* * * foo *"} {
+		if _, err := parseCron(expr); err == nil {
+			t.Errorf("parseCron(%q) expected an error", expr)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+)
+
+// Sentinel errors returned by the Kubernetes clients and the watchdog. They
+// are wrapped with %w, so callers can classify failures with errors.Is.
+var (
+	ErrMetricsUnavailable = errors.New("metrics unavailable")
+	ErrTargetNotFound     = errors.New("target not found")
+	ErrRestartFailed      = errors.New("restart failed")
+	ErrForbidden          = errors.New("forbidden")
+	ErrNotSilenced        = errors.New("target not silenced")
+	ErrNotPaused          = errors.New("target not paused")
+	ErrNoPodMetrics       = errors.New("pod metrics unsupported")
+	// ErrRolloutNotReady is returned once a restart rolled out a new
+	// revision that didn't become ready, which is the only failure undone
+	// by rolling back
+	ErrRolloutNotReady = errors.New("rollout not ready")
+)
+
+// RolloutNotReady wraps err, the failure of waiting for the revision of a
+// restart to roll out, so that it is also ErrRolloutNotReady
+func RolloutNotReady(err error) error {
+	return rolloutNotReady{err: err}
+}
+
+type rolloutNotReady struct {
+	err error
+}
+
+func (e rolloutNotReady) Error() string        { return e.err.Error() }
+func (e rolloutNotReady) Unwrap() error        { return e.err }
+func (e rolloutNotReady) Is(target error) bool { return target == ErrRolloutNotReady }
+
+// ClassifyError returns a short, stable label describing err, suitable for
+// use as a metric label
+func ClassifyError(err error) string {
+	switch {
+	case err == nil:
+		return ""
+	case errors.Is(err, ErrForbidden):
+		return "forbidden"
+	case errors.Is(err, ErrTargetNotFound):
+		return "target_not_found"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	case errors.Is(err, ErrMetricsUnavailable):
+		return "metrics_unavailable"
+	case errors.Is(err, ErrRestartFailed):
+		return "restart_failed"
+	default:
+		return "unknown"
+	}
+}
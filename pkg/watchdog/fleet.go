@@ -0,0 +1,94 @@
+package watchdog
+
+import (
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// DefaultCluster names the cluster of the configuration in fleet summaries
+// and metrics; the other clusters are named by Cluster.String
+const DefaultCluster = "default"
+
+// ClusterSummary aggregates the state of the targets of a cluster, for a
+// view of the memory health of a whole fleet. Memory and Threshold are the
+// totals of the targets whose last check succeeded, Breaching counts the
+// targets with an open breach, Failing those whose last check failed and
+// Restarts the restarts since the watchdog started.
+type ClusterSummary struct {
+	Cluster   string `json:"cluster"`
+	Targets   int    `json:"targets"`
+	Memory    int    `json:"memory"`
+	Threshold int    `json:"threshold"`
+	Breaching int    `json:"breaching"`
+	Failing   int    `json:"failing"`
+	Paused    int    `json:"paused"`
+	Restarts  int    `json:"restarts"`
+}
+
+// clusterName returns the name of the cluster of the target in fleet
+// summaries
+func (t Target) clusterName() string {
+	if t.Cluster == nil || *t.Cluster == (Cluster{}) {
+		return DefaultCluster
+	}
+	return t.Cluster.String()
+}
+
+// Fleet aggregates statuses by cluster, the default cluster first and the
+// others in the order of their first target
+func Fleet(statuses []TargetStatus) []ClusterSummary {
+	summaries := []ClusterSummary{{Cluster: DefaultCluster}}
+	index := map[string]int{DefaultCluster: 0}
+	for _, status := range statuses {
+		name := status.Target.clusterName()
+		i, ok := index[name]
+		if !ok {
+			i = len(summaries)
+			index[name] = i
+			summaries = append(summaries, ClusterSummary{Cluster: name})
+		}
+		summary := &summaries[i]
+		summary.Targets++
+		summary.Restarts += status.Restarts
+		if status.Paused {
+			summary.Paused++
+		}
+		if status.BreachedSince != nil {
+			summary.Breaching++
+		}
+		if last := status.LastResult; last != nil {
+			if last.Err != nil {
+				summary.Failing++
+			} else {
+				summary.Memory += last.Memory
+				summary.Threshold += last.Threshold
+			}
+		}
+	}
+	return summaries
+}
+
+// countRestart counts a successful restart of target, in the metrics of the
+// target and of its cluster and in its status
+func (w *Watchdog) countRestart(target Target) {
+	w.telemetry.Inc(telemetry.MetricRestartsTotal, "target", target.Name)
+	w.telemetry.Inc(telemetry.MetricClusterRestarts, "cluster", target.clusterName())
+	w.mu.Lock()
+	if loop, ok := w.targets[target.Name]; ok {
+		loop.restarts++
+	}
+	w.mu.Unlock()
+}
+
+// exportFleet updates the gauges of the clusters from the status of the
+// targets
+func (w *Watchdog) exportFleet() {
+	if w.telemetry == nil {
+		return
+	}
+	for _, summary := range Fleet(w.Status()) {
+		w.telemetry.Set(telemetry.MetricClusterTargets, float64(summary.Targets), "cluster", summary.Cluster)
+		w.telemetry.Set(telemetry.MetricClusterMemory, float64(summary.Memory), "cluster", summary.Cluster)
+		w.telemetry.Set(telemetry.MetricClusterThreshold, float64(summary.Threshold), "cluster", summary.Cluster)
+		w.telemetry.Set(telemetry.MetricClusterBreaching, float64(summary.Breaching), "cluster", summary.Cluster)
+	}
+}
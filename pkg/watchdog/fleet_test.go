@@ -0,0 +1,58 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestFleet(t *testing.T) {
+	now := time.Now()
+	staging := &Cluster{Context: "staging"}
+	statuses := []TargetStatus{
+		{Target: Target{Name: "staging/api", Cluster: staging}, LastResult: &CheckResult{Memory: 900, Threshold: 1000}, BreachedSince: &now, Restarts: 2},
+		{Target: Target{Name: "api"}, LastResult: &CheckResult{Memory: 1500, Threshold: 2000}, Restarts: 1},
+		{Target: Target{Name: "worker"}, LastResult: &CheckResult{Err: errors.New("metrics unavailable")}},
+		{Target: Target{Name: "staging/jobs", Cluster: staging}, Paused: true},
+	}
+
+	expected := []ClusterSummary{
+		{Cluster: DefaultCluster, Targets: 2, Memory: 1500, Threshold: 2000, Failing: 1, Restarts: 1},
+		{Cluster: "context staging", Targets: 2, Memory: 900, Threshold: 1000, Breaching: 1, Paused: 1, Restarts: 2},
+	}
+	summaries := Fleet(statuses)
+	if len(summaries) != len(expected) {
+		t.Fatalf("Fleet() = %+v, want %+v", summaries, expected)
+	}
+	for i := range expected {
+		if summaries[i] != expected[i] {
+			t.Errorf("Fleet()[%d] = %+v, want %+v", i, summaries[i], expected[i])
+		}
+	}
+
+	// the default cluster is listed even without targets
+	if summaries := Fleet(nil); len(summaries) != 1 || summaries[0] != (ClusterSummary{Cluster: DefaultCluster}) {
+		t.Errorf("Fleet(nil) = %+v, want the empty default cluster", summaries)
+	}
+}
+
+func TestStatusCountsRestarts(t *testing.T) {
+	client := watchdogtest.NewFakeClient(3000)
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets:         []Target{{Name: "api", DeploymentName: "api"}},
+	})
+	watchdog.CheckOnce(context.Background())
+	if _, err := watchdog.RestartTarget(context.Background(), "api", "deploy hotfix"); err != nil {
+		t.Fatal(err)
+	}
+
+	if statuses := watchdog.Status(); len(statuses) != 1 || statuses[0].Restarts != 2 {
+		t.Errorf("Status() = %+v, want 2 restarts", statuses)
+	}
+}
@@ -0,0 +1,39 @@
+package watchdog
+
+import (
+	"context"
+	"time"
+)
+
+// FreezeConfig configures the periods during which breaching targets are
+// only reported and not restarted. Calendar is the URL or path of an
+// iCalendar file of change freezes, read again every Refresh. Restarts are
+// also suppressed on the public holidays of HolidayCountry and on the
+// "2006-01-02" dates of Holidays.
+type FreezeConfig struct {
+	Calendar       string        `yaml:"calendar"`
+	Refresh        time.Duration `yaml:"refresh"`
+	HolidayCountry string        `yaml:"holiday_country"`
+	Holidays       []string      `yaml:"holidays"`
+}
+
+// Freeze reports whether automated restarts are suppressed at a given time,
+// and why. An error is only logged: a freeze can still be reported with it,
+// for example from a stale copy of a calendar that could not be refreshed.
+type Freeze interface {
+	Frozen(ctx context.Context, t time.Time) (string, bool, error)
+}
+
+// frozen returns the reason restarts are suppressed at t, if they are
+func (w *Watchdog) frozen(ctx context.Context, t time.Time) (string, bool) {
+	for _, freeze := range w.freezes {
+		reason, frozen, err := freeze.Frozen(ctx, t)
+		if err != nil {
+			w.logger.Errorf("Error checking freeze windows: %v", err)
+		}
+		if frozen {
+			return reason, true
+		}
+	}
+	return "", false
+}
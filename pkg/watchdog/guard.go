@@ -0,0 +1,37 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+)
+
+// Availability reports how many replicas of a deployment are available and
+// how many it should have
+type Availability interface {
+	AvailableReplicas(ctx context.Context, namespace, deployment string) (available, desired int, err error)
+}
+
+// degraded returns why the deployment of target shouldn't be restarted when
+// the target guards its restarts with AvailableGuard: it doesn't have all
+// its desired replicas available, or their number couldn't be read.
+// Restarting a service that is already short of replicas would only make
+// the outage worse, so the breach is escalated instead.
+func (w *Watchdog) degraded(ctx context.Context, target Target) (string, bool) {
+	if !target.AvailableGuard {
+		return "", false
+	}
+	if w.available == nil {
+		return "no availability source to check the replicas of the deployment", true
+	}
+
+	availabilityCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+	available, desired, err := w.available.AvailableReplicas(availabilityCtx, target.Namespace, target.DeploymentName)
+	cancel()
+	if err != nil {
+		return fmt.Sprintf("availability of the deployment unknown: %v", err), true
+	}
+	if available < desired {
+		return fmt.Sprintf("deployment degraded, %d of %d replicas available", available, desired), true
+	}
+	return "", false
+}
@@ -0,0 +1,77 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// replicaAvailability reports fixed available and desired replicas, or
+// fails with err
+type replicaAvailability struct {
+	available, desired int
+	err                error
+}
+
+func (a replicaAvailability) AvailableReplicas(ctx context.Context, namespace, deployment string) (int, int, error) {
+	return a.available, a.desired, a.err
+}
+
+func TestAvailableGuard(t *testing.T) {
+	tests := []struct {
+		name      string
+		guard     bool
+		available Availability
+		escalated string
+	}{
+		{name: "fully available", guard: true, available: replicaAvailability{available: 3, desired: 3}},
+		{name: "degraded", guard: true, available: replicaAvailability{available: 1, desired: 3}, escalated: "deployment degraded, 1 of 3 replicas available"},
+		{name: "unknown availability", guard: true, available: replicaAvailability{err: errors.New("forbidden")}, escalated: "availability of the deployment unknown: forbidden"},
+		{name: "no availability source", guard: true, escalated: "no availability source to check the replicas of the deployment"},
+		{name: "guard disabled", available: replicaAvailability{available: 1, desired: 3}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []Event
+			options := []Option{WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+				events = append(events, event)
+				return nil
+			}))}
+			if tt.available != nil {
+				options = append(options, WithAvailability(tt.available))
+			}
+			client := watchdogtest.NewFakeClient(3000)
+			watchdog := NewWatchdog(client, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 2000,
+				AvailableGuard:  tt.guard,
+				CheckInterval:   time.Minute,
+			}, options...)
+
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Breached || result.Escalated != tt.escalated {
+				t.Fatalf("CheckTarget() breached = %v, escalated = %q, want a breach escalated with %q", result.Breached, result.Escalated, tt.escalated)
+			}
+			if tt.escalated == "" {
+				if len(client.Restarts()) != 1 {
+					t.Errorf("Restarts() = %v, want one", client.Restarts())
+				}
+				return
+			}
+			if len(client.Restarts()) != 0 {
+				t.Errorf("Restarts() = %v, want none for a degraded deployment", client.Restarts())
+			}
+			if len(events) != 2 || events[1].Type != EventEscalated || events[1].Reason != tt.escalated {
+				t.Errorf("events = %+v, want a breach and an escalated event", events)
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package watchdog
+
+import "context"
+
+// Hold reports whether the restarts of a target are held back from outside
+// the watchdog, such as by a silence of Alertmanager matching it, and why
+type Hold interface {
+	Held(ctx context.Context, target Target) (string, bool, error)
+}
+
+// held returns why the restart of target is held back, if it is. A failed
+// lookup doesn't hold the restart back.
+func (w *Watchdog) held(ctx context.Context, target Target, result CheckResult) (string, bool) {
+	for _, hold := range w.holds {
+		holdCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+		reason, held, err := hold.Held(holdCtx, target)
+		cancel()
+		if err != nil {
+			w.logger.Warnf("Error checking whether the restart of deployment '%s' is held: %v%s", target.DeploymentName, err, result.correlation())
+			continue
+		}
+		if held {
+			return reason, true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,67 @@
+package watchdog
+
+// NotificationsConfig configures where the watchdog's events are published
+type NotificationsConfig struct {
+	CloudEvents  CloudEventsConfig          `yaml:"cloudevents"`
+	NATS         NATSConfig                 `yaml:"nats"`
+	Kafka        KafkaConfig                `yaml:"kafka"`
+	SNS          SNSConfig                  `yaml:"sns"`
+	Alertmanager AlertmanagerConfig         `yaml:"alertmanager"`
+	Template     NotificationTemplateConfig `yaml:"template"`
+}
+
+// CloudEventsConfig configures publishing events as CloudEvents to an
+// HTTP endpoint, such as a Knative broker or an Argo Events webhook
+type CloudEventsConfig struct {
+	URL    string `yaml:"url"`
+	Source string `yaml:"source"`
+}
+
+// NATSConfig configures publishing events to a NATS server. {namespace},
+// {deployment}, {target} and {type} in Subject are replaced by those of
+// the event.
+type NATSConfig struct {
+	URL     string `yaml:"url"`
+	Subject string `yaml:"subject"`
+	Token   string `yaml:"token"`
+}
+
+// KafkaConfig configures producing events to Kafka. The memory samples of
+// every check go to SamplesTopic and the other events to EventsTopic; an
+// empty topic drops them. Username and Password authenticate with
+// SASL/PLAIN.
+type KafkaConfig struct {
+	Brokers      []string `yaml:"brokers"`
+	EventsTopic  string   `yaml:"events_topic"`
+	SamplesTopic string   `yaml:"samples_topic"`
+	TLS          bool     `yaml:"tls"`
+	Username     string   `yaml:"username"`
+	Password     string   `yaml:"password"`
+}
+
+// SNSConfig configures publishing events to an Amazon SNS topic, in the
+// region of its ARN
+type SNSConfig struct {
+	TopicARN string `yaml:"topic_arn"`
+}
+
+// AlertmanagerConfig configures sending the events as alerts to the v2 API
+// of Prometheus Alertmanager. Every instance of a highly available
+// Alertmanager is listed in URLs, as they don't share alerts, and Labels
+// are added to every alert, e.g. to route them. With Silences, a silence
+// matching the breach alert of a target holds its restarts back.
+type AlertmanagerConfig struct {
+	URLs     []string          `yaml:"urls"`
+	Labels   map[string]string `yaml:"labels"`
+	Silences bool              `yaml:"silences"`
+}
+
+// NotificationTemplateConfig configures the Go templates of human-facing
+// notifications. Empty Subject and Body use the defaults; HistoryURL and
+// RunbookURL are templates of the links added to them.
+type NotificationTemplateConfig struct {
+	Subject    string `yaml:"subject"`
+	Body       string `yaml:"body"`
+	HistoryURL string `yaml:"history_url"`
+	RunbookURL string `yaml:"runbook_url"`
+}
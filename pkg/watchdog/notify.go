@@ -0,0 +1,148 @@
+package watchdog
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+)
+
+// EventType identifies what happened to a target
+type EventType string
+
+// Events emitted by the watchdog
+const (
+	// EventCheck is emitted for every reading of a target's memory usage,
+	// with the reason "outlier" when the reading is ignored. It is only
+	// sent to event streams, see WithEventStream.
+	EventCheck         EventType = "check"
+	EventBreach        EventType = "breach"
+	EventRestart       EventType = "restart"
+	EventRestartFailed EventType = "restart_failed"
+	EventCheckFailed   EventType = "check_failed"
+	// EventSourceDegraded is emitted when a source of a target's chain fails
+	// and the next one is used instead
+	EventSourceDegraded EventType = "source_degraded"
+	// EventSuppressed is emitted instead of a restart when a breaching
+	// target is not restarted during a freeze, while it is held, while its
+	// deployment is rolling out, while its circuit is open, or once the
+	// restart budget is exhausted
+	EventSuppressed EventType = "suppressed"
+	// EventResolved is emitted when the usage of a target that breached
+	// drops back under its recovery threshold, closing the breach
+	EventResolved EventType = "resolved"
+	// EventEscalated is emitted instead of a restart when a breaching
+	// target guarded by AvailableGuard is already short of available
+	// replicas, for an operator to step in
+	EventEscalated EventType = "escalated"
+	// EventRollback is emitted when the rollout of a deployment whose
+	// restart failed is undone, with Err set when undoing it failed too.
+	// It is critical, so it is always sent to the notifiers.
+	EventRollback EventType = "rollback"
+	// EventCircuitOpen is emitted when repeated failed restarts of a target
+	// open its circuit, holding its restarts back, and EventCircuitClosed
+	// when a probing restart succeeds again
+	EventCircuitOpen   EventType = "circuit_open"
+	EventCircuitClosed EventType = "circuit_closed"
+	// EventBudgetExhausted is emitted when more restarts than the restart
+	// budget allows happened across all targets, and the watchdog stops
+	// restarting. It is critical, so it is always sent to the notifiers.
+	EventBudgetExhausted EventType = "budget_exhausted"
+	// EventSilenced and EventUnsilenced are emitted when an operator mutes
+	// the notifications of a target and when the silence is lifted or
+	// expires. They are always sent to the notifiers.
+	EventSilenced   EventType = "silenced"
+	EventUnsilenced EventType = "unsilenced"
+)
+
+// Event describes something the watchdog observed or did. CheckID and
+// ActionID identify the check and action it belongs to, and are filled in
+// from the context when the watchdog sends it.
+type Event struct {
+	Type      EventType
+	Target    Target
+	Memory    int
+	Threshold int
+	Time      time.Time
+	Reason    string
+	Err       error
+	CheckID   string
+	ActionID  string
+}
+
+// MarshalJSON encodes the event as a flat object with its error as a string
+func (e Event) MarshalJSON() ([]byte, error) {
+	out := struct {
+		Time       time.Time `json:"time"`
+		Type       EventType `json:"type"`
+		Target     string    `json:"target"`
+		Namespace  string    `json:"namespace"`
+		Deployment string    `json:"deployment"`
+		Memory     int       `json:"memory"`
+		Threshold  int       `json:"threshold"`
+		Reason     string    `json:"reason,omitempty"`
+		Error      string    `json:"error,omitempty"`
+		CheckID    string    `json:"check_id,omitempty"`
+		ActionID   string    `json:"action_id,omitempty"`
+	}{
+		Time:       e.Time,
+		Type:       e.Type,
+		Target:     e.Target.Name,
+		Namespace:  e.Target.Namespace,
+		Deployment: e.Target.DeploymentName,
+		Memory:     e.Memory,
+		Threshold:  e.Threshold,
+		Reason:     e.Reason,
+		CheckID:    e.CheckID,
+		ActionID:   e.ActionID,
+	}
+	if e.Err != nil {
+		out.Error = e.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// Notifier delivers watchdog events to an external destination
+type Notifier interface {
+	Notify(ctx context.Context, event Event) error
+}
+
+// NotifierFunc adapts a function to the Notifier interface
+type NotifierFunc func(ctx context.Context, event Event) error
+
+// Notify calls f(ctx, event)
+func (f NotifierFunc) Notify(ctx context.Context, event Event) error {
+	return f(ctx, event)
+}
+
+// notify sends an event to every notifier and event stream, logging
+// delivery failures. Notifiers don't receive the events of silenced
+// targets.
+func (w *Watchdog) notify(ctx context.Context, event Event) {
+	if event.CheckID == "" {
+		event.CheckID = CheckID(ctx)
+	}
+	if event.ActionID == "" {
+		event.ActionID = ActionID(ctx)
+	}
+	notifiers := w.streams
+	if w.notifies(ctx, event) {
+		notifiers = append(notifiers[:len(notifiers):len(notifiers)], w.notifiers...)
+	}
+	for _, notifier := range notifiers {
+		if err := notifier.Notify(ctx, event); err != nil {
+			w.logger.Errorf("Error sending %s notification for target '%s': %v", event.Type, event.Target.Name, err)
+		}
+	}
+}
+
+// notifies reports whether an event goes to the notifiers on top of the
+// event streams
+func (w *Watchdog) notifies(ctx context.Context, event Event) bool {
+	switch event.Type {
+	case EventCheck:
+		return false
+	case EventSilenced, EventUnsilenced, EventRollback, EventBudgetExhausted:
+		return true
+	}
+	return !w.silenced(ctx, event.Target.Name)
+}
@@ -0,0 +1,179 @@
+package watchdog
+
+import (
+	"log"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/clock"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// Option configures optional capabilities of a Watchdog
+type Option func(*Watchdog)
+
+// WithNotifier adds a notifier receiving the watchdog's events. It can be
+// given several times to notify several destinations.
+func WithNotifier(notifier Notifier) Option {
+	return func(w *Watchdog) {
+		w.notifiers = append(w.notifiers, notifier)
+	}
+}
+
+// WithEventStream adds a notifier receiving every event, including the
+// check events of each reading that other notifiers don't receive
+func WithEventStream(notifier Notifier) Option {
+	return func(w *Watchdog) {
+		w.streams = append(w.streams, notifier)
+	}
+}
+
+// WithSource registers a named metrics provider that targets can list in
+// their source chain
+func WithSource(name string, provider MetricsProvider) Option {
+	return func(w *Watchdog) {
+		w.sources[name] = provider
+	}
+}
+
+// WithLogger replaces the standard logger used by the watchdog, writing
+// messages up to the info level to logger
+func WithLogger(logger *log.Logger) Option {
+	return WithLogging(logging.New(logger, logging.Levels{Default: logging.LevelInfo}))
+}
+
+// WithLogging logs the watchdog's decisions as the "watchdog" component of
+// logger, and the readings of its metrics sources as the "metrics"
+// component. A nil logger discards them.
+func WithLogging(logger *logging.Logger) Option {
+	return func(w *Watchdog) {
+		w.logger = logger.Component("watchdog")
+		w.metricsLog = logger.Component("metrics")
+		w.auditLog = logger.Component("audit")
+	}
+}
+
+// WithClock replaces the system clock driving the check intervals
+func WithClock(c clock.Clock) Option {
+	return func(w *Watchdog) {
+		w.clock = c
+	}
+}
+
+// WithStateStore records the result of every check in store
+func WithStateStore(store StateStore) Option {
+	return func(w *Watchdog) {
+		w.store = store
+	}
+}
+
+// WithFreeze suppresses restarts while freeze reports a freeze. It can be
+// given several times, restarts being suppressed during any of them.
+func WithFreeze(freeze Freeze) Option {
+	return func(w *Watchdog) {
+		w.freezes = append(w.freezes, freeze)
+	}
+}
+
+// WithHold suppresses the restarts of the targets hold reports as held. It
+// can be given several times, a restart being suppressed by any of them.
+func WithHold(hold Hold) Option {
+	return func(w *Watchdog) {
+		w.holds = append(w.holds, hold)
+	}
+}
+
+// WithAction replaces the default restart with another remediation
+func WithAction(action Action) Option {
+	return func(w *Watchdog) {
+		w.action = action
+	}
+}
+
+// WithReplicaCounter counts the ready replicas of the deployments of the
+// targets with a per-replica threshold
+func WithReplicaCounter(counter ReplicaCounter) Option {
+	return func(w *Watchdog) {
+		w.replicas = counter
+	}
+}
+
+// WithPodStarts reads the start times of pods for the targets with a
+// warm-up
+func WithPodStarts(starts PodStarts) Option {
+	return func(w *Watchdog) {
+		w.podStarts = starts
+	}
+}
+
+// WithPodOwners reads the owners of pods, so that the pods of Jobs are left
+// out of the namespace scope of targets unless they include them. Without
+// it, batch pods are counted, and targets setting BatchPodsExclude fail to
+// start.
+func WithPodOwners(owners PodOwners) Option {
+	return func(w *Watchdog) {
+		w.podOwners = owners
+	}
+}
+
+// WithSwapSource reads the swap usage of the targets with a swap threshold
+// from swap
+func WithSwapSource(swap SwapProvider) Option {
+	return func(w *Watchdog) {
+		w.swap = swap
+	}
+}
+
+// WithThrottlingSource reads the CPU throttling of the targets with a
+// throttling threshold from throttling
+func WithThrottlingSource(throttling ThrottlingProvider) Option {
+	return func(w *Watchdog) {
+		w.throttle = throttling
+	}
+}
+
+// WithColorResolver resolves the active deployment of the blue/green targets
+// with colors
+func WithColorResolver(colors ColorResolver) Option {
+	return func(w *Watchdog) {
+		w.colors = colors
+	}
+}
+
+// WithAvailability reads the available replicas of the deployments of the
+// targets guarded by AvailableGuard
+func WithAvailability(availability Availability) Option {
+	return func(w *Watchdog) {
+		w.available = availability
+	}
+}
+
+// WithRolloutStatus defers the restarts of deployments with a rollout in
+// progress, as reported by rollouts, until it settles
+func WithRolloutStatus(rollouts RolloutStatus) Option {
+	return func(w *Watchdog) {
+		w.rollouts = rollouts
+	}
+}
+
+// WithRolloutUndoer rolls back the deployments of the targets with Rollback
+// set whose restart failed
+func WithRolloutUndoer(undo RolloutUndoer) Option {
+	return func(w *Watchdog) {
+		w.undo = undo
+	}
+}
+
+// WithTenantPolicies reads the targets of the tenant namespaces of the
+// multi-tenant mode from policies
+func WithTenantPolicies(policies TenantPolicies) Option {
+	return func(w *Watchdog) {
+		w.tenants = policies
+	}
+}
+
+// WithTelemetry reports the watchdog's own metrics to t
+func WithTelemetry(t *telemetry.Telemetry) Option {
+	return func(w *Watchdog) {
+		w.telemetry = t
+	}
+}
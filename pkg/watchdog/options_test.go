@@ -0,0 +1,140 @@
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"log"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestWatchdogOptions(t *testing.T) {
+	client := watchdogtest.NewFakeClient(3000)
+	fakeClock := watchdogtest.NewFakeClock(time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC))
+	store := NewMemoryStateStore(0)
+	var logs bytes.Buffer
+
+	var mu sync.Mutex
+	var events []EventType
+	notifier := NotifierFunc(func(ctx context.Context, event Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		events = append(events, event.Type)
+		return nil
+	})
+
+	var executed []string
+	action := ActionFunc(func(ctx context.Context, target Target) error {
+		executed = append(executed, target.Name)
+		return errors.New("scale failed")
+	})
+
+	watchdog := NewWatchdog(client, client, Config{MemoryThreshold: 2000},
+		WithNotifier(notifier),
+		WithLogger(log.New(&logs, "", 0)),
+		WithClock(fakeClock),
+		WithStateStore(store),
+		WithAction(action),
+	)
+
+	target := Target{Name: "default/app", Namespace: "default", DeploymentName: "app", MemoryThreshold: 2000}
+	if result := watchdog.check(context.Background(), target); result.Err == nil {
+		t.Fatal("check() expected the action error")
+	}
+
+	if len(executed) != 1 || len(client.Restarts()) != 0 {
+		t.Errorf("custom action executed %d times and client restarted %d times, want 1 and 0",
+			len(executed), len(client.Restarts()))
+	}
+	if len(events) != 2 || events[0] != EventBreach || events[1] != EventRestartFailed {
+		t.Errorf("events = %v, want [breach restart_failed]", events)
+	}
+	if !strings.Contains(logs.String(), "exceeded threshold") {
+		t.Errorf("custom logger did not receive output: %q", logs.String())
+	}
+
+	records, _ := store.List(context.Background(), time.Time{})
+	if len(records) != 1 {
+		t.Fatalf("store has %d records, want 1", len(records))
+	}
+	record := records[0]
+	if !record.Time.Equal(fakeClock.Now()) || record.Memory != 3000 || !record.Breached || record.Error == "" {
+		t.Errorf("record = %+v", record)
+	}
+}
+
+func TestWithLogging(t *testing.T) {
+	client := watchdogtest.NewFakeClient(1000)
+	var records []logging.Record
+	sink := logging.SinkFunc(func(record logging.Record) {
+		records = append(records, record)
+	})
+	logger := logging.NewWithOutputs(logging.Output{Sink: sink, Levels: logging.Levels{Default: logging.LevelDebug}})
+
+	watchdog := NewWatchdog(client, client, Config{MemoryThreshold: 2000}, WithLogging(logger))
+	watchdog.check(context.Background(), Target{Name: "default/app", Namespace: "default", DeploymentName: "app", MemoryThreshold: 2000})
+
+	components := make(map[string]bool)
+	for _, record := range records {
+		if record.Level != logging.LevelDebug {
+			t.Errorf("record %+v, want the debug level", record)
+		}
+		components[record.Component] = true
+	}
+	if !components["watchdog"] || !components["metrics"] {
+		t.Errorf("records = %+v, want the watchdog and metrics components", records)
+	}
+
+	// a nil logger discards everything
+	NewWatchdog(client, client, Config{MemoryThreshold: 2000}, WithLogging(nil)).
+		check(context.Background(), Target{Name: "default/app", Namespace: "default", DeploymentName: "app", MemoryThreshold: 500})
+}
+
+func TestMemoryStateStore(t *testing.T) {
+	store := NewMemoryStateStore(2)
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		store.Save(context.Background(), Record{Time: start.Add(time.Duration(i) * time.Hour), Memory: i})
+	}
+
+	records, _ := store.List(context.Background(), time.Time{})
+	if len(records) != 2 || records[0].Memory != 1 || records[1].Memory != 2 {
+		t.Errorf("List() = %+v, want the 2 newest records", records)
+	}
+
+	records, _ = store.List(context.Background(), start.Add(2*time.Hour))
+	if len(records) != 1 || records[0].Memory != 2 {
+		t.Errorf("List(since) = %+v, want only the newest record", records)
+	}
+}
+
+func TestFileStateStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	store := NewFileStateStore(path)
+
+	if records, err := store.List(context.Background(), time.Time{}); err != nil || len(records) != 0 {
+		t.Errorf("List() of a missing file = %+v, %v, want no records", records, err)
+	}
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		if err := store.Save(context.Background(), Record{Time: start.Add(time.Duration(i) * time.Hour), Target: "default/app", Memory: i}); err != nil {
+			t.Fatalf("Save() error = %v", err)
+		}
+	}
+
+	records, err := NewFileStateStore(path).List(context.Background(), start.Add(time.Hour))
+	if err != nil {
+		t.Fatalf("List() error = %v", err)
+	}
+	if len(records) != 2 || records[0].Memory != 1 || records[1].Memory != 2 || records[1].Target != "default/app" {
+		t.Errorf("List(since) = %+v, want the 2 newest records", records)
+	}
+}
@@ -0,0 +1,87 @@
+package watchdog
+
+import (
+	"math"
+	"sort"
+)
+
+// OutlierConfig configures the rejection of readings far from the recent
+// ones of a target. A reading is rejected when it is more than K deviations
+// away from the median of the last Window readings, measured as the median
+// absolute deviation ("mad", the default) or the standard deviation
+// ("stddev"). A Window of zero disables the filter.
+type OutlierConfig struct {
+	Window int     `yaml:"window"`
+	K      float64 `yaml:"k"`
+	Method string  `yaml:"method"`
+}
+
+// minOutlierSamples is how many readings the window needs before readings
+// can be rejected
+const minOutlierSamples = 3
+
+// isOutlier reports whether memory is an outlier compared to window
+func (c OutlierConfig) isOutlier(window []int, memory int) bool {
+	if c.Window <= 0 || c.K <= 0 || len(window) < minOutlierSamples {
+		return false
+	}
+
+	values := make([]float64, len(window))
+	for i, v := range window {
+		values[i] = float64(v)
+	}
+	center := median(values)
+
+	var spread float64
+	if c.Method == "stddev" {
+		var sum float64
+		for _, v := range values {
+			sum += (v - center) * (v - center)
+		}
+		spread = math.Sqrt(sum / float64(len(values)))
+	} else {
+		deviations := make([]float64, len(values))
+		for i, v := range values {
+			deviations[i] = math.Abs(v - center)
+		}
+		// scaled to be comparable with the standard deviation of normal data
+		spread = 1.4826 * median(deviations)
+	}
+	if spread == 0 {
+		// identical readings give no measure of what deviating means
+		return false
+	}
+	return math.Abs(float64(memory)-center) > c.K*spread
+}
+
+// median returns the median of values, reordering them
+func median(values []float64) float64 {
+	sort.Float64s(values)
+	n := len(values)
+	if n%2 == 1 {
+		return values[n/2]
+	}
+	return (values[n/2-1] + values[n/2]) / 2
+}
+
+// observe checks a reading of a target against its window and then adds it
+// to the window. Outliers are added too, so a lasting change in usage
+// becomes the new normal once it fills half the window.
+func (w *Watchdog) observe(target Target, memory int) bool {
+	config := w.config.Outliers
+	if config.Window <= 0 {
+		return false
+	}
+
+	w.windowMu.Lock()
+	defer w.windowMu.Unlock()
+
+	window := w.windows[target.Name]
+	outlier := config.isOutlier(window, memory)
+	window = append(window, memory)
+	if len(window) > config.Window {
+		window = window[len(window)-config.Window:]
+	}
+	w.windows[target.Name] = window
+	return outlier
+}
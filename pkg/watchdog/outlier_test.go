@@ -0,0 +1,64 @@
+package watchdog
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestOutlierConfigIsOutlier(t *testing.T) {
+	window := []int{1000, 1020, 990, 1010, 1005}
+	tests := []struct {
+		name     string
+		config   OutlierConfig
+		window   []int
+		memory   int
+		expected bool
+	}{
+		{name: "disabled", config: OutlierConfig{}, window: window, memory: 90000, expected: false},
+		{name: "mad spike", config: OutlierConfig{Window: 5, K: 3}, window: window, memory: 90000, expected: true},
+		{name: "mad normal", config: OutlierConfig{Window: 5, K: 3}, window: window, memory: 1020, expected: false},
+		{name: "stddev spike", config: OutlierConfig{Window: 5, K: 3, Method: "stddev"}, window: window, memory: 5000, expected: true},
+		{name: "stddev normal", config: OutlierConfig{Window: 5, K: 3, Method: "stddev"}, window: window, memory: 1020, expected: false},
+		{name: "too few samples", config: OutlierConfig{Window: 5, K: 3}, window: window[:2], memory: 90000, expected: false},
+		{name: "no spread", config: OutlierConfig{Window: 5, K: 3}, window: []int{1000, 1000, 1000}, memory: 1001, expected: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if result := tt.config.isOutlier(tt.window, tt.memory); result != tt.expected {
+				t.Errorf("isOutlier() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestCheckRejectsOutliers(t *testing.T) {
+	client := watchdogtest.NewFakeClient(1000, 1010, 990, 1005, 90000, 1000)
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "default",
+		DeploymentName:  "app",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Outliers:        OutlierConfig{Window: 10, K: 3.5},
+	})
+
+	var results []CheckResult
+	for i := 0; i < 6; i++ {
+		result, _ := watchdog.CheckTarget(context.Background(), "default/app")
+		results = append(results, result)
+	}
+
+	spike := results[4]
+	if spike.Memory != 90000 || !spike.Outlier || spike.Breached {
+		t.Errorf("CheckTarget() of the spike = %+v, want a rejected outlier", spike)
+	}
+	if len(client.Restarts()) != 0 {
+		t.Errorf("Restarts() = %v, want none for an outlier", client.Restarts())
+	}
+	if results[5].Outlier {
+		t.Errorf("CheckTarget() after the spike = %+v, want a normal reading", results[5])
+	}
+}
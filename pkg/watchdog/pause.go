@@ -0,0 +1,75 @@
+package watchdog
+
+import (
+	"fmt"
+	"time"
+)
+
+// Pause stops the scheduled checks of a target, as requested by an
+// operator, until it expires. A zero Until pauses the target until it is
+// resumed.
+type Pause struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// expired reports whether the pause is over at now
+func (p *Pause) expired(now time.Time) bool {
+	return p != nil && !p.Until.IsZero() && !now.Before(p.Until)
+}
+
+// PauseTarget stops the scheduled checks of the named target for duration,
+// or until it is resumed when duration is zero, and returns the pause. The
+// target resumes on its own once the pause expires. Pausing a target again
+// replaces its pause. The pause is logged by the audit component with
+// reason.
+func (w *Watchdog) PauseTarget(name string, duration time.Duration, reason string) (Pause, error) {
+	if duration < 0 {
+		return Pause{}, fmt.Errorf("invalid pause duration %s", duration)
+	}
+	pause := Pause{Reason: reason}
+	if duration > 0 {
+		pause.Until = w.clock.Now().Add(duration)
+	}
+
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	if exists {
+		loop.paused = true
+		loop.pause = &pause
+	}
+	w.mu.Unlock()
+	if !exists {
+		return Pause{}, fmt.Errorf("%w: '%s'", ErrTargetNotFound, name)
+	}
+
+	if pause.Until.IsZero() {
+		w.auditLog.Infof("Paused the checks of target '%s' until resumed: %s", name, reason)
+	} else {
+		w.auditLog.Infof("Paused the checks of target '%s' until %s: %s", name, pause.Until.Format(time.RFC3339), reason)
+	}
+	return pause, nil
+}
+
+// ResumeTarget restarts the scheduled checks of the named target before its
+// pause expires. It is logged like PauseTarget, and fails with ErrNotPaused
+// when the target isn't paused.
+func (w *Watchdog) ResumeTarget(name, reason string) error {
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	paused := exists && loop.paused && !loop.pause.expired(w.clock.Now())
+	if exists {
+		loop.paused = false
+		loop.pause = nil
+	}
+	w.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("%w: '%s'", ErrTargetNotFound, name)
+	}
+	if !paused {
+		return fmt.Errorf("%w: '%s'", ErrNotPaused, name)
+	}
+
+	w.auditLog.Infof("Resumed the checks of target '%s': %s", name, reason)
+	return nil
+}
@@ -0,0 +1,38 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+)
+
+// ReplicaCounter reports the number of ready replicas of a deployment, for
+// the targets whose threshold is set per replica
+type ReplicaCounter interface {
+	ReadyReplicas(ctx context.Context, namespace, deployment string) (int, error)
+}
+
+// perReplica returns target with its threshold set to its BaseMemory plus
+// its ReplicaMemory times the ready replicas of its deployment, or target
+// unchanged when it has no per-replica threshold
+func (w *Watchdog) perReplica(ctx context.Context, target Target) (Target, error) {
+	if target.ReplicaMemory <= 0 {
+		return target, nil
+	}
+	if w.replicas == nil {
+		return target, fmt.Errorf("%w: no replica counter to apply a per-replica threshold", ErrMetricsUnavailable)
+	}
+
+	replicasCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+	replicas, err := w.replicas.ReadyReplicas(replicasCtx, target.Namespace, target.DeploymentName)
+	cancel()
+	if err != nil {
+		return target, err
+	}
+	if replicas == 0 {
+		return target, fmt.Errorf("%w: deployment '%s' has no ready replicas", ErrMetricsUnavailable, target.DeploymentName)
+	}
+
+	target.MemoryThreshold = target.BaseMemory + target.ReplicaMemory*replicas
+	w.logger.Debugf("Threshold of target '%s' is %dMi for %d ready replicas", target.Name, target.MemoryThreshold, replicas)
+	return target, nil
+}
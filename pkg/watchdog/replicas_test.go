@@ -0,0 +1,67 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// replicaCount reports a fixed number of ready replicas for every deployment
+type replicaCount int
+
+func (c replicaCount) ReadyReplicas(ctx context.Context, namespace, deployment string) (int, error) {
+	return int(c), nil
+}
+
+func TestPerReplicaThreshold(t *testing.T) {
+	tests := []struct {
+		name      string
+		counter   ReplicaCounter
+		base      int
+		threshold int
+		breached  bool
+		wantErr   error
+	}{
+		{name: "three replicas", counter: replicaCount(3), threshold: 1500, breached: true},
+		{name: "four replicas", counter: replicaCount(4), threshold: 2000},
+		{name: "base and three replicas", counter: replicaCount(3), base: 200, threshold: 1700},
+		{name: "base and two replicas", counter: replicaCount(2), base: 200, threshold: 1200, breached: true},
+		{name: "no ready replicas", counter: replicaCount(0), wantErr: ErrMetricsUnavailable},
+		{name: "no counter", wantErr: ErrMetricsUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []Option
+			if tt.counter != nil {
+				options = append(options, WithReplicaCounter(tt.counter))
+			}
+			client := watchdogtest.NewFakeClient(1600)
+			watchdog := NewWatchdog(client, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 5000,
+				ReplicaMemory:   500,
+				BaseMemory:      tt.base,
+				CheckInterval:   time.Minute,
+			}, options...)
+
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !errors.Is(result.Err, tt.wantErr) || (tt.wantErr == nil && result.Err != nil) {
+				t.Fatalf("CheckTarget() error = %v, want %v", result.Err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if result.Threshold != tt.threshold || result.Breached != tt.breached {
+				t.Errorf("CheckTarget() threshold = %d, breached = %v, want %d, %v", result.Threshold, result.Breached, tt.threshold, tt.breached)
+			}
+		})
+	}
+}
@@ -0,0 +1,102 @@
+package watchdog
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+)
+
+// CheckResult is the outcome of checking a single target
+type CheckResult struct {
+	Target    Target        `json:"target"`
+	Memory    int           `json:"memory"`
+	Pod       *PodMemory    `json:"pod,omitempty"`
+	Swap      int           `json:"swap,omitempty"`
+	Throttled int           `json:"throttled,omitempty"`
+	Source    string        `json:"source,omitempty"`
+	Threshold int           `json:"threshold"`
+	Baseline  int           `json:"baseline,omitempty"`
+	Breached  bool          `json:"breached"`
+	Outlier   bool          `json:"outlier,omitempty"`
+	WarmingUp bool          `json:"warming_up,omitempty"`
+	Frozen    string        `json:"frozen,omitempty"`
+	Held      string        `json:"held,omitempty"`
+	Escalated string        `json:"escalated,omitempty"`
+	Deferred  string        `json:"deferred,omitempty"`
+	Circuit   string        `json:"circuit,omitempty"`
+	Disabled  string        `json:"disabled,omitempty"`
+	Resolved  bool          `json:"resolved,omitempty"`
+	Action    string        `json:"action,omitempty"`
+	Time      time.Time     `json:"time"`
+	Duration  time.Duration `json:"duration"`
+	CheckID   string        `json:"check_id,omitempty"`
+	ActionID  string        `json:"action_id,omitempty"`
+	Err       error         `json:"-"`
+}
+
+// MarshalJSON encodes the result with its error as a string
+func (r CheckResult) MarshalJSON() ([]byte, error) {
+	type plain CheckResult
+	out := struct {
+		plain
+		Error string `json:"error,omitempty"`
+	}{plain: plain(r)}
+	if r.Err != nil {
+		out.Error = r.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON decodes a result encoded by MarshalJSON, with its error
+// restored from the string
+func (r *CheckResult) UnmarshalJSON(data []byte) error {
+	type plain CheckResult
+	var in struct {
+		plain
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*r = CheckResult(in.plain)
+	if in.Error != "" {
+		r.Err = errors.New(in.Error)
+	}
+	return nil
+}
+
+// correlation returns the IDs of the check and action of the result for
+// log messages, such as " [check 3f2a9c1e5b7d4a60]"
+func (r CheckResult) correlation() string {
+	var ids []string
+	if r.CheckID != "" {
+		ids = append(ids, "check "+r.CheckID)
+	}
+	if r.ActionID != "" {
+		ids = append(ids, "action "+r.ActionID)
+	}
+	if len(ids) == 0 {
+		return ""
+	}
+	return " [" + strings.Join(ids, ", ") + "]"
+}
+
+// record converts the result into a persisted Record
+func (r CheckResult) record() Record {
+	record := Record{
+		Time:      r.Time,
+		Target:    r.Target.Name,
+		Namespace: r.Target.Namespace,
+		Memory:    r.Memory,
+		Threshold: r.Threshold,
+		Breached:  r.Breached,
+		Action:    r.Action,
+		CheckID:   r.CheckID,
+		ActionID:  r.ActionID,
+	}
+	if r.Err != nil {
+		record.Error = r.Err.Error()
+	}
+	return record
+}
@@ -0,0 +1,45 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// RolloutUndoer rolls a deployment back to its previous revision
+type RolloutUndoer interface {
+	UndoRollout(ctx context.Context, namespace, deployment string) error
+}
+
+// rollBack undoes the rollout of the deployment of target once its restart
+// failed with restartErr, when the target has Rollback set, rather than
+// leaving it half rolled out. Only a restart whose new revision didn't
+// become ready, ErrRolloutNotReady, is undone: a restart failing before it
+// rolled anything out would otherwise revert the last deploy of the team.
+// The outcome is sent as an EventRollback.
+func (w *Watchdog) rollBack(ctx context.Context, target Target, memory int, restartErr error) {
+	if !target.Rollback {
+		return
+	}
+	if !errors.Is(restartErr, ErrRolloutNotReady) {
+		w.logger.Infof("Not rolling back deployment '%s', its restart failed before rolling out: %v", target.DeploymentName, restartErr)
+		return
+	}
+
+	event := w.event(EventRollback, target, memory, nil)
+	event.Reason = restartErr.Error()
+	if w.undo == nil {
+		event.Err = fmt.Errorf("%w: no way to undo the rollout", ErrRestartFailed)
+	} else {
+		// the restart may have used up its own timeout
+		undoCtx, cancel := withOptionalTimeout(ctx, w.config.RestartTimeout)
+		event.Err = w.undo.UndoRollout(undoCtx, target.Namespace, target.DeploymentName)
+		cancel()
+	}
+	if event.Err != nil {
+		w.logger.Errorf("Error rolling back deployment '%s' after its failed restart: %v", target.DeploymentName, event.Err)
+	} else {
+		w.logger.Warnf("Rolled back deployment '%s' after its failed restart", target.DeploymentName)
+	}
+	w.notify(ctx, event)
+}
@@ -0,0 +1,100 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// rolloutUndoer records the deployments rolled back, or fails with err
+type rolloutUndoer struct {
+	undone []string
+	err    error
+}
+
+func (u *rolloutUndoer) UndoRollout(ctx context.Context, namespace, deployment string) error {
+	u.undone = append(u.undone, namespace+"/"+deployment)
+	return u.err
+}
+
+func TestRollback(t *testing.T) {
+	tests := []struct {
+		name       string
+		rollback   bool
+		restartErr error
+		undo       *rolloutUndoer
+		undone     bool
+		undoErr    bool
+	}{
+		{name: "rolled back", rollback: true, undo: &rolloutUndoer{}, undone: true},
+		{name: "failed rollback", rollback: true, undo: &rolloutUndoer{err: errors.New("forbidden")}, undone: true, undoErr: true},
+		{name: "no undoer", rollback: true, undoErr: true},
+		{name: "rollback disabled", undo: &rolloutUndoer{}},
+		// nothing was rolled out, so undoing would revert the last deploy
+		{name: "annotate failed", rollback: true, restartErr: fmt.Errorf("%w: annotate: timeout", ErrRestartFailed), undo: &rolloutUndoer{}},
+		{name: "restart refused", rollback: true, restartErr: fmt.Errorf("%w: rollout restart", ErrForbidden), undo: &rolloutUndoer{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []Event
+			options := []Option{WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+				if event.Type != EventSilenced {
+					events = append(events, event)
+				}
+				return nil
+			}))}
+			if tt.undo != nil {
+				options = append(options, WithRolloutUndoer(tt.undo))
+			}
+			client := watchdogtest.NewFakeClient(3000)
+			restartErr := tt.restartErr
+			if restartErr == nil {
+				restartErr = RolloutNotReady(errors.New("rollout timed out"))
+			}
+			client.FailRestarts(restartErr, 1)
+			watchdog := NewWatchdog(client, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 2000,
+				Rollback:        tt.rollback,
+				CheckInterval:   time.Minute,
+			}, options...)
+			// the rollback is critical, so it isn't silenced like the
+			// breach and the failed restart
+			if _, err := watchdog.Silence(context.Background(), "prod/api", time.Hour, "maintenance"); err != nil {
+				t.Fatal(err)
+			}
+
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Err == nil {
+				t.Fatal("CheckTarget() error = nil, want the restart error")
+			}
+			if !tt.rollback || tt.restartErr != nil {
+				if len(events) != 0 {
+					t.Errorf("events = %+v, want none", events)
+				}
+				if tt.undo != nil && len(tt.undo.undone) != 0 {
+					t.Errorf("undone = %v, want no rollback", tt.undo.undone)
+				}
+				return
+			}
+			if len(events) != 1 || events[0].Type != EventRollback || events[0].Reason != "rollout timed out" {
+				t.Fatalf("events = %+v, want a rollback event", events)
+			}
+			if (events[0].Err != nil) != tt.undoErr {
+				t.Errorf("rollback error = %v, want an error %v", events[0].Err, tt.undoErr)
+			}
+			if tt.undo != nil && (len(tt.undo.undone) == 1 && tt.undo.undone[0] == "prod/api") != tt.undone {
+				t.Errorf("undone = %v, want prod/api rolled back %v", tt.undo.undone, tt.undone)
+			}
+		})
+	}
+}
@@ -0,0 +1,29 @@
+package watchdog
+
+import "context"
+
+// RolloutStatus reports whether a deployment is in the middle of a rollout,
+// and why
+type RolloutStatus interface {
+	RolloutInProgress(ctx context.Context, namespace, deployment string) (string, bool, error)
+}
+
+// rollingOut returns why the restart of the deployment of target is
+// deferred, when a rollout is already in progress: restarting it would stack
+// a second rollout on top of a deploy that just happened, and the memory of
+// pods being replaced says little anyway. A failed lookup doesn't hold the
+// restart back.
+func (w *Watchdog) rollingOut(ctx context.Context, target Target, result CheckResult) (string, bool) {
+	if w.rollouts == nil {
+		return "", false
+	}
+
+	rolloutCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+	reason, inProgress, err := w.rollouts.RolloutInProgress(rolloutCtx, target.Namespace, target.DeploymentName)
+	cancel()
+	if err != nil {
+		w.logger.Warnf("Error checking the rollout status of deployment '%s': %v%s", target.DeploymentName, err, result.correlation())
+		return "", false
+	}
+	return reason, inProgress
+}
@@ -0,0 +1,73 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// rolloutStatus reports a fixed rollout status, or fails with err
+type rolloutStatus struct {
+	reason string
+	err    error
+}
+
+func (s rolloutStatus) RolloutInProgress(ctx context.Context, namespace, deployment string) (string, bool, error) {
+	return s.reason, s.reason != "", s.err
+}
+
+func TestRolloutInProgress(t *testing.T) {
+	tests := []struct {
+		name     string
+		rollouts RolloutStatus
+		deferred string
+	}{
+		{name: "rollout in progress", rollouts: rolloutStatus{reason: "1 of 3 replicas updated"}, deferred: "1 of 3 replicas updated"},
+		{name: "rollout settled", rollouts: rolloutStatus{}},
+		{name: "failed lookup", rollouts: rolloutStatus{err: errors.New("forbidden")}},
+		{name: "no rollout status"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var events []Event
+			options := []Option{WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+				events = append(events, event)
+				return nil
+			}))}
+			if tt.rollouts != nil {
+				options = append(options, WithRolloutStatus(tt.rollouts))
+			}
+			client := watchdogtest.NewFakeClient(3000)
+			watchdog := NewWatchdog(client, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 2000,
+				CheckInterval:   time.Minute,
+			}, options...)
+
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !result.Breached || result.Deferred != tt.deferred {
+				t.Fatalf("CheckTarget() breached = %v, deferred = %q, want a breach deferred by %q", result.Breached, result.Deferred, tt.deferred)
+			}
+			if tt.deferred == "" {
+				if len(client.Restarts()) != 1 {
+					t.Errorf("Restarts() = %v, want one", client.Restarts())
+				}
+				return
+			}
+			if len(client.Restarts()) != 0 {
+				t.Errorf("Restarts() = %v, want none during a rollout", client.Restarts())
+			}
+			if len(events) != 2 || events[1].Type != EventSuppressed || events[1].Reason != "rollout in progress: "+tt.deferred {
+				t.Errorf("events = %+v, want a breach and a suppressed event", events)
+			}
+		})
+	}
+}
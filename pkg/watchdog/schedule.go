@@ -0,0 +1,104 @@
+package watchdog
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ThresholdSchedule overrides the memory threshold of a target during part
+// of the day. Start and End are given as "15:04", and a schedule ending
+// before it starts runs overnight into the next day. Days restricts the
+// schedule to the days it starts on ("mon", "tue", ...); by default it
+// applies every day.
+type ThresholdSchedule struct {
+	Days            []string `yaml:"days" json:"days,omitempty"`
+	Start           string   `yaml:"start" json:"start"`
+	End             string   `yaml:"end" json:"end"`
+	MemoryThreshold int      `yaml:"memory_threshold" json:"memory_threshold"`
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// validate checks the times and days of the schedule
+func (s ThresholdSchedule) validate() error {
+	if _, err := parseClock(s.Start); err != nil {
+		return err
+	}
+	if _, err := parseClock(s.End); err != nil {
+		return err
+	}
+	for _, day := range s.Days {
+		if _, ok := weekdays[strings.ToLower(day)]; !ok {
+			return fmt.Errorf("invalid day of the week '%s'", day)
+		}
+	}
+	if s.MemoryThreshold <= 0 {
+		return fmt.Errorf("schedule %s-%s has no memory threshold", s.Start, s.End)
+	}
+	return nil
+}
+
+// active reports whether the schedule applies at now. Invalid schedules
+// never apply.
+func (s ThresholdSchedule) active(now time.Time) bool {
+	start, err := parseClock(s.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(s.End)
+	if err != nil {
+		return false
+	}
+
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end && s.onDay(now.Weekday())
+	}
+	if minute >= start {
+		return s.onDay(now.Weekday())
+	}
+	return minute < end && s.onDay((now.Weekday()+6)%7)
+}
+
+// onDay reports whether the schedule starts on day
+func (s ThresholdSchedule) onDay(day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses a "15:04" time of day into minutes since midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time of day '%s', want HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// thresholdAt returns the memory threshold of the target at now: that of
+// the first active schedule, or the target's own threshold
+func (t Target) thresholdAt(now time.Time) int {
+	now = now.In(t.location())
+	for _, schedule := range t.Schedules {
+		if schedule.active(now) {
+			return schedule.MemoryThreshold
+		}
+	}
+	return t.MemoryThreshold
+}
@@ -0,0 +1,92 @@
+package watchdog
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTargetThresholdAt(t *testing.T) {
+	target := Target{
+		MemoryThreshold: 5000,
+		Schedules: []ThresholdSchedule{
+			{Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "08:00", End: "18:00", MemoryThreshold: 4000},
+			{Days: []string{"fri"}, Start: "22:00", End: "06:00", MemoryThreshold: 6000},
+		},
+	}
+
+	tests := []struct {
+		name     string
+		time     string
+		expected int
+	}{
+		{name: "business hours", time: "2024-03-13T10:00:00Z", expected: 4000},
+		{name: "end is exclusive", time: "2024-03-13T18:00:00Z", expected: 5000},
+		{name: "weekend", time: "2024-03-16T10:00:00Z", expected: 5000},
+		{name: "overnight start", time: "2024-03-15T23:30:00Z", expected: 6000},
+		{name: "overnight next day", time: "2024-03-16T05:59:00Z", expected: 6000},
+		{name: "overnight other day", time: "2024-03-14T05:00:00Z", expected: 5000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tt.time)
+			if result := target.thresholdAt(now); result != tt.expected {
+				t.Errorf("thresholdAt() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+}
+
+func TestThresholdScheduleValidate(t *testing.T) {
+	tests := []struct {
+		name     string
+		schedule ThresholdSchedule
+		wantErr  bool
+	}{
+		{name: "valid", schedule: ThresholdSchedule{Days: []string{"Sat"}, Start: "00:00", End: "06:00", MemoryThreshold: 1}},
+		{name: "invalid time", schedule: ThresholdSchedule{Start: "8am", End: "06:00", MemoryThreshold: 1}, wantErr: true},
+		{name: "invalid day", schedule: ThresholdSchedule{Days: []string{"someday"}, Start: "00:00", End: "06:00", MemoryThreshold: 1}, wantErr: true},
+		{name: "no threshold", schedule: ThresholdSchedule{Start: "00:00", End: "06:00"}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.schedule.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestTargetThresholdAtTimezone(t *testing.T) {
+	if _, err := LoadLocation("America/Sao_Paulo"); err != nil {
+		t.Skipf("no time zone database: %v", err)
+	}
+	target := Target{
+		MemoryThreshold: 5000,
+		Timezone:        "America/Sao_Paulo",
+		Schedules:       []ThresholdSchedule{{Start: "08:00", End: "18:00", MemoryThreshold: 4000}},
+	}
+
+	tests := []struct {
+		time     string
+		expected int
+	}{
+		{time: "2024-03-13T10:00:00Z", expected: 5000},
+		{time: "2024-03-13T12:00:00Z", expected: 4000},
+		{time: "2024-03-13T20:30:00Z", expected: 4000},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.time, func(t *testing.T) {
+			now, _ := time.Parse(time.RFC3339, tt.time)
+			if result := target.thresholdAt(now); result != tt.expected {
+				t.Errorf("thresholdAt() = %v, want %v", result, tt.expected)
+			}
+		})
+	}
+
+	if _, err := LoadLocation("Mars/Olympus_Mons"); err == nil {
+		t.Error("LoadLocation() with an unknown zone expected an error")
+	}
+}
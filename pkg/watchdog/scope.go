@@ -0,0 +1,141 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// Scopes of the memory usage compared with the threshold of a target
+const (
+	// ScopeNamespace sums the memory usage of every pod in the namespace
+	ScopeNamespace = "namespace"
+	// ScopeDeployment sums the memory usage of the pods of the deployment
+	ScopeDeployment = "deployment"
+	// ScopePod compares the memory usage of each pod of the deployment with
+	// the threshold, so the largest pod decides unless another aggregation
+	// is set
+	ScopePod = "pod"
+)
+
+// PodMemory is the memory usage of a single pod, in Mi
+type PodMemory struct {
+	Pod    string `json:"pod"`
+	Memory int    `json:"memory"`
+}
+
+// reading is the memory usage of a target read from a source, with the
+// largest pod of its deployment when the source reports each pod. Without
+// any pod out of its warm-up, the reading is only warmingUp.
+type reading struct {
+	memory    int
+	largest   *PodMemory
+	warmingUp bool
+}
+
+// PodMetricsProvider is implemented by sources able to report the memory
+// usage of each pod in a namespace, rather than only the total. Targets
+// scoped to their deployment or pods require it.
+type PodMetricsProvider interface {
+	GetPodMemory(ctx context.Context, namespace string) ([]PodMemory, error)
+}
+
+// validScope reports whether scope is empty, for the namespace, or one of
+// the scopes
+func validScope(scope string) bool {
+	switch scope {
+	case "", ScopeNamespace, ScopeDeployment, ScopePod:
+		return true
+	}
+	return false
+}
+
+// usage reads the memory usage of target from provider in the scope of the
+// target, aggregated over its pods except the warming ones
+func (w *Watchdog) usage(ctx context.Context, provider MetricsProvider, target Target, warming map[string]bool) (reading, error) {
+	namespaceScope := target.Scope == "" || target.Scope == ScopeNamespace
+	aggregation := target.aggregation()
+	source, ok := provider.(PodMetricsProvider)
+	// batch pods can only be told apart in the readings of each pod
+	excludeBatch := ok && namespaceScope && target.BatchPods != BatchPodsInclude && w.podOwners != nil
+	if namespaceScope && aggregation == AggregateSum && target.PodThreshold <= 0 && warming == nil && !excludeBatch {
+		memory, err := provider.GetPodMemoryUsage(ctx, target.Namespace)
+		return reading{memory: memory}, err
+	}
+	if !ok {
+		switch {
+		case !namespaceScope:
+			return reading{}, fmt.Errorf("%w: the %s scope requires a source reporting the memory of each pod", ErrNoPodMetrics, target.Scope)
+		case aggregation != AggregateSum:
+			return reading{}, fmt.Errorf("%w: the %s aggregation requires a source reporting the memory of each pod", ErrNoPodMetrics, aggregation)
+		case warming != nil:
+			return reading{}, fmt.Errorf("%w: the warm-up requires a source reporting the memory of each pod", ErrNoPodMetrics)
+		}
+		return reading{}, fmt.Errorf("%w: the pod threshold requires a source reporting the memory of each pod", ErrNoPodMetrics)
+	}
+	pods, err := source.GetPodMemory(ctx, target.Namespace)
+	if err != nil {
+		return reading{}, err
+	}
+	var batch map[string]bool
+	if excludeBatch {
+		if batch, err = w.batchPods(ctx, target); err != nil {
+			return reading{}, fmt.Errorf("error getting pod owners: %w", err)
+		}
+	}
+
+	var values []int
+	var largest *PodMemory
+	skipped := 0
+	for _, p := range pods {
+		deploymentPod := isDeploymentPod(target.DeploymentName, p.Pod)
+		if (!namespaceScope && !deploymentPod) || batch[p.Pod] {
+			continue
+		}
+		if warming[p.Pod] {
+			skipped++
+			continue
+		}
+		values = append(values, p.Memory)
+		if deploymentPod && (largest == nil || p.Memory > largest.Memory) {
+			pod := p
+			largest = &pod
+		}
+	}
+	if skipped > 0 {
+		w.metricsLog.Debugf("Ignoring %d pods of target '%s' warming up", skipped, target.Name)
+	}
+	if len(values) == 0 {
+		if skipped > 0 {
+			return reading{warmingUp: true}, nil
+		}
+		if namespaceScope {
+			return reading{}, fmt.Errorf("%w: no pods in namespace '%s'", ErrMetricsUnavailable, target.Namespace)
+		}
+		return reading{}, fmt.Errorf("%w: no pods of deployment '%s' in namespace '%s'", ErrMetricsUnavailable, target.DeploymentName, target.Namespace)
+	}
+	if largest != nil {
+		w.metricsLog.Debugf("Largest pod of target '%s' is '%s' with %dMi", target.Name, largest.Pod, largest.Memory)
+	}
+	memory, err := aggregate(aggregation, values)
+	return reading{memory: memory, largest: largest}, err
+}
+
+// isDeploymentPod reports whether pod is named like the pods of deployment,
+// <deployment>-<pod template hash>-<suffix>
+func isDeploymentPod(deployment, pod string) bool {
+	rest := strings.TrimPrefix(pod, deployment+"-")
+	if rest == pod {
+		return false
+	}
+	parts := strings.Split(rest, "-")
+	if len(parts) != 2 {
+		return false
+	}
+	for _, part := range parts {
+		if part == "" || strings.Trim(part, "abcdefghijklmnopqrstuvwxyz0123456789") != "" {
+			return false
+		}
+	}
+	return true
+}
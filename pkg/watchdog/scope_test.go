@@ -0,0 +1,144 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// podSource reports fixed readings per pod
+type podSource []PodMemory
+
+func (s podSource) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	total := 0
+	for _, p := range s {
+		total += p.Memory
+	}
+	return total, nil
+}
+
+func (s podSource) GetPodMemory(ctx context.Context, namespace string) ([]PodMemory, error) {
+	return s, nil
+}
+
+func TestScope(t *testing.T) {
+	pods := podSource{
+		{Pod: "api-7d9f8b6c5d-x2k4p", Memory: 900},
+		{Pod: "api-7d9f8b6c5d-q8z7m", Memory: 600},
+		{Pod: "api-worker-5c6b7d8f9-h3j4k", Memory: 2000},
+		{Pod: "redis-0", Memory: 500},
+	}
+	tests := []struct {
+		scope       string
+		aggregation string
+		source      MetricsProvider
+		memory      int
+		wantErr     error
+	}{
+		{scope: "", source: pods, memory: 4000},
+		{scope: ScopeNamespace, source: pods, memory: 4000},
+		{scope: ScopeDeployment, source: pods, memory: 1500},
+		{scope: ScopePod, source: pods, memory: 900},
+		{scope: ScopeDeployment, source: podSource{{Pod: "redis-0", Memory: 500}}, wantErr: ErrMetricsUnavailable},
+		{scope: ScopePod, source: watchdogtest.NewFakeClient(1000), wantErr: ErrNoPodMetrics},
+		{scope: ScopeNamespace, aggregation: AggregateMax, source: pods, memory: 2000},
+		{scope: ScopeNamespace, aggregation: AggregateMean, source: pods, memory: 1000},
+		{scope: ScopeDeployment, aggregation: AggregateMean, source: pods, memory: 750},
+		{scope: ScopePod, aggregation: AggregateMedian, source: pods, memory: 750},
+		{scope: ScopeNamespace, aggregation: "p90", source: watchdogtest.NewFakeClient(1000), wantErr: ErrNoPodMetrics},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.scope+"/"+tt.aggregation, func(t *testing.T) {
+			client := watchdogtest.NewFakeClient()
+			watchdog := NewWatchdog(tt.source, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 1000,
+				Scope:           tt.scope,
+				Aggregation:     tt.aggregation,
+				CheckInterval:   time.Minute,
+			})
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !errors.Is(result.Err, tt.wantErr) || (tt.wantErr == nil && result.Err != nil) {
+				t.Fatalf("CheckTarget() error = %v, want %v", result.Err, tt.wantErr)
+			}
+			if result.Memory != tt.memory {
+				t.Errorf("CheckTarget() memory = %d, want %d", result.Memory, tt.memory)
+			}
+		})
+	}
+}
+
+func TestIsDeploymentPod(t *testing.T) {
+	tests := []struct {
+		pod  string
+		want bool
+	}{
+		{pod: "api-7d9f8b6c5d-x2k4p", want: true},
+		{pod: "api-worker-5c6b7d8f9-h3j4k", want: false},
+		{pod: "api-x2k4p", want: false},
+		{pod: "api", want: false},
+		{pod: "web-7d9f8b6c5d-x2k4p", want: false},
+		{pod: "api-7d9f8b6c5d-", want: false},
+	}
+	for _, tt := range tests {
+		if got := isDeploymentPod("api", tt.pod); got != tt.want {
+			t.Errorf("isDeploymentPod(api, %q) = %v, want %v", tt.pod, got, tt.want)
+		}
+	}
+}
+
+func TestPodThreshold(t *testing.T) {
+	pods := podSource{
+		{Pod: "api-7d9f8b6c5d-x2k4p", Memory: 900},
+		{Pod: "api-7d9f8b6c5d-q8z7m", Memory: 600},
+		{Pod: "api-worker-5c6b7d8f9-h3j4k", Memory: 2000},
+	}
+	tests := []struct {
+		name         string
+		source       MetricsProvider
+		podThreshold int
+		breached     bool
+		wantErr      error
+	}{
+		{name: "runaway pod", source: pods, podThreshold: 800, breached: true},
+		{name: "pods under threshold", source: pods, podThreshold: 1000},
+		{name: "total only source", source: watchdogtest.NewFakeClient(1000), podThreshold: 800, wantErr: ErrNoPodMetrics},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := watchdogtest.NewFakeClient()
+			watchdog := NewWatchdog(tt.source, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 5000,
+				PodThreshold:    tt.podThreshold,
+				CheckInterval:   time.Minute,
+			})
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !errors.Is(result.Err, tt.wantErr) || (tt.wantErr == nil && result.Err != nil) {
+				t.Fatalf("CheckTarget() error = %v, want %v", result.Err, tt.wantErr)
+			}
+			if tt.wantErr != nil {
+				return
+			}
+			if result.Memory != 3500 || result.Breached != tt.breached {
+				t.Errorf("CheckTarget() memory = %d, breached = %v, want 3500, %v", result.Memory, result.Breached, tt.breached)
+			}
+			if result.Pod == nil || result.Pod.Pod != "api-7d9f8b6c5d-x2k4p" {
+				t.Errorf("CheckTarget() pod = %+v, want api-7d9f8b6c5d-x2k4p", result.Pod)
+			}
+		})
+	}
+}
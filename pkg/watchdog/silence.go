@@ -0,0 +1,91 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// Silence mutes the notifications of a target until it expires. The target
+// is still checked and remediated, and its events still go to the event
+// streams.
+type Silence struct {
+	Until  time.Time `json:"until"`
+	Reason string    `json:"reason,omitempty"`
+}
+
+// Silence mutes the notifications of the named target for duration, as
+// requested by an operator, and returns the silence. Silencing a target
+// again replaces its silence. The silence is logged by the audit component
+// and sent to the notifiers as a silenced event with reason as its Reason.
+func (w *Watchdog) Silence(ctx context.Context, name string, duration time.Duration, reason string) (Silence, error) {
+	if duration <= 0 {
+		return Silence{}, fmt.Errorf("invalid silence duration %s", duration)
+	}
+	silence := Silence{Until: w.clock.Now().Add(duration), Reason: reason}
+
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	if exists {
+		loop.silence = &silence
+	}
+	w.mu.Unlock()
+	if !exists {
+		return Silence{}, fmt.Errorf("%w: '%s'", ErrTargetNotFound, name)
+	}
+
+	w.auditLog.Infof("Silenced notifications of target '%s' until %s: %s", name, silence.Until.Format(time.RFC3339), reason)
+	event := w.event(EventSilenced, loop.target, 0, nil)
+	event.Reason = reason
+	w.notify(ctx, event)
+	return silence, nil
+}
+
+// Unsilence lifts the silence of the named target before it expires. It
+// is logged and notified like Silence, and fails with ErrNotSilenced when
+// the target has no silence.
+func (w *Watchdog) Unsilence(ctx context.Context, name, reason string) error {
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	silenced := exists && loop.silence != nil && w.clock.Now().Before(loop.silence.Until)
+	if exists {
+		loop.silence = nil
+	}
+	w.mu.Unlock()
+	if !exists {
+		return fmt.Errorf("%w: '%s'", ErrTargetNotFound, name)
+	}
+	if !silenced {
+		return fmt.Errorf("%w: '%s'", ErrNotSilenced, name)
+	}
+
+	w.auditLog.Infof("Lifted the silence of target '%s': %s", name, reason)
+	event := w.event(EventUnsilenced, loop.target, 0, nil)
+	event.Reason = reason
+	w.notify(ctx, event)
+	return nil
+}
+
+// silenced reports whether the notifications of a target are muted. An
+// expired silence is removed, logged and notified as an unsilenced event.
+func (w *Watchdog) silenced(ctx context.Context, name string) bool {
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	if !exists || loop.silence == nil {
+		w.mu.Unlock()
+		return false
+	}
+	if w.clock.Now().Before(loop.silence.Until) {
+		w.mu.Unlock()
+		return true
+	}
+	loop.silence = nil
+	target := loop.target
+	w.mu.Unlock()
+
+	w.auditLog.Infof("Silence of target '%s' expired", name)
+	event := w.event(EventUnsilenced, target, 0, nil)
+	event.Reason = "expired"
+	w.notify(ctx, event)
+	return false
+}
@@ -0,0 +1,109 @@
+package watchdog
+
+import "time"
+
+// SourceConfig selects where memory usage is read from. Type is "kubectl"
+// (the default) or the name of one of the sources configured below.
+type SourceConfig struct {
+	Type       string                `yaml:"type"`
+	Kubectl    KubectlConfig         `yaml:"kubectl"`
+	Datadog    DatadogConfig         `yaml:"datadog"`
+	CloudWatch CloudWatchConfig      `yaml:"cloudwatch"`
+	Prometheus PrometheusConfig      `yaml:"prometheus"`
+	NewRelic   NewRelicConfig        `yaml:"newrelic"`
+	Custom     CustomMetricsConfig   `yaml:"custom"`
+	External   ExternalMetricsConfig `yaml:"external"`
+	Scrape     ScrapeConfig          `yaml:"scrape"`
+}
+
+// Whether the kubectl source counts the memory of the init containers still
+// running in a pod
+const (
+	// InitContainersInclude counts them, the default
+	InitContainersInclude = "include"
+	// InitContainersExclude leaves them out, so a long migration doesn't
+	// distort the readings of a rollout
+	InitContainersExclude = "exclude"
+)
+
+// KubectlConfig configures the kubectl top source. InitContainers is
+// InitContainersInclude or InitContainersExclude.
+type KubectlConfig struct {
+	InitContainers string `yaml:"init_containers"`
+}
+
+// DatadogConfig configures the Datadog metrics API source. {namespace} in
+// Query is replaced by the namespace of the target.
+type DatadogConfig struct {
+	Site   string        `yaml:"site"`
+	APIKey string        `yaml:"api_key"`
+	AppKey string        `yaml:"app_key"`
+	Query  string        `yaml:"query"`
+	Window time.Duration `yaml:"window"`
+}
+
+// CloudWatchConfig configures the CloudWatch Container Insights source.
+// Credentials are read from the standard AWS environment variables.
+type CloudWatchConfig struct {
+	Region      string `yaml:"region"`
+	ClusterName string `yaml:"cluster_name"`
+	Metric      string `yaml:"metric"`
+}
+
+// PrometheusConfig configures the Prometheus source. {namespace} in Query,
+// and in SwapQuery and CPUQuery reading the swap usage and the percentage of
+// throttled CPU periods, is replaced by the namespace of the target. Auth is
+// "bearer" (the default, sending BearerToken when set), "sigv4" for Amazon
+// Managed Prometheus or "google" for Google Managed Prometheus.
+type PrometheusConfig struct {
+	URL         string `yaml:"url"`
+	Query       string `yaml:"query"`
+	SwapQuery   string `yaml:"swap_query"`
+	CPUQuery    string `yaml:"cpu_query"`
+	Auth        string `yaml:"auth"`
+	BearerToken string `yaml:"bearer_token"`
+	Region      string `yaml:"region"`
+}
+
+// NewRelicConfig configures the New Relic NRQL source. {namespace} in Query
+// is replaced by the namespace of the target. Region is "us" (the default)
+// or "eu".
+type NewRelicConfig struct {
+	APIKey    string `yaml:"api_key"`
+	AccountID int    `yaml:"account_id"`
+	Region    string `yaml:"region"`
+	Query     string `yaml:"query"`
+}
+
+// CustomMetricsConfig configures the custom metrics API source, reading
+// Metric for the pods of a namespace, optionally filtered by the label
+// Selector. Unit is "bytes" (the default), converted to Mi, or "raw" to
+// compare thresholds with the metric's own value.
+type CustomMetricsConfig struct {
+	Metric   string `yaml:"metric"`
+	Selector string `yaml:"selector"`
+	Unit     string `yaml:"unit"`
+}
+
+// ExternalMetricsConfig configures the external metrics API source, reading
+// Metric in the namespace of the target, optionally filtered by the label
+// Selector. Unit is as for CustomMetricsConfig; external signals such as
+// queue depth usually need "raw".
+type ExternalMetricsConfig struct {
+	Metric   string `yaml:"metric"`
+	Selector string `yaml:"selector"`
+	Unit     string `yaml:"unit"`
+}
+
+// ScrapeConfig configures the source scraping the Prometheus endpoint of
+// the pods themselves. Metric is a series name with optional label
+// matchers, e.g. jvm_memory_used_bytes{area="heap"}; its value is in bytes.
+// Deployments may override Port, Path and Metric for their pods with the
+// memory-watchdog.io/port, /path and /metric annotations.
+type ScrapeConfig struct {
+	Port     int    `yaml:"port"`
+	Path     string `yaml:"path"`
+	Scheme   string `yaml:"scheme"`
+	Metric   string `yaml:"metric"`
+	Selector string `yaml:"selector"`
+}
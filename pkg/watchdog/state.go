@@ -0,0 +1,141 @@
+package watchdog
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+// Record is the persisted outcome of a single check
+type Record struct {
+	Time      time.Time `json:"time"`
+	Target    string    `json:"target"`
+	Namespace string    `json:"namespace"`
+	Memory    int       `json:"memory"`
+	Threshold int       `json:"threshold"`
+	Breached  bool      `json:"breached"`
+	Action    string    `json:"action,omitempty"`
+	Error     string    `json:"error,omitempty"`
+	CheckID   string    `json:"check_id,omitempty"`
+	ActionID  string    `json:"action_id,omitempty"`
+}
+
+// StateStore persists check records
+type StateStore interface {
+	Save(ctx context.Context, record Record) error
+	List(ctx context.Context, since time.Time) ([]Record, error)
+}
+
+// MemoryStateStore keeps records in memory, dropping the oldest ones once
+// the limit is reached
+type MemoryStateStore struct {
+	mu      sync.Mutex
+	limit   int
+	records []Record
+}
+
+// NewMemoryStateStore creates a new instance of MemoryStateStore holding at
+// most limit records. A limit of zero or less keeps every record.
+func NewMemoryStateStore(limit int) *MemoryStateStore {
+	return &MemoryStateStore{
+		limit: limit,
+	}
+}
+
+// Save appends a record
+func (s *MemoryStateStore) Save(ctx context.Context, record Record) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.records = append(s.records, record)
+	if s.limit > 0 && len(s.records) > s.limit {
+		s.records = append([]Record(nil), s.records[len(s.records)-s.limit:]...)
+	}
+	return nil
+}
+
+// List returns the records saved at or after since, oldest first
+func (s *MemoryStateStore) List(ctx context.Context, since time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var records []Record
+	for _, record := range s.records {
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+	}
+	return records, nil
+}
+
+// FileStateStore appends records to a file, one JSON object per line, so
+// the history survives restarts of the watchdog
+type FileStateStore struct {
+	mu   sync.Mutex
+	path string
+}
+
+// NewFileStateStore creates a new instance of FileStateStore writing to path.
+// The file is created on the first save.
+func NewFileStateStore(path string) *FileStateStore {
+	return &FileStateStore{
+		path: path,
+	}
+}
+
+// Save appends a record to the file
+func (s *FileStateStore) Save(ctx context.Context, record Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.OpenFile(s.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// List returns the records saved at or after since, oldest first. A missing
+// file holds no records.
+func (s *FileStateStore) List(ctx context.Context, since time.Time) ([]Record, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var records []Record
+	scanner := bufio.NewScanner(f)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("%s: line %d: %v", s.path, line, err)
+		}
+		if !record.Time.Before(since) {
+			records = append(records, record)
+		}
+	}
+	return records, scanner.Err()
+}
@@ -0,0 +1,37 @@
+package watchdog
+
+import (
+	"context"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// SwapProvider reports the swap usage of the pods of a namespace in Mi, on
+// clusters whose nodes have swap enabled
+type SwapProvider interface {
+	GetSwapUsage(ctx context.Context, namespace string) (int, error)
+}
+
+// swapUsage reads the swap usage of target when it has a swap threshold.
+// Swap is an early signal on top of the memory usage, so a failed reading
+// is logged and the check goes on without it.
+func (w *Watchdog) swapUsage(ctx context.Context, target Target, result CheckResult) (int, bool) {
+	if target.SwapThreshold <= 0 {
+		return 0, false
+	}
+	if w.swap == nil {
+		w.metricsLog.Warnf("Not checking the swap usage of target '%s' without a swap source%s", target.Name, result.correlation())
+		return 0, false
+	}
+
+	swapCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+	defer cancel()
+	swap, err := w.swap.GetSwapUsage(swapCtx, target.Namespace)
+	if err != nil {
+		w.metricsLog.Warnf("Error reading the swap usage of target '%s': %v%s", target.Name, err, result.correlation())
+		return 0, false
+	}
+	w.metricsLog.Debugf("Read %dMi of swap for target '%s'", swap, target.Name)
+	w.telemetry.Set(telemetry.MetricSwapUsage, float64(swap), "target", target.Name)
+	return swap, true
+}
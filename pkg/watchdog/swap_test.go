@@ -0,0 +1,63 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// swapSource reports a fixed swap usage, or fails with err
+type swapSource struct {
+	swap int
+	err  error
+}
+
+func (s swapSource) GetSwapUsage(ctx context.Context, namespace string) (int, error) {
+	return s.swap, s.err
+}
+
+func TestSwapThreshold(t *testing.T) {
+	tests := []struct {
+		name          string
+		swap          SwapProvider
+		swapThreshold int
+		breached      bool
+		wantSwap      int
+	}{
+		{name: "swap over threshold", swap: swapSource{swap: 600}, swapThreshold: 500, breached: true, wantSwap: 600},
+		{name: "swap under threshold", swap: swapSource{swap: 200}, swapThreshold: 500, wantSwap: 200},
+		{name: "swap disabled", swap: swapSource{swap: 600}},
+		{name: "failed reading", swap: swapSource{err: errors.New("connection refused")}, swapThreshold: 500},
+		{name: "no swap source", swapThreshold: 500},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.swap != nil {
+				opts = append(opts, WithSwapSource(tt.swap))
+			}
+			client := watchdogtest.NewFakeClient()
+			watchdog := NewWatchdog(watchdogtest.NewFakeClient(1000), client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 5000,
+				SwapThreshold:   tt.swapThreshold,
+				CheckInterval:   time.Minute,
+			}, opts...)
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Err != nil {
+				t.Fatalf("CheckTarget() error = %v", result.Err)
+			}
+			if result.Breached != tt.breached || result.Swap != tt.wantSwap {
+				t.Errorf("CheckTarget() breached = %v, swap = %d, want %v, %d", result.Breached, result.Swap, tt.breached, tt.wantSwap)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/yaml"
+)
+
+// TenantConfig configures the multi-tenant mode, where the teams owning the
+// Namespaces define the targets of their own namespace. Each of them may
+// hold a ConfigMap named ConfigMap, whose TenantPolicyKey entry lists its
+// targets in the format of the targets of the configuration file. The
+// ConfigMaps are read again every Refresh.
+type TenantConfig struct {
+	Namespaces []string      `yaml:"namespaces"`
+	ConfigMap  string        `yaml:"configmap"`
+	Refresh    time.Duration `yaml:"refresh"`
+}
+
+// TenantPolicyKey is the entry of a tenant ConfigMap holding its targets
+const TenantPolicyKey = "targets"
+
+// TenantPolicies reads the policies of the tenant namespaces
+type TenantPolicies interface {
+	// TenantPolicy returns the TenantPolicyKey entry of the named
+	// ConfigMap of namespace, and false when there is no such ConfigMap
+	TenantPolicy(ctx context.Context, namespace, configMap string) (string, bool, error)
+}
+
+// ParseTenantPolicy reads the targets a tenant policy defines for its
+// namespace. Tenants can only act on their own namespace: a target of
+// another namespace is rejected, and the names of the targets are
+// prefixed with the namespace so they can't replace the targets of
+// another tenant or of the configuration.
+func ParseTenantPolicy(namespace, policy string) ([]Target, error) {
+	var targets []Target
+	if err := yaml.UnmarshalStrict([]byte(policy), &targets); err != nil {
+		return nil, err
+	}
+	for i, t := range targets {
+		if t.Namespace != "" && t.Namespace != namespace {
+			return nil, fmt.Errorf("target '%s' is in namespace '%s', tenants can only target their own namespace",
+				t.DeploymentName, t.Namespace)
+		}
+		if t.Cluster != nil {
+			return nil, fmt.Errorf("target '%s' sets a cluster, tenants can only target their own namespace", t.DeploymentName)
+		}
+		targets[i].Namespace = namespace
+		if t.Name != "" {
+			targets[i].Name = namespace + "/" + t.Name
+		}
+	}
+	return targets, nil
+}
+
+// runTenants keeps the targets of the tenants in sync with their policies
+// until ctx is done
+func (w *Watchdog) runTenants(ctx context.Context) {
+	ticker := w.clock.NewTicker(w.config.Tenants.Refresh)
+	defer ticker.Stop()
+
+	for {
+		for _, namespace := range w.config.Tenants.Namespaces {
+			w.syncTenant(ctx, namespace)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C():
+		}
+	}
+}
+
+// syncTenant replaces the targets of the tenant namespace with the ones of
+// its policy. They are kept as they are when the policy can't be read or is
+// invalid, and all removed when the namespace has no policy anymore.
+func (w *Watchdog) syncTenant(ctx context.Context, namespace string) {
+	policy, found, err := w.tenants.TenantPolicy(ctx, namespace, w.config.Tenants.ConfigMap)
+	if err != nil {
+		w.logger.Errorf("Error reading the policy of tenant '%s': %v", namespace, err)
+		return
+	}
+	var targets []Target
+	if found {
+		if targets, err = ParseTenantPolicy(namespace, policy); err != nil {
+			w.logger.Errorf("Ignoring the invalid policy of tenant '%s': %v", namespace, err)
+			return
+		}
+	}
+	wanted := make(map[string]Target, len(targets))
+	for _, t := range targets {
+		t = w.config.resolveTarget(t)
+		wanted[t.Name] = t
+	}
+
+	w.mu.Lock()
+	var removed []string
+	for _, name := range w.order {
+		loop := w.targets[name]
+		if loop.tenant != namespace {
+			continue
+		}
+		if t, ok := wanted[name]; ok && reflect.DeepEqual(t, loop.target) {
+			delete(wanted, name)
+			continue
+		}
+		removed = append(removed, name)
+	}
+	w.mu.Unlock()
+
+	for _, name := range removed {
+		if err := w.RemoveTarget(name); err == nil {
+			w.logger.Infof("Stopped monitoring target '%s' of tenant '%s'", name, namespace)
+		}
+	}
+	for _, t := range targets {
+		t = w.config.resolveTarget(t)
+		if _, ok := wanted[t.Name]; !ok {
+			continue
+		}
+		if err := w.addTarget(t, namespace); err != nil {
+			w.logger.Errorf("Not monitoring a target of tenant '%s': %v", namespace, err)
+			continue
+		}
+		w.logger.Infof("Monitoring target '%s' of tenant '%s'", t.Name, namespace)
+	}
+}
@@ -0,0 +1,108 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// tenantPolicies serves the policies of the tenant namespaces, failing
+// with err when it is set
+type tenantPolicies struct {
+	policies map[string]string
+	err      error
+}
+
+func (p *tenantPolicies) TenantPolicy(ctx context.Context, namespace, configMap string) (string, bool, error) {
+	policy, found := p.policies[namespace]
+	return policy, found, p.err
+}
+
+func TestParseTenantPolicy(t *testing.T) {
+	targets, err := ParseTenantPolicy("team-a", `
+- deployment: "api"
+  memory_threshold: 1500
+- name: "worker"
+  namespace: "team-a"
+  deployment: "worker-v2"
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(targets) != 2 || targets[0].Namespace != "team-a" || targets[0].Name != "" || targets[0].MemoryThreshold != 1500 ||
+		targets[1].Name != "team-a/worker" || targets[1].DeploymentName != "worker-v2" {
+		t.Errorf("ParseTenantPolicy() = %+v", targets)
+	}
+
+	if _, err := ParseTenantPolicy("team-a", "- deployment: \"api\"\n  namespace: \"kube-system\"\n"); err == nil {
+		t.Error("ParseTenantPolicy() error = nil, want another namespace rejected")
+	}
+	if _, err := ParseTenantPolicy("team-a", "- deployment: \"api\"\n  cluster:\n    context: \"prod\"\n"); err == nil {
+		t.Error("ParseTenantPolicy() error = nil, want another cluster rejected")
+	}
+	if _, err := ParseTenantPolicy("team-a", "- deployment: \"api\"\n  threshold: 1500\n"); err == nil {
+		t.Error("ParseTenantPolicy() error = nil, want the unknown field rejected")
+	}
+}
+
+func TestSyncTenant(t *testing.T) {
+	client := watchdogtest.NewFakeClient(1000)
+	policies := &tenantPolicies{policies: map[string]string{
+		"team-a": "- deployment: \"api\"\n- deployment: \"worker\"\n",
+	}}
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "platform",
+		DeploymentName:  "ingress",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Tenants:         TenantConfig{Namespaces: []string{"team-a"}, ConfigMap: "memory-watchdog", Refresh: time.Minute},
+	}, WithTenantPolicies(policies))
+	names := func() []string {
+		var names []string
+		for _, target := range watchdog.Targets() {
+			names = append(names, target.Name+"@"+target.Namespace)
+		}
+		return names
+	}
+	sync := func(want ...string) {
+		t.Helper()
+		watchdog.syncTenant(context.Background(), "team-a")
+		if got := names(); !equalStrings(got, want) {
+			t.Errorf("targets = %v, want %v", got, want)
+		}
+	}
+
+	sync("platform/ingress@platform", "team-a/api@team-a", "team-a/worker@team-a")
+
+	// a changed target is replaced, and a dropped one removed
+	policies.policies["team-a"] = "- deployment: \"api\"\n  memory_threshold: 500\n"
+	sync("platform/ingress@platform", "team-a/api@team-a")
+	if threshold := watchdog.Targets()[1].MemoryThreshold; threshold != 500 {
+		t.Errorf("threshold = %d, want the changed one", threshold)
+	}
+
+	// targets are kept while the policy can't be read or is invalid
+	policies.err = errors.New("forbidden")
+	sync("platform/ingress@platform", "team-a/api@team-a")
+	policies.err = nil
+	policies.policies["team-a"] = "- deployment: \"api\"\n  namespace: \"platform\"\n"
+	sync("platform/ingress@platform", "team-a/api@team-a")
+
+	delete(policies.policies, "team-a")
+	sync("platform/ingress@platform")
+}
+
+func equalStrings(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
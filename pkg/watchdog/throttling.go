@@ -0,0 +1,38 @@
+package watchdog
+
+import (
+	"context"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// ThrottlingProvider reports the percentage of CPU periods in which the
+// containers of a namespace were throttled, averaged over a window long
+// enough that a single burst doesn't count
+type ThrottlingProvider interface {
+	GetCPUThrottling(ctx context.Context, namespace string) (int, error)
+}
+
+// cpuThrottling reads the CPU throttling of target when it has a throttling
+// threshold. Like the swap usage, a failed reading is logged and the check
+// goes on without it.
+func (w *Watchdog) cpuThrottling(ctx context.Context, target Target, result CheckResult) (int, bool) {
+	if target.CPUThrottling <= 0 {
+		return 0, false
+	}
+	if w.throttle == nil {
+		w.metricsLog.Warnf("Not checking the CPU throttling of target '%s' without a throttling source%s", target.Name, result.correlation())
+		return 0, false
+	}
+
+	throttlingCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+	defer cancel()
+	throttling, err := w.throttle.GetCPUThrottling(throttlingCtx, target.Namespace)
+	if err != nil {
+		w.metricsLog.Warnf("Error reading the CPU throttling of target '%s': %v%s", target.Name, err, result.correlation())
+		return 0, false
+	}
+	w.metricsLog.Debugf("Read %d%% CPU throttling for target '%s'", throttling, target.Name)
+	w.telemetry.Set(telemetry.MetricCPUThrottling, float64(throttling), "target", target.Name)
+	return throttling, true
+}
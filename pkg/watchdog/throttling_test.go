@@ -0,0 +1,62 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// throttlingSource reports a fixed CPU throttling, or fails with err
+type throttlingSource struct {
+	throttling int
+	err        error
+}
+
+func (s throttlingSource) GetCPUThrottling(ctx context.Context, namespace string) (int, error) {
+	return s.throttling, s.err
+}
+
+func TestCPUThrottling(t *testing.T) {
+	tests := []struct {
+		name          string
+		throttling    ThrottlingProvider
+		cpuThrottling int
+		breached      bool
+		wantThrottled int
+	}{
+		{name: "sustained throttling", throttling: throttlingSource{throttling: 75}, cpuThrottling: 50, breached: true, wantThrottled: 75},
+		{name: "light throttling", throttling: throttlingSource{throttling: 10}, cpuThrottling: 50, wantThrottled: 10},
+		{name: "throttling disabled", throttling: throttlingSource{throttling: 75}},
+		{name: "failed reading", throttling: throttlingSource{err: errors.New("connection refused")}, cpuThrottling: 50},
+		{name: "no throttling source", cpuThrottling: 50},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var opts []Option
+			if tt.throttling != nil {
+				opts = append(opts, WithThrottlingSource(tt.throttling))
+			}
+			watchdog := NewWatchdog(watchdogtest.NewFakeClient(1000), watchdogtest.NewFakeClient(), Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 5000,
+				CPUThrottling:   tt.cpuThrottling,
+				CheckInterval:   time.Minute,
+			}, opts...)
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Err != nil {
+				t.Fatalf("CheckTarget() error = %v", result.Err)
+			}
+			if result.Breached != tt.breached || result.Throttled != tt.wantThrottled {
+				t.Errorf("CheckTarget() breached = %v, throttled = %d, want %v, %d", result.Breached, result.Throttled, tt.breached, tt.wantThrottled)
+			}
+		})
+	}
+}
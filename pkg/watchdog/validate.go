@@ -0,0 +1,222 @@
+package watchdog
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/internal/secret"
+)
+
+// validate checks the settings of a resolved target that don't depend on
+// the metric sources registered with the watchdog
+func (t Target) validate() error {
+	if t.DeploymentName == "" {
+		return errors.New("target has no deployment name")
+	}
+	if t.CheckInterval <= 0 && t.Cron == "" {
+		return fmt.Errorf("target '%s' has no check interval", t.Name)
+	}
+	if t.Cron != "" {
+		if _, err := parseCron(t.Cron); err != nil {
+			return fmt.Errorf("target '%s': %w", t.Name, err)
+		}
+	}
+	if !validScope(t.Scope) {
+		return fmt.Errorf("target '%s' has an unknown scope '%s', want namespace, deployment or pod", t.Name, t.Scope)
+	}
+	if !validBatchPods(t.BatchPods) {
+		return fmt.Errorf("target '%s' has an unknown batch_pods '%s', want exclude or include", t.Name, t.BatchPods)
+	}
+	if !validRestartStrategy(t.RestartStrategy) {
+		return fmt.Errorf("target '%s' has an unknown restart_strategy '%s', want rollout, scale or delete", t.Name, t.RestartStrategy)
+	}
+	if t.ColorService != "" && t.ColorLabel == "" {
+		return fmt.Errorf("target '%s' sets color_service without the color_label telling the colors apart", t.Name)
+	}
+	if t.SurgeReplicas < 0 {
+		return fmt.Errorf("target '%s' has a negative surge_replicas", t.Name)
+	}
+	if t.SurgeReplicas > 0 && t.RestartStrategy == RestartScale {
+		return fmt.Errorf("target '%s' sets surge_replicas with the scale restart strategy, which scales the deployment to zero anyway", t.Name)
+	}
+	if t.Rollback && (t.RestartStrategy == RestartScale || t.RestartStrategy == RestartDelete) {
+		return fmt.Errorf("target '%s' sets rollback with the %s restart strategy, which doesn't roll out a new revision to undo", t.Name, t.RestartStrategy)
+	}
+	if t.GracePeriod < 0 || t.DeleteDelay < 0 {
+		return fmt.Errorf("target '%s' has a negative grace_period or delete_delay", t.Name)
+	}
+	if t.GracePeriod%time.Second != 0 {
+		return fmt.Errorf("target '%s' has a grace_period of %v, not a whole number of seconds", t.Name, t.GracePeriod)
+	}
+	if err := validAggregation(t.Aggregation); err != nil {
+		return fmt.Errorf("target '%s': %w", t.Name, err)
+	}
+	if t.ReplicaMemory < 0 {
+		return fmt.Errorf("target '%s' has a negative memory_per_replica", t.Name)
+	}
+	if t.BaseMemory < 0 {
+		return fmt.Errorf("target '%s' has a negative memory_base", t.Name)
+	}
+	if t.BaseMemory > 0 && t.ReplicaMemory <= 0 {
+		return fmt.Errorf("target '%s' sets memory_base without memory_per_replica, which it is added to", t.Name)
+	}
+	if t.ReplicaMemory > 0 && len(t.Schedules) > 0 {
+		return fmt.Errorf("target '%s' sets both memory_per_replica and schedules, whose thresholds are totals; use one of them", t.Name)
+	}
+	if t.PodThreshold < 0 {
+		return fmt.Errorf("target '%s' has a negative pod_threshold", t.Name)
+	}
+	if t.SwapThreshold < 0 {
+		return fmt.Errorf("target '%s' has a negative swap_threshold", t.Name)
+	}
+	if t.CPUThrottling < 0 || t.CPUThrottling > 100 {
+		return fmt.Errorf("target '%s' has a cpu_throttling of %d%%, not between 0 and 100", t.Name, t.CPUThrottling)
+	}
+	if t.WarmUp < 0 {
+		return fmt.Errorf("target '%s' has a negative warm_up", t.Name)
+	}
+	if t.Quorum > len(t.Sources) {
+		return fmt.Errorf("target '%s' has a quorum of %d but only %d sources", t.Name, t.Quorum, len(t.Sources))
+	}
+	if _, err := LoadLocation(t.Timezone); err != nil {
+		return fmt.Errorf("target '%s': %w", t.Name, err)
+	}
+	for _, schedule := range t.Schedules {
+		if err := schedule.validate(); err != nil {
+			return fmt.Errorf("target '%s': %w", t.Name, err)
+		}
+	}
+	if t.Cluster != nil {
+		if err := t.Cluster.validate(); err != nil {
+			return fmt.Errorf("target '%s': %w", t.Name, err)
+		}
+	}
+	return nil
+}
+
+// Problems returns the settings of the configuration that would keep the
+// watchdog from starting or that contradict each other, such as a metrics
+// timeout longer than the interval between checks. It doesn't look at the
+// cluster, so a configuration without problems may still refer to missing
+// deployments.
+func (c Config) Problems() []string {
+	var problems []string
+	targets := c.ResolveTargets()
+	if len(targets) == 0 {
+		problems = append(problems, "no deployment is configured, set deployment or list targets")
+	}
+
+	names := make(map[string]bool)
+	for _, t := range targets {
+		if err := t.validate(); err != nil {
+			problems = append(problems, err.Error())
+		}
+		if names[t.Name] {
+			problems = append(problems, fmt.Sprintf("target '%s' is configured more than once, give the targets distinct names", t.Name))
+		}
+		names[t.Name] = true
+		if t.MemoryThreshold <= 0 && t.ReplicaMemory <= 0 {
+			problems = append(problems, fmt.Sprintf("target '%s' has no memory threshold, set memory_threshold", t.Name))
+		}
+		if t.Cron == "" && t.CheckInterval > 0 && c.MetricsTimeout >= t.CheckInterval {
+			problems = append(problems, fmt.Sprintf("metrics_timeout (%s) is not shorter than the check interval of target '%s' (%s), "+
+				"so a slow metric source delays its next check; lower metrics_timeout or raise check_interval",
+				c.MetricsTimeout, t.Name, t.CheckInterval))
+		}
+	}
+
+	if c.RecoveryPercent < 0 || c.RecoveryPercent > 100 {
+		problems = append(problems, fmt.Sprintf("recovery_percent (%g) must be between 0 and 100", c.RecoveryPercent))
+	}
+	if ic := c.Source.Kubectl.InitContainers; ic != "" && ic != InitContainersInclude && ic != InitContainersExclude {
+		problems = append(problems, fmt.Sprintf("source.kubectl.init_containers '%s' is unknown, want include or exclude", ic))
+	}
+	if c.Circuit.Failures > 0 && c.Circuit.Probe <= 0 {
+		problems = append(problems, "circuit_breaker.probe must be positive when circuit_breaker.failures is set")
+	}
+	if c.Budget.Restarts > 0 && c.Budget.Window <= 0 {
+		problems = append(problems, "restart_budget.window must be positive when restart_budget.restarts is set")
+	}
+	if len(c.Tenants.Namespaces) > 0 && (c.Tenants.ConfigMap == "" || c.Tenants.Refresh <= 0) {
+		problems = append(problems, "tenants.namespaces requires the tenants.configmap read from them and a positive tenants.refresh")
+	}
+	if c.Proxy.URL != "" {
+		if u, err := url.Parse(c.Proxy.URL); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https" && u.Scheme != "socks5") {
+			problems = append(problems, fmt.Sprintf("proxy.url '%s' is invalid, want an http, https or socks5 URL", c.Proxy.URL))
+		}
+	}
+	if c.OIDC.IssuerURL != "" {
+		if u, err := url.Parse(c.OIDC.IssuerURL); err != nil || u.Scheme != "https" || u.Host == "" {
+			problems = append(problems, fmt.Sprintf("oidc.issuer_url '%s' is invalid, want an https URL", c.OIDC.IssuerURL))
+		}
+		if c.OIDC.ClientID == "" || c.OIDC.RefreshToken == "" {
+			problems = append(problems, "oidc.issuer_url requires the oidc.client_id and oidc.refresh_token of the watchdog")
+		}
+		// Secrets are read with kubectl, which needs the OIDC tokens first
+		if strings.HasPrefix(c.OIDC.ClientSecret, secret.SecretPrefix) || strings.HasPrefix(c.OIDC.RefreshToken, secret.SecretPrefix) {
+			problems = append(problems, "oidc.client_secret and oidc.refresh_token can't refer to a Kubernetes Secret, use a file instead")
+		}
+	}
+	methods := 0
+	for _, set := range []bool{c.EKS.Cluster != "", c.GKE.Enabled, c.AKS.Enabled, c.OIDC.IssuerURL != ""} {
+		if set {
+			methods++
+		}
+	}
+	if methods > 1 {
+		problems = append(problems, "eks.cluster, gke.enabled, aks.enabled and oidc.issuer_url each set how kubectl authenticates; use only one of them")
+	}
+	for _, raw := range c.Notifications.Alertmanager.URLs {
+		if strings.HasPrefix(raw, secret.FilePrefix) || strings.HasPrefix(raw, secret.SecretPrefix) {
+			continue
+		}
+		if u, err := url.Parse(raw); err != nil || u.Host == "" || (u.Scheme != "http" && u.Scheme != "https") {
+			problems = append(problems, fmt.Sprintf("notifications.alertmanager.urls has an invalid URL '%s', want an http or https URL", raw))
+		}
+	}
+	if c.Notifications.Alertmanager.Silences && len(c.Notifications.Alertmanager.URLs) == 0 {
+		problems = append(problems, "notifications.alertmanager.silences requires the notifications.alertmanager.urls they are read from")
+	}
+	var labels []string
+	for name := range c.Notifications.Alertmanager.Labels {
+		if !validLabelName(name) {
+			labels = append(labels, name)
+		}
+	}
+	sort.Strings(labels)
+	for _, name := range labels {
+		problems = append(problems, fmt.Sprintf("notifications.alertmanager.labels has an invalid label name '%s'", name))
+	}
+	if c.Outliers.Window > 0 && c.Outliers.K <= 0 {
+		problems = append(problems, "outliers.k must be positive when outliers.window is set")
+	}
+	if c.Baseline.Percent > 0 && c.StateFile == "" && !c.Dashboard.Enabled {
+		problems = append(problems, "baseline.percent has no effect without the history of a state_file")
+	}
+	if c.Metrics.Enabled || c.Dashboard.Enabled || c.Admin.Enabled {
+		if (c.HTTP.TLS.CertFile == "") != (c.HTTP.TLS.KeyFile == "") {
+			problems = append(problems, "http.tls requires both cert_file and key_file")
+		}
+	}
+	if c.GRPC.Enabled && (c.GRPC.TLS.CertFile == "" || c.GRPC.TLS.KeyFile == "") {
+		problems = append(problems, "the gRPC API requires grpc.tls.cert_file and grpc.tls.key_file")
+	}
+	return problems
+}
+
+// validLabelName reports whether name is a valid Prometheus label name
+func validLabelName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && (r < 'a' || r > 'z') && (r < 'A' || r > 'Z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
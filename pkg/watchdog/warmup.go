@@ -0,0 +1,39 @@
+package watchdog
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// PodStarts reports when the pods of a namespace started, for the targets
+// ignoring pods during their warm-up
+type PodStarts interface {
+	PodStartTimes(ctx context.Context, namespace string) (map[string]time.Time, error)
+}
+
+// warmingPods returns the pods of the namespace of target started less than
+// its WarmUp ago, or nil when it has no warm-up
+func (w *Watchdog) warmingPods(ctx context.Context, target Target) (map[string]bool, error) {
+	if target.WarmUp <= 0 {
+		return nil, nil
+	}
+	if w.podStarts == nil {
+		return nil, fmt.Errorf("%w: no pod start times to apply a warm-up", ErrMetricsUnavailable)
+	}
+
+	startsCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+	starts, err := w.podStarts.PodStartTimes(startsCtx, target.Namespace)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+	now := w.clock.Now()
+	warming := make(map[string]bool)
+	for pod, start := range starts {
+		if now.Sub(start) < target.WarmUp {
+			warming[pod] = true
+		}
+	}
+	return warming, nil
+}
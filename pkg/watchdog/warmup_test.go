@@ -0,0 +1,71 @@
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+// podStarts reports fixed pod start times
+type podStarts map[string]time.Time
+
+func (s podStarts) PodStartTimes(ctx context.Context, namespace string) (map[string]time.Time, error) {
+	return s, nil
+}
+
+func TestWarmUp(t *testing.T) {
+	pods := podSource{
+		{Pod: "api-7d9f8b6c5d-x2k4p", Memory: 2500},
+		{Pod: "api-7d9f8b6c5d-q8z7m", Memory: 600},
+		{Pod: "redis-0", Memory: 500},
+	}
+	now := time.Now()
+	tests := []struct {
+		name      string
+		scope     string
+		starts    PodStarts
+		memory    int
+		warmingUp bool
+		wantErr   error
+	}{
+		{name: "one warming pod", starts: podStarts{"api-7d9f8b6c5d-x2k4p": now.Add(-time.Minute), "api-7d9f8b6c5d-q8z7m": now.Add(-time.Hour)}, memory: 1100},
+		{name: "warmed up", starts: podStarts{"api-7d9f8b6c5d-x2k4p": now.Add(-time.Hour)}, memory: 3600},
+		{name: "all warming", scope: ScopeDeployment, starts: podStarts{"api-7d9f8b6c5d-x2k4p": now, "api-7d9f8b6c5d-q8z7m": now}, warmingUp: true},
+		{name: "no start times", wantErr: ErrMetricsUnavailable},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var options []Option
+			if tt.starts != nil {
+				options = append(options, WithPodStarts(tt.starts))
+			}
+			client := watchdogtest.NewFakeClient()
+			watchdog := NewWatchdog(pods, client, Config{
+				Namespace:       "prod",
+				DeploymentName:  "api",
+				MemoryThreshold: 3000,
+				Scope:           tt.scope,
+				WarmUp:          5 * time.Minute,
+				CheckInterval:   time.Minute,
+			}, options...)
+
+			result, err := watchdog.CheckTarget(context.Background(), "prod/api")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !errors.Is(result.Err, tt.wantErr) || (tt.wantErr == nil && result.Err != nil) {
+				t.Fatalf("CheckTarget() error = %v, want %v", result.Err, tt.wantErr)
+			}
+			if result.Memory != tt.memory || result.WarmingUp != tt.warmingUp {
+				t.Errorf("CheckTarget() memory = %d, warming up = %v, want %d, %v", result.Memory, result.WarmingUp, tt.memory, tt.warmingUp)
+			}
+			if result.Breached != (tt.memory >= 3000) {
+				t.Errorf("CheckTarget() breached = %v with %dMi", result.Breached, result.Memory)
+			}
+		})
+	}
+}
@@ -0,0 +1,686 @@
+// Package watchdog monitors the memory usage of Kubernetes deployments and
+// restarts them when a threshold is exceeded.
+package watchdog
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/clock"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/logging"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/telemetry"
+)
+
+// MetricsProvider measures the memory usage of pods
+type MetricsProvider interface {
+	GetPodMemoryUsage(ctx context.Context, namespace string) (int, error)
+}
+
+// Restarter restarts deployments
+type Restarter interface {
+	RestartDeployment(ctx context.Context, namespace, deployment string) error
+}
+
+// KubernetesClient interface for Kubernetes operations, for implementations
+// providing both measurement and remediation
+type KubernetesClient interface {
+	MetricsProvider
+	Restarter
+}
+
+// Watchdog monitors memory usage and restarts deployments when needed
+type Watchdog struct {
+	metrics   MetricsProvider
+	config    Config
+	telemetry *telemetry.Telemetry
+	clock     clock.Clock
+	logger    *logging.Logger
+	// metricsLog logs the readings of the metrics sources
+	metricsLog *logging.Logger
+	// auditLog logs the actions of operators
+	auditLog  *logging.Logger
+	notifiers []Notifier
+	streams   []Notifier
+	freezes   []Freeze
+	holds     []Hold
+	sources   map[string]MetricsProvider
+	store     StateStore
+	action    Action
+	replicas  ReplicaCounter
+	podStarts PodStarts
+	podOwners PodOwners
+	swap      SwapProvider
+	throttle  ThrottlingProvider
+	colors    ColorResolver
+	available Availability
+	rollouts  RolloutStatus
+	undo      RolloutUndoer
+	tenants   TenantPolicies
+
+	windowMu sync.Mutex
+	windows  map[string][]int
+
+	mu      sync.Mutex
+	ctx     context.Context
+	wg      sync.WaitGroup
+	targets map[string]*targetLoop
+	order   []string
+	// lastCheck is when a target's memory usage was last read
+	lastCheck time.Time
+	// restarts are the times of the automated restarts within the budget
+	// window, and disabled is when the budget was exhausted
+	restarts []time.Time
+	disabled time.Time
+}
+
+// targetLoop tracks the goroutine checking a single target, with its state
+type targetLoop struct {
+	target Target
+	cancel context.CancelFunc
+	done   chan struct{}
+	paused bool
+	// pause is the pause of an operator, nil when paused without one
+	pause *Pause
+	last  *CheckResult
+	// lastAction is the latest result with an action
+	lastAction *CheckResult
+	// alive is when the loop last started waiting for a tick, zero when
+	// it is not running
+	alive time.Time
+	// breachedAt is when the target's open breach started, zero when
+	// there is none
+	breachedAt time.Time
+	silence    *Silence
+	// failures counts the failed restarts in a row, and circuitAt is when
+	// the circuit opened or was last probed, zero while it is closed
+	failures  int
+	circuitAt time.Time
+	// tenant is the namespace whose policy defines the target, empty for
+	// the targets of the configuration
+	tenant string
+	// restarts counts the successful restarts since the watchdog started
+	restarts int
+}
+
+// NewWatchdog creates a new instance of Watchdog measuring usage with
+// metrics. By default breaching targets are restarted through restarter;
+// options attach further capabilities.
+func NewWatchdog(metrics MetricsProvider, restarter Restarter, config Config, opts ...Option) *Watchdog {
+	w := &Watchdog{
+		metrics: metrics,
+		config:  config,
+		clock:   clock.System{},
+		action:  restartAction{restarter: restarter},
+		sources: make(map[string]MetricsProvider),
+		windows: make(map[string][]int),
+		targets: make(map[string]*targetLoop),
+	}
+	WithLogging(logging.New(log.Default(), logging.Levels{Default: logging.LevelInfo}))(w)
+	for _, opt := range opts {
+		opt(w)
+	}
+	for _, t := range config.ResolveTargets() {
+		if _, exists := w.targets[t.Name]; !exists {
+			w.targets[t.Name] = &targetLoop{target: t}
+			w.order = append(w.order, t.Name)
+		}
+	}
+	return w
+}
+
+// Run starts the monitoring. Each target is checked by its own goroutine
+// until the context is cancelled. It fails right away when a target can't
+// be monitored with the options of the watchdog.
+func (w *Watchdog) Run(ctx context.Context) error {
+	w.mu.Lock()
+	if w.ctx != nil {
+		w.mu.Unlock()
+		return errors.New("watchdog is already running")
+	}
+	for _, name := range w.order {
+		if err := w.checkBatchPods(w.targets[name].target); err != nil {
+			w.mu.Unlock()
+			return err
+		}
+	}
+	w.ctx = ctx
+	for _, name := range w.order {
+		w.startLocked(w.targets[name])
+	}
+	if w.tenants != nil && len(w.config.Tenants.Namespaces) > 0 {
+		w.wg.Add(1)
+		go func() {
+			defer w.wg.Done()
+			w.runTenants(ctx)
+		}()
+	}
+	w.mu.Unlock()
+
+	<-ctx.Done()
+	w.wg.Wait()
+
+	w.mu.Lock()
+	w.ctx = nil
+	w.mu.Unlock()
+
+	return ctx.Err()
+}
+
+// AddTarget starts monitoring a new target. If the watchdog is not running
+// yet, the target is started together with the others when Run is called.
+func (w *Watchdog) AddTarget(target Target) error {
+	return w.addTarget(target, "")
+}
+
+// addTarget adds a target defined by the policy of the tenant namespace,
+// or by the configuration when tenant is empty
+func (w *Watchdog) addTarget(target Target, tenant string) error {
+	resolved := w.config.resolveTarget(target)
+	if err := resolved.validate(); err != nil {
+		return err
+	}
+	if err := w.checkBatchPods(resolved); err != nil {
+		return err
+	}
+	for _, source := range resolved.Sources {
+		if _, ok := w.sources[source]; !ok {
+			return fmt.Errorf("target '%s' uses unknown metrics source '%s'", resolved.Name, source)
+		}
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if _, exists := w.targets[resolved.Name]; exists {
+		return fmt.Errorf("target '%s' already exists", resolved.Name)
+	}
+	loop := &targetLoop{target: resolved, tenant: tenant}
+	w.targets[resolved.Name] = loop
+	w.order = append(w.order, resolved.Name)
+	if w.ctx != nil {
+		w.startLocked(loop)
+	}
+	return nil
+}
+
+// RemoveTarget stops monitoring the named target and waits for any check
+// in progress to finish
+func (w *Watchdog) RemoveTarget(name string) error {
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	if !exists {
+		w.mu.Unlock()
+		return fmt.Errorf("%w: '%s'", ErrTargetNotFound, name)
+	}
+	delete(w.targets, name)
+	for i, n := range w.order {
+		if n == name {
+			w.order = append(w.order[:i], w.order[i+1:]...)
+			break
+		}
+	}
+	w.mu.Unlock()
+
+	if loop.cancel != nil {
+		loop.cancel()
+		<-loop.done
+	}
+	return nil
+}
+
+// Targets returns the targets currently monitored
+func (w *Watchdog) Targets() []Target {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	targets := make([]Target, 0, len(w.order))
+	for _, name := range w.order {
+		targets = append(targets, w.targets[name].target)
+	}
+	return targets
+}
+
+// startLocked launches the goroutine for a target. w.mu must be held.
+func (w *Watchdog) startLocked(loop *targetLoop) {
+	ctx, cancel := context.WithCancel(w.ctx)
+	loop.cancel = cancel
+	loop.done = make(chan struct{})
+
+	w.wg.Add(1)
+	go func() {
+		defer w.wg.Done()
+		defer close(loop.done)
+		w.runTarget(ctx, loop.target)
+	}()
+}
+
+// runTarget checks a single target on its own interval. Targets with a
+// cron expression are considered every minute and only checked in the
+// minutes it matches.
+func (w *Watchdog) runTarget(ctx context.Context, target Target) {
+	interval := target.CheckInterval
+	var schedule *cronSchedule
+	if target.Cron != "" {
+		var err error
+		if schedule, err = parseCron(target.Cron); err != nil {
+			w.logger.Errorf("Not checking target '%s': %v", target.Name, err)
+			return
+		}
+		interval = time.Minute
+	}
+
+	ticker := w.clock.NewTicker(interval)
+	defer ticker.Stop()
+	defer w.setAlive(target.Name, time.Time{})
+
+	for {
+		w.setAlive(target.Name, w.clock.Now())
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C():
+			if schedule != nil && !schedule.matches(now.In(target.location())) {
+				continue
+			}
+			if w.paused(target.Name) {
+				continue
+			}
+			if result := w.check(ctx, target); result.Err != nil {
+				w.logger.Errorf("Error during check of target '%s': %v%s", target.Name, result.Err, result.correlation())
+			}
+		}
+	}
+}
+
+// CheckOnce checks every target once, concurrently, and returns the results
+// in target order. Breaching targets are remediated as in Run.
+func (w *Watchdog) CheckOnce(ctx context.Context) []CheckResult {
+	targets := w.Targets()
+	results := make([]CheckResult, len(targets))
+
+	var wg sync.WaitGroup
+	for i, target := range targets {
+		wg.Add(1)
+		go func(i int, target Target) {
+			defer wg.Done()
+			results[i] = w.check(ctx, target)
+		}(i, target)
+	}
+	wg.Wait()
+
+	return results
+}
+
+// CheckTarget checks the named target once and returns the result
+func (w *Watchdog) CheckTarget(ctx context.Context, name string) (CheckResult, error) {
+	w.mu.Lock()
+	loop, exists := w.targets[name]
+	w.mu.Unlock()
+	if !exists {
+		return CheckResult{}, fmt.Errorf("%w: '%s'", ErrTargetNotFound, name)
+	}
+	return w.check(ctx, loop.target), nil
+}
+
+// check checks memory usage and restarts if necessary
+func (w *Watchdog) check(ctx context.Context, target Target) CheckResult {
+	now := w.clock.Now()
+	result := CheckResult{
+		Target:    target,
+		Threshold: target.thresholdAt(now),
+		Time:      now,
+		CheckID:   newID(),
+	}
+	ctx = ContextWithCluster(ContextWithIDs(ctx, result.CheckID, ""), target.Cluster)
+	defer func() {
+		result.Duration = w.clock.Now().Sub(result.Time)
+		if result.Err != nil {
+			w.telemetry.Inc(telemetry.MetricErrorsTotal, "target", target.Name, "reason", ClassifyError(result.Err))
+		}
+		w.saveRecord(ctx, result.record())
+		w.setLastResult(result)
+		w.exportFleet()
+	}()
+
+	w.telemetry.Inc(telemetry.MetricChecksTotal, "target", target.Name)
+
+	target, err := w.activeColor(ctx, target)
+	if err != nil {
+		result.Err = fmt.Errorf("error resolving the active color: %w", err)
+		w.notify(ctx, w.event(EventCheckFailed, target, 0, result.Err))
+		return result
+	}
+	target, err = w.perReplica(ctx, target)
+	if err != nil {
+		result.Err = fmt.Errorf("error getting ready replicas: %w", err)
+		w.notify(ctx, w.event(EventCheckFailed, target, 0, result.Err))
+		return result
+	}
+	result.Target, result.Threshold = target, target.thresholdAt(now)
+
+	warming, err := w.warmingPods(ctx, target)
+	if err != nil {
+		result.Err = fmt.Errorf("error getting pod start times: %w", err)
+		w.notify(ctx, w.event(EventCheckFailed, target, 0, result.Err))
+		return result
+	}
+
+	r, source, err := w.measure(ctx, target, warming)
+	if err != nil {
+		result.Err = fmt.Errorf("error getting memory usage: %w", err)
+		w.notify(ctx, w.event(EventCheckFailed, target, 0, result.Err))
+		return result
+	}
+	if r.warmingUp {
+		result.WarmingUp = true
+		w.setLastCheck(w.clock.Now())
+		w.logger.Infof("All pods of target '%s' are warming up, skipping the check%s", target.Name, result.correlation())
+		return result
+	}
+	totalMemory := r.memory
+	result.Memory = totalMemory
+	result.Pod = r.largest
+	result.Source = source
+	w.setLastCheck(w.clock.Now())
+	w.telemetry.Set(telemetry.MetricMemoryUsage, float64(totalMemory), "target", target.Name)
+
+	w.logger.Debugf("Total memory usage in namespace '%s': %dMi%s", target.Namespace, totalMemory, result.correlation())
+
+	if w.observe(target, totalMemory) {
+		result.Outlier = true
+		w.logger.Warnf("Ignoring outlier memory usage of %dMi for target '%s'%s", totalMemory, target.Name, result.correlation())
+		event := w.event(EventCheck, target, totalMemory, nil)
+		event.Reason = "outlier"
+		w.notify(ctx, event)
+		return result
+	}
+	w.notify(ctx, w.event(EventCheck, target, totalMemory, nil))
+
+	swap, swapRead := w.swapUsage(ctx, target, result)
+	if swapRead {
+		result.Swap = swap
+	}
+	throttling, throttlingRead := w.cpuThrottling(ctx, target, result)
+	if throttlingRead {
+		result.Throttled = throttling
+	}
+
+	var reason string
+	if totalMemory >= result.Threshold {
+		result.Breached = true
+		w.logger.Infof("Memory usage of deployment '%s' exceeded threshold (%dMi).%s",
+			target.DeploymentName, result.Threshold, result.correlation())
+	} else if pod := result.Pod; pod != nil && target.PodThreshold > 0 && pod.Memory >= target.PodThreshold {
+		result.Breached = true
+		reason = fmt.Sprintf("pod '%s' at %dMi", pod.Pod, pod.Memory)
+		w.logger.Infof("Memory usage of pod '%s' (%dMi) of deployment '%s' exceeded the pod threshold (%dMi).%s",
+			pod.Pod, pod.Memory, target.DeploymentName, target.PodThreshold, result.correlation())
+	} else if swapRead && swap >= target.SwapThreshold {
+		result.Breached = true
+		reason = fmt.Sprintf("swap at %dMi", swap)
+		w.logger.Infof("Swap usage (%dMi) of deployment '%s' exceeded the swap threshold (%dMi).%s",
+			swap, target.DeploymentName, target.SwapThreshold, result.correlation())
+	} else if throttlingRead && throttling >= target.CPUThrottling {
+		result.Breached = true
+		reason = fmt.Sprintf("CPU throttled at %d%%", throttling)
+		w.logger.Infof("CPU throttling (%d%%) of deployment '%s' exceeded the throttling threshold (%d%%).%s",
+			throttling, target.DeploymentName, target.CPUThrottling, result.correlation())
+	} else if baseline, ok := w.baseline(ctx, target, result.Time); ok {
+		result.Baseline = baseline
+		if w.config.Baseline.aboveBaseline(totalMemory, baseline) {
+			result.Breached = true
+			w.logger.Infof("Memory usage of deployment '%s' is more than %g%% above the weekly baseline (%dMi).%s",
+				target.DeploymentName, w.config.Baseline.Percent, baseline, result.correlation())
+		}
+	}
+
+	if result.Breached {
+		w.openBreach(target.Name, result.Time)
+		event := w.event(EventBreach, target, totalMemory, nil)
+		event.Reason = reason
+		w.notify(ctx, event)
+		if reason, off := w.notifyOnly(); off {
+			result.Disabled = reason
+			w.logger.Infof("Not restarting deployment '%s': %s%s", target.DeploymentName, reason, result.correlation())
+			event := w.event(EventSuppressed, target, totalMemory, nil)
+			event.Reason = reason
+			w.notify(ctx, event)
+			return result
+		}
+		if reason, frozen := w.frozen(ctx, result.Time); frozen {
+			result.Frozen = reason
+			w.logger.Infof("Not restarting deployment '%s' during freeze: %s%s", target.DeploymentName, reason, result.correlation())
+			event := w.event(EventSuppressed, target, totalMemory, nil)
+			event.Reason = reason
+			w.notify(ctx, event)
+			return result
+		}
+		if reason, held := w.held(ctx, target, result); held {
+			result.Held = reason
+			w.logger.Infof("Not restarting deployment '%s': %s%s", target.DeploymentName, reason, result.correlation())
+			event := w.event(EventSuppressed, target, totalMemory, nil)
+			event.Reason = reason
+			w.notify(ctx, event)
+			return result
+		}
+		if reason, open := w.circuitOpen(target.Name, result.Time); open {
+			result.Circuit = reason
+			w.logger.Infof("Not restarting deployment '%s': %s%s", target.DeploymentName, reason, result.correlation())
+			event := w.event(EventSuppressed, target, totalMemory, nil)
+			event.Reason = reason
+			w.notify(ctx, event)
+			return result
+		}
+		if reason, inProgress := w.rollingOut(ctx, target, result); inProgress {
+			result.Deferred = reason
+			w.logger.Infof("Not restarting deployment '%s' during a rollout: %s%s", target.DeploymentName, reason, result.correlation())
+			event := w.event(EventSuppressed, target, totalMemory, nil)
+			event.Reason = "rollout in progress: " + reason
+			w.notify(ctx, event)
+			return result
+		}
+		if reason, degraded := w.degraded(ctx, target); degraded {
+			result.Escalated = reason
+			w.logger.Warnf("Not restarting deployment '%s', escalating instead: %s%s", target.DeploymentName, reason, result.correlation())
+			event := w.event(EventEscalated, target, totalMemory, nil)
+			event.Reason = reason
+			w.notify(ctx, event)
+			return result
+		}
+		result.ActionID = newID()
+		ctx = ContextWithIDs(ctx, result.CheckID, result.ActionID)
+		w.logger.Infof("Restarting deployment '%s'...%s", target.DeploymentName, result.correlation())
+		restartCtx, cancel := withOptionalTimeout(ctx, w.config.RestartTimeout)
+		defer cancel()
+		if err := w.action.Execute(restartCtx, target); err != nil {
+			result.Err = fmt.Errorf("error restarting deployment: %w", err)
+			w.notify(ctx, w.event(EventRestartFailed, target, totalMemory, result.Err))
+			w.rollBack(ctx, target, totalMemory, err)
+			w.restartFailed(ctx, target, result.Time, totalMemory)
+			return result
+		}
+		result.Action = string(EventRestart)
+		w.countRestart(target)
+		w.notify(ctx, w.event(EventRestart, target, totalMemory, nil))
+		w.restartSucceeded(ctx, target, totalMemory)
+		w.spendBudget(ctx, target, result.Time, totalMemory)
+		w.logger.Infof("Deployment successfully restarted.%s", result.correlation())
+	} else {
+		w.logger.Debugf("Memory usage is within threshold. No action needed.%s", result.correlation())
+		recovered := totalMemory < w.config.recoveryThreshold(result.Threshold)
+		if pod := result.Pod; pod != nil && target.PodThreshold > 0 && pod.Memory >= w.config.recoveryThreshold(target.PodThreshold) {
+			recovered = false
+		}
+		if swapRead && swap >= w.config.recoveryThreshold(target.SwapThreshold) {
+			recovered = false
+		}
+		if throttlingRead && throttling >= w.config.recoveryThreshold(target.CPUThrottling) {
+			recovered = false
+		}
+		if recovered {
+			if since, ok := w.closeBreach(target.Name); ok {
+				result.Resolved = true
+				w.logger.Infof("Memory usage of target '%s' is back to normal (%dMi), resolving the breach opened at %s%s",
+					target.Name, totalMemory, since.Format(time.RFC3339), result.correlation())
+				event := w.event(EventResolved, target, totalMemory, nil)
+				event.Reason = fmt.Sprintf("breached for %s", result.Time.Sub(since).Round(time.Second))
+				w.notify(ctx, event)
+			}
+		}
+	}
+
+	return result
+}
+
+// measure reads the memory usage of a target from its source chain, falling
+// back to the next source when one fails, and returns the name of the source
+// used. Targets without a chain use the default provider. Warming pods are
+// left out of the reading.
+func (w *Watchdog) measure(ctx context.Context, target Target, warming map[string]bool) (reading, string, error) {
+	if len(target.Sources) == 0 {
+		metricsCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+		defer cancel()
+		r, err := w.usage(metricsCtx, w.metrics, target, warming)
+		if err == nil {
+			w.metricsLog.Debugf("Read %dMi for target '%s'", r.memory, target.Name)
+		}
+		return r, "", err
+	}
+	if target.Quorum > 0 {
+		return w.measureQuorum(ctx, target, warming)
+	}
+
+	var err error
+	for i, name := range target.Sources {
+		provider, ok := w.sources[name]
+		if !ok {
+			err = fmt.Errorf("unknown metrics source '%s'", name)
+		} else {
+			metricsCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+			var r reading
+			r, err = w.usage(metricsCtx, provider, target, warming)
+			cancel()
+			if err == nil {
+				w.metricsLog.Debugf("Read %dMi from source '%s' for target '%s'", r.memory, name, target.Name)
+				return r, name, nil
+			}
+		}
+		if ctx.Err() != nil || i == len(target.Sources)-1 {
+			break
+		}
+
+		next := target.Sources[i+1]
+		w.metricsLog.Warnf("Metrics source '%s' failed for target '%s', falling back to '%s': %v", name, target.Name, next, err)
+		w.telemetry.Inc(telemetry.MetricSourceFallbacks, "target", target.Name, "source", name)
+		w.notify(ctx, w.event(EventSourceDegraded, target, 0, fmt.Errorf("source '%s' failed: %w", name, err)))
+	}
+	return reading{}, "", err
+}
+
+// measureQuorum reads every source of a target concurrently and returns the
+// reading ranked Quorum-th from the top, so the target breaches only when at
+// least Quorum sources report a breach. The largest pod is ranked the same
+// way among the sources reporting each pod. It fails when fewer sources
+// than the quorum could be read, and skips the check when a source finds
+// every pod warming up.
+func (w *Watchdog) measureQuorum(ctx context.Context, target Target, warming map[string]bool) (reading, string, error) {
+	metricsCtx, cancel := withOptionalTimeout(ctx, w.config.MetricsTimeout)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		readings []int
+		largest  []PodMemory
+		young    bool
+		errs     []string
+	)
+	for _, name := range target.Sources {
+		provider, ok := w.sources[name]
+		if !ok {
+			errs = append(errs, fmt.Sprintf("unknown metrics source '%s'", name))
+			continue
+		}
+		wg.Add(1)
+		go func(name string, provider MetricsProvider) {
+			defer wg.Done()
+			r, err := w.usage(metricsCtx, provider, target, warming)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				errs = append(errs, fmt.Sprintf("%s: %v", name, err))
+				return
+			}
+			if r.warmingUp {
+				young = true
+				return
+			}
+			w.metricsLog.Debugf("Read %dMi from source '%s' for target '%s'", r.memory, name, target.Name)
+			readings = append(readings, r.memory)
+			if r.largest != nil {
+				largest = append(largest, *r.largest)
+			}
+		}(name, provider)
+	}
+	wg.Wait()
+
+	// the pods are the same whatever the source
+	if young {
+		return reading{warmingUp: true}, "quorum", nil
+	}
+	if len(readings) < target.Quorum {
+		return reading{}, "", fmt.Errorf("%w: only %d of %d sources available for a quorum of %d (%s)",
+			ErrMetricsUnavailable, len(readings), len(target.Sources), target.Quorum, strings.Join(errs, "; "))
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(readings)))
+	memory := readings[target.Quorum-1]
+	threshold := target.thresholdAt(w.clock.Now())
+	if readings[0] >= threshold && memory < threshold {
+		w.metricsLog.Infof("Memory usage of target '%s' exceeded threshold according to some sources, but the quorum of %d was not reached",
+			target.Name, target.Quorum)
+	}
+	r := reading{memory: memory}
+	if len(largest) >= target.Quorum {
+		sort.Slice(largest, func(i, j int) bool { return largest[i].Memory > largest[j].Memory })
+		r.largest = &largest[target.Quorum-1]
+	}
+	return r, "quorum", nil
+}
+
+// event builds an Event for a target at the current time
+func (w *Watchdog) event(eventType EventType, target Target, memory int, err error) Event {
+	now := w.clock.Now()
+	return Event{
+		Type:      eventType,
+		Target:    target,
+		Memory:    memory,
+		Threshold: target.thresholdAt(now),
+		Time:      now,
+		Err:       err,
+	}
+}
+
+// saveRecord persists a check record when a state store is configured
+func (w *Watchdog) saveRecord(ctx context.Context, record Record) {
+	if w.store == nil {
+		return
+	}
+	if err := w.store.Save(ctx, record); err != nil {
+		w.logger.Errorf("Error saving state for target '%s': %v", record.Target, err)
+	}
+}
+
+// withOptionalTimeout derives a context with the given timeout, or a plain
+// cancellable context when the timeout is not positive
+func withOptionalTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, timeout)
+}
@@ -0,0 +1,604 @@
+package watchdog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+func TestWatchdogRun(t *testing.T) {
+	tests := []struct {
+		name          string
+		memoryUsage   int
+		threshold     int
+		restartErr    error
+		shouldRestart bool
+		checkInterval time.Duration
+		timeout       time.Duration
+	}{
+		{
+			name:          "memory below threshold",
+			memoryUsage:   1000,
+			threshold:     2000,
+			shouldRestart: false,
+			checkInterval: 100 * time.Millisecond,
+			timeout:       200 * time.Millisecond,
+		},
+		{
+			name:          "memory above threshold",
+			memoryUsage:   3000,
+			threshold:     2000,
+			shouldRestart: true,
+			checkInterval: 100 * time.Millisecond,
+			timeout:       200 * time.Millisecond,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := watchdogtest.NewFakeClient(tt.memoryUsage)
+			mockClient.FailRestarts(tt.restartErr, 0)
+
+			config := Config{
+				DeploymentName:  "test-deployment",
+				MemoryThreshold: tt.threshold,
+				CheckInterval:   tt.checkInterval,
+				Verbose:         true,
+			}
+
+			watchdog := NewWatchdog(mockClient, mockClient, config)
+			ctx, cancel := context.WithTimeout(context.Background(), tt.timeout)
+			defer cancel()
+
+			err := watchdog.Run(ctx)
+			if err != nil && err != context.DeadlineExceeded {
+				t.Errorf("Unexpected error: %v", err)
+			}
+			if restarted := len(mockClient.Restarts()) > 0; restarted != tt.shouldRestart {
+				t.Errorf("restarted = %v, want %v", restarted, tt.shouldRestart)
+			}
+		})
+	}
+}
+
+func TestWatchdogAddRemoveTarget(t *testing.T) {
+	mockClient := watchdogtest.NewFakeClient(1000)
+	config := Config{
+		Namespace:       "default",
+		DeploymentName:  "app",
+		MemoryThreshold: 2000,
+		CheckInterval:   10 * time.Millisecond,
+	}
+
+	watchdog := NewWatchdog(mockClient, mockClient, config)
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- watchdog.Run(ctx) }()
+
+	if err := watchdog.AddTarget(Target{Name: "worker", DeploymentName: "worker"}); err != nil {
+		t.Fatalf("AddTarget() error = %v", err)
+	}
+	if err := watchdog.AddTarget(Target{Name: "worker", DeploymentName: "worker"}); err == nil {
+		t.Error("AddTarget() with duplicate name should fail")
+	}
+	if got := len(watchdog.Targets()); got != 2 {
+		t.Errorf("Targets() returned %d targets, want 2", got)
+	}
+
+	time.Sleep(30 * time.Millisecond)
+
+	if err := watchdog.RemoveTarget("default/app"); err != nil {
+		t.Fatalf("RemoveTarget() error = %v", err)
+	}
+	if err := watchdog.RemoveTarget("default/app"); err == nil {
+		t.Error("RemoveTarget() of unknown target should fail")
+	}
+	if targets := watchdog.Targets(); len(targets) != 1 || targets[0].Name != "worker" {
+		t.Errorf("Targets() = %+v, want only worker", targets)
+	}
+
+	cancel()
+	if err := <-done; err != context.Canceled {
+		t.Errorf("Run() error = %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestCheckTimeouts(t *testing.T) {
+	blockingClient := watchdogtest.NewFakeClient(1000)
+	blockingClient.SetDelay(time.Hour)
+
+	watchdog := NewWatchdog(blockingClient, blockingClient, Config{
+		MetricsTimeout: 10 * time.Millisecond,
+	})
+	target := Target{Name: "default/app", Namespace: "default", DeploymentName: "app"}
+
+	start := time.Now()
+	result := watchdog.check(context.Background(), target)
+	if result.Err == nil {
+		t.Fatal("check() expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("check() took %v, want the metrics timeout to apply", elapsed)
+	}
+}
+
+// eventually polls cond until it holds or a second has passed
+func eventually(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("condition not met within 1s")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWatchdogRunWithFakeClock(t *testing.T) {
+	client := watchdogtest.NewFakeClient(1000, 1500, 3000)
+	fakeClock := watchdogtest.NewFakeClock(time.Now())
+
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "default",
+		DeploymentName:  "app",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+	}, WithClock(fakeClock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchdog.Run(ctx)
+	fakeClock.WaitForTickers(1)
+
+	for check := 1; check <= 3; check++ {
+		fakeClock.Advance(time.Minute)
+		eventually(t, func() bool { return client.MetricsCalls("default") == check })
+	}
+	eventually(t, func() bool { return len(client.Restarts()) == 1 })
+
+	if got := client.MetricsCalls("default"); got != 3 {
+		t.Errorf("MetricsCalls() = %v, want 3", got)
+	}
+}
+
+func TestCheckOnce(t *testing.T) {
+	client := watchdogtest.NewFakeClient()
+	client.SetSeries("prod", 3000)
+	client.SetSeries("staging", 500)
+
+	watchdog := NewWatchdog(client, client, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets: []Target{
+			{Namespace: "prod", DeploymentName: "api"},
+			{Namespace: "staging", DeploymentName: "api"},
+		},
+	})
+
+	results := watchdog.CheckOnce(context.Background())
+	if len(results) != 2 {
+		t.Fatalf("CheckOnce() returned %d results, want 2", len(results))
+	}
+
+	prod, staging := results[0], results[1]
+	if prod.Target.Name != "prod/api" || prod.Memory != 3000 || !prod.Breached || prod.Action != "restart" || prod.Err != nil {
+		t.Errorf("CheckOnce()[0] = %+v", prod)
+	}
+	if staging.Target.Name != "staging/api" || staging.Memory != 500 || staging.Breached || staging.Action != "" {
+		t.Errorf("CheckOnce()[1] = %+v", staging)
+	}
+
+	if _, err := watchdog.CheckTarget(context.Background(), "missing"); !errors.Is(err, ErrTargetNotFound) {
+		t.Errorf("CheckTarget() error = %v, want %v", err, ErrTargetNotFound)
+	}
+
+	client.FailMetrics(ErrMetricsUnavailable, 1)
+	result, err := watchdog.CheckTarget(context.Background(), "staging/api")
+	if err != nil || !errors.Is(result.Err, ErrMetricsUnavailable) {
+		t.Errorf("CheckTarget() = %+v, %v, want a metrics error in the result", result, err)
+	}
+
+	data, _ := json.Marshal(result)
+	if !strings.Contains(string(data), `"error":"error getting memory usage: metrics unavailable"`) {
+		t.Errorf("json.Marshal(result) = %s", data)
+	}
+}
+
+func TestSourceFailover(t *testing.T) {
+	primary := watchdogtest.NewFakeClient(1000)
+	secondary := watchdogtest.NewFakeClient(3000)
+	var events []EventType
+
+	watchdog := NewWatchdog(primary, primary, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets: []Target{
+			{Namespace: "prod", DeploymentName: "api", Sources: []string{"primary", "secondary"}},
+		},
+	},
+		WithSource("primary", primary),
+		WithSource("secondary", secondary),
+		WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+			events = append(events, event.Type)
+			return nil
+		})),
+	)
+
+	result, _ := watchdog.CheckTarget(context.Background(), "prod/api")
+	if result.Source != "primary" || result.Memory != 1000 || len(events) != 0 {
+		t.Errorf("CheckTarget() = %+v with events %v, want a reading from primary", result, events)
+	}
+
+	primary.FailMetrics(ErrMetricsUnavailable, 1)
+	result, _ = watchdog.CheckTarget(context.Background(), "prod/api")
+	if result.Source != "secondary" || result.Memory != 3000 || result.Err != nil {
+		t.Errorf("CheckTarget() = %+v, want a reading from secondary", result)
+	}
+	if len(events) == 0 || events[0] != EventSourceDegraded {
+		t.Errorf("events = %v, want %v first", events, EventSourceDegraded)
+	}
+
+	primary.FailMetrics(ErrMetricsUnavailable, 1)
+	secondary.FailMetrics(ErrForbidden, 1)
+	result, _ = watchdog.CheckTarget(context.Background(), "prod/api")
+	if !errors.Is(result.Err, ErrForbidden) {
+		t.Errorf("CheckTarget() error = %v, want the last source's error", result.Err)
+	}
+
+	if err := watchdog.AddTarget(Target{DeploymentName: "worker", Sources: []string{"missing"}}); err == nil {
+		t.Error("AddTarget() with an unknown source should fail")
+	}
+}
+
+func TestSourceQuorum(t *testing.T) {
+	metricsServer := watchdogtest.NewFakeClient(3000)
+	prometheus := watchdogtest.NewFakeClient(1000)
+
+	watchdog := NewWatchdog(metricsServer, metricsServer, Config{
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets: []Target{
+			{Namespace: "prod", DeploymentName: "api", Sources: []string{"kubectl", "prometheus"}, Quorum: 2},
+		},
+	}, WithSource("kubectl", metricsServer), WithSource("prometheus", prometheus))
+
+	result, _ := watchdog.CheckTarget(context.Background(), "prod/api")
+	if result.Breached || result.Memory != 1000 || result.Err != nil {
+		t.Errorf("CheckTarget() = %+v, want no breach without quorum", result)
+	}
+	if len(metricsServer.Restarts()) != 0 {
+		t.Error("deployment restarted without quorum")
+	}
+
+	prometheus.SetSeries("prod", 2500)
+	result, _ = watchdog.CheckTarget(context.Background(), "prod/api")
+	if !result.Breached || result.Memory != 2500 || result.Source != "quorum" {
+		t.Errorf("CheckTarget() = %+v, want a breach agreed by both sources", result)
+	}
+
+	prometheus.FailMetrics(ErrMetricsUnavailable, 1)
+	result, _ = watchdog.CheckTarget(context.Background(), "prod/api")
+	if !errors.Is(result.Err, ErrMetricsUnavailable) || result.Breached {
+		t.Errorf("CheckTarget() = %+v, want an error when a quorum can't be reached", result)
+	}
+
+	if err := watchdog.AddTarget(Target{DeploymentName: "worker", Sources: []string{"kubectl"}, Quorum: 2}); err == nil {
+		t.Error("AddTarget() with a quorum larger than its sources should fail")
+	}
+}
+
+type fakeFreeze struct {
+	reason string
+}
+
+func (f fakeFreeze) Frozen(ctx context.Context, t time.Time) (string, bool, error) {
+	return f.reason, f.reason != "", errors.New("calendar is stale")
+}
+
+func TestCheckDuringFreeze(t *testing.T) {
+	client := watchdogtest.NewFakeClient(3000)
+	var events []Event
+	var logs bytes.Buffer
+
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+	},
+		WithFreeze(fakeFreeze{}),
+		WithFreeze(fakeFreeze{reason: "Black Friday"}),
+		WithLogger(log.New(&logs, "", 0)),
+		WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+			events = append(events, event)
+			return nil
+		})),
+	)
+
+	result, _ := watchdog.CheckTarget(context.Background(), "prod/api")
+	if !result.Breached || result.Action != "" || result.Frozen != "Black Friday" {
+		t.Errorf("CheckTarget() = %+v, want a breach suppressed by the freeze", result)
+	}
+	if len(client.Restarts()) != 0 {
+		t.Errorf("Restarts() = %v, want none during a freeze", client.Restarts())
+	}
+	if len(events) != 2 || events[1].Type != EventSuppressed || events[1].Reason != "Black Friday" {
+		t.Errorf("events = %+v, want a breach and a suppressed event", events)
+	}
+	if strings.Contains(logs.String(), "Restarting deployment") {
+		t.Errorf("logs = %q, want no restart logged during a freeze", logs.String())
+	}
+}
+
+type fakeHold map[string]string
+
+func (f fakeHold) Held(ctx context.Context, target Target) (string, bool, error) {
+	if target.Name == "broken" {
+		return "", false, errors.New("alertmanager unavailable")
+	}
+	reason, held := f[target.Name]
+	return reason, held, nil
+}
+
+func TestCheckWhileHeld(t *testing.T) {
+	client := watchdogtest.NewFakeClient(3000)
+	var events []Event
+
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+		Targets: []Target{
+			{Name: "api", DeploymentName: "api"},
+			{Name: "broken", DeploymentName: "broken"},
+		},
+	},
+		WithHold(fakeHold{"api": "silenced in Alertmanager"}),
+		WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+			events = append(events, event)
+			return nil
+		})),
+	)
+
+	result, _ := watchdog.CheckTarget(context.Background(), "api")
+	if !result.Breached || result.Action != "" || result.Held != "silenced in Alertmanager" {
+		t.Errorf("CheckTarget() = %+v, want a breach held back", result)
+	}
+	if len(events) != 2 || events[1].Type != EventSuppressed || events[1].Reason != "silenced in Alertmanager" {
+		t.Errorf("events = %+v, want a breach and a suppressed event", events)
+	}
+	// a failed lookup doesn't hold the restart back
+	result, _ = watchdog.CheckTarget(context.Background(), "broken")
+	if result.Action != string(EventRestart) || result.Held != "" {
+		t.Errorf("CheckTarget() = %+v, want a restart when the hold can't be checked", result)
+	}
+	if restarts := client.Restarts(); len(restarts) != 1 {
+		t.Errorf("Restarts() = %v, want only the target that isn't held", restarts)
+	}
+}
+
+func TestWatchdogRunWithCron(t *testing.T) {
+	client := watchdogtest.NewFakeClient(1000)
+	fakeClock := watchdogtest.NewFakeClock(time.Date(2024, 3, 13, 10, 0, 0, 0, time.UTC))
+
+	watchdog := NewWatchdog(client, client, Config{
+		MemoryThreshold: 2000,
+		Targets:         []Target{{Namespace: "default", DeploymentName: "app", Cron: "0 * * * *", Timezone: "UTC"}},
+	}, WithClock(fakeClock))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watchdog.Run(ctx)
+	fakeClock.WaitForTickers(1)
+
+	// ticks are dropped while the loop is busy, so only advance to the
+	// matching minute
+	fakeClock.Advance(59 * time.Minute)
+	time.Sleep(10 * time.Millisecond)
+	if got := client.MetricsCalls("default"); got != 0 {
+		t.Errorf("MetricsCalls() = %v before the scheduled minute, want 0", got)
+	}
+	fakeClock.Advance(time.Minute)
+	eventually(t, func() bool { return client.MetricsCalls("default") == 1 })
+}
+
+func TestEventStream(t *testing.T) {
+	client := watchdogtest.NewFakeClient(1000, 3000)
+	var notified, streamed []EventType
+
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+	},
+		WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+			notified = append(notified, event.Type)
+			return nil
+		})),
+		WithEventStream(NotifierFunc(func(ctx context.Context, event Event) error {
+			streamed = append(streamed, event.Type)
+			return nil
+		})),
+	)
+
+	watchdog.CheckTarget(context.Background(), "prod/api")
+	watchdog.CheckTarget(context.Background(), "prod/api")
+
+	if want := []EventType{EventCheck, EventCheck, EventBreach, EventRestart}; fmt.Sprint(streamed) != fmt.Sprint(want) {
+		t.Errorf("streamed events = %v, want %v", streamed, want)
+	}
+	if want := []EventType{EventBreach, EventRestart}; fmt.Sprint(notified) != fmt.Sprint(want) {
+		t.Errorf("notified events = %v, want %v", notified, want)
+	}
+}
+
+func TestCorrelationIDs(t *testing.T) {
+	client := watchdogtest.NewFakeClient(3000)
+	store := NewMemoryStateStore(0)
+	var events []Event
+	var checkID, actionID string
+
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+	},
+		WithStateStore(store),
+		WithAction(ActionFunc(func(ctx context.Context, target Target) error {
+			checkID, actionID = CheckID(ctx), ActionID(ctx)
+			return nil
+		})),
+		WithEventStream(NotifierFunc(func(ctx context.Context, event Event) error {
+			events = append(events, event)
+			return nil
+		})),
+	)
+
+	result, _ := watchdog.CheckTarget(context.Background(), "prod/api")
+	if result.CheckID == "" || result.ActionID == "" || result.CheckID == result.ActionID {
+		t.Fatalf("CheckTarget() IDs = %q, %q, want two distinct IDs", result.CheckID, result.ActionID)
+	}
+	if checkID != result.CheckID || actionID != result.ActionID {
+		t.Errorf("action context IDs = %q, %q, want %q, %q", checkID, actionID, result.CheckID, result.ActionID)
+	}
+	for _, event := range events {
+		wantAction := ""
+		if event.Type == EventRestart {
+			wantAction = result.ActionID
+		}
+		if event.CheckID != result.CheckID || event.ActionID != wantAction {
+			t.Errorf("%s event IDs = %q, %q, want %q, %q", event.Type, event.CheckID, event.ActionID, result.CheckID, wantAction)
+		}
+	}
+	records, _ := store.List(context.Background(), time.Time{})
+	if len(records) != 1 || records[0].CheckID != result.CheckID || records[0].ActionID != result.ActionID {
+		t.Errorf("records = %+v, want the IDs of the check", records)
+	}
+
+	next, _ := watchdog.CheckTarget(context.Background(), "prod/api")
+	if next.CheckID == result.CheckID {
+		t.Errorf("CheckTarget() reused the check ID %q", next.CheckID)
+	}
+}
+
+func TestResolvedBreach(t *testing.T) {
+	client := watchdogtest.NewFakeClient(3000, 1900, 1700, 1700)
+	fakeClock := watchdogtest.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	var events []Event
+
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+		RecoveryPercent: 90,
+		CheckInterval:   time.Minute,
+	},
+		WithClock(fakeClock),
+		WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+			events = append(events, event)
+			return nil
+		})),
+	)
+
+	watchdog.CheckTarget(context.Background(), "prod/api")
+	if status := watchdog.Status(); status[0].BreachedSince == nil {
+		t.Errorf("Status() = %+v, want an open breach", status[0])
+	}
+	fakeClock.Advance(5 * time.Minute)
+	// 1900Mi is under the threshold but above the recovery threshold
+	if result, _ := watchdog.CheckTarget(context.Background(), "prod/api"); result.Resolved {
+		t.Errorf("CheckTarget() resolved the breach at %dMi, want it to stay open", result.Memory)
+	}
+	fakeClock.Advance(5 * time.Minute)
+	if result, _ := watchdog.CheckTarget(context.Background(), "prod/api"); !result.Resolved {
+		t.Errorf("CheckTarget() = %+v, want the breach resolved", result)
+	}
+	watchdog.CheckTarget(context.Background(), "prod/api")
+
+	var types []EventType
+	for _, event := range events {
+		types = append(types, event.Type)
+	}
+	if want := []EventType{EventBreach, EventRestart, EventResolved}; fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Fatalf("events = %v, want %v", types, want)
+	}
+	if events[2].Memory != 1700 || events[2].Reason != "breached for 10m0s" {
+		t.Errorf("resolved event = %+v, want the usage and duration of the breach", events[2])
+	}
+	if status := watchdog.Status(); status[0].BreachedSince != nil {
+		t.Errorf("Status() = %+v, want no open breach", status[0])
+	}
+	if last := watchdog.Status()[0].LastAction; last == nil || last.Action != "restart" || last.Memory != 3000 {
+		t.Errorf("Status()[0].LastAction = %+v, want the restart of the breach", last)
+	}
+}
+
+func TestSilence(t *testing.T) {
+	client := watchdogtest.NewFakeClient(3000)
+	fakeClock := watchdogtest.NewFakeClock(time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC))
+	var notified, streamed []EventType
+
+	watchdog := NewWatchdog(client, client, Config{
+		Namespace:       "prod",
+		DeploymentName:  "api",
+		MemoryThreshold: 2000,
+		CheckInterval:   time.Minute,
+	},
+		WithClock(fakeClock),
+		WithNotifier(NotifierFunc(func(ctx context.Context, event Event) error {
+			notified = append(notified, event.Type)
+			return nil
+		})),
+		WithEventStream(NotifierFunc(func(ctx context.Context, event Event) error {
+			if event.Type != EventCheck {
+				streamed = append(streamed, event.Type)
+			}
+			return nil
+		})),
+	)
+	ctx := context.Background()
+
+	if _, err := watchdog.Silence(ctx, "prod/missing", time.Hour, ""); !errors.Is(err, ErrTargetNotFound) {
+		t.Errorf("Silence() error = %v, want ErrTargetNotFound", err)
+	}
+	if err := watchdog.Unsilence(ctx, "prod/api", ""); !errors.Is(err, ErrNotSilenced) {
+		t.Errorf("Unsilence() error = %v, want ErrNotSilenced", err)
+	}
+	silence, err := watchdog.Silence(ctx, "prod/api", time.Hour, "maintenance")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := fakeClock.Now().Add(time.Hour); !silence.Until.Equal(want) {
+		t.Errorf("Silence().Until = %v, want %v", silence.Until, want)
+	}
+	if status := watchdog.Status(); status[0].Silence == nil || status[0].Silence.Reason != "maintenance" {
+		t.Errorf("Status() = %+v, want the silence", status[0])
+	}
+
+	watchdog.CheckTarget(ctx, "prod/api")
+	if len(client.Restarts()) != 1 {
+		t.Errorf("Restarts() = %v, want silenced targets to be restarted", client.Restarts())
+	}
+	fakeClock.Advance(time.Hour)
+	watchdog.CheckTarget(ctx, "prod/api")
+
+	if want := []EventType{EventSilenced, EventUnsilenced, EventBreach, EventRestart}; fmt.Sprint(notified) != fmt.Sprint(want) {
+		t.Errorf("notified events = %v, want %v", notified, want)
+	}
+	if want := []EventType{EventSilenced, EventBreach, EventRestart, EventUnsilenced, EventBreach, EventRestart}; fmt.Sprint(streamed) != fmt.Sprint(want) {
+		t.Errorf("streamed events = %v, want %v", streamed, want)
+	}
+	if status := watchdog.Status(); status[0].Silence != nil {
+		t.Errorf("Status() = %+v, want the silence expired", status[0])
+	}
+}
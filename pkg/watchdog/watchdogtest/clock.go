@@ -0,0 +1,110 @@
+package watchdogtest
+
+import (
+	"sync"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/clock"
+)
+
+// FakeClock is a clock.Clock whose time only moves when Advance is
+// called. Tickers fire synchronously from Advance.
+type FakeClock struct {
+	mu      sync.Mutex
+	cond    *sync.Cond
+	now     time.Time
+	tickers []*FakeTicker
+}
+
+// FakeTicker is a ticker created by a FakeClock
+type FakeTicker struct {
+	clock    *FakeClock
+	c        chan time.Time
+	interval time.Duration
+	next     time.Time
+	stopped  bool
+}
+
+// NewFakeClock creates a FakeClock starting at the given time
+func NewFakeClock(now time.Time) *FakeClock {
+	c := &FakeClock{now: now}
+	c.cond = sync.NewCond(&c.mu)
+	return c
+}
+
+// Now returns the fake current time
+func (c *FakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+// NewTicker creates a ticker firing every d of fake time
+func (c *FakeClock) NewTicker(d time.Duration) clock.Ticker {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	t := &FakeTicker{
+		clock:    c,
+		c:        make(chan time.Time, 1),
+		interval: d,
+		next:     c.now.Add(d),
+	}
+	c.tickers = append(c.tickers, t)
+	c.cond.Broadcast()
+	return t
+}
+
+// Advance moves the fake time forward by d, firing every ticker that comes
+// due. Like time.Ticker, ticks are dropped when a receiver falls behind.
+func (c *FakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+	for _, t := range c.tickers {
+		if t.stopped {
+			continue
+		}
+		for !t.next.After(c.now) {
+			select {
+			case t.c <- t.next:
+			default:
+			}
+			t.next = t.next.Add(t.interval)
+		}
+	}
+}
+
+// WaitForTickers blocks until at least n tickers are active, which tells
+// the test that the watchdog loops are ready to receive ticks
+func (c *FakeClock) WaitForTickers(n int) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for c.activeLocked() < n {
+		c.cond.Wait()
+	}
+}
+
+func (c *FakeClock) activeLocked() int {
+	active := 0
+	for _, t := range c.tickers {
+		if !t.stopped {
+			active++
+		}
+	}
+	return active
+}
+
+// C returns the channel on which ticks are delivered
+func (t *FakeTicker) C() <-chan time.Time {
+	return t.c
+}
+
+// Stop turns off the ticker
+func (t *FakeTicker) Stop() {
+	t.clock.mu.Lock()
+	defer t.clock.mu.Unlock()
+	t.stopped = true
+	t.clock.cond.Broadcast()
+}
@@ -0,0 +1,47 @@
+package watchdogtest_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/clock"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+var _ clock.Clock = (*watchdogtest.FakeClock)(nil)
+
+func TestFakeClockTicker(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := watchdogtest.NewFakeClock(start)
+	ticker := fake.NewTicker(time.Minute)
+	fake.WaitForTickers(1)
+
+	fake.Advance(30 * time.Second)
+	select {
+	case <-ticker.C():
+		t.Fatal("ticker fired before its interval elapsed")
+	default:
+	}
+
+	fake.Advance(30 * time.Second)
+	select {
+	case tick := <-ticker.C():
+		if !tick.Equal(start.Add(time.Minute)) {
+			t.Errorf("tick = %v, want %v", tick, start.Add(time.Minute))
+		}
+	default:
+		t.Fatal("ticker did not fire after its interval elapsed")
+	}
+
+	if got := fake.Now(); !got.Equal(start.Add(time.Minute)) {
+		t.Errorf("Now() = %v, want %v", got, start.Add(time.Minute))
+	}
+
+	ticker.Stop()
+	fake.Advance(time.Minute)
+	select {
+	case <-ticker.C():
+		t.Fatal("stopped ticker fired")
+	default:
+	}
+}
@@ -0,0 +1,175 @@
+// Package watchdogtest provides test doubles for code built on the watchdog
+// package.
+package watchdogtest
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Restart records a RestartDeployment call made to a FakeClient
+type Restart struct {
+	Namespace  string
+	Deployment string
+}
+
+// FakeClient is a scriptable watchdog.MetricsProvider and watchdog.Restarter. Each namespace
+// reports memory readings from a series, one value per call, repeating the
+// last value once the series is exhausted. Failures can be injected for
+// metric collection and restarts.
+type FakeClient struct {
+	mu sync.Mutex
+
+	series        map[string][]int
+	defaultSeries []int
+	position      map[string]int
+	delay         time.Duration
+
+	metricsErr   error
+	metricsFails int
+	restartErr   error
+	restartFails int
+
+	metricsCalls map[string]int
+	restarts     []Restart
+}
+
+// NewFakeClient creates a FakeClient reporting the given memory series in
+// every namespace
+func NewFakeClient(memory ...int) *FakeClient {
+	return &FakeClient{
+		series:        make(map[string][]int),
+		defaultSeries: memory,
+		position:      make(map[string]int),
+		metricsCalls:  make(map[string]int),
+	}
+}
+
+// SetSeries scripts the memory readings reported for a namespace
+func (f *FakeClient) SetSeries(namespace string, memory ...int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.series[namespace] = memory
+	f.position[namespace] = 0
+}
+
+// SetDelay makes every call block for d before answering, unless the
+// context is done first
+func (f *FakeClient) SetDelay(d time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.delay = d
+}
+
+// FailMetrics makes the next times calls to GetPodMemoryUsage return err.
+// A times value of zero or less fails every call until reset with a nil err.
+func (f *FakeClient) FailMetrics(err error, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.metricsErr = err
+	f.metricsFails = times
+}
+
+// FailRestarts makes the next times calls to RestartDeployment return err.
+// A times value of zero or less fails every call until reset with a nil err.
+func (f *FakeClient) FailRestarts(err error, times int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.restartErr = err
+	f.restartFails = times
+}
+
+// GetPodMemoryUsage returns the next reading of the namespace's series
+func (f *FakeClient) GetPodMemoryUsage(ctx context.Context, namespace string) (int, error) {
+	if err := f.wait(ctx); err != nil {
+		return 0, err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.metricsCalls[namespace]++
+	if err := takeFailure(&f.metricsErr, &f.metricsFails); err != nil {
+		return 0, err
+	}
+
+	series, ok := f.series[namespace]
+	if !ok {
+		series = f.defaultSeries
+	}
+	if len(series) == 0 {
+		return 0, nil
+	}
+	pos := f.position[namespace]
+	if pos >= len(series) {
+		pos = len(series) - 1
+	} else {
+		f.position[namespace]++
+	}
+	return series[pos], nil
+}
+
+// RestartDeployment records the restart, or returns an injected failure
+func (f *FakeClient) RestartDeployment(ctx context.Context, namespace, deployment string) error {
+	if err := f.wait(ctx); err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if err := takeFailure(&f.restartErr, &f.restartFails); err != nil {
+		return err
+	}
+	f.restarts = append(f.restarts, Restart{Namespace: namespace, Deployment: deployment})
+	return nil
+}
+
+// MetricsCalls returns how many times metrics were requested for a namespace
+func (f *FakeClient) MetricsCalls(namespace string) int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.metricsCalls[namespace]
+}
+
+// Restarts returns the successful restarts in the order they happened
+func (f *FakeClient) Restarts() []Restart {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Restart(nil), f.restarts...)
+}
+
+func (f *FakeClient) wait(ctx context.Context) error {
+	f.mu.Lock()
+	delay := f.delay
+	f.mu.Unlock()
+
+	if delay <= 0 {
+		return nil
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// takeFailure returns the injected error, consuming one use of it when it
+// is limited to a number of calls
+func takeFailure(err *error, times *int) error {
+	if *err == nil {
+		return nil
+	}
+	failure := *err
+	if *times > 0 {
+		*times--
+		if *times == 0 {
+			*err = nil
+		}
+	}
+	return failure
+}
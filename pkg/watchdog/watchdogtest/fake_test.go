@@ -0,0 +1,66 @@
+package watchdogtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog"
+	"github.com/renancavalcantercb/k8s-memory-watchdog/pkg/watchdog/watchdogtest"
+)
+
+var (
+	_ watchdog.MetricsProvider = (*watchdogtest.FakeClient)(nil)
+	_ watchdog.Restarter       = (*watchdogtest.FakeClient)(nil)
+)
+
+func TestFakeClientSeries(t *testing.T) {
+	client := watchdogtest.NewFakeClient(100, 200)
+	client.SetSeries("prod", 1000, 2000, 3000)
+	ctx := context.Background()
+
+	for _, want := range []int{100, 200, 200} {
+		if got, _ := client.GetPodMemoryUsage(ctx, "default"); got != want {
+			t.Errorf("GetPodMemoryUsage(default) = %v, want %v", got, want)
+		}
+	}
+	for _, want := range []int{1000, 2000, 3000, 3000} {
+		if got, _ := client.GetPodMemoryUsage(ctx, "prod"); got != want {
+			t.Errorf("GetPodMemoryUsage(prod) = %v, want %v", got, want)
+		}
+	}
+	if got := client.MetricsCalls("prod"); got != 4 {
+		t.Errorf("MetricsCalls(prod) = %v, want 4", got)
+	}
+}
+
+func TestFakeClientFailures(t *testing.T) {
+	client := watchdogtest.NewFakeClient(100)
+	ctx := context.Background()
+
+	client.FailMetrics(watchdog.ErrMetricsUnavailable, 2)
+	for i := 0; i < 2; i++ {
+		if _, err := client.GetPodMemoryUsage(ctx, "default"); !errors.Is(err, watchdog.ErrMetricsUnavailable) {
+			t.Errorf("GetPodMemoryUsage() error = %v, want %v", err, watchdog.ErrMetricsUnavailable)
+		}
+	}
+	if _, err := client.GetPodMemoryUsage(ctx, "default"); err != nil {
+		t.Errorf("GetPodMemoryUsage() error = %v after injected failures", err)
+	}
+
+	client.FailRestarts(watchdog.ErrForbidden, 0)
+	for i := 0; i < 3; i++ {
+		if err := client.RestartDeployment(ctx, "default", "app"); !errors.Is(err, watchdog.ErrForbidden) {
+			t.Errorf("RestartDeployment() error = %v, want %v", err, watchdog.ErrForbidden)
+		}
+	}
+	client.FailRestarts(nil, 0)
+	if err := client.RestartDeployment(ctx, "default", "app"); err != nil {
+		t.Errorf("RestartDeployment() error = %v", err)
+	}
+
+	restarts := client.Restarts()
+	if len(restarts) != 1 || restarts[0] != (watchdogtest.Restart{Namespace: "default", Deployment: "app"}) {
+		t.Errorf("Restarts() = %+v", restarts)
+	}
+}